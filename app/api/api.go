@@ -1,12 +1,320 @@
+// Package api provides a management API of a running V2Ray instance in gRPC, over a dedicated port. Inbounds and
+// outbounds are managed by HandlerService, traffic counters are queried by StatsService, live connections are listed
+// and closed by ConnectionService, the pprof and expvar endpoint is toggled by DebugService, and the build and uptime are
+// reported by VersionService.
 package api
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"v2ray.com/core"
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/debug"
+	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common/log"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 const (
 	APP_ID = app.ID(5)
+
+	HandlerServiceName    = "v2ray.core.app.api.HandlerService"
+	StatsServiceName      = "v2ray.core.app.api.StatsService"
+	DebugServiceName      = "v2ray.core.app.api.DebugService"
+	ConnectionServiceName = "v2ray.core.app.api.ConnectionService"
+	VersionServiceName    = "v2ray.core.app.api.VersionService"
+)
+
+var (
+	ErrAlreadyStarted = errors.New("API: Server is already started.")
 )
 
+// HandlerManager manages inbounds and outbounds of a running instance. Inbounds and outbounds are in the JSON format
+// of inbound and outbound detours in config files, and they are identified by their tags.
+type HandlerManager interface {
+	AddInbound(config []byte) error
+	RemoveInbound(tag string) error
+	AddOutbound(config []byte) error
+	RemoveOutbound(tag string) error
+	// AlterInbound adds users to and removes users by email from an inbound, whose handlers must support changing
+	// users, e.g. VMess. Users are in the JSON format of users in inbound settings.
+	AlterInbound(tag string, addUsers [][]byte, removeEmails []string) error
+}
+
+// InstanceInfo describes the running instance. HandlerManagers may implement it for VersionService to report the
+// uptime and the config hash.
+type InstanceInfo interface {
+	StartTime() time.Time
+	ConfigHash() string
+}
+
+// HandlerManagerProvider is implemented by inbound handler managers, to give the HandlerManager of the instance.
+type HandlerManagerProvider interface {
+	HandlerManager() HandlerManager
+}
+
 type ApiServer struct {
+	config   *Config
+	server   *http.Server
+	listener net.Listener
+	manager  HandlerManager
+	stats    *stats.Manager
+	debug    *debug.Server
+	conns    dispatcher.ConnectionManager
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "api",
+		Config: (*Config)(nil),
+		After:  []app.ID{proxyman.APP_ID_INBOUND_MANAGER, dispatcher.APP_ID, debug.APP_ID, stats.APP_ID},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			server := NewApiServer(space, config.(*Config), nil)
+			err := space.RequireFeatures(func(provider HandlerManagerProvider) {
+				server.manager = provider.HandlerManager()
+			})
+			return server, err
+		},
+	})
+}
+
+// NewApiServer returns a server managing handlers by the given manager. If the manager is nil, it must be set before
+// the server starts.
+func NewApiServer(space app.Space, config *Config, manager HandlerManager) *ApiServer {
+	grpc := newGRPCServer()
+	server := &ApiServer{
+		config:  config,
+		manager: manager,
+		server: &http.Server{
+			Handler: h2c.NewHandler(grpc, &http2.Server{}),
+		},
+	}
+	server.registerHandlerService(grpc)
+	server.registerStatsService(grpc)
+	server.registerDebugService(grpc)
+	server.registerConnectionService(grpc)
+	server.registerVersionService(grpc)
+	space.InitializeApplication(func() error {
+		if space.HasApp(stats.APP_ID) {
+			server.stats = space.GetApp(stats.APP_ID).(*stats.Manager)
+		}
+		if space.HasApp(debug.APP_ID) {
+			server.debug = space.GetApp(debug.APP_ID).(*debug.Server)
+		}
+		if manager, ok := space.GetApp(dispatcher.APP_ID).(dispatcher.ConnectionManager); ok {
+			server.conns = manager
+		}
+		return nil
+	})
+	return server
+}
+
+func (this *ApiServer) registerHandlerService(grpc *grpcServer) {
+	grpc.register(HandlerServiceName, "AddInbound", &method{
+		newRequest: func() proto.Message { return new(AddInboundRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			return new(AddInboundResponse), this.manager.AddInbound(request.(*AddInboundRequest).Inbound)
+		},
+	})
+	grpc.register(HandlerServiceName, "RemoveInbound", &method{
+		newRequest: func() proto.Message { return new(RemoveInboundRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			return new(RemoveInboundResponse), this.manager.RemoveInbound(request.(*RemoveInboundRequest).Tag)
+		},
+	})
+	grpc.register(HandlerServiceName, "AddOutbound", &method{
+		newRequest: func() proto.Message { return new(AddOutboundRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			return new(AddOutboundResponse), this.manager.AddOutbound(request.(*AddOutboundRequest).Outbound)
+		},
+	})
+	grpc.register(HandlerServiceName, "RemoveOutbound", &method{
+		newRequest: func() proto.Message { return new(RemoveOutboundRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			return new(RemoveOutboundResponse), this.manager.RemoveOutbound(request.(*RemoveOutboundRequest).Tag)
+		},
+	})
+	grpc.register(HandlerServiceName, "AlterInbound", &method{
+		newRequest: func() proto.Message { return new(AlterInboundRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			alter := request.(*AlterInboundRequest)
+			return new(AlterInboundResponse), this.manager.AlterInbound(alter.Tag, alter.AddUsers, alter.RemoveEmails)
+		},
+	})
+}
+
+func (this *ApiServer) getStats() (*stats.Manager, error) {
+	if this.stats == nil {
+		return nil, &Status{Code: codeUnimplemented, Message: "stats are not enabled"}
+	}
+	return this.stats, nil
+}
+
+func (this *ApiServer) registerStatsService(grpc *grpcServer) {
+	grpc.register(StatsServiceName, "GetStats", &method{
+		newRequest: func() proto.Message { return new(GetStatsRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			manager, err := this.getStats()
+			if err != nil {
+				return nil, err
+			}
+			get := request.(*GetStatsRequest)
+			counter := manager.GetCounter(get.Name)
+			if counter == nil {
+				return nil, &Status{Code: codeNotFound, Message: "counter " + get.Name + " not found"}
+			}
+			value := counter.Value()
+			if get.Reset_ {
+				value = counter.Set(0)
+			}
+			return &GetStatsResponse{Stat: &Stat{Name: get.Name, Value: value}}, nil
+		},
+	})
+	grpc.register(StatsServiceName, "QueryStats", &method{
+		newRequest: func() proto.Message { return new(QueryStatsRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			manager, err := this.getStats()
+			if err != nil {
+				return nil, err
+			}
+			query := request.(*QueryStatsRequest)
+			names, values := manager.Query(query.Pattern, query.Reset_)
+			response := &QueryStatsResponse{
+				Stat: make([]*Stat, len(names)),
+			}
+			for idx, name := range names {
+				response.Stat[idx] = &Stat{Name: name, Value: values[idx]}
+			}
+			return response, nil
+		},
+	})
+}
+
+func (this *ApiServer) registerDebugService(grpc *grpcServer) {
+	grpc.register(DebugServiceName, "SetDebug", &method{
+		newRequest: func() proto.Message { return new(SetDebugRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			if this.debug == nil {
+				return nil, &Status{Code: codeUnimplemented, Message: "debug endpoint is not configured"}
+			}
+			if err := this.debug.SetEnabled(request.(*SetDebugRequest).Enabled); err != nil {
+				return nil, err
+			}
+			return &SetDebugResponse{Enabled: this.debug.Enabled(), Address: this.debug.Address()}, nil
+		},
+	})
+}
+
+func (this *ApiServer) getConnections() (dispatcher.ConnectionManager, error) {
+	if this.conns == nil {
+		return nil, &Status{Code: codeUnimplemented, Message: "connections are not tracked"}
+	}
+	return this.conns, nil
+}
+
+func (this *ApiServer) registerVersionService(grpc *grpcServer) {
+	grpc.register(VersionServiceName, "GetVersion", &method{
+		newRequest: func() proto.Message { return new(GetVersionRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			build := core.GetBuildInfo()
+			response := &GetVersionResponse{
+				Version:   build.Version,
+				Codename:  build.Codename,
+				Build:     build.Build,
+				GoVersion: build.GoVersion,
+				Os:        build.OS,
+				Arch:      build.Arch,
+				Tags:      build.Tags,
+				Features:  build.Features,
+			}
+			if info, ok := this.manager.(InstanceInfo); ok {
+				if start := info.StartTime(); !start.IsZero() {
+					response.StartTime = start.Unix()
+					response.Uptime = int64(time.Since(start).Seconds())
+				}
+				response.ConfigHash = info.ConfigHash()
+			}
+			return response, nil
+		},
+	})
+}
+
+func (this *ApiServer) registerConnectionService(grpc *grpcServer) {
+	grpc.register(ConnectionServiceName, "ListConnections", &method{
+		newRequest: func() proto.Message { return new(ListConnectionsRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			manager, err := this.getConnections()
+			if err != nil {
+				return nil, err
+			}
+			infos := manager.ListConnections()
+			response := &ListConnectionsResponse{
+				Connection: make([]*Connection, len(infos)),
+			}
+			now := time.Now()
+			for idx, info := range infos {
+				response.Connection[idx] = &Connection{
+					Id:          info.ID,
+					Source:      info.Source.NetAddr(),
+					Destination: info.Destination.String(),
+					Inbound:     info.InboundTag,
+					Outbound:    info.OutboundTag,
+					User:        info.User,
+					Domain:      info.SniffedDomain,
+					Age:         int64(now.Sub(info.Start).Seconds()),
+					Uplink:      info.Uplink,
+					Downlink:    info.Downlink,
+				}
+			}
+			return response, nil
+		},
+	})
+	grpc.register(ConnectionServiceName, "CloseConnection", &method{
+		newRequest: func() proto.Message { return new(CloseConnectionRequest) },
+		call: func(request proto.Message) (proto.Message, error) {
+			manager, err := this.getConnections()
+			if err != nil {
+				return nil, err
+			}
+			id := request.(*CloseConnectionRequest).Id
+			if !manager.CloseConnection(id) {
+				return nil, &Status{Code: codeNotFound, Message: fmt.Sprint("connection ", id, " not found")}
+			}
+			return new(CloseConnectionResponse), nil
+		},
+	})
+}
+
+// Start listens on the configured port, and serves API calls in background.
+func (this *ApiServer) Start() error {
+	if this.listener != nil {
+		return ErrAlreadyStarted
+	}
+	address := net.JoinHostPort(this.config.Listen.String(), this.config.Port.String())
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Error("API: Failed to listen on ", address, ": ", err)
+		return err
+	}
+	this.listener = listener
+	log.Info("API: Listening on ", address)
+	go this.server.Serve(listener)
+	return nil
+}
+
+func (this *ApiServer) Release() {
+	if this.listener != nil {
+		this.server.Close()
+		this.listener = nil
+	}
 }
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+)
+
+// Client calls the API of a running instance.
+type Client struct {
+	address string
+	client  *http.Client
+}
+
+// NewClient returns a Client of the API server at address, e.g. "127.0.0.1:10085".
+func NewClient(address string, timeout time.Duration) *Client {
+	return &Client{
+		address: address,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLS: func(network string, addr string, config *tls.Config) (net.Conn, error) {
+					return net.DialTimeout(network, addr, timeout)
+				},
+			},
+		},
+	}
+}
+
+// Call calls a method of a service, e.g. "v2ray.core.app.api.HandlerService" and "AddInbound".
+func (this *Client) Call(service string, name string, request proto.Message, response proto.Message) error {
+	frame, err := encodeMessage(request)
+	if err != nil {
+		return err
+	}
+	httpRequest, err := http.NewRequest("POST", "http://"+this.address+"/"+service+"/"+name, bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", grpcContentType)
+	httpRequest.Header.Set("TE", "trailers")
+	httpResponse, err := this.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		return &Status{Code: codeUnknown, Message: httpResponse.Status}
+	}
+
+	payload, err := ioutil.ReadAll(io.LimitReader(httpResponse.Body, maxMessageSize+5))
+	if err != nil {
+		return err
+	}
+	// The status is in headers if the response has no messages.
+	header := httpResponse.Trailer
+	if len(header.Get("Grpc-Status")) == 0 {
+		header = httpResponse.Header
+	}
+	code, err := strconv.Atoi(header.Get("Grpc-Status"))
+	if err != nil {
+		return &Status{Code: codeUnknown, Message: "missing status"}
+	}
+	if code != codeOK {
+		return &Status{Code: code, Message: decodeStatusMessage(header.Get("Grpc-Message"))}
+	}
+	return readMessage(bytes.NewReader(payload), response)
+}
@@ -5,5 +5,7 @@ import (
 )
 
 type Config struct {
-	DirectPort v2net.Port
+	// Listen is the address that the API server listens on, localhost by default.
+	Listen v2net.Address
+	Port   v2net.Port
 }
@@ -0,0 +1,33 @@
+// +build json
+
+package api
+
+import (
+	"errors"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Listen *v2net.AddressPB `json:"listen"`
+		Port   v2net.Port       `json:"port"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("API: Failed to parse config: ", err)
+	}
+	if jsonConfig.Port == 0 {
+		return errors.New("API: Port is not specified.")
+	}
+	this.Listen = v2net.LocalHostIP
+	if jsonConfig.Listen != nil {
+		if jsonConfig.Listen.AsAddress().Family().IsDomain() {
+			return errors.New("API: Unable to listen on domain address: " + jsonConfig.Listen.AsAddress().Domain())
+		}
+		this.Listen = jsonConfig.Listen.AsAddress()
+	}
+	this.Port = jsonConfig.Port
+	return nil
+}
@@ -0,0 +1,112 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/api/connection.proto
+// DO NOT EDIT!
+
+package api
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type Connection struct {
+	Id          uint64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Source      string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Destination string `protobuf:"bytes,3,opt,name=destination" json:"destination,omitempty"`
+	Inbound     string `protobuf:"bytes,4,opt,name=inbound" json:"inbound,omitempty"`
+	// Outbound tag, which is empty for the global default outbound.
+	Outbound string `protobuf:"bytes,5,opt,name=outbound" json:"outbound,omitempty"`
+	User     string `protobuf:"bytes,6,opt,name=user" json:"user,omitempty"`
+	Domain   string `protobuf:"bytes,7,opt,name=domain" json:"domain,omitempty"`
+	// Age of the connection in seconds.
+	Age      int64 `protobuf:"varint,8,opt,name=age" json:"age,omitempty"`
+	Uplink   int64 `protobuf:"varint,9,opt,name=uplink" json:"uplink,omitempty"`
+	Downlink int64 `protobuf:"varint,10,opt,name=downlink" json:"downlink,omitempty"`
+}
+
+func (m *Connection) Reset()                    { *m = Connection{} }
+func (m *Connection) String() string            { return proto.CompactTextString(m) }
+func (*Connection) ProtoMessage()               {}
+func (*Connection) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{0} }
+
+type ListConnectionsRequest struct {
+}
+
+func (m *ListConnectionsRequest) Reset()                    { *m = ListConnectionsRequest{} }
+func (m *ListConnectionsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListConnectionsRequest) ProtoMessage()               {}
+func (*ListConnectionsRequest) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{1} }
+
+type ListConnectionsResponse struct {
+	Connection []*Connection `protobuf:"bytes,1,rep,name=connection" json:"connection,omitempty"`
+}
+
+func (m *ListConnectionsResponse) Reset()                    { *m = ListConnectionsResponse{} }
+func (m *ListConnectionsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListConnectionsResponse) ProtoMessage()               {}
+func (*ListConnectionsResponse) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{2} }
+
+func (m *ListConnectionsResponse) GetConnection() []*Connection {
+	if m != nil {
+		return m.Connection
+	}
+	return nil
+}
+
+type CloseConnectionRequest struct {
+	Id uint64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *CloseConnectionRequest) Reset()                    { *m = CloseConnectionRequest{} }
+func (m *CloseConnectionRequest) String() string            { return proto.CompactTextString(m) }
+func (*CloseConnectionRequest) ProtoMessage()               {}
+func (*CloseConnectionRequest) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{3} }
+
+type CloseConnectionResponse struct {
+}
+
+func (m *CloseConnectionResponse) Reset()                    { *m = CloseConnectionResponse{} }
+func (m *CloseConnectionResponse) String() string            { return proto.CompactTextString(m) }
+func (*CloseConnectionResponse) ProtoMessage()               {}
+func (*CloseConnectionResponse) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{4} }
+
+func init() {
+	proto.RegisterType((*Connection)(nil), "v2ray.core.app.api.Connection")
+	proto.RegisterType((*ListConnectionsRequest)(nil), "v2ray.core.app.api.ListConnectionsRequest")
+	proto.RegisterType((*ListConnectionsResponse)(nil), "v2ray.core.app.api.ListConnectionsResponse")
+	proto.RegisterType((*CloseConnectionRequest)(nil), "v2ray.core.app.api.CloseConnectionRequest")
+	proto.RegisterType((*CloseConnectionResponse)(nil), "v2ray.core.app.api.CloseConnectionResponse")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/api/connection.proto", fileDescriptor3) }
+
+var fileDescriptor3 = []byte{
+	// 363 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x52, 0x3d, 0x6f, 0xea, 0x30,
+	0x14, 0x7d, 0x21, 0x3c, 0x3e, 0x2e, 0xd2, 0xe3, 0xd5, 0x43, 0x70, 0x19, 0xaa, 0x28, 0x4b, 0xa3,
+	0x22, 0x25, 0x12, 0xdd, 0x3b, 0xc0, 0xda, 0xa1, 0x4a, 0xa7, 0x76, 0x33, 0x89, 0x55, 0x59, 0x05,
+	0x5f, 0xd7, 0x4e, 0xa8, 0xfa, 0xab, 0xfb, 0x0f, 0xaa, 0xca, 0x0e, 0x10, 0x04, 0x19, 0xd8, 0x7c,
+	0xee, 0x39, 0x47, 0xe7, 0xf8, 0xda, 0x70, 0xbb, 0x9d, 0x6b, 0xf6, 0x95, 0xe4, 0xb8, 0x49, 0x73,
+	0xd4, 0x3c, 0x65, 0x4a, 0xa5, 0x4c, 0x89, 0x34, 0x47, 0x29, 0x79, 0x5e, 0x0a, 0x94, 0x89, 0xd2,
+	0x58, 0x22, 0x21, 0x7b, 0xa1, 0xe6, 0x09, 0x53, 0x2a, 0x61, 0x4a, 0x44, 0x3f, 0x1e, 0xc0, 0xf2,
+	0x20, 0x24, 0xff, 0xa0, 0x23, 0x0a, 0xea, 0x85, 0x5e, 0xdc, 0xcd, 0x3a, 0xa2, 0x20, 0x01, 0xf4,
+	0x0c, 0x56, 0x3a, 0xe7, 0xb4, 0x13, 0x7a, 0xf1, 0x30, 0xdb, 0x21, 0x12, 0xc2, 0xa8, 0xe0, 0xa6,
+	0x14, 0x92, 0x59, 0x1b, 0xf5, 0x1d, 0x79, 0x3c, 0x22, 0x14, 0xfa, 0x42, 0xae, 0xb0, 0x92, 0x05,
+	0xed, 0x3a, 0x76, 0x0f, 0xc9, 0x14, 0x06, 0x58, 0x95, 0x35, 0xf5, 0xd7, 0x51, 0x07, 0x4c, 0x08,
+	0x74, 0x2b, 0xc3, 0x35, 0xed, 0xb9, 0xb9, 0x3b, 0xdb, 0x0e, 0x05, 0x6e, 0x98, 0x90, 0xb4, 0x5f,
+	0x77, 0xa8, 0x11, 0xf9, 0x0f, 0x3e, 0x7b, 0xe3, 0x74, 0x10, 0x7a, 0xb1, 0x9f, 0xd9, 0xa3, 0x55,
+	0x56, 0x6a, 0x2d, 0xe4, 0x3b, 0x1d, 0xba, 0xe1, 0x0e, 0xd9, 0xc4, 0x02, 0x3f, 0xa5, 0x63, 0xc0,
+	0x31, 0x07, 0x1c, 0x51, 0x08, 0x1e, 0x85, 0x29, 0x9b, 0x1d, 0x98, 0x8c, 0x7f, 0x54, 0xdc, 0x94,
+	0xd1, 0x0b, 0x4c, 0xce, 0x18, 0xa3, 0x50, 0x1a, 0x4e, 0x1e, 0x00, 0x9a, 0xed, 0x52, 0x2f, 0xf4,
+	0xe3, 0xd1, 0xfc, 0x26, 0x39, 0x5f, 0x6f, 0xd2, 0x98, 0xb3, 0x23, 0x47, 0x14, 0x43, 0xb0, 0x5c,
+	0xa3, 0xe1, 0x47, 0x74, 0x1d, 0x7a, 0xfa, 0x00, 0xd1, 0x35, 0x4c, 0xce, 0x94, 0x75, 0x89, 0xf9,
+	0xb7, 0x07, 0x57, 0xcd, 0xf8, 0x99, 0xeb, 0xad, 0xc8, 0x39, 0x59, 0xc3, 0xf8, 0xa4, 0x35, 0xb9,
+	0x6b, 0x6b, 0xd6, 0x7e, 0xe9, 0xe9, 0xec, 0x22, 0x6d, 0xdd, 0x20, 0xfa, 0x63, 0xd3, 0x4e, 0xea,
+	0xb5, 0xa7, 0xb5, 0xdf, 0x76, 0x3a, 0xbb, 0x48, 0xbb, 0x4f, 0x5b, 0x24, 0x10, 0xe4, 0xb8, 0x69,
+	0xf1, 0x2c, 0xc6, 0x8d, 0xfe, 0xc9, 0xfe, 0xf5, 0x57, 0x9f, 0x29, 0xb1, 0xea, 0xb9, 0x7f, 0x7f,
+	0xff, 0x3b, 0x00, 0x4e, 0x70, 0x74, 0x66, 0x22, 0x03, 0x00, 0x00,
+}
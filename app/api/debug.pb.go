@@ -0,0 +1,59 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/api/debug.proto
+// DO NOT EDIT!
+
+package api
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type SetDebugRequest struct {
+	// Whether the pprof and expvar endpoint is enabled.
+	Enabled bool `protobuf:"varint,1,opt,name=enabled" json:"enabled,omitempty"`
+}
+
+func (m *SetDebugRequest) Reset()                    { *m = SetDebugRequest{} }
+func (m *SetDebugRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetDebugRequest) ProtoMessage()               {}
+func (*SetDebugRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{0} }
+
+type SetDebugResponse struct {
+	Enabled bool `protobuf:"varint,1,opt,name=enabled" json:"enabled,omitempty"`
+	// Address that the endpoint listens on.
+	Address string `protobuf:"bytes,2,opt,name=address" json:"address,omitempty"`
+}
+
+func (m *SetDebugResponse) Reset()                    { *m = SetDebugResponse{} }
+func (m *SetDebugResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetDebugResponse) ProtoMessage()               {}
+func (*SetDebugResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{1} }
+
+func init() {
+	proto.RegisterType((*SetDebugRequest)(nil), "v2ray.core.app.api.SetDebugRequest")
+	proto.RegisterType((*SetDebugResponse)(nil), "v2ray.core.app.api.SetDebugResponse")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/api/debug.proto", fileDescriptor2) }
+
+var fileDescriptor2 = []byte{
+	// 201 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0xd0, 0x4f, 0x4b, 0x80, 0x40,
+	0x10, 0x05, 0xf0, 0x2c, 0x28, 0x1b, 0x82, 0x62, 0x0f, 0x21, 0x9d, 0x64, 0xeb, 0x60, 0x04, 0xbb,
+	0x60, 0xdf, 0x40, 0xa2, 0x73, 0xe8, 0x21, 0xe8, 0x36, 0xee, 0x0e, 0xb2, 0x90, 0xee, 0xb4, 0xab,
+	0x42, 0xdf, 0x3e, 0x34, 0x96, 0xa0, 0x7f, 0xc7, 0x61, 0x7e, 0x3c, 0x1e, 0x0f, 0xe4, 0x5a, 0x07,
+	0x7c, 0x57, 0xc6, 0x8f, 0xda, 0xf8, 0x40, 0x1a, 0x99, 0x35, 0xb2, 0xd3, 0x96, 0xfa, 0x65, 0x50,
+	0x1c, 0xfc, 0xec, 0x85, 0x48, 0x26, 0x90, 0x42, 0x66, 0x85, 0xec, 0xe4, 0x1d, 0x9c, 0x77, 0x34,
+	0x3f, 0x6c, 0xaa, 0xa5, 0xb7, 0x85, 0xe2, 0x2c, 0x0a, 0x38, 0xa1, 0x09, 0xfb, 0x57, 0xb2, 0x45,
+	0x56, 0x66, 0x55, 0xde, 0xa6, 0x53, 0x3e, 0xc2, 0xc5, 0x17, 0x8e, 0xec, 0xa7, 0x48, 0x7f, 0xeb,
+	0xed, 0x83, 0xd6, 0x06, 0x8a, 0xb1, 0x38, 0x2c, 0xb3, 0xea, 0xb4, 0x4d, 0x67, 0x3d, 0xc0, 0xd9,
+	0x1e, 0xd2, 0x51, 0x58, 0x9d, 0x21, 0xf1, 0x0c, 0x79, 0xca, 0x15, 0xd7, 0xea, 0x67, 0x4b, 0xf5,
+	0xad, 0xe2, 0xd5, 0xcd, 0xff, 0xe8, 0xb3, 0x9a, 0x3c, 0x68, 0x6e, 0xe1, 0xd2, 0xf8, 0xf1, 0x17,
+	0xdc, 0xc0, 0x4e, 0x9f, 0xb6, 0x5d, 0x5e, 0x8e, 0x90, 0x5d, 0x7f, 0xbc, 0x6f, 0x74, 0xff, 0x31,
+	0x00, 0x61, 0x2f, 0x8c, 0x61, 0x49, 0x01, 0x00, 0x00,
+}
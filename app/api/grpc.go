@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/proxy"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Status codes of gRPC, see https://github.com/grpc/grpc/blob/master/doc/statuscodes.md.
+const (
+	codeOK              = 0
+	codeUnknown         = 2
+	codeInvalidArgument = 3
+	codeNotFound        = 5
+	codeAlreadyExists   = 6
+	codeUnimplemented   = 12
+	codeInternal        = 13
+)
+
+const (
+	grpcContentType = "application/grpc"
+	// maxMessageSize is the max size of requests and responses.
+	maxMessageSize = 4 * 1024 * 1024
+)
+
+var (
+	ErrMessageTooLarge = errors.New("API: Message too large.")
+)
+
+// Status is the error returned by a gRPC call.
+type Status struct {
+	Code    int
+	Message string
+}
+
+func (this *Status) Error() string {
+	return fmt.Sprintf("API: Call failed with status %d: %s", this.Code, this.Message)
+}
+
+func statusOf(err error) *Status {
+	if status, ok := err.(*Status); ok {
+		return status
+	}
+	code := codeUnknown
+	switch err {
+	case common.ErrObjectNotFound, proxy.ErrUserNotFound:
+		code = codeNotFound
+	case common.ErrDuplicatedName:
+		code = codeAlreadyExists
+	case common.ErrBadConfiguration:
+		code = codeInvalidArgument
+	}
+	return &Status{Code: code, Message: err.Error()}
+}
+
+// method is a unary method of a gRPC service.
+type method struct {
+	newRequest func() proto.Message
+	call       func(request proto.Message) (proto.Message, error)
+}
+
+// grpcServer serves unary gRPC calls over HTTP/2. Compression is not supported.
+type grpcServer struct {
+	methods map[string]*method
+}
+
+func newGRPCServer() *grpcServer {
+	return &grpcServer{
+		methods: make(map[string]*method),
+	}
+}
+
+// register registers a method of a service, e.g. "v2ray.core.app.api.HandlerService" and "AddInbound".
+func (this *grpcServer) register(service string, name string, m *method) {
+	this.methods["/"+service+"/"+name] = m
+}
+
+// readMessage reads a length-prefixed message.
+func readMessage(reader io.Reader, message proto.Message) error {
+	var header [5]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return err
+	}
+	if header[0] != 0 {
+		return &Status{Code: codeUnimplemented, Message: "compression is not supported"}
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return ErrMessageTooLarge
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return err
+	}
+	return proto.Unmarshal(payload, message)
+}
+
+// encodeMessage returns a length-prefixed message.
+func encodeMessage(message proto.Message) ([]byte, error) {
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+	frame := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	copy(frame[5:], payload)
+	return frame, nil
+}
+
+// encodeStatusMessage percent-encodes a status message for the grpc-message header.
+func encodeStatusMessage(message string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		} else {
+			encoded.WriteByte(c)
+		}
+	}
+	return encoded.String()
+}
+
+func decodeStatusMessage(message string) string {
+	var decoded strings.Builder
+	for i := 0; i < len(message); i++ {
+		if message[i] == '%' && i+2 < len(message) {
+			if c, err := strconv.ParseUint(message[i+1:i+3], 16, 8); err == nil {
+				decoded.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		decoded.WriteByte(message[i])
+	}
+	return decoded.String()
+}
+
+func (this *grpcServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" || !strings.HasPrefix(request.Header.Get("Content-Type"), grpcContentType) {
+		writer.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	writer.Header().Set("Content-Type", grpcContentType)
+	writer.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	response, err := this.call(request)
+	var frame []byte
+	if err == nil {
+		frame, err = encodeMessage(response)
+	}
+	writer.WriteHeader(http.StatusOK)
+	if err != nil {
+		status := statusOf(err)
+		log.Warning("API: ", request.URL.Path, " failed: ", status.Message)
+		writer.Header().Set("Grpc-Status", strconv.Itoa(status.Code))
+		writer.Header().Set("Grpc-Message", encodeStatusMessage(status.Message))
+		return
+	}
+	writer.Write(frame)
+	writer.Header().Set("Grpc-Status", strconv.Itoa(codeOK))
+}
+
+func (this *grpcServer) call(request *http.Request) (proto.Message, error) {
+	m, found := this.methods[request.URL.Path]
+	if !found {
+		return nil, &Status{Code: codeUnimplemented, Message: "unknown method " + request.URL.Path}
+	}
+	message := m.newRequest()
+	if err := readMessage(request.Body, message); err != nil {
+		if _, ok := err.(*Status); ok {
+			return nil, err
+		}
+		return nil, &Status{Code: codeInternal, Message: "invalid request: " + err.Error()}
+	}
+	// Drains the request, which should have one message only.
+	io.Copy(ioutil.Discard, request.Body)
+	return m.call(message)
+}
@@ -0,0 +1,212 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/api/handler.proto
+// DO NOT EDIT!
+
+/*
+Package api is a generated protocol buffer package.
+
+It is generated from these files:
+
+	v2ray.com/core/app/api/handler.proto
+	v2ray.com/core/app/api/stats.proto
+	v2ray.com/core/app/api/debug.proto
+	v2ray.com/core/app/api/connection.proto
+	v2ray.com/core/app/api/version.proto
+
+It has these top-level messages:
+
+	AddInboundRequest
+	AddInboundResponse
+	RemoveInboundRequest
+	RemoveInboundResponse
+	AddOutboundRequest
+	AddOutboundResponse
+	RemoveOutboundRequest
+	RemoveOutboundResponse
+	AlterInboundRequest
+	AlterInboundResponse
+	Stat
+	GetStatsRequest
+	GetStatsResponse
+	QueryStatsRequest
+	QueryStatsResponse
+	SetDebugRequest
+	SetDebugResponse
+	Connection
+	ListConnectionsRequest
+	ListConnectionsResponse
+	CloseConnectionRequest
+	CloseConnectionResponse
+	GetVersionRequest
+	GetVersionResponse
+*/
+package api
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Inbounds and outbounds are in the JSON format of inbound and outbound detours in config files.
+type AddInboundRequest struct {
+	Inbound []byte `protobuf:"bytes,1,opt,name=inbound,proto3" json:"inbound,omitempty"`
+}
+
+func (m *AddInboundRequest) Reset()                    { *m = AddInboundRequest{} }
+func (m *AddInboundRequest) String() string            { return proto.CompactTextString(m) }
+func (*AddInboundRequest) ProtoMessage()               {}
+func (*AddInboundRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type AddInboundResponse struct {
+}
+
+func (m *AddInboundResponse) Reset()                    { *m = AddInboundResponse{} }
+func (m *AddInboundResponse) String() string            { return proto.CompactTextString(m) }
+func (*AddInboundResponse) ProtoMessage()               {}
+func (*AddInboundResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type RemoveInboundRequest struct {
+	Tag string `protobuf:"bytes,1,opt,name=tag" json:"tag,omitempty"`
+}
+
+func (m *RemoveInboundRequest) Reset()                    { *m = RemoveInboundRequest{} }
+func (m *RemoveInboundRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemoveInboundRequest) ProtoMessage()               {}
+func (*RemoveInboundRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *RemoveInboundRequest) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+type RemoveInboundResponse struct {
+}
+
+func (m *RemoveInboundResponse) Reset()                    { *m = RemoveInboundResponse{} }
+func (m *RemoveInboundResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemoveInboundResponse) ProtoMessage()               {}
+func (*RemoveInboundResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type AddOutboundRequest struct {
+	Outbound []byte `protobuf:"bytes,1,opt,name=outbound,proto3" json:"outbound,omitempty"`
+}
+
+func (m *AddOutboundRequest) Reset()                    { *m = AddOutboundRequest{} }
+func (m *AddOutboundRequest) String() string            { return proto.CompactTextString(m) }
+func (*AddOutboundRequest) ProtoMessage()               {}
+func (*AddOutboundRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type AddOutboundResponse struct {
+}
+
+func (m *AddOutboundResponse) Reset()                    { *m = AddOutboundResponse{} }
+func (m *AddOutboundResponse) String() string            { return proto.CompactTextString(m) }
+func (*AddOutboundResponse) ProtoMessage()               {}
+func (*AddOutboundResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type RemoveOutboundRequest struct {
+	Tag string `protobuf:"bytes,1,opt,name=tag" json:"tag,omitempty"`
+}
+
+func (m *RemoveOutboundRequest) Reset()                    { *m = RemoveOutboundRequest{} }
+func (m *RemoveOutboundRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemoveOutboundRequest) ProtoMessage()               {}
+func (*RemoveOutboundRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *RemoveOutboundRequest) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+type RemoveOutboundResponse struct {
+}
+
+func (m *RemoveOutboundResponse) Reset()                    { *m = RemoveOutboundResponse{} }
+func (m *RemoveOutboundResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemoveOutboundResponse) ProtoMessage()               {}
+func (*RemoveOutboundResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+// Users are in the JSON format of users in inbound settings, e.g. "clients" of VMess.
+type AlterInboundRequest struct {
+	Tag          string   `protobuf:"bytes,1,opt,name=tag" json:"tag,omitempty"`
+	AddUsers     [][]byte `protobuf:"bytes,2,rep,name=add_users,json=addUsers,proto3" json:"add_users,omitempty"`
+	RemoveEmails []string `protobuf:"bytes,3,rep,name=remove_emails,json=removeEmails" json:"remove_emails,omitempty"`
+}
+
+func (m *AlterInboundRequest) Reset()                    { *m = AlterInboundRequest{} }
+func (m *AlterInboundRequest) String() string            { return proto.CompactTextString(m) }
+func (*AlterInboundRequest) ProtoMessage()               {}
+func (*AlterInboundRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *AlterInboundRequest) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+type AlterInboundResponse struct {
+}
+
+func (m *AlterInboundResponse) Reset()                    { *m = AlterInboundResponse{} }
+func (m *AlterInboundResponse) String() string            { return proto.CompactTextString(m) }
+func (*AlterInboundResponse) ProtoMessage()               {}
+func (*AlterInboundResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func init() {
+	proto.RegisterType((*AddInboundRequest)(nil), "v2ray.core.app.api.AddInboundRequest")
+	proto.RegisterType((*AddInboundResponse)(nil), "v2ray.core.app.api.AddInboundResponse")
+	proto.RegisterType((*RemoveInboundRequest)(nil), "v2ray.core.app.api.RemoveInboundRequest")
+	proto.RegisterType((*RemoveInboundResponse)(nil), "v2ray.core.app.api.RemoveInboundResponse")
+	proto.RegisterType((*AddOutboundRequest)(nil), "v2ray.core.app.api.AddOutboundRequest")
+	proto.RegisterType((*AddOutboundResponse)(nil), "v2ray.core.app.api.AddOutboundResponse")
+	proto.RegisterType((*RemoveOutboundRequest)(nil), "v2ray.core.app.api.RemoveOutboundRequest")
+	proto.RegisterType((*RemoveOutboundResponse)(nil), "v2ray.core.app.api.RemoveOutboundResponse")
+	proto.RegisterType((*AlterInboundRequest)(nil), "v2ray.core.app.api.AlterInboundRequest")
+	proto.RegisterType((*AlterInboundResponse)(nil), "v2ray.core.app.api.AlterInboundResponse")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/api/handler.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 387 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0xdb, 0x4e, 0xdb, 0x40,
+	0x14, 0x6c, 0xea, 0xaa, 0x6d, 0x4e, 0x9d, 0xa8, 0xdd, 0x5c, 0x6a, 0xb9, 0x2f, 0x91, 0xdb, 0x82,
+	0x03, 0xc2, 0x46, 0xe1, 0x0b, 0x12, 0x09, 0x09, 0x9e, 0x40, 0x46, 0xbc, 0x20, 0xa1, 0xb0, 0xf1,
+	0x2e, 0xb0, 0x52, 0xec, 0x5d, 0xd6, 0x76, 0x24, 0x3e, 0x81, 0xbf, 0x46, 0xbe, 0x12, 0x5f, 0xc0,
+	0x79, 0xf3, 0x19, 0xcd, 0x99, 0x99, 0xf5, 0xec, 0xc2, 0xbf, 0xcd, 0x4c, 0xe2, 0x67, 0xcb, 0xe5,
+	0x9e, 0xed, 0x72, 0x49, 0x6d, 0x2c, 0x84, 0x8d, 0x05, 0xb3, 0x1f, 0xb1, 0x4f, 0xd6, 0x54, 0x5a,
+	0x42, 0xf2, 0x90, 0x23, 0x94, 0xb3, 0x24, 0xb5, 0xb0, 0x10, 0x16, 0x16, 0xcc, 0x38, 0x82, 0x5f,
+	0x73, 0x42, 0xce, 0xfd, 0x15, 0x8f, 0x7c, 0xe2, 0xd0, 0xa7, 0x88, 0x06, 0x21, 0xd2, 0xe0, 0x1b,
+	0x4b, 0x11, 0xad, 0x33, 0xe9, 0x98, 0xaa, 0x93, 0x8f, 0xc6, 0x10, 0xd0, 0x36, 0x3d, 0x10, 0xdc,
+	0x0f, 0xa8, 0x61, 0xc2, 0xd0, 0xa1, 0x1e, 0xdf, 0xd0, 0x8a, 0xce, 0x4f, 0x50, 0x42, 0xfc, 0x90,
+	0x68, 0x74, 0x9d, 0xf8, 0xd3, 0xf8, 0x0d, 0xa3, 0x0a, 0x33, 0x93, 0x38, 0x4e, 0x84, 0x2f, 0xa2,
+	0xb0, 0x24, 0xa0, 0xc3, 0x77, 0x9e, 0x41, 0x59, 0x92, 0x62, 0x36, 0x46, 0x30, 0x28, 0x6d, 0x64,
+	0x42, 0xd3, 0xdc, 0xa1, 0xaa, 0x55, 0x0f, 0xa3, 0xc1, 0xb8, 0x4a, 0xcd, 0x44, 0x18, 0x0c, 0xe6,
+	0xeb, 0x90, 0xca, 0xb6, 0xf3, 0xa0, 0x3f, 0xd0, 0xc5, 0x84, 0x2c, 0xa3, 0x80, 0xca, 0x40, 0xfb,
+	0x3c, 0x51, 0xe2, 0x84, 0x98, 0x90, 0xeb, 0x78, 0x46, 0x7f, 0xa1, 0x27, 0x13, 0xfd, 0x25, 0xf5,
+	0x30, 0x5b, 0x07, 0x9a, 0x32, 0x51, 0xcc, 0xae, 0xa3, 0xa6, 0xe0, 0x69, 0x82, 0x19, 0x63, 0x18,
+	0x96, 0xad, 0xd2, 0x08, 0xb3, 0x97, 0x2f, 0xd0, 0x3f, 0x4b, 0xeb, 0xbb, 0xa2, 0x72, 0xc3, 0x5c,
+	0x8a, 0x6e, 0x01, 0xde, 0x7e, 0x3e, 0xfa, 0x6f, 0xd5, 0xeb, 0xb4, 0x6a, 0x5d, 0xea, 0x7b, 0x6d,
+	0xb4, 0xec, 0xc8, 0x9f, 0xd0, 0x3d, 0xf4, 0x4a, 0xdd, 0x20, 0xb3, 0x69, 0xb5, 0xa9, 0x68, 0x7d,
+	0xba, 0x03, 0xb3, 0xf0, 0xb9, 0x83, 0x1f, 0x5b, 0xc5, 0xa1, 0xf7, 0x02, 0x56, 0xfa, 0xd3, 0xf7,
+	0x5b, 0x79, 0x85, 0x03, 0x83, 0x7e, 0xb9, 0x58, 0xf4, 0x41, 0xc0, 0xaa, 0xcf, 0xc1, 0x2e, 0xd4,
+	0xc2, 0xca, 0x05, 0x75, 0xbb, 0x3e, 0xd4, 0x9c, 0xb2, 0x7e, 0x97, 0x74, 0xb3, 0x9d, 0x98, 0x9b,
+	0x2c, 0x0e, 0x61, 0xec, 0x72, 0xaf, 0x61, 0x61, 0xa1, 0x66, 0x57, 0xe4, 0x32, 0x7e, 0xe0, 0x37,
+	0x0a, 0x16, 0x6c, 0xf5, 0x35, 0x79, 0xec, 0x27, 0xaf, 0x03, 0x00, 0x4c, 0x51, 0xfe, 0x78, 0x14,
+	0x04, 0x00, 0x00,
+}
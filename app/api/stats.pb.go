@@ -0,0 +1,110 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/api/stats.proto
+// DO NOT EDIT!
+
+package api
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type Stat struct {
+	Name  string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Value int64  `protobuf:"varint,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *Stat) Reset()                    { *m = Stat{} }
+func (m *Stat) String() string            { return proto.CompactTextString(m) }
+func (*Stat) ProtoMessage()               {}
+func (*Stat) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
+
+type GetStatsRequest struct {
+	// Name of the counter, e.g. "user>>>love@v2ray.com>>>traffic>>>uplink".
+	Name   string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Reset_ bool   `protobuf:"varint,2,opt,name=reset" json:"reset,omitempty"`
+}
+
+func (m *GetStatsRequest) Reset()                    { *m = GetStatsRequest{} }
+func (m *GetStatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetStatsRequest) ProtoMessage()               {}
+func (*GetStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+
+type GetStatsResponse struct {
+	Stat *Stat `protobuf:"bytes,1,opt,name=stat" json:"stat,omitempty"`
+}
+
+func (m *GetStatsResponse) Reset()                    { *m = GetStatsResponse{} }
+func (m *GetStatsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetStatsResponse) ProtoMessage()               {}
+func (*GetStatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
+
+func (m *GetStatsResponse) GetStat() *Stat {
+	if m != nil {
+		return m.Stat
+	}
+	return nil
+}
+
+type QueryStatsRequest struct {
+	// Counters whose names contain the pattern are returned. All counters are returned if it's empty.
+	Pattern string `protobuf:"bytes,1,opt,name=pattern" json:"pattern,omitempty"`
+	Reset_  bool   `protobuf:"varint,2,opt,name=reset" json:"reset,omitempty"`
+}
+
+func (m *QueryStatsRequest) Reset()                    { *m = QueryStatsRequest{} }
+func (m *QueryStatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*QueryStatsRequest) ProtoMessage()               {}
+func (*QueryStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
+
+type QueryStatsResponse struct {
+	Stat []*Stat `protobuf:"bytes,1,rep,name=stat" json:"stat,omitempty"`
+}
+
+func (m *QueryStatsResponse) Reset()                    { *m = QueryStatsResponse{} }
+func (m *QueryStatsResponse) String() string            { return proto.CompactTextString(m) }
+func (*QueryStatsResponse) ProtoMessage()               {}
+func (*QueryStatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
+
+func (m *QueryStatsResponse) GetStat() []*Stat {
+	if m != nil {
+		return m.Stat
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Stat)(nil), "v2ray.core.app.api.Stat")
+	proto.RegisterType((*GetStatsRequest)(nil), "v2ray.core.app.api.GetStatsRequest")
+	proto.RegisterType((*GetStatsResponse)(nil), "v2ray.core.app.api.GetStatsResponse")
+	proto.RegisterType((*QueryStatsRequest)(nil), "v2ray.core.app.api.QueryStatsRequest")
+	proto.RegisterType((*QueryStatsResponse)(nil), "v2ray.core.app.api.QueryStatsResponse")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/api/stats.proto", fileDescriptor1) }
+
+var fileDescriptor1 = []byte{
+	// 283 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x92, 0x4d, 0x4b, 0xc3, 0x40,
+	0x10, 0x86, 0x8d, 0xad, 0x5a, 0x47, 0x41, 0x1d, 0x44, 0x42, 0x4f, 0x65, 0xfd, 0xa0, 0x82, 0x6c,
+	0xa4, 0x1e, 0xbd, 0x48, 0x3c, 0x78, 0xd5, 0xf4, 0x20, 0x08, 0x1e, 0xc6, 0x30, 0x87, 0x80, 0xc9,
+	0xae, 0xbb, 0x9b, 0x40, 0xff, 0xa0, 0xbf, 0x4b, 0xb2, 0x31, 0x54, 0x4d, 0x6a, 0x6f, 0xfb, 0xc2,
+	0xfb, 0x0c, 0xcf, 0x0e, 0x03, 0xa2, 0x9a, 0x19, 0x5a, 0xc8, 0x54, 0xe5, 0x51, 0xaa, 0x0c, 0x47,
+	0xa4, 0x75, 0x44, 0x3a, 0x8b, 0xac, 0x23, 0x67, 0xa5, 0x36, 0xca, 0x29, 0xc4, 0xb6, 0x63, 0x58,
+	0x92, 0xd6, 0x92, 0x74, 0x26, 0xae, 0x61, 0x38, 0x77, 0xe4, 0x10, 0x61, 0x58, 0x50, 0xce, 0x61,
+	0x30, 0x09, 0xa6, 0xbb, 0x89, 0x7f, 0xe3, 0x31, 0x6c, 0x55, 0xf4, 0x5e, 0x72, 0xb8, 0x39, 0x09,
+	0xa6, 0x83, 0xa4, 0x09, 0xe2, 0x16, 0x0e, 0x1e, 0xd8, 0xd5, 0x90, 0x4d, 0xf8, 0xa3, 0x64, 0xbb,
+	0x12, 0x36, 0x6c, 0xd9, 0x79, 0x78, 0x94, 0x34, 0x41, 0xdc, 0xc1, 0xe1, 0x12, 0xb6, 0x5a, 0x15,
+	0x96, 0xf1, 0x0a, 0x86, 0xb5, 0xa5, 0xa7, 0xf7, 0x66, 0xa1, 0xec, 0x5a, 0xca, 0x1a, 0x48, 0x7c,
+	0x4b, 0xdc, 0xc3, 0xd1, 0x53, 0xc9, 0x66, 0xf1, 0x4b, 0x20, 0x84, 0x1d, 0x4d, 0xce, 0xb1, 0x29,
+	0xbe, 0x1d, 0xda, 0xb8, 0x42, 0x23, 0x06, 0xfc, 0x39, 0xa4, 0x23, 0x32, 0x58, 0x2f, 0x32, 0xfb,
+	0x0c, 0x60, 0xdf, 0xf3, 0x73, 0x36, 0x55, 0x96, 0x32, 0x3e, 0xc3, 0xa8, 0xfd, 0x1b, 0x9e, 0xf6,
+	0xc1, 0x7f, 0xd6, 0x36, 0x3e, 0xfb, 0xbf, 0xd4, 0x58, 0x89, 0x0d, 0x7c, 0x05, 0x58, 0xda, 0xe2,
+	0x79, 0x1f, 0xd5, 0x59, 0xc9, 0xf8, 0x62, 0x5d, 0xad, 0x1d, 0x1f, 0x5f, 0xc2, 0x49, 0xaa, 0xf2,
+	0x9e, 0x7a, 0x0c, 0xbe, 0xfa, 0x58, 0x1f, 0xcf, 0xcb, 0x80, 0x74, 0xf6, 0xb6, 0xed, 0x0f, 0xe9,
+	0xe6, 0x6b, 0x00, 0x18, 0x7f, 0xc4, 0x56, 0x6e, 0x02, 0x00, 0x00,
+}
@@ -0,0 +1,78 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/api/version.proto
+// DO NOT EDIT!
+
+package api
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type GetVersionRequest struct {
+}
+
+func (m *GetVersionRequest) Reset()                    { *m = GetVersionRequest{} }
+func (m *GetVersionRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetVersionRequest) ProtoMessage()               {}
+func (*GetVersionRequest) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{0} }
+
+type GetVersionResponse struct {
+	Version   string `protobuf:"bytes,1,opt,name=version" json:"version,omitempty"`
+	Codename  string `protobuf:"bytes,2,opt,name=codename" json:"codename,omitempty"`
+	Build     string `protobuf:"bytes,3,opt,name=build" json:"build,omitempty"`
+	GoVersion string `protobuf:"bytes,4,opt,name=go_version,json=goVersion" json:"go_version,omitempty"`
+	Os        string `protobuf:"bytes,5,opt,name=os" json:"os,omitempty"`
+	Arch      string `protobuf:"bytes,6,opt,name=arch" json:"arch,omitempty"`
+	// Build tags that change the features, e.g. "json".
+	Tags []string `protobuf:"bytes,7,rep,name=tags" json:"tags,omitempty"`
+	// Protocols and config formats compiled in, e.g. "inbound:vmess".
+	Features []string `protobuf:"bytes,8,rep,name=features" json:"features,omitempty"`
+	// Time that the server started, in seconds since Unix epoch.
+	StartTime int64 `protobuf:"varint,9,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	// Uptime in seconds.
+	Uptime int64 `protobuf:"varint,10,opt,name=uptime" json:"uptime,omitempty"`
+	// SHA-256 in hex of the running config.
+	ConfigHash string `protobuf:"bytes,11,opt,name=config_hash,json=configHash" json:"config_hash,omitempty"`
+}
+
+func (m *GetVersionResponse) Reset()                    { *m = GetVersionResponse{} }
+func (m *GetVersionResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetVersionResponse) ProtoMessage()               {}
+func (*GetVersionResponse) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{1} }
+
+func init() {
+	proto.RegisterType((*GetVersionRequest)(nil), "v2ray.core.app.api.GetVersionRequest")
+	proto.RegisterType((*GetVersionResponse)(nil), "v2ray.core.app.api.GetVersionResponse")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/api/version.proto", fileDescriptor4) }
+
+var fileDescriptor4 = []byte{
+	// 324 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x91, 0xdf, 0x4a, 0xf3, 0x40,
+	0x10, 0xc5, 0xbf, 0x26, 0xfd, 0x97, 0xe9, 0x47, 0xc1, 0x55, 0xca, 0x52, 0x10, 0x4b, 0x51, 0x29,
+	0x08, 0x09, 0xd4, 0x37, 0xe8, 0x8d, 0x5e, 0x4a, 0x15, 0x2f, 0x04, 0x29, 0xdb, 0xed, 0x34, 0x59,
+	0x30, 0x99, 0x75, 0x77, 0x53, 0xf0, 0x99, 0x7c, 0x49, 0xc9, 0x26, 0xad, 0x42, 0x05, 0xef, 0xe6,
+	0xfc, 0xce, 0x61, 0x66, 0x98, 0x81, 0xcb, 0xdd, 0xdc, 0x88, 0x8f, 0x58, 0x52, 0x9e, 0x48, 0x32,
+	0x98, 0x08, 0xad, 0x13, 0xa1, 0x55, 0xb2, 0x43, 0x63, 0x15, 0x15, 0xb1, 0x36, 0xe4, 0x88, 0xb1,
+	0x7d, 0xca, 0x60, 0x2c, 0xb4, 0x8e, 0x85, 0x56, 0xd3, 0x53, 0x38, 0xb9, 0x43, 0xf7, 0x5c, 0xe7,
+	0x96, 0xf8, 0x5e, 0xa2, 0x75, 0xd3, 0xcf, 0x00, 0xd8, 0x4f, 0x6a, 0x35, 0x15, 0x16, 0x19, 0x87,
+	0x5e, 0xd3, 0x90, 0xb7, 0x26, 0xad, 0x59, 0xb4, 0xdc, 0x4b, 0x36, 0x86, 0xbe, 0xa4, 0x0d, 0x16,
+	0x22, 0x47, 0x1e, 0x78, 0xeb, 0xa0, 0xd9, 0x19, 0x74, 0xd6, 0xa5, 0x7a, 0xdb, 0xf0, 0xd0, 0x1b,
+	0xb5, 0x60, 0xe7, 0x00, 0x29, 0xad, 0xf6, 0xed, 0xda, 0xde, 0x8a, 0x52, 0x6a, 0x46, 0xb2, 0x21,
+	0x04, 0x64, 0x79, 0xc7, 0xe3, 0x80, 0x2c, 0x63, 0xd0, 0x16, 0x46, 0x66, 0xbc, 0xeb, 0x89, 0xaf,
+	0x2b, 0xe6, 0x44, 0x6a, 0x79, 0x6f, 0x12, 0x56, 0xac, 0xaa, 0xab, 0x45, 0xb6, 0x28, 0x5c, 0x69,
+	0xd0, 0xf2, 0xbe, 0xe7, 0x07, 0x5d, 0x8d, 0xb4, 0x4e, 0x18, 0xb7, 0x72, 0x2a, 0x47, 0x1e, 0x4d,
+	0x5a, 0xb3, 0x70, 0x19, 0x79, 0xf2, 0xa4, 0x72, 0x64, 0x23, 0xe8, 0x96, 0xda, 0x5b, 0xe0, 0xad,
+	0x46, 0xb1, 0x0b, 0x18, 0x48, 0x2a, 0xb6, 0x2a, 0x5d, 0x65, 0xc2, 0x66, 0x7c, 0xe0, 0x37, 0x80,
+	0x1a, 0xdd, 0x0b, 0x9b, 0xcd, 0x09, 0x86, 0xcd, 0xda, 0x8f, 0x68, 0x76, 0x4a, 0x22, 0x7b, 0x05,
+	0xf8, 0x3e, 0x1f, 0xbb, 0x8a, 0x8f, 0xef, 0x1e, 0x1f, 0x1d, 0x7d, 0x7c, 0xfd, 0x57, 0xac, 0xfe,
+	0xc2, 0xf4, 0xdf, 0xe2, 0x06, 0x46, 0x92, 0xf2, 0x5f, 0xe2, 0x8b, 0xff, 0x4d, 0xf8, 0xa1, 0xfa,
+	0xf7, 0x4b, 0x28, 0xb4, 0x5a, 0x77, 0xfd, 0xef, 0x6f, 0xbf, 0x06, 0x00, 0x98, 0x7a, 0x1d, 0x30,
+	0x23, 0x02, 0x00, 0x00,
+}
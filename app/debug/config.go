@@ -0,0 +1,12 @@
+package debug
+
+import (
+	v2net "v2ray.com/core/common/net"
+)
+
+type Config struct {
+	// Port is the port on localhost that the debug endpoint listens on.
+	Port v2net.Port
+	// Enabled starts the debug endpoint on startup. Otherwise it is started by the management API.
+	Enabled bool
+}
@@ -0,0 +1,27 @@
+// +build json
+
+package debug
+
+import (
+	"errors"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Port    v2net.Port `json:"port"`
+		Enabled bool       `json:"enabled"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Debug: Failed to parse config: ", err)
+	}
+	if jsonConfig.Port == 0 {
+		return errors.New("Debug: Port is not specified.")
+	}
+	this.Port = jsonConfig.Port
+	this.Enabled = jsonConfig.Enabled
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package debug serves pprof profiles and expvar-style variables of a running V2Ray instance on localhost, so that
+// profiles can be taken during incidents.
+package debug
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	APP_ID = app.ID(10)
+)
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "debug",
+		Config: (*Config)(nil),
+		After:  []app.ID{proxyman.APP_ID_INBOUND_MANAGER},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewServer(config.(*Config)), nil
+		},
+	})
+}
+
+// Server is the debug endpoint. It only listens on localhost, and it can be enabled and disabled at runtime.
+type Server struct {
+	sync.Mutex
+	config   *Config
+	handler  http.Handler
+	server   *http.Server
+	listener net.Listener
+}
+
+func NewServer(config *Config) *Server {
+	return &Server{
+		config:  config,
+		handler: newHandler(),
+	}
+}
+
+// Address returns the address that the endpoint listens on.
+func (this *Server) Address() string {
+	return net.JoinHostPort(v2net.LocalHostIP.String(), this.config.Port.String())
+}
+
+// Start starts the endpoint if it is enabled in config.
+func (this *Server) Start() error {
+	if !this.config.Enabled {
+		return nil
+	}
+	return this.SetEnabled(true)
+}
+
+// Enabled returns true if the endpoint is serving.
+func (this *Server) Enabled() bool {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.listener != nil
+}
+
+// SetEnabled starts or stops the endpoint. It does nothing if the endpoint is already in the given state.
+func (this *Server) SetEnabled(enabled bool) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if enabled == (this.listener != nil) {
+		return nil
+	}
+	if !enabled {
+		this.server.Close()
+		this.server = nil
+		this.listener = nil
+		log.Info("Debug: Stopped.")
+		return nil
+	}
+	address := this.Address()
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Error("Debug: Failed to listen on ", address, ": ", err)
+		return err
+	}
+	// A server can't be served again after closed.
+	this.server = &http.Server{
+		Handler: this.handler,
+	}
+	this.listener = listener
+	log.Warning("Debug: Serving pprof and expvar on ", address)
+	go this.server.Serve(listener)
+	return nil
+}
+
+func (this *Server) Release() {
+	this.SetEnabled(false)
+}
@@ -0,0 +1,59 @@
+package debug_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	. "v2ray.com/core/app/debug"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestDebugServer(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	server := NewServer(&Config{Port: v2net.Port(port)})
+	assert.Error(server.Start()).IsNil()
+	assert.Bool(server.Enabled()).IsFalse()
+
+	assert.Error(server.SetEnabled(true)).IsNil()
+	assert.Bool(server.Enabled()).IsTrue()
+	response, err := http.Get("http://" + server.Address() + "/debug/vars")
+	assert.Error(err).IsNil()
+	content, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Error(err).IsNil()
+	assert.String(string(content)).Contains("\"bufferPools\"")
+
+	response, err = http.Get("http://" + server.Address() + "/debug/pprof/")
+	assert.Error(err).IsNil()
+	content, err = ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Error(err).IsNil()
+	assert.String(string(content)).Contains("goroutine")
+
+	assert.Error(server.SetEnabled(false)).IsNil()
+	assert.Bool(server.Enabled()).IsFalse()
+	_, err = http.Get("http://" + server.Address() + "/debug/vars")
+	assert.Error(err).IsNotNil()
+
+	server.Release()
+}
+
+func TestNoDefaultServeMuxHandlers(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, path := range []string{"/debug/pprof/", "/debug/vars"} {
+		request, err := http.NewRequest("GET", "http://localhost"+path, nil)
+		assert.Error(err).IsNil()
+		_, pattern := http.DefaultServeMux.Handler(request)
+		assert.String(pattern).Equals("")
+	}
+}
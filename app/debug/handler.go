@@ -0,0 +1,119 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+)
+
+// The handlers below replace net/http/pprof and expvar, which register themselves on http.DefaultServeMux when
+// imported. Other listeners, like WebSocket, may serve a mux of their own, so the debug handlers must only be
+// reachable from the mux of Server.
+
+const (
+	maxProfileSeconds = 60
+)
+
+func newHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", profileIndex)
+	mux.HandleFunc("/debug/pprof/cmdline", profileCmdline)
+	mux.HandleFunc("/debug/pprof/profile", profileCPU)
+	mux.HandleFunc("/debug/pprof/trace", profileTrace)
+	mux.HandleFunc("/debug/vars", serveVars)
+	return mux
+}
+
+// profileSeconds returns the duration in "seconds" of the request, or the given default.
+func profileSeconds(r *http.Request, defaultSeconds int) time.Duration {
+	seconds, err := strconv.Atoi(r.FormValue("seconds"))
+	if err != nil || seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	if seconds > maxProfileSeconds {
+		seconds = maxProfileSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func profileIndex(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/")
+	if len(name) > 0 {
+		profile := pprof.Lookup(name)
+		if profile == nil {
+			http.Error(w, "Unknown profile: "+name, http.StatusNotFound)
+			return
+		}
+		level, _ := strconv.Atoi(r.FormValue("debug"))
+		if level > 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		profile.WriteTo(w, level)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, profile := range pprof.Profiles() {
+		fmt.Fprintf(w, "%d\t%s\n", profile.Count(), profile.Name())
+	}
+	fmt.Fprintln(w, "-\tprofile")
+	fmt.Fprintln(w, "-\ttrace")
+}
+
+func profileCmdline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, strings.Join(os.Args, "\x00"))
+}
+
+func profileCPU(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if err := pprof.StartCPUProfile(w); err != nil {
+		http.Error(w, "Failed to start CPU profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wait(r, profileSeconds(r, 30))
+	pprof.StopCPUProfile()
+}
+
+func profileTrace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if err := trace.Start(w); err != nil {
+		http.Error(w, "Failed to start trace: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wait(r, profileSeconds(r, 1))
+	trace.Stop()
+}
+
+// wait blocks for the given duration, or until the client goes away.
+func wait(r *http.Request, duration time.Duration) {
+	select {
+	case <-time.After(duration):
+	case <-r.Context().Done():
+	}
+}
+
+// serveVars writes the variables in the JSON format of expvar.
+func serveVars(w http.ResponseWriter, r *http.Request) {
+	memStats := new(runtime.MemStats)
+	runtime.ReadMemStats(memStats)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bufferPools": alloc.GetPoolStats(),
+		"cmdline":     os.Args,
+		"goroutines":  runtime.NumGoroutine(),
+		"memstats":    memStats,
+	})
+}
@@ -1,7 +1,11 @@
 package dispatcher
 
 import (
+	"context"
+	"time"
+
 	"v2ray.com/core/app"
+	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/ray"
 )
@@ -12,5 +16,29 @@ const (
 
 // PacketDispatcher dispatch a packet and possibly further network payload to its destination.
 type PacketDispatcher interface {
-	DispatchToOutbound(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay
+	// DispatchToOutbound sends the session through an outbound. Ctx is the context of the session in its inbound, and
+	// the outbound stops once it is done.
+	DispatchToOutbound(ctx context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay
+}
+
+// ConnectionInfo describes a live connection through the dispatcher.
+type ConnectionInfo struct {
+	ID          uint64
+	Source      v2net.Destination
+	Destination v2net.Destination
+	InboundTag  string
+	// OutboundTag is empty before the connection is routed, or if it is routed to the global default outbound.
+	OutboundTag   string
+	User          string
+	SniffedDomain string
+	Start         time.Time
+	Uplink        int64
+	Downlink      int64
+}
+
+// ConnectionManager lists and closes the live connections through the dispatcher.
+type ConnectionManager interface {
+	ListConnections() []*ConnectionInfo
+	// CloseConnection closes the connection of the given ID, and returns false if it is not found.
+	CloseConnection(id uint64) bool
 }
@@ -0,0 +1,258 @@
+package impl
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/app/tracing"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+// connectionTracker keeps the live connections through the dispatcher.
+type connectionTracker struct {
+	sync.Mutex
+	lastID      uint64
+	connections map[uint64]*connection
+	// logger records accesses of finished connections. Nil for the default logger.
+	logger *log.Logger
+}
+
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{
+		connections: make(map[uint64]*connection),
+	}
+}
+
+// open tracks a new connection on the given ray, and returns the ray whose traffic is counted for the connection. The
+// context of the connection derives from parent, and the trace ends when the connection finishes.
+func (this *connectionTracker) open(parent context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, link ray.Ray, trace *tracing.Span) (*connection, ray.Ray) {
+	ctx, cancel := context.WithCancel(proxy.ContextWithSession(parent, meta, session))
+	conn := &connection{
+		ctx:     ctx,
+		cancel:  cancel,
+		meta:    meta,
+		session: session,
+		start:   time.Now(),
+		link:    link,
+		tracker: this,
+		trace:   trace,
+	}
+
+	this.Lock()
+	this.lastID++
+	conn.id = this.lastID
+	this.connections[conn.id] = conn
+	this.Unlock()
+
+	return conn, ray.NewCountedRay(link, &conn.uplink, &conn.downlink)
+}
+
+func (this *connectionTracker) remove(id uint64) {
+	this.Lock()
+	defer this.Unlock()
+
+	delete(this.connections, id)
+}
+
+// List returns the live connections, ordered by ID.
+func (this *connectionTracker) List() []*dispatcher.ConnectionInfo {
+	this.Lock()
+	infos := make([]*dispatcher.ConnectionInfo, 0, len(this.connections))
+	for _, conn := range this.connections {
+		infos = append(infos, conn.Info())
+	}
+	this.Unlock()
+
+	sort.Sort(connectionInfoByID(infos))
+	return infos
+}
+
+// Close closes the connection of the given ID, and returns false if it is not found.
+func (this *connectionTracker) Close(id uint64) bool {
+	this.Lock()
+	conn, found := this.connections[id]
+	this.Unlock()
+
+	if !found {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+type connectionInfoByID []*dispatcher.ConnectionInfo
+
+func (this connectionInfoByID) Len() int           { return len(this) }
+func (this connectionInfoByID) Swap(i, j int)      { this[i], this[j] = this[j], this[i] }
+func (this connectionInfoByID) Less(i, j int) bool { return this[i].ID < this[j].ID }
+
+// connection is a live connection through the dispatcher. It is tracked until the outbound finishes, when it is logged
+// as an access record if enabled.
+type connection struct {
+	sync.Mutex
+	id          uint64
+	ctx         context.Context
+	cancel      context.CancelFunc
+	meta        *proxy.InboundHandlerMeta
+	session     *proxy.SessionInfo
+	start       time.Time
+	outboundTag string
+	domain      string
+	uplink      stats.Counter
+	downlink    stats.Counter
+	link        ray.Ray
+	tracker     *connectionTracker
+	trace       *tracing.Span
+	onFinish    []func()
+}
+
+// OnFinish adds a function to call when the connection finishes.
+func (this *connection) OnFinish(f func()) {
+	this.Lock()
+	this.onFinish = append(this.onFinish, f)
+	this.Unlock()
+}
+
+// Context returns the context of the connection, which is done once the connection is closed or finished.
+func (this *connection) Context() context.Context {
+	return this.ctx
+}
+
+// childSpan starts a stage of the trace of the connection. It returns nil if the connection is nil or not traced.
+func (this *connection) childSpan(name string, kind tracing.SpanKind) *tracing.Span {
+	if this == nil {
+		return nil
+	}
+	return this.trace.Child(name, kind)
+}
+
+func (this *connection) Sniffed(sniffed *SniffResult) {
+	if sniffed == nil {
+		return
+	}
+	this.Lock()
+	this.domain = sniffed.Domain
+	this.Unlock()
+}
+
+// Handler wraps the outbound with the given tag, so that the connection is finished when Dispatch returns.
+func (this *connection) Handler(handler proxy.OutboundHandler, tag string) proxy.OutboundHandler {
+	this.Lock()
+	this.outboundTag = tag
+	this.Unlock()
+
+	return &trackedConnectionHandler{
+		OutboundHandler: handler,
+		conn:            this,
+	}
+}
+
+func (this *connection) Info() *dispatcher.ConnectionInfo {
+	this.Lock()
+	defer this.Unlock()
+
+	info := &dispatcher.ConnectionInfo{
+		ID:            this.id,
+		Source:        this.session.Source,
+		Destination:   this.session.Destination,
+		InboundTag:    this.meta.Tag,
+		OutboundTag:   this.outboundTag,
+		SniffedDomain: this.domain,
+		Start:         this.start,
+		Uplink:        this.uplink.Value(),
+		Downlink:      this.downlink.Value(),
+	}
+	if this.session.User != nil {
+		info.User = this.session.User.Email
+	}
+	return info
+}
+
+// Close closes both directions of the connection, and cancels its context, so that the inbound and the outbound stop.
+func (this *connection) Close() {
+	log.Info("DefaultDispatcher: Closing connection ", this.id, " from ", this.session.Source)
+	this.cancel()
+	this.link.InboundInput().Close()
+	this.link.InboundOutput().Close()
+}
+
+// Finish stops tracking the connection, ends its trace, and logs it as an access record if enabled.
+func (this *connection) Finish() {
+	this.cancel()
+	this.tracker.remove(this.id)
+	this.Lock()
+	onFinish := this.onFinish
+	this.onFinish = nil
+	this.Unlock()
+	for _, f := range onFinish {
+		f()
+	}
+	logger := this.tracker.logger
+	if this.trace == nil && !logger.AccessRecordEnabled() {
+		return
+	}
+	info := this.Info()
+	if this.trace != nil {
+		this.trace.SetAttribute("uplink", strconv.FormatInt(info.Uplink, 10))
+		this.trace.SetAttribute("downlink", strconv.FormatInt(info.Downlink, 10))
+		this.trace.End()
+	}
+	if !logger.AccessRecordEnabled() {
+		return
+	}
+	logger.AccessRecordLog(&log.AccessRecord{
+		Time:          info.Start,
+		Source:        info.Source.NetAddr(),
+		Destination:   info.Destination.String(),
+		Hostname:      logger.AccessHostname(info.Destination),
+		Status:        log.AccessAccepted,
+		InboundTag:    info.InboundTag,
+		OutboundTag:   info.OutboundTag,
+		User:          info.User,
+		SniffedDomain: info.SniffedDomain,
+		Uplink:        info.Uplink,
+		Downlink:      info.Downlink,
+		Duration:      time.Since(info.Start).Seconds(),
+	})
+}
+
+type trackedConnectionHandler struct {
+	proxy.OutboundHandler
+	conn *connection
+}
+
+func (this *trackedConnectionHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	defer this.conn.Finish()
+
+	// The outbound stage covers dialing and relaying, with the first byte from the server as an event.
+	span := this.conn.childSpan("outbound", tracing.SpanKindClient)
+	if span != nil {
+		link = ray.NewCountedOutboundRay(link, nil, &firstByteCounter{span: span})
+	}
+	err := this.OutboundHandler.Dispatch(ctx, destination, payload, link)
+	span.SetError(err)
+	span.End()
+	return err
+}
+
+// firstByteCounter records the first data from the server in a span.
+type firstByteCounter struct {
+	once sync.Once
+	span *tracing.Span
+}
+
+func (this *firstByteCounter) Add(delta int64) int64 {
+	this.once.Do(func() {
+		this.span.AddEvent("first_byte")
+	})
+	return delta
+}
@@ -0,0 +1,143 @@
+package impl_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	. "v2ray.com/core/app/dispatcher/impl"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+// echoHandler sends back all input until the input is closed.
+type echoHandler struct{}
+
+func (this *echoHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	defer link.OutboundOutput().Close()
+	if !payload.IsEmpty() {
+		link.OutboundOutput().Write(payload)
+	}
+	for {
+		data, err := link.OutboundInput().Read()
+		if err != nil {
+			return nil
+		}
+		link.OutboundOutput().Write(data)
+	}
+}
+
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestListAndCloseConnections(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	ohm.SetDefaultHandler(new(echoHandler))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, ohm)
+	dispatcher := NewDefaultDispatcher(space)
+	assert.Error(space.Initialize()).IsNil()
+
+	link := dispatcher.DispatchToOutbound(context.Background(), &proxy.InboundHandlerMeta{Tag: "in", AllowPassiveConnection: true}, &proxy.SessionInfo{
+		Source:      v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(12345)),
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), v2net.Port(443)),
+	})
+	payload := alloc.NewLocalBuffer(32).Clear()
+	payload.AppendString("ping")
+	assert.Error(link.InboundInput().Write(payload)).IsNil()
+	response, err := link.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	assert.String(response.String()).Equals("ping")
+
+	connections := dispatcher.ListConnections()
+	assert.Int(len(connections)).Equals(1)
+	assert.String(connections[0].InboundTag).Equals("in")
+	assert.String(connections[0].Destination.String()).Equals("tcp:v2ray.com:443")
+	assert.Int64(connections[0].Uplink).Equals(4)
+	assert.Int64(connections[0].Downlink).Equals(4)
+
+	assert.Bool(dispatcher.CloseConnection(connections[0].ID + 1)).IsFalse()
+	assert.Bool(dispatcher.CloseConnection(connections[0].ID)).IsTrue()
+	assert.Bool(waitFor(func() bool { return len(dispatcher.ListConnections()) == 0 })).IsTrue()
+	_, err = link.InboundOutput().Read()
+	assert.Error(err).IsNotNil()
+}
+
+// waitingHandler waits until its context is done, and reports the session in the context.
+type waitingHandler struct {
+	sessions chan *proxy.SessionInfo
+}
+
+func (this *waitingHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	defer link.OutboundOutput().Close()
+	<-ctx.Done()
+	this.sessions <- proxy.SessionFromContext(ctx)
+	return ctx.Err()
+}
+
+func TestReleaseCancelsConnections(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	handler := &waitingHandler{sessions: make(chan *proxy.SessionInfo, 1)}
+	ohm.SetDefaultHandler(handler)
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, ohm)
+	dispatcher := NewDefaultDispatcher(space)
+	assert.Error(space.Initialize()).IsNil()
+
+	dispatcher.DispatchToOutbound(context.Background(), &proxy.InboundHandlerMeta{Tag: "in", AllowPassiveConnection: true}, &proxy.SessionInfo{
+		Source:      v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(12345)),
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), v2net.Port(443)),
+	})
+	dispatcher.Release()
+
+	select {
+	case session := <-handler.sessions:
+		assert.String(session.Destination.String()).Equals("tcp:v2ray.com:443")
+	case <-time.After(time.Second):
+		t.Fatal("Outbound is not canceled.")
+	}
+	assert.Bool(waitFor(func() bool { return len(dispatcher.ListConnections()) == 0 })).IsTrue()
+}
+
+func TestInboundContextCancelsConnection(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	handler := &waitingHandler{sessions: make(chan *proxy.SessionInfo, 1)}
+	ohm.SetDefaultHandler(handler)
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, ohm)
+	dispatcher := NewDefaultDispatcher(space)
+	assert.Error(space.Initialize()).IsNil()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatcher.DispatchToOutbound(ctx, &proxy.InboundHandlerMeta{Tag: "in", AllowPassiveConnection: true}, &proxy.SessionInfo{
+		Source:      v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(12345)),
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), v2net.Port(443)),
+	})
+	cancel()
+
+	select {
+	case session := <-handler.sessions:
+		assert.String(session.Destination.String()).Equals("tcp:v2ray.com:443")
+	case <-time.After(time.Second):
+		t.Fatal("Outbound is not canceled.")
+	}
+	assert.Bool(waitFor(func() bool { return len(dispatcher.ListConnections()) == 0 })).IsTrue()
+}
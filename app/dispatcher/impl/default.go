@@ -1,23 +1,61 @@
 package impl
 
 import (
+	"context"
+
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/dns"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/app/router"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/app/throttle"
+	"v2ray.com/core/app/tracing"
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/ratelimit"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/ray"
 )
 
 type DefaultDispatcher struct {
-	ohm    proxyman.OutboundHandlerManager
-	router router.Router
+	ohm       proxyman.OutboundHandlerManager
+	router    router.Router
+	policy    *policy.Manager
+	throttler *throttle.Throttler
+	stats     *stats.Manager
+	fakeDNS   dns.FakeDNSEngine
+	tracer    *tracing.Tracer
+	tracker   *connectionTracker
+	// logger is the logger of the instance. Nil for the default logger.
+	logger *log.Logger
+	// ctx is canceled on release, which cancels the contexts of all connections.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func init() {
+	// The dispatcher has no config, and it is bound to spaces by shells.
+	app.MustRegisterFeature(&app.Feature{
+		ID:   dispatcher.APP_ID,
+		Name: "dispatcher",
+		After: []app.ID{
+			applog.APP_ID, dns.APP_ID, router.APP_ID, policy.APP_ID, throttle.APP_ID, stats.APP_ID, tracing.APP_ID,
+			proxyman.APP_ID_OUTBOUND_MANAGER,
+		},
+	})
 }
 
 func NewDefaultDispatcher(space app.Space) *DefaultDispatcher {
-	d := &DefaultDispatcher{}
+	d := &DefaultDispatcher{
+		tracker: newConnectionTracker(),
+		logger:  applog.FromSpace(space),
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
 	space.InitializeApplication(func() error {
 		return d.Initialize(space)
 	})
@@ -27,7 +65,7 @@ func NewDefaultDispatcher(space app.Space) *DefaultDispatcher {
 // Private: Used by app.Space only.
 func (this *DefaultDispatcher) Initialize(space app.Space) error {
 	if !space.HasApp(proxyman.APP_ID_OUTBOUND_MANAGER) {
-		log.Error("DefaultDispatcher: OutboundHandlerManager is not found in the space.")
+		this.logger.Error("DefaultDispatcher: OutboundHandlerManager is not found in the space.")
 		return app.ErrMissingApplication
 	}
 	this.ohm = space.GetApp(proxyman.APP_ID_OUTBOUND_MANAGER).(proxyman.OutboundHandlerManager)
@@ -36,48 +74,335 @@ func (this *DefaultDispatcher) Initialize(space app.Space) error {
 		this.router = space.GetApp(router.APP_ID).(router.Router)
 	}
 
+	this.policy = policy.FromSpace(space)
+	this.tracker.logger = this.logger
+
+	if space.HasApp(throttle.APP_ID) {
+		this.throttler = space.GetApp(throttle.APP_ID).(*throttle.Throttler)
+	}
+
+	if space.HasApp(stats.APP_ID) {
+		this.stats = space.GetApp(stats.APP_ID).(*stats.Manager)
+	}
+
+	if space.HasApp(tracing.APP_ID) {
+		this.tracer = space.GetApp(tracing.APP_ID).(*tracing.Tracer)
+	}
+
+	if space.HasApp(dns.APP_ID) {
+		if fakeDNS, ok := space.GetApp(dns.APP_ID).(dns.FakeDNSEngine); ok {
+			this.fakeDNS = fakeDNS
+		}
+	}
+
 	return nil
 }
 
+// Release cancels the contexts of all connections, so that their outbounds stop.
 func (this *DefaultDispatcher) Release() {
+	this.cancel()
+}
 
+func (this *DefaultDispatcher) ListConnections() []*dispatcher.ConnectionInfo {
+	return this.tracker.List()
 }
 
-func (this *DefaultDispatcher) DispatchToOutbound(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay {
-	direct := ray.NewRay()
-	dispatcher := this.ohm.GetDefaultHandler()
-	destination := session.Destination
+func (this *DefaultDispatcher) CloseConnection(id uint64) bool {
+	return this.tracker.Close(id)
+}
 
-	if this.router != nil {
-		if tag, err := this.router.TakeDetour(destination); err == nil {
-			if handler := this.ohm.GetHandler(tag); handler != nil {
-				log.Info("DefaultDispatcher: Taking detour [", tag, "] for [", destination, "].")
-				dispatcher = handler
-			} else {
-				log.Warning("DefaultDispatcher: Nonexisting tag: ", tag)
-			}
-		} else {
-			log.Info("DefaultDispatcher: Default route for ", destination)
-		}
+// restoreFakeDomain replaces a fake IP destination with the domain it was given to, so that the connection is routed
+// and sent as if the client connected to the domain.
+func (this *DefaultDispatcher) restoreFakeDomain(session *proxy.SessionInfo) *proxy.SessionInfo {
+	dest := session.Destination
+	if this.fakeDNS == nil || dest.Address == nil || !dest.Address.Family().Either(v2net.AddressFamilyIPv4, v2net.AddressFamilyIPv6) {
+		return session
+	}
+	domain := this.fakeDNS.GetDomainFromFakeIP(dest.Address.IP())
+	if len(domain) == 0 {
+		return session
+	}
+	this.logger.Debug("DefaultDispatcher: Restored domain ", domain, " from fake IP ", dest.Address)
+	restored := *session
+	restored.Destination.Address = v2net.DomainAddress(domain)
+	return &restored
+}
+
+func (this *DefaultDispatcher) DispatchToOutbound(ctx context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay {
+	session = this.restoreFakeDomain(session)
+	level := uint32(0)
+	if session.User != nil {
+		level = session.User.Level
+	}
+	p := this.policy.ForLevel(level)
+	direct := ray.NewLimitedRay(p.BufferSize, ray.NewBufferLimit(p.PooledBuffers, p.BufferClass))
+	releaseSession, accepted := this.policy.OpenSession(session.User)
+	if !accepted {
+		this.logger.Warning("DefaultDispatcher: User ", session.User.Email, " has too many connections, rejecting ", session.Source)
+		direct.OutboundInput().Release()
+		direct.OutboundOutput().Release()
+		return direct
+	}
+	releaseDevice, accepted := this.openDevice(session)
+	if !accepted {
+		this.logger.Warning("DefaultDispatcher: User ", session.User.Email, " is connected from too many devices, rejecting ", session.Source)
+		releaseSession()
+		direct.OutboundInput().Release()
+		direct.OutboundOutput().Release()
+		return direct
+	}
+	direct, accepted = this.policy.ApplyQuota(session.User, direct, this.stats)
+	if !accepted {
+		this.logger.Warning("DefaultDispatcher: User ", session.User.Email, " is over quota, rejecting ", session.Source)
+		releaseDevice()
+		releaseSession()
+		direct.OutboundInput().Release()
+		direct.OutboundOutput().Release()
+		return direct
+	}
+	direct = ray.NewLingeringRay(direct, p.UplinkOnly, p.DownlinkOnly)
+	releaseThrottle := func() {}
+	if this.throttler != nil {
+		direct, releaseThrottle = this.throttler.Throttle(meta, session, direct)
+	}
+	if this.stats != nil {
+		direct = this.stats.Count(meta, session, direct)
 	}
+	conn, counted := this.tracker.open(ctx, meta, session, direct, this.startTrace(meta, session))
+	// Releasing the dispatcher cancels the connection as well as its inbound does.
+	stopRelease := context.AfterFunc(this.ctx, conn.cancel)
+	conn.OnFinish(func() { stopRelease() })
+	conn.OnFinish(releaseDevice)
+	conn.OnFinish(releaseSession)
+	conn.OnFinish(releaseThrottle)
 
-	if meta.AllowPassiveConnection {
-		go dispatcher.Dispatch(destination, alloc.NewLocalBuffer(32).Clear(), direct)
+	if meta.AllowPassiveConnection && !protocol.IsPacketAddrDestination(session.Destination) {
+		// The server may speak first, so the connection is routed without payload.
+		handler := this.pickHandler(meta, session, nil, conn)
+		go handler.Dispatch(conn.Context(), session.Destination, alloc.NewLocalBuffer(32).Clear(), counted)
 	} else {
-		go this.FilterPacketAndDispatch(destination, direct, dispatcher)
+		go this.filterPacketAndDispatch(meta, session, counted, conn)
 	}
 
-	return direct
+	return counted
+}
+
+// openDevice counts the source IP of the connection as a device of its user, and returns a function to release it once
+// the connection finishes. It returns false if the user is at the device limit.
+func (this *DefaultDispatcher) openDevice(session *proxy.SessionInfo) (func(), bool) {
+	if this.stats == nil || session.User == nil || session.Source.Address == nil {
+		return func() {}, true
+	}
+	return this.stats.OpenDevice(session.User.Email, session.Source.Address.String())
+}
+
+// startTrace starts the trace of a connection, which covers the stages from the inbound handing it over to the outbound
+// finishing. It returns nil if the connection is not traced.
+func (this *DefaultDispatcher) startTrace(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) *tracing.Span {
+	span := this.tracer.StartTrace("connection", tracing.SpanKindServer)
+	if span == nil {
+		return nil
+	}
+	span.SetAttribute("inbound", meta.Tag)
+	span.SetAttribute("source", session.Source.NetAddr())
+	span.SetAttribute("destination", session.Destination.String())
+	if session.User != nil && len(session.User.Email) > 0 {
+		span.SetAttribute("user", session.User.Email)
+	}
+	return span
+}
+
+// defaultHandler returns the outbound for connections that no routing rule matches, which is the default outbound of
+// the inbound if set, or the global default outbound. The tag is empty for the global default outbound.
+func (this *DefaultDispatcher) defaultHandler(meta *proxy.InboundHandlerMeta) (proxy.OutboundHandler, string) {
+	if len(meta.DefaultOutboundTag) > 0 {
+		if handler := this.ohm.GetHandler(meta.DefaultOutboundTag); handler != nil {
+			return handler, meta.DefaultOutboundTag
+		}
+		this.logger.Warning("DefaultDispatcher: Nonexisting default outbound of inbound [", meta.Tag, "]: ", meta.DefaultOutboundTag)
+	}
+	return this.ohm.GetDefaultHandler(), ""
+}
+
+// outboundHandler wraps the outbound with the given tag to apply its bandwidth limit and to count it.
+func (this *DefaultDispatcher) outboundHandler(handler proxy.OutboundHandler, tag string) proxy.OutboundHandler {
+	return this.countHandler(this.throttleHandler(handler, tag), tag)
+}
+
+// throttleHandler wraps the outbound with the given tag to limit its aggregate traffic, if it has a limit.
+func (this *DefaultDispatcher) throttleHandler(handler proxy.OutboundHandler, tag string) proxy.OutboundHandler {
+	if this.throttler == nil {
+		return handler
+	}
+	uplink, downlink := this.throttler.ThrottleOutbound(tag)
+	if uplink == nil && downlink == nil {
+		return handler
+	}
+	return &throttledHandler{
+		OutboundHandler: handler,
+		uplink:          uplink,
+		downlink:        downlink,
+	}
+}
+
+// countHandler wraps the outbound with the given tag to count its open connections, and its traffic when enabled.
+func (this *DefaultDispatcher) countHandler(handler proxy.OutboundHandler, tag string) proxy.OutboundHandler {
+	if this.stats == nil {
+		return handler
+	}
+	uplink, downlink := this.stats.CountOutbound(tag)
+	return &countedHandler{
+		OutboundHandler: handler,
+		tag:             tag,
+		stats:           this.stats,
+		uplink:          uplink,
+		downlink:        downlink,
+	}
+}
+
+// pickHandler routes the connection, with the protocol sniffed from its first payload if available. The connection is
+// tracked until the outbound finishes if conn is not nil.
+func (this *DefaultDispatcher) pickHandler(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, sniffed *SniffResult, conn *connection) proxy.OutboundHandler {
+	span := conn.childSpan("route", tracing.SpanKindInternal)
+	handler, tag := this.routeHandler(meta, session, sniffed)
+	span.SetAttribute("outbound", tag)
+	span.End()
+	if conn == nil {
+		return handler
+	}
+	conn.Sniffed(sniffed)
+	return conn.Handler(handler, tag)
+}
+
+// routeHandler returns the outbound for the connection and its tag.
+func (this *DefaultDispatcher) routeHandler(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, sniffed *SniffResult) (proxy.OutboundHandler, string) {
+	dispatcher, defaultTag := this.defaultHandler(meta)
+	destination := session.Destination
+	if this.router == nil {
+		return this.outboundHandler(dispatcher, defaultTag), defaultTag
+	}
+
+	ctx := &router.Context{
+		InboundTag:  meta.Tag,
+		Source:      session.Source,
+		Destination: destination,
+		User:        session.User,
+	}
+	if sniffed != nil {
+		this.logger.Debug("DefaultDispatcher: Sniffed ", sniffed.Protocol, " ", sniffed.Domain, " towards ", destination)
+		ctx.Protocol = sniffed.Protocol
+		ctx.SniffedDomain = sniffed.Domain
+	}
+	tag, err := this.router.TakeDetour(ctx)
+	if err != nil {
+		this.logger.Info("DefaultDispatcher: Default route for ", destination)
+		return this.outboundHandler(dispatcher, defaultTag), defaultTag
+	}
+	handler := this.ohm.GetHandler(tag)
+	if handler == nil {
+		this.logger.Warning("DefaultDispatcher: Nonexisting tag: ", tag)
+		return this.outboundHandler(dispatcher, defaultTag), defaultTag
+	}
+	handler = this.outboundHandler(handler, tag)
+	this.logger.Info("DefaultDispatcher: Taking detour [", tag, "] for [", destination, "].")
+	if tracker, ok := this.router.(router.LoadTracker); ok {
+		return &trackedHandler{
+			OutboundHandler: handler,
+			tag:             tag,
+			tracker:         tracker,
+		}, tag
+	}
+	return handler, tag
+}
+
+// trackedHandler reports the connection on an outbound to the router for the duration of Dispatch.
+type trackedHandler struct {
+	proxy.OutboundHandler
+	tag     string
+	tracker router.LoadTracker
+}
+
+func (this *trackedHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	this.tracker.OnConnectionOpen(this.tag)
+	defer this.tracker.OnConnectionClose(this.tag)
+
+	return this.OutboundHandler.Dispatch(ctx, destination, payload, link)
+}
+
+// throttledHandler limits the traffic of all connections on an outbound, including the first payloads.
+type throttledHandler struct {
+	proxy.OutboundHandler
+	uplink   *ratelimit.TokenBucket
+	downlink *ratelimit.TokenBucket
+}
+
+func (this *throttledHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	if this.uplink != nil {
+		this.uplink.Wait(payload.Len())
+	}
+	return this.OutboundHandler.Dispatch(ctx, destination, payload, ray.NewThrottledOutboundRay(link, this.uplink, this.downlink))
+}
+
+// countedHandler counts the connections on an outbound for the duration of Dispatch, and their traffic including the
+// first payload.
+type countedHandler struct {
+	proxy.OutboundHandler
+	tag      string
+	stats    *stats.Manager
+	uplink   ray.Counter
+	downlink ray.Counter
+}
+
+func (this *countedHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	this.stats.OnConnectionOpen(this.tag)
+	defer this.stats.OnConnectionClose(this.tag)
+
+	if this.uplink != nil {
+		this.uplink.Add(int64(payload.Len()))
+	}
+	return this.OutboundHandler.Dispatch(ctx, destination, payload, ray.NewCountedOutboundRay(link, this.uplink, this.downlink))
 }
 
 // Private: Visible for testing.
-func (this *DefaultDispatcher) FilterPacketAndDispatch(destination v2net.Destination, link ray.OutboundRay, dispatcher proxy.OutboundHandler) {
+func (this *DefaultDispatcher) FilterPacketAndDispatch(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, link ray.OutboundRay) {
+	this.filterPacketAndDispatch(meta, session, link, nil)
+}
+
+func (this *DefaultDispatcher) filterPacketAndDispatch(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, link ray.OutboundRay, conn *connection) {
+	destination := session.Destination
+	// The sniffing stage includes waiting for the first payload from the client.
+	span := conn.childSpan("sniff", tracing.SpanKindInternal)
 	payload, err := link.OutboundInput().Read()
 	if err != nil {
-		log.Info("DefaultDispatcher: No payload towards ", destination, ", stopping now.")
+		this.logger.Info("DefaultDispatcher: No payload towards ", destination, ", stopping now.")
+		span.SetError(err)
+		span.End()
 		link.OutboundInput().Release()
 		link.OutboundOutput().Release()
+		if conn != nil {
+			conn.Finish()
+		}
 		return
 	}
-	dispatcher.Dispatch(destination, payload, link)
+	ctx := proxy.ContextWithSession(this.ctx, meta, session)
+	if conn != nil {
+		ctx = conn.Context()
+	}
+	if protocol.IsPacketAddrDestination(destination) {
+		// Datagrams are routed by their own targets.
+		span.End()
+		this.dispatchPacketAddr(ctx, meta, session, payload, link, conn)
+		return
+	}
+	var sniffed *SniffResult
+	if this.router != nil {
+		sniffed = Sniff(payload.Value, destination.Network)
+	}
+	if sniffed != nil {
+		span.SetAttribute("protocol", sniffed.Protocol)
+		span.SetAttribute("domain", sniffed.Domain)
+	}
+	span.End()
+	handler := this.pickHandler(meta, session, sniffed, conn)
+	handler.Dispatch(ctx, destination, payload, link)
 }
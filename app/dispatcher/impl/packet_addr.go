@@ -0,0 +1,115 @@
+package impl
+
+import (
+	"context"
+
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+// packetAddrRouteCacheSize is the number of targets whose outbounds are kept by a packet-addressed session.
+const packetAddrRouteCacheSize = 256
+
+// dispatchPacketAddr routes a packet-addressed session by the target of its first datagram. As the session goes
+// through a single outbound, datagrams whose targets are routed to other outbounds are dropped.
+func (this *DefaultDispatcher) dispatchPacketAddr(ctx context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, payload *alloc.Buffer, link ray.OutboundRay, conn *connection) {
+	target, err := protocol.PeekPacketAddr(payload)
+	if err != nil {
+		this.logger.Info("DefaultDispatcher: Invalid packet address from ", session.Source, ", stopping now.")
+		payload.Release()
+		link.OutboundInput().Release()
+		link.OutboundOutput().Release()
+		if conn != nil {
+			conn.Finish()
+		}
+		return
+	}
+	routed := *session
+	routed.Destination = target
+	handler := this.pickHandler(meta, &routed, nil, conn)
+	if this.router != nil {
+		filter := &packetAddrInputStream{
+			InputStream: link.OutboundInput(),
+			dispatcher:  this,
+			meta:        meta,
+			session:     session,
+			routes:      make(map[string]string),
+		}
+		filter.tag = filter.route(target)
+		link = &packetAddrOutboundRay{
+			input:  filter,
+			output: link.OutboundOutput(),
+		}
+	}
+	handler.Dispatch(ctx, session.Destination, payload, link)
+}
+
+// routeTag returns the tag of the outbound that the router picks for the target, or the tag of the default outbound.
+func (this *DefaultDispatcher) routeTag(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, target v2net.Destination) string {
+	tag, err := this.router.TakeDetour(&router.Context{
+		InboundTag:  meta.Tag,
+		Source:      session.Source,
+		Destination: target,
+		User:        session.User,
+	})
+	if err != nil || this.ohm.GetHandler(tag) == nil {
+		_, tag = this.defaultHandler(meta)
+	}
+	return tag
+}
+
+type packetAddrOutboundRay struct {
+	input  ray.InputStream
+	output ray.OutputStream
+}
+
+func (this *packetAddrOutboundRay) OutboundInput() ray.InputStream {
+	return this.input
+}
+
+func (this *packetAddrOutboundRay) OutboundOutput() ray.OutputStream {
+	return this.output
+}
+
+// packetAddrInputStream drops the datagrams of a packet-addressed session whose targets are not routed to the outbound
+// of the session. It is read by the outbound only, so the routes are not guarded.
+type packetAddrInputStream struct {
+	ray.InputStream
+	dispatcher *DefaultDispatcher
+	meta       *proxy.InboundHandlerMeta
+	session    *proxy.SessionInfo
+	tag        string
+	routes     map[string]string
+}
+
+func (this *packetAddrInputStream) route(target v2net.Destination) string {
+	key := target.String()
+	if tag, found := this.routes[key]; found {
+		return tag
+	}
+	if len(this.routes) >= packetAddrRouteCacheSize {
+		this.routes = make(map[string]string)
+	}
+	tag := this.dispatcher.routeTag(this.meta, this.session, target)
+	this.routes[key] = tag
+	return tag
+}
+
+func (this *packetAddrInputStream) Read() (*alloc.Buffer, error) {
+	for {
+		payload, err := this.InputStream.Read()
+		if err != nil {
+			return nil, err
+		}
+		target, err := protocol.PeekPacketAddr(payload)
+		if err == nil && this.route(target) == this.tag {
+			return payload, nil
+		}
+		this.dispatcher.logger.Debug("DefaultDispatcher: Dropping datagram from ", this.session.Source, " to ", target, " not routed to [", this.tag, "].")
+		payload.Release()
+	}
+}
@@ -0,0 +1,73 @@
+package impl_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"v2ray.com/core/app"
+	. "v2ray.com/core/app/dispatcher/impl"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+)
+
+// blockingRouter routes the blocked address to the outbound "blocked", and everything else to the default outbound.
+type blockingRouter struct {
+	blocked v2net.Address
+}
+
+func (this *blockingRouter) TakeDetour(ctx *router.Context) (string, error) {
+	if ctx.Destination.Address.String() == this.blocked.String() {
+		return "blocked", nil
+	}
+	return "", errors.New("No rule matches.")
+}
+
+func (this *blockingRouter) Release() {}
+
+func packetAddrPayload(dest v2net.Destination, content string) *alloc.Buffer {
+	payload := alloc.NewLocalBuffer(32).Clear()
+	payload.AppendString(content)
+	encoded, _ := protocol.EncodePacketAddr(dest, payload)
+	return encoded
+}
+
+func TestPacketAddrRouting(t *testing.T) {
+	assert := assert.On(t)
+
+	blocked := v2net.IPAddress([]byte{10, 0, 0, 2})
+	space := app.NewSpace()
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	ohm.SetDefaultHandler(new(echoHandler))
+	ohm.SetHandler("blocked", new(echoHandler))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, ohm)
+	space.BindApp(router.APP_ID, &blockingRouter{blocked: blocked})
+	dispatcher := NewDefaultDispatcher(space)
+	assert.Error(space.Initialize()).IsNil()
+
+	allowed := v2net.UDPDestination(v2net.IPAddress([]byte{10, 0, 0, 1}), v2net.Port(53))
+	link := dispatcher.DispatchToOutbound(context.Background(), &proxy.InboundHandlerMeta{Tag: "in"}, &proxy.SessionInfo{
+		Source:      v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(12345)),
+		Destination: protocol.PacketAddrDestination(),
+	})
+	assert.Error(link.InboundInput().Write(packetAddrPayload(allowed, "first"))).IsNil()
+	assert.Error(link.InboundInput().Write(packetAddrPayload(v2net.UDPDestination(blocked, v2net.Port(53)), "blocked"))).IsNil()
+	assert.Error(link.InboundInput().Write(packetAddrPayload(allowed, "second"))).IsNil()
+	link.InboundInput().Close()
+
+	for _, expected := range []string{"first", "second"} {
+		response, err := link.InboundOutput().Read()
+		assert.Error(err).IsNil()
+		dest, err := protocol.DecodePacketAddr(response)
+		assert.Error(err).IsNil()
+		assert.String(dest.String()).Equals(allowed.String())
+		assert.String(response.String()).Equals(expected)
+	}
+	_, err := link.InboundOutput().Read()
+	assert.Error(err).IsNotNil()
+}
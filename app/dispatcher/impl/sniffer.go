@@ -0,0 +1,183 @@
+package impl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	ProtocolHTTP       = "http"
+	ProtocolTLS        = "tls"
+	ProtocolBitTorrent = "bittorrent"
+	ProtocolQUIC       = "quic"
+)
+
+var (
+	httpMethods = []string{"GET", "POST", "HEAD", "PUT", "DELETE", "OPTIONS", "CONNECT", "PATCH", "TRACE"}
+
+	bitTorrentHandshake = []byte("\x13BitTorrent protocol")
+)
+
+// SniffResult is the application protocol detected from the first payload of a connection.
+type SniffResult struct {
+	Protocol string
+	// Domain is the host in HTTP requests, or the server name in TLS client hellos. Empty if not available.
+	Domain string
+}
+
+// Sniff detects the protocol of a connection from its first payload. It returns nil if the protocol is unknown.
+func Sniff(payload []byte, network v2net.Network) *SniffResult {
+	if network == v2net.Network_UDP {
+		switch {
+		case sniffQUIC(payload):
+			return &SniffResult{Protocol: ProtocolQUIC}
+		case sniffDHT(payload):
+			return &SniffResult{Protocol: ProtocolBitTorrent}
+		}
+		return nil
+	}
+
+	if domain, ok := sniffTLS(payload); ok {
+		return &SniffResult{Protocol: ProtocolTLS, Domain: domain}
+	}
+	if domain, ok := sniffHTTP(payload); ok {
+		return &SniffResult{Protocol: ProtocolHTTP, Domain: domain}
+	}
+	if bytes.HasPrefix(payload, bitTorrentHandshake) {
+		return &SniffResult{Protocol: ProtocolBitTorrent}
+	}
+	return nil
+}
+
+func sniffHTTP(payload []byte) (string, bool) {
+	lineEnd := bytes.Index(payload, []byte("\r\n"))
+	if lineEnd < 0 {
+		return "", false
+	}
+	parts := strings.Split(string(payload[:lineEnd]), " ")
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "HTTP/") {
+		return "", false
+	}
+	isMethod := false
+	for _, method := range httpMethods {
+		if parts[0] == method {
+			isMethod = true
+			break
+		}
+	}
+	if !isMethod {
+		return "", false
+	}
+
+	for _, line := range strings.Split(string(payload[lineEnd+2:]), "\r\n") {
+		if len(line) == 0 {
+			break
+		}
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 || !strings.EqualFold(strings.TrimSpace(line[:idx]), "host") {
+			continue
+		}
+		host := strings.TrimSpace(line[idx+1:])
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return strings.ToLower(host), true
+	}
+	return "", true
+}
+
+// sniffTLS parses the server name from a TLS client hello. It returns true for client hellos without server name too.
+func sniffTLS(payload []byte) (string, bool) {
+	// Record header: content type, version, length.
+	if len(payload) < 5+4 || payload[0] != 0x16 || payload[1] != 0x03 {
+		return "", false
+	}
+	// Handshake header: type, length.
+	data := payload[5:]
+	if data[0] != 0x01 {
+		return "", false
+	}
+	handshakeLen := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	data = data[4:]
+	if len(data) > handshakeLen {
+		data = data[:handshakeLen]
+	}
+
+	// Client version and random.
+	if len(data) < 2+32+1 {
+		return "", true
+	}
+	data = data[2+32:]
+	sessionIDLen := int(data[0])
+	if len(data) < 1+sessionIDLen+2 {
+		return "", true
+	}
+	data = data[1+sessionIDLen:]
+	cipherSuitesLen := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+cipherSuitesLen+1 {
+		return "", true
+	}
+	data = data[2+cipherSuitesLen:]
+	compressionLen := int(data[0])
+	if len(data) < 1+compressionLen+2 {
+		return "", true
+	}
+	data = data[1+compressionLen:]
+	extensionsLen := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) > extensionsLen {
+		data = data[:extensionsLen]
+	}
+
+	for len(data) >= 4 {
+		extType := binary.BigEndian.Uint16(data)
+		extLen := int(binary.BigEndian.Uint16(data[2:]))
+		data = data[4:]
+		if len(data) < extLen {
+			break
+		}
+		if extType == 0 {
+			return parseServerNameExtension(data[:extLen]), true
+		}
+		data = data[extLen:]
+	}
+	return "", true
+}
+
+func parseServerNameExtension(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	data = data[2:]
+	for len(data) >= 3 {
+		nameType := data[0]
+		nameLen := int(binary.BigEndian.Uint16(data[1:]))
+		data = data[3:]
+		if len(data) < nameLen {
+			return ""
+		}
+		if nameType == 0 {
+			return strings.ToLower(string(data[:nameLen]))
+		}
+		data = data[nameLen:]
+	}
+	return ""
+}
+
+// sniffQUIC detects the long header packets of QUIC version 1 and 2, and of the IETF drafts.
+func sniffQUIC(payload []byte) bool {
+	if len(payload) < 1200 || payload[0]&0xc0 != 0xc0 {
+		return false
+	}
+	version := binary.BigEndian.Uint32(payload[1:])
+	return version == 0x00000001 || version == 0x6b3343cf || version&0xffffff00 == 0xff000000
+}
+
+// sniffDHT detects the KRPC messages of BitTorrent DHT, which are bencoded dictionaries with the message type "y".
+func sniffDHT(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("d1:")) && bytes.Contains(payload, []byte("1:y1:"))
+}
@@ -0,0 +1,79 @@
+package impl_test
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	. "v2ray.com/core/app/dispatcher/impl"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+)
+
+func clientHello(serverName string) []byte {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go tls.Client(client, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	}).Handshake()
+
+	buffer := make([]byte, 4096)
+	nBytes, _ := server.Read(buffer)
+	return buffer[:nBytes]
+}
+
+func TestSniffTLS(t *testing.T) {
+	assert := assert.On(t)
+
+	result := Sniff(clientHello("www.V2Ray.com"), v2net.Network_TCP)
+	assert.Pointer(result).IsNotNil()
+	assert.String(result.Protocol).Equals(ProtocolTLS)
+	assert.String(result.Domain).Equals("www.v2ray.com")
+
+	result = Sniff(clientHello(""), v2net.Network_TCP)
+	assert.Pointer(result).IsNotNil()
+	assert.String(result.Protocol).Equals(ProtocolTLS)
+	assert.String(result.Domain).Equals("")
+}
+
+func TestSniffHTTP(t *testing.T) {
+	assert := assert.On(t)
+
+	result := Sniff([]byte("GET / HTTP/1.1\r\nUser-Agent: curl\r\nHost: www.v2ray.com:8080\r\n\r\n"), v2net.Network_TCP)
+	assert.Pointer(result).IsNotNil()
+	assert.String(result.Protocol).Equals(ProtocolHTTP)
+	assert.String(result.Domain).Equals("www.v2ray.com")
+
+	assert.Pointer(Sniff([]byte("GOT / HTTP/1.1\r\nHost: www.v2ray.com\r\n\r\n"), v2net.Network_TCP)).IsNil()
+	assert.Pointer(Sniff([]byte("SSH-2.0-OpenSSH_7.2\r\n"), v2net.Network_TCP)).IsNil()
+}
+
+func TestSniffBitTorrent(t *testing.T) {
+	assert := assert.On(t)
+
+	result := Sniff(append([]byte("\x13BitTorrent protocol"), make([]byte, 48)...), v2net.Network_TCP)
+	assert.Pointer(result).IsNotNil()
+	assert.String(result.Protocol).Equals(ProtocolBitTorrent)
+
+	result = Sniff([]byte("d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe"), v2net.Network_UDP)
+	assert.Pointer(result).IsNotNil()
+	assert.String(result.Protocol).Equals(ProtocolBitTorrent)
+}
+
+func TestSniffQUIC(t *testing.T) {
+	assert := assert.On(t)
+
+	packet := make([]byte, 1200)
+	packet[0] = 0xc3
+	packet[4] = 0x01
+	result := Sniff(packet, v2net.Network_UDP)
+	assert.Pointer(result).IsNotNil()
+	assert.String(result.Protocol).Equals(ProtocolQUIC)
+
+	// Short packets are not QUIC initials, e.g. DNS queries.
+	assert.Pointer(Sniff(packet[:64], v2net.Network_UDP)).IsNil()
+	assert.Pointer(Sniff(packet, v2net.Network_TCP)).IsNil()
+}
@@ -1,6 +1,8 @@
 package testing
 
 import (
+	"context"
+
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/ray"
@@ -30,7 +32,7 @@ func NewTestPacketDispatcher(handler func(destination v2net.Destination, traffic
 	}
 }
 
-func (this *TestPacketDispatcher) DispatchToOutbound(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay {
+func (this *TestPacketDispatcher) DispatchToOutbound(ctx context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay {
 	traffic := ray.NewRay()
 	this.Destination <- session.Destination
 	go this.Handler(session.Destination, traffic)
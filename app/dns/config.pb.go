@@ -9,6 +9,9 @@ It is generated from these files:
 	v2ray.com/core/app/dns/config.proto
 
 It has these top-level messages:
+	NameServerConfig
+	FakeDNSConfig
+	CacheConfig
 	Config
 */
 package dns
@@ -30,15 +33,91 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type QueryStrategy int32
+
+const (
+	QueryStrategy_USE_IP  QueryStrategy = 0
+	QueryStrategy_USE_IP4 QueryStrategy = 1
+	QueryStrategy_USE_IP6 QueryStrategy = 2
+)
+
+var QueryStrategy_name = map[int32]string{
+	0: "USE_IP",
+	1: "USE_IP4",
+	2: "USE_IP6",
+}
+var QueryStrategy_value = map[string]int32{
+	"USE_IP":  0,
+	"USE_IP4": 1,
+	"USE_IP6": 2,
+}
+
+func (x QueryStrategy) String() string {
+	return proto.EnumName(QueryStrategy_name, int32(x))
+}
+func (QueryStrategy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type NameServerConfig struct {
+	Address     *v2ray_core_common_net2.DestinationPB `protobuf:"bytes,1,opt,name=Address,json=address" json:"Address,omitempty"`
+	Domain      []string                              `protobuf:"bytes,2,rep,name=Domain,json=domain" json:"Domain,omitempty"`
+	URL         string                                `protobuf:"bytes,3,opt,name=URL,json=uRL" json:"URL,omitempty"`
+	OutboundTag string                                `protobuf:"bytes,4,opt,name=OutboundTag,json=outboundTag" json:"OutboundTag,omitempty"`
+	ExpectIPs   []string                              `protobuf:"bytes,5,rep,name=ExpectIPs,json=expectIPs" json:"ExpectIPs,omitempty"`
+	ClientIP    []byte                                `protobuf:"bytes,6,opt,name=ClientIP,json=clientIP,proto3" json:"ClientIP,omitempty"`
+	DNSSEC      bool                                  `protobuf:"varint,7,opt,name=DNSSEC,json=dNSSEC" json:"DNSSEC,omitempty"`
+}
+
+func (m *NameServerConfig) Reset()                    { *m = NameServerConfig{} }
+func (m *NameServerConfig) String() string            { return proto.CompactTextString(m) }
+func (*NameServerConfig) ProtoMessage()               {}
+func (*NameServerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *NameServerConfig) GetAddress() *v2ray_core_common_net2.DestinationPB {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+type FakeDNSConfig struct {
+	IPPool   string `protobuf:"bytes,1,opt,name=IPPool,json=iPPool" json:"IPPool,omitempty"`
+	PoolSize uint32 `protobuf:"varint,2,opt,name=PoolSize,json=poolSize" json:"PoolSize,omitempty"`
+}
+
+func (m *FakeDNSConfig) Reset()                    { *m = FakeDNSConfig{} }
+func (m *FakeDNSConfig) String() string            { return proto.CompactTextString(m) }
+func (*FakeDNSConfig) ProtoMessage()               {}
+func (*FakeDNSConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type CacheConfig struct {
+	Disabled             bool   `protobuf:"varint,1,opt,name=Disabled,json=disabled" json:"Disabled,omitempty"`
+	MinTTL               uint32 `protobuf:"varint,2,opt,name=MinTTL,json=minTTL" json:"MinTTL,omitempty"`
+	MaxTTL               uint32 `protobuf:"varint,3,opt,name=MaxTTL,json=maxTTL" json:"MaxTTL,omitempty"`
+	MaxNegativeTTL       uint32 `protobuf:"varint,4,opt,name=MaxNegativeTTL,json=maxNegativeTTL" json:"MaxNegativeTTL,omitempty"`
+	DisableNegativeCache bool   `protobuf:"varint,5,opt,name=DisableNegativeCache,json=disableNegativeCache" json:"DisableNegativeCache,omitempty"`
+}
+
+func (m *CacheConfig) Reset()                    { *m = CacheConfig{} }
+func (m *CacheConfig) String() string            { return proto.CompactTextString(m) }
+func (*CacheConfig) ProtoMessage()               {}
+func (*CacheConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
 type Config struct {
-	NameServers []*v2ray_core_common_net2.DestinationPB     `protobuf:"bytes,1,rep,name=NameServers,json=nameServers" json:"NameServers,omitempty"`
-	Hosts       map[string]*v2ray_core_common_net.AddressPB `protobuf:"bytes,2,rep,name=Hosts,json=hosts" json:"Hosts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	NameServers     []*v2ray_core_common_net2.DestinationPB     `protobuf:"bytes,1,rep,name=NameServers,json=nameServers" json:"NameServers,omitempty"`
+	Hosts           map[string]*v2ray_core_common_net.AddressPB `protobuf:"bytes,2,rep,name=Hosts,json=hosts" json:"Hosts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Servers         []*NameServerConfig                         `protobuf:"bytes,3,rep,name=Servers,json=servers" json:"Servers,omitempty"`
+	FakeDNS         *FakeDNSConfig                              `protobuf:"bytes,4,opt,name=FakeDNS,json=fakeDNS" json:"FakeDNS,omitempty"`
+	Cache           *CacheConfig                                `protobuf:"bytes,5,opt,name=Cache,json=cache" json:"Cache,omitempty"`
+	QueryStrategy   QueryStrategy                               `protobuf:"varint,6,opt,name=QueryStrategy,json=queryStrategy,enum=v2ray.core.app.dns.QueryStrategy" json:"QueryStrategy,omitempty"`
+	ParallelQuery   bool                                        `protobuf:"varint,7,opt,name=ParallelQuery,json=parallelQuery" json:"ParallelQuery,omitempty"`
+	FailoverTimeout uint32                                      `protobuf:"varint,8,opt,name=FailoverTimeout,json=failoverTimeout" json:"FailoverTimeout,omitempty"`
+	TrustAnchors    []string                                    `protobuf:"bytes,9,rep,name=TrustAnchors,json=trustAnchors" json:"TrustAnchors,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
 func (m *Config) String() string            { return proto.CompactTextString(m) }
 func (*Config) ProtoMessage()               {}
-func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *Config) GetNameServers() []*v2ray_core_common_net2.DestinationPB {
 	if m != nil {
@@ -54,29 +133,78 @@ func (m *Config) GetHosts() map[string]*v2ray_core_common_net.AddressPB {
 	return nil
 }
 
+func (m *Config) GetServers() []*NameServerConfig {
+	if m != nil {
+		return m.Servers
+	}
+	return nil
+}
+
+func (m *Config) GetFakeDNS() *FakeDNSConfig {
+	if m != nil {
+		return m.FakeDNS
+	}
+	return nil
+}
+
+func (m *Config) GetCache() *CacheConfig {
+	if m != nil {
+		return m.Cache
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterType((*NameServerConfig)(nil), "v2ray.core.app.dns.NameServerConfig")
+	proto.RegisterType((*FakeDNSConfig)(nil), "v2ray.core.app.dns.FakeDNSConfig")
+	proto.RegisterType((*CacheConfig)(nil), "v2ray.core.app.dns.CacheConfig")
 	proto.RegisterType((*Config)(nil), "v2ray.core.app.dns.Config")
+	proto.RegisterEnum("v2ray.core.app.dns.QueryStrategy", QueryStrategy_name, QueryStrategy_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/app/dns/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 270 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x90, 0xcb, 0x4a, 0xc4, 0x30,
-	0x14, 0x86, 0x49, 0x4b, 0x07, 0x4c, 0x37, 0x92, 0x85, 0x94, 0xae, 0x8a, 0x17, 0x2c, 0x0a, 0x09,
-	0x54, 0x10, 0xd1, 0x95, 0xf5, 0x82, 0x2b, 0x29, 0x75, 0x37, 0xbb, 0xd8, 0x1c, 0xb5, 0x68, 0x4f,
-	0x4a, 0x12, 0x0b, 0x7d, 0x64, 0xdf, 0x42, 0xda, 0x20, 0x15, 0x9d, 0xd9, 0x1d, 0xc2, 0xf7, 0x5f,
-	0xf2, 0xd3, 0x83, 0xa1, 0x30, 0x72, 0xe4, 0x8d, 0xee, 0x44, 0xa3, 0x0d, 0x08, 0xd9, 0xf7, 0x42,
-	0xa1, 0x15, 0x8d, 0xc6, 0x97, 0xf6, 0x95, 0xf7, 0x46, 0x3b, 0xcd, 0xd8, 0x0f, 0x64, 0x80, 0xcb,
-	0xbe, 0xe7, 0x0a, 0x6d, 0x7a, 0xfc, 0x47, 0xd8, 0xe8, 0xae, 0xd3, 0x28, 0x10, 0x9c, 0x90, 0x4a,
-	0x19, 0xb0, 0xd6, 0x8b, 0xd3, 0xd3, 0xed, 0xa0, 0x02, 0xeb, 0x5a, 0x94, 0xae, 0xd5, 0xe8, 0xe1,
-	0xfd, 0x2f, 0x42, 0x57, 0x37, 0x73, 0x34, 0xbb, 0xa7, 0xf1, 0xa3, 0xec, 0xe0, 0x09, 0xcc, 0x00,
-	0xc6, 0x26, 0x24, 0x0b, 0xf3, 0xb8, 0x38, 0xe4, 0xbf, 0xaa, 0x78, 0x27, 0x8e, 0xe0, 0xf8, 0xed,
-	0xe2, 0x54, 0x95, 0x75, 0x8c, 0x8b, 0x90, 0x5d, 0xd1, 0xe8, 0x41, 0x5b, 0x67, 0x93, 0x60, 0x76,
-	0x38, 0xe2, 0xff, 0x3f, 0xc3, 0x7d, 0x24, 0x9f, 0xb9, 0x3b, 0x74, 0x66, 0xac, 0xa3, 0xb7, 0xe9,
-	0x4e, 0xd7, 0x94, 0x2e, 0x8f, 0x6c, 0x97, 0x86, 0xef, 0x30, 0x26, 0x24, 0x23, 0xf9, 0x4e, 0x3d,
-	0x9d, 0xec, 0x9c, 0x46, 0x83, 0xfc, 0xf8, 0x84, 0x24, 0xc8, 0x48, 0x1e, 0x17, 0xd9, 0x96, 0x7a,
-	0xd7, 0x7e, 0x91, 0xaa, 0xac, 0x3d, 0x7e, 0x19, 0x5c, 0x90, 0xf2, 0x84, 0xee, 0x35, 0xba, 0xdb,
-	0x50, 0xa7, 0x8c, 0x7d, 0x9f, 0x6a, 0x9a, 0x64, 0x1d, 0x2a, 0xb4, 0xcf, 0xab, 0x79, 0x9e, 0xb3,
-	0xef, 0x00, 0x00, 0x00, 0xff, 0xff, 0x17, 0x9a, 0x1d, 0xee, 0xaf, 0x01, 0x00, 0x00,
+	// 653 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xed, 0x6a, 0xdb, 0x3c,
+	0x14, 0x7e, 0x9d, 0x34, 0x5f, 0xc7, 0x4d, 0xdf, 0x20, 0x46, 0x31, 0x61, 0x30, 0x2f, 0xeb, 0xb6,
+	0xd0, 0x81, 0x03, 0xd9, 0x5a, 0xc6, 0x06, 0x83, 0xe6, 0xa3, 0x5b, 0xa0, 0xcd, 0x3c, 0xc5, 0xfd,
+	0xd3, 0x3f, 0x43, 0xb5, 0xb5, 0xd4, 0xd4, 0x96, 0x82, 0xac, 0x84, 0x66, 0xf7, 0xb5, 0x9b, 0xd9,
+	0x55, 0xec, 0x12, 0x86, 0x65, 0xa5, 0x6e, 0xd2, 0x14, 0xf6, 0xcb, 0x3a, 0x1f, 0xcf, 0x73, 0x8e,
+	0xcf, 0x79, 0x24, 0x78, 0xb1, 0xe8, 0x0a, 0xb2, 0x74, 0x7c, 0x1e, 0x77, 0x7c, 0x2e, 0x68, 0x87,
+	0xcc, 0x66, 0x9d, 0x80, 0x25, 0x1d, 0x9f, 0xb3, 0x1f, 0xe1, 0xd4, 0x99, 0x09, 0x2e, 0x39, 0x42,
+	0xab, 0x24, 0x41, 0x1d, 0x32, 0x9b, 0x39, 0x01, 0x4b, 0x9a, 0xaf, 0x37, 0x80, 0x3e, 0x8f, 0x63,
+	0xce, 0x3a, 0x8c, 0xca, 0x0e, 0x09, 0x02, 0x41, 0x93, 0x24, 0x03, 0x37, 0xdf, 0x3c, 0x9e, 0x18,
+	0xd0, 0x44, 0x86, 0x8c, 0xc8, 0x90, 0xb3, 0x2c, 0xb9, 0xf5, 0xc7, 0x80, 0xc6, 0x98, 0xc4, 0x74,
+	0x42, 0xc5, 0x82, 0x8a, 0xbe, 0x6a, 0x02, 0x7d, 0x82, 0xca, 0x49, 0x46, 0x69, 0x19, 0xb6, 0xd1,
+	0x36, 0xbb, 0x07, 0xce, 0xbd, 0x86, 0x32, 0x3e, 0x87, 0x51, 0xe9, 0x0c, 0x72, 0x3e, 0xb7, 0x87,
+	0x57, 0x20, 0xb4, 0x0f, 0xe5, 0x01, 0x8f, 0x49, 0xc8, 0xac, 0x82, 0x5d, 0x6c, 0xd7, 0xb0, 0xb6,
+	0x50, 0x03, 0x8a, 0x17, 0xf8, 0xcc, 0x2a, 0xda, 0x46, 0xbb, 0x86, 0xd3, 0x23, 0xb2, 0xc1, 0xfc,
+	0x3a, 0x97, 0x57, 0x7c, 0xce, 0x02, 0x8f, 0x4c, 0xad, 0x1d, 0x15, 0xb9, 0xef, 0x42, 0x4f, 0xa1,
+	0x36, 0xbc, 0x9d, 0x51, 0x5f, 0x8e, 0xdc, 0xc4, 0x2a, 0x29, 0xba, 0xdc, 0x81, 0x9a, 0x50, 0xed,
+	0x47, 0x21, 0x65, 0x72, 0xe4, 0x5a, 0x65, 0xdb, 0x68, 0xef, 0xe2, 0x3b, 0x5b, 0x75, 0x31, 0x9e,
+	0x4c, 0x86, 0x7d, 0xab, 0x62, 0x1b, 0xed, 0x2a, 0xd6, 0x56, 0xab, 0x0f, 0xf5, 0x53, 0x72, 0x43,
+	0x07, 0xe3, 0x89, 0xfe, 0xdd, 0x7d, 0x28, 0x8f, 0x5c, 0x97, 0xf3, 0x48, 0xfd, 0x6d, 0x0d, 0x6b,
+	0x2b, 0x25, 0x4f, 0xbf, 0x93, 0xf0, 0x27, 0xb5, 0x0a, 0xb6, 0xd1, 0xae, 0xe3, 0x3b, 0xbb, 0xf5,
+	0xcb, 0x00, 0xb3, 0x4f, 0xfc, 0x6b, 0xaa, 0x39, 0x9a, 0x50, 0x1d, 0x84, 0x09, 0xb9, 0x8a, 0x68,
+	0xa0, 0x58, 0xaa, 0xf8, 0xce, 0x4e, 0xf9, 0xcf, 0x43, 0xe6, 0x79, 0x67, 0x9a, 0x45, 0x5b, 0xca,
+	0x4f, 0x6e, 0x3d, 0x2f, 0x9b, 0x48, 0x1d, 0x6b, 0x0b, 0xbd, 0x82, 0xbd, 0x73, 0x72, 0x3b, 0xa6,
+	0x53, 0x22, 0xc3, 0x05, 0x4d, 0xe3, 0x3b, 0x2a, 0xbe, 0xe1, 0x45, 0x5d, 0x78, 0xa2, 0x6b, 0xac,
+	0xbc, 0xaa, 0x23, 0xab, 0xa4, 0xea, 0x6f, 0x8d, 0xb5, 0x7e, 0xef, 0x40, 0x59, 0xb7, 0x7c, 0x0a,
+	0x66, 0xbe, 0xf9, 0x74, 0xd3, 0xc5, 0x7f, 0xde, 0xf4, 0x7d, 0x20, 0xfa, 0x08, 0xa5, 0x2f, 0x3c,
+	0x91, 0x89, 0x5a, 0xb6, 0xd9, 0x7d, 0xe9, 0x3c, 0x14, 0xaf, 0x93, 0x95, 0x74, 0x54, 0xde, 0x90,
+	0x49, 0xb1, 0xc4, 0x19, 0x26, 0x95, 0xda, 0xaa, 0x81, 0xe2, 0xc3, 0x06, 0x56, 0xf0, 0x4d, 0x85,
+	0xe2, 0x4a, 0x5e, 0xbc, 0xa2, 0x97, 0xa9, 0x86, 0x64, 0x76, 0x9f, 0x6f, 0xc3, 0xaf, 0xed, 0x1b,
+	0xaf, 0x10, 0xe8, 0x08, 0x4a, 0xf9, 0xc4, 0xcc, 0xee, 0xb3, 0xad, 0x9d, 0xe7, 0x4b, 0xc6, 0x59,
+	0x36, 0xfa, 0x0c, 0xf5, 0x6f, 0x73, 0x2a, 0x96, 0x13, 0x29, 0x88, 0xa4, 0xd3, 0xa5, 0x52, 0xde,
+	0xde, 0xf6, 0xca, 0x6b, 0x89, 0x78, 0x1d, 0x87, 0x0e, 0xa0, 0xee, 0x12, 0x41, 0xa2, 0x88, 0x46,
+	0x2a, 0xa0, 0x85, 0xba, 0xee, 0x44, 0x6d, 0xf8, 0xff, 0x94, 0x84, 0x11, 0x5f, 0x50, 0xe1, 0x85,
+	0x31, 0xe5, 0x73, 0x69, 0x55, 0x95, 0x1e, 0x36, 0xdd, 0xa8, 0x05, 0xbb, 0x9e, 0x98, 0x27, 0xf2,
+	0x84, 0xf9, 0xd7, 0x5c, 0x24, 0x56, 0x4d, 0x5d, 0x97, 0x35, 0x5f, 0xf3, 0x12, 0x20, 0xdf, 0x42,
+	0x7a, 0x23, 0x6f, 0xe8, 0x52, 0xeb, 0x3e, 0x3d, 0xa2, 0x63, 0x28, 0x2d, 0x48, 0x34, 0xcf, 0x14,
+	0x6f, 0x76, 0xed, 0x47, 0xf4, 0xa0, 0xaf, 0xba, 0xdb, 0xc3, 0x59, 0xfa, 0x87, 0xc2, 0x7b, 0xe3,
+	0xf0, 0x68, 0x63, 0x30, 0x08, 0xa0, 0x7c, 0x31, 0x19, 0x7e, 0x1f, 0xb9, 0x8d, 0xff, 0x90, 0x09,
+	0x95, 0xec, 0xfc, 0xae, 0x61, 0xe4, 0xc6, 0x71, 0xa3, 0xd0, 0x3b, 0x84, 0x7d, 0x9f, 0xc7, 0x5b,
+	0xa6, 0xd7, 0x33, 0xb3, 0xc1, 0xbb, 0xe9, 0x53, 0x75, 0x59, 0x0c, 0x58, 0x72, 0x55, 0x56, 0xcf,
+	0xd6, 0xdb, 0xbf, 0x03, 0x00, 0xf6, 0xa6, 0x76, 0x5b, 0x47, 0x05, 0x00, 0x00,
 }
@@ -4,31 +4,250 @@ package dns
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
 
+	"v2ray.com/core/common/collect"
+	"v2ray.com/core/common/loader"
 	v2net "v2ray.com/core/common/net"
 )
 
+func newNameServerConfig(address *v2net.AddressPB, port uint16) *NameServerConfig {
+	if addr := address.AsAddress(); addr.Family().IsDomain() && (IsDoHURL(addr.Domain()) || IsDoTURL(addr.Domain()) || IsTCPURL(addr.Domain())) {
+		return &NameServerConfig{
+			URL: addr.Domain(),
+		}
+	}
+	if port == 0 {
+		port = 53
+	}
+	return &NameServerConfig{
+		Address: &v2net.DestinationPB{
+			Network: v2net.Network_UDP,
+			Address: address,
+			Port:    uint32(port),
+		},
+	}
+}
+
+// parseNameServer parses an entry of "servers", which is either the address or DoH/DoT/TCP URL of a name server, or an object
+// with the address and options of a name server.
+func parseNameServer(data []byte) (*NameServerConfig, error) {
+	address := new(v2net.AddressPB)
+	if err := json.Unmarshal(data, address); err == nil {
+		return newNameServerConfig(address, 0), nil
+	}
+	type JsonNameServer struct {
+		Address     *v2net.AddressPB    `json:"address"`
+		Port        uint16              `json:"port"`
+		Domains     *collect.StringList `json:"domains"`
+		OutboundTag string              `json:"outboundTag"`
+		ExpectIPs   *collect.StringList `json:"expectIPs"`
+		ClientIP    string              `json:"clientIp"`
+		DNSSEC      bool                `json:"dnssec"`
+	}
+	jsonServer := new(JsonNameServer)
+	if err := loader.DecodeJSON(data, jsonServer); err != nil {
+		return nil, err
+	}
+	if jsonServer.Address == nil {
+		return nil, errors.New("DNS: Name server address is not specified.")
+	}
+	config := newNameServerConfig(jsonServer.Address, jsonServer.Port)
+	if jsonServer.Domains != nil {
+		config.Domain = *jsonServer.Domains
+	}
+	config.OutboundTag = jsonServer.OutboundTag
+	config.DNSSEC = jsonServer.DNSSEC
+	if jsonServer.ExpectIPs != nil {
+		config.ExpectIPs = *jsonServer.ExpectIPs
+	}
+	if len(jsonServer.ClientIP) > 0 {
+		clientIP := net.ParseIP(jsonServer.ClientIP)
+		if clientIP == nil {
+			return nil, errors.New("DNS: Invalid client IP: " + jsonServer.ClientIP)
+		}
+		if ip4 := clientIP.To4(); ip4 != nil {
+			clientIP = ip4
+		}
+		config.ClientIP = []byte(clientIP)
+	}
+	return config, nil
+}
+
 func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonFakeDNS struct {
+		IPPool   string `json:"ipPool"`
+		PoolSize uint32 `json:"poolSize"`
+	}
+	type JsonCache struct {
+		Disabled             bool   `json:"disabled"`
+		MinTTL               uint32 `json:"minTTL"`
+		MaxTTL               uint32 `json:"maxTTL"`
+		MaxNegativeTTL       uint32 `json:"maxNegativeTTL"`
+		DisableNegativeCache bool   `json:"disableNegativeCache"`
+	}
 	type JsonConfig struct {
-		Servers []*v2net.AddressPB          `json:"servers"`
-		Hosts   map[string]*v2net.AddressPB `json:"hosts"`
+		Servers         []json.RawMessage           `json:"servers"`
+		Hosts           map[string]*v2net.AddressPB `json:"hosts"`
+		FakeDNS         *JsonFakeDNS                `json:"fakedns"`
+		Cache           *JsonCache                  `json:"cache"`
+		QueryStrategy   string                      `json:"queryStrategy"`
+		ParallelQuery   bool                        `json:"parallelQuery"`
+		FailoverTimeout uint32                      `json:"failoverTimeout"`
+		TrustAnchors    *collect.StringList         `json:"trustAnchors"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
 		return err
 	}
-	this.NameServers = make([]*v2net.DestinationPB, len(jsonConfig.Servers))
-	for idx, server := range jsonConfig.Servers {
-		this.NameServers[idx] = &v2net.DestinationPB{
-			Network: v2net.Network_UDP,
-			Address: server,
-			Port:    53,
+	this.Servers = make([]*NameServerConfig, len(jsonConfig.Servers))
+	for idx, rawServer := range jsonConfig.Servers {
+		server, err := parseNameServer(rawServer)
+		if err != nil {
+			if unknown, ok := err.(*loader.UnknownFieldError); ok {
+				return unknown.Under("servers", "["+strconv.Itoa(idx)+"]")
+			}
+			return errors.New("DNS: Invalid name server: " + err.Error())
 		}
+		this.Servers[idx] = server
 	}
 
 	if jsonConfig.Hosts != nil {
 		this.Hosts = jsonConfig.Hosts
 	}
 
+	if jsonConfig.FakeDNS != nil {
+		this.FakeDNS = &FakeDNSConfig{
+			IPPool:   jsonConfig.FakeDNS.IPPool,
+			PoolSize: jsonConfig.FakeDNS.PoolSize,
+		}
+	}
+
+	switch strings.ToLower(jsonConfig.QueryStrategy) {
+	case "", "useip", "use_ip":
+		this.QueryStrategy = QueryStrategy_USE_IP
+	case "useipv4", "use_ip4":
+		this.QueryStrategy = QueryStrategy_USE_IP4
+	case "useipv6", "use_ip6":
+		this.QueryStrategy = QueryStrategy_USE_IP6
+	default:
+		return errors.New("DNS: Unknown query strategy: " + jsonConfig.QueryStrategy)
+	}
+
+	this.ParallelQuery = jsonConfig.ParallelQuery
+	this.FailoverTimeout = jsonConfig.FailoverTimeout
+
+	if jsonConfig.TrustAnchors != nil {
+		if _, err := ParseTrustAnchors(*jsonConfig.TrustAnchors); err != nil {
+			return errors.New("DNS: Invalid trust anchors: " + err.Error())
+		}
+		this.TrustAnchors = *jsonConfig.TrustAnchors
+	}
+
+	if jsonConfig.Cache != nil {
+		if jsonConfig.Cache.MaxTTL > 0 && jsonConfig.Cache.MinTTL > jsonConfig.Cache.MaxTTL {
+			return errors.New("DNS: minTTL is larger than maxTTL.")
+		}
+		this.Cache = &CacheConfig{
+			Disabled:             jsonConfig.Cache.Disabled,
+			MinTTL:               jsonConfig.Cache.MinTTL,
+			MaxTTL:               jsonConfig.Cache.MaxTTL,
+			MaxNegativeTTL:       jsonConfig.Cache.MaxNegativeTTL,
+			DisableNegativeCache: jsonConfig.Cache.DisableNegativeCache,
+		}
+	}
+
 	return nil
 }
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *NameServerConfig) MarshalJSON() ([]byte, error) {
+	if len(this.URL) > 0 {
+		return json.Marshal(this.URL)
+	}
+	type JsonNameServer struct {
+		Address     *v2net.AddressPB `json:"address"`
+		Port        uint32           `json:"port,omitempty"`
+		Domains     []string         `json:"domains,omitempty"`
+		OutboundTag string           `json:"outboundTag,omitempty"`
+		ExpectIPs   []string         `json:"expectIPs,omitempty"`
+		ClientIP    string           `json:"clientIp,omitempty"`
+		DNSSEC      bool             `json:"dnssec,omitempty"`
+	}
+	jsonServer := &JsonNameServer{
+		Domains:     this.Domain,
+		OutboundTag: this.OutboundTag,
+		ExpectIPs:   this.ExpectIPs,
+		DNSSEC:      this.DNSSEC,
+	}
+	if this.Address != nil {
+		jsonServer.Address = this.Address.Address
+		jsonServer.Port = this.Address.Port
+	}
+	if len(this.ClientIP) > 0 {
+		jsonServer.ClientIP = net.IP(this.ClientIP).String()
+	}
+	return json.Marshal(jsonServer)
+}
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON. Name servers in NameServers are encoded as servers.
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type JsonFakeDNS struct {
+		IPPool   string `json:"ipPool,omitempty"`
+		PoolSize uint32 `json:"poolSize,omitempty"`
+	}
+	type JsonCache struct {
+		Disabled             bool   `json:"disabled,omitempty"`
+		MinTTL               uint32 `json:"minTTL,omitempty"`
+		MaxTTL               uint32 `json:"maxTTL,omitempty"`
+		MaxNegativeTTL       uint32 `json:"maxNegativeTTL,omitempty"`
+		DisableNegativeCache bool   `json:"disableNegativeCache,omitempty"`
+	}
+	type JsonConfig struct {
+		Servers         []*NameServerConfig         `json:"servers,omitempty"`
+		Hosts           map[string]*v2net.AddressPB `json:"hosts,omitempty"`
+		FakeDNS         *JsonFakeDNS                `json:"fakedns,omitempty"`
+		Cache           *JsonCache                  `json:"cache,omitempty"`
+		QueryStrategy   string                      `json:"queryStrategy,omitempty"`
+		ParallelQuery   bool                        `json:"parallelQuery,omitempty"`
+		FailoverTimeout uint32                      `json:"failoverTimeout,omitempty"`
+		TrustAnchors    []string                    `json:"trustAnchors,omitempty"`
+	}
+	jsonConfig := &JsonConfig{
+		Hosts:           this.Hosts,
+		ParallelQuery:   this.ParallelQuery,
+		FailoverTimeout: this.FailoverTimeout,
+		TrustAnchors:    this.TrustAnchors,
+	}
+	for _, nameServer := range this.NameServers {
+		jsonConfig.Servers = append(jsonConfig.Servers, &NameServerConfig{
+			Address: nameServer,
+		})
+	}
+	jsonConfig.Servers = append(jsonConfig.Servers, this.Servers...)
+	if this.FakeDNS != nil {
+		jsonConfig.FakeDNS = &JsonFakeDNS{
+			IPPool:   this.FakeDNS.IPPool,
+			PoolSize: this.FakeDNS.PoolSize,
+		}
+	}
+	if this.Cache != nil {
+		jsonConfig.Cache = &JsonCache{
+			Disabled:             this.Cache.Disabled,
+			MinTTL:               this.Cache.MinTTL,
+			MaxTTL:               this.Cache.MaxTTL,
+			MaxNegativeTTL:       this.Cache.MaxNegativeTTL,
+			DisableNegativeCache: this.Cache.DisableNegativeCache,
+		}
+	}
+	switch this.QueryStrategy {
+	case QueryStrategy_USE_IP4:
+		jsonConfig.QueryStrategy = "useipv4"
+	case QueryStrategy_USE_IP6:
+		jsonConfig.QueryStrategy = "useipv6"
+	}
+	return json.Marshal(jsonConfig)
+}
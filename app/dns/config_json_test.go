@@ -21,9 +21,124 @@ func TestConfigParsing(t *testing.T) {
 	config := new(Config)
 	err := json.Unmarshal([]byte(rawJson), config)
 	assert.Error(err).IsNil()
-	assert.Int(len(config.NameServers)).Equals(1)
-	dest := config.NameServers[0].AsDestination()
+	assert.Int(len(config.Servers)).Equals(1)
+	dest := config.Servers[0].Address.AsDestination()
 	assert.Destination(dest).IsUDP()
 	assert.Address(dest.Address).Equals(v2net.IPAddress([]byte{8, 8, 8, 8}))
 	assert.Port(dest.Port).Equals(v2net.Port(53))
 }
+
+func TestNameServerParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "servers": [
+      {
+        "address": "114.114.114.114",
+        "domains": ["geosite:cn", "domain:baidu.com"],
+        "expectIPs": ["geoip:cn", "10.0.0.0/8"]
+      },
+      "https://1.1.1.1/dns-query",
+      {
+        "address": "https://dns.google/dns-query",
+        "outboundTag": "proxy",
+        "clientIp": "1.2.3.4"
+      },
+      {
+        "address": "1.1.1.1",
+        "port": 5353
+      },
+      "tls://1.1.1.1:853",
+      {
+        "address": "tcp://8.8.8.8:53",
+        "outboundTag": "proxy"
+      }
+    ]
+  }`
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(rawJson), config)
+	assert.Error(err).IsNil()
+	assert.Int(len(config.Servers)).Equals(6)
+	dest := config.Servers[0].Address.AsDestination()
+	assert.Address(dest.Address).Equals(v2net.IPAddress([]byte{114, 114, 114, 114}))
+	assert.Port(dest.Port).Equals(v2net.Port(53))
+	assert.Int(len(config.Servers[0].Domain)).Equals(2)
+	assert.Int(len(config.Servers[0].ExpectIPs)).Equals(2)
+	assert.String(config.Servers[0].ExpectIPs[0]).Equals("geoip:cn")
+	assert.String(config.Servers[1].URL).Equals("https://1.1.1.1/dns-query")
+	assert.Pointer(config.Servers[1].Address).IsNil()
+	assert.String(config.Servers[2].URL).Equals("https://dns.google/dns-query")
+	assert.String(config.Servers[2].OutboundTag).Equals("proxy")
+	assert.Bytes(config.Servers[2].ClientIP).Equals([]byte{1, 2, 3, 4})
+	assert.Port(config.Servers[3].Address.AsDestination().Port).Equals(v2net.Port(5353))
+	assert.String(config.Servers[4].URL).Equals("tls://1.1.1.1:853")
+	assert.String(config.Servers[5].URL).Equals("tcp://8.8.8.8:53")
+	assert.String(config.Servers[5].OutboundTag).Equals("proxy")
+
+	err = json.Unmarshal([]byte(`{"servers": [{"port": 53}]}`), new(Config))
+	assert.Error(err).IsNotNil()
+}
+
+func TestFakeDNSParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"fakedns": {"ipPool": "198.18.0.0/16"}}`), config)
+	assert.Error(err).IsNil()
+	assert.String(config.FakeDNS.IPPool).Equals("198.18.0.0/16")
+	assert.Uint32(config.FakeDNS.PoolSize).Equals(0)
+}
+
+func TestCacheParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"cache": {"minTTL": 60, "maxTTL": 3600, "disableNegativeCache": true}}`), config)
+	assert.Error(err).IsNil()
+	assert.Uint32(config.Cache.MinTTL).Equals(60)
+	assert.Uint32(config.Cache.MaxTTL).Equals(3600)
+	assert.Bool(config.Cache.DisableNegativeCache).IsTrue()
+	assert.Bool(config.Cache.Disabled).IsFalse()
+
+	err = json.Unmarshal([]byte(`{"cache": {"minTTL": 600, "maxTTL": 60}}`), new(Config))
+	assert.Error(err).IsNotNil()
+}
+
+func TestQueryStrategyParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"queryStrategy": "UseIPv4"}`), config)
+	assert.Error(err).IsNil()
+	assert.Bool(config.QueryStrategy == QueryStrategy_USE_IP4).IsTrue()
+
+	err = json.Unmarshal([]byte(`{"queryStrategy": "UseIPv5"}`), new(Config))
+	assert.Error(err).IsNotNil()
+}
+
+func TestFailoverParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"parallelQuery": true, "failoverTimeout": 500}`), config)
+	assert.Error(err).IsNil()
+	assert.Bool(config.ParallelQuery).IsTrue()
+	assert.Int(int(config.FailoverTimeout)).Equals(500)
+}
+
+func TestDNSSECParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{
+    "servers": [{"address": "8.8.8.8", "dnssec": true}],
+    "trustAnchors": [". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"]
+  }`), config)
+	assert.Error(err).IsNil()
+	assert.Bool(config.Servers[0].DNSSEC).IsTrue()
+	assert.Int(len(config.TrustAnchors)).Equals(1)
+
+	err = json.Unmarshal([]byte(`{"trustAnchors": ["v2ray.com. IN A 10.0.0.1"]}`), new(Config))
+	assert.Error(err).IsNotNil()
+}
@@ -4,6 +4,7 @@ import (
 	"net"
 
 	"v2ray.com/core/app"
+	"v2ray.com/core/common"
 )
 
 const (
@@ -14,3 +15,75 @@ const (
 type Server interface {
 	Get(domain string) []net.IP
 }
+
+// ValidatingServer is implemented by Servers that validate answers with DNSSEC. GetValidated returns the IPs of a domain
+// as Get does, and true if the IPs are validated.
+type ValidatingServer interface {
+	GetValidated(domain string) ([]net.IP, bool)
+}
+
+// LookupServer is implemented by Servers that tell domains that don't exist from those without IPs. Lookup returns the
+// IPs of a domain as Get does, and true if the name server answered that the domain does not exist (NXDOMAIN).
+type LookupServer interface {
+	Lookup(domain string) ([]net.IP, bool)
+}
+
+// ReverseResolver is implemented by Servers that look up the domains of IPs. ReverseLookup returns the domains without
+// the trailing dot, or nil if none is found.
+type ReverseResolver interface {
+	ReverseLookup(ip net.IP) []string
+}
+
+// CacheFlusher is implemented by Servers that cache resolutions. FlushCache removes the cached resolutions of a
+// domain, or all of them if the domain is empty.
+type CacheFlusher interface {
+	FlushCache(domain string)
+}
+
+// Reloadable is implemented by Servers that can replace their name servers and settings at runtime, without restarting
+// the apps depending on them. The cache is flushed on reload.
+type Reloadable interface {
+	Reload(config *Config) error
+	// PrepareReload validates the config without changing the server, and returns the function that applies it.
+	PrepareReload(config *Config) (func(), error)
+}
+
+// DomainMatcher matches domains in lower case, without the trailing dot.
+type DomainMatcher interface {
+	Match(domain string) bool
+}
+
+// DomainMatcherFactory creates a DomainMatcher from a domain rule of DomainNameServers.
+type DomainMatcherFactory func(rule string) (DomainMatcher, error)
+
+var (
+	domainMatcherFactory DomainMatcherFactory
+	ipMatcherFactory     IPMatcherFactory
+)
+
+// RegisterDomainMatcherFactory sets the factory of domain rules in DomainNameServers. The router registers the domain
+// rules of routing, so that both use the same syntax.
+func RegisterDomainMatcherFactory(factory DomainMatcherFactory) error {
+	if domainMatcherFactory != nil {
+		return common.ErrDuplicatedName
+	}
+	domainMatcherFactory = factory
+	return nil
+}
+
+type IPMatcher interface {
+	Match(ip net.IP) bool
+}
+
+// IPMatcherFactory creates an IPMatcher from an entry of expected IPs, e.g. a CIDR or "geoip:cn".
+type IPMatcherFactory func(rule string) (IPMatcher, error)
+
+// RegisterIPMatcherFactory sets the factory of expected IPs of name servers. The router registers the IP rules of
+// routing.
+func RegisterIPMatcherFactory(factory IPMatcherFactory) error {
+	if ipMatcherFactory != nil {
+		return common.ErrDuplicatedName
+	}
+	ipMatcherFactory = factory
+	return nil
+}
@@ -0,0 +1,457 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// MaxKeyTTL is the maximum time in seconds that validated DNSKEYs of a zone are cached.
+	MaxKeyTTL = uint32(3600)
+
+	maxCNAMEChain = 8
+)
+
+var (
+	// RootTrustAnchors are the DS records of the root key signing keys published by IANA.
+	RootTrustAnchors = []string{
+		". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+		". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+	}
+
+	ErrDNSSECUnsupported = errors.New("DNS: DNSSEC is not supported by the name server.")
+	ErrDNSSECBogus       = errors.New("DNS: Answer failed DNSSEC validation.")
+)
+
+// msgExchanger is implemented by name servers that send arbitrary queries.
+type msgExchanger interface {
+	Exchange(query *dns.Msg) (*dns.Msg, error)
+}
+
+// ParseTrustAnchors parses DS records of the root zone in presentation format.
+func ParseTrustAnchors(anchors []string) ([]*dns.DS, error) {
+	records := make([]*dns.DS, 0, len(anchors))
+	for _, anchor := range anchors {
+		rr, err := dns.NewRR(anchor)
+		if err != nil {
+			return nil, err
+		}
+		ds, ok := rr.(*dns.DS)
+		if !ok || ds.Hdr.Name != "." {
+			return nil, errors.New("DNS: Trust anchor is not a DS record of the root zone: " + anchor)
+		}
+		records = append(records, ds)
+	}
+	return records, nil
+}
+
+// setDNSSECOK asks the server for the DNSSEC records of answers.
+func setDNSSECOK(msg *dns.Msg) {
+	if opt := msg.IsEdns0(); opt != nil {
+		opt.SetDo()
+		opt.SetUDPSize(dns.DefaultMsgSize)
+		return
+	}
+	msg.SetEdns0(dns.DefaultMsgSize, true)
+}
+
+// findRRSet returns the records of the given name and type, and the signatures of them.
+func findRRSet(records []dns.RR, name string, rrtype uint16) ([]dns.RR, []*dns.RRSIG) {
+	var rrset []dns.RR
+	var sigs []*dns.RRSIG
+	for _, rr := range records {
+		header := rr.Header()
+		if !strings.EqualFold(header.Name, name) {
+			continue
+		}
+		if header.Rrtype == rrtype {
+			rrset = append(rrset, rr)
+		} else if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == rrtype {
+			sigs = append(sigs, sig)
+		}
+	}
+	return rrset, sigs
+}
+
+// verifyRRSet returns nil if any of the signatures is valid now and made by any of the keys.
+func verifyRRSet(rrset []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY) error {
+	now := time.Now()
+	for _, sig := range sigs {
+		if !sig.ValidityPeriod(now) {
+			continue
+		}
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm || !strings.EqualFold(key.Hdr.Name, sig.SignerName) {
+				continue
+			}
+			if sig.Verify(key, rrset) == nil {
+				return nil
+			}
+		}
+	}
+	return ErrDNSSECBogus
+}
+
+// hasType returns true if the type bitmap of an NSEC or NSEC3 record has the type.
+func hasType(bitmap []uint16, rrtype uint16) bool {
+	for _, t := range bitmap {
+		if t == rrtype {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalCompare compares two names in the canonical order of RFC 4034 section 6.1.
+func canonicalCompare(a, b string) int {
+	labelsA := dns.SplitDomainName(strings.ToLower(a))
+	labelsB := dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(labelsA)-1, len(labelsB)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(labelsA[i], labelsB[j]); c != 0 {
+			return c
+		}
+	}
+	return len(labelsA) - len(labelsB)
+}
+
+// nsecCovers returns true if the name is between the owner and the next name of the NSEC record, i.e. it doesn't
+// exist.
+func nsecCovers(nsec *dns.NSEC, name string) bool {
+	owner, next := nsec.Hdr.Name, nsec.NextDomain
+	if canonicalCompare(owner, next) < 0 {
+		return canonicalCompare(owner, name) < 0 && canonicalCompare(name, next) < 0
+	}
+	// The last NSEC record of a zone points back to its apex.
+	return dns.IsSubDomain(next, name) && canonicalCompare(owner, name) < 0
+}
+
+func parentOf(name string) string {
+	labels := dns.Split(name)
+	if len(labels) <= 1 {
+		return "."
+	}
+	return name[labels[1]:]
+}
+
+type zoneKeys struct {
+	// zone is the closest enclosing zone of the name, and keys are its DNSKEYs. Keys are nil if the zone is not signed.
+	zone   string
+	keys   []*dns.DNSKEY
+	expire time.Time
+}
+
+// dnssecValidator validates answers by the chain of trust from the root trust anchors. The DNSKEY and DS records on
+// the chain are queried from the same name server as the answers.
+type dnssecValidator struct {
+	sync.Mutex
+	anchors  []*dns.DS
+	exchange func(query *dns.Msg) (*dns.Msg, error)
+	// zones are the enclosing zones by name.
+	zones  map[string]*zoneKeys
+	logger *log.Logger
+}
+
+func newDNSSECValidator(anchors []*dns.DS, exchange func(query *dns.Msg) (*dns.Msg, error), logger *log.Logger) *dnssecValidator {
+	return &dnssecValidator{
+		anchors:  anchors,
+		exchange: exchange,
+		zones:    make(map[string]*zoneKeys),
+		logger:   logger,
+	}
+}
+
+func (this *dnssecValidator) query(name string, qtype uint16) (*dns.Msg, error) {
+	query := buildQuery(name, 0, qtype, nil)
+	setDNSSECOK(query)
+	response, err := this.exchange(query)
+	if err != nil {
+		return nil, err
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, errors.New("DNS: Failed to query " + dns.TypeToString[qtype] + " of " + name + ": " + dns.RcodeToString[response.Rcode])
+	}
+	return response, nil
+}
+
+func (this *dnssecValidator) cachedZone(name string) (*zoneKeys, bool) {
+	this.Lock()
+	defer this.Unlock()
+
+	cached, found := this.zones[name]
+	if !found || !cached.expire.After(time.Now()) {
+		return nil, false
+	}
+	return cached, true
+}
+
+func (this *dnssecValidator) cacheZone(name string, zone string, keys []*dns.DNSKEY, ttl uint32) *zoneKeys {
+	if ttl > MaxKeyTTL {
+		ttl = MaxKeyTTL
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	cached := &zoneKeys{
+		zone:   zone,
+		keys:   keys,
+		expire: time.Now().Add(time.Second * time.Duration(ttl)),
+	}
+	this.zones[name] = cached
+	return cached
+}
+
+// keysOf returns the DNSKEYs of a zone, validated by its DS records.
+func (this *dnssecValidator) keysOf(zone string, dsRecords []*dns.DS) ([]*dns.DNSKEY, uint32, error) {
+	response, err := this.query(zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, 0, err
+	}
+	rrset, sigs := findRRSet(response.Answer, zone, dns.TypeDNSKEY)
+	keys := make([]*dns.DNSKEY, 0, len(rrset))
+	var trusted []*dns.DNSKEY
+	ttl := MaxKeyTTL
+	for _, rr := range rrset {
+		key := rr.(*dns.DNSKEY)
+		keys = append(keys, key)
+		if rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+		for _, ds := range dsRecords {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+				continue
+			}
+			if digest := key.ToDS(ds.DigestType); digest != nil && strings.EqualFold(digest.Digest, ds.Digest) {
+				trusted = append(trusted, key)
+			}
+		}
+	}
+	if len(trusted) == 0 {
+		this.logger.Warning("DNS: No DNSKEY of zone ", zone, " matches its DS records.")
+		return nil, 0, ErrDNSSECBogus
+	}
+	if err := verifyRRSet(rrset, sigs, trusted); err != nil {
+		return nil, 0, err
+	}
+	return keys, ttl, nil
+}
+
+// denyDS validates the proof in the authority section of a response that the name has no DS records, with the keys of
+// its enclosing zone. It returns true if the name is a delegation to a zone not signed, and false if the name is in the
+// enclosing zone. Opt-out NSEC3 records covering the name are taken as delegations, without checking the closest
+// encloser.
+func (this *dnssecValidator) denyDS(response *dns.Msg, name string, parent *zoneKeys) (bool, uint32, error) {
+	for _, rr := range response.Ns {
+		var delegation bool
+		switch nsec := rr.(type) {
+		case *dns.NSEC:
+			if strings.EqualFold(nsec.Hdr.Name, name) {
+				if hasType(nsec.TypeBitMap, dns.TypeDS) {
+					return false, 0, ErrDNSSECBogus
+				}
+				delegation = hasType(nsec.TypeBitMap, dns.TypeNS) && !hasType(nsec.TypeBitMap, dns.TypeSOA)
+			} else if !nsecCovers(nsec, name) {
+				continue
+			}
+		case *dns.NSEC3:
+			if nsec.Match(name) {
+				if hasType(nsec.TypeBitMap, dns.TypeDS) {
+					return false, 0, ErrDNSSECBogus
+				}
+				delegation = hasType(nsec.TypeBitMap, dns.TypeNS) && !hasType(nsec.TypeBitMap, dns.TypeSOA)
+			} else if nsec.Cover(name) {
+				delegation = nsec.Flags&1 == 1
+			} else {
+				continue
+			}
+		default:
+			continue
+		}
+		rrset, sigs := findRRSet(response.Ns, rr.Header().Name, rr.Header().Rrtype)
+		if err := verifyRRSet(rrset, sigs, parent.keys); err != nil {
+			return false, 0, err
+		}
+		return delegation, rr.Header().Ttl, nil
+	}
+	this.logger.Warning("DNS: No proof that ", name, " has no DS records.")
+	return false, 0, ErrDNSSECBogus
+}
+
+// zoneOf returns the closest enclosing zone of the name and its validated DNSKEYs, walking down from the root by the
+// DS records of each ancestor. Keys are nil if the name is under a delegation that is proven not signed. A missing DS
+// record without a valid denial of existence from the signed parent zone is bogus.
+func (this *dnssecValidator) zoneOf(name string) (*zoneKeys, error) {
+	name = dns.Fqdn(strings.ToLower(name))
+	if cached, found := this.cachedZone(name); found {
+		return cached, nil
+	}
+	if name == "." {
+		keys, ttl, err := this.keysOf(name, this.anchors)
+		if err != nil {
+			return nil, err
+		}
+		return this.cacheZone(name, name, keys, ttl), nil
+	}
+
+	parent, err := this.zoneOf(parentOf(name))
+	if err != nil {
+		return nil, err
+	}
+	if parent.keys == nil {
+		return this.cacheZone(name, parent.zone, nil, DefaultNegativeTTL), nil
+	}
+
+	response, err := this.query(name, dns.TypeDS)
+	if err != nil {
+		return nil, err
+	}
+	rrset, sigs := findRRSet(response.Answer, name, dns.TypeDS)
+	if len(rrset) == 0 {
+		delegation, ttl, err := this.denyDS(response, name, parent)
+		if err != nil {
+			return nil, err
+		}
+		if delegation {
+			this.logger.Debug("DNS: Zone ", name, " is not signed.")
+			return this.cacheZone(name, name, nil, ttl), nil
+		}
+		return this.cacheZone(name, parent.zone, parent.keys, ttl), nil
+	}
+	if err := verifyRRSet(rrset, sigs, parent.keys); err != nil {
+		return nil, err
+	}
+	dsRecords := make([]*dns.DS, 0, len(rrset))
+	ttl := MaxKeyTTL
+	for _, rr := range rrset {
+		dsRecords = append(dsRecords, rr.(*dns.DS))
+		if rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	keys, keyTTL, err := this.keysOf(name, dsRecords)
+	if err != nil {
+		return nil, err
+	}
+	if keyTTL < ttl {
+		ttl = keyTTL
+	}
+	return this.cacheZone(name, name, keys, ttl), nil
+}
+
+// verify validates an RRset of an answer. It returns false if the name is under a zone proven not signed, and
+// ErrDNSSECBogus if the RRset is not signed by its signed zone.
+func (this *dnssecValidator) verify(name string, rrset []dns.RR, sigs []*dns.RRSIG) (bool, error) {
+	zone, err := this.zoneOf(name)
+	if err != nil {
+		return false, err
+	}
+	if zone.keys == nil {
+		return false, nil
+	}
+	if err := verifyRRSet(rrset, sigs, zone.keys); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Validate validates the answer of a query, following the CNAME records in it. It returns true if all records on the
+// way to the answer are validated, false if any of them is in a zone not signed, and ErrDNSSECBogus if any signature
+// is invalid or missing. Negative answers are never validated, as denial of existence of answers is not checked.
+func (this *dnssecValidator) Validate(response *dns.Msg) (bool, error) {
+	if len(response.Question) != 1 {
+		return false, nil
+	}
+	name := response.Question[0].Name
+	qtype := response.Question[0].Qtype
+	for i := 0; i < maxCNAMEChain; i++ {
+		if rrset, sigs := findRRSet(response.Answer, name, qtype); len(rrset) > 0 {
+			return this.verify(name, rrset, sigs)
+		}
+		rrset, sigs := findRRSet(response.Answer, name, dns.TypeCNAME)
+		if len(rrset) == 0 {
+			return false, nil
+		}
+		validated, err := this.verify(name, rrset, sigs)
+		if err != nil || !validated {
+			return false, err
+		}
+		name = rrset[0].(*dns.CNAME).Target
+	}
+	return false, nil
+}
+
+// dnssecNameServer validates the answers of a name server with DNSSEC. Answers with invalid or missing signatures are
+// discarded, so that the next server is queried. Answers of zones proven not signed are kept, but not marked as
+// validated.
+type dnssecNameServer struct {
+	name      string
+	exchanger msgExchanger
+	validator *dnssecValidator
+	clientIP  net.IP
+	logger    *log.Logger
+}
+
+func newDNSSECNameServer(server NameServer, anchors []*dns.DS, logger *log.Logger) (NameServer, error) {
+	exchanger, ok := server.(msgExchanger)
+	if !ok {
+		return nil, ErrDNSSECUnsupported
+	}
+	return &dnssecNameServer{
+		name:      server.Name(),
+		exchanger: exchanger,
+		validator: newDNSSECValidator(anchors, exchanger.Exchange, logger),
+		logger:    logger,
+	}, nil
+}
+
+// SetClientIP sets the IP sent in the EDNS Client Subnet option of queries.
+func (this *dnssecNameServer) SetClientIP(ip net.IP) {
+	this.clientIP = ip
+}
+
+// Exchange sends a query to the name server as is. The response is not validated.
+func (this *dnssecNameServer) Exchange(query *dns.Msg) (*dns.Msg, error) {
+	return this.exchanger.Exchange(query)
+}
+
+func (this *dnssecNameServer) query(domain string, qtype uint16) (*ARecord, error) {
+	query := buildQuery(domain, 0, qtype, this.clientIP)
+	setDNSSECOK(query)
+	response, err := this.exchanger.Exchange(query)
+	if err != nil {
+		return nil, err
+	}
+	record := parseARecord(response)
+	if record == nil || len(record.IPs) == 0 {
+		return record, nil
+	}
+	validated, err := this.validator.Validate(response)
+	if err != nil {
+		this.logger.Warning("DNS: Discarding answer of ", domain, " from ", this.name, ": ", err)
+		return nil, err
+	}
+	if !validated {
+		this.logger.Debug("DNS: Answer of ", domain, " from ", this.name, " is not signed.")
+	}
+	record.Validated = validated
+	return record, nil
+}
+
+func (this *dnssecNameServer) Name() string {
+	return this.name
+}
+
+func (this *dnssecNameServer) QueryA(domain string) <-chan *ARecord {
+	return queryAsync(this.name, domain, dns.TypeA, this.query)
+}
+
+func (this *dnssecNameServer) QueryAAAA(domain string) <-chan *ARecord {
+	return queryAsync(this.name, domain, dns.TypeAAAA, this.query)
+}
@@ -0,0 +1,237 @@
+package dns_test
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	. "v2ray.com/core/app/dns"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+
+	"github.com/miekg/dns"
+)
+
+type zoneSigner struct {
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newZoneSigner(zone string) *zoneSigner {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		panic(err)
+	}
+	return &zoneSigner{
+		key:  key,
+		priv: priv.(crypto.Signer),
+	}
+}
+
+func (this *zoneSigner) sign(rrset ...dns.RR) []dns.RR {
+	header := rrset[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: header.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: header.Ttl},
+		TypeCovered: header.Rrtype,
+		Algorithm:   this.key.Algorithm,
+		Labels:      uint8(dns.CountLabel(header.Name)),
+		OrigTtl:     header.Ttl,
+		Expiration:  uint32(time.Now().Add(time.Hour).Unix()),
+		Inception:   uint32(time.Now().Add(-time.Hour).Unix()),
+		KeyTag:      this.key.KeyTag(),
+		SignerName:  this.key.Hdr.Name,
+	}
+	if err := sig.Sign(this.priv, rrset); err != nil {
+		panic(err)
+	}
+	return append(rrset, sig)
+}
+
+func (this *zoneSigner) ds() *dns.DS {
+	return this.key.ToDS(dns.SHA256)
+}
+
+func newA(name string, ip ...byte) *dns.A {
+	return &dns.A{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   net.IP(ip),
+	}
+}
+
+func newNSEC(name string, next string, types ...uint16) *dns.NSEC {
+	return &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 60},
+		NextDomain: next,
+		TypeBitMap: append(types, dns.TypeRRSIG, dns.TypeNSEC),
+	}
+}
+
+// signedDNSDispatcher answers DNS queries with the records of the queried name and type, and their signatures. Queries
+// without answers are answered with the denial records of the name in the authority section.
+type signedDNSDispatcher struct {
+	records []dns.RR
+	denials map[string][]dns.RR
+}
+
+func (this *signedDNSDispatcher) Release() {}
+
+func (this *signedDNSDispatcher) DispatchToOutbound(ctx context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay {
+	link := ray.NewRay()
+	go func() {
+		defer link.OutboundOutput().Close()
+		for {
+			payload, err := link.OutboundInput().Read()
+			if err != nil {
+				return
+			}
+			query := new(dns.Msg)
+			if err := query.Unpack(payload.Value); err != nil {
+				return
+			}
+			payload.Release()
+			response := new(dns.Msg).SetReply(query)
+			question := query.Question[0]
+			for _, rr := range this.records {
+				if !strings.EqualFold(rr.Header().Name, question.Name) {
+					continue
+				}
+				if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == question.Qtype || rr.Header().Rrtype == question.Qtype {
+					response.Answer = append(response.Answer, rr)
+				}
+			}
+			if len(response.Answer) == 0 {
+				response.Ns = this.denials[strings.ToLower(question.Name)]
+			}
+			data, err := response.Pack()
+			if err != nil {
+				return
+			}
+			link.OutboundOutput().Write(alloc.NewBuffer().Clear().Append(data))
+		}
+	}()
+	return link
+}
+
+// newDNSSECServer creates a server validating answers from a signed root zone, where
+//   - com. and v2ray.com. are signed zones,
+//   - insecure.com. is proven not signed by the NSEC record of com.,
+//   - stripped.com. has its DS record without signatures, and
+//   - nodenial.com. has neither DS records nor the denial of them.
+func newDNSSECServer(assert *assert.Assert) *CacheServer {
+	root := newZoneSigner(".")
+	com := newZoneSigner("com.")
+	v2ray := newZoneSigner("v2ray.com.")
+	stripped := newZoneSigner("stripped.com.")
+
+	var records []dns.RR
+	records = append(records, root.sign(root.key)...)
+	records = append(records, root.sign(com.ds())...)
+	records = append(records, com.sign(com.key)...)
+	records = append(records, com.sign(v2ray.ds())...)
+	records = append(records, v2ray.sign(v2ray.key)...)
+	records = append(records, v2ray.sign(newA("www.v2ray.com.", 10, 0, 0, 1))...)
+	records = append(records, newA("unsigned.v2ray.com.", 10, 0, 0, 2))
+	bogus := v2ray.sign(newA("bogus.v2ray.com.", 10, 0, 0, 3))
+	bogus[0].(*dns.A).A = net.IP([]byte{10, 0, 0, 4})
+	records = append(records, bogus...)
+	records = append(records, newA("www.insecure.com.", 10, 0, 0, 5))
+	records = append(records, stripped.ds())
+	records = append(records, stripped.sign(stripped.key)...)
+	records = append(records, stripped.sign(newA("www.stripped.com.", 10, 0, 0, 6))...)
+	records = append(records, newA("www.nodenial.com.", 10, 0, 0, 7))
+
+	denials := map[string][]dns.RR{
+		"insecure.com.":       com.sign(newNSEC("insecure.com.", "nodenial.com.", dns.TypeNS)),
+		"www.v2ray.com.":      v2ray.sign(newNSEC("www.v2ray.com.", "v2ray.com.", dns.TypeA)),
+		"unsigned.v2ray.com.": v2ray.sign(newNSEC("unsigned.v2ray.com.", "www.v2ray.com.", dns.TypeA)),
+		"bogus.v2ray.com.":    v2ray.sign(newNSEC("bogus.v2ray.com.", "unsigned.v2ray.com.", dns.TypeA)),
+		"www.stripped.com.":   stripped.sign(newNSEC("www.stripped.com.", "stripped.com.", dns.TypeA)),
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &signedDNSDispatcher{records: records, denials: denials})
+	server := NewCacheServer(space, &Config{
+		Servers: []*NameServerConfig{
+			{
+				Address: &v2net.DestinationPB{
+					Network: v2net.Network_UDP,
+					Address: ipAddressPB(8, 8, 8, 8),
+					Port:    53,
+				},
+				DNSSEC: true,
+			},
+		},
+		TrustAnchors:  []string{root.ds().String()},
+		QueryStrategy: QueryStrategy_USE_IP4,
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+	return server
+}
+
+func TestDNSSEC(t *testing.T) {
+	assert := assert.On(t)
+
+	server := newDNSSECServer(assert)
+
+	ips, validated := server.GetValidated("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 1}))
+	assert.Bool(validated).IsTrue()
+
+	ips, validated = server.GetValidated("www.insecure.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 5}))
+	assert.Bool(validated).IsFalse()
+
+	ips, validated = server.GetValidated("bogus.v2ray.com")
+	assert.Bool(ips == nil).IsTrue()
+	assert.Bool(validated).IsFalse()
+}
+
+func TestDNSSECStrippedSignatures(t *testing.T) {
+	assert := assert.On(t)
+
+	server := newDNSSECServer(assert)
+
+	// The answer is not signed, but its zone is.
+	ips, validated := server.GetValidated("unsigned.v2ray.com")
+	assert.Bool(ips == nil).IsTrue()
+	assert.Bool(validated).IsFalse()
+
+	// The DS record of the zone is not signed, but its parent zone is.
+	ips, validated = server.GetValidated("www.stripped.com")
+	assert.Bool(ips == nil).IsTrue()
+	assert.Bool(validated).IsFalse()
+
+	// The zone has no DS records, and the parent zone doesn't prove it.
+	ips, validated = server.GetValidated("www.nodenial.com")
+	assert.Bool(ips == nil).IsTrue()
+	assert.Bool(validated).IsFalse()
+}
+
+func TestParseTrustAnchors(t *testing.T) {
+	assert := assert.On(t)
+
+	anchors, err := ParseTrustAnchors(RootTrustAnchors)
+	assert.Error(err).IsNil()
+	assert.Int(len(anchors)).Equals(2)
+	assert.Int(int(anchors[0].KeyTag)).Equals(20326)
+
+	_, err = ParseTrustAnchors([]string{"com. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"})
+	assert.Error(err).IsNotNil()
+}
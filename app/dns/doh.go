@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"v2ray.com/core/common/log"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/http2"
+)
+
+const (
+	dnsMessageType     = "application/dns-message"
+	maxDNSResponseSize = 65535
+)
+
+// DoHNameServer is a DNS-over-HTTPS (RFC 8484) name server. Connections to the server are kept alive and reused, over
+// HTTP/2 if the server supports it.
+type DoHNameServer struct {
+	url      string
+	client   *http.Client
+	clientIP net.IP
+}
+
+func NewDoHNameServer(url string, dial DialFunc) *DoHNameServer {
+	transport := &http.Transport{
+		Dial:                dial,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     time.Minute * 5,
+		TLSHandshakeTimeout: QueryTimeout,
+	}
+	if err := http2.ConfigureTransport(transport); err != nil {
+		log.Warning("DNS: Failed to enable HTTP/2 for ", url, ": ", err)
+	}
+	return &DoHNameServer{
+		url: url,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   QueryTimeout,
+		},
+	}
+}
+
+// SetClientIP sets the IP sent in the EDNS Client Subnet option of queries.
+func (this *DoHNameServer) SetClientIP(ip net.IP) {
+	this.clientIP = ip
+}
+
+// Exchange sends a query to the server and waits for the response. The ID of the query is set to 0, for the
+// responses to be cache friendly.
+func (this *DoHNameServer) Exchange(query *dns.Msg) (*dns.Msg, error) {
+	query.Id = 0
+	data, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("POST", this.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", dnsMessageType)
+	request.Header.Set("Accept", dnsMessageType)
+	response, err := this.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("DNS: Unexpected status from " + this.url + ": " + response.Status)
+	}
+	body, err := ioutil.ReadAll(&io.LimitedReader{R: response.Body, N: maxDNSResponseSize})
+	if err != nil {
+		return nil, err
+	}
+	msg := new(dns.Msg)
+	if err := msg.Unpack(body); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (this *DoHNameServer) query(domain string, qtype uint16) (*ARecord, error) {
+	response, err := this.Exchange(buildQuery(domain, 0, qtype, this.clientIP))
+	if err != nil {
+		return nil, err
+	}
+	return parseARecord(response), nil
+}
+
+func (this *DoHNameServer) Name() string {
+	return this.url
+}
+
+func (this *DoHNameServer) QueryA(domain string) <-chan *ARecord {
+	return queryAsync(this.url, domain, dns.TypeA, this.query)
+}
+
+func (this *DoHNameServer) QueryAAAA(domain string) <-chan *ARecord {
+	return queryAsync(this.url, domain, dns.TypeAAAA, this.query)
+}
+
+// IsDoHURL returns true if the address of a name server is a DNS-over-HTTPS URL.
+func IsDoHURL(address string) bool {
+	return strings.HasPrefix(strings.ToLower(address), "https://")
+}
@@ -0,0 +1,145 @@
+package dns
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultFakeIPPool   = "198.18.0.0/15"
+	DefaultFakePoolSize = 65535
+)
+
+var (
+	ErrInvalidFakeIPPool = errors.New("DNS|FakeDNS: Invalid IP pool.")
+)
+
+// FakeDNSEngine answers queries with fake IPs, and maps the fake IPs back to domains when connections to them are
+// dispatched.
+type FakeDNSEngine interface {
+	// GetFakeIP returns the fake IP of the domain, or nil if fake DNS is disabled.
+	GetFakeIP(domain string) net.IP
+	// GetDomainFromFakeIP returns the domain of a fake IP, or an empty string if the IP is not a fake IP in use.
+	GetDomainFromFakeIP(ip net.IP) string
+}
+
+type fakeIPEntry struct {
+	domain string
+	offset uint32
+}
+
+// FakeDNSPool allocates IPs from a reserved range to domains. When the pool is full, the IP of the least recently used
+// domain is reallocated.
+type FakeDNSPool struct {
+	sync.Mutex
+	ipNet    *net.IPNet
+	size     uint32
+	next     uint32
+	lru      *list.List
+	byDomain map[string]*list.Element
+	byOffset map[uint32]*list.Element
+}
+
+// NewFakeDNSPool creates a pool of at most size IPs in the given CIDR.
+func NewFakeDNSPool(cidr string, size uint32) (*FakeDNSPool, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, ErrInvalidFakeIPPool
+	}
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		ipNet.IP = ip4
+	}
+	ones, bits := ipNet.Mask.Size()
+	// The first IP of the range is not used.
+	if hostBits := uint(bits - ones); hostBits < 32 {
+		if capacity := uint32(1)<<hostBits - 1; size > capacity {
+			size = capacity
+		}
+	}
+	if size == 0 {
+		return nil, ErrInvalidFakeIPPool
+	}
+	return &FakeDNSPool{
+		ipNet:    ipNet,
+		size:     size,
+		lru:      list.New(),
+		byDomain: make(map[string]*list.Element),
+		byOffset: make(map[uint32]*list.Element),
+	}, nil
+}
+
+func (this *FakeDNSPool) ipOf(offset uint32) net.IP {
+	ip := make(net.IP, len(this.ipNet.IP))
+	copy(ip, this.ipNet.IP)
+	tail := ip[len(ip)-4:]
+	binary.BigEndian.PutUint32(tail, binary.BigEndian.Uint32(tail)+offset)
+	return ip
+}
+
+func (this *FakeDNSPool) offsetOf(ip net.IP) (uint32, bool) {
+	if ip4 := ip.To4(); ip4 != nil && len(this.ipNet.IP) == net.IPv4len {
+		ip = ip4
+	}
+	if len(ip) != len(this.ipNet.IP) || !this.ipNet.Contains(ip) {
+		return 0, false
+	}
+	offset := binary.BigEndian.Uint32(ip[len(ip)-4:]) - binary.BigEndian.Uint32(this.ipNet.IP[len(ip)-4:])
+	if offset == 0 || offset > this.size || !this.ipOf(offset).Equal(ip) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// Contains returns true if the IP is in the range of the pool.
+func (this *FakeDNSPool) Contains(ip net.IP) bool {
+	_, ok := this.offsetOf(ip)
+	return ok
+}
+
+func (this *FakeDNSPool) GetFakeIP(domain string) net.IP {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if element, found := this.byDomain[domain]; found {
+		this.lru.MoveToFront(element)
+		return this.ipOf(element.Value.(*fakeIPEntry).offset)
+	}
+
+	var entry *fakeIPEntry
+	if this.next < this.size {
+		this.next++
+		entry = &fakeIPEntry{offset: this.next}
+	} else {
+		oldest := this.lru.Back()
+		entry = this.lru.Remove(oldest).(*fakeIPEntry)
+		delete(this.byDomain, entry.domain)
+	}
+	entry.domain = domain
+	element := this.lru.PushFront(entry)
+	this.byDomain[domain] = element
+	this.byOffset[entry.offset] = element
+	return this.ipOf(entry.offset)
+}
+
+func (this *FakeDNSPool) GetDomainFromFakeIP(ip net.IP) string {
+	offset, ok := this.offsetOf(ip)
+	if !ok {
+		return ""
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	element, found := this.byOffset[offset]
+	if !found {
+		return ""
+	}
+	this.lru.MoveToFront(element)
+	return element.Value.(*fakeIPEntry).domain
+}
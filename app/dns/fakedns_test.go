@@ -0,0 +1,49 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+
+	. "v2ray.com/core/app/dns"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestFakeDNSPool(t *testing.T) {
+	assert := assert.On(t)
+
+	pool, err := NewFakeDNSPool("198.18.0.0/15", 2)
+	assert.Error(err).IsNil()
+
+	ip1 := pool.GetFakeIP("v2ray.com.")
+	assert.IP(ip1).Equals(net.IP([]byte{198, 18, 0, 1}))
+	assert.IP(pool.GetFakeIP("V2Ray.com")).Equals(ip1)
+	ip2 := pool.GetFakeIP("www.v2ray.com")
+	assert.IP(ip2).Equals(net.IP([]byte{198, 18, 0, 2}))
+	assert.String(pool.GetDomainFromFakeIP(ip2)).Equals("www.v2ray.com")
+	assert.String(pool.GetDomainFromFakeIP(ip1)).Equals("v2ray.com")
+	assert.String(pool.GetDomainFromFakeIP(net.IP([]byte{198, 18, 0, 3}))).Equals("")
+	assert.String(pool.GetDomainFromFakeIP(net.IP([]byte{8, 8, 8, 8}))).Equals("")
+	assert.Bool(pool.Contains(net.ParseIP("198.18.0.2"))).IsTrue()
+
+	// The pool is full, so the least recently used IP, which is ip2, is reallocated.
+	ip3 := pool.GetFakeIP("github.com")
+	assert.IP(ip3).Equals(ip2)
+	assert.String(pool.GetDomainFromFakeIP(ip2)).Equals("github.com")
+	assert.String(pool.GetDomainFromFakeIP(ip1)).Equals("v2ray.com")
+}
+
+func TestFakeDNSPoolIPv6(t *testing.T) {
+	assert := assert.On(t)
+
+	pool, err := NewFakeDNSPool("fc00::/18", 65535)
+	assert.Error(err).IsNil()
+	ip := pool.GetFakeIP("v2ray.com")
+	assert.IP(ip).Equals(net.ParseIP("fc00::1"))
+	assert.String(pool.GetDomainFromFakeIP(net.ParseIP("fc00::1"))).Equals("v2ray.com")
+	assert.String(pool.GetDomainFromFakeIP(net.ParseIP("fc00:1::1"))).Equals("")
+
+	_, err = NewFakeDNSPool("198.18.0.0/32", 10)
+	assert.Error(err).IsNotNil()
+	_, err = NewFakeDNSPool("invalid", 10)
+	assert.Error(err).IsNotNil()
+}
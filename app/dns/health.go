@@ -0,0 +1,86 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	// UnhealthyFailures is the number of consecutive failures after which a name server is considered down.
+	UnhealthyFailures = 3
+	// UnhealthyDuration is the time a name server down is queried after all others, before it is tried first again.
+	UnhealthyDuration = time.Second * 30
+)
+
+type serverHealth struct {
+	failures    int
+	lastFailure time.Time
+}
+
+// healthTracker tracks the consecutive failures of name servers, by their names.
+type healthTracker struct {
+	sync.RWMutex
+	servers map[string]*serverHealth
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		servers: make(map[string]*serverHealth),
+	}
+}
+
+func (this *healthTracker) OnSuccess(name string) {
+	this.Lock()
+	defer this.Unlock()
+
+	if health, found := this.servers[name]; found {
+		if health.failures >= UnhealthyFailures {
+			log.Info("DNS: Name server ", name, " is up again.")
+		}
+		delete(this.servers, name)
+	}
+}
+
+func (this *healthTracker) OnFailure(name string) {
+	this.Lock()
+	defer this.Unlock()
+
+	health, found := this.servers[name]
+	if !found {
+		health = new(serverHealth)
+		this.servers[name] = health
+	}
+	health.failures++
+	health.lastFailure = time.Now()
+	if health.failures == UnhealthyFailures {
+		log.Warning("DNS: Name server ", name, " is down after ", health.failures, " consecutive failures.")
+	}
+}
+
+// IsHealthy returns false if the name server failed recently UnhealthyFailures times in a row.
+func (this *healthTracker) IsHealthy(name string) bool {
+	this.RLock()
+	defer this.RUnlock()
+
+	health, found := this.servers[name]
+	if !found {
+		return true
+	}
+	return health.failures < UnhealthyFailures || time.Since(health.lastFailure) > UnhealthyDuration
+}
+
+// Sort returns the healthy name servers followed by the ones down, each in their original order.
+func (this *healthTracker) Sort(servers []NameServer) []NameServer {
+	sorted := make([]NameServer, 0, len(servers))
+	var down []NameServer
+	for _, server := range servers {
+		if this.IsHealthy(server.Name()) {
+			sorted = append(sorted, server)
+		} else {
+			down = append(down, server)
+		}
+	}
+	return append(sorted, down...)
+}
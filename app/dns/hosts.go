@@ -0,0 +1,138 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	// maxHostsAliasDepth is the maximum number of domain-to-domain mappings followed in static hosts.
+	maxHostsAliasDepth = 8
+)
+
+var (
+	ErrHostsAliasLoop = errors.New("DNS|Hosts: Too many domain aliases.")
+)
+
+type hostsRule struct {
+	key     string
+	matcher DomainMatcher
+	address v2net.Address
+}
+
+// StaticHosts maps domains to IPs or other domains. Keys are full domains by default. Keys of "domain:" match the
+// domain and all its subdomains, and keys of "keyword:", "regexp:" and "geosite:" are matched by the domain rules of the
+// router. Full domains take precedence over the other keys, which are tried in alphabetical order.
+type StaticHosts struct {
+	full  map[string]v2net.Address
+	rules []*hostsRule
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+func NewStaticHosts(hosts map[string]*v2net.AddressPB) (*StaticHosts, error) {
+	keys := make([]string, 0, len(hosts))
+	for key := range hosts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	staticHosts := &StaticHosts{
+		full: make(map[string]v2net.Address),
+	}
+	for _, key := range keys {
+		address := hosts[key].AsAddress()
+		switch {
+		case strings.HasPrefix(key, "full:"):
+			staticHosts.full[normalizeDomain(key[5:])] = address
+		case strings.HasPrefix(key, "domain:"):
+			staticHosts.rules = append(staticHosts.rules, &hostsRule{
+				key:     key,
+				matcher: subDomainMatcher(normalizeDomain(key[7:])),
+				address: address,
+			})
+		case strings.HasPrefix(key, "keyword:"), strings.HasPrefix(key, "regexp:"), strings.HasPrefix(key, "geosite:"):
+			if domainMatcherFactory == nil {
+				return nil, ErrDomainRuleUnsupported
+			}
+			rule := key
+			if strings.HasPrefix(key, "keyword:") {
+				rule = key[8:]
+			}
+			matcher, err := domainMatcherFactory(rule)
+			if err != nil {
+				log.Error("DNS|Hosts: Invalid domain rule ", key, ": ", err)
+				return nil, err
+			}
+			staticHosts.rules = append(staticHosts.rules, &hostsRule{
+				key:     key,
+				matcher: matcher,
+				address: address,
+			})
+		default:
+			staticHosts.full[normalizeDomain(key)] = address
+		}
+	}
+	return staticHosts, nil
+}
+
+// Lookup returns the address that the domain is mapped to, or nil if the domain is not in the hosts.
+func (this *StaticHosts) Lookup(domain string) v2net.Address {
+	domain = normalizeDomain(domain)
+	if address, found := this.full[domain]; found {
+		return address
+	}
+	for _, rule := range this.rules {
+		if rule.matcher.Match(domain) {
+			return rule.address
+		}
+	}
+	return nil
+}
+
+// Resolve follows the domain mappings in the hosts. It returns the IP if the domain is eventually mapped to one, or
+// the last domain in the chain otherwise, which is the domain itself if it is not in the hosts.
+func (this *StaticHosts) Resolve(domain string) (v2net.Address, error) {
+	current := v2net.DomainAddress(domain)
+	for i := 0; i < maxHostsAliasDepth; i++ {
+		address := this.Lookup(current.Domain())
+		if address == nil {
+			return current, nil
+		}
+		if !address.Family().IsDomain() {
+			return address, nil
+		}
+		log.Debug("DNS|Hosts: ", current.Domain(), " is mapped to ", address.Domain())
+		current = address
+	}
+	return nil, ErrHostsAliasLoop
+}
+
+// ReverseLookup returns the full domains mapped directly to the IP, in alphabetical order.
+func (this *StaticHosts) ReverseLookup(ip net.IP) []string {
+	var domains []string
+	for domain, address := range this.full {
+		if !address.Family().IsDomain() && address.IP().Equal(ip) {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+type subDomainMatcher string
+
+func (this subDomainMatcher) Match(domain string) bool {
+	pattern := string(this)
+	if !strings.HasSuffix(domain, pattern) {
+		return false
+	}
+	return len(domain) == len(pattern) || domain[len(domain)-len(pattern)-1] == '.'
+}
@@ -0,0 +1,66 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+
+	. "v2ray.com/core/app/dns"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+)
+
+func ipAddressPB(ip ...byte) *v2net.AddressPB {
+	return &v2net.AddressPB{
+		Address: &v2net.AddressPB_Ip{
+			Ip: ip,
+		},
+	}
+}
+
+func domainAddressPB(domain string) *v2net.AddressPB {
+	return &v2net.AddressPB{
+		Address: &v2net.AddressPB_Domain{
+			Domain: domain,
+		},
+	}
+}
+
+func TestStaticHosts(t *testing.T) {
+	assert := assert.On(t)
+
+	hosts, err := NewStaticHosts(map[string]*v2net.AddressPB{
+		"v2ray.com":                ipAddressPB(1, 1, 1, 1),
+		"domain:v2ray.com":         ipAddressPB(2, 2, 2, 2),
+		"full:www.example.com":     domainAddressPB("example.com"),
+		"example.com":              domainAddressPB("Alias.Example.Org"),
+		"keyword:google":           ipAddressPB(3, 3, 3, 3),
+		"domain:alias.example.org": ipAddressPB(4, 4, 4, 4),
+	})
+	assert.Error(err).IsNil()
+
+	resolve := func(domain string) v2net.Address {
+		address, err := hosts.Resolve(domain)
+		assert.Error(err).IsNil()
+		return address
+	}
+
+	assert.IP(resolve("v2ray.com").IP()).Equals(net.IP([]byte{1, 1, 1, 1}))
+	assert.IP(resolve("V2Ray.com.").IP()).Equals(net.IP([]byte{1, 1, 1, 1}))
+	assert.IP(resolve("www.v2ray.com").IP()).Equals(net.IP([]byte{2, 2, 2, 2}))
+	assert.IP(resolve("www.google.com").IP()).Equals(net.IP([]byte{3, 3, 3, 3}))
+	assert.IP(resolve("www.example.com").IP()).Equals(net.IP([]byte{4, 4, 4, 4}))
+	assert.String(resolve("notv2ray.com").Domain()).Equals("notv2ray.com")
+}
+
+func TestStaticHostsAliasLoop(t *testing.T) {
+	assert := assert.On(t)
+
+	hosts, err := NewStaticHosts(map[string]*v2net.AddressPB{
+		"a.v2ray.com": domainAddressPB("b.v2ray.com"),
+		"b.v2ray.com": domainAddressPB("a.v2ray.com"),
+	})
+	assert.Error(err).IsNil()
+
+	_, err = hosts.Resolve("a.v2ray.com")
+	assert.Error(err).Equals(ErrHostsAliasLoop)
+}
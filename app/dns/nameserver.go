@@ -17,27 +17,61 @@ import (
 )
 
 const (
-	DefaultTTL       = uint32(3600)
-	CleanupInterval  = time.Second * 120
-	CleanupThreshold = 512
+	DefaultTTL = uint32(3600)
+	// DefaultNegativeTTL is the TTL of negative answers without SOA records.
+	DefaultNegativeTTL = uint32(300)
+	CleanupInterval    = time.Second * 120
+	CleanupThreshold   = 512
+
+	// Prefix lengths of the IPs in EDNS Client Subnet options.
+	ClientSubnetIPv4Prefix = 24
+	ClientSubnetIPv6Prefix = 56
 )
 
 var (
 	pseudoDestination = v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(53))
 )
 
+// ARecord is the answer of a name server to an A or AAAA query. It is a negative answer if IPs is empty.
 type ARecord struct {
 	IPs    []net.IP
+	TTL    uint32
 	Expire time.Time
+	// Validated is true if the IPs are validated with DNSSEC.
+	Validated bool
+	// NameError is true if the name server answered that the domain does not exist (NXDOMAIN).
+	NameError bool
 }
 
 type NameServer interface {
+	// Name returns the address of the server, for logs and stats.
+	Name() string
 	QueryA(domain string) <-chan *ARecord
+	QueryAAAA(domain string) <-chan *ARecord
+}
+
+// queryAsync runs a blocking query in a new goroutine. The returned channel is closed without any record if the query
+// fails.
+func queryAsync(url string, domain string, qtype uint16, query func(domain string, qtype uint16) (*ARecord, error)) <-chan *ARecord {
+	response := make(chan *ARecord, 1)
+
+	go func() {
+		defer close(response)
+
+		record, err := query(domain, qtype)
+		if err != nil {
+			log.Info("DNS: Failed to query ", domain, " from ", url, ": ", err)
+			return
+		}
+		response <- record
+	}()
+
+	return response
 }
 
 type PendingRequest struct {
 	expire   time.Time
-	response chan<- *ARecord
+	response chan<- *dns.Msg
 }
 
 type UDPNameServer struct {
@@ -46,6 +80,7 @@ type UDPNameServer struct {
 	requests    map[uint16]*PendingRequest
 	udpServer   *udp.UDPServer
 	nextCleanup time.Time
+	clientIP    net.IP
 }
 
 func NewUDPNameServer(address v2net.Destination, dispatcher dispatcher.PacketDispatcher) *UDPNameServer {
@@ -54,7 +89,7 @@ func NewUDPNameServer(address v2net.Destination, dispatcher dispatcher.PacketDis
 		requests: make(map[uint16]*PendingRequest),
 		udpServer: udp.NewUDPServer(&proxy.InboundHandlerMeta{
 			AllowPassiveConnection: false,
-		}, dispatcher),
+		}, dispatcher, nil),
 	}
 	return s
 }
@@ -78,7 +113,7 @@ func (this *UDPNameServer) Cleanup() {
 }
 
 // Private: Visible for testing.
-func (this *UDPNameServer) AssignUnusedID(response chan<- *ARecord) uint16 {
+func (this *UDPNameServer) AssignUnusedID(response chan<- *dns.Msg) uint16 {
 	var id uint16
 	this.Lock()
 	if len(this.requests) > CleanupThreshold && this.nextCleanup.Before(time.Now()) {
@@ -110,11 +145,7 @@ func (this *UDPNameServer) HandleResponse(dest v2net.Destination, payload *alloc
 		log.Warning("DNS: Failed to parse DNS response: ", err)
 		return
 	}
-	record := &ARecord{
-		IPs: make([]net.IP, 0, 16),
-	}
 	id := msg.Id
-	ttl := DefaultTTL
 	log.Debug("DNS: Handling response for id ", id, " content: ", msg.String())
 
 	this.Lock()
@@ -126,6 +157,23 @@ func (this *UDPNameServer) HandleResponse(dest v2net.Destination, payload *alloc
 	delete(this.requests, id)
 	this.Unlock()
 
+	request.response <- msg
+	close(request.response)
+}
+
+// parseARecord returns the IPs in the answers of a DNS response. The record expires with the shortest TTL of the
+// answers. The TTL of negative answers is taken from the SOA record in the authority section, as in RFC 2308. It
+// returns nil if the server failed to answer, so that other servers are tried.
+func parseARecord(msg *dns.Msg) *ARecord {
+	if msg.Rcode != dns.RcodeSuccess && msg.Rcode != dns.RcodeNameError {
+		log.Info("DNS: Server failed to answer: ", dns.RcodeToString[msg.Rcode])
+		return nil
+	}
+	record := &ARecord{
+		IPs:       make([]net.IP, 0, 16),
+		NameError: msg.Rcode == dns.RcodeNameError,
+	}
+	ttl := DefaultTTL
 	for _, rr := range msg.Answer {
 		switch rr := rr.(type) {
 		case *dns.A:
@@ -140,27 +188,80 @@ func (this *UDPNameServer) HandleResponse(dest v2net.Destination, payload *alloc
 			}
 		}
 	}
+	if len(record.IPs) == 0 {
+		ttl = DefaultNegativeTTL
+		for _, rr := range msg.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				ttl = soa.Hdr.Ttl
+				if soa.Minttl < ttl {
+					ttl = soa.Minttl
+				}
+				break
+			}
+		}
+	}
+	record.TTL = ttl
 	record.Expire = time.Now().Add(time.Second * time.Duration(ttl))
-
-	request.response <- record
-	close(request.response)
+	return record
 }
 
-func (this *UDPNameServer) BuildQueryA(domain string, id uint16) *alloc.Buffer {
-	buffer := alloc.NewBuffer()
+// buildQuery builds a query of the given type. The query carries an EDNS Client Subnet option if clientIP is not nil, so
+// that the answers are close to the client rather than the server.
+func buildQuery(domain string, id uint16, qtype uint16, clientIP net.IP) *dns.Msg {
 	msg := new(dns.Msg)
 	msg.Id = id
 	msg.RecursionDesired = true
 	msg.Question = []dns.Question{
 		{
 			Name:   dns.Fqdn(domain),
-			Qtype:  dns.TypeA,
+			Qtype:  qtype,
 			Qclass: dns.ClassINET,
 		}}
+	if clientIP != nil {
+		msg.Extra = append(msg.Extra, buildClientSubnet(clientIP))
+	}
+	return msg
+}
+
+// buildClientSubnet builds an OPT record with the EDNS Client Subnet option of the given IP. Only the first 24 bits of
+// IPv4 and the first 56 bits of IPv6 are sent, as suggested in RFC 7871.
+func buildClientSubnet(clientIP net.IP) *dns.OPT {
+	subnet := &dns.EDNS0_SUBNET{
+		Code: dns.EDNS0SUBNET,
+	}
+	if ip4 := clientIP.To4(); ip4 != nil {
+		subnet.Family = 1
+		subnet.SourceNetmask = ClientSubnetIPv4Prefix
+		subnet.Address = ip4.Mask(net.CIDRMask(ClientSubnetIPv4Prefix, 8*net.IPv4len))
+	} else {
+		subnet.Family = 2
+		subnet.SourceNetmask = ClientSubnetIPv6Prefix
+		subnet.Address = clientIP.Mask(net.CIDRMask(ClientSubnetIPv6Prefix, 8*net.IPv6len))
+	}
+	opt := &dns.OPT{
+		Hdr: dns.RR_Header{
+			Name:   ".",
+			Rrtype: dns.TypeOPT,
+		},
+	}
+	opt.SetUDPSize(dns.DefaultMsgSize)
+	opt.Option = append(opt.Option, subnet)
+	return opt
+}
+
+// SetClientIP sets the IP sent in the EDNS Client Subnet option of queries.
+func (this *UDPNameServer) SetClientIP(ip net.IP) {
+	this.clientIP = ip
+}
 
+func (this *UDPNameServer) BuildQuery(domain string, id uint16, qtype uint16) *alloc.Buffer {
+	return packQuery(buildQuery(domain, id, qtype, this.clientIP))
+}
+
+func packQuery(msg *dns.Msg) *alloc.Buffer {
+	buffer := alloc.NewBuffer()
 	writtenBuffer, _ := msg.PackBuffer(buffer.Value)
 	buffer.Slice(0, len(writtenBuffer))
-
 	return buffer
 }
 
@@ -168,49 +269,100 @@ func (this *UDPNameServer) DispatchQuery(payload *alloc.Buffer) {
 	this.udpServer.Dispatch(&proxy.SessionInfo{Source: pseudoDestination, Destination: this.address}, payload, this.HandleResponse)
 }
 
-func (this *UDPNameServer) QueryA(domain string) <-chan *ARecord {
-	response := make(chan *ARecord, 1)
-	id := this.AssignUnusedID(response)
+// Exchange sends a query to the server and waits for the response. The ID of the query is assigned by the server.
+// The query is sent again every second until the response arrives, at most twice.
+func (this *UDPNameServer) Exchange(query *dns.Msg) (*dns.Msg, error) {
+	response := make(chan *dns.Msg, 1)
+	query.Id = this.AssignUnusedID(response)
 
-	this.DispatchQuery(this.BuildQueryA(domain, id))
+	this.DispatchQuery(packQuery(query))
 
 	go func() {
 		for i := 0; i < 2; i++ {
 			time.Sleep(time.Second)
 			this.Lock()
-			_, found := this.requests[id]
+			_, found := this.requests[query.Id]
 			this.Unlock()
 			if found {
-				this.DispatchQuery(this.BuildQueryA(domain, id))
+				this.DispatchQuery(packQuery(query))
 			} else {
 				break
 			}
 		}
 	}()
 
-	return response
+	select {
+	case msg, open := <-response:
+		if !open {
+			return nil, ErrQueryTimeout
+		}
+		return msg, nil
+	case <-time.After(QueryTimeout):
+		return nil, ErrQueryTimeout
+	}
+}
+
+func (this *UDPNameServer) query(domain string, qtype uint16) (*ARecord, error) {
+	response, err := this.Exchange(buildQuery(domain, 0, qtype, this.clientIP))
+	if err != nil {
+		return nil, err
+	}
+	return parseARecord(response), nil
+}
+
+func (this *UDPNameServer) Name() string {
+	return this.address.String()
+}
+
+func (this *UDPNameServer) QueryA(domain string) <-chan *ARecord {
+	return queryAsync(this.Name(), domain, dns.TypeA, this.query)
+}
+
+func (this *UDPNameServer) QueryAAAA(domain string) <-chan *ARecord {
+	return queryAsync(this.Name(), domain, dns.TypeAAAA, this.query)
 }
 
 type LocalNameServer struct {
 }
 
-func (this *LocalNameServer) QueryA(domain string) <-chan *ARecord {
+// query looks up the IPs of the domain in the system resolver, and returns the IPv4 addresses if ipv4 is true, or the
+// IPv6 addresses otherwise.
+func (this *LocalNameServer) query(domain string, ipv4 bool) <-chan *ARecord {
 	response := make(chan *ARecord, 1)
 
 	go func() {
 		defer close(response)
 
-		ips, err := net.LookupIP(domain)
+		allIPs, err := net.LookupIP(domain)
 		if err != nil {
 			log.Info("DNS: Failed to lookup IPs for domain ", domain)
 			return
 		}
+		ips := make([]net.IP, 0, len(allIPs))
+		for _, ip := range allIPs {
+			if (ip.To4() != nil) == ipv4 {
+				ips = append(ips, ip)
+			}
+		}
 
 		response <- &ARecord{
 			IPs:    ips,
+			TTL:    DefaultTTL,
 			Expire: time.Now().Add(time.Second * time.Duration(DefaultTTL)),
 		}
 	}()
 
 	return response
 }
+
+func (this *LocalNameServer) Name() string {
+	return "localhost"
+}
+
+func (this *LocalNameServer) QueryA(domain string) <-chan *ARecord {
+	return this.query(domain, true)
+}
+
+func (this *LocalNameServer) QueryAAAA(domain string) <-chan *ARecord {
+	return this.query(domain, false)
+}
@@ -0,0 +1,100 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+var (
+	ErrOutboundNotFound = errors.New("DNS: Outbound not found.")
+)
+
+// outboundDispatcher is a PacketDispatcher that sends all connections through the outbound of the given tag, instead
+// of routing them. The outbound is looked up for each connection, as outbounds may be added after the DNS app.
+type outboundDispatcher struct {
+	ohm         proxyman.OutboundHandlerManager
+	outboundTag string
+}
+
+func (this *outboundDispatcher) DispatchToOutbound(ctx context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay {
+	link := ray.NewRay()
+	go func() {
+		var handler proxy.OutboundHandler
+		if this.ohm != nil {
+			handler = this.ohm.GetHandler(this.outboundTag)
+		}
+		if handler == nil {
+			log.Warning("DNS: Outbound not found: ", this.outboundTag)
+			link.OutboundInput().Release()
+			link.OutboundOutput().Release()
+			return
+		}
+		payload, err := link.OutboundInput().Read()
+		if err != nil {
+			link.OutboundInput().Release()
+			link.OutboundOutput().Release()
+			return
+		}
+		handler.Dispatch(proxy.ContextWithSession(ctx, meta, session), session.Destination, payload, link)
+	}()
+	return link
+}
+
+// newQueryDispatcher returns the dispatcher for the queries of a name server. Queries are routed as other connections
+// if outboundTag is empty.
+func newQueryDispatcher(packetDispatcher dispatcher.PacketDispatcher, ohm proxyman.OutboundHandlerManager, outboundTag string) dispatcher.PacketDispatcher {
+	if len(outboundTag) == 0 {
+		return packetDispatcher
+	}
+	return &outboundDispatcher{
+		ohm:         ohm,
+		outboundTag: outboundTag,
+	}
+}
+
+// DialFunc opens a TCP connection for a name server, in the form of net.Dial.
+type DialFunc func(network, addr string) (net.Conn, error)
+
+// NewDispatcherDialer returns a DialFunc that opens connections through the outbound of the given tag, or routes them
+// through the dispatcher if outboundTag is empty.
+func NewDispatcherDialer(packetDispatcher dispatcher.PacketDispatcher, ohm proxyman.OutboundHandlerManager, outboundTag string) DialFunc {
+	return func(network, addr string) (net.Conn, error) {
+		host, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		port, err := v2net.PortFromString(portStr)
+		if err != nil {
+			return nil, err
+		}
+		dest := v2net.TCPDestination(v2net.ParseAddress(host), port)
+		if len(outboundTag) == 0 {
+			link := packetDispatcher.DispatchToOutbound(context.Background(), &proxy.InboundHandlerMeta{
+				Tag: "dns",
+			}, &proxy.SessionInfo{
+				Source:      pseudoDestination,
+				Destination: dest,
+			})
+			return ray.NewConnection(link), nil
+		}
+		if ohm == nil {
+			return nil, ErrOutboundNotFound
+		}
+		handler := ohm.GetHandler(outboundTag)
+		if handler == nil {
+			return nil, ErrOutboundNotFound
+		}
+		link := ray.NewRay()
+		go handler.Dispatch(context.Background(), dest, alloc.NewLocalBuffer(32).Clear(), link)
+		return ray.NewConnection(link), nil
+	}
+}
@@ -0,0 +1,178 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	ErrReverseLookupUnsupported = errors.New("DNS: Reverse lookup is not supported by the name server.")
+)
+
+// PTRRecord is the answer of a name server to a PTR query. It is a negative answer if Domains is empty.
+type PTRRecord struct {
+	Domains []string
+	Expire  time.Time
+}
+
+// queryPTR looks up the domains of an IP from a name server. Expected IPs of the server don't apply.
+func queryPTR(server NameServer, ip net.IP) (*PTRRecord, error) {
+	if expected, ok := server.(*expectedIPNameServer); ok {
+		server = expected.server
+	}
+	if _, ok := server.(*LocalNameServer); ok {
+		domains, err := net.LookupAddr(ip.String())
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); !ok || dnsErr.Temporary() {
+				return nil, err
+			}
+		}
+		return newPTRRecord(domains, DefaultTTL), nil
+	}
+	exchanger, ok := server.(msgExchanger)
+	if !ok {
+		return nil, ErrReverseLookupUnsupported
+	}
+	name, err := dns.ReverseAddr(ip.String())
+	if err != nil {
+		return nil, err
+	}
+	response, err := exchanger.Exchange(buildQuery(name, 0, dns.TypePTR, nil))
+	if err != nil {
+		return nil, err
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, errors.New("DNS: Server failed to answer PTR query: " + dns.RcodeToString[response.Rcode])
+	}
+	var domains []string
+	ttl := DefaultTTL
+	for _, rr := range response.Answer {
+		if ptr, ok := rr.(*dns.PTR); ok {
+			domains = append(domains, ptr.Ptr)
+			if ptr.Hdr.Ttl < ttl {
+				ttl = ptr.Hdr.Ttl
+			}
+		}
+	}
+	if len(domains) == 0 {
+		ttl = DefaultNegativeTTL
+	}
+	return newPTRRecord(domains, ttl), nil
+}
+
+func newPTRRecord(domains []string, ttl uint32) *PTRRecord {
+	record := &PTRRecord{
+		Domains: make([]string, 0, len(domains)),
+		Expire:  time.Now().Add(time.Second * time.Duration(ttl)),
+	}
+	for _, domain := range domains {
+		record.Domains = append(record.Domains, normalizeDomain(domain))
+	}
+	return record
+}
+
+func (this *CacheServer) getCachedPTR(ip string) *PTRRecord {
+	this.RLock()
+	defer this.RUnlock()
+
+	if record, found := this.ptrRecords[ip]; found && record.Expire.After(time.Now()) {
+		return record
+	}
+	return nil
+}
+
+func (this *CacheServer) storePTR(ip string, record *PTRRecord) {
+	cache := this.currentSet().cache
+	if cache.Disabled || (len(record.Domains) == 0 && cache.DisableNegativeCache) {
+		return
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if len(this.ptrRecords) >= CleanupThreshold {
+		now := time.Now()
+		for cachedIP, cached := range this.ptrRecords {
+			if !cached.Expire.After(now) {
+				delete(this.ptrRecords, cachedIP)
+			}
+		}
+	}
+	this.ptrRecords[ip] = record
+}
+
+// ReverseLookup returns the domains of an IP. Fake IPs and IPs in static hosts are looked up locally. Other IPs are
+// looked up with PTR queries to the name servers without domain rules, in order.
+func (this *CacheServer) ReverseLookup(ip net.IP) []string {
+	if domain := this.GetDomainFromFakeIP(ip); len(domain) > 0 {
+		return []string{domain}
+	}
+	set := this.currentSet()
+	if domains := set.hosts.ReverseLookup(ip); len(domains) > 0 {
+		return domains
+	}
+
+	key := ip.String()
+	if record := this.getCachedPTR(key); record != nil {
+		this.logger.Debug("DNS: Cache hit for PTR of ", key, ": ", record.Domains)
+		return nilIfEmpty(record.Domains)
+	}
+	for _, server := range this.health.Sort(set.servers) {
+		record, err := queryPTR(server, ip)
+		if err != nil {
+			this.logger.Debug("DNS: ", server.Name(), " failed to answer PTR of ", key, ": ", err)
+			continue
+		}
+		this.logger.Debug("DNS: ", server.Name(), " answered PTR of ", key, " with ", strings.Join(record.Domains, ","))
+		this.storePTR(key, record)
+		return nilIfEmpty(record.Domains)
+	}
+	return nil
+}
+
+func nilIfEmpty(domains []string) []string {
+	if len(domains) == 0 {
+		return nil
+	}
+	return domains
+}
+
+// ParseReverseName returns the IP of a name in in-addr.arpa or ip6.arpa, as in PTR queries. It returns nil if the name
+// is not the full reverse name of an IP.
+func ParseReverseName(name string) net.IP {
+	name = strings.ToLower(dns.Fqdn(name))
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa."):
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa."), ".")
+		if len(labels) != net.IPv4len {
+			return nil
+		}
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		return net.ParseIP(strings.Join(labels, ".")).To4()
+	case strings.HasSuffix(name, ".ip6.arpa."):
+		labels := strings.Split(strings.TrimSuffix(name, ".ip6.arpa."), ".")
+		if len(labels) != 2*net.IPv6len {
+			return nil
+		}
+		ip := make(net.IP, net.IPv6len)
+		for i, label := range labels {
+			if len(label) != 1 {
+				return nil
+			}
+			nibble := strings.IndexByte("0123456789abcdef", label[0])
+			if nibble < 0 {
+				return nil
+			}
+			idx := len(labels) - 1 - i
+			ip[idx/2] |= byte(nibble) << uint(4*(1-idx%2))
+		}
+		return ip
+	}
+	return nil
+}
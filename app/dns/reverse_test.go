@@ -0,0 +1,65 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	. "v2ray.com/core/app/dns"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseReverseName(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, ip := range []string{"10.0.0.1", "2001:db8::1"} {
+		name, err := dns.ReverseAddr(ip)
+		assert.Error(err).IsNil()
+		assert.String(ParseReverseName(name).String()).Equals(ip)
+	}
+	assert.IP(ParseReverseName("1.0.0.10.IN-ADDR.ARPA")).Equals(net.IP([]byte{10, 0, 0, 1}))
+	assert.Bool(ParseReverseName("0.10.in-addr.arpa.") == nil).IsTrue()
+	assert.Bool(ParseReverseName("www.v2ray.com.") == nil).IsTrue()
+}
+
+func TestReverseLookup(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &signedDNSDispatcher{
+		records: []dns.RR{
+			&dns.PTR{
+				Hdr: dns.RR_Header{Name: "2.0.0.10.in-addr.arpa.", Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 60},
+				Ptr: "www.v2ray.com.",
+			},
+		},
+	})
+	server := NewCacheServer(space, &Config{
+		NameServers: []*v2net.DestinationPB{
+			{
+				Network: v2net.Network_UDP,
+				Address: ipAddressPB(8, 8, 8, 8),
+				Port:    53,
+			},
+		},
+		Hosts: map[string]*v2net.AddressPB{
+			"local.v2ray.com": ipAddressPB(10, 0, 0, 1),
+		},
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	domains := server.ReverseLookup(net.IP([]byte{10, 0, 0, 1}))
+	assert.Int(len(domains)).Equals(1)
+	assert.String(domains[0]).Equals("local.v2ray.com")
+
+	domains = server.ReverseLookup(net.IP([]byte{10, 0, 0, 2}))
+	assert.Int(len(domains)).Equals(1)
+	assert.String(domains[0]).Equals("www.v2ray.com")
+
+	assert.Bool(server.ReverseLookup(net.IP([]byte{10, 0, 0, 3})) == nil).IsTrue()
+}
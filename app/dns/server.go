@@ -1,15 +1,21 @@
 package dns
 
 import (
+	"errors"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/task"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/miekg/dns"
 )
 
@@ -17,97 +23,709 @@ const (
 	QueryTimeout = time.Second * 8
 )
 
+var (
+	ErrDomainRuleUnsupported = errors.New("DNS: Domain rules are not supported.")
+	ErrIPRuleUnsupported     = errors.New("DNS: IP rules are not supported.")
+)
+
 type DomainRecord struct {
 	A *ARecord
 }
 
+// domainNameServer is a name server for the domains matching any of its matchers.
+type domainNameServer struct {
+	server   NameServer
+	matchers []DomainMatcher
+}
+
+func (this *domainNameServer) Match(domain string) bool {
+	for _, matcher := range this.matchers {
+		if matcher.Match(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedIPNameServer drops the IPs in answers that match none of its matchers. Answers without any expected IP are
+// discarded, so that the next server is queried.
+type expectedIPNameServer struct {
+	server   NameServer
+	matchers []IPMatcher
+}
+
+func (this *expectedIPNameServer) isExpected(ip net.IP) bool {
+	for _, matcher := range this.matchers {
+		if matcher.Match(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRecord returns the record with the expected IPs only, or nil if none of its IPs is expected.
+func (this *expectedIPNameServer) filterRecord(domain string, record *ARecord) *ARecord {
+	ips := make([]net.IP, 0, len(record.IPs))
+	for _, ip := range record.IPs {
+		if this.isExpected(ip) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		log.Info("DNS: Discarding answer of ", domain, " without expected IPs: ", record.IPs)
+		return nil
+	}
+	return &ARecord{
+		IPs:       ips,
+		TTL:       record.TTL,
+		Expire:    record.Expire,
+		Validated: record.Validated,
+	}
+}
+
+func (this *expectedIPNameServer) filter(domain string, answer <-chan *ARecord) <-chan *ARecord {
+	response := make(chan *ARecord, 1)
+
+	go func() {
+		defer close(response)
+
+		record, open := <-answer
+		if !open || record == nil {
+			return
+		}
+		if record = this.filterRecord(domain, record); record != nil {
+			response <- record
+		}
+	}()
+
+	return response
+}
+
+func (this *expectedIPNameServer) Name() string {
+	return this.server.Name()
+}
+
+func (this *expectedIPNameServer) QueryA(domain string) <-chan *ARecord {
+	return this.filter(domain, this.server.QueryA(domain))
+}
+
+func (this *expectedIPNameServer) QueryAAAA(domain string) <-chan *ARecord {
+	return this.filter(domain, this.server.QueryAAAA(domain))
+}
+
+func newExpectedIPNameServer(server NameServer, expectIPs []string) (NameServer, error) {
+	if ipMatcherFactory == nil {
+		return nil, ErrIPRuleUnsupported
+	}
+	expected := &expectedIPNameServer{
+		server:   server,
+		matchers: make([]IPMatcher, 0, len(expectIPs)),
+	}
+	for _, rule := range expectIPs {
+		matcher, err := ipMatcherFactory(rule)
+		if err != nil {
+			log.Error("DNS: Invalid expected IP ", rule, ": ", err)
+			return nil, err
+		}
+		expected.matchers = append(expected.matchers, matcher)
+	}
+	return expected, nil
+}
+
+// serverSet is the part of a CacheServer created from its config, which is replaced as a whole on reload.
+type serverSet struct {
+	config        *Config
+	hosts         *StaticHosts
+	servers       []NameServer
+	domainServers []*domainNameServer
+	fakeDNS       *FakeDNSPool
+	cache         *CacheConfig
+	strategy      QueryStrategy
+	parallel      bool
+	failover      time.Duration
+}
+
 type CacheServer struct {
 	sync.RWMutex
-	space   app.Space
-	hosts   map[string]net.IP
-	records map[string]*DomainRecord
-	servers []NameServer
+	space      app.Space
+	set        *serverSet
+	records    map[string]*DomainRecord
+	ptrRecords map[string]*PTRRecord
+	stats      *statsCounter
+	health     *healthTracker
+	cleanup    *task.Periodic
+	// logger is the logger of the instance. Nil for the default logger.
+	logger *log.Logger
 }
 
-func NewCacheServer(space app.Space, config *Config) *CacheServer {
-	server := &CacheServer{
-		records: make(map[string]*DomainRecord),
-		servers: make([]NameServer, len(config.NameServers)),
-		hosts:   config.GetInternalHosts(),
+func createNameServer(destPB *v2net.DestinationPB, dispatcher dispatcher.PacketDispatcher) NameServer {
+	address := destPB.Address.AsAddress()
+	if address.Family().IsDomain() && address.Domain() == "localhost" {
+		return &LocalNameServer{}
 	}
-	space.InitializeApplication(func() error {
-		if !space.HasApp(dispatcher.APP_ID) {
-			log.Error("DNS: Dispatcher is not found in the space.")
-			return app.ErrMissingApplication
+	dest := destPB.AsDestination()
+	if dest.Network == v2net.Network_Unknown {
+		dest.Network = v2net.Network_UDP
+	}
+	if dest.Network == v2net.Network_UDP {
+		return NewUDPNameServer(dest, dispatcher)
+	}
+	return nil
+}
+
+// clientIPSetter is implemented by name servers that support EDNS Client Subnet.
+type clientIPSetter interface {
+	SetClientIP(ip net.IP)
+}
+
+// createServer creates the name server of a NameServerConfig.
+func createServer(config *NameServerConfig, packetDispatcher dispatcher.PacketDispatcher, ohm proxyman.OutboundHandlerManager) (NameServer, error) {
+	if len(config.URL) > 0 {
+		dial := NewDispatcherDialer(packetDispatcher, ohm, config.OutboundTag)
+		switch {
+		case IsDoHURL(config.URL):
+			return NewDoHNameServer(config.URL, dial), nil
+		case IsDoTURL(config.URL):
+			return NewDoTNameServer(config.URL, dial)
+		case IsTCPURL(config.URL):
+			return NewTCPNameServer(config.URL, dial)
 		}
+		return nil, errors.New("DNS: Unsupported name server URL: " + config.URL)
+	}
+	if config.Address == nil {
+		return nil, errors.New("DNS: Name server address is not specified.")
+	}
+	server := createNameServer(config.Address, newQueryDispatcher(packetDispatcher, ohm, config.OutboundTag))
+	if server == nil {
+		return nil, errors.New("DNS: Unsupported name server: " + config.Address.AsDestination().String())
+	}
+	if _, ok := server.(*LocalNameServer); ok && len(config.OutboundTag) > 0 {
+		log.Warning("DNS: Outbound tag is ignored for local name server.")
+	}
+	return server, nil
+}
 
-		dispatcher := space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
-		for idx, destPB := range config.NameServers {
-			address := destPB.Address.AsAddress()
-			if address.Family().IsDomain() && address.Domain() == "localhost" {
-				server.servers[idx] = &LocalNameServer{}
-			} else {
-				dest := destPB.AsDestination()
-				if dest.Network == v2net.Network_Unknown {
-					dest.Network = v2net.Network_UDP
+func createDomainNameServer(config *NameServerConfig, server NameServer) (*domainNameServer, error) {
+	if domainMatcherFactory == nil {
+		return nil, ErrDomainRuleUnsupported
+	}
+	domainServer := &domainNameServer{
+		server:   server,
+		matchers: make([]DomainMatcher, 0, len(config.Domain)),
+	}
+	for _, rule := range config.Domain {
+		matcher, err := domainMatcherFactory(rule)
+		if err != nil {
+			log.Error("DNS: Invalid domain rule ", rule, ": ", err)
+			return nil, err
+		}
+		domainServer.matchers = append(domainServer.matchers, matcher)
+	}
+	return domainServer, nil
+}
+
+// newServerSet creates the name servers and settings of a config. The fake IP pool of old is kept if the fake DNS
+// config is unchanged, so that fake IPs handed out remain valid.
+func newServerSet(space app.Space, config *Config, old *serverSet) (*serverSet, error) {
+	set := &serverSet{
+		config:   config,
+		servers:  make([]NameServer, len(config.NameServers)),
+		cache:    config.Cache,
+		strategy: config.QueryStrategy,
+		parallel: config.ParallelQuery,
+		failover: time.Millisecond * time.Duration(config.FailoverTimeout),
+	}
+	if set.cache == nil {
+		set.cache = new(CacheConfig)
+	}
+
+	hosts, err := NewStaticHosts(config.Hosts)
+	if err != nil {
+		log.Error("DNS: Failed to create static hosts: ", err)
+		return nil, err
+	}
+	set.hosts = hosts
+
+	if !space.HasApp(dispatcher.APP_ID) {
+		log.Error("DNS: Dispatcher is not found in the space.")
+		return nil, app.ErrMissingApplication
+	}
+
+	dispatcher := space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+	var ohm proxyman.OutboundHandlerManager
+	if space.HasApp(proxyman.APP_ID_OUTBOUND_MANAGER) {
+		ohm = space.GetApp(proxyman.APP_ID_OUTBOUND_MANAGER).(proxyman.OutboundHandlerManager)
+	}
+	for idx, destPB := range config.NameServers {
+		set.servers[idx] = createNameServer(destPB, dispatcher)
+	}
+	var trustAnchors []*dns.DS
+	for _, serverConfig := range config.Servers {
+		nameServer, err := createServer(serverConfig, dispatcher, ohm)
+		if err != nil {
+			log.Error("DNS: Failed to create name server: ", err)
+			return nil, err
+		}
+		if serverConfig.DNSSEC {
+			if trustAnchors == nil {
+				anchors := config.TrustAnchors
+				if len(anchors) == 0 {
+					anchors = RootTrustAnchors
 				}
-				if dest.Network == v2net.Network_UDP {
-					server.servers[idx] = NewUDPNameServer(dest, dispatcher)
+				trustAnchors, err = ParseTrustAnchors(anchors)
+				if err != nil {
+					log.Error("DNS: Invalid trust anchors: ", err)
+					return nil, err
 				}
 			}
+			nameServer, err = newDNSSECNameServer(nameServer, trustAnchors, applog.FromSpace(space))
+			if err != nil {
+				log.Error("DNS: Failed to enable DNSSEC: ", err)
+				return nil, err
+			}
 		}
-		if len(config.NameServers) == 0 {
-			server.servers = append(server.servers, &LocalNameServer{})
+		if len(serverConfig.ClientIP) > 0 {
+			if setter, ok := nameServer.(clientIPSetter); ok {
+				setter.SetClientIP(net.IP(serverConfig.ClientIP))
+			} else {
+				log.Warning("DNS: Client IP is not supported by local name server.")
+			}
 		}
+		if len(serverConfig.ExpectIPs) > 0 {
+			nameServer, err = newExpectedIPNameServer(nameServer, serverConfig.ExpectIPs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(serverConfig.Domain) == 0 {
+			set.servers = append(set.servers, nameServer)
+			continue
+		}
+		domainServer, err := createDomainNameServer(serverConfig, nameServer)
+		if err != nil {
+			return nil, err
+		}
+		set.domainServers = append(set.domainServers, domainServer)
+	}
+	if len(set.servers) == 0 {
+		set.servers = append(set.servers, &LocalNameServer{})
+	}
+	if fakeConfig := config.FakeDNS; fakeConfig != nil {
+		if old != nil && old.fakeDNS != nil && proto.Equal(fakeConfig, old.config.FakeDNS) {
+			set.fakeDNS = old.fakeDNS
+			return set, nil
+		}
+		ipPool := fakeConfig.IPPool
+		if len(ipPool) == 0 {
+			ipPool = DefaultFakeIPPool
+		}
+		poolSize := fakeConfig.PoolSize
+		if poolSize == 0 {
+			poolSize = DefaultFakePoolSize
+		}
+		pool, err := NewFakeDNSPool(ipPool, poolSize)
+		if err != nil {
+			log.Error("DNS: Failed to create fake DNS pool ", ipPool, ": ", err)
+			return nil, err
+		}
+		set.fakeDNS = pool
+	}
+	return set, nil
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "dns",
+		Config: (*Config)(nil),
+		After:  []app.ID{applog.APP_ID, proxyman.APP_ID_OUTBOUND_MANAGER},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewCacheServer(space, config.(*Config)), nil
+		},
+	})
+}
+
+func NewCacheServer(space app.Space, config *Config) *CacheServer {
+	server := &CacheServer{
+		space:      space,
+		records:    make(map[string]*DomainRecord),
+		ptrRecords: make(map[string]*PTRRecord),
+		stats:      newStatsCounter(),
+		health:     newHealthTracker(),
+		logger:     applog.FromSpace(space),
+	}
+	server.cleanup = &task.Periodic{
+		Name:     "DNS cache cleanup",
+		Interval: CleanupInterval,
+		Execute:  server.Cleanup,
+	}
+	space.InitializeApplication(func() error {
+		set, err := newServerSet(space, config, nil)
+		if err != nil {
+			return err
+		}
+		server.set = set
+		server.cleanup.Start()
 		return nil
 	})
 	return server
 }
 
+// Reload replaces the name servers and settings of the server with the given config, and flushes the cache. Queries
+// in progress finish with the old name servers. The server is unchanged if the new config is invalid.
+func (this *CacheServer) Reload(config *Config) error {
+	apply, err := this.PrepareReload(config)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// PrepareReload creates the name servers of the config, and returns the function that replaces the current ones with
+// them. Nothing is changed if the function is not called.
+func (this *CacheServer) PrepareReload(config *Config) (func(), error) {
+	set, err := newServerSet(this.space, config, this.currentSet())
+	if err != nil {
+		this.logger.Error("DNS: Failed to reload: ", err)
+		return nil, err
+	}
+
+	return func() {
+		this.Lock()
+		this.set = set
+		this.records = make(map[string]*DomainRecord)
+		this.ptrRecords = make(map[string]*PTRRecord)
+		this.Unlock()
+
+		this.logger.Info("DNS: Reloaded ", len(set.servers)+len(set.domainServers), " name servers.")
+	}, nil
+}
+
+func (this *CacheServer) currentSet() *serverSet {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.set
+}
+
 func (this *CacheServer) Release() {
+	this.cleanup.Close()
+}
+
+// Cleanup removes the expired records from the cache.
+func (this *CacheServer) Cleanup() error {
+	now := time.Now()
 
+	this.Lock()
+	defer this.Unlock()
+
+	for domain, record := range this.records {
+		if !record.A.Expire.After(now) {
+			delete(this.records, domain)
+		}
+	}
+	for ip, record := range this.ptrRecords {
+		if !record.Expire.After(now) {
+			delete(this.ptrRecords, ip)
+		}
+	}
+	return nil
 }
 
 // Private: Visible for testing.
 func (this *CacheServer) GetCached(domain string) []net.IP {
+	if record := this.getCachedRecord(domain); record != nil {
+		return record.IPs
+	}
+	return nil
+}
+
+func (this *CacheServer) getCachedRecord(domain string) *ARecord {
 	this.RLock()
 	defer this.RUnlock()
 
 	if record, found := this.records[domain]; found && record.A.Expire.After(time.Now()) {
-		return record.A.IPs
+		return record.A
 	}
 	return nil
 }
 
-func (this *CacheServer) Get(domain string) []net.IP {
-	if ip, found := this.hosts[domain]; found {
-		return []net.IP{ip}
+func waitRecord(response <-chan *ARecord, deadline time.Time) *ARecord {
+	select {
+	case record := <-response:
+		return record
+	case <-time.After(deadline.Sub(time.Now())):
+		return nil
 	}
+}
 
-	domain = dns.Fqdn(domain)
-	ips := this.GetCached(domain)
-	if ips != nil {
-		return ips
+// mergeRecords merges the answers of A and AAAA queries. Either may be nil if its query failed.
+func mergeRecords(a *ARecord, aaaa *ARecord) *ARecord {
+	if a == nil || (len(a.IPs) == 0 && aaaa != nil && len(aaaa.IPs) > 0) {
+		return aaaa
 	}
+	if aaaa == nil || (len(aaaa.IPs) == 0 && len(a.IPs) > 0) {
+		return a
+	}
+	merged := &ARecord{
+		IPs:       make([]net.IP, 0, len(a.IPs)+len(aaaa.IPs)),
+		TTL:       a.TTL,
+		Expire:    a.Expire,
+		Validated: a.Validated && aaaa.Validated,
+		NameError: a.NameError || aaaa.NameError,
+	}
+	merged.IPs = append(append(merged.IPs, a.IPs...), aaaa.IPs...)
+	if aaaa.TTL < merged.TTL {
+		merged.TTL = aaaa.TTL
+		merged.Expire = aaaa.Expire
+	}
+	return merged
+}
+
+// query queries the IPs of a domain from a name server, in the query strategy. It returns nil if the server fails to
+// answer in time.
+func (this *CacheServer) query(server NameServer, domain string) *ARecord {
+	deadline := time.Now().Add(QueryTimeout)
+	switch this.currentSet().strategy {
+	case QueryStrategy_USE_IP4:
+		return waitRecord(server.QueryA(domain), deadline)
+	case QueryStrategy_USE_IP6:
+		return waitRecord(server.QueryAAAA(domain), deadline)
+	default:
+		a := server.QueryA(domain)
+		aaaa := server.QueryAAAA(domain)
+		return mergeRecords(waitRecord(a, deadline), waitRecord(aaaa, deadline))
+	}
+}
+
+// cacheTTL returns the TTL of a record in cache, after the limits in config. It returns false if the record should not
+// be cached.
+func (this *CacheServer) cacheTTL(record *ARecord) (uint32, bool) {
+	cache := this.currentSet().cache
+	if cache.Disabled {
+		return 0, false
+	}
+	ttl := record.TTL
+	if len(record.IPs) == 0 {
+		if cache.DisableNegativeCache {
+			return 0, false
+		}
+		maxTTL := cache.MaxNegativeTTL
+		if maxTTL == 0 {
+			maxTTL = DefaultNegativeTTL
+		}
+		if ttl > maxTTL {
+			ttl = maxTTL
+		}
+	}
+	if cache.MinTTL > 0 && ttl < cache.MinTTL {
+		ttl = cache.MinTTL
+	}
+	if cache.MaxTTL > 0 && ttl > cache.MaxTTL {
+		ttl = cache.MaxTTL
+	}
+	return ttl, ttl > 0
+}
+
+func (this *CacheServer) store(domain string, record *ARecord) {
+	ttl, ok := this.cacheTTL(record)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	record.Expire = now.Add(time.Second * time.Duration(ttl))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if len(this.records) >= CleanupThreshold {
+		for cachedDomain, cached := range this.records {
+			if !cached.A.Expire.After(now) {
+				delete(this.records, cachedDomain)
+			}
+		}
+	}
+	this.records[domain] = &DomainRecord{
+		A: record,
+	}
+}
 
-	for _, server := range this.servers {
-		response := server.QueryA(domain)
+// FlushCache removes the cached resolutions of the given domain, or all cached resolutions if domain is empty.
+func (this *CacheServer) FlushCache(domain string) {
+	this.Lock()
+	defer this.Unlock()
+
+	if len(domain) == 0 {
+		this.records = make(map[string]*DomainRecord)
+		this.ptrRecords = make(map[string]*PTRRecord)
+		return
+	}
+	delete(this.records, dns.Fqdn(strings.ToLower(domain)))
+}
+
+func (this *CacheServer) GetFakeIP(domain string) net.IP {
+	fakeDNS := this.currentSet().fakeDNS
+	if fakeDNS == nil {
+		return nil
+	}
+	return fakeDNS.GetFakeIP(domain)
+}
+
+func (this *CacheServer) GetDomainFromFakeIP(ip net.IP) string {
+	fakeDNS := this.currentSet().fakeDNS
+	if fakeDNS == nil {
+		return ""
+	}
+	return fakeDNS.GetDomainFromFakeIP(ip)
+}
+
+// serversFor returns the name servers to query for the domain in order, which are the domain name servers matching the
+// domain, followed by the general name servers.
+func (this *serverSet) serversFor(domain string) []NameServer {
+	if len(this.domainServers) == 0 {
+		return this.servers
+	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	servers := make([]NameServer, 0, len(this.domainServers)+len(this.servers))
+	for _, domainServer := range this.domainServers {
+		if domainServer.Match(domain) {
+			servers = append(servers, domainServer.server)
+		}
+	}
+	return append(servers, this.servers...)
+}
+
+// queryServer queries a name server, and records its stats and health.
+// Answers without expected IPs count as failures in stats, but not in health, as the server is up anyway.
+func (this *CacheServer) queryServer(server NameServer, domain string) *ARecord {
+	start := time.Now()
+	expected, hasExpectedIPs := server.(*expectedIPNameServer)
+	if hasExpectedIPs {
+		server = expected.server
+	}
+	record := this.query(server, domain)
+	if record == nil {
+		this.health.OnFailure(server.Name())
+	} else {
+		this.health.OnSuccess(server.Name())
+		if hasExpectedIPs {
+			record = expected.filterRecord(domain, record)
+		}
+	}
+	this.stats.OnServerQuery(server.Name(), record != nil)
+	if record == nil {
+		this.logger.Debug("DNS: ", server.Name(), " failed to answer domain ", domain, " in ", time.Since(start))
+		return nil
+	}
+	this.logger.Debug("DNS: ", server.Name(), " answered domain ", domain, " with ", record.IPs, " in ", time.Since(start))
+	return record
+}
+
+// resolve queries the name servers of a domain, healthy ones first, and returns the first answer. Servers are queried
+// all at once in parallel mode. Otherwise the next server is queried when the previous ones failed, or didn't answer
+// within the failover timeout.
+func (this *CacheServer) resolve(domain string) *ARecord {
+	set := this.currentSet()
+	servers := this.health.Sort(set.serversFor(domain))
+	answers := make(chan *ARecord, len(servers))
+	next := 0
+	pending := 0
+	queryNext := func() {
+		server := servers[next]
+		next++
+		pending++
+		go func() {
+			answers <- this.queryServer(server, domain)
+		}()
+	}
+
+	queryNext()
+	for set.parallel && next < len(servers) {
+		queryNext()
+	}
+	for pending > 0 {
+		var failover <-chan time.Time
+		if set.failover > 0 && next < len(servers) {
+			failover = time.After(set.failover)
+		}
 		select {
-		case a, open := <-response:
-			if !open || a == nil {
-				continue
+		case record := <-answers:
+			pending--
+			if record != nil {
+				return record
 			}
-			this.Lock()
-			this.records[domain] = &DomainRecord{
-				A: a,
+			if next < len(servers) {
+				queryNext()
 			}
-			this.Unlock()
-			log.Debug("DNS: Returning ", len(a.IPs), " IPs for domain ", domain)
-			return a.IPs
-		case <-time.After(QueryTimeout):
+		case <-failover:
+			queryNext()
 		}
 	}
+	return nil
+}
+
+func (this *CacheServer) Get(domain string) []net.IP {
+	ips, _ := this.GetValidated(domain)
+	return ips
+}
+
+// GetValidated returns the IPs of a domain, and whether they are validated with DNSSEC. IPs in static hosts are
+// always validated.
+func (this *CacheServer) GetValidated(domain string) ([]net.IP, bool) {
+	record := this.lookup(domain)
+	if record == nil {
+		return nil, false
+	}
+	return record.IPs, record.Validated
+}
+
+// Lookup implements LookupServer.
+func (this *CacheServer) Lookup(domain string) ([]net.IP, bool) {
+	record := this.lookup(domain)
+	if record == nil {
+		return nil, false
+	}
+	return record.IPs, record.NameError
+}
 
-	log.Debug("DNS: Returning nil for domain ", domain)
+// lookup returns the record of a domain in static hosts, the cache or from the name servers. It returns nil if the
+// domain fails to resolve.
+func (this *CacheServer) lookup(domain string) *ARecord {
+	address, err := this.currentSet().hosts.Resolve(domain)
+	if err != nil {
+		this.logger.Warning("DNS: Failed to resolve ", domain, " in static hosts: ", err)
+		return nil
+	}
+	if !address.Family().IsDomain() {
+		return &ARecord{
+			IPs:       []net.IP{address.IP()},
+			Validated: true,
+		}
+	}
+
+	domain = dns.Fqdn(strings.ToLower(address.Domain()))
+	record := this.getCachedRecord(domain)
+	this.stats.OnQuery(record != nil)
+	if record != nil {
+		this.logger.Debug("DNS: Cache hit for domain ", domain, ": ", record.IPs)
+		return record
+	}
+
+	if record := this.resolve(domain); record != nil {
+		this.store(domain, record)
+		return record
+	}
+
+	this.stats.OnFailure()
+	this.logger.Debug("DNS: Returning nil for domain ", domain)
 	return nil
 }
+
+func (this *CacheServer) GetStats() *Stats {
+	stats := this.stats.Snapshot()
+	for name, server := range stats.Servers {
+		server.Healthy = this.health.IsHealthy(name)
+	}
+	return stats
+}
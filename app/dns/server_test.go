@@ -1,19 +1,31 @@
 package dns_test
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
 	dispatchers "v2ray.com/core/app/dispatcher/impl"
 	. "v2ray.com/core/app/dns"
 	"v2ray.com/core/app/proxyman"
+	_ "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/common/alloc"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/proxy/freedom"
 	"v2ray.com/core/testing/assert"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+
+	"github.com/miekg/dns"
 )
 
 func TestDnsAdd(t *testing.T) {
@@ -54,3 +66,721 @@ func TestDnsAdd(t *testing.T) {
 	assert.Int(len(ips)).Equals(1)
 	assert.IP(ips[0].To4()).Equals(net.IP([]byte{127, 0, 0, 1}))
 }
+
+// staticDNSDispatcher answers DNS queries to each name server with the IP of the server in ips, or NXDOMAIN if the
+// server is not in ips. Queries to the servers in down are never answered.
+type staticDNSDispatcher struct {
+	ips  map[string]net.IP
+	down map[string]bool
+}
+
+func (this *staticDNSDispatcher) Release() {}
+
+func (this *staticDNSDispatcher) DispatchToOutbound(ctx context.Context, meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo) ray.InboundRay {
+	link := ray.NewRay()
+	if this.down[session.Destination.Address.String()] {
+		go discardDNS(link)
+		return link
+	}
+	go serveStaticDNS(link, this.ips[session.Destination.Address.String()])
+	return link
+}
+
+func discardDNS(link ray.OutboundRay) {
+	for {
+		payload, err := link.OutboundInput().Read()
+		if err != nil {
+			return
+		}
+		payload.Release()
+	}
+}
+
+// answerStaticDNS answers a DNS query with the given IP, or NXDOMAIN if ip is nil. Queries of the other address family
+// are answered without records.
+func answerStaticDNS(payload *alloc.Buffer, ip net.IP) *alloc.Buffer {
+	query := new(dns.Msg)
+	if err := query.Unpack(payload.Value); err != nil {
+		return nil
+	}
+	payload.Release()
+	response := new(dns.Msg).SetReply(query)
+	if ip == nil {
+		response.Rcode = dns.RcodeNameError
+	} else if ip.To4() != nil && query.Question[0].Qtype == dns.TypeA {
+		response.Answer = append(response.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   ip,
+		})
+	} else if ip.To4() == nil && query.Question[0].Qtype == dns.TypeAAAA {
+		response.Answer = append(response.Answer, &dns.AAAA{
+			Hdr:  dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 60},
+			AAAA: ip,
+		})
+	}
+	data, err := response.Pack()
+	if err != nil {
+		return nil
+	}
+	return alloc.NewBuffer().Clear().Append(data)
+}
+
+func serveStaticDNS(link ray.OutboundRay, ip net.IP) {
+	defer link.OutboundOutput().Close()
+	for {
+		payload, err := link.OutboundInput().Read()
+		if err != nil {
+			return
+		}
+		response := answerStaticDNS(payload, ip)
+		if response == nil {
+			return
+		}
+		link.OutboundOutput().Write(response)
+	}
+}
+
+// staticDNSHandler is an outbound that answers DNS queries with its IP.
+type staticDNSHandler struct {
+	ip net.IP
+}
+
+func (this *staticDNSHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	if response := answerStaticDNS(payload, this.ip); response != nil {
+		link.OutboundOutput().Write(response)
+	}
+	serveStaticDNS(link, this.ip)
+	return nil
+}
+
+func TestDomainNameServers(t *testing.T) {
+	assert := assert.On(t)
+
+	nameServer := func(ip []byte) *v2net.DestinationPB {
+		return &v2net.DestinationPB{
+			Network: v2net.Network_UDP,
+			Address: &v2net.AddressPB{
+				Address: &v2net.AddressPB_Ip{
+					Ip: ip,
+				},
+			},
+			Port: 53,
+		}
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+		ips: map[string]net.IP{
+			"114.114.114.114": net.IP([]byte{10, 0, 0, 1}),
+			"8.8.8.8":         net.IP([]byte{10, 0, 0, 2}),
+		},
+	})
+	server := NewCacheServer(space, &Config{
+		NameServers: []*v2net.DestinationPB{nameServer([]byte{8, 8, 8, 8})},
+		Servers: []*NameServerConfig{
+			{
+				Address: nameServer([]byte{114, 114, 114, 114}),
+				Domain:  []string{"domain:cn", "full:v2ray.com"},
+			},
+		},
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	ips := server.Get("www.baidu.cn")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 1}))
+
+	ips = server.Get("V2Ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 1}))
+
+	ips = server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 2}))
+}
+
+func TestDoHNameServer(t *testing.T) {
+	assert := assert.On(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil || r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		query := new(dns.Msg)
+		if err := query.Unpack(data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := new(dns.Msg).SetReply(query)
+		response.Answer = append(response.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.IP([]byte{10, 0, 0, 3}),
+		})
+		data, _ = response.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	nameServer := NewDoHNameServer(server.URL+"/dns-query", net.Dial)
+	record, open := <-nameServer.QueryA("v2ray.com")
+	assert.Bool(open).IsTrue()
+	assert.Int(len(record.IPs)).Equals(1)
+	assert.IP(record.IPs[0].To4()).Equals(net.IP([]byte{10, 0, 0, 3}))
+
+	nameServer = NewDoHNameServer(server.URL+"/dns-query", func(network, addr string) (net.Conn, error) {
+		return nil, io.EOF
+	})
+	_, open = <-nameServer.QueryA("v2ray.com")
+	assert.Bool(open).IsFalse()
+}
+
+func TestDoTNameServerVerifiesCertificate(t *testing.T) {
+	assert := assert.On(t)
+
+	// The certificate of the test server is not trusted.
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var dialed string
+	nameServer, err := NewDoTNameServer("tls://dns.v2ray.com", func(network, addr string) (net.Conn, error) {
+		dialed = addr
+		return net.Dial("tcp", server.Listener.Addr().String())
+	})
+	assert.Error(err).IsNil()
+	_, open := <-nameServer.QueryA("v2ray.com")
+	assert.Bool(open).IsFalse()
+	assert.String(dialed).Equals("dns.v2ray.com:853")
+
+	_, err = NewDoTNameServer("tls://", net.Dial)
+	assert.Error(err).IsNotNil()
+}
+
+func TestDNSCache(t *testing.T) {
+	assert := assert.On(t)
+
+	newServer := func(cache *CacheConfig) *CacheServer {
+		space := app.NewSpace()
+		space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+			ips: map[string]net.IP{
+				"8.8.8.8": net.IP([]byte{10, 0, 0, 1}),
+			},
+		})
+		server := NewCacheServer(space, &Config{
+			Servers: []*NameServerConfig{
+				{
+					Address: &v2net.DestinationPB{
+						Network: v2net.Network_UDP,
+						Address: ipAddressPB(8, 8, 8, 8),
+						Port:    53,
+					},
+				},
+				{
+					Address: &v2net.DestinationPB{
+						Network: v2net.Network_UDP,
+						Address: ipAddressPB(8, 8, 4, 4),
+						Port:    53,
+					},
+					Domain: []string{"domain:invalid"},
+				},
+			},
+			Cache: cache,
+		})
+		space.BindApp(APP_ID, server)
+		assert.Error(space.Initialize()).IsNil()
+		return server
+	}
+
+	server := newServer(nil)
+	assert.Int(len(server.Get("www.v2ray.com"))).Equals(1)
+	assert.Int(len(server.GetCached("www.v2ray.com."))).Equals(1)
+	server.FlushCache("WWW.v2ray.com")
+	assert.Bool(server.GetCached("www.v2ray.com.") == nil).IsTrue()
+
+	assert.Int(len(server.Get("www.v2ray.invalid"))).Equals(0)
+	assert.Bool(server.GetCached("www.v2ray.invalid.") != nil).IsTrue()
+	server.FlushCache("")
+	assert.Bool(server.GetCached("www.v2ray.invalid.") == nil).IsTrue()
+
+	server = newServer(&CacheConfig{
+		DisableNegativeCache: true,
+		MaxTTL:               1,
+	})
+	assert.Int(len(server.Get("www.v2ray.invalid"))).Equals(0)
+	assert.Bool(server.GetCached("www.v2ray.invalid.") == nil).IsTrue()
+	assert.Int(len(server.Get("www.v2ray.com"))).Equals(1)
+	assert.Int(len(server.GetCached("www.v2ray.com."))).Equals(1)
+	time.Sleep(time.Millisecond * 1100)
+	assert.Bool(server.GetCached("www.v2ray.com.") == nil).IsTrue()
+
+	server = newServer(&CacheConfig{
+		Disabled: true,
+	})
+	assert.Int(len(server.Get("www.v2ray.com"))).Equals(1)
+	assert.Bool(server.GetCached("www.v2ray.com.") == nil).IsTrue()
+}
+
+func TestLookupNameError(t *testing.T) {
+	assert := assert.On(t)
+
+	// 8.8.4.4 answers NXDOMAIN for all domains.
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+		ips: map[string]net.IP{
+			"8.8.8.8": net.IP([]byte{10, 0, 0, 1}),
+		},
+	})
+	server := NewCacheServer(space, &Config{
+		Servers: []*NameServerConfig{
+			{
+				Address: &v2net.DestinationPB{
+					Network: v2net.Network_UDP,
+					Address: ipAddressPB(8, 8, 8, 8),
+					Port:    53,
+				},
+			},
+			{
+				Address: &v2net.DestinationPB{
+					Network: v2net.Network_UDP,
+					Address: ipAddressPB(8, 8, 4, 4),
+					Port:    53,
+				},
+				Domain: []string{"domain:invalid"},
+			},
+		},
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	ips, nameError := server.Lookup("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.Bool(nameError).IsFalse()
+
+	// The answer is cached with the error.
+	for i := 0; i < 2; i++ {
+		ips, nameError = server.Lookup("www.v2ray.invalid")
+		assert.Int(len(ips)).Equals(0)
+		assert.Bool(nameError).IsTrue()
+	}
+}
+
+func TestExpectedIPs(t *testing.T) {
+	assert := assert.On(t)
+
+	nameServer := func(ip ...byte) *v2net.DestinationPB {
+		return &v2net.DestinationPB{
+			Network: v2net.Network_UDP,
+			Address: ipAddressPB(ip...),
+			Port:    53,
+		}
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+		ips: map[string]net.IP{
+			"114.114.114.114": net.IP([]byte{192, 168, 0, 1}),
+			"8.8.8.8":         net.IP([]byte{10, 0, 0, 2}),
+		},
+	})
+	server := NewCacheServer(space, &Config{
+		Servers: []*NameServerConfig{
+			{
+				Address:   nameServer(114, 114, 114, 114),
+				ExpectIPs: []string{"10.0.0.0/8", "geoip:private"},
+			},
+			{
+				Address:   nameServer(8, 8, 8, 8),
+				ExpectIPs: []string{"10.0.0.0/8"},
+			},
+		},
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	ips := server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{192, 168, 0, 1}))
+
+	space = app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+		ips: map[string]net.IP{
+			"114.114.114.114": net.IP([]byte{192, 168, 0, 1}),
+			"8.8.8.8":         net.IP([]byte{10, 0, 0, 2}),
+		},
+	})
+	server = NewCacheServer(space, &Config{
+		Servers: []*NameServerConfig{
+			{
+				Address:   nameServer(114, 114, 114, 114),
+				ExpectIPs: []string{"10.0.0.0/8"},
+			},
+			{
+				Address: nameServer(8, 8, 8, 8),
+			},
+		},
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	ips = server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 2}))
+}
+
+func TestClientSubnet(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server answers with the address in the EDNS Client Subnet option, or 0.0.0.0 if there is none.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		query := new(dns.Msg)
+		if err := query.Unpack(data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ip := net.IPv4zero
+		if opt := query.IsEdns0(); opt != nil {
+			for _, option := range opt.Option {
+				if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+					ip = subnet.Address
+				}
+			}
+		}
+		response := new(dns.Msg).SetReply(query)
+		response.Answer = append(response.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   ip,
+		})
+		data, _ = response.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	nameServer := NewDoHNameServer(server.URL+"/dns-query", net.Dial)
+	record := <-nameServer.QueryA("v2ray.com")
+	assert.IP(record.IPs[0].To4()).Equals(net.IP([]byte{0, 0, 0, 0}))
+
+	nameServer.SetClientIP(net.IP([]byte{1, 2, 3, 4}))
+	record = <-nameServer.QueryA("v2ray.com")
+	assert.IP(record.IPs[0].To4()).Equals(net.IP([]byte{1, 2, 3, 0}))
+}
+
+func TestNameServerOutbound(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+		ips: map[string]net.IP{
+			"8.8.8.8": net.IP([]byte{10, 0, 0, 1}),
+		},
+	})
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	ohm.SetHandler("proxy", &staticDNSHandler{ip: net.IP([]byte{10, 0, 0, 2})})
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, ohm)
+	server := NewCacheServer(space, &Config{
+		Servers: []*NameServerConfig{
+			{
+				Address: &v2net.DestinationPB{
+					Network: v2net.Network_UDP,
+					Address: ipAddressPB(8, 8, 8, 8),
+					Port:    53,
+				},
+				OutboundTag: "proxy",
+			},
+		},
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	ips := server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 2}))
+}
+
+func TestTCPNameServer(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				dnsConn := &dns.Conn{Conn: conn}
+				for {
+					query, err := dnsConn.ReadMsg()
+					if err != nil {
+						return
+					}
+					response := new(dns.Msg).SetReply(query)
+					response.Answer = append(response.Answer, &dns.A{
+						Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+						A:   net.IP([]byte{10, 0, 0, 4}),
+					})
+					if err := dnsConn.WriteMsg(response); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	nameServer, err := NewTCPNameServer("tcp://"+listener.Addr().String(), net.Dial)
+	assert.Error(err).IsNil()
+	for i := 0; i < 2; i++ {
+		record, open := <-nameServer.QueryA("v2ray.com")
+		assert.Bool(open).IsTrue()
+		assert.Int(len(record.IPs)).Equals(1)
+		assert.IP(record.IPs[0].To4()).Equals(net.IP([]byte{10, 0, 0, 4}))
+	}
+}
+
+func TestTCPNameServerRetriesOnNewConnection(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server answers one query on each connection and closes it. The first two connections are answered together,
+	// so that both are idle afterwards.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	var access sync.Mutex
+	accepted := 0
+	both := make(chan bool)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				dnsConn := &dns.Conn{Conn: conn}
+				query, err := dnsConn.ReadMsg()
+				if err != nil {
+					return
+				}
+				access.Lock()
+				accepted++
+				if accepted == 2 {
+					close(both)
+				}
+				access.Unlock()
+				<-both
+				dnsConn.WriteMsg(new(dns.Msg).SetReply(query))
+			}()
+		}
+	}()
+
+	dials := 0
+	nameServer, err := NewTCPNameServer("tcp://"+listener.Addr().String(), func(network, addr string) (net.Conn, error) {
+		access.Lock()
+		dials++
+		access.Unlock()
+		return net.Dial(network, addr)
+	})
+	assert.Error(err).IsNil()
+
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := nameServer.Exchange(new(dns.Msg).SetQuestion("v2ray.com.", dns.TypeA))
+			results <- err
+		}()
+	}
+	assert.Error(<-results).IsNil()
+	assert.Error(<-results).IsNil()
+
+	_, err = nameServer.Exchange(new(dns.Msg).SetQuestion("v2ray.com.", dns.TypeA))
+	assert.Error(err).IsNil()
+	assert.Int(dials).Equals(3)
+}
+
+func TestQueryStrategy(t *testing.T) {
+	assert := assert.On(t)
+
+	query := func(strategy QueryStrategy, ip net.IP) []net.IP {
+		space := app.NewSpace()
+		space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+			ips: map[string]net.IP{
+				"8.8.8.8": ip,
+			},
+		})
+		server := NewCacheServer(space, &Config{
+			Servers: []*NameServerConfig{
+				{
+					Address: &v2net.DestinationPB{
+						Network: v2net.Network_UDP,
+						Address: ipAddressPB(8, 8, 8, 8),
+						Port:    53,
+					},
+				},
+			},
+			QueryStrategy: strategy,
+		})
+		space.BindApp(APP_ID, server)
+		assert.Error(space.Initialize()).IsNil()
+		return server.Get("www.v2ray.com")
+	}
+
+	ipv4 := net.IP([]byte{10, 0, 0, 1})
+	ipv6 := net.ParseIP("2001:db8::1")
+
+	ips := query(QueryStrategy_USE_IP, ipv6)
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0]).Equals(ipv6)
+	assert.Int(len(query(QueryStrategy_USE_IP, ipv4))).Equals(1)
+	assert.Int(len(query(QueryStrategy_USE_IP4, ipv4))).Equals(1)
+	assert.Int(len(query(QueryStrategy_USE_IP4, ipv6))).Equals(0)
+	assert.Int(len(query(QueryStrategy_USE_IP6, ipv6))).Equals(1)
+	assert.Int(len(query(QueryStrategy_USE_IP6, ipv4))).Equals(0)
+}
+
+func TestDNSStats(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+		ips: map[string]net.IP{
+			"114.114.114.114": net.IP([]byte{192, 168, 0, 1}),
+			"8.8.8.8":         net.IP([]byte{10, 0, 0, 2}),
+		},
+	})
+	server := NewCacheServer(space, &Config{
+		Servers: []*NameServerConfig{
+			{
+				Address: &v2net.DestinationPB{
+					Network: v2net.Network_UDP,
+					Address: ipAddressPB(114, 114, 114, 114),
+					Port:    53,
+				},
+				ExpectIPs: []string{"10.0.0.0/8"},
+			},
+			{
+				Address: &v2net.DestinationPB{
+					Network: v2net.Network_UDP,
+					Address: ipAddressPB(8, 8, 8, 8),
+					Port:    53,
+				},
+			},
+		},
+		QueryStrategy: QueryStrategy_USE_IP4,
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	assert.Int(len(server.Get("www.v2ray.com"))).Equals(1)
+	assert.Int(len(server.Get("www.v2ray.com"))).Equals(1)
+
+	stats := server.GetStats()
+	assert.Int64(int64(stats.Queries)).Equals(2)
+	assert.Int64(int64(stats.CacheHits)).Equals(1)
+	assert.Int64(int64(stats.Failures)).Equals(0)
+	assert.Int64(int64(stats.Servers["udp:114.114.114.114:53"].Queries)).Equals(1)
+	assert.Int64(int64(stats.Servers["udp:114.114.114.114:53"].Failures)).Equals(1)
+	assert.Int64(int64(stats.Servers["udp:8.8.8.8:53"].Queries)).Equals(1)
+	assert.Int64(int64(stats.Servers["udp:8.8.8.8:53"].Failures)).Equals(0)
+}
+
+func TestFailover(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, config := range []*Config{
+		{FailoverTimeout: 100},
+		{ParallelQuery: true},
+	} {
+		space := app.NewSpace()
+		space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+			ips: map[string]net.IP{
+				"8.8.8.8": net.IP([]byte{10, 0, 0, 2}),
+			},
+			down: map[string]bool{
+				"114.114.114.114": true,
+			},
+		})
+		config.NameServers = []*v2net.DestinationPB{
+			{
+				Network: v2net.Network_UDP,
+				Address: ipAddressPB(114, 114, 114, 114),
+				Port:    53,
+			},
+			{
+				Network: v2net.Network_UDP,
+				Address: ipAddressPB(8, 8, 8, 8),
+				Port:    53,
+			},
+		}
+		config.QueryStrategy = QueryStrategy_USE_IP4
+		server := NewCacheServer(space, config)
+		space.BindApp(APP_ID, server)
+		assert.Error(space.Initialize()).IsNil()
+
+		start := time.Now()
+		ips := server.Get("www.v2ray.com")
+		assert.Int(len(ips)).Equals(1)
+		assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 2}))
+		assert.Bool(time.Since(start) < QueryTimeout).IsTrue()
+
+		stats := server.GetStats()
+		assert.Int64(int64(stats.Servers["udp:8.8.8.8:53"].Queries)).Equals(1)
+		assert.Bool(stats.Servers["udp:8.8.8.8:53"].Healthy).IsTrue()
+	}
+}
+
+func TestReloadDNS(t *testing.T) {
+	assert := assert.On(t)
+
+	nameServer := func(a, b, c, d byte) *v2net.DestinationPB {
+		return &v2net.DestinationPB{
+			Network: v2net.Network_UDP,
+			Address: ipAddressPB(a, b, c, d),
+			Port:    53,
+		}
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &staticDNSDispatcher{
+		ips: map[string]net.IP{
+			"114.114.114.114": net.IP([]byte{10, 0, 0, 1}),
+			"8.8.8.8":         net.IP([]byte{10, 0, 0, 2}),
+		},
+	})
+	server := NewCacheServer(space, &Config{
+		NameServers:   []*v2net.DestinationPB{nameServer(114, 114, 114, 114)},
+		QueryStrategy: QueryStrategy_USE_IP4,
+		FakeDNS:       &FakeDNSConfig{},
+	})
+	space.BindApp(APP_ID, server)
+	assert.Error(space.Initialize()).IsNil()
+
+	ips := server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 1}))
+	fakeIP := server.GetFakeIP("www.v2ray.com")
+
+	assert.Error(server.Reload(&Config{
+		NameServers:   []*v2net.DestinationPB{nameServer(8, 8, 8, 8)},
+		QueryStrategy: QueryStrategy_USE_IP4,
+		FakeDNS:       &FakeDNSConfig{},
+	})).IsNil()
+	assert.Bool(server.GetCached("www.v2ray.com.") == nil).IsTrue()
+	ips = server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 2}))
+	assert.String(server.GetDomainFromFakeIP(fakeIP)).Equals("www.v2ray.com")
+
+	assert.Error(server.Reload(&Config{
+		NameServers: []*v2net.DestinationPB{nameServer(114, 114, 114, 114)},
+		FakeDNS:     &FakeDNSConfig{IPPool: "invalid"},
+	})).IsNotNil()
+	ips = server.Get("www.v2ray.com")
+	assert.IP(ips[0].To4()).Equals(net.IP([]byte{10, 0, 0, 2}))
+}
@@ -0,0 +1,103 @@
+package dns
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ServerStats counts the queries to a name server. A query of both IPv4 and IPv6 addresses counts as one.
+type ServerStats struct {
+	Queries  uint64
+	Failures uint64
+	// Healthy is false if the name server is considered down after consecutive failures.
+	Healthy bool
+}
+
+// Stats counts the resolutions of a Server. Resolutions by static hosts and fake DNS are not counted.
+type Stats struct {
+	Queries   uint64
+	CacheHits uint64
+	// Failures is the number of queries that no name server answered.
+	Failures uint64
+	// Servers are the stats of name servers, by their names.
+	Servers map[string]*ServerStats
+}
+
+// StatsReporter is implemented by Servers that count their resolutions.
+type StatsReporter interface {
+	GetStats() *Stats
+}
+
+type statsCounter struct {
+	sync.RWMutex
+	queries   uint64
+	cacheHits uint64
+	failures  uint64
+	servers   map[string]*ServerStats
+}
+
+func newStatsCounter() *statsCounter {
+	return &statsCounter{
+		servers: make(map[string]*ServerStats),
+	}
+}
+
+func (this *statsCounter) server(name string) *ServerStats {
+	this.RLock()
+	stats, found := this.servers[name]
+	this.RUnlock()
+	if found {
+		return stats
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if stats, found := this.servers[name]; found {
+		return stats
+	}
+	stats = new(ServerStats)
+	this.servers[name] = stats
+	return stats
+}
+
+func (this *statsCounter) OnQuery(cacheHit bool) {
+	atomic.AddUint64(&this.queries, 1)
+	if cacheHit {
+		atomic.AddUint64(&this.cacheHits, 1)
+	}
+}
+
+func (this *statsCounter) OnFailure() {
+	atomic.AddUint64(&this.failures, 1)
+}
+
+func (this *statsCounter) OnServerQuery(name string, success bool) {
+	stats := this.server(name)
+	atomic.AddUint64(&stats.Queries, 1)
+	if !success {
+		atomic.AddUint64(&stats.Failures, 1)
+	}
+}
+
+// Snapshot returns a copy of the current counters.
+func (this *statsCounter) Snapshot() *Stats {
+	stats := &Stats{
+		Queries:   atomic.LoadUint64(&this.queries),
+		CacheHits: atomic.LoadUint64(&this.cacheHits),
+		Failures:  atomic.LoadUint64(&this.failures),
+		Servers:   make(map[string]*ServerStats),
+	}
+
+	this.RLock()
+	defer this.RUnlock()
+
+	for name, server := range this.servers {
+		stats.Servers[name] = &ServerStats{
+			Queries:  atomic.LoadUint64(&server.Queries),
+			Failures: atomic.LoadUint64(&server.Failures),
+			Healthy:  true,
+		}
+	}
+	return stats
+}
@@ -0,0 +1,233 @@
+package dns
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"v2ray.com/core/common/dice"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	DefaultTCPPort = "53"
+	DefaultDoTPort = "853"
+
+	maxIdleTCPConnections = 4
+)
+
+var (
+	ErrQueryTimeout = errors.New("DNS: Query timed out.")
+)
+
+// IsTCPURL returns true if the address of a name server is a DNS-over-TCP URL, e.g. "tcp://8.8.8.8:53".
+func IsTCPURL(address string) bool {
+	return strings.HasPrefix(strings.ToLower(address), "tcp://")
+}
+
+// IsDoTURL returns true if the address of a name server is a DNS-over-TLS URL, e.g. "tls://1.1.1.1:853".
+func IsDoTURL(address string) bool {
+	return strings.HasPrefix(strings.ToLower(address), "tls://")
+}
+
+// TCPNameServer is a name server over TCP, or over TLS (RFC 7858) if it is created from a DoT URL. The certificate of a
+// DoT server is verified against the host in its URL. Idle connections are kept in a pool for later queries.
+type TCPNameServer struct {
+	url       string
+	address   string
+	tlsConfig *tls.Config
+	dial      DialFunc
+	idle      chan net.Conn
+	clientIP  net.IP
+}
+
+// parseServerURL returns the host and the address of a name server URL in the form of "scheme://host[:port]".
+func parseServerURL(url string, defaultPort string) (string, string, error) {
+	address := url
+	if idx := strings.Index(address, "://"); idx >= 0 {
+		address = address[idx+3:]
+	}
+	if idx := strings.IndexByte(address, '/'); idx >= 0 {
+		address = address[:idx]
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host = strings.Trim(address, "[]")
+		port = defaultPort
+	}
+	if len(host) == 0 {
+		return "", "", errors.New("DNS: Invalid name server URL: " + url)
+	}
+	return host, net.JoinHostPort(host, port), nil
+}
+
+// NewTCPNameServer creates a TCPNameServer from a URL in the form of "tcp://host[:port]". The port is 53 if not
+// specified.
+func NewTCPNameServer(url string, dial DialFunc) (*TCPNameServer, error) {
+	_, address, err := parseServerURL(url, DefaultTCPPort)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPNameServer{
+		url:     url,
+		address: address,
+		dial:    dial,
+		idle:    make(chan net.Conn, maxIdleTCPConnections),
+	}, nil
+}
+
+// NewDoTNameServer creates a TCPNameServer from a URL in the form of "tls://host[:port]". The port is 853 if not
+// specified.
+func NewDoTNameServer(url string, dial DialFunc) (*TCPNameServer, error) {
+	host, address, err := parseServerURL(url, DefaultDoTPort)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPNameServer{
+		url:     url,
+		address: address,
+		tlsConfig: &tls.Config{
+			ServerName: host,
+		},
+		dial: dial,
+		idle: make(chan net.Conn, maxIdleTCPConnections),
+	}, nil
+}
+
+// SetClientIP sets the IP sent in the EDNS Client Subnet option of queries.
+func (this *TCPNameServer) SetClientIP(ip net.IP) {
+	this.clientIP = ip
+}
+
+func (this *TCPNameServer) idleConnection() net.Conn {
+	select {
+	case conn := <-this.idle:
+		return conn
+	default:
+		return nil
+	}
+}
+
+// dialConnection dials a new connection, and completes the TLS handshake within QueryTimeout for a DoT server.
+func (this *TCPNameServer) dialConnection() (net.Conn, error) {
+	rawConn, err := this.dial("tcp", this.address)
+	if err != nil {
+		return nil, err
+	}
+	if this.tlsConfig == nil {
+		return rawConn, nil
+	}
+	conn := tls.Client(rawConn, this.tlsConfig)
+	conn.SetDeadline(time.Now().Add(QueryTimeout))
+	if err := conn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (this *TCPNameServer) putConnection(conn net.Conn) {
+	select {
+	case this.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func exchangeTCP(conn net.Conn, query *dns.Msg) (*dns.Msg, error) {
+	data, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(buffer, uint16(len(data)))
+	copy(buffer[2:], data)
+	if _, err := conn.Write(buffer); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(conn, buffer[:2]); err != nil {
+		return nil, err
+	}
+	buffer = make([]byte, binary.BigEndian.Uint16(buffer))
+	if _, err := io.ReadFull(conn, buffer); err != nil {
+		return nil, err
+	}
+	response := new(dns.Msg)
+	if err := response.Unpack(buffer); err != nil {
+		return nil, err
+	}
+	if response.Id != query.Id {
+		return nil, errors.New("DNS: Mismatched response ID.")
+	}
+	return response, nil
+}
+
+// exchange sends the query on a connection and waits for the response. The connection is closed on failure, as its
+// state is unknown.
+func (this *TCPNameServer) exchange(conn net.Conn, query *dns.Msg) (*dns.Msg, error) {
+	type result struct {
+		response *dns.Msg
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := exchangeTCP(conn, query)
+		done <- result{response: response, err: err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			conn.Close()
+			return nil, r.err
+		}
+		this.putConnection(conn)
+		return r.response, nil
+	case <-time.After(QueryTimeout):
+		conn.Close()
+		return nil, ErrQueryTimeout
+	}
+}
+
+// Exchange sends a query to the server and waits for the response. The ID of the query is assigned randomly. An idle
+// connection is reused if any.
+func (this *TCPNameServer) Exchange(query *dns.Msg) (*dns.Msg, error) {
+	query.Id = uint16(dice.Roll(65536))
+	if conn := this.idleConnection(); conn != nil {
+		response, err := this.exchange(conn, query)
+		if err == nil {
+			return response, nil
+		}
+		// The server may have closed the idle connection, and so may have the other idle ones. Retry on a new one.
+	}
+	conn, err := this.dialConnection()
+	if err != nil {
+		return nil, err
+	}
+	return this.exchange(conn, query)
+}
+
+func (this *TCPNameServer) query(domain string, qtype uint16) (*ARecord, error) {
+	response, err := this.Exchange(buildQuery(domain, 0, qtype, this.clientIP))
+	if err != nil {
+		return nil, err
+	}
+	return parseARecord(response), nil
+}
+
+func (this *TCPNameServer) Name() string {
+	return this.url
+}
+
+func (this *TCPNameServer) QueryA(domain string) <-chan *ARecord {
+	return queryAsync(this.url, domain, dns.TypeA, this.query)
+}
+
+func (this *TCPNameServer) QueryAAAA(domain string) <-chan *ARecord {
+	return queryAsync(this.url, domain, dns.TypeAAAA, this.query)
+}
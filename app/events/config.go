@@ -0,0 +1,60 @@
+package events
+
+import (
+	"errors"
+	"time"
+
+	"v2ray.com/core/common/event"
+)
+
+const (
+	DefaultCertificateExpiry = 14 * 24 * time.Hour
+)
+
+type WebhookConfig struct {
+	// URL receives events in JSON by POST.
+	URL string
+	// Types are the types of events to send. All events are sent if empty.
+	Types []event.Type
+}
+
+type ExecConfig struct {
+	// Command is run for each event, with the event in JSON as stdin.
+	Command string
+	Args    []string
+	// Types are the types of events to send. All events are sent if empty.
+	Types []event.Type
+}
+
+type Config struct {
+	Webhooks []*WebhookConfig
+	Execs    []*ExecConfig
+	// AuthFailureLimit is the number of authentication failures of a source within AuthFailureWindow that publishes an
+	// event.AuthFailures.
+	AuthFailureLimit  int
+	AuthFailureWindow time.Duration
+	// CertificateExpiry is how long before expiry that TLS certificates are reported.
+	CertificateExpiry time.Duration
+}
+
+var (
+	knownTypes = map[event.Type]bool{
+		event.OutboundUnhealthy:   true,
+		event.OutboundHealthy:     true,
+		event.AuthFailures:        true,
+		event.SourceBanned:        true,
+		event.CertificateExpiring: true,
+	}
+)
+
+// ParseTypes parses the types of events in configs, and rejects unknown ones.
+func ParseTypes(types []string) ([]event.Type, error) {
+	parsed := make([]event.Type, len(types))
+	for idx, t := range types {
+		parsed[idx] = event.Type(t)
+		if !knownTypes[parsed[idx]] {
+			return nil, errors.New("Events: Unknown event type: " + t)
+		}
+	}
+	return parsed, nil
+}
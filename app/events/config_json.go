@@ -0,0 +1,82 @@
+// +build json
+
+package events
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/proxy"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonWebhookConfig struct {
+		URL   string   `json:"url"`
+		Types []string `json:"types"`
+	}
+	type JsonExecConfig struct {
+		Command string   `json:"command"`
+		Args    []string `json:"args"`
+		Types   []string `json:"types"`
+	}
+	type JsonConfig struct {
+		Webhooks     []*JsonWebhookConfig `json:"webhooks"`
+		Execs        []*JsonExecConfig    `json:"exec"`
+		AuthFailures *struct {
+			Limit  int    `json:"limit"`
+			Window uint32 `json:"window"`
+		} `json:"authFailures"`
+		CertificateExpiry uint32 `json:"certificateExpiry"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Events: Failed to parse config: ", err)
+	}
+	this.Webhooks = make([]*WebhookConfig, len(jsonConfig.Webhooks))
+	for idx, webhook := range jsonConfig.Webhooks {
+		target, err := url.Parse(webhook.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+			return errors.New("Events: Invalid webhook URL: " + webhook.URL)
+		}
+		types, err := ParseTypes(webhook.Types)
+		if err != nil {
+			return err
+		}
+		this.Webhooks[idx] = &WebhookConfig{
+			URL:   webhook.URL,
+			Types: types,
+		}
+	}
+	this.Execs = make([]*ExecConfig, len(jsonConfig.Execs))
+	for idx, execConfig := range jsonConfig.Execs {
+		if len(execConfig.Command) == 0 {
+			return errors.New("Events: Command is not specified.")
+		}
+		types, err := ParseTypes(execConfig.Types)
+		if err != nil {
+			return err
+		}
+		this.Execs[idx] = &ExecConfig{
+			Command: execConfig.Command,
+			Args:    execConfig.Args,
+			Types:   types,
+		}
+	}
+	this.AuthFailureLimit = proxy.DefaultAuthFailureLimit
+	this.AuthFailureWindow = proxy.DefaultAuthFailureWindow
+	if authFailures := jsonConfig.AuthFailures; authFailures != nil {
+		// Zero limit disables the event.
+		this.AuthFailureLimit = authFailures.Limit
+		if authFailures.Window > 0 {
+			this.AuthFailureWindow = time.Duration(authFailures.Window) * time.Second
+		}
+	}
+	// Expiry is in days.
+	this.CertificateExpiry = DefaultCertificateExpiry
+	if jsonConfig.CertificateExpiry > 0 {
+		this.CertificateExpiry = time.Duration(jsonConfig.CertificateExpiry) * 24 * time.Hour
+	}
+	return nil
+}
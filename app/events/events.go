@@ -0,0 +1,274 @@
+// Package events sends events of the event bus to operators, by webhooks or commands.
+package events
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/event"
+	"v2ray.com/core/common/log"
+)
+
+const (
+	APP_ID = app.ID(11)
+
+	sinkQueueSize            = 64
+	webhookTimeout           = 10 * time.Second
+	execTimeout              = 30 * time.Second
+	certificateCheckInterval = 12 * time.Hour
+)
+
+var (
+	ErrAlreadyStarted = errors.New("Events: Notifier is already started.")
+)
+
+// sink sends events of some types in background. Events are dropped if the queue is full.
+type sink struct {
+	name  string
+	types map[event.Type]bool
+	queue chan *event.Event
+	send  func(content []byte, e *event.Event) error
+}
+
+func newSink(name string, types []event.Type, send func(content []byte, e *event.Event) error) *sink {
+	s := &sink{
+		name:  name,
+		types: make(map[event.Type]bool, len(types)),
+		queue: make(chan *event.Event, sinkQueueSize),
+		send:  send,
+	}
+	for _, t := range types {
+		s.types[t] = true
+	}
+	return s
+}
+
+func (this *sink) handle(e *event.Event) {
+	if len(this.types) > 0 && !this.types[e.Type] {
+		return
+	}
+	select {
+	case this.queue <- e:
+	default:
+		log.Warning("Events: Dropped event ", e.Type, " to ", this.name)
+	}
+}
+
+// run sends the queued events until done is closed. The queue is never closed, so that handle may be called any time.
+func (this *sink) run(done chan bool) {
+	for {
+		var e *event.Event
+		select {
+		case e = <-this.queue:
+		case <-done:
+			return
+		}
+		content, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		if err := this.send(content, e); err != nil {
+			log.Warning("Events: Failed to send event ", e.Type, " to ", this.name, ": ", err)
+		}
+	}
+}
+
+func sendWebhook(client *http.Client, url string) func(content []byte, e *event.Event) error {
+	return func(content []byte, e *event.Event) error {
+		response, err := client.Post(url, "application/json", bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode >= 300 {
+			return errors.New("Events: Unexpected status of webhook: " + response.Status)
+		}
+		return nil
+	}
+}
+
+func runCommand(command string, args []string) func(content []byte, e *event.Event) error {
+	return func(content []byte, e *event.Event) error {
+		cmd := exec.Command(command, args...)
+		cmd.Stdin = bytes.NewReader(content)
+		cmd.Env = append(os.Environ(), "V2RAY_EVENT_TYPE="+string(e.Type), "V2RAY_EVENT_MESSAGE="+e.Message)
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		timer := time.AfterFunc(execTimeout, func() {
+			cmd.Process.Kill()
+		})
+		defer timer.Stop()
+		return cmd.Wait()
+	}
+}
+
+// Notifier sends events to the configured webhooks and commands. It also reports TLS certificates in use that are
+// about to expire. It owns the event bus of the instance.
+type Notifier struct {
+	sync.Mutex
+	config       *Config
+	certificates []*x509.Certificate
+	bus          *event.Bus
+	authFailures *event.Threshold
+	sinks        []*sink
+	unsubscribe  func()
+	done         chan bool
+}
+
+// CertificateSource is implemented by inbound handler managers, to report the TLS certificates in use.
+type CertificateSource interface {
+	Certificates() []*x509.Certificate
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "events",
+		Config: (*Config)(nil),
+		After:  []app.ID{applog.APP_ID},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			notifier := NewNotifier(config.(*Config), nil)
+			space.InitializeApplication(func() error {
+				if source, ok := space.GetApp(proxyman.APP_ID_INBOUND_MANAGER).(CertificateSource); ok {
+					notifier.certificates = source.Certificates()
+				}
+				return nil
+			})
+			return notifier, nil
+		},
+	})
+}
+
+// NewNotifier returns a Notifier that checks the expiry of the given certificates.
+func NewNotifier(config *Config, certificates []*x509.Certificate) *Notifier {
+	return &Notifier{
+		config:       config,
+		certificates: certificates,
+		bus:          event.NewBus(),
+		authFailures: event.NewThreshold(config.AuthFailureLimit, config.AuthFailureWindow),
+	}
+}
+
+// Bus returns the event bus of the instance.
+func (this *Notifier) Bus() *event.Bus {
+	return this.bus
+}
+
+// FromSpace returns the event bus of the notifier in the space, or nil if there is none, which drops events. The
+// notifier is bound before any proxy handler is created, so handlers may call it on creation.
+func FromSpace(space app.Space) *event.Bus {
+	if !space.HasApp(APP_ID) {
+		return nil
+	}
+	return space.GetApp(APP_ID).(*Notifier).Bus()
+}
+
+// countAuthFailure counts an event.AuthFailure, and publishes an event.AuthFailures if its source failed too many
+// times on the inbound.
+func (this *Notifier) countAuthFailure(e *event.Event) {
+	inbound := e.Fields["inbound"]
+	source := e.Fields["source"]
+	count := this.authFailures.Hit(inbound + "|" + source)
+	if count == 0 {
+		return
+	}
+	log.Warning("Events: ", count, " authentication failures from ", source, " on inbound [", inbound, "].")
+	this.bus.Publish(event.AuthFailures, "Too many authentication failures from "+source+".", map[string]string{
+		"inbound": inbound,
+		"source":  source,
+		"count":   strconv.Itoa(count),
+	})
+}
+
+func (this *Notifier) Start() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.unsubscribe != nil {
+		return ErrAlreadyStarted
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	this.sinks = make([]*sink, 0, len(this.config.Webhooks)+len(this.config.Execs))
+	for _, webhook := range this.config.Webhooks {
+		this.sinks = append(this.sinks, newSink(webhook.URL, webhook.Types, sendWebhook(client, webhook.URL)))
+	}
+	for _, execConfig := range this.config.Execs {
+		this.sinks = append(this.sinks, newSink(execConfig.Command, execConfig.Types, runCommand(execConfig.Command, execConfig.Args)))
+	}
+	this.done = make(chan bool)
+	for _, s := range this.sinks {
+		go s.run(this.done)
+	}
+	sinks := this.sinks
+	this.unsubscribe = this.bus.Subscribe(func(e *event.Event) {
+		if e.Type == event.AuthFailure {
+			this.countAuthFailure(e)
+			return
+		}
+		for _, s := range sinks {
+			s.handle(e)
+		}
+	})
+
+	go this.watchCertificates(this.done)
+	return nil
+}
+
+func (this *Notifier) watchCertificates(done chan bool) {
+	if len(this.certificates) == 0 {
+		return
+	}
+	for {
+		this.CheckCertificates(time.Now())
+		select {
+		case <-done:
+			return
+		case <-time.After(certificateCheckInterval):
+		}
+	}
+}
+
+// CheckCertificates publishes an event.CertificateExpiring for each certificate that expires within the configured
+// time after now.
+func (this *Notifier) CheckCertificates(now time.Time) {
+	for _, cert := range this.certificates {
+		if cert.NotAfter.Sub(now) > this.config.CertificateExpiry {
+			continue
+		}
+		name := cert.Subject.CommonName
+		if len(cert.DNSNames) > 0 {
+			name = strings.Join(cert.DNSNames, ",")
+		}
+		log.Warning("Events: Certificate of ", name, " expires at ", cert.NotAfter)
+		this.bus.Publish(event.CertificateExpiring, "Certificate of "+name+" expires at "+cert.NotAfter.Format(time.RFC3339)+".", map[string]string{
+			"names":    name,
+			"notAfter": cert.NotAfter.Format(time.RFC3339),
+		})
+	}
+}
+
+func (this *Notifier) Release() {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.unsubscribe == nil {
+		return
+	}
+	this.unsubscribe()
+	this.unsubscribe = nil
+	close(this.done)
+	this.sinks = nil
+}
@@ -0,0 +1,99 @@
+package events_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/app/events"
+	"v2ray.com/core/common/event"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestWebhook(t *testing.T) {
+	assert := assert.On(t)
+
+	received := make(chan *event.Event, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := new(event.Event)
+		if err := json.NewDecoder(r.Body).Decode(e); err == nil {
+			received <- e
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(&Config{
+		Webhooks: []*WebhookConfig{{
+			URL:   server.URL,
+			Types: []event.Type{event.OutboundUnhealthy},
+		}},
+	}, nil)
+	assert.Error(notifier.Start()).IsNil()
+	defer notifier.Release()
+
+	notifier.Bus().Publish(event.OutboundHealthy, "Outbound is healthy.", nil)
+	notifier.Bus().Publish(event.OutboundUnhealthy, "Outbound is unhealthy.", map[string]string{"outbound": "proxy"})
+
+	select {
+	case e := <-received:
+		assert.String(string(e.Type)).Equals(string(event.OutboundUnhealthy))
+		assert.String(e.Fields["outbound"]).Equals("proxy")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Webhook is not called.")
+	}
+	select {
+	case e := <-received:
+		t.Fatal("Unexpected event: ", e.Type)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	assert := assert.On(t)
+
+	notifier := NewNotifier(&Config{
+		AuthFailureLimit:  2,
+		AuthFailureWindow: time.Minute,
+	}, nil)
+	assert.Error(notifier.Start()).IsNil()
+	defer notifier.Release()
+
+	var received []*event.Event
+	unsubscribe := notifier.Bus().Subscribe(func(e *event.Event) {
+		if e.Type == event.AuthFailures {
+			received = append(received, e)
+		}
+	})
+	defer unsubscribe()
+
+	fields := map[string]string{"inbound": "vmess", "source": "1.2.3.4"}
+	notifier.Bus().Publish(event.AuthFailure, "Authentication failure from 1.2.3.4.", fields)
+	assert.Int(len(received)).Equals(0)
+	notifier.Bus().Publish(event.AuthFailure, "Authentication failure from 1.2.3.4.", fields)
+	assert.Int(len(received)).Equals(1)
+	assert.String(received[0].Fields["source"]).Equals("1.2.3.4")
+	assert.String(received[0].Fields["count"]).Equals("2")
+}
+
+func TestReleaseWhilePublishing(t *testing.T) {
+	assert := assert.On(t)
+
+	notifier := NewNotifier(&Config{
+		Webhooks: []*WebhookConfig{{
+			URL: "http://127.0.0.1:1/",
+		}},
+	}, nil)
+	assert.Error(notifier.Start()).IsNil()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			notifier.Bus().Publish(event.OutboundUnhealthy, "Outbound is unhealthy.", nil)
+		}
+		close(done)
+	}()
+	notifier.Release()
+	<-done
+}
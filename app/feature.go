@@ -0,0 +1,160 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+
+	"v2ray.com/core"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
+)
+
+var (
+	ErrUnknownFeature = errors.New("App: Unknown feature.")
+	ErrFeatureCycle   = errors.New("App: Features depend on each other.")
+)
+
+// Startable is an application that runs in background once started, e.g. a server.
+type Startable interface {
+	Start() error
+}
+
+// FeatureCreator creates an application from its config. The application may resolve other applications in the
+// space on initialization.
+type FeatureCreator func(space Space, config interface{}) (Application, error)
+
+// Feature describes an application that is created from its config, or is only bound to spaces by shells, e.g. the
+// dispatcher.
+type Feature struct {
+	ID   ID
+	Name string
+	// Config is a nil pointer of the config type, e.g. (*dns.Config)(nil). Configs of this type create the feature.
+	// Features without configs are not created by CreateFeature.
+	Config interface{}
+	// After are the applications that are started before, and released after, this feature, if they are in the same
+	// space.
+	After  []ID
+	Create FeatureCreator
+}
+
+var (
+	featureAccess    sync.RWMutex
+	featuresByID     = make(map[ID]*Feature)
+	featuresByConfig = make(map[reflect.Type]*Feature)
+)
+
+// RegisterFeature registers a feature, which is usually called on init of its package.
+func RegisterFeature(feature *Feature) error {
+	featureAccess.Lock()
+	defer featureAccess.Unlock()
+
+	if _, found := featuresByID[feature.ID]; found {
+		return common.ErrDuplicatedName
+	}
+	var configType reflect.Type
+	if feature.Config != nil {
+		configType = reflect.TypeOf(feature.Config)
+		if _, found := featuresByConfig[configType]; found {
+			return common.ErrDuplicatedName
+		}
+		if feature.Create == nil {
+			return common.ErrBadConfiguration
+		}
+	}
+	featuresByID[feature.ID] = feature
+	if configType != nil {
+		featuresByConfig[configType] = feature
+	}
+	core.RegisterFeature("app:" + feature.Name)
+	return nil
+}
+
+// MustRegisterFeature registers a feature, and panics on error.
+func MustRegisterFeature(feature *Feature) {
+	if err := RegisterFeature(feature); err != nil {
+		panic(err)
+	}
+}
+
+// GetFeature returns the feature of the given ID, or nil if not registered.
+func GetFeature(id ID) *Feature {
+	featureAccess.RLock()
+	defer featureAccess.RUnlock()
+
+	return featuresByID[id]
+}
+
+// CreateFeature creates the application of the given config, and binds it to the space by its feature ID.
+func CreateFeature(space Space, config interface{}) (Application, error) {
+	featureAccess.RLock()
+	feature, found := featuresByConfig[reflect.TypeOf(config)]
+	featureAccess.RUnlock()
+	if !found {
+		log.Error("App: No feature for config of type ", reflect.TypeOf(config))
+		return nil, ErrUnknownFeature
+	}
+	application, err := feature.Create(space, config)
+	if err != nil {
+		log.Error("App: Failed to create ", feature.Name, ": ", err)
+		return nil, err
+	}
+	space.BindApp(feature.ID, application)
+	return application, nil
+}
+
+// StartOrder sorts the given applications, so that each one is after those it starts after. Applications not
+// depending on each other are sorted by ID, so the order is the same on every run.
+func StartOrder(ids []ID) ([]ID, error) {
+	present := make(map[ID]bool, len(ids))
+	for _, id := range ids {
+		present[id] = true
+	}
+
+	pending := make(map[ID]int, len(ids))
+	dependents := make(map[ID][]ID, len(ids))
+	for id := range present {
+		pending[id] = 0
+		feature := GetFeature(id)
+		if feature == nil {
+			continue
+		}
+		for _, after := range feature.After {
+			if !present[after] || after == id {
+				continue
+			}
+			pending[id]++
+			dependents[after] = append(dependents[after], id)
+		}
+	}
+
+	order := make([]ID, 0, len(pending))
+	for len(pending) > 0 {
+		next := ID(-1)
+		for id, count := range pending {
+			if count == 0 && (next < 0 || id < next) {
+				next = id
+			}
+		}
+		if next < 0 {
+			return nil, ErrFeatureCycle
+		}
+		delete(pending, next)
+		order = append(order, next)
+		for _, id := range dependents[next] {
+			pending[id]--
+		}
+	}
+	return order, nil
+}
+
+type idSlice []ID
+
+func (this idSlice) Len() int           { return len(this) }
+func (this idSlice) Less(i, j int) bool { return this[i] < this[j] }
+func (this idSlice) Swap(i, j int)      { this[i], this[j] = this[j], this[i] }
+
+func sortIDs(ids []ID) {
+	sort.Sort(idSlice(ids))
+}
@@ -0,0 +1,126 @@
+package app_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/app"
+	"v2ray.com/core/common"
+	"v2ray.com/core/testing/assert"
+)
+
+type greeter interface {
+	Greet() string
+}
+
+type testConfig struct {
+	Greeting string
+}
+
+type testApp struct {
+	greeting string
+}
+
+func (this *testApp) Greet() string {
+	return this.greeting
+}
+
+func (this *testApp) Release() {}
+
+type otherApp struct{}
+
+func (this *otherApp) Release() {}
+
+const (
+	testID = ID(1001) + iota
+	firstID
+	secondID
+	thirdID
+	cycleAID
+	cycleBID
+)
+
+func init() {
+	MustRegisterFeature(&Feature{
+		ID:     testID,
+		Name:   "test",
+		Config: (*testConfig)(nil),
+		Create: func(space Space, config interface{}) (Application, error) {
+			return &testApp{greeting: config.(*testConfig).Greeting}, nil
+		},
+	})
+	MustRegisterFeature(&Feature{ID: firstID, Name: "first", After: []ID{thirdID}})
+	MustRegisterFeature(&Feature{ID: secondID, Name: "second"})
+	MustRegisterFeature(&Feature{ID: thirdID, Name: "third", After: []ID{secondID, ID(999)}})
+	MustRegisterFeature(&Feature{ID: cycleAID, Name: "cycleA", After: []ID{cycleBID}})
+	MustRegisterFeature(&Feature{ID: cycleBID, Name: "cycleB", After: []ID{cycleAID}})
+}
+
+func TestDuplicatedFeature(t *testing.T) {
+	assert := assert.On(t)
+
+	assert.Error(RegisterFeature(&Feature{ID: testID, Name: "again"})).Equals(common.ErrDuplicatedName)
+	assert.Error(RegisterFeature(&Feature{
+		ID:     ID(2001),
+		Name:   "again",
+		Config: (*testConfig)(nil),
+		Create: func(space Space, config interface{}) (Application, error) { return nil, nil },
+	})).Equals(common.ErrDuplicatedName)
+}
+
+func TestCreateFeature(t *testing.T) {
+	assert := assert.On(t)
+
+	space := NewSpace()
+	application, err := CreateFeature(space, &testConfig{Greeting: "hello"})
+	assert.Error(err).IsNil()
+	assert.Bool(space.GetApp(testID) == application).IsTrue()
+
+	_, err = CreateFeature(space, "unknown")
+	assert.Error(err).Equals(ErrUnknownFeature)
+}
+
+func TestStartOrder(t *testing.T) {
+	assert := assert.On(t)
+
+	order, err := StartOrder([]ID{firstID, thirdID, testID, secondID})
+	assert.Error(err).IsNil()
+	assert.Int(len(order)).Equals(4)
+	assert.Int(int(order[0])).Equals(int(testID))
+	assert.Int(int(order[1])).Equals(int(secondID))
+	assert.Int(int(order[2])).Equals(int(thirdID))
+	assert.Int(int(order[3])).Equals(int(firstID))
+
+	// Features not in the space don't affect the order.
+	order, err = StartOrder([]ID{firstID, secondID})
+	assert.Error(err).IsNil()
+	assert.Int(int(order[0])).Equals(int(firstID))
+	assert.Int(int(order[1])).Equals(int(secondID))
+
+	_, err = StartOrder([]ID{cycleAID, cycleBID})
+	assert.Error(err).Equals(ErrFeatureCycle)
+}
+
+func TestRequireFeatures(t *testing.T) {
+	assert := assert.On(t)
+
+	space := NewSpace()
+	space.BindApp(ID(3), &otherApp{})
+	space.BindApp(ID(2), &testApp{greeting: "second"})
+	space.BindApp(ID(1), &testApp{greeting: "first"})
+
+	var greeting string
+	assert.Error(space.RequireFeatures(func(g greeter) {
+		greeting = g.Greet()
+	})).IsNil()
+	assert.Error(space.RequireFeatures("not a function")).Equals(ErrBadFeatureCallback)
+	assert.Error(space.RequireFeatures(func(app *testApp) {})).Equals(ErrBadFeatureCallback)
+	assert.Error(space.Initialize()).IsNil()
+	assert.String(greeting).Equals("first")
+
+	space = NewSpace()
+	space.BindApp(ID(1), &otherApp{})
+	assert.Error(space.RequireFeatures(func(g greeter) error {
+		return nil
+	})).IsNil()
+	assert.Error(space.Initialize()).Equals(ErrMissingApplication)
+}
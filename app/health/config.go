@@ -0,0 +1,20 @@
+package health
+
+import (
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	DefaultPath = "/healthz"
+)
+
+type Config struct {
+	// Listen is the address that the endpoint listens on, all addresses by default so that load balancers can reach
+	// it.
+	Listen v2net.Address
+	Port   v2net.Port
+	// Path is the HTTP path of the endpoint, DefaultPath if empty.
+	Path string
+	// Outbounds are the tags of critical outbounds, which must pass the health probes of the router.
+	Outbounds []string
+}
@@ -0,0 +1,165 @@
+// Package health serves an HTTP endpoint that reports whether a running V2Ray instance is healthy, for load balancers
+// and probes of Kubernetes.
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/log"
+)
+
+const (
+	APP_ID = app.ID(13)
+
+	OutboundAlive   = "alive"
+	OutboundDead    = "dead"
+	OutboundUnknown = "unknown"
+)
+
+var (
+	ErrAlreadyStarted = errors.New("Health: Server is already started.")
+)
+
+// InboundReporter is implemented by inbound handler managers, to report whether inbounds are accepting connections by
+// their tags.
+type InboundReporter interface {
+	InboundStatus() map[string]bool
+}
+
+// Status is the health of an instance. It is healthy if all inbounds are accepting connections and no critical
+// outbound is dead. Outbounds that are not probed yet are unknown, which doesn't fail the check.
+type Status struct {
+	Healthy   bool              `json:"healthy"`
+	Inbounds  map[string]bool   `json:"inbounds"`
+	Outbounds map[string]string `json:"outbounds,omitempty"`
+}
+
+type Server struct {
+	config   *Config
+	server   *http.Server
+	listener net.Listener
+	inbounds InboundReporter
+	router   router.HealthReporter
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "health",
+		Config: (*Config)(nil),
+		// The endpoint starts after inbounds, so that probes don't pass before the server accepts connections.
+		After: []app.ID{proxyman.APP_ID_INBOUND_MANAGER, router.APP_ID},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewServer(space, config.(*Config)), nil
+		},
+	})
+}
+
+func NewServer(space app.Space, config *Config) *Server {
+	server := &Server{
+		config: config,
+	}
+	path := config.Path
+	if len(path) == 0 {
+		path = DefaultPath
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, server.serveHealth)
+	server.server = &http.Server{
+		Handler: mux,
+	}
+	space.InitializeApplication(func() error {
+		if reporter, ok := space.GetApp(proxyman.APP_ID_INBOUND_MANAGER).(InboundReporter); ok {
+			server.inbounds = reporter
+		}
+		if reporter, ok := space.GetApp(router.APP_ID).(router.HealthReporter); ok {
+			server.router = reporter
+		} else if len(config.Outbounds) > 0 {
+			log.Warning("Health: Outbounds are not probed by the router, so their health is unknown.")
+		}
+		return nil
+	})
+	return server
+}
+
+// Start listens on the configured port, and serves the endpoint in background.
+func (this *Server) Start() error {
+	if this.listener != nil {
+		return ErrAlreadyStarted
+	}
+	address := net.JoinHostPort(this.config.Listen.String(), this.config.Port.String())
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Error("Health: Failed to listen on ", address, ": ", err)
+		return err
+	}
+	this.listener = listener
+	log.Info("Health: Listening on ", address)
+	go this.server.Serve(listener)
+	return nil
+}
+
+func (this *Server) Release() {
+	if this.listener != nil {
+		this.server.Close()
+		this.listener = nil
+	}
+}
+
+// Check returns the current health.
+func (this *Server) Check() *Status {
+	status := &Status{
+		Healthy:  true,
+		Inbounds: make(map[string]bool),
+	}
+	if this.inbounds != nil {
+		status.Inbounds = this.inbounds.InboundStatus()
+	}
+	for _, accepting := range status.Inbounds {
+		if !accepting {
+			status.Healthy = false
+		}
+	}
+	if len(this.config.Outbounds) > 0 {
+		status.Outbounds = make(map[string]string, len(this.config.Outbounds))
+		for _, tag := range this.config.Outbounds {
+			status.Outbounds[tag] = OutboundUnknown
+			if this.router == nil {
+				continue
+			}
+			outboundStatus := this.router.GetStatus(tag)
+			if outboundStatus == nil {
+				continue
+			}
+			if outboundStatus.Alive {
+				status.Outbounds[tag] = OutboundAlive
+			} else {
+				status.Outbounds[tag] = OutboundDead
+				status.Healthy = false
+			}
+		}
+	}
+	return status
+}
+
+// serveHealth responds the health in JSON, with status 200 if healthy, or 503 otherwise.
+func (this *Server) serveHealth(writer http.ResponseWriter, request *http.Request) {
+	status := this.Check()
+	content, err := json.Marshal(status)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+	if !status.Healthy {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writer.Write(content)
+}
@@ -0,0 +1,74 @@
+package health_test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"v2ray.com/core/app"
+	. "v2ray.com/core/app/health"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+)
+
+type inbounds map[string]bool
+
+func (this inbounds) InboundStatus() map[string]bool {
+	return this
+}
+
+func (this inbounds) Release() {}
+
+type outbounds map[string]*router.OutboundStatus
+
+func (this outbounds) GetStatus(tag string) *router.OutboundStatus {
+	return this[tag]
+}
+
+func (this outbounds) Release() {}
+
+func TestHealthServer(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	status := inbounds{"inbound": true, "vmess": true}
+	probes := outbounds{"proxy": &router.OutboundStatus{Alive: true}}
+	space := app.NewSpace()
+	space.BindApp(proxyman.APP_ID_INBOUND_MANAGER, status)
+	space.BindApp(router.APP_ID, probes)
+	server := NewServer(space, &Config{
+		Listen:    v2net.LocalHostIP,
+		Port:      v2net.Port(port),
+		Path:      DefaultPath,
+		Outbounds: []string{"proxy", "backup"},
+	})
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+	defer server.Release()
+
+	check := server.Check()
+	assert.Bool(check.Healthy).IsTrue()
+	assert.String(check.Outbounds["proxy"]).Equals(OutboundAlive)
+	assert.String(check.Outbounds["backup"]).Equals(OutboundUnknown)
+
+	response, err := http.Get("http://127.0.0.1:" + v2net.Port(port).String() + DefaultPath)
+	assert.Error(err).IsNil()
+	response.Body.Close()
+	assert.Int(response.StatusCode).Equals(http.StatusOK)
+
+	probes["backup"] = &router.OutboundStatus{Alive: false}
+	response, err = http.Get("http://127.0.0.1:" + v2net.Port(port).String() + DefaultPath)
+	assert.Error(err).IsNil()
+	response.Body.Close()
+	assert.Int(response.StatusCode).Equals(http.StatusServiceUnavailable)
+
+	delete(probes, "backup")
+	status["vmess"] = false
+	assert.Bool(server.Check().Healthy).IsFalse()
+}
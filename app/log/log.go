@@ -0,0 +1,28 @@
+// Package log binds the logger of an instance in its space, so that apps and proxies of the instance write access logs
+// into their own logger instead of the default one.
+package log
+
+import (
+	"v2ray.com/core/app"
+	"v2ray.com/core/common/log"
+)
+
+const (
+	APP_ID = app.ID(15)
+)
+
+func init() {
+	// The logger is created from the log settings of shells, which bind it to their spaces.
+	app.MustRegisterFeature(&app.Feature{
+		ID:   APP_ID,
+		Name: "log",
+	})
+}
+
+// FromSpace returns the logger in the space, or nil if there is none. Methods of a nil logger use the default logger.
+func FromSpace(space app.Space) *log.Logger {
+	if !space.HasApp(APP_ID) {
+		return nil
+	}
+	return space.GetApp(APP_ID).(*log.Logger)
+}
@@ -0,0 +1,17 @@
+package metrics
+
+import (
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	DefaultPath = "/metrics"
+)
+
+type Config struct {
+	// Listen is the address that the metrics endpoint listens on, localhost by default.
+	Listen v2net.Address
+	Port   v2net.Port
+	// Path is the HTTP path of the metrics, DefaultPath if empty.
+	Path string
+}
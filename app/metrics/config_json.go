@@ -0,0 +1,42 @@
+// +build json
+
+package metrics
+
+import (
+	"errors"
+	"strings"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Listen *v2net.AddressPB `json:"listen"`
+		Port   v2net.Port       `json:"port"`
+		Path   string           `json:"path"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Metrics: Failed to parse config: ", err)
+	}
+	if jsonConfig.Port == 0 {
+		return errors.New("Metrics: Port is not specified.")
+	}
+	this.Listen = v2net.LocalHostIP
+	if jsonConfig.Listen != nil {
+		if jsonConfig.Listen.AsAddress().Family().IsDomain() {
+			return errors.New("Metrics: Unable to listen on domain address: " + jsonConfig.Listen.AsAddress().Domain())
+		}
+		this.Listen = jsonConfig.Listen.AsAddress()
+	}
+	this.Port = jsonConfig.Port
+	this.Path = DefaultPath
+	if len(jsonConfig.Path) > 0 {
+		if !strings.HasPrefix(jsonConfig.Path, "/") {
+			return errors.New("Metrics: Path must start with /: " + jsonConfig.Path)
+		}
+		this.Path = jsonConfig.Path
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// Package metrics serves metrics of a running V2Ray instance over HTTP, in the text format of Prometheus.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+)
+
+const (
+	APP_ID = app.ID(9)
+
+	textContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	ErrAlreadyStarted = errors.New("Metrics: Server is already started.")
+)
+
+// Server serves metrics collected from the apps in a space: traffic counters and open connections from the stats
+// app, DNS queries and cache hits from the DNS app, and latencies of outbounds probed by the router. Buffer pool stats
+// are always served.
+type Server struct {
+	config   *Config
+	server   *http.Server
+	listener net.Listener
+	stats    *stats.Manager
+	dns      dns.StatsReporter
+	router   router.StatusLister
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "metrics",
+		Config: (*Config)(nil),
+		// Metrics are served once inbounds are started.
+		After: []app.ID{proxyman.APP_ID_INBOUND_MANAGER, dns.APP_ID, router.APP_ID, stats.APP_ID},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewServer(space, config.(*Config)), nil
+		},
+	})
+}
+
+func NewServer(space app.Space, config *Config) *Server {
+	server := &Server{
+		config: config,
+	}
+	path := config.Path
+	if len(path) == 0 {
+		path = DefaultPath
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, server.serveMetrics)
+	server.server = &http.Server{
+		Handler: mux,
+	}
+	space.InitializeApplication(func() error {
+		if space.HasApp(stats.APP_ID) {
+			server.stats = space.GetApp(stats.APP_ID).(*stats.Manager)
+		}
+		if reporter, ok := space.GetApp(dns.APP_ID).(dns.StatsReporter); ok {
+			server.dns = reporter
+		}
+		if lister, ok := space.GetApp(router.APP_ID).(router.StatusLister); ok {
+			server.router = lister
+		}
+		return nil
+	})
+	return server
+}
+
+// Start listens on the configured port, and serves metrics in background.
+func (this *Server) Start() error {
+	if this.listener != nil {
+		return ErrAlreadyStarted
+	}
+	address := net.JoinHostPort(this.config.Listen.String(), this.config.Port.String())
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Error("Metrics: Failed to listen on ", address, ": ", err)
+		return err
+	}
+	this.listener = listener
+	log.Info("Metrics: Listening on ", address)
+	go this.server.Serve(listener)
+	return nil
+}
+
+func (this *Server) Release() {
+	if this.listener != nil {
+		this.server.Close()
+		this.listener = nil
+	}
+}
+
+func (this *Server) serveMetrics(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", textContentType)
+	this.WriteMetrics(writer)
+}
+
+// WriteMetrics writes all metrics in the text format of Prometheus.
+func (this *Server) WriteMetrics(writer io.Writer) {
+	if this.stats != nil {
+		writeTrafficMetrics(writer, this.stats)
+		writeConnectionMetrics(writer, this.stats)
+		writeDeviceMetrics(writer, this.stats)
+	}
+	writePoolMetrics(writer)
+	if this.dns != nil {
+		writeDNSMetrics(writer, this.dns.GetStats())
+	}
+	if this.router != nil {
+		writeOutboundMetrics(writer, this.router.ListStatus())
+	}
+}
+
+// escapeLabel escapes a label value, see https://prometheus.io/docs/instrumenting/exposition_formats/.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func writeHeader(writer io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeTrafficMetrics(writer io.Writer, manager *stats.Manager) {
+	names, values := manager.Query(">>>traffic>>>", false)
+	writeHeader(writer, "v2ray_traffic_bytes_total", "counter", "Bytes of traffic by user, inbound or outbound.")
+	for idx, name := range names {
+		// Names are in the form of "user>>>love@v2ray.com>>>traffic>>>uplink".
+		parts := strings.Split(name, ">>>")
+		if len(parts) != 4 {
+			continue
+		}
+		fmt.Fprintf(writer, "v2ray_traffic_bytes_total{%s=\"%s\",direction=\"%s\"} %d\n", parts[0], escapeLabel(parts[1]), parts[3], values[idx])
+	}
+}
+
+func writeConnectionMetrics(writer io.Writer, manager *stats.Manager) {
+	connections := manager.Connections()
+	tags := make([]string, 0, len(connections))
+	for tag := range connections {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	writeHeader(writer, "v2ray_outbound_connections", "gauge", "Open connections by outbound. The default outbound has an empty tag.")
+	for _, tag := range tags {
+		fmt.Fprintf(writer, "v2ray_outbound_connections{outbound=\"%s\"} %d\n", escapeLabel(tag), connections[tag])
+	}
+}
+
+func writeDeviceMetrics(writer io.Writer, manager *stats.Manager) {
+	names, values := manager.Query(">>>online>>>devices", false)
+	if len(names) == 0 {
+		return
+	}
+	writeHeader(writer, "v2ray_user_devices", "gauge", "Distinct source IPs of the open connections by user.")
+	for idx, name := range names {
+		// Names are in the form of "user>>>love@v2ray.com>>>online>>>devices".
+		parts := strings.Split(name, ">>>")
+		if len(parts) != 4 {
+			continue
+		}
+		fmt.Fprintf(writer, "v2ray_user_devices{user=\"%s\"} %d\n", escapeLabel(parts[1]), values[idx])
+	}
+}
+
+func writePoolMetrics(writer io.Writer) {
+	pools := alloc.GetPoolStats()
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeHeader(writer, "v2ray_buffer_pool_capacity", "gauge", "Number of buffers kept by the pool.")
+	for _, name := range names {
+		fmt.Fprintf(writer, "v2ray_buffer_pool_capacity{pool=\"%s\",size=\"%d\"} %d\n", name, pools[name].BufferSize, pools[name].Capacity)
+	}
+	writeHeader(writer, "v2ray_buffer_pool_idle", "gauge", "Number of buffers in the pool that are not in use.")
+	for _, name := range names {
+		fmt.Fprintf(writer, "v2ray_buffer_pool_idle{pool=\"%s\",size=\"%d\"} %d\n", name, pools[name].BufferSize, pools[name].Idle)
+	}
+	writeHeader(writer, "v2ray_buffer_pool_overflows_total", "counter", "Number of buffers allocated because the pool was empty.")
+	for _, name := range names {
+		fmt.Fprintf(writer, "v2ray_buffer_pool_overflows_total{pool=\"%s\",size=\"%d\"} %d\n", name, pools[name].BufferSize, pools[name].Overflows)
+	}
+}
+
+func writeDNSMetrics(writer io.Writer, dnsStats *dns.Stats) {
+	writeHeader(writer, "v2ray_dns_queries_total", "counter", "Number of DNS resolutions.")
+	fmt.Fprintf(writer, "v2ray_dns_queries_total %d\n", dnsStats.Queries)
+	writeHeader(writer, "v2ray_dns_cache_hits_total", "counter", "Number of DNS resolutions answered by the cache.")
+	fmt.Fprintf(writer, "v2ray_dns_cache_hits_total %d\n", dnsStats.CacheHits)
+	writeHeader(writer, "v2ray_dns_failures_total", "counter", "Number of DNS resolutions that no name server answered.")
+	fmt.Fprintf(writer, "v2ray_dns_failures_total %d\n", dnsStats.Failures)
+}
+
+func writeOutboundMetrics(writer io.Writer, status map[string]*router.OutboundStatus) {
+	tags := make([]string, 0, len(status))
+	for tag := range status {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	writeHeader(writer, "v2ray_outbound_alive", "gauge", "Whether the latest health check of the outbound succeeded.")
+	for _, tag := range tags {
+		alive := 0
+		if status[tag].Alive {
+			alive = 1
+		}
+		fmt.Fprintf(writer, "v2ray_outbound_alive{outbound=\"%s\"} %d\n", escapeLabel(tag), alive)
+	}
+	writeHeader(writer, "v2ray_outbound_delay_seconds", "gauge", "Round trip time of the latest successful health check of the outbound.")
+	for _, tag := range tags {
+		fmt.Fprintf(writer, "v2ray_outbound_delay_seconds{outbound=\"%s\"} %g\n", escapeLabel(tag), status[tag].Delay.Seconds())
+	}
+}
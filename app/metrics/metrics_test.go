@@ -0,0 +1,34 @@
+package metrics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"v2ray.com/core/app"
+	. "v2ray.com/core/app/metrics"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	manager := stats.NewManager(&stats.Config{Users: true})
+	space.BindApp(stats.APP_ID, manager)
+	server := NewServer(space, &Config{})
+	assert.Error(space.Initialize()).IsNil()
+
+	manager.RegisterCounter(stats.UserUplinkCounterName(`a"b@v2ray.com`)).Add(1024)
+	manager.OnConnectionOpen("direct")
+
+	output := new(bytes.Buffer)
+	server.WriteMetrics(output)
+	lines := strings.Split(output.String(), "\n")
+	assert.String(lines[0]).Equals("# HELP v2ray_traffic_bytes_total Bytes of traffic by user, inbound or outbound.")
+	assert.Bool(strings.Contains(output.String(), "v2ray_traffic_bytes_total{user=\"a\\\"b@v2ray.com\",direction=\"uplink\"} 1024\n")).IsTrue()
+	assert.Bool(strings.Contains(output.String(), "v2ray_outbound_connections{outbound=\"direct\"} 1\n")).IsTrue()
+	assert.Bool(strings.Contains(output.String(), "v2ray_buffer_pool_capacity{pool=\"small\",size=\"1600\"} 256\n")).IsTrue()
+	assert.Bool(strings.Contains(output.String(), "v2ray_dns_queries_total")).IsFalse()
+}
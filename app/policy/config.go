@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ban"
+)
+
+const (
+	DefaultHandshake      = 8 * time.Second
+	DefaultConnectionIdle = 120 * time.Second
+	DefaultBufferSize     = 128
+	DefaultUDPIdle        = 16 * time.Second
+)
+
+// Policy is the set of limits applied to the connections of users of one level. A zero timeout disables it.
+type Policy struct {
+	// Handshake is the time that a client has to finish the handshake of the inbound protocol.
+	Handshake time.Duration
+	// ConnectionIdle is the time after which an inbound connection stops reading a client that sends nothing.
+	ConnectionIdle time.Duration
+	// UplinkOnly is the time that a connection stays open without activity once the downlink has finished.
+	UplinkOnly time.Duration
+	// DownlinkOnly is the time that a connection stays open without activity once the uplink has finished.
+	DownlinkOnly time.Duration
+	// BufferSize is the number of buffers queued in each direction of a connection.
+	BufferSize int
+	// PooledBuffers is the number of buffers from the shared pools that a connection may hold in its queues. Data
+	// beyond it is queued in buffers that are not pooled. Zero means no limit.
+	PooledBuffers int
+	// BufferClass is the largest pool that a connection may queue data in.
+	BufferClass alloc.SizeClass
+	// MaxConnections is the number of simultaneous sessions of each user with email. Zero means no limit.
+	MaxConnections int
+	// ConnectionQueue is the time that a session over MaxConnections waits for another to finish, before it is
+	// rejected. Zero rejects it at once.
+	ConnectionQueue time.Duration
+	// Quota is the number of bytes, in both directions, that each user with email may transfer in a quota period.
+	// Zero means no quota.
+	Quota int64
+	// QuotaPeriod is the length of quota periods, counted from the Unix epoch. Usage is reset when a new period
+	// starts. Zero means the quota never resets by itself.
+	QuotaPeriod time.Duration
+	// OverQuotaRate is the bandwidth in bytes per second, shared by all connections of a user over quota. Zero
+	// rejects the connections instead.
+	OverQuotaRate uint64
+	// UDPIdle is the time after which a UDP session without traffic is closed.
+	UDPIdle time.Duration
+	// UDPSessions is the number of simultaneous UDP sessions of each source IP on an inbound. Packets that need more
+	// are dropped. Zero means no limit.
+	UDPSessions int
+}
+
+// DefaultPolicy returns the policy of the given level when it is not configured. Connections of levels above 0 never
+// become idle.
+func DefaultPolicy(level uint32) *Policy {
+	policy := &Policy{
+		Handshake:      DefaultHandshake,
+		ConnectionIdle: DefaultConnectionIdle,
+		BufferSize:     DefaultBufferSize,
+		UDPIdle:        DefaultUDPIdle,
+	}
+	if level > 0 {
+		policy.ConnectionIdle = 0
+	}
+	return policy
+}
+
+// Seconds returns the timeout in whole seconds, as taken by v2net.TimeOutReader.
+func Seconds(timeout time.Duration) uint32 {
+	return uint32(timeout / time.Second)
+}
+
+type Config struct {
+	// Levels are policies by user level.
+	Levels map[uint32]*Policy
+	// Ban bans sources that fail authentication too often on any inbound. Nil disables banning.
+	Ban *ban.Config
+}
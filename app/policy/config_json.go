@@ -0,0 +1,150 @@
+// +build json
+
+package policy
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ban"
+	"v2ray.com/core/common/loader"
+)
+
+// JsonPolicy is a policy in JSON. Times are in seconds except the quota period in days, and unset fields take the
+// defaults of the level.
+type JsonPolicy struct {
+	Handshake     *uint32 `json:"handshake"`
+	ConnIdle      *uint32 `json:"connIdle"`
+	UplinkOnly    *uint32 `json:"uplinkOnly"`
+	DownlinkOnly  *uint32 `json:"downlinkOnly"`
+	BufferSize    *int    `json:"bufferSize"`
+	PooledBuffers int     `json:"pooledBuffers"`
+	BufferClass   string  `json:"bufferClass"`
+	MaxConns      int     `json:"maxConnections"`
+	ConnQueue     uint32  `json:"connectionQueue"`
+	Quota         int64   `json:"quota"`
+	QuotaPeriod   uint32  `json:"quotaPeriod"`
+	OverQuotaRate uint64  `json:"overQuotaRate"`
+	UDPIdle       *uint32 `json:"udpIdle"`
+	UDPSessions   int     `json:"udpSessions"`
+}
+
+func setSeconds(target *time.Duration, value *uint32) {
+	if value != nil {
+		*target = time.Duration(*value) * time.Second
+	}
+}
+
+func (this *JsonPolicy) Build(level uint32) (*Policy, error) {
+	policy := DefaultPolicy(level)
+	setSeconds(&policy.Handshake, this.Handshake)
+	setSeconds(&policy.ConnectionIdle, this.ConnIdle)
+	setSeconds(&policy.UplinkOnly, this.UplinkOnly)
+	setSeconds(&policy.DownlinkOnly, this.DownlinkOnly)
+	if this.BufferSize != nil {
+		if *this.BufferSize <= 0 {
+			return nil, errors.New("Policy: Buffer size must be positive.")
+		}
+		policy.BufferSize = *this.BufferSize
+	}
+	if this.PooledBuffers < 0 {
+		return nil, errors.New("Policy: Number of pooled buffers must not be negative.")
+	}
+	policy.PooledBuffers = this.PooledBuffers
+	if len(this.BufferClass) > 0 {
+		class, err := alloc.ParseSizeClass(this.BufferClass)
+		if err != nil {
+			return nil, errors.New("Policy: Unknown buffer class: " + this.BufferClass)
+		}
+		policy.BufferClass = class
+	}
+	if this.MaxConns < 0 {
+		return nil, errors.New("Policy: Number of connections must not be negative.")
+	}
+	policy.MaxConnections = this.MaxConns
+	policy.ConnectionQueue = time.Duration(this.ConnQueue) * time.Second
+	if this.Quota < 0 {
+		return nil, errors.New("Policy: Quota must not be negative.")
+	}
+	policy.Quota = this.Quota
+	policy.QuotaPeriod = time.Duration(this.QuotaPeriod) * 24 * time.Hour
+	policy.OverQuotaRate = this.OverQuotaRate
+	setSeconds(&policy.UDPIdle, this.UDPIdle)
+	if policy.UDPIdle == 0 {
+		return nil, errors.New("Policy: UDP idle timeout must be positive.")
+	}
+	if this.UDPSessions < 0 {
+		return nil, errors.New("Policy: Number of UDP sessions must not be negative.")
+	}
+	policy.UDPSessions = this.UDPSessions
+	return policy, nil
+}
+
+// JsonBan is the config of bans in JSON. Times are in seconds, and zero times take the defaults.
+type JsonBan struct {
+	Failures    int    `json:"failures"`
+	Window      uint32 `json:"window"`
+	Duration    uint32 `json:"duration"`
+	MaxDuration uint32 `json:"maxDuration"`
+}
+
+func (this *JsonBan) Build() (*ban.Config, error) {
+	if this.Failures <= 0 {
+		return nil, errors.New("Policy: Number of failures to ban must be positive.")
+	}
+	config := &ban.Config{
+		Failures:    this.Failures,
+		Window:      ban.DefaultWindow,
+		Duration:    ban.DefaultDuration,
+		MaxDuration: ban.DefaultMaxDuration,
+	}
+	if this.Window > 0 {
+		config.Window = time.Duration(this.Window) * time.Second
+	}
+	if this.Duration > 0 {
+		config.Duration = time.Duration(this.Duration) * time.Second
+	}
+	if this.MaxDuration > 0 {
+		config.MaxDuration = time.Duration(this.MaxDuration) * time.Second
+	}
+	if config.MaxDuration < config.Duration {
+		return nil, errors.New("Policy: Max ban duration must not be shorter than the ban duration.")
+	}
+	return config, nil
+}
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Levels map[string]*JsonPolicy `json:"levels"`
+		Ban    *JsonBan               `json:"ban"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Policy: Failed to parse config: ", err)
+	}
+	this.Levels = make(map[uint32]*Policy, len(jsonConfig.Levels))
+	for key, jsonPolicy := range jsonConfig.Levels {
+		level, err := strconv.ParseUint(key, 10, 32)
+		if err != nil {
+			return errors.New("Policy: Invalid user level: " + key)
+		}
+		if jsonPolicy == nil {
+			jsonPolicy = new(JsonPolicy)
+		}
+		policy, err := jsonPolicy.Build(uint32(level))
+		if err != nil {
+			return err
+		}
+		this.Levels[uint32(level)] = policy
+	}
+	if jsonConfig.Ban != nil {
+		config, err := jsonConfig.Ban.Build()
+		if err != nil {
+			return err
+		}
+		this.Ban = config
+	}
+	return nil
+}
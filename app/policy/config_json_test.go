@@ -0,0 +1,90 @@
+// +build json
+
+package policy_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/app/policy"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ban"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestConfigParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "levels": {
+      "0": {"handshake": 4, "connIdle": 300, "uplinkOnly": 2, "downlinkOnly": 5, "bufferSize": 16},
+      "1": {"handshake": 10, "pooledBuffers": 4, "bufferClass": "medium", "maxConnections": 8, "connectionQueue": 3,
+            "quota": 1073741824, "quotaPeriod": 30, "overQuotaRate": 1024, "udpIdle": 60, "udpSessions": 32}
+    }
+  }`
+	config := new(Config)
+	err := json.Unmarshal([]byte(rawJson), config)
+	assert.Error(err).IsNil()
+
+	level0 := config.Levels[0]
+	assert.Pointer(level0).IsNotNil()
+	assert.Int64(int64(level0.Handshake)).Equals(int64(4 * time.Second))
+	assert.Int64(int64(level0.ConnectionIdle)).Equals(int64(300 * time.Second))
+	assert.Int64(int64(level0.UplinkOnly)).Equals(int64(2 * time.Second))
+	assert.Int64(int64(level0.DownlinkOnly)).Equals(int64(5 * time.Second))
+	assert.Int(level0.BufferSize).Equals(16)
+
+	level1 := config.Levels[1]
+	assert.Pointer(level1).IsNotNil()
+	assert.Int64(int64(level1.Handshake)).Equals(int64(10 * time.Second))
+	assert.Int64(int64(level1.ConnectionIdle)).Equals(0)
+	assert.Int(level1.BufferSize).Equals(DefaultBufferSize)
+	assert.Int(level1.PooledBuffers).Equals(4)
+	assert.Bool(level1.BufferClass == alloc.MediumClass).IsTrue()
+	assert.Bool(level0.BufferClass == alloc.LargeClass).IsTrue()
+	assert.Int(level1.MaxConnections).Equals(8)
+	assert.Int64(int64(level1.ConnectionQueue)).Equals(int64(3 * time.Second))
+	assert.Int64(level1.Quota).Equals(1073741824)
+	assert.Int64(int64(level1.QuotaPeriod)).Equals(int64(30 * 24 * time.Hour))
+	assert.Int64(int64(level1.OverQuotaRate)).Equals(1024)
+	assert.Int64(level0.Quota).Equals(0)
+	assert.Int64(int64(level0.UDPIdle)).Equals(int64(DefaultUDPIdle))
+	assert.Int64(int64(level1.UDPIdle)).Equals(int64(60 * time.Second))
+	assert.Int(level1.UDPSessions).Equals(32)
+
+	assert.Error(json.Unmarshal([]byte(`{"levels": {"0": {"quota": -1}}}`), new(Config))).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"levels": {"0": {"udpIdle": 0}}}`), new(Config))).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"levels": {"0": {"udpSessions": -1}}}`), new(Config))).IsNotNil()
+}
+
+func TestInvalidBufferSize(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"levels": {"0": {"bufferSize": 0}}}`), config)
+	assert.Error(err).IsNotNil()
+}
+
+func TestInvalidBufferClass(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"levels": {"0": {"bufferClass": "huge"}}}`), config)
+	assert.Error(err).IsNotNil()
+}
+
+func TestBanParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"ban": {"failures": 5, "duration": 120}}`), config)
+	assert.Error(err).IsNil()
+	assert.Int(config.Ban.Failures).Equals(5)
+	assert.Int64(int64(config.Ban.Window)).Equals(int64(ban.DefaultWindow))
+	assert.Int64(int64(config.Ban.Duration)).Equals(int64(2 * time.Minute))
+	assert.Int64(int64(config.Ban.MaxDuration)).Equals(int64(ban.DefaultMaxDuration))
+
+	err = json.Unmarshal([]byte(`{"ban": {"failures": 0}}`), new(Config))
+	assert.Error(err).IsNotNil()
+}
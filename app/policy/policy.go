@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"time"
+
+	"v2ray.com/core/app"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common/ban"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	APP_ID = app.ID(14)
+)
+
+// Manager gives the policy of each user level, and limits the sessions and traffic of each user.
+type Manager struct {
+	config   *Config
+	sessions *sessionCounter
+	quotas   *quotaTracker
+	bans     *ban.List
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "policy",
+		Config: (*Config)(nil),
+		After:  []app.ID{applog.APP_ID, stats.APP_ID},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewManager(config.(*Config)), nil
+		},
+	})
+}
+
+func NewManager(config *Config) *Manager {
+	return &Manager{
+		config:   config,
+		sessions: newSessionCounter(),
+		quotas:   newQuotaTracker(),
+		bans:     ban.NewList(),
+	}
+}
+
+// Bans returns the list of banned sources of the instance, or nil if the manager is nil, which bans nothing.
+func (this *Manager) Bans() *ban.List {
+	if this == nil {
+		return nil
+	}
+	return this.bans
+}
+
+// ForLevel returns the policy of the given level, or the default one if the level is not configured. A nil manager
+// gives the defaults of all levels.
+func (this *Manager) ForLevel(level uint32) *Policy {
+	if this != nil && this.config != nil {
+		if policy, found := this.config.Levels[level]; found {
+			return policy
+		}
+	}
+	return DefaultPolicy(level)
+}
+
+// OpenSession takes one of the sessions allowed to the user by the policy of its level, and returns a function to
+// release it once the session finishes. When the user is at the limit, it waits for a session to finish up to the
+// queue time of the policy, and returns false if none does. Users without email are not limited.
+func (this *Manager) OpenSession(user *protocol.User) (func(), bool) {
+	if this == nil || user == nil || len(user.Email) == 0 {
+		return func() {}, true
+	}
+	policy := this.ForLevel(user.Level)
+	if policy.MaxConnections == 0 {
+		return func() {}, true
+	}
+	return this.sessions.open(user.Email, policy.MaxConnections, policy.ConnectionQueue)
+}
+
+// ApplyQuota wraps the ray of a session of the user, so that its traffic counts into the quota of the user. It returns
+// false if the user is already over quota and the policy rejects such users. Usage is kept in the counters of the
+// given stats manager if not nil. Users without email are not limited.
+func (this *Manager) ApplyQuota(user *protocol.User, r ray.Ray, counters *stats.Manager) (ray.Ray, bool) {
+	if this == nil || user == nil || len(user.Email) == 0 {
+		return r, true
+	}
+	policy := this.ForLevel(user.Level)
+	if policy.Quota == 0 {
+		return r, true
+	}
+	quota := this.quotas.get(user.Email, counters)
+	if quota.refresh(policy, time.Now()) >= policy.Quota && policy.OverQuotaRate == 0 {
+		return r, false
+	}
+	return &quotaRay{
+		Ray:    r,
+		input:  &quotaStream{InputStream: r.OutboundInput(), quota: quota, policy: policy},
+		output: &quotaStream{InputStream: r.InboundOutput(), quota: quota, policy: policy},
+	}, true
+}
+
+// Start applies the config of bans.
+func (this *Manager) Start() error {
+	if this.config != nil && this.config.Ban != nil {
+		this.bans.Configure(*this.config.Ban)
+	}
+	return nil
+}
+
+// Release disables bans, and lifts all of them.
+func (this *Manager) Release() {
+	this.bans.Configure(ban.Config{})
+}
+
+// FromSpace returns the policy manager in the space, or nil if there is none, which gives the default policies. The
+// manager is bound before any proxy handler is created, so handlers may call it on creation.
+func FromSpace(space app.Space) *Manager {
+	if !space.HasApp(APP_ID) {
+		return nil
+	}
+	return space.GetApp(APP_ID).(*Manager)
+}
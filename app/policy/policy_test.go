@@ -0,0 +1,142 @@
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	. "v2ray.com/core/app/policy"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+func TestManagerForLevel(t *testing.T) {
+	assert := assert.On(t)
+
+	manager := NewManager(&Config{
+		Levels: map[uint32]*Policy{
+			1: {
+				Handshake:      2 * time.Second,
+				ConnectionIdle: 60 * time.Second,
+				BufferSize:     8,
+			},
+		},
+	})
+	assert.Int64(int64(manager.ForLevel(1).ConnectionIdle)).Equals(int64(60 * time.Second))
+	assert.Int64(int64(manager.ForLevel(0).ConnectionIdle)).Equals(int64(DefaultConnectionIdle))
+	assert.Int64(int64(manager.ForLevel(2).ConnectionIdle)).Equals(0)
+
+	var none *Manager
+	assert.Int64(int64(none.ForLevel(0).Handshake)).Equals(int64(DefaultHandshake))
+	assert.Int(int(Seconds(none.ForLevel(0).Handshake))).Equals(8)
+}
+
+func TestManagerOpenSession(t *testing.T) {
+	assert := assert.On(t)
+
+	manager := NewManager(&Config{
+		Levels: map[uint32]*Policy{
+			0: {
+				MaxConnections: 1,
+			},
+			1: {
+				MaxConnections:  1,
+				ConnectionQueue: time.Second,
+			},
+		},
+	})
+	user := &protocol.User{Email: "love@v2ray.com"}
+
+	release, ok := manager.OpenSession(user)
+	assert.Bool(ok).IsTrue()
+	_, ok = manager.OpenSession(user)
+	assert.Bool(ok).IsFalse()
+	release()
+	release()
+
+	release, ok = manager.OpenSession(user)
+	assert.Bool(ok).IsTrue()
+	release()
+
+	// Sessions over the limit wait for another to finish.
+	queued := &protocol.User{Email: "queued@v2ray.com", Level: 1}
+	release, ok = manager.OpenSession(queued)
+	assert.Bool(ok).IsTrue()
+	time.AfterFunc(100*time.Millisecond, release)
+	release, ok = manager.OpenSession(queued)
+	assert.Bool(ok).IsTrue()
+	release()
+
+	_, ok = manager.OpenSession(&protocol.User{})
+	assert.Bool(ok).IsTrue()
+}
+
+func TestManagerApplyQuota(t *testing.T) {
+	assert := assert.On(t)
+
+	manager := NewManager(&Config{
+		Levels: map[uint32]*Policy{
+			0: {
+				Quota: 10,
+			},
+		},
+	})
+	counters := stats.NewManager(&stats.Config{})
+	user := &protocol.User{Email: "love@v2ray.com"}
+
+	direct := ray.NewRay()
+	r, ok := manager.ApplyQuota(user, direct, counters)
+	assert.Bool(ok).IsTrue()
+
+	for i := 0; i < 2; i++ {
+		assert.Error(r.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().Append(make([]byte, 8)))).IsNil()
+		buffer, err := r.OutboundInput().Read()
+		assert.Error(err).IsNil()
+		buffer.Release()
+	}
+	assert.Int64(counters.GetCounter(stats.UserQuotaCounterName(user.Email)).Value()).Equals(16)
+
+	// Connections are closed once the user is over quota, and new ones are rejected.
+	r.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().Append(make([]byte, 8)))
+	_, err := r.OutboundInput().Read()
+	assert.Error(err).IsNotNil()
+	_, ok = manager.ApplyQuota(user, ray.NewRay(), counters)
+	assert.Bool(ok).IsFalse()
+
+	// Resetting the counter lifts the user.
+	counters.GetCounter(stats.UserQuotaCounterName(user.Email)).Set(0)
+	_, ok = manager.ApplyQuota(user, ray.NewRay(), counters)
+	assert.Bool(ok).IsTrue()
+
+	_, ok = manager.ApplyQuota(&protocol.User{}, ray.NewRay(), nil)
+	assert.Bool(ok).IsTrue()
+}
+
+func TestQuotaKeptAcrossManagers(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &Config{
+		Levels: map[uint32]*Policy{
+			0: {
+				Quota:       10,
+				QuotaPeriod: time.Hour,
+			},
+		},
+	}
+	counters := stats.NewManager(&stats.Config{})
+	user := &protocol.User{Email: "love@v2ray.com"}
+
+	r, ok := NewManager(config).ApplyQuota(user, ray.NewRay(), counters)
+	assert.Bool(ok).IsTrue()
+	assert.Error(r.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().Append(make([]byte, 16)))).IsNil()
+	buffer, err := r.OutboundInput().Read()
+	assert.Error(err).IsNil()
+	buffer.Release()
+
+	// A new manager, as created on reload, shares the counter and keeps the usage of the current period.
+	_, ok = NewManager(config).ApplyQuota(user, ray.NewRay(), counters)
+	assert.Bool(ok).IsFalse()
+	assert.Int64(counters.GetCounter(stats.UserQuotaCounterName(user.Email)).Value()).Equals(16)
+}
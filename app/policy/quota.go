@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ratelimit"
+	"v2ray.com/core/transport/ray"
+)
+
+// userQuota is the usage of a user in the current quota period.
+type userQuota struct {
+	sync.Mutex
+	used   *stats.Counter
+	period int64
+	bucket *ratelimit.TokenBucket
+	rate   uint64
+}
+
+// refresh resets the usage if a new period has started, and returns the usage. The usage is not reset on the first
+// refresh, as the counter may be shared with a previous policy manager, which counted the current period.
+func (this *userQuota) refresh(policy *Policy, now time.Time) int64 {
+	this.Lock()
+	defer this.Unlock()
+
+	if policy.QuotaPeriod > 0 {
+		period := now.UnixNano() / int64(policy.QuotaPeriod)
+		if period != this.period {
+			if this.period >= 0 {
+				this.used.Set(0)
+			}
+			this.period = period
+		}
+	}
+	if policy.OverQuotaRate != this.rate {
+		this.rate = policy.OverQuotaRate
+		this.bucket = nil
+		if this.rate > 0 {
+			this.bucket = ratelimit.NewTokenBucket(this.rate, 0)
+		}
+	}
+	return this.used.Value()
+}
+
+type quotaTracker struct {
+	sync.Mutex
+	users map[string]*userQuota
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{
+		users: make(map[string]*userQuota),
+	}
+}
+
+// get returns the usage of the user. The usage is kept in the counter of the stats manager if there is one, so it
+// can be queried and reset through the API.
+func (this *quotaTracker) get(email string, counters *stats.Manager) *userQuota {
+	this.Lock()
+	defer this.Unlock()
+
+	quota, found := this.users[email]
+	if !found {
+		quota = &userQuota{
+			period: -1,
+		}
+		if counters != nil {
+			quota.used = counters.RegisterCounter(stats.UserQuotaCounterName(email))
+		} else {
+			quota.used = new(stats.Counter)
+		}
+		this.users[email] = quota
+	}
+	return quota
+}
+
+type quotaRay struct {
+	ray.Ray
+	input  ray.InputStream
+	output ray.InputStream
+}
+
+func (this *quotaRay) OutboundInput() ray.InputStream {
+	return this.input
+}
+
+func (this *quotaRay) InboundOutput() ray.InputStream {
+	return this.output
+}
+
+// quotaStream counts data read from the stream into the usage of the user. Once the user is over quota, the stream is
+// either throttled or closed, as the policy tells.
+type quotaStream struct {
+	ray.InputStream
+	quota  *userQuota
+	policy *Policy
+}
+
+func (this *quotaStream) Read() (*alloc.Buffer, error) {
+	buffer, err := this.InputStream.Read()
+	if err != nil {
+		return nil, err
+	}
+	if this.quota.refresh(this.policy, time.Now()) >= this.policy.Quota {
+		this.quota.Lock()
+		bucket := this.quota.bucket
+		this.quota.Unlock()
+		if bucket == nil {
+			buffer.Release()
+			this.InputStream.Release()
+			return nil, io.EOF
+		}
+		bucket.Wait(buffer.Len())
+	}
+	this.quota.used.Add(int64(buffer.Len()))
+	return buffer, nil
+}
@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"sync"
+	"time"
+)
+
+// userSessions holds the session slots of one user. It is removed once nobody holds or waits for a slot.
+type userSessions struct {
+	slots chan struct{}
+	refs  int
+}
+
+type sessionCounter struct {
+	sync.Mutex
+	users map[string]*userSessions
+}
+
+func newSessionCounter() *sessionCounter {
+	return &sessionCounter{
+		users: make(map[string]*userSessions),
+	}
+}
+
+func (this *sessionCounter) ref(email string, max int) *userSessions {
+	this.Lock()
+	defer this.Unlock()
+
+	user, found := this.users[email]
+	if !found {
+		user = &userSessions{
+			slots: make(chan struct{}, max),
+		}
+		this.users[email] = user
+	}
+	user.refs++
+	return user
+}
+
+func (this *sessionCounter) unref(email string, user *userSessions) {
+	this.Lock()
+	defer this.Unlock()
+
+	user.refs--
+	if user.refs == 0 {
+		delete(this.users, email)
+	}
+}
+
+// open takes a slot of the user, waiting up to the given time when all slots are taken.
+func (this *sessionCounter) open(email string, max int, wait time.Duration) (func(), bool) {
+	user := this.ref(email, max)
+	select {
+	case user.slots <- struct{}{}:
+	default:
+		if wait == 0 {
+			this.unref(email, user)
+			return nil, false
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case user.slots <- struct{}{}:
+		case <-timer.C:
+			this.unref(email, user)
+			return nil, false
+		}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-user.slots
+			this.unref(email, user)
+		})
+	}, true
+}
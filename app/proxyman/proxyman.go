@@ -67,3 +67,10 @@ func (this *DefaultOutboundHandlerManager) SetHandler(tag string, handler proxy.
 
 	this.taggedHandler[tag] = handler
 }
+
+func (this *DefaultOutboundHandlerManager) RemoveHandler(tag string) {
+	this.Lock()
+	defer this.Unlock()
+
+	delete(this.taggedHandler, tag)
+}
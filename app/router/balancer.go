@@ -0,0 +1,293 @@
+package router
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/log"
+)
+
+var (
+	ErrNoOutboundInBalancer = errors.New("Router|Balancer: No outbound in balancer.")
+	ErrInvalidWeights       = errors.New("Router|Balancer: Invalid outbound weights.")
+)
+
+// LoadTracker is implemented by routers that need to know the number of active connections on each outbound, e.g.
+// for the least-load balancing strategy. The dispatcher reports the connections it dispatches.
+type LoadTracker interface {
+	OnConnectionOpen(outboundTag string)
+	OnConnectionClose(outboundTag string)
+}
+
+// OutboundLoad counts the active connections on each outbound.
+type OutboundLoad struct {
+	sync.RWMutex
+	connections map[string]int
+}
+
+func NewOutboundLoad() *OutboundLoad {
+	return &OutboundLoad{
+		connections: make(map[string]int),
+	}
+}
+
+func (this *OutboundLoad) OnConnectionOpen(outboundTag string) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.connections[outboundTag]++
+}
+
+func (this *OutboundLoad) OnConnectionClose(outboundTag string) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.connections[outboundTag] <= 1 {
+		delete(this.connections, outboundTag)
+		return
+	}
+	this.connections[outboundTag]--
+}
+
+// Get returns the number of active connections on the given outbound.
+func (this *OutboundLoad) Get(outboundTag string) int {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.connections[outboundTag]
+}
+
+// BalancingStrategy picks one of the outbounds in a balancer for a connection. The list of outbounds is never empty.
+type BalancingStrategy interface {
+	PickOutbound(outboundTags []string) string
+}
+
+// WeightedBalancingStrategy is implemented by strategies that respect the weights of outbounds. weights has the same
+// length as outboundTags, and its sum is positive.
+type WeightedBalancingStrategy interface {
+	BalancingStrategy
+	PickWeightedOutbound(outboundTags []string, weights []uint32) string
+}
+
+// BalancingStrategyCreator creates a strategy for a balancer. Strategies that depend on load may keep the given
+// OutboundLoad, which is shared by all balancers of a router. health is nil if the outbounds are not probed.
+type BalancingStrategyCreator func(load *OutboundLoad, health HealthReporter) BalancingStrategy
+
+var (
+	strategyCache = make(map[string]BalancingStrategyCreator)
+)
+
+func RegisterBalancingStrategy(name string, creator BalancingStrategyCreator) error {
+	if _, found := strategyCache[name]; found {
+		return common.ErrDuplicatedName
+	}
+	strategyCache[name] = creator
+	return nil
+}
+
+func CreateBalancingStrategy(name string, load *OutboundLoad, health HealthReporter) (BalancingStrategy, error) {
+	if creator, found := strategyCache[name]; found {
+		return creator(load, health), nil
+	}
+	log.Error("Router|Balancer: Strategy not found: ", name)
+	return nil, common.ErrObjectNotFound
+}
+
+// RandomStrategy picks an outbound randomly.
+type RandomStrategy struct{}
+
+func (this *RandomStrategy) PickOutbound(outboundTags []string) string {
+	return outboundTags[dice.Roll(len(outboundTags))]
+}
+
+// PickWeightedOutbound picks an outbound with the probability proportional to its weight.
+func (this *RandomStrategy) PickWeightedOutbound(outboundTags []string, weights []uint32) string {
+	total := 0
+	for _, weight := range weights {
+		total += int(weight)
+	}
+	roll := dice.Roll(total)
+	for idx, weight := range weights {
+		roll -= int(weight)
+		if roll < 0 {
+			return outboundTags[idx]
+		}
+	}
+	return outboundTags[len(outboundTags)-1]
+}
+
+// RoundRobinStrategy picks the outbounds in turn. With weights, it spreads the picks of each outbound evenly in a
+// cycle, in the way of smooth weighted round-robin.
+type RoundRobinStrategy struct {
+	sync.Mutex
+	next    uint32
+	current []int
+}
+
+func (this *RoundRobinStrategy) PickOutbound(outboundTags []string) string {
+	idx := atomic.AddUint32(&this.next, 1) - 1
+	return outboundTags[int(idx%uint32(len(outboundTags)))]
+}
+
+func (this *RoundRobinStrategy) PickWeightedOutbound(outboundTags []string, weights []uint32) string {
+	this.Lock()
+	defer this.Unlock()
+
+	if len(this.current) != len(weights) {
+		this.current = make([]int, len(weights))
+	}
+	total := 0
+	selected := 0
+	for idx, weight := range weights {
+		this.current[idx] += int(weight)
+		total += int(weight)
+		if this.current[idx] > this.current[selected] {
+			selected = idx
+		}
+	}
+	this.current[selected] -= total
+	return outboundTags[selected]
+}
+
+// aliveOutbounds returns the outbounds that didn't fail their latest probe. All outbounds are returned if none is
+// alive, as a dead exit is no worse than no exit at all.
+func aliveOutbounds(outboundTags []string, health HealthReporter) []string {
+	if health == nil {
+		return outboundTags
+	}
+	alive := make([]string, 0, len(outboundTags))
+	for _, tag := range outboundTags {
+		if status := health.GetStatus(tag); status == nil || status.Alive {
+			alive = append(alive, tag)
+		}
+	}
+	if len(alive) == 0 {
+		return outboundTags
+	}
+	return alive
+}
+
+// LeastLoadStrategy picks the outbound with the fewest active connections. Ties are broken randomly. Outbounds that
+// failed their latest probe are skipped.
+type LeastLoadStrategy struct {
+	load   *OutboundLoad
+	health HealthReporter
+}
+
+func (this *LeastLoadStrategy) PickOutbound(outboundTags []string) string {
+	var candidates []string
+	least := -1
+	for _, tag := range aliveOutbounds(outboundTags, this.health) {
+		connections := this.load.Get(tag)
+		switch {
+		case least < 0 || connections < least:
+			least = connections
+			candidates = append(candidates[:0], tag)
+		case connections == least:
+			candidates = append(candidates, tag)
+		}
+	}
+	return candidates[dice.Roll(len(candidates))]
+}
+
+// LeastPingStrategy picks the alive outbound with the lowest delay in its latest probe. It picks randomly until the
+// outbounds are probed.
+type LeastPingStrategy struct {
+	health HealthReporter
+}
+
+func (this *LeastPingStrategy) PickOutbound(outboundTags []string) string {
+	if this.health == nil {
+		return outboundTags[dice.Roll(len(outboundTags))]
+	}
+	selected := ""
+	var least time.Duration
+	for _, tag := range outboundTags {
+		status := this.health.GetStatus(tag)
+		if status == nil || !status.Alive {
+			continue
+		}
+		if len(selected) == 0 || status.Delay < least {
+			selected = tag
+			least = status.Delay
+		}
+	}
+	if len(selected) == 0 {
+		return outboundTags[dice.Roll(len(outboundTags))]
+	}
+	return selected
+}
+
+// Balancer selects one of a set of outbounds for each connection, using a BalancingStrategy.
+type Balancer struct {
+	tag          string
+	outboundTags []string
+	weights      []uint32
+	strategy     BalancingStrategy
+}
+
+func NewBalancer(tag string, outboundTags []string, strategy BalancingStrategy) *Balancer {
+	return &Balancer{
+		tag:          tag,
+		outboundTags: outboundTags,
+		strategy:     strategy,
+	}
+}
+
+// NewWeightedBalancer creates a Balancer whose outbounds are picked in proportion to the given weights, if the
+// strategy supports weights. Outbounds of weight 0 are never picked.
+func NewWeightedBalancer(tag string, outboundTags []string, weights []uint32, strategy BalancingStrategy) (*Balancer, error) {
+	if len(weights) != len(outboundTags) {
+		return nil, ErrInvalidWeights
+	}
+	total := uint32(0)
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		return nil, ErrInvalidWeights
+	}
+	return &Balancer{
+		tag:          tag,
+		outboundTags: outboundTags,
+		weights:      weights,
+		strategy:     strategy,
+	}, nil
+}
+
+func (this *Balancer) Tag() string {
+	return this.tag
+}
+
+func (this *Balancer) OutboundTags() []string {
+	return this.outboundTags
+}
+
+func (this *Balancer) PickOutbound() (string, error) {
+	if len(this.outboundTags) == 0 {
+		return "", ErrNoOutboundInBalancer
+	}
+	if weighted, ok := this.strategy.(WeightedBalancingStrategy); ok && this.weights != nil {
+		return weighted.PickWeightedOutbound(this.outboundTags, this.weights), nil
+	}
+	return this.strategy.PickOutbound(this.outboundTags), nil
+}
+
+func init() {
+	RegisterBalancingStrategy("random", func(load *OutboundLoad, health HealthReporter) BalancingStrategy {
+		return new(RandomStrategy)
+	})
+	RegisterBalancingStrategy("roundrobin", func(load *OutboundLoad, health HealthReporter) BalancingStrategy {
+		return new(RoundRobinStrategy)
+	})
+	RegisterBalancingStrategy("leastload", func(load *OutboundLoad, health HealthReporter) BalancingStrategy {
+		return &LeastLoadStrategy{load: load, health: health}
+	})
+	RegisterBalancingStrategy("leastping", func(load *OutboundLoad, health HealthReporter) BalancingStrategy {
+		return &LeastPingStrategy{health: health}
+	})
+}
@@ -0,0 +1,149 @@
+package router_test
+
+import (
+	"testing"
+	"time"
+
+	. "v2ray.com/core/app/router"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestRoundRobinBalancer(t *testing.T) {
+	assert := assert.On(t)
+
+	strategy, err := CreateBalancingStrategy("roundrobin", NewOutboundLoad(), nil)
+	assert.Error(err).IsNil()
+	balancer := NewBalancer("b", []string{"a", "b", "c"}, strategy)
+
+	for _, expected := range []string{"a", "b", "c", "a"} {
+		tag, err := balancer.PickOutbound()
+		assert.Error(err).IsNil()
+		assert.String(tag).Equals(expected)
+	}
+}
+
+func TestRandomBalancer(t *testing.T) {
+	assert := assert.On(t)
+
+	strategy, err := CreateBalancingStrategy("random", NewOutboundLoad(), nil)
+	assert.Error(err).IsNil()
+	balancer := NewBalancer("b", []string{"a", "b"}, strategy)
+
+	for i := 0; i < 16; i++ {
+		tag, err := balancer.PickOutbound()
+		assert.Error(err).IsNil()
+		assert.Bool(tag == "a" || tag == "b").IsTrue()
+	}
+
+	_, err = NewBalancer("empty", nil, strategy).PickOutbound()
+	assert.Error(err).Equals(ErrNoOutboundInBalancer)
+}
+
+func TestLeastLoadBalancer(t *testing.T) {
+	assert := assert.On(t)
+
+	load := NewOutboundLoad()
+	strategy, err := CreateBalancingStrategy("leastload", load, nil)
+	assert.Error(err).IsNil()
+	balancer := NewBalancer("b", []string{"a", "b", "c"}, strategy)
+
+	load.OnConnectionOpen("a")
+	load.OnConnectionOpen("b")
+	load.OnConnectionOpen("b")
+	load.OnConnectionOpen("c")
+	tag, err := balancer.PickOutbound()
+	assert.Error(err).IsNil()
+	assert.Bool(tag == "a" || tag == "c").IsTrue()
+
+	load.OnConnectionClose("b")
+	load.OnConnectionClose("b")
+	assert.Int(load.Get("b")).Equals(0)
+	tag, err = balancer.PickOutbound()
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("b")
+
+	_, err = CreateBalancingStrategy("fastest", load, nil)
+	assert.Error(err).IsNotNil()
+}
+
+type staticHealth map[string]*OutboundStatus
+
+func (this staticHealth) GetStatus(outboundTag string) *OutboundStatus {
+	return this[outboundTag]
+}
+
+func TestLeastPingBalancer(t *testing.T) {
+	assert := assert.On(t)
+
+	health := staticHealth{
+		"a": {Alive: true, Delay: time.Millisecond * 300},
+		"b": {Alive: true, Delay: time.Millisecond * 100},
+		"c": {Alive: false, Failures: 3},
+	}
+	strategy, err := CreateBalancingStrategy("leastping", NewOutboundLoad(), health)
+	assert.Error(err).IsNil()
+	tag, err := NewBalancer("b", []string{"a", "b", "c"}, strategy).PickOutbound()
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("b")
+
+	health["b"].Alive = false
+	tag, err = NewBalancer("b", []string{"a", "b", "c"}, strategy).PickOutbound()
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("a")
+}
+
+func TestLeastLoadSkipsDeadOutbounds(t *testing.T) {
+	assert := assert.On(t)
+
+	load := NewOutboundLoad()
+	load.OnConnectionOpen("a")
+	health := staticHealth{
+		"b": {Alive: false},
+	}
+	strategy, err := CreateBalancingStrategy("leastload", load, health)
+	assert.Error(err).IsNil()
+	tag, err := NewBalancer("b", []string{"a", "b"}, strategy).PickOutbound()
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("a")
+}
+
+func TestWeightedRoundRobinBalancer(t *testing.T) {
+	assert := assert.On(t)
+
+	strategy, err := CreateBalancingStrategy("roundrobin", NewOutboundLoad(), nil)
+	assert.Error(err).IsNil()
+	balancer, err := NewWeightedBalancer("b", []string{"cheap", "fallback"}, []uint32{4, 1}, strategy)
+	assert.Error(err).IsNil()
+
+	picks := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		tag, err := balancer.PickOutbound()
+		assert.Error(err).IsNil()
+		picks[tag]++
+	}
+	assert.Int(picks["cheap"]).Equals(80)
+	assert.Int(picks["fallback"]).Equals(20)
+
+	_, err = NewWeightedBalancer("b", []string{"a", "b"}, []uint32{1}, strategy)
+	assert.Error(err).Equals(ErrInvalidWeights)
+	_, err = NewWeightedBalancer("b", []string{"a", "b"}, []uint32{0, 0}, strategy)
+	assert.Error(err).Equals(ErrInvalidWeights)
+}
+
+func TestWeightedRandomBalancer(t *testing.T) {
+	assert := assert.On(t)
+
+	strategy, err := CreateBalancingStrategy("random", NewOutboundLoad(), nil)
+	assert.Error(err).IsNil()
+	balancer, err := NewWeightedBalancer("b", []string{"cheap", "fallback", "disabled"}, []uint32{4, 1, 0}, strategy)
+	assert.Error(err).IsNil()
+
+	picks := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		tag, err := balancer.PickOutbound()
+		assert.Error(err).IsNil()
+		picks[tag]++
+	}
+	assert.Int(picks["disabled"]).Equals(0)
+	assert.Bool(picks["cheap"] > 7500 && picks["cheap"] < 8500).IsTrue()
+}
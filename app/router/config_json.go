@@ -5,6 +5,7 @@ package router
 import (
 	"encoding/json"
 
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 )
 
@@ -14,12 +15,15 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		Settings json.RawMessage `json:"settings"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
 		return err
 	}
 	settings, err := CreateRouterConfig(jsonConfig.Strategy, []byte(jsonConfig.Settings))
 	if err != nil {
 		log.Error("Router: Failed to load router settings: ", err)
+		if unknown, ok := err.(*loader.UnknownFieldError); ok {
+			return unknown.Under("settings")
+		}
 		return err
 	}
 	this.Strategy = jsonConfig.Strategy
@@ -0,0 +1,210 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/event"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/task"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	DefaultProbeURL      = "https://www.google.com/generate_204"
+	DefaultProbeInterval = time.Minute
+	DefaultProbeTimeout  = time.Second * 10
+)
+
+var (
+	ErrOutboundNotFound = errors.New("Router|Observatory: Outbound not found.")
+)
+
+// OutboundStatus is the result of the latest probes on an outbound.
+type OutboundStatus struct {
+	Alive bool
+	// Delay is the round trip time of the latest successful probe.
+	Delay time.Duration
+	// Failures is the number of consecutive failed probes.
+	Failures  int
+	LastProbe time.Time
+}
+
+// HealthReporter reports the health of outbounds. GetStatus returns nil for outbounds that are not probed yet.
+type HealthReporter interface {
+	GetStatus(outboundTag string) *OutboundStatus
+}
+
+// StatusLister is implemented by routers that probe outbounds, to report the status of all outbounds probed so far.
+type StatusLister interface {
+	ListStatus() map[string]*OutboundStatus
+}
+
+// Observatory probes outbounds periodically, by sending an HTTP(S) GET request through each of them.
+type Observatory struct {
+	sync.RWMutex
+	ohm          proxyman.OutboundHandlerManager
+	outboundTags []string
+	probeURL     string
+	timeout      time.Duration
+	status       map[string]*OutboundStatus
+	probe        *task.Periodic
+	events       *event.Bus
+}
+
+// NewObservatory returns an Observatory that probes the outbounds, and publishes changes of their health on the event
+// bus, which may be nil.
+func NewObservatory(ohm proxyman.OutboundHandlerManager, outboundTags []string, probeURL string, interval time.Duration, events *event.Bus) *Observatory {
+	if len(probeURL) == 0 {
+		probeURL = DefaultProbeURL
+	}
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+	observatory := &Observatory{
+		ohm:          ohm,
+		outboundTags: outboundTags,
+		probeURL:     probeURL,
+		timeout:      DefaultProbeTimeout,
+		status:       make(map[string]*OutboundStatus),
+		events:       events,
+	}
+	observatory.probe = &task.Periodic{
+		Name:     "Outbound probe",
+		Interval: interval,
+		Jitter:   interval / 10,
+		Execute: func() error {
+			observatory.ProbeAll()
+			return nil
+		},
+	}
+	return observatory
+}
+
+func (this *Observatory) GetStatus(outboundTag string) *OutboundStatus {
+	this.RLock()
+	defer this.RUnlock()
+
+	status, found := this.status[outboundTag]
+	if !found {
+		return nil
+	}
+	copied := *status
+	return &copied
+}
+
+// ListStatus returns the status of all outbounds probed so far, by their tags.
+func (this *Observatory) ListStatus() map[string]*OutboundStatus {
+	this.RLock()
+	defer this.RUnlock()
+
+	list := make(map[string]*OutboundStatus, len(this.status))
+	for tag, status := range this.status {
+		copied := *status
+		list[tag] = &copied
+	}
+	return list
+}
+
+// Start probes all outbounds now, and then periodically.
+func (this *Observatory) Start() {
+	this.probe.Run()
+}
+
+// Close stops probing. Probes in progress are not waited for, as they may take up to the probe timeout.
+func (this *Observatory) Close() {
+	this.probe.Stop()
+}
+
+// ProbeAll probes all outbounds concurrently, and returns when all probes are done.
+func (this *Observatory) ProbeAll() {
+	var wg sync.WaitGroup
+	for _, tag := range this.outboundTags {
+		wg.Add(1)
+		go func(tag string) {
+			defer wg.Done()
+			delay, err := this.Probe(tag)
+			this.update(tag, delay, err)
+		}(tag)
+	}
+	wg.Wait()
+}
+
+func (this *Observatory) update(outboundTag string, delay time.Duration, err error) {
+	this.Lock()
+	defer this.Unlock()
+
+	status, found := this.status[outboundTag]
+	if !found {
+		status = new(OutboundStatus)
+		this.status[outboundTag] = status
+	}
+	status.LastProbe = time.Now()
+	if err != nil {
+		log.Info("Router|Observatory: Outbound [", outboundTag, "] failed probe: ", err)
+		if !found || status.Alive {
+			this.events.Publish(event.OutboundUnhealthy, "Outbound ["+outboundTag+"] failed health probe.", map[string]string{
+				"outbound": outboundTag,
+				"error":    err.Error(),
+			})
+		}
+		status.Alive = false
+		status.Failures++
+		return
+	}
+	log.Debug("Router|Observatory: Outbound [", outboundTag, "] delay: ", delay)
+	if found && !status.Alive {
+		this.events.Publish(event.OutboundHealthy, "Outbound ["+outboundTag+"] passed health probe again.", map[string]string{
+			"outbound": outboundTag,
+			"delay":    delay.String(),
+		})
+	}
+	status.Alive = true
+	status.Delay = delay
+	status.Failures = 0
+}
+
+// Probe sends a GET request to the probe URL through the given outbound, and returns the time until the response.
+func (this *Observatory) Probe(outboundTag string) (time.Duration, error) {
+	handler := this.ohm.GetHandler(outboundTag)
+	if handler == nil {
+		return 0, ErrOutboundNotFound
+	}
+	// Connections through the outbound are canceled once the probe finishes.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &http.Client{
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				host, portStr, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				port, err := v2net.PortFromString(portStr)
+				if err != nil {
+					return nil, err
+				}
+				link := ray.NewRay()
+				go handler.Dispatch(ctx, v2net.TCPDestination(v2net.ParseAddress(host), port), alloc.NewLocalBuffer(32).Clear(), link)
+				return ray.NewConnection(link), nil
+			},
+			DisableKeepAlives: true,
+		},
+		Timeout: this.timeout,
+	}
+
+	start := time.Now()
+	response, err := client.Get(this.probeURL)
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+	return time.Since(start), nil
+}
@@ -0,0 +1,106 @@
+package router_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app/proxyman"
+	. "v2ray.com/core/app/router"
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+// directHandler connects to the destination directly, like freedom.
+type directHandler struct{}
+
+func (this *directHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	defer link.OutboundOutput().Close()
+
+	conn, err := net.Dial("tcp", destination.NetAddr())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		v2io.Pipe(link.OutboundInput(), v2io.NewAdaptiveWriter(conn))
+	}()
+	return v2io.Pipe(v2io.NewAdaptiveReader(conn), link.OutboundOutput())
+}
+
+// deadHandler fails all connections.
+type deadHandler struct{}
+
+func (this *deadHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	link.OutboundInput().Release()
+	link.OutboundOutput().Close()
+	return io.EOF
+}
+
+func TestObservatory(t *testing.T) {
+	assert := assert.On(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	ohm.SetHandler("alive", new(directHandler))
+	ohm.SetHandler("dead", new(deadHandler))
+
+	observatory := NewObservatory(ohm, []string{"alive", "dead", "missing"}, server.URL, 0, nil)
+	assert.Pointer(observatory.GetStatus("alive")).IsNil()
+	observatory.ProbeAll()
+
+	status := observatory.GetStatus("alive")
+	assert.Bool(status.Alive).IsTrue()
+	assert.Int(status.Failures).Equals(0)
+	assert.Bool(status.Delay > 0).IsTrue()
+
+	status = observatory.GetStatus("dead")
+	assert.Bool(status.Alive).IsFalse()
+	assert.Int(status.Failures).Equals(1)
+
+	observatory.ProbeAll()
+	assert.Int(observatory.GetStatus("dead").Failures).Equals(2)
+	assert.Bool(observatory.GetStatus("missing").Alive).IsFalse()
+
+	strategy, err := CreateBalancingStrategy("leastping", NewOutboundLoad(), observatory)
+	assert.Error(err).IsNil()
+	tag, err := NewBalancer("b", []string{"dead", "alive"}, strategy).PickOutbound()
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("alive")
+}
+
+func TestObservatoryStartClose(t *testing.T) {
+	assert := assert.On(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	ohm.SetHandler("dead", new(deadHandler))
+
+	observatory := NewObservatory(ohm, []string{"dead"}, server.URL, 50*time.Millisecond, nil)
+	observatory.Start()
+	time.Sleep(200 * time.Millisecond)
+	observatory.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// Outbounds are probed once on start, and then periodically until closed.
+	failures := observatory.GetStatus("dead").Failures
+	assert.Bool(failures >= 2).IsTrue()
+	time.Sleep(200 * time.Millisecond)
+	assert.Int(observatory.GetStatus("dead").Failures).Equals(failures)
+}
@@ -1,19 +1,71 @@
 package router
 
 import (
+	"net"
+	"time"
+
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/events"
+	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
 )
 
 const (
 	APP_ID = app.ID(3)
 )
 
+// Context is the information of a connection for routing.
+type Context struct {
+	// InboundTag is the tag of the inbound handler that accepted the connection.
+	InboundTag  string
+	Source      v2net.Destination
+	Destination v2net.Destination
+	// User is the authenticated user of the connection, or nil if the inbound doesn't authenticate users.
+	User *protocol.User
+	// Protocol is the application protocol sniffed from the first payload, such as "http" or "tls". Empty if unknown.
+	Protocol string
+	// SniffedDomain is the HTTP host or TLS server name sniffed from the first payload. Empty if unknown.
+	SniffedDomain string
+	// ResolvedIPs are the IPs of the destination domain, for IP rules to match domain destinations. Nil if the
+	// destination is not resolved.
+	ResolvedIPs []net.IP
+	// ResolvedValidated is true if the resolved IPs are validated with DNSSEC.
+	ResolvedValidated bool
+	// Time is when the connection is routed, for schedule rules. Zero means now.
+	Time time.Time
+}
+
 type Router interface {
 	common.Releasable
-	TakeDetour(v2net.Destination) (string, error)
+	TakeDetour(*Context) (string, error)
+}
+
+// Decision is the result of routing a connection, with the trace of rule evaluations.
+type Decision struct {
+	OutboundTag string
+	// BalancerTag is the balancer that picked the outbound. Empty if the rule refers to an outbound directly.
+	BalancerTag string
+	// RuleIndex is the index of the matched rule, or -1 if no rule matched.
+	RuleIndex int
+	Trace     []string
+}
+
+// DryRunner is implemented by routers that can route a synthetic connection without dispatching it, for debugging
+// rule sets.
+type DryRunner interface {
+	DryRun(ctx *Context) (*Decision, error)
+}
+
+// Reloadable is implemented by routers that can replace their rules at runtime, without restarting inbounds.
+// rawConfig is of the same type as the settings the router was created with.
+type Reloadable interface {
+	Reload(rawConfig interface{}) error
+	// PrepareReload validates the settings without changing the router, and returns the function that applies them.
+	PrepareReload(rawConfig interface{}) (func(), error)
 }
 
 type RouterFactory interface {
@@ -24,6 +76,19 @@ var (
 	routerCache = make(map[string]RouterFactory)
 )
 
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "router",
+		Config: (*Config)(nil),
+		After:  []app.ID{dns.APP_ID, events.APP_ID, proxyman.APP_ID_OUTBOUND_MANAGER},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			routerConfig := config.(*Config)
+			return CreateRouter(routerConfig.Strategy, routerConfig.Settings, space)
+		},
+	})
+}
+
 func RegisterRouter(name string, factory RouterFactory) error {
 	if _, found := routerCache[name]; found {
 		return common.ErrDuplicatedName
@@ -1,15 +1,17 @@
 package rules
 
 import (
+	"fmt"
 	"net"
 	"regexp"
 	"strings"
 
+	"v2ray.com/core/app/router"
 	v2net "v2ray.com/core/common/net"
 )
 
 type Condition interface {
-	Apply(dest v2net.Destination) bool
+	Apply(ctx *router.Context) bool
 }
 
 type ConditionChan []Condition
@@ -24,9 +26,9 @@ func (this *ConditionChan) Add(cond Condition) *ConditionChan {
 	return this
 }
 
-func (this *ConditionChan) Apply(dest v2net.Destination) bool {
+func (this *ConditionChan) Apply(ctx *router.Context) bool {
 	for _, cond := range *this {
-		if !cond.Apply(dest) {
+		if !cond.Apply(ctx) {
 			return false
 		}
 	}
@@ -37,6 +39,10 @@ func (this *ConditionChan) Len() int {
 	return len(*this)
 }
 
+func (this *ConditionChan) String() string {
+	return describeConditions("all of", *this)
+}
+
 type AnyCondition []Condition
 
 func NewAnyCondition() *AnyCondition {
@@ -49,9 +55,9 @@ func (this *AnyCondition) Add(cond Condition) *AnyCondition {
 	return this
 }
 
-func (this *AnyCondition) Apply(dest v2net.Destination) bool {
+func (this *AnyCondition) Apply(ctx *router.Context) bool {
 	for _, cond := range *this {
-		if cond.Apply(dest) {
+		if cond.Apply(ctx) {
 			return true
 		}
 	}
@@ -62,24 +68,65 @@ func (this *AnyCondition) Len() int {
 	return len(*this)
 }
 
+func (this *AnyCondition) String() string {
+	return describeConditions("any of", *this)
+}
+
+// DomainMatcher is a Condition on domain destinations. Match takes a domain name in lower case.
+type DomainMatcher interface {
+	Condition
+	Match(domain string) bool
+}
+
+// applyDomainMatcher applies a DomainMatcher to the domain of a connection. It is the destination domain, or the
+// sniffed domain if the destination is an IP.
+func applyDomainMatcher(matcher DomainMatcher, ctx *router.Context) bool {
+	if ctx.Destination.Address.Family().IsDomain() {
+		return matcher.Match(strings.ToLower(ctx.Destination.Address.Domain()))
+	}
+	if len(ctx.SniffedDomain) > 0 {
+		return matcher.Match(strings.ToLower(ctx.SniffedDomain))
+	}
+	return false
+}
+
+// NewDomainMatcher creates a matcher by the prefix of the pattern: "regexp:" for regular expression, "full:" for
+// exact match, "domain:" for the domain and its subdomains, and no prefix for substring match.
+func NewDomainMatcher(pattern string) (DomainMatcher, error) {
+	switch {
+	case strings.HasPrefix(pattern, "regexp:"):
+		return NewRegexpDomainMatcher(pattern[7:])
+	case strings.HasPrefix(pattern, "full:"):
+		return NewFullDomainMatcher(pattern[5:]), nil
+	case strings.HasPrefix(pattern, "domain:"):
+		return NewSubDomainMatcher(pattern[7:]), nil
+	default:
+		return NewPlainDomainMatcher(pattern), nil
+	}
+}
+
 type PlainDomainMatcher struct {
 	pattern string
 }
 
 func NewPlainDomainMatcher(pattern string) *PlainDomainMatcher {
 	return &PlainDomainMatcher{
-		pattern: pattern,
+		pattern: strings.ToLower(pattern),
 	}
 }
 
-func (this *PlainDomainMatcher) Apply(dest v2net.Destination) bool {
-	if !dest.Address.Family().IsDomain() {
-		return false
-	}
-	domain := dest.Address.Domain()
+func (this *PlainDomainMatcher) Match(domain string) bool {
 	return strings.Contains(domain, this.pattern)
 }
 
+func (this *PlainDomainMatcher) Apply(ctx *router.Context) bool {
+	return applyDomainMatcher(this, ctx)
+}
+
+func (this *PlainDomainMatcher) String() string {
+	return "keyword:" + this.pattern
+}
+
 type RegexpDomainMatcher struct {
 	pattern *regexp.Regexp
 }
@@ -94,19 +141,82 @@ func NewRegexpDomainMatcher(pattern string) (*RegexpDomainMatcher, error) {
 	}, nil
 }
 
-func (this *RegexpDomainMatcher) Apply(dest v2net.Destination) bool {
-	if !dest.Address.Family().IsDomain() {
+func (this *RegexpDomainMatcher) Match(domain string) bool {
+	return this.pattern.MatchString(domain)
+}
+
+func (this *RegexpDomainMatcher) Apply(ctx *router.Context) bool {
+	return applyDomainMatcher(this, ctx)
+}
+
+func (this *RegexpDomainMatcher) String() string {
+	return "regexp:" + this.pattern.String()
+}
+
+// FullDomainMatcher matches the domain exactly.
+type FullDomainMatcher struct {
+	pattern string
+}
+
+func NewFullDomainMatcher(pattern string) *FullDomainMatcher {
+	return &FullDomainMatcher{
+		pattern: strings.ToLower(pattern),
+	}
+}
+
+func (this *FullDomainMatcher) Match(domain string) bool {
+	return domain == this.pattern
+}
+
+func (this *FullDomainMatcher) Apply(ctx *router.Context) bool {
+	return applyDomainMatcher(this, ctx)
+}
+
+func (this *FullDomainMatcher) String() string {
+	return "full:" + this.pattern
+}
+
+// SubDomainMatcher matches the domain and all its subdomains.
+type SubDomainMatcher struct {
+	pattern string
+}
+
+func NewSubDomainMatcher(pattern string) *SubDomainMatcher {
+	return &SubDomainMatcher{
+		pattern: strings.ToLower(pattern),
+	}
+}
+
+func (this *SubDomainMatcher) Match(domain string) bool {
+	if !strings.HasSuffix(domain, this.pattern) {
 		return false
 	}
-	domain := dest.Address.Domain()
-	return this.pattern.MatchString(strings.ToLower(domain))
+	return len(domain) == len(this.pattern) || domain[len(domain)-len(this.pattern)-1] == '.'
+}
+
+func (this *SubDomainMatcher) Apply(ctx *router.Context) bool {
+	return applyDomainMatcher(this, ctx)
+}
+
+func (this *SubDomainMatcher) String() string {
+	return "domain:" + this.pattern
 }
 
 type CIDRMatcher struct {
 	cidr *net.IPNet
 }
 
+// NewCIDRMatcher creates a matcher of a CIDR, or of a single IP if there is no prefix length.
 func NewCIDRMatcher(ipnet string) (*CIDRMatcher, error) {
+	if !strings.Contains(ipnet, "/") {
+		if ip := net.ParseIP(ipnet); ip != nil {
+			if ip.To4() != nil {
+				ipnet += "/32"
+			} else {
+				ipnet += "/128"
+			}
+		}
+	}
 	_, cidr, err := net.ParseCIDR(ipnet)
 	if err != nil {
 		return nil, err
@@ -116,11 +226,29 @@ func NewCIDRMatcher(ipnet string) (*CIDRMatcher, error) {
 	}, nil
 }
 
-func (this *CIDRMatcher) Apply(dest v2net.Destination) bool {
-	if !dest.Address.Family().Either(v2net.AddressFamilyIPv4, v2net.AddressFamilyIPv6) {
-		return false
+// applyIPMatcher applies an IP condition to the destination IP, or to the IPs resolved from the destination domain.
+func applyIPMatcher(ctx *router.Context, match func(ip net.IP) bool) bool {
+	if ctx.Destination.Address.Family().Either(v2net.AddressFamilyIPv4, v2net.AddressFamilyIPv6) {
+		return match(ctx.Destination.Address.IP())
+	}
+	for _, ip := range ctx.ResolvedIPs {
+		if match(ip) {
+			return true
+		}
 	}
-	return this.cidr.Contains(dest.Address.IP())
+	return false
+}
+
+func (this *CIDRMatcher) Match(ip net.IP) bool {
+	return this.cidr.Contains(ip)
+}
+
+func (this *CIDRMatcher) Apply(ctx *router.Context) bool {
+	return applyIPMatcher(ctx, this.Match)
+}
+
+func (this *CIDRMatcher) String() string {
+	return "ip:" + this.cidr.String()
 }
 
 type IPv4Matcher struct {
@@ -133,25 +261,99 @@ func NewIPv4Matcher(ipnet *v2net.IPNet) *IPv4Matcher {
 	}
 }
 
-func (this *IPv4Matcher) Apply(dest v2net.Destination) bool {
-	if !dest.Address.Family().Either(v2net.AddressFamilyIPv4) {
+func (this *IPv4Matcher) Apply(ctx *router.Context) bool {
+	return applyIPMatcher(ctx, func(ip net.IP) bool {
+		return ip.To4() != nil && this.ipv4net.Contains(ip)
+	})
+}
+
+// SourceMatcher applies a condition on destination address, such as CIDRMatcher or GeoIPMatcher, to the source address
+// of the connection.
+type SourceMatcher struct {
+	condition Condition
+}
+
+func NewSourceMatcher(condition Condition) *SourceMatcher {
+	return &SourceMatcher{
+		condition: condition,
+	}
+}
+
+func (this *SourceMatcher) Apply(ctx *router.Context) bool {
+	if ctx.Source.Address == nil {
 		return false
 	}
-	return this.ipv4net.Contains(dest.Address.IP())
+	sourceCtx := *ctx
+	sourceCtx.Destination = ctx.Source
+	sourceCtx.ResolvedIPs = nil
+	return this.condition.Apply(&sourceCtx)
 }
 
+func (this *SourceMatcher) String() string {
+	return "source " + describeCondition(this.condition)
+}
+
+// sourceDependence returns whether the condition matches the source address and the source port, so that routing
+// results depend on them.
+func sourceDependence(cond Condition) (address bool, port bool) {
+	switch cond := cond.(type) {
+	case *SourceMatcher:
+		return true, false
+	case *PortMatcher:
+		return false, cond.onSource
+	case *ConditionChan:
+		for _, c := range *cond {
+			a, p := sourceDependence(c)
+			address, port = address || a, port || p
+		}
+	case *AnyCondition:
+		for _, c := range *cond {
+			a, p := sourceDependence(c)
+			address, port = address || a, port || p
+		}
+	}
+	return
+}
+
+// PortMatcher matches the destination port, or the source port if onSource is true, against a list of ranges.
 type PortMatcher struct {
-	port v2net.PortRange
+	ports    *v2net.PortList
+	onSource bool
 }
 
-func NewPortMatcher(portRange v2net.PortRange) *PortMatcher {
+func NewPortMatcher(ports *v2net.PortList) *PortMatcher {
 	return &PortMatcher{
-		port: portRange,
+		ports: ports,
 	}
 }
 
-func (this *PortMatcher) Apply(dest v2net.Destination) bool {
-	return this.port.Contains(dest.Port)
+func NewSourcePortMatcher(ports *v2net.PortList) *PortMatcher {
+	return &PortMatcher{
+		ports:    ports,
+		onSource: true,
+	}
+}
+
+func (this *PortMatcher) Apply(ctx *router.Context) bool {
+	if this.onSource {
+		return this.ports.Contains(ctx.Source.Port)
+	}
+	return this.ports.Contains(ctx.Destination.Port)
+}
+
+func (this *PortMatcher) String() string {
+	ranges := make([]string, len(this.ports.Range))
+	for idx, r := range this.ports.Range {
+		if r.From == r.To {
+			ranges[idx] = r.FromPort().String()
+		} else {
+			ranges[idx] = r.FromPort().String() + "-" + r.ToPort().String()
+		}
+	}
+	if this.onSource {
+		return "sourcePort:" + strings.Join(ranges, ",")
+	}
+	return "port:" + strings.Join(ranges, ",")
 }
 
 type NetworkMatcher struct {
@@ -164,6 +366,152 @@ func NewNetworkMatcher(network *v2net.NetworkList) *NetworkMatcher {
 	}
 }
 
-func (this *NetworkMatcher) Apply(dest v2net.Destination) bool {
-	return this.network.HasNetwork(dest.Network)
+func (this *NetworkMatcher) Apply(ctx *router.Context) bool {
+	return this.network.HasNetwork(ctx.Destination.Network)
+}
+
+func (this *NetworkMatcher) String() string {
+	networks := make([]string, len(this.network.Network))
+	for idx, network := range this.network.Network {
+		networks[idx] = strings.ToLower(network.String())
+	}
+	return "network:" + strings.Join(networks, ",")
+}
+
+// InboundTagMatcher matches the connections from the inbound handlers of the given tags.
+type InboundTagMatcher struct {
+	tags []string
+}
+
+func NewInboundTagMatcher(tags []string) *InboundTagMatcher {
+	return &InboundTagMatcher{
+		tags: tags,
+	}
+}
+
+func (this *InboundTagMatcher) Apply(ctx *router.Context) bool {
+	for _, tag := range this.tags {
+		if tag == ctx.InboundTag {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *InboundTagMatcher) String() string {
+	return "inboundTag:" + strings.Join(this.tags, ",")
+}
+
+// UserMatcher matches the connections of the users with the given emails.
+type UserMatcher struct {
+	emails []string
+}
+
+func NewUserMatcher(emails []string) *UserMatcher {
+	return &UserMatcher{
+		emails: emails,
+	}
+}
+
+func (this *UserMatcher) Apply(ctx *router.Context) bool {
+	if ctx.User == nil {
+		return false
+	}
+	for _, email := range this.emails {
+		if strings.EqualFold(email, ctx.User.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *UserMatcher) String() string {
+	return "user:" + strings.Join(this.emails, ",")
+}
+
+// ProtocolMatcher matches the connections of the given sniffed protocols.
+type ProtocolMatcher struct {
+	protocols []string
+}
+
+func NewProtocolMatcher(protocols []string) *ProtocolMatcher {
+	return &ProtocolMatcher{
+		protocols: protocols,
+	}
+}
+
+func (this *ProtocolMatcher) Apply(ctx *router.Context) bool {
+	if len(ctx.Protocol) == 0 {
+		return false
+	}
+	for _, protocol := range this.protocols {
+		if strings.EqualFold(protocol, ctx.Protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *ProtocolMatcher) String() string {
+	return "protocol:" + strings.Join(this.protocols, ",")
+}
+
+// DNSSECMatcher matches domain destinations resolved by the DNS app, by whether their IPs are validated with DNSSEC.
+// It matches nothing if the destination is not resolved, so it takes effect with the IP domain strategies only.
+type DNSSECMatcher struct {
+	validated bool
+}
+
+func NewDNSSECMatcher(validated bool) *DNSSECMatcher {
+	return &DNSSECMatcher{
+		validated: validated,
+	}
+}
+
+func (this *DNSSECMatcher) Apply(ctx *router.Context) bool {
+	return ctx.ResolvedIPs != nil && ctx.ResolvedValidated == this.validated
+}
+
+func (this *DNSSECMatcher) String() string {
+	if this.validated {
+		return "dnssec:validated"
+	}
+	return "dnssec:unvalidated"
+}
+
+// describeCondition returns a readable description of the condition for tracing.
+func describeCondition(cond Condition) string {
+	if stringer, ok := cond.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", cond)
+}
+
+func describeConditions(prefix string, conds []Condition) string {
+	const maxDescribed = 5
+	if len(conds) > maxDescribed {
+		return fmt.Sprintf("%s %d conditions", prefix, len(conds))
+	}
+	descriptions := make([]string, len(conds))
+	for idx, cond := range conds {
+		descriptions[idx] = describeCondition(cond)
+	}
+	return prefix + " [" + strings.Join(descriptions, ", ") + "]"
+}
+
+// explainCondition applies the condition, and describes why it matched or not. For a chain of conditions, the first
+// condition that didn't match is described.
+func explainCondition(cond Condition, ctx *router.Context) (bool, string) {
+	if chain, ok := cond.(*ConditionChan); ok {
+		for _, c := range *chain {
+			if !c.Apply(ctx) {
+				return false, "not matched: " + describeCondition(c)
+			}
+		}
+		return true, "matched: " + describeCondition(chain)
+	}
+	if cond.Apply(ctx) {
+		return true, "matched: " + describeCondition(cond)
+	}
+	return false, "not matched: " + describeCondition(cond)
 }
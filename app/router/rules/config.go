@@ -1,27 +1,64 @@
 package rules
 
 import (
+	"time"
+
+	"v2ray.com/core/app/router"
 	v2net "v2ray.com/core/common/net"
 )
 
 type Rule struct {
-	Tag       string
-	Condition Condition
+	Tag string
+	// BalancerTag is the balancer that picks the outbound of this rule. It takes precedence over Tag.
+	BalancerTag string
+	Condition   Condition
 }
 
+// Apply checks the rule against a destination, without other information of the connection.
 func (this *Rule) Apply(dest v2net.Destination) bool {
-	return this.Condition.Apply(dest)
+	return this.Condition.Apply(&router.Context{
+		Destination: dest,
+	})
 }
 
 type DomainStrategy int
 
 var (
-	DomainAsIs      = DomainStrategy(0)
-	AlwaysUseIP     = DomainStrategy(1)
+	DomainAsIs = DomainStrategy(0)
+	// AlwaysUseIP resolves domain destinations via the DNS app, so that IP rules match the resolved IPs too.
+	AlwaysUseIP = DomainStrategy(1)
+	// UseIPIfNonMatch resolves domain destinations only if no rule matches the domain, and tries the rules on the IPs.
 	UseIPIfNonMatch = DomainStrategy(2)
 )
 
+// BalancerConfig is a set of outbounds that rules may refer to by BalancerTag.
+type BalancerConfig struct {
+	Tag          string
+	OutboundTags []string
+	// Weights are the weights of OutboundTags respectively, used by the random and round-robin strategies. Nil for
+	// equal weights.
+	Weights []uint32
+	// Strategy is the name of a strategy registered by router.RegisterBalancingStrategy. Default to "random".
+	Strategy string
+	// FallbackTags are tags of balancers or outbounds, tried in order when all OutboundTags fail their probes. The
+	// first one with an alive outbound is used, or the last one if none is alive.
+	FallbackTags []string
+}
+
+// ObservatoryConfig is the settings of probing the outbounds in balancers.
+type ObservatoryConfig struct {
+	// ProbeURL is the HTTP(S) URL requested through each outbound. Default to router.DefaultProbeURL.
+	ProbeURL      string
+	ProbeInterval time.Duration
+}
+
 type RouterRuleConfig struct {
-	Rules          []*Rule
+	Rules     []*Rule
+	Balancers []*BalancerConfig
+	// Observatory enables probing the outbounds in balancers. It is enabled with default settings if any balancer
+	// uses "leastping" or has fallbacks.
+	Observatory    *ObservatoryConfig
 	DomainStrategy DomainStrategy
+	// Trace enables logging every rule evaluation of every connection. Routing results are not cached in trace mode.
+	Trace bool
 }
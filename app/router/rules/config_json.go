@@ -5,69 +5,241 @@ package rules
 import (
 	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	router "v2ray.com/core/app/router"
 	"v2ray.com/core/common/collect"
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/platform"
+)
+
+const (
+	// maxRuleSetDepth limits nested includes of rule sets, so that an include cycle fails instead of looping forever.
+	maxRuleSetDepth = 8
 )
 
 type JsonRule struct {
 	Type        string `json:"type"`
 	OutboundTag string `json:"outboundTag"`
+	BalancerTag string `json:"balancerTag"`
+}
+
+// parseDomainRule parses an entry in the domain list of a field rule. See NewDomainMatcher for the prefixes in
+// addition to "geosite:".
+func parseDomainRule(rawDomain string) (Condition, error) {
+	if strings.HasPrefix(rawDomain, "geosite:") {
+		matcher, err := GetGeoSiteMatcher(rawDomain[8:])
+		if err != nil {
+			log.Error("Router: Failed to load GeoSite: ", err)
+			return nil, err
+		}
+		return matcher, nil
+	}
+	matcher, err := NewDomainMatcher(rawDomain)
+	if err != nil {
+		log.Error("Router: Invalid domain rule: ", err)
+		return nil, err
+	}
+	return matcher, nil
+}
+
+// parseDomainList parses the domain list of a field rule. "full:" and "domain:" entries are put in a DomainTrie, so
+// that large lists are matched quickly.
+func parseDomainList(rawDomains []string) (Condition, error) {
+	anyCond := NewAnyCondition()
+	trie := NewDomainTrie()
+	for _, rawDomain := range rawDomains {
+		switch {
+		case strings.HasPrefix(rawDomain, "full:"):
+			if err := trie.Add(rawDomain[5:], false); err != nil {
+				log.Error("Router: Invalid domain rule: ", rawDomain)
+				return nil, err
+			}
+		case strings.HasPrefix(rawDomain, "domain:"):
+			if err := trie.Add(rawDomain[7:], true); err != nil {
+				log.Error("Router: Invalid domain rule: ", rawDomain)
+				return nil, err
+			}
+		default:
+			matcher, err := parseDomainRule(rawDomain)
+			if err != nil {
+				return nil, err
+			}
+			anyCond.Add(matcher)
+		}
+	}
+	if trie.Len() > 0 {
+		anyCond.Add(trie)
+	}
+	return anyCond, nil
+}
+
+// parseNetworkList creates a matcher of the known networks in the list. A rule with no known network would never
+// match, so it is rejected.
+func parseNetworkList(list *v2net.NetworkList) (*NetworkMatcher, error) {
+	known := &v2net.NetworkList{}
+	for _, network := range list.Network {
+		if network == v2net.Network_Unknown {
+			log.Warning("Router: Unknown network in rule is ignored.")
+			continue
+		}
+		known.Network = append(known.Network, network)
+	}
+	if len(known.Network) == 0 {
+		return nil, errors.New("Router: No known network in rule.")
+	}
+	return NewNetworkMatcher(known), nil
+}
+
+// parseIPList parses a list of IPs, CIDRs and "geoip:" entries into a condition that matches any of them.
+func parseIPList(ips []string) (Condition, error) {
+	anyCond := NewAnyCondition()
+	for _, ipStr := range ips {
+		if strings.HasPrefix(ipStr, "geoip:") {
+			geoIPMatcher, err := GetGeoIPMatcher(ipStr[6:])
+			if err != nil {
+				log.Error("Router: Failed to load GeoIP: ", err)
+				return nil, err
+			}
+			anyCond.Add(geoIPMatcher)
+			continue
+		}
+		cidrMatcher, err := NewCIDRMatcher(ipStr)
+		if err != nil {
+			log.Error("Router: Invalid IP range in router rule: ", err)
+			return nil, err
+		}
+		anyCond.Add(cidrMatcher)
+	}
+	return anyCond, nil
+}
+
+type JsonSchedule struct {
+	Days     *collect.StringList `json:"days"`
+	Time     *collect.StringList `json:"time"`
+	Timezone string              `json:"timezone"`
+}
+
+func parseSchedule(rawSchedule *JsonSchedule) (*ScheduleMatcher, error) {
+	var weekdays []time.Weekday
+	if rawSchedule.Days != nil {
+		days, err := ParseWeekdays(*rawSchedule.Days)
+		if err != nil {
+			return nil, errors.New("Router: Invalid days in schedule.")
+		}
+		weekdays = days
+	}
+	var ranges []TimeRange
+	if rawSchedule.Time != nil {
+		for _, rangeStr := range *rawSchedule.Time {
+			r, err := ParseTimeRange(rangeStr)
+			if err != nil {
+				return nil, errors.New("Router: Invalid time range in schedule: " + rangeStr)
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	if len(weekdays) == 0 && len(ranges) == 0 {
+		return nil, errors.New("Router: Empty schedule.")
+	}
+	var location *time.Location
+	if len(rawSchedule.Timezone) > 0 {
+		loc, err := time.LoadLocation(rawSchedule.Timezone)
+		if err != nil {
+			return nil, errors.New("Router: Unknown time zone in schedule: " + rawSchedule.Timezone)
+		}
+		location = loc
+	}
+	return NewScheduleMatcher(weekdays, ranges, location), nil
 }
 
 func parseFieldRule(msg json.RawMessage) (*Rule, error) {
 	type RawFieldRule struct {
 		JsonRule
-		Domain  *collect.StringList `json:"domain"`
-		IP      *collect.StringList `json:"ip"`
-		Port    *v2net.PortRange    `json:"port"`
-		Network *v2net.NetworkList  `json:"network"`
+		Domain     *collect.StringList `json:"domain"`
+		IP         *collect.StringList `json:"ip"`
+		Port       *v2net.PortList     `json:"port"`
+		SourcePort *v2net.PortList     `json:"sourcePort"`
+		Source     *collect.StringList `json:"source"`
+		Protocol   *collect.StringList `json:"protocol"`
+		Network    *v2net.NetworkList  `json:"network"`
+		InboundTag *collect.StringList `json:"inboundTag"`
+		User       *collect.StringList `json:"user"`
+		Schedule   *JsonSchedule       `json:"schedule"`
+		DNSSEC     string              `json:"dnssec"`
 	}
 	rawFieldRule := new(RawFieldRule)
-	err := json.Unmarshal(msg, rawFieldRule)
+	err := loader.DecodeJSON(msg, rawFieldRule)
 	if err != nil {
 		return nil, err
 	}
 	conds := NewConditionChan()
 
 	if rawFieldRule.Domain != nil && rawFieldRule.Domain.Len() > 0 {
-		anyCond := NewAnyCondition()
-		for _, rawDomain := range *(rawFieldRule.Domain) {
-			var matcher Condition
-			if strings.HasPrefix(rawDomain, "regexp:") {
-				rawMatcher, err := NewRegexpDomainMatcher(rawDomain[7:])
-				if err != nil {
-					return nil, err
-				}
-				matcher = rawMatcher
-			} else {
-				matcher = NewPlainDomainMatcher(rawDomain)
-			}
-			anyCond.Add(matcher)
+		domainCond, err := parseDomainList(*rawFieldRule.Domain)
+		if err != nil {
+			return nil, err
 		}
-		conds.Add(anyCond)
+		conds.Add(domainCond)
 	}
 
 	if rawFieldRule.IP != nil && rawFieldRule.IP.Len() > 0 {
-		anyCond := NewAnyCondition()
-		for _, ipStr := range *(rawFieldRule.IP) {
-			cidrMatcher, err := NewCIDRMatcher(ipStr)
-			if err != nil {
-				log.Error("Router: Invalid IP range in router rule: ", err)
-				return nil, err
-			}
-			anyCond.Add(cidrMatcher)
+		ipCond, err := parseIPList(*rawFieldRule.IP)
+		if err != nil {
+			return nil, err
 		}
-		conds.Add(anyCond)
+		conds.Add(ipCond)
 	}
-	if rawFieldRule.Port != nil {
-		conds.Add(NewPortMatcher(*rawFieldRule.Port))
+	if rawFieldRule.Source != nil && rawFieldRule.Source.Len() > 0 {
+		ipCond, err := parseIPList(*rawFieldRule.Source)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(NewSourceMatcher(ipCond))
+	}
+	if rawFieldRule.Port != nil && len(rawFieldRule.Port.Range) > 0 {
+		conds.Add(NewPortMatcher(rawFieldRule.Port))
+	}
+	if rawFieldRule.SourcePort != nil && len(rawFieldRule.SourcePort.Range) > 0 {
+		conds.Add(NewSourcePortMatcher(rawFieldRule.SourcePort))
 	}
 	if rawFieldRule.Network != nil {
-		conds.Add(NewNetworkMatcher(rawFieldRule.Network))
+		networkMatcher, err := parseNetworkList(rawFieldRule.Network)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(networkMatcher)
+	}
+	if rawFieldRule.InboundTag != nil && rawFieldRule.InboundTag.Len() > 0 {
+		conds.Add(NewInboundTagMatcher(*rawFieldRule.InboundTag))
+	}
+	if rawFieldRule.User != nil && rawFieldRule.User.Len() > 0 {
+		conds.Add(NewUserMatcher(*rawFieldRule.User))
+	}
+	if rawFieldRule.Protocol != nil && rawFieldRule.Protocol.Len() > 0 {
+		conds.Add(NewProtocolMatcher(*rawFieldRule.Protocol))
+	}
+	if rawFieldRule.Schedule != nil {
+		scheduleMatcher, err := parseSchedule(rawFieldRule.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(scheduleMatcher)
+	}
+	switch strings.ToLower(rawFieldRule.DNSSEC) {
+	case "":
+	case "validated":
+		conds.Add(NewDNSSECMatcher(true))
+	case "unvalidated":
+		conds.Add(NewDNSSECMatcher(false))
+	default:
+		return nil, errors.New("Router: Unknown DNSSEC status: " + rawFieldRule.DNSSEC)
 	}
 	if conds.Len() == 0 {
 		return nil, errors.New("Router: This rule has no effective fields.")
@@ -85,6 +257,14 @@ func ParseRule(msg json.RawMessage) *Rule {
 		log.Error("Router: Invalid router rule: ", err)
 		return nil
 	}
+	rule := parseTypedRule(rawRule, msg)
+	if rule != nil {
+		rule.BalancerTag = rawRule.BalancerTag
+	}
+	return rule
+}
+
+func parseTypedRule(rawRule *JsonRule, msg json.RawMessage) *Rule {
 	if rawRule.Type == "field" {
 
 		fieldrule, err := parseFieldRule(msg)
@@ -114,19 +294,144 @@ func ParseRule(msg json.RawMessage) *Rule {
 	return nil
 }
 
+type JsonBalancer struct {
+	Tag          string              `json:"tag"`
+	OutboundTags *collect.StringList `json:"outboundTags"`
+	Strategy     string              `json:"strategy"`
+	// Weights maps outbound tags to their weights. Outbounds not in the map have weight 1.
+	Weights      map[string]uint32   `json:"weights"`
+	FallbackTags *collect.StringList `json:"fallbackTags"`
+}
+
+func parseBalancer(rawBalancer *JsonBalancer) (*BalancerConfig, error) {
+	if len(rawBalancer.Tag) == 0 {
+		return nil, errors.New("Router: Empty balancer tag.")
+	}
+	if rawBalancer.OutboundTags == nil || rawBalancer.OutboundTags.Len() == 0 {
+		return nil, errors.New("Router: No outbound in balancer " + rawBalancer.Tag + ".")
+	}
+	config := &BalancerConfig{
+		Tag:          rawBalancer.Tag,
+		OutboundTags: *rawBalancer.OutboundTags,
+		Strategy:     strings.ToLower(rawBalancer.Strategy),
+	}
+	if rawBalancer.FallbackTags != nil {
+		config.FallbackTags = *rawBalancer.FallbackTags
+	}
+	if len(rawBalancer.Weights) > 0 {
+		config.Weights = make([]uint32, len(config.OutboundTags))
+		weighted := 0
+		for idx, tag := range config.OutboundTags {
+			config.Weights[idx] = 1
+			if weight, found := rawBalancer.Weights[tag]; found {
+				config.Weights[idx] = weight
+				weighted++
+			}
+		}
+		if weighted != len(rawBalancer.Weights) {
+			return nil, errors.New("Router: Weight of unknown outbound in balancer " + rawBalancer.Tag + ".")
+		}
+	}
+	return config, nil
+}
+
+// ruleSetPath returns the path of a rule set file. Relative paths are resolved against dir, the directory of the
+// including rule set, if any. Otherwise, those that don't exist in the working directory are looked up in the asset
+// location, where bundled rule sets are installed along with geoip.dat.
+func ruleSetPath(path string, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if len(dir) > 0 {
+		if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+			return filepath.Join(dir, path)
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return platform.GetAssetLocation(path)
+}
+
+// loadRuleSet loads the rules in a rule set file. The file is either a JSON array of rules, or an object with the
+// rules in its "rules" field, like the routing settings.
+func loadRuleSet(path string, dir string, depth int) ([]*Rule, error) {
+	if depth >= maxRuleSetDepth {
+		return nil, errors.New("Router: Too many nested rule sets: " + path)
+	}
+	path = ruleSetPath(path, dir)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rawRules []json.RawMessage
+	if err := json.Unmarshal(data, &rawRules); err != nil {
+		ruleSet := new(struct {
+			RuleList []json.RawMessage `json:"rules"`
+		})
+		if err := loader.DecodeJSON(data, ruleSet); err != nil {
+			return nil, errors.New("Router: Invalid rule set " + path + ": " + err.Error())
+		}
+		rawRules = ruleSet.RuleList
+	}
+	log.Info("Router: Loaded ", len(rawRules), " rules from ", path)
+	return parseRuleList(rawRules, filepath.Dir(path), depth+1)
+}
+
+// parseRuleList parses a list of rules. An entry of type "include" is replaced by the rules in the rule set file at
+// its "path", so that rules keep their order across files. Relative paths are resolved against dir, which is empty for
+// the routing settings.
+func parseRuleList(rawRules []json.RawMessage, dir string, depth int) ([]*Rule, error) {
+	type JsonInclude struct {
+		Type string `json:"type"`
+		Path string `json:"path"`
+	}
+	rules := make([]*Rule, 0, len(rawRules))
+	for _, rawRule := range rawRules {
+		include := new(JsonInclude)
+		if err := json.Unmarshal(rawRule, include); err == nil && include.Type == "include" {
+			if len(include.Path) == 0 {
+				return nil, errors.New("Router: Empty path of rule set.")
+			}
+			included, err := loadRuleSet(include.Path, dir, depth)
+			if err != nil {
+				log.Error("Router: Failed to load rule set ", include.Path, ": ", err)
+				return nil, err
+			}
+			rules = append(rules, included...)
+			continue
+		}
+		rules = append(rules, ParseRule(rawRule))
+	}
+	return rules, nil
+}
+
+type JsonObservatory struct {
+	ProbeURL      string `json:"probeURL"`
+	ProbeInterval uint32 `json:"probeInterval"`
+}
+
 func init() {
 	router.RegisterRouterConfig("rules", func(data []byte) (interface{}, error) {
 		type JsonConfig struct {
 			RuleList       []json.RawMessage `json:"rules"`
+			Balancers      []*JsonBalancer   `json:"balancers"`
+			Observatory    *JsonObservatory  `json:"observatory"`
 			DomainStrategy string            `json:"domainStrategy"`
+			Trace          bool              `json:"trace"`
 		}
 		jsonConfig := new(JsonConfig)
-		if err := json.Unmarshal(data, jsonConfig); err != nil {
+		if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+			return nil, err
+		}
+		rules, err := parseRuleList(jsonConfig.RuleList, "", 0)
+		if err != nil {
 			return nil, err
 		}
 		config := &RouterRuleConfig{
-			Rules:          make([]*Rule, len(jsonConfig.RuleList)),
+			Rules:          rules,
 			DomainStrategy: DomainAsIs,
+			Trace:          jsonConfig.Trace,
 		}
 		domainStrategy := strings.ToLower(jsonConfig.DomainStrategy)
 		if domainStrategy == "alwaysip" {
@@ -134,9 +439,19 @@ func init() {
 		} else if domainStrategy == "ipifnonmatch" {
 			config.DomainStrategy = UseIPIfNonMatch
 		}
-		for idx, rawRule := range jsonConfig.RuleList {
-			rule := ParseRule(rawRule)
-			config.Rules[idx] = rule
+		for _, rawBalancer := range jsonConfig.Balancers {
+			balancer, err := parseBalancer(rawBalancer)
+			if err != nil {
+				log.Error("Router: Invalid balancer: ", err)
+				return nil, err
+			}
+			config.Balancers = append(config.Balancers, balancer)
+		}
+		if jsonConfig.Observatory != nil {
+			config.Observatory = &ObservatoryConfig{
+				ProbeURL:      jsonConfig.Observatory.ProbeURL,
+				ProbeInterval: time.Duration(jsonConfig.Observatory.ProbeInterval) * time.Second,
+			}
 		}
 		return config, nil
 	})
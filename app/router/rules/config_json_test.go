@@ -3,10 +3,17 @@
 package rules_test
 
 import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"v2ray.com/core/app/router"
 	. "v2ray.com/core/app/router/rules"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/testing/assert"
 )
 
@@ -49,3 +56,357 @@ func TestIPRule(t *testing.T) {
 	assert.Bool(rule.Apply(v2net.TCPDestination(v2net.IPAddress([]byte{127, 0, 0, 1}), 80))).IsFalse()
 	assert.Bool(rule.Apply(v2net.TCPDestination(v2net.IPAddress([]byte{192, 0, 0, 1}), 80))).IsTrue()
 }
+
+func TestGeoIPRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "ip": [
+      "geoip:private",
+      "8.8.8.8/32"
+    ],
+    "outboundTag": "direct"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	assert.Bool(rule.Apply(v2net.TCPDestination(v2net.IPAddress([]byte{192, 168, 0, 1}), 80))).IsTrue()
+	assert.Bool(rule.Apply(v2net.TCPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 80))).IsTrue()
+	assert.Bool(rule.Apply(v2net.TCPDestination(v2net.IPAddress([]byte{1, 1, 1, 1}), 80))).IsFalse()
+}
+
+func TestDomainRulePrefixes(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "domain": [
+      "full:www.v2ray.com",
+      "domain:Example.org",
+      "regexp:^ads?\\."
+    ],
+    "outboundTag": "direct"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	assert.Bool(rule.Apply(makeDomainDestination("www.v2ray.com"))).IsTrue()
+	assert.Bool(rule.Apply(makeDomainDestination("v2ray.com"))).IsFalse()
+	assert.Bool(rule.Apply(makeDomainDestination("www.v2ray.com.cn"))).IsFalse()
+	assert.Bool(rule.Apply(makeDomainDestination("example.org"))).IsTrue()
+	assert.Bool(rule.Apply(makeDomainDestination("WWW.EXAMPLE.ORG"))).IsTrue()
+	assert.Bool(rule.Apply(makeDomainDestination("myexample.org"))).IsFalse()
+	assert.Bool(rule.Apply(makeDomainDestination("ad.v2ray.com"))).IsTrue()
+	assert.Bool(rule.Apply(makeDomainDestination("ads.v2ray.com"))).IsTrue()
+	assert.Bool(rule.Apply(makeDomainDestination("adds.v2ray.com"))).IsFalse()
+
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "domain": ["regexp:("], "outboundTag": "direct"}`))).IsNil()
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "domain": ["domain:"], "outboundTag": "direct"}`))).IsNil()
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "domain": ["full:"], "outboundTag": "direct"}`))).IsNil()
+}
+
+func TestInboundTagRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "inboundTag": ["socks-in", "redirect-in"],
+    "outboundTag": "direct"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	dest := makeDomainDestination("v2ray.com")
+	assert.Bool(rule.Condition.Apply(&router.Context{InboundTag: "socks-in", Destination: dest})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{InboundTag: "redirect-in", Destination: dest})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{InboundTag: "vmess-in", Destination: dest})).IsFalse()
+	assert.Bool(rule.Apply(dest)).IsFalse()
+}
+
+func TestUserRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "user": ["alice@v2ray.com", "bob@v2ray.com"],
+    "outboundTag": "direct"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	dest := makeDomainDestination("v2ray.com")
+	assert.Bool(rule.Condition.Apply(&router.Context{User: &protocol.User{Email: "alice@v2ray.com"}, Destination: dest})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{User: &protocol.User{Email: "Bob@V2Ray.com"}, Destination: dest})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{User: &protocol.User{Email: "carol@v2ray.com"}, Destination: dest})).IsFalse()
+	assert.Bool(rule.Apply(dest)).IsFalse()
+}
+
+func TestBalancerConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	rawConfig, err := router.CreateRouterConfig("rules", []byte(`{
+    "rules": [{
+      "type": "field",
+      "network": "tcp",
+      "balancerTag": "exits"
+    }],
+    "balancers": [{
+      "tag": "exits",
+      "outboundTags": ["exit1", "exit2"],
+      "strategy": "leastLoad",
+      "weights": {"exit1": 4},
+      "fallbackTags": ["backup", "direct"]
+    }],
+    "observatory": {
+      "probeURL": "https://www.v2ray.com/",
+      "probeInterval": 30
+    }
+  }`))
+	assert.Error(err).IsNil()
+	config := rawConfig.(*RouterRuleConfig)
+	assert.String(config.Rules[0].BalancerTag).Equals("exits")
+	assert.Int(len(config.Balancers)).Equals(1)
+	assert.String(config.Balancers[0].Tag).Equals("exits")
+	assert.String(config.Balancers[0].Strategy).Equals("leastload")
+	assert.Int(len(config.Balancers[0].OutboundTags)).Equals(2)
+	assert.Uint32(config.Balancers[0].Weights[0]).Equals(4)
+	assert.Uint32(config.Balancers[0].Weights[1]).Equals(1)
+	assert.Int(len(config.Balancers[0].FallbackTags)).Equals(2)
+	assert.String(config.Balancers[0].FallbackTags[1]).Equals("direct")
+	assert.String(config.Observatory.ProbeURL).Equals("https://www.v2ray.com/")
+	assert.Int64(int64(config.Observatory.ProbeInterval)).Equals(int64(30 * time.Second))
+
+	_, err = router.CreateRouterConfig("rules", []byte(`{"balancers": [{"tag": "exits"}]}`))
+	assert.Error(err).IsNotNil()
+	_, err = router.CreateRouterConfig("rules", []byte(`{"balancers": [{"tag": "exits", "outboundTags": ["exit1"], "weights": {"exit2": 1}}]}`))
+	assert.Error(err).IsNotNil()
+}
+
+func TestPortListRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "port": "53,443,1000-2000",
+    "sourcePort": [5000, "6000-7000"],
+    "outboundTag": "direct"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	source := v2net.TCPDestination(v2net.LocalHostIP, 6500)
+	assert.Bool(rule.Condition.Apply(&router.Context{
+		Source:      source,
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 443),
+	})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{
+		Source:      source,
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 1024),
+	})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{
+		Source:      source,
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80),
+	})).IsFalse()
+	assert.Bool(rule.Condition.Apply(&router.Context{
+		Source:      v2net.TCPDestination(v2net.LocalHostIP, 5001),
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 53),
+	})).IsFalse()
+}
+
+func TestSourceRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "source": ["192.168.1.10", "10.0.0.0/8", "fd00::/8"],
+    "outboundTag": "direct"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	dest := v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 443)
+	for _, source := range []string{"192.168.1.10", "10.1.2.3", "fd00::1"} {
+		assert.Bool(rule.Condition.Apply(&router.Context{
+			Source:      v2net.TCPDestination(v2net.ParseAddress(source), 50000),
+			Destination: dest,
+		})).IsTrue()
+	}
+	for _, source := range []string{"192.168.1.11", "172.16.0.1", "fe80::1"} {
+		assert.Bool(rule.Condition.Apply(&router.Context{
+			Source:      v2net.TCPDestination(v2net.ParseAddress(source), 50000),
+			Destination: dest,
+		})).IsFalse()
+	}
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest})).IsFalse()
+	// The destination address is not checked against source ranges.
+	assert.Bool(rule.Apply(v2net.TCPDestination(v2net.ParseAddress("10.1.2.3"), 443))).IsFalse()
+
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "source": ["10.0.0.0/33"], "outboundTag": "direct"}`))).IsNil()
+}
+
+func TestProtocolRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "protocol": ["bittorrent"],
+    "outboundTag": "blocked"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	dest := v2net.TCPDestination(v2net.ParseAddress("1.2.3.4"), 6881)
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest, Protocol: "bittorrent"})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest, Protocol: "tls"})).IsFalse()
+	assert.Bool(rule.Apply(dest)).IsFalse()
+
+	// Domain rules match the sniffed server name of IP destinations.
+	rule = ParseRule([]byte(`{
+    "type": "field",
+    "protocol": "tls",
+    "domain": ["domain:v2ray.com"],
+    "outboundTag": "proxy"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	dest = v2net.TCPDestination(v2net.ParseAddress("1.2.3.4"), 443)
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest, Protocol: "tls", SniffedDomain: "www.v2ray.com"})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest, Protocol: "tls", SniffedDomain: "v2ray.org"})).IsFalse()
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest, Protocol: "tls"})).IsFalse()
+}
+
+func TestNetworkRule(t *testing.T) {
+	assert := assert.On(t)
+
+	config, err := router.CreateRouterConfig("rules", []byte(`{
+    "rules": [{
+      "type": "field",
+      "network": "udp",
+      "port": 443,
+      "outboundTag": "blocked"
+    }, {
+      "type": "field",
+      "network": "tcp, udp",
+      "port": 443,
+      "outboundTag": "proxy"
+    }]
+  }`))
+	assert.Error(err).IsNil()
+	rules := config.(*RouterRuleConfig).Rules
+	address := v2net.DomainAddress("www.v2ray.com")
+
+	assert.Bool(rules[0].Apply(v2net.UDPDestination(address, 443))).IsTrue()
+	assert.Bool(rules[0].Apply(v2net.TCPDestination(address, 443))).IsFalse()
+	assert.Bool(rules[1].Apply(v2net.TCPDestination(address, 443))).IsTrue()
+	assert.Bool(rules[1].Apply(v2net.TCPDestination(address, 80))).IsFalse()
+
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "network": "tpc", "outboundTag": "proxy"}`))).IsNil()
+}
+
+func TestRuleSetInclude(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-rules")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	adsFile := filepath.Join(dir, "ads.json")
+	assert.Error(ioutil.WriteFile(adsFile, []byte(`[{
+    "type": "field",
+    "domain": ["domain:ads.com"],
+    "outboundTag": "blocked"
+  }]`), 0644)).IsNil()
+	listFile := filepath.Join(dir, "list.json")
+	assert.Error(ioutil.WriteFile(listFile, []byte(`{
+    "rules": [
+      {"type": "include", "path": "`+adsFile+`"},
+      {"type": "field", "ip": ["10.0.0.0/8"], "outboundTag": "direct"}
+    ]
+  }`), 0644)).IsNil()
+
+	rawConfig, err := router.CreateRouterConfig("rules", []byte(`{
+    "rules": [
+      {"type": "field", "inboundTag": "api", "outboundTag": "api"},
+      {"type": "include", "path": "`+listFile+`"},
+      {"type": "field", "network": "tcp,udp", "outboundTag": "proxy"}
+    ]
+  }`))
+	assert.Error(err).IsNil()
+	config := rawConfig.(*RouterRuleConfig)
+	assert.Int(len(config.Rules)).Equals(4)
+	assert.String(config.Rules[0].Tag).Equals("api")
+	assert.String(config.Rules[1].Tag).Equals("blocked")
+	assert.Bool(config.Rules[1].Apply(v2net.TCPDestination(v2net.DomainAddress("www.ads.com"), 80))).IsTrue()
+	assert.String(config.Rules[2].Tag).Equals("direct")
+	assert.String(config.Rules[3].Tag).Equals("proxy")
+
+	_, err = router.CreateRouterConfig("rules", []byte(`{"rules": [{"type": "include", "path": "`+filepath.Join(dir, "none.json")+`"}]}`))
+	assert.Error(err).IsNotNil()
+
+	loopFile := filepath.Join(dir, "loop.json")
+	assert.Error(ioutil.WriteFile(loopFile, []byte(`[{"type": "include", "path": "`+loopFile+`"}]`), 0644)).IsNil()
+	_, err = router.CreateRouterConfig("rules", []byte(`{"rules": [{"type": "include", "path": "`+loopFile+`"}]}`))
+	assert.Error(err).IsNotNil()
+}
+
+func TestNestedRuleSetRelativeInclude(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-rules")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	// The nested include is relative to the directory of list.json, not the working directory.
+	assert.Error(os.Mkdir(filepath.Join(dir, "sets"), 0755)).IsNil()
+	assert.Error(ioutil.WriteFile(filepath.Join(dir, "sets", "ads.json"), []byte(`[{
+    "type": "field",
+    "domain": ["domain:ads.com"],
+    "outboundTag": "blocked"
+  }]`), 0644)).IsNil()
+	listFile := filepath.Join(dir, "list.json")
+	assert.Error(ioutil.WriteFile(listFile, []byte(`[{"type": "include", "path": "sets/ads.json"}]`), 0644)).IsNil()
+
+	rawConfig, err := router.CreateRouterConfig("rules", []byte(`{"rules": [{"type": "include", "path": "`+listFile+`"}]}`))
+	assert.Error(err).IsNil()
+	config := rawConfig.(*RouterRuleConfig)
+	assert.Int(len(config.Rules)).Equals(1)
+	assert.String(config.Rules[0].Tag).Equals("blocked")
+}
+
+func TestScheduleRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "domain": ["domain:youtube.com"],
+    "schedule": {
+      "days": ["sat", "sun"],
+      "time": ["08:00-12:00", "20:00-23:00"],
+      "timezone": "UTC"
+    },
+    "outboundTag": "fast"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	ctx := &router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("www.youtube.com"), 443),
+		// A Saturday.
+		Time: time.Date(2017, 1, 7, 9, 30, 0, 0, time.UTC),
+	}
+	assert.Bool(rule.Condition.Apply(ctx)).IsTrue()
+	ctx.Time = time.Date(2017, 1, 7, 13, 0, 0, 0, time.UTC)
+	assert.Bool(rule.Condition.Apply(ctx)).IsFalse()
+	ctx.Time = time.Date(2017, 1, 9, 9, 30, 0, 0, time.UTC)
+	assert.Bool(rule.Condition.Apply(ctx)).IsFalse()
+
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "schedule": {}, "outboundTag": "fast"}`))).IsNil()
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "schedule": {"time": "8:00"}, "outboundTag": "fast"}`))).IsNil()
+	assert.Pointer(ParseRule([]byte(`{"type": "field", "schedule": {"days": "mon", "timezone": "Nowhere/City"}, "outboundTag": "fast"}`))).IsNil()
+}
+
+func TestDNSSECRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "dnssec": "unvalidated",
+    "outboundTag": "proxy"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	dest := v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), 80)
+	ips := []net.IP{net.IP([]byte{10, 0, 0, 1})}
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest, ResolvedIPs: ips})).IsTrue()
+	assert.Bool(rule.Condition.Apply(&router.Context{Destination: dest, ResolvedIPs: ips, ResolvedValidated: true})).IsFalse()
+	assert.Bool(rule.Apply(dest)).IsFalse()
+
+	assert.Pointer(ParseRule([]byte(`{
+    "type": "field",
+    "dnssec": "secure",
+    "outboundTag": "proxy"
+  }`))).IsNil()
+}
@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"v2ray.com/core/app/router"
+)
+
+var (
+	ErrEmptyDomain = errors.New("Router: Empty domain in domain rule.")
+)
+
+type trieNode struct {
+	children map[string]*trieNode
+	// full is true if the domain ending at this node matches exactly.
+	full bool
+	// suffix is true if the domain ending at this node and all its subdomains match.
+	suffix bool
+}
+
+// DomainTrie matches domains against a set of full domains and domain suffixes. Domains are stored by their labels
+// from right to left, so that a lookup takes time linear to the number of labels regardless of the size of the set.
+type DomainTrie struct {
+	root *trieNode
+	size int
+}
+
+func NewDomainTrie() *DomainTrie {
+	return &DomainTrie{
+		root: new(trieNode),
+	}
+}
+
+// Add adds a domain to the trie. If withSubdomains is true, all subdomains of the domain match too. Empty domains are
+// rejected, as they would match all domains or none.
+func (this *DomainTrie) Add(domain string, withSubdomains bool) error {
+	if len(domain) == 0 {
+		return ErrEmptyDomain
+	}
+	node := this.root
+	domain = strings.ToLower(domain)
+	for len(domain) > 0 {
+		var label string
+		if idx := strings.LastIndex(domain, "."); idx >= 0 {
+			label = domain[idx+1:]
+			domain = domain[:idx]
+		} else {
+			label = domain
+			domain = ""
+		}
+		if node.children == nil {
+			node.children = make(map[string]*trieNode)
+		}
+		child, found := node.children[label]
+		if !found {
+			child = new(trieNode)
+			node.children[label] = child
+		}
+		node = child
+	}
+	if withSubdomains {
+		node.suffix = true
+	} else {
+		node.full = true
+	}
+	this.size++
+	return nil
+}
+
+// Len returns the number of domains added.
+func (this *DomainTrie) Len() int {
+	return this.size
+}
+
+func (this *DomainTrie) Match(domain string) bool {
+	node := this.root
+	for len(domain) > 0 {
+		var label string
+		if idx := strings.LastIndex(domain, "."); idx >= 0 {
+			label = domain[idx+1:]
+			domain = domain[:idx]
+		} else {
+			label = domain
+			domain = ""
+		}
+		child, found := node.children[label]
+		if !found {
+			return false
+		}
+		node = child
+		if node.suffix {
+			return true
+		}
+	}
+	return node.full
+}
+
+func (this *DomainTrie) Apply(ctx *router.Context) bool {
+	return applyDomainMatcher(this, ctx)
+}
+
+func (this *DomainTrie) String() string {
+	return fmt.Sprintf("%d domains", this.size)
+}
@@ -0,0 +1,75 @@
+package rules_test
+
+import (
+	"strconv"
+	"testing"
+
+	. "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestDomainTrie(t *testing.T) {
+	assert := assert.On(t)
+
+	trie := NewDomainTrie()
+	trie.Add("v2ray.com", true)
+	trie.Add("www.Google.com", false)
+	trie.Add("cn", true)
+	assert.Int(trie.Len()).Equals(3)
+
+	assert.Bool(trie.Match("v2ray.com")).IsTrue()
+	assert.Bool(trie.Match("www.v2ray.com")).IsTrue()
+	assert.Bool(trie.Match("a.b.v2ray.com")).IsTrue()
+	assert.Bool(trie.Match("xv2ray.com")).IsFalse()
+	assert.Bool(trie.Match("v2ray.com.hk")).IsFalse()
+
+	assert.Bool(trie.Match("www.google.com")).IsTrue()
+	assert.Bool(trie.Match("google.com")).IsFalse()
+	assert.Bool(trie.Match("mail.www.google.com")).IsFalse()
+
+	assert.Bool(trie.Match("baidu.cn")).IsTrue()
+	assert.Bool(trie.Match("com")).IsFalse()
+	assert.Bool(trie.Match("")).IsFalse()
+
+	assert.Bool(trie.Apply(makeContext(makeDomainDestination("mail.v2ray.com")))).IsTrue()
+
+	assert.Error(trie.Add("", true)).Equals(ErrEmptyDomain)
+	assert.Error(trie.Add("", false)).Equals(ErrEmptyDomain)
+	assert.Int(trie.Len()).Equals(3)
+}
+
+func buildLargeTrie(size int) *DomainTrie {
+	trie := NewDomainTrie()
+	for i := 0; i < size; i++ {
+		trie.Add("site"+strconv.Itoa(i)+".example"+strconv.Itoa(i%100)+".com", i%2 == 0)
+	}
+	return trie
+}
+
+func BenchmarkDomainTrie100K(b *testing.B) {
+	trie := buildLargeTrie(100000)
+	domains := []string{
+		"www.site50000.example0.com",
+		"site99999.example99.com",
+		"www.v2ray.com",
+		"a.b.c.d.site123.example23.org",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Match(domains[i%len(domains)])
+	}
+}
+
+func BenchmarkLinearDomainMatchers100K(b *testing.B) {
+	anyCond := NewAnyCondition()
+	for i := 0; i < 100000; i++ {
+		anyCond.Add(NewSubDomainMatcher("site" + strconv.Itoa(i) + ".example" + strconv.Itoa(i%100) + ".com"))
+	}
+	ctx := makeContext(makeDomainDestination("www.v2ray.com"))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		anyCond.Apply(ctx)
+	}
+}
@@ -0,0 +1,261 @@
+package rules
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/platform"
+)
+
+const (
+	GeoIPFile = "geoip.dat"
+)
+
+var (
+	ErrInvalidCIDR = errors.New("Router|GeoIP: Invalid CIDR.")
+
+	geoIPAccess sync.Mutex
+	geoIPCache  = make(map[string]*GeoIPMatcher)
+
+	// privateCIDRs are the reserved ranges matched by "geoip:private".
+	privateCIDRs = []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.88.99.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+)
+
+type ipv4Range struct {
+	from uint32
+	to   uint32
+}
+
+type ipv6Range struct {
+	from [2]uint64
+	to   [2]uint64
+}
+
+func lessIPv6(a, b [2]uint64) bool {
+	return a[0] < b[0] || (a[0] == b[0] && a[1] < b[1])
+}
+
+type ipv4Ranges []ipv4Range
+
+func (this ipv4Ranges) Len() int           { return len(this) }
+func (this ipv4Ranges) Less(i, j int) bool { return this[i].from < this[j].from }
+func (this ipv4Ranges) Swap(i, j int)      { this[i], this[j] = this[j], this[i] }
+
+type ipv6Ranges []ipv6Range
+
+func (this ipv6Ranges) Len() int           { return len(this) }
+func (this ipv6Ranges) Less(i, j int) bool { return lessIPv6(this[i].from, this[j].from) }
+func (this ipv6Ranges) Swap(i, j int)      { this[i], this[j] = this[j], this[i] }
+
+// GeoIPMatcher matches IP destinations against a set of CIDRs, such as all IPs of a country.
+type GeoIPMatcher struct {
+	ip4 []ipv4Range
+	ip6 []ipv6Range
+	// country is the country code in geoip.dat, for tracing. Empty if the matcher is not from geoip.dat.
+	country string
+}
+
+func NewGeoIPMatcher(cidrs []*CIDR) (*GeoIPMatcher, error) {
+	matcher := new(GeoIPMatcher)
+	for _, cidr := range cidrs {
+		switch len(cidr.Ip) {
+		case net.IPv4len:
+			if cidr.Prefix > 32 {
+				return nil, ErrInvalidCIDR
+			}
+			mask := uint32(0)
+			if cidr.Prefix > 0 {
+				mask = ^uint32(0) << (32 - cidr.Prefix)
+			}
+			from := binary.BigEndian.Uint32(cidr.Ip) & mask
+			matcher.ip4 = append(matcher.ip4, ipv4Range{from: from, to: from | ^mask})
+		case net.IPv6len:
+			if cidr.Prefix > 128 {
+				return nil, ErrInvalidCIDR
+			}
+			var from, to [2]uint64
+			for i := 0; i < 2; i++ {
+				prefix := int(cidr.Prefix) - i*64
+				mask := uint64(0)
+				switch {
+				case prefix >= 64:
+					mask = ^uint64(0)
+				case prefix > 0:
+					mask = ^uint64(0) << uint(64-prefix)
+				}
+				from[i] = binary.BigEndian.Uint64(cidr.Ip[i*8:]) & mask
+				to[i] = from[i] | ^mask
+			}
+			matcher.ip6 = append(matcher.ip6, ipv6Range{from: from, to: to})
+		default:
+			return nil, ErrInvalidCIDR
+		}
+	}
+	matcher.normalize()
+	return matcher, nil
+}
+
+// normalize sorts the ranges and merges the overlapping ones, so that they can be binary searched.
+func (this *GeoIPMatcher) normalize() {
+	sort.Sort(ipv4Ranges(this.ip4))
+	ip4 := this.ip4[:0]
+	for _, r := range this.ip4 {
+		if last := len(ip4) - 1; last >= 0 && r.from <= ip4[last].to {
+			if r.to > ip4[last].to {
+				ip4[last].to = r.to
+			}
+			continue
+		}
+		ip4 = append(ip4, r)
+	}
+	this.ip4 = ip4
+
+	sort.Sort(ipv6Ranges(this.ip6))
+	ip6 := this.ip6[:0]
+	for _, r := range this.ip6 {
+		if last := len(ip6) - 1; last >= 0 && !lessIPv6(ip6[last].to, r.from) {
+			if lessIPv6(ip6[last].to, r.to) {
+				ip6[last].to = r.to
+			}
+			continue
+		}
+		ip6 = append(ip6, r)
+	}
+	this.ip6 = ip6
+}
+
+func (this *GeoIPMatcher) Match(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		v := binary.BigEndian.Uint32(ip4)
+		idx := sort.Search(len(this.ip4), func(i int) bool { return this.ip4[i].to >= v })
+		return idx < len(this.ip4) && this.ip4[idx].from <= v
+	}
+	if len(ip) != net.IPv6len {
+		return false
+	}
+	v := [2]uint64{binary.BigEndian.Uint64(ip), binary.BigEndian.Uint64(ip[8:])}
+	idx := sort.Search(len(this.ip6), func(i int) bool { return !lessIPv6(this.ip6[i].to, v) })
+	return idx < len(this.ip6) && !lessIPv6(v, this.ip6[idx].from)
+}
+
+func (this *GeoIPMatcher) Apply(ctx *router.Context) bool {
+	return applyIPMatcher(ctx, this.Match)
+}
+
+func (this *GeoIPMatcher) String() string {
+	return "geoip:" + this.country
+}
+
+func parseCIDRs(cidrs []string) []*CIDR {
+	list := make([]*CIDR, 0, len(cidrs))
+	for _, s := range cidrs {
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			panic(err)
+		}
+		ip := ipNet.IP
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		prefix, _ := ipNet.Mask.Size()
+		list = append(list, &CIDR{Ip: ip, Prefix: uint32(prefix)})
+	}
+	return list
+}
+
+// LoadGeoIP loads the CIDRs of a country code from the given file in asset location.
+func LoadGeoIP(file string, country string) ([]*CIDR, error) {
+	data, err := ioutil.ReadFile(platform.GetAssetLocation(file))
+	if err != nil {
+		log.Error("Router|GeoIP: Failed to read ", file, ": ", err)
+		return nil, err
+	}
+	list := new(GeoIPList)
+	if err := proto.Unmarshal(data, list); err != nil {
+		log.Error("Router|GeoIP: Failed to parse ", file, ": ", err)
+		return nil, err
+	}
+	for _, entry := range list.Entry {
+		if strings.EqualFold(entry.CountryCode, country) {
+			return entry.Cidr, nil
+		}
+	}
+	return nil, errors.New("Router|GeoIP: Country code not found in " + file + ": " + country)
+}
+
+// GetGeoIPMatcher returns the matcher of a country code in geoip.dat. "private" is for the reserved IP ranges, and
+// doesn't require geoip.dat. Matchers are shared by all rules of the same country.
+func GetGeoIPMatcher(country string) (*GeoIPMatcher, error) {
+	country = strings.ToLower(country)
+
+	geoIPAccess.Lock()
+	defer geoIPAccess.Unlock()
+
+	if matcher, found := geoIPCache[country]; found {
+		return matcher, nil
+	}
+	var cidrs []*CIDR
+	if country == "private" {
+		cidrs = parseCIDRs(privateCIDRs)
+	} else {
+		var err error
+		cidrs, err = LoadGeoIP(GeoIPFile, country)
+		if err != nil {
+			return nil, err
+		}
+	}
+	matcher, err := NewGeoIPMatcher(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	matcher.country = country
+	geoIPCache[country] = matcher
+	return matcher, nil
+}
+
+// newDNSIPMatcher creates a matcher for the expected IPs of name servers, which are in the same syntax as the IP list of
+// field rules.
+func newDNSIPMatcher(rule string) (dns.IPMatcher, error) {
+	if strings.HasPrefix(rule, "geoip:") {
+		matcher, err := GetGeoIPMatcher(rule[6:])
+		if err != nil {
+			return nil, err
+		}
+		return matcher, nil
+	}
+	return NewCIDRMatcher(rule)
+}
+
+func init() {
+	dns.RegisterIPMatcherFactory(newDNSIPMatcher)
+}
@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/router/rules/geoip.proto
+// DO NOT EDIT!
+
+/*
+Package rules is a generated protocol buffer package.
+
+It is generated from these files:
+
+	v2ray.com/core/app/router/rules/geoip.proto
+	v2ray.com/core/app/router/rules/geosite.proto
+
+It has these top-level messages:
+
+	CIDR
+	GeoIP
+	GeoIPList
+	Domain
+	GeoSite
+	GeoSiteList
+*/
+package rules
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// CIDR is an IP range. IP is 4 bytes for IPv4 and 16 bytes for IPv6.
+type CIDR struct {
+	Ip     []byte `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	Prefix uint32 `protobuf:"varint,2,opt,name=prefix" json:"prefix,omitempty"`
+}
+
+func (m *CIDR) Reset()                    { *m = CIDR{} }
+func (m *CIDR) String() string            { return proto.CompactTextString(m) }
+func (*CIDR) ProtoMessage()               {}
+func (*CIDR) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type GeoIP struct {
+	CountryCode string  `protobuf:"bytes,1,opt,name=country_code,json=countryCode" json:"country_code,omitempty"`
+	Cidr        []*CIDR `protobuf:"bytes,2,rep,name=cidr" json:"cidr,omitempty"`
+}
+
+func (m *GeoIP) Reset()                    { *m = GeoIP{} }
+func (m *GeoIP) String() string            { return proto.CompactTextString(m) }
+func (*GeoIP) ProtoMessage()               {}
+func (*GeoIP) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *GeoIP) GetCidr() []*CIDR {
+	if m != nil {
+		return m.Cidr
+	}
+	return nil
+}
+
+// GeoIPList is the content of geoip.dat.
+type GeoIPList struct {
+	Entry []*GeoIP `protobuf:"bytes,1,rep,name=entry" json:"entry,omitempty"`
+}
+
+func (m *GeoIPList) Reset()                    { *m = GeoIPList{} }
+func (m *GeoIPList) String() string            { return proto.CompactTextString(m) }
+func (*GeoIPList) ProtoMessage()               {}
+func (*GeoIPList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *GeoIPList) GetEntry() []*GeoIP {
+	if m != nil {
+		return m.Entry
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*CIDR)(nil), "v2ray.core.app.router.rules.CIDR")
+	proto.RegisterType((*GeoIP)(nil), "v2ray.core.app.router.rules.GeoIP")
+	proto.RegisterType((*GeoIPList)(nil), "v2ray.core.app.router.rules.GeoIPList")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/router/rules/geoip.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 233 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x90, 0x41, 0x4b, 0xc3, 0x40,
+	0x10, 0x85, 0x49, 0x6c, 0x0a, 0x9d, 0x56, 0x0f, 0x7b, 0x90, 0x80, 0x07, 0xd3, 0x9c, 0x02, 0xc2,
+	0x2e, 0x54, 0x05, 0xcf, 0xad, 0x22, 0x05, 0x0f, 0x65, 0x8f, 0x5e, 0x24, 0x6e, 0x46, 0x59, 0xb0,
+	0xce, 0x30, 0x4d, 0xc4, 0xfe, 0x7b, 0xc9, 0x34, 0x5e, 0x73, 0xdc, 0xe1, 0xfb, 0xde, 0x3e, 0x1e,
+	0xdc, 0xfc, 0xac, 0xa4, 0x3e, 0xda, 0x40, 0x7b, 0x17, 0x48, 0xd0, 0xd5, 0xcc, 0x4e, 0xa8, 0x6b,
+	0x51, 0x9c, 0x74, 0x5f, 0x78, 0x70, 0x9f, 0x48, 0x91, 0x2d, 0x0b, 0xb5, 0x64, 0xae, 0xfe, 0x61,
+	0x41, 0x5b, 0x33, 0xdb, 0x13, 0x68, 0x15, 0x2c, 0x2d, 0x4c, 0x36, 0xdb, 0x47, 0x6f, 0x2e, 0x20,
+	0x8d, 0x9c, 0x27, 0x45, 0x52, 0x2d, 0x7c, 0x1a, 0xd9, 0x5c, 0xc2, 0x94, 0x05, 0x3f, 0xe2, 0x6f,
+	0x9e, 0x16, 0x49, 0x75, 0xee, 0x87, 0x57, 0x59, 0x43, 0xf6, 0x8c, 0xb4, 0xdd, 0x99, 0x25, 0x2c,
+	0x02, 0x75, 0xdf, 0xad, 0x1c, 0xdf, 0x02, 0x35, 0xa8, 0xea, 0xcc, 0xcf, 0x87, 0xdb, 0x86, 0x1a,
+	0x34, 0xf7, 0x30, 0x09, 0xb1, 0x91, 0x3c, 0x2d, 0xce, 0xaa, 0xf9, 0x6a, 0x69, 0x47, 0x7a, 0xd8,
+	0xbe, 0x84, 0x57, 0xbc, 0x7c, 0x82, 0x99, 0x7e, 0xf1, 0x12, 0x0f, 0xad, 0x79, 0x80, 0x0c, 0xfb,
+	0xc0, 0x3c, 0xd1, 0x90, 0x72, 0x34, 0x44, 0x35, 0x7f, 0x12, 0xd6, 0x77, 0x70, 0x1d, 0x68, 0x3f,
+	0xc6, 0xaf, 0x41, 0x85, 0x5d, 0xbf, 0xd2, 0x6b, 0xa6, 0xa7, 0xf7, 0xa9, 0x6e, 0x76, 0xfb, 0x37,
+	0x00, 0x8a, 0x3f, 0xde, 0xce, 0x62, 0x01, 0x00, 0x00,
+}
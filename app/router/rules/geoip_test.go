@@ -0,0 +1,85 @@
+package rules_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"v2ray.com/core/app/router"
+	. "v2ray.com/core/app/router/rules"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/platform"
+	"v2ray.com/core/testing/assert"
+)
+
+func makeContext(dest v2net.Destination) *router.Context {
+	return &router.Context{
+		Destination: dest,
+	}
+}
+
+func TestGeoIPMatcher(t *testing.T) {
+	assert := assert.On(t)
+
+	matcher, err := NewGeoIPMatcher([]*CIDR{
+		{Ip: []byte{8, 8, 0, 0}, Prefix: 16},
+		{Ip: []byte{8, 8, 8, 0}, Prefix: 24},
+		{Ip: []byte{1, 0, 0, 0}, Prefix: 24},
+		{Ip: net.ParseIP("2001:4860::"), Prefix: 32},
+	})
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("8.8.8.8")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("8.8.255.255")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("1.0.0.1")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("8.9.0.0")))).IsFalse()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("1.0.1.0")))).IsFalse()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("2001:4860:4860::8888")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("2001:4861::1")))).IsFalse()
+
+	_, err = NewGeoIPMatcher([]*CIDR{{Ip: []byte{1, 2, 3}, Prefix: 8}})
+	assert.Error(err).Equals(ErrInvalidCIDR)
+}
+
+func TestGeoIPPrivate(t *testing.T) {
+	assert := assert.On(t)
+
+	matcher, err := GetGeoIPMatcher("private")
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("192.168.1.1")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("127.0.0.1")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("fd00::1")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("8.8.8.8")))).IsFalse()
+}
+
+func TestGeoIPFromFile(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-geoip")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	data, err := proto.Marshal(&GeoIPList{
+		Entry: []*GeoIP{
+			{
+				CountryCode: "TEST",
+				Cidr:        []*CIDR{{Ip: []byte{121, 14, 0, 0}, Prefix: 16}},
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(ioutil.WriteFile(filepath.Join(dir, GeoIPFile), data, 0644)).IsNil()
+
+	os.Setenv(platform.AssetLocationEnv, dir)
+	defer os.Unsetenv(platform.AssetLocationEnv)
+
+	matcher, err := GetGeoIPMatcher("test")
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("121.14.1.189")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("8.8.8.8")))).IsFalse()
+
+	_, err = GetGeoIPMatcher("unknown")
+	assert.Error(err).IsNotNil()
+}
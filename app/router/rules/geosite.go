@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/platform"
+)
+
+const (
+	GeoSiteFile = "geosite.dat"
+)
+
+var (
+	geoSiteAccess sync.Mutex
+	geoSiteCache  = make(map[string]*GeoSiteMatcher)
+)
+
+// GeoSiteMatcher matches domain destinations against a compiled domain list. Full and subdomain entries are looked up
+// in a DomainTrie, so that large lists don't slow down matching.
+type GeoSiteMatcher struct {
+	domains *DomainTrie
+	plain   []string
+	regexps []*regexp.Regexp
+	// name is the list name in geosite.dat, for tracing. Empty if the matcher is not from geosite.dat.
+	name string
+}
+
+func NewGeoSiteMatcher(domains []*Domain) (*GeoSiteMatcher, error) {
+	matcher := &GeoSiteMatcher{
+		domains: NewDomainTrie(),
+	}
+	for _, domain := range domains {
+		value := strings.ToLower(domain.Value)
+		switch domain.Type {
+		case Domain_Full:
+			matcher.domains.Add(value, false)
+		case Domain_Domain:
+			matcher.domains.Add(value, true)
+		case Domain_Plain:
+			matcher.plain = append(matcher.plain, value)
+		case Domain_Regex:
+			r, err := regexp.Compile(domain.Value)
+			if err != nil {
+				return nil, err
+			}
+			matcher.regexps = append(matcher.regexps, r)
+		default:
+			return nil, errors.New("Router|GeoSite: Unknown domain type: " + domain.Type.String())
+		}
+	}
+	return matcher, nil
+}
+
+func (this *GeoSiteMatcher) Match(domain string) bool {
+	if this.domains.Match(domain) {
+		return true
+	}
+	for _, pattern := range this.plain {
+		if strings.Contains(domain, pattern) {
+			return true
+		}
+	}
+	for _, pattern := range this.regexps {
+		if pattern.MatchString(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *GeoSiteMatcher) Apply(ctx *router.Context) bool {
+	return applyDomainMatcher(this, ctx)
+}
+
+func (this *GeoSiteMatcher) String() string {
+	return "geosite:" + this.name
+}
+
+func hasAttributes(domain *Domain, attributes []string) bool {
+	for _, key := range attributes {
+		found := false
+		for _, attr := range domain.Attribute {
+			if strings.EqualFold(attr.Key, key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadGeoSite loads the domains of a list from the given file in asset location. Only the domains with all the given
+// attributes are returned.
+func LoadGeoSite(file string, list string, attributes []string) ([]*Domain, error) {
+	data, err := ioutil.ReadFile(platform.GetAssetLocation(file))
+	if err != nil {
+		log.Error("Router|GeoSite: Failed to read ", file, ": ", err)
+		return nil, err
+	}
+	siteList := new(GeoSiteList)
+	if err := proto.Unmarshal(data, siteList); err != nil {
+		log.Error("Router|GeoSite: Failed to parse ", file, ": ", err)
+		return nil, err
+	}
+	for _, entry := range siteList.Entry {
+		if !strings.EqualFold(entry.CountryCode, list) {
+			continue
+		}
+		if len(attributes) == 0 {
+			return entry.Domain, nil
+		}
+		domains := make([]*Domain, 0, len(entry.Domain))
+		for _, domain := range entry.Domain {
+			if hasAttributes(domain, attributes) {
+				domains = append(domains, domain)
+			}
+		}
+		return domains, nil
+	}
+	return nil, errors.New("Router|GeoSite: List not found in " + file + ": " + list)
+}
+
+// GetGeoSiteMatcher returns the matcher of a domain list in geosite.dat. The name may be followed by attribute filters,
+// e.g. "google@cn" for the domains of Google with attribute "cn". Matchers are shared by all rules of the same name.
+func GetGeoSiteMatcher(name string) (*GeoSiteMatcher, error) {
+	name = strings.ToLower(name)
+
+	geoSiteAccess.Lock()
+	defer geoSiteAccess.Unlock()
+
+	if matcher, found := geoSiteCache[name]; found {
+		return matcher, nil
+	}
+	parts := strings.Split(name, "@")
+	for _, part := range parts {
+		if len(part) == 0 {
+			return nil, errors.New("Router|GeoSite: Invalid list name: " + name)
+		}
+	}
+	domains, err := LoadGeoSite(GeoSiteFile, parts[0], parts[1:])
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := NewGeoSiteMatcher(domains)
+	if err != nil {
+		return nil, err
+	}
+	matcher.name = name
+	geoSiteCache[name] = matcher
+	return matcher, nil
+}
+
+// newDNSDomainMatcher creates a matcher for the domain rules of the DNS app, which are in the same syntax as the domain
+// list of field rules.
+func newDNSDomainMatcher(rule string) (dns.DomainMatcher, error) {
+	if strings.HasPrefix(rule, "geosite:") {
+		matcher, err := GetGeoSiteMatcher(rule[8:])
+		if err != nil {
+			return nil, err
+		}
+		return matcher, nil
+	}
+	return NewDomainMatcher(rule)
+}
+
+func init() {
+	dns.RegisterDomainMatcherFactory(newDNSDomainMatcher)
+}
@@ -0,0 +1,147 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/router/rules/geosite.proto
+// DO NOT EDIT!
+
+package rules
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type Domain_Type int32
+
+const (
+	// Plain matches the domains containing the value.
+	Domain_Plain Domain_Type = 0
+	// Regex matches the domains by the value as a regular expression.
+	Domain_Regex Domain_Type = 1
+	// Domain matches the value and its subdomains.
+	Domain_Domain Domain_Type = 2
+	// Full matches the value exactly.
+	Domain_Full Domain_Type = 3
+)
+
+var Domain_Type_name = map[int32]string{
+	0: "Plain",
+	1: "Regex",
+	2: "Domain",
+	3: "Full",
+}
+var Domain_Type_value = map[string]int32{
+	"Plain":  0,
+	"Regex":  1,
+	"Domain": 2,
+	"Full":   3,
+}
+
+func (x Domain_Type) String() string {
+	return proto.EnumName(Domain_Type_name, int32(x))
+}
+func (Domain_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor1, []int{0, 0} }
+
+// Domain is an entry of a domain list.
+type Domain struct {
+	Type      Domain_Type         `protobuf:"varint,1,opt,name=type,enum=v2ray.core.app.router.rules.Domain_Type" json:"type,omitempty"`
+	Value     string              `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+	Attribute []*Domain_Attribute `protobuf:"bytes,3,rep,name=attribute" json:"attribute,omitempty"`
+}
+
+func (m *Domain) Reset()                    { *m = Domain{} }
+func (m *Domain) String() string            { return proto.CompactTextString(m) }
+func (*Domain) ProtoMessage()               {}
+func (*Domain) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
+
+func (m *Domain) GetAttribute() []*Domain_Attribute {
+	if m != nil {
+		return m.Attribute
+	}
+	return nil
+}
+
+// Attribute tags a domain, such as "ads" or "cn". Domains are filtered by the attribute keys.
+type Domain_Attribute struct {
+	Key       string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	BoolValue bool   `protobuf:"varint,2,opt,name=bool_value,json=boolValue" json:"bool_value,omitempty"`
+	IntValue  int64  `protobuf:"varint,3,opt,name=int_value,json=intValue" json:"int_value,omitempty"`
+}
+
+func (m *Domain_Attribute) Reset()                    { *m = Domain_Attribute{} }
+func (m *Domain_Attribute) String() string            { return proto.CompactTextString(m) }
+func (*Domain_Attribute) ProtoMessage()               {}
+func (*Domain_Attribute) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0, 0} }
+
+type GeoSite struct {
+	CountryCode string    `protobuf:"bytes,1,opt,name=country_code,json=countryCode" json:"country_code,omitempty"`
+	Domain      []*Domain `protobuf:"bytes,2,rep,name=domain" json:"domain,omitempty"`
+}
+
+func (m *GeoSite) Reset()                    { *m = GeoSite{} }
+func (m *GeoSite) String() string            { return proto.CompactTextString(m) }
+func (*GeoSite) ProtoMessage()               {}
+func (*GeoSite) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+
+func (m *GeoSite) GetDomain() []*Domain {
+	if m != nil {
+		return m.Domain
+	}
+	return nil
+}
+
+// GeoSiteList is the content of geosite.dat.
+type GeoSiteList struct {
+	Entry []*GeoSite `protobuf:"bytes,1,rep,name=entry" json:"entry,omitempty"`
+}
+
+func (m *GeoSiteList) Reset()                    { *m = GeoSiteList{} }
+func (m *GeoSiteList) String() string            { return proto.CompactTextString(m) }
+func (*GeoSiteList) ProtoMessage()               {}
+func (*GeoSiteList) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
+
+func (m *GeoSiteList) GetEntry() []*GeoSite {
+	if m != nil {
+		return m.Entry
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Domain)(nil), "v2ray.core.app.router.rules.Domain")
+	proto.RegisterType((*Domain_Attribute)(nil), "v2ray.core.app.router.rules.Domain.Attribute")
+	proto.RegisterType((*GeoSite)(nil), "v2ray.core.app.router.rules.GeoSite")
+	proto.RegisterType((*GeoSiteList)(nil), "v2ray.core.app.router.rules.GeoSiteList")
+	proto.RegisterEnum("v2ray.core.app.router.rules.Domain_Type", Domain_Type_name, Domain_Type_value)
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/router/rules/geosite.proto", fileDescriptor1) }
+
+var fileDescriptor1 = []byte{
+	// 357 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0x41, 0x4b, 0xfb, 0x40,
+	0x10, 0xc5, 0xff, 0x49, 0x9a, 0xfe, 0x9b, 0x69, 0x91, 0xb0, 0x78, 0x08, 0x16, 0x31, 0x46, 0x0f,
+	0xb9, 0x74, 0x03, 0x11, 0x3c, 0xa8, 0x17, 0xab, 0x28, 0xa2, 0x87, 0xb2, 0x8a, 0xa0, 0x97, 0x92,
+	0xa6, 0x43, 0x59, 0x4c, 0xb3, 0x61, 0xbb, 0x29, 0xe6, 0x9b, 0xf9, 0xf1, 0x24, 0x9b, 0xd4, 0xde,
+	0x4a, 0x6f, 0x93, 0x37, 0xf3, 0x7b, 0x79, 0x3b, 0x0c, 0x8c, 0xd6, 0xb1, 0x4c, 0x2a, 0x9a, 0x8a,
+	0x65, 0x94, 0x0a, 0x89, 0x51, 0x52, 0x14, 0x91, 0x14, 0xa5, 0x42, 0x19, 0xc9, 0x32, 0xc3, 0x55,
+	0xb4, 0x40, 0xb1, 0xe2, 0x0a, 0x69, 0x21, 0x85, 0x12, 0x64, 0xb8, 0x19, 0x97, 0x48, 0x93, 0xa2,
+	0xa0, 0xcd, 0x28, 0xd5, 0xa3, 0xc1, 0x8f, 0x09, 0xdd, 0x7b, 0xb1, 0x4c, 0x78, 0x4e, 0x6e, 0xa0,
+	0xa3, 0xaa, 0x02, 0x3d, 0xc3, 0x37, 0xc2, 0x83, 0x38, 0xa4, 0x3b, 0x30, 0xda, 0x20, 0xf4, 0xad,
+	0x2a, 0x90, 0x69, 0x8a, 0x1c, 0x82, 0xbd, 0x4e, 0xb2, 0x12, 0x3d, 0xd3, 0x37, 0x42, 0x87, 0x35,
+	0x1f, 0xe4, 0x19, 0x9c, 0x44, 0x29, 0xc9, 0x67, 0xa5, 0x42, 0xcf, 0xf2, 0xad, 0xb0, 0x1f, 0x8f,
+	0xf6, 0x31, 0xbe, 0xdd, 0x40, 0x6c, 0xcb, 0x1f, 0x7d, 0x80, 0xf3, 0xa7, 0x13, 0x17, 0xac, 0x2f,
+	0xac, 0x74, 0x58, 0x87, 0xd5, 0x25, 0x39, 0x06, 0x98, 0x09, 0x91, 0x4d, 0xb7, 0x31, 0x7a, 0xcc,
+	0xa9, 0x95, 0x77, 0x1d, 0x65, 0x08, 0x0e, 0xcf, 0x55, 0xdb, 0xb5, 0x7c, 0x23, 0xb4, 0x58, 0x8f,
+	0xe7, 0x4a, 0x37, 0x83, 0x18, 0x3a, 0xf5, 0x5b, 0x88, 0x03, 0xf6, 0x24, 0x4b, 0x78, 0xee, 0xfe,
+	0xab, 0x4b, 0x86, 0x0b, 0xfc, 0x76, 0x0d, 0x02, 0x9b, 0x1d, 0xb9, 0x26, 0xe9, 0x41, 0xe7, 0xa1,
+	0xcc, 0x32, 0xd7, 0x0a, 0x38, 0xfc, 0x7f, 0x44, 0xf1, 0xca, 0x15, 0x92, 0x53, 0x18, 0xa4, 0xa2,
+	0xcc, 0x95, 0xac, 0xa6, 0xa9, 0x98, 0x63, 0x9b, 0xaa, 0xdf, 0x6a, 0x77, 0x62, 0x8e, 0xe4, 0x1a,
+	0xba, 0x73, 0xed, 0xe1, 0x99, 0x7a, 0x0d, 0x67, 0x7b, 0xac, 0x81, 0xb5, 0x48, 0xf0, 0x04, 0xfd,
+	0xf6, 0x57, 0x2f, 0x7c, 0xa5, 0xc8, 0x15, 0xd8, 0x58, 0x1b, 0x7b, 0x86, 0xb6, 0x3a, 0xdf, 0x69,
+	0xd5, 0x82, 0xac, 0x41, 0xc6, 0x97, 0x70, 0x92, 0x8a, 0xe5, 0x2e, 0x62, 0x3c, 0x68, 0x91, 0x49,
+	0x7d, 0x3e, 0x9f, 0xb6, 0x16, 0x67, 0x5d, 0x7d, 0x4c, 0x17, 0xbf, 0x03, 0x00, 0x73, 0xb2, 0xd1,
+	0x34, 0x7d, 0x02, 0x00, 0x00,
+}
@@ -0,0 +1,77 @@
+package rules_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	. "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/common/platform"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestGeoSiteMatcher(t *testing.T) {
+	assert := assert.On(t)
+
+	matcher, err := NewGeoSiteMatcher([]*Domain{
+		{Type: Domain_Full, Value: "www.v2ray.com"},
+		{Type: Domain_Domain, Value: "Google.com"},
+		{Type: Domain_Plain, Value: "ads"},
+		{Type: Domain_Regex, Value: `^tracker\d+\.`},
+	})
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("www.v2ray.com")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("v2ray.com")))).IsFalse()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("google.com")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("mail.GOOGLE.com")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("notgoogle.com")))).IsFalse()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("myads.example.com")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("tracker12.example.com")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("tracker.example.com")))).IsFalse()
+	assert.Bool(matcher.Apply(makeContext(makeDestination("8.8.8.8")))).IsFalse()
+
+	_, err = NewGeoSiteMatcher([]*Domain{{Type: Domain_Regex, Value: "("}})
+	assert.Error(err).IsNotNil()
+}
+
+func TestGeoSiteFromFile(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-geosite")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	data, err := proto.Marshal(&GeoSiteList{
+		Entry: []*GeoSite{
+			{
+				CountryCode: "TEST-SITES",
+				Domain: []*Domain{
+					{Type: Domain_Domain, Value: "example.com"},
+					{Type: Domain_Domain, Value: "example.cn", Attribute: []*Domain_Attribute{{Key: "cn", BoolValue: true}}},
+				},
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(ioutil.WriteFile(filepath.Join(dir, GeoSiteFile), data, 0644)).IsNil()
+
+	os.Setenv(platform.AssetLocationEnv, dir)
+	defer os.Unsetenv(platform.AssetLocationEnv)
+
+	matcher, err := GetGeoSiteMatcher("test-sites")
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("www.example.com")))).IsTrue()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("www.example.cn")))).IsTrue()
+
+	matcher, err = GetGeoSiteMatcher("test-sites@cn")
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("www.example.com")))).IsFalse()
+	assert.Bool(matcher.Apply(makeContext(makeDomainDestination("www.example.cn")))).IsTrue()
+
+	_, err = GetGeoSiteMatcher("unknown")
+	assert.Error(err).IsNotNil()
+	_, err = GetGeoSiteMatcher("test-sites@")
+	assert.Error(err).IsNotNil()
+}
@@ -2,10 +2,18 @@ package rules
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"sync"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/events"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/app/router"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/event"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 )
@@ -13,22 +21,71 @@ import (
 var (
 	ErrInvalidRule      = errors.New("Invalid Rule")
 	ErrNoRuleApplicable = errors.New("No rule applicable")
+	ErrUnknownBalancer  = errors.New("Router: Unknown balancer.")
+	ErrInvalidFallback  = errors.New("Router: Invalid balancer fallback.")
 )
 
+// ruleSet is the part of a Router that is replaced on reload.
+type ruleSet struct {
+	config      *RouterRuleConfig
+	cache       *RoutingTable
+	observatory *router.Observatory
+	balancers   map[string]*router.Balancer
+	// fallbacks are the FallbackTags of balancers, by balancer tag.
+	fallbacks map[string][]string
+	// timeDependent is true if any rule has a schedule, so that routing results are not cached.
+	timeDependent bool
+	// sourceDependent and sourcePortDependent are true if any rule matches the source address or port, so that they
+	// are part of the cache key.
+	sourceDependent     bool
+	sourcePortDependent bool
+	logger              *log.Logger
+}
+
+func (this *ruleSet) start() {
+	if this.observatory != nil {
+		this.observatory.Start()
+	}
+}
+
+func (this *ruleSet) close() {
+	if this.observatory != nil {
+		this.observatory.Close()
+	}
+}
+
 type Router struct {
-	config    *RouterRuleConfig
-	cache     *RoutingTable
+	sync.RWMutex
+	rules     *ruleSet
 	dnsServer dns.Server
+	ohm       proxyman.OutboundHandlerManager
+	events    *event.Bus
+	load      *router.OutboundLoad
+	// logger is the logger of the instance. Nil for the default logger.
+	logger *log.Logger
 }
 
 func NewRouter(config *RouterRuleConfig, space app.Space) *Router {
 	r := &Router{
-		config: config,
-		cache:  NewRoutingTable(),
+		load:   router.NewOutboundLoad(),
+		logger: applog.FromSpace(space),
 	}
+	space.InitializeApplication(func() error {
+		if space.HasApp(proxyman.APP_ID_OUTBOUND_MANAGER) {
+			r.ohm = space.GetApp(proxyman.APP_ID_OUTBOUND_MANAGER).(proxyman.OutboundHandlerManager)
+		}
+		r.events = events.FromSpace(space)
+		rules, err := r.buildRuleSet(config)
+		if err != nil {
+			return err
+		}
+		rules.start()
+		r.rules = rules
+		return nil
+	})
 	space.InitializeApplication(func() error {
 		if !space.HasApp(dns.APP_ID) {
-			log.Error("DNS: Router is not found in the space.")
+			r.logger.Error("DNS: Router is not found in the space.")
 			return app.ErrMissingApplication
 		}
 		r.dnsServer = space.GetApp(dns.APP_ID).(dns.Server)
@@ -37,13 +94,217 @@ func NewRouter(config *RouterRuleConfig, space app.Space) *Router {
 	return r
 }
 
+// observatoryConfig returns the observatory settings, or nil if the outbounds don't need probing.
+func observatoryConfig(config *RouterRuleConfig) *ObservatoryConfig {
+	if config.Observatory != nil {
+		return config.Observatory
+	}
+	for _, balancerConfig := range config.Balancers {
+		if balancerConfig.Strategy == "leastping" || len(balancerConfig.FallbackTags) > 0 {
+			return new(ObservatoryConfig)
+		}
+	}
+	return nil
+}
+
+func (this *Router) createObservatory(config *RouterRuleConfig) (*router.Observatory, error) {
+	observatoryConfig := observatoryConfig(config)
+	if observatoryConfig == nil || len(config.Balancers) == 0 {
+		return nil, nil
+	}
+	if this.ohm == nil {
+		this.logger.Error("Router: OutboundHandlerManager is not found in the space.")
+		return nil, app.ErrMissingApplication
+	}
+
+	var outboundTags []string
+	probed := make(map[string]bool)
+	balancerTags := make(map[string]bool)
+	for _, balancerConfig := range config.Balancers {
+		balancerTags[balancerConfig.Tag] = true
+	}
+	for _, balancerConfig := range config.Balancers {
+		tags := make([]string, 0, len(balancerConfig.OutboundTags)+len(balancerConfig.FallbackTags))
+		tags = append(tags, balancerConfig.OutboundTags...)
+		tags = append(tags, balancerConfig.FallbackTags...)
+		for _, tag := range tags {
+			if !probed[tag] && !balancerTags[tag] {
+				probed[tag] = true
+				outboundTags = append(outboundTags, tag)
+			}
+		}
+	}
+	return router.NewObservatory(this.ohm, outboundTags, observatoryConfig.ProbeURL, observatoryConfig.ProbeInterval, this.events), nil
+}
+
+// buildRuleSet validates the config and creates its balancers. The observatory of the returned rule set is not
+// started.
+func (this *Router) buildRuleSet(config *RouterRuleConfig) (*ruleSet, error) {
+	timeDependent := false
+	sourceDependent := false
+	sourcePortDependent := false
+	for _, rule := range config.Rules {
+		if rule == nil || rule.Condition == nil {
+			return nil, ErrInvalidRule
+		}
+		if isTimeDependent(rule.Condition) {
+			timeDependent = true
+		}
+		address, port := sourceDependence(rule.Condition)
+		sourceDependent = sourceDependent || address
+		sourcePortDependent = sourcePortDependent || port
+	}
+	observatory, err := this.createObservatory(config)
+	if err != nil {
+		return nil, err
+	}
+	var health router.HealthReporter
+	if observatory != nil {
+		health = observatory
+	}
+	balancers := make(map[string]*router.Balancer)
+	fallbacks := make(map[string][]string)
+	for _, balancerConfig := range config.Balancers {
+		for _, tag := range balancerConfig.FallbackTags {
+			if len(tag) == 0 || tag == balancerConfig.Tag {
+				this.logger.Error("Router: Invalid fallback in balancer ", balancerConfig.Tag, ": ", tag)
+				return nil, ErrInvalidFallback
+			}
+		}
+		if len(balancerConfig.FallbackTags) > 0 {
+			fallbacks[balancerConfig.Tag] = balancerConfig.FallbackTags
+		}
+		strategyName := balancerConfig.Strategy
+		if len(strategyName) == 0 {
+			strategyName = "random"
+		}
+		strategy, err := router.CreateBalancingStrategy(strategyName, this.load, health)
+		if err != nil {
+			return nil, err
+		}
+		if balancerConfig.Weights == nil {
+			balancers[balancerConfig.Tag] = router.NewBalancer(balancerConfig.Tag, balancerConfig.OutboundTags, strategy)
+			continue
+		}
+		balancer, err := router.NewWeightedBalancer(balancerConfig.Tag, balancerConfig.OutboundTags, balancerConfig.Weights, strategy)
+		if err != nil {
+			this.logger.Error("Router: Invalid weights in balancer ", balancerConfig.Tag, ": ", err)
+			return nil, err
+		}
+		balancers[balancerConfig.Tag] = balancer
+	}
+	for _, rule := range config.Rules {
+		if len(rule.BalancerTag) == 0 {
+			continue
+		}
+		if _, found := balancers[rule.BalancerTag]; !found {
+			this.logger.Error("Router: Balancer not found: ", rule.BalancerTag)
+			return nil, ErrUnknownBalancer
+		}
+	}
+	return &ruleSet{
+		config:              config,
+		cache:               NewRoutingTable(),
+		observatory:         observatory,
+		balancers:           balancers,
+		fallbacks:           fallbacks,
+		timeDependent:       timeDependent,
+		sourceDependent:     sourceDependent,
+		sourcePortDependent: sourcePortDependent,
+		logger:              this.logger,
+	}, nil
+}
+
+// Reload replaces the rules, balancers and domain strategy of the router atomically. Connections being routed finish
+// with the old rules. The router is unchanged if the new config is invalid.
+func (this *Router) Reload(rawConfig interface{}) error {
+	apply, err := this.PrepareReload(rawConfig)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// PrepareReload builds the rules and balancers of the config, and returns the function that replaces the current ones
+// with them. Nothing is changed or started if the function is not called.
+func (this *Router) PrepareReload(rawConfig interface{}) (func(), error) {
+	config, ok := rawConfig.(*RouterRuleConfig)
+	if !ok {
+		return nil, common.ErrBadConfiguration
+	}
+	rules, err := this.buildRuleSet(config)
+	if err != nil {
+		this.logger.Error("Router: Failed to reload rules: ", err)
+		return nil, err
+	}
+
+	return func() {
+		rules.start()
+		this.Lock()
+		old := this.rules
+		this.rules = rules
+		this.Unlock()
+
+		if old != nil {
+			old.close()
+		}
+		this.logger.Info("Router: Reloaded ", len(config.Rules), " rules.")
+	}, nil
+}
+
+func (this *Router) currentRules() *ruleSet {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.rules
+}
+
 func (this *Router) Release() {
+	if rules := this.currentRules(); rules != nil {
+		rules.close()
+	}
+}
+
+// Observatory returns the observatory that probes the outbounds in balancers, or nil if probing is not enabled.
+func (this *Router) Observatory() *router.Observatory {
+	if rules := this.currentRules(); rules != nil {
+		return rules.observatory
+	}
+	return nil
+}
+
+// ListStatus returns the status of the outbounds probed by the observatory, or nil if probing is not enabled.
+func (this *Router) ListStatus() map[string]*router.OutboundStatus {
+	if observatory := this.Observatory(); observatory != nil {
+		return observatory.ListStatus()
+	}
+	return nil
+}
+
+func (this *Router) OnConnectionOpen(outboundTag string) {
+	this.load.OnConnectionOpen(outboundTag)
+}
+
+func (this *Router) OnConnectionClose(outboundTag string) {
+	this.load.OnConnectionClose(outboundTag)
+}
 
+// resolve returns the IPs of a domain, and whether they are validated with DNSSEC.
+func (this *Router) resolve(domain string) ([]net.IP, bool) {
+	if server, ok := this.dnsServer.(dns.ValidatingServer); ok {
+		return server.GetValidated(domain)
+	}
+	return this.dnsServer.Get(domain), false
 }
 
 // Private: Visible for testing.
 func (this *Router) ResolveIP(dest v2net.Destination) []v2net.Destination {
-	ips := this.dnsServer.Get(dest.Address.Domain())
+	ips, _ := this.resolve(dest.Address.Domain())
+	return resolvedDestinations(dest, ips)
+}
+
+func resolvedDestinations(dest v2net.Destination, ips []net.IP) []v2net.Destination {
 	if len(ips) == 0 {
 		return nil
 	}
@@ -58,39 +319,152 @@ func (this *Router) ResolveIP(dest v2net.Destination) []v2net.Destination {
 	return dests
 }
 
-func (this *Router) takeDetourWithoutCache(dest v2net.Destination) (string, error) {
-	for _, rule := range this.config.Rules {
-		if rule.Apply(dest) {
-			return rule.Tag, nil
+func describeRule(idx int, rule *Rule) string {
+	if len(rule.BalancerTag) > 0 {
+		return fmt.Sprintf("rule #%d (balancer %s)", idx, rule.BalancerTag)
+	}
+	return fmt.Sprintf("rule #%d (outbound %s)", idx, rule.Tag)
+}
+
+func matchRules(rules []*Rule, ctx *router.Context, tracer func(string)) (*Rule, int) {
+	for idx, rule := range rules {
+		if tracer == nil {
+			if rule.Condition.Apply(ctx) {
+				return rule, idx
+			}
+			continue
 		}
+		matched, reason := explainCondition(rule.Condition, ctx)
+		tracer(describeRule(idx, rule) + " " + reason)
+		if matched {
+			return rule, idx
+		}
+	}
+	return nil, -1
+}
+
+// matchRule returns the first rule that matches the connection, and its index. If tracer is not nil, it is called
+// with the description of every rule evaluation.
+func (this *Router) matchRule(config *RouterRuleConfig, ctx *router.Context, tracer func(string)) (*Rule, int, error) {
+	dest := ctx.Destination
+	if config.DomainStrategy == AlwaysUseIP && dest.Address.Family().IsDomain() && ctx.ResolvedIPs == nil {
+		resolvedCtx := *ctx
+		resolvedCtx.ResolvedIPs, resolvedCtx.ResolvedValidated = this.resolve(dest.Address.Domain())
+		if tracer != nil {
+			tracer(fmt.Sprintf("resolved %s to %v", dest.Address.Domain(), resolvedCtx.ResolvedIPs))
+		}
+		ctx = &resolvedCtx
+	}
+	if rule, idx := matchRules(config.Rules, ctx, tracer); rule != nil {
+		return rule, idx, nil
 	}
-	if this.config.DomainStrategy == UseIPIfNonMatch && dest.Address.Family().IsDomain() {
-		log.Info("Router: Looking up IP for ", dest)
-		ipDests := this.ResolveIP(dest)
+	if config.DomainStrategy == UseIPIfNonMatch && dest.Address.Family().IsDomain() {
+		this.logger.Info("Router: Looking up IP for ", dest)
+		ips, validated := this.resolve(dest.Address.Domain())
+		ipDests := resolvedDestinations(dest, ips)
 		if ipDests != nil {
 			for _, ipDest := range ipDests {
-				log.Info("Router: Trying IP ", ipDest)
-				for _, rule := range this.config.Rules {
-					if rule.Apply(ipDest) {
-						return rule.Tag, nil
-					}
+				this.logger.Info("Router: Trying IP ", ipDest)
+				if tracer != nil {
+					tracer("trying resolved IP " + ipDest.String())
+				}
+				ipCtx := *ctx
+				ipCtx.Destination = ipDest
+				ipCtx.ResolvedIPs = ips
+				ipCtx.ResolvedValidated = validated
+				if rule, idx := matchRules(config.Rules, &ipCtx, tracer); rule != nil {
+					return rule, idx, nil
 				}
 			}
 		}
 	}
+	if tracer != nil {
+		tracer("no rule matched")
+	}
+	return nil, -1, ErrNoRuleApplicable
+}
 
-	return "", ErrNoRuleApplicable
+func (this *Router) takeDetourWithoutCache(config *RouterRuleConfig, ctx *router.Context) (*Rule, error) {
+	rule, _, err := this.matchRule(config, ctx, nil)
+	return rule, err
 }
 
-func (this *Router) TakeDetour(dest v2net.Destination) (string, error) {
-	destStr := dest.String()
-	found, tag, err := this.cache.Get(destStr)
+// pickOutbound returns the outbound of the rule. Balancers pick for each connection, so only the matched rule is
+// cached.
+func (this *ruleSet) pickOutbound(rule *Rule) (string, error) {
+	if len(rule.BalancerTag) == 0 {
+		return rule.Tag, nil
+	}
+	balancer, found := this.balancers[rule.BalancerTag]
 	if !found {
-		tag, err := this.takeDetourWithoutCache(dest)
-		this.cache.Set(destStr, tag, err)
-		return tag, err
+		return "", ErrUnknownBalancer
 	}
-	return tag, err
+	fallbacks := this.fallbacks[rule.BalancerTag]
+	if len(fallbacks) == 0 || this.hasAlive(balancer.OutboundTags()) {
+		return balancer.PickOutbound()
+	}
+	for idx, tag := range fallbacks {
+		last := idx == len(fallbacks)-1
+		if fallback, found := this.balancers[tag]; found {
+			if last || this.hasAlive(fallback.OutboundTags()) {
+				this.logger.Info("Router: Balancer ", rule.BalancerTag, " is down. Falling back to balancer ", tag)
+				return fallback.PickOutbound()
+			}
+			continue
+		}
+		if last || this.hasAlive([]string{tag}) {
+			this.logger.Info("Router: Balancer ", rule.BalancerTag, " is down. Falling back to outbound ", tag)
+			return tag, nil
+		}
+	}
+	return balancer.PickOutbound()
+}
+
+// hasAlive returns true if any of the outbounds didn't fail its latest probe. Outbounds not probed yet are considered
+// alive.
+func (this *ruleSet) hasAlive(outboundTags []string) bool {
+	if this.observatory == nil {
+		return true
+	}
+	for _, tag := range outboundTags {
+		if status := this.observatory.GetStatus(tag); status == nil || status.Alive {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *Router) TakeDetour(ctx *router.Context) (string, error) {
+	rules := this.currentRules()
+	if rules.config.Trace {
+		return this.traceDetour(rules, ctx)
+	}
+	key := ctx.InboundTag + "|" + ctx.Destination.String() + "|" + ctx.Protocol + "|" + ctx.SniffedDomain
+	if ctx.User != nil {
+		key += "|" + ctx.User.Email
+	}
+	if rules.sourceDependent && ctx.Source.Address != nil {
+		key += "|" + ctx.Source.Address.String()
+	}
+	if rules.sourcePortDependent {
+		key += "|" + ctx.Source.Port.String()
+	}
+	if rules.timeDependent {
+		rule, err := this.takeDetourWithoutCache(rules.config, ctx)
+		if err != nil {
+			return "", err
+		}
+		return rules.pickOutbound(rule)
+	}
+	found, rule, err := rules.cache.Get(key)
+	if !found {
+		rule, err = this.takeDetourWithoutCache(rules.config, ctx)
+		rules.cache.Set(key, rule, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return rules.pickOutbound(rule)
 }
 
 type RouterFactory struct {
@@ -103,3 +477,45 @@ func (this *RouterFactory) Create(rawConfig interface{}, space app.Space) (route
 func init() {
 	router.RegisterRouter("rules", &RouterFactory{})
 }
+
+func (this *Router) traceDetour(rules *ruleSet, ctx *router.Context) (string, error) {
+	source := "unknown"
+	if ctx.Source.Address != nil {
+		source = ctx.Source.String()
+	}
+	prefix := "Router|Trace: [" + ctx.InboundTag + "] " + source + " -> " + ctx.Destination.String() + ": "
+	rule, _, err := this.matchRule(rules.config, ctx, func(message string) {
+		this.logger.Info(prefix, message)
+	})
+	if err != nil {
+		return "", err
+	}
+	tag, err := rules.pickOutbound(rule)
+	if err == nil {
+		this.logger.Info(prefix, "routed to ", tag)
+	}
+	return tag, err
+}
+
+// DryRun routes a synthetic connection with the current rules, and returns the decision with the trace of all rule
+// evaluations. Results are not cached, but balancers pick as for a real connection.
+func (this *Router) DryRun(ctx *router.Context) (*router.Decision, error) {
+	rules := this.currentRules()
+	decision := &router.Decision{
+		RuleIndex: -1,
+	}
+	rule, idx, err := this.matchRule(rules.config, ctx, func(message string) {
+		decision.Trace = append(decision.Trace, message)
+	})
+	if err != nil {
+		return decision, err
+	}
+	decision.RuleIndex = idx
+	decision.BalancerTag = rule.BalancerTag
+	tag, err := rules.pickOutbound(rule)
+	if err != nil {
+		return decision, err
+	}
+	decision.OutboundTag = tag
+	return decision, nil
+}
@@ -1,6 +1,7 @@
 package rules_test
 
 import (
+	"context"
 	"testing"
 
 	"v2ray.com/core/app"
@@ -10,8 +11,10 @@ import (
 	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/app/router"
 	. "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/common/alloc"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
 )
 
 func TestSimpleRouter(t *testing.T) {
@@ -34,7 +37,425 @@ func TestSimpleRouter(t *testing.T) {
 	space.BindApp(router.APP_ID, r)
 	assert.Error(space.Initialize()).IsNil()
 
-	tag, err := r.TakeDetour(v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80))
+	tag, err := r.TakeDetour(&router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80),
+	})
 	assert.Error(err).IsNil()
 	assert.String(tag).Equals("test")
 }
+
+func TestInboundTagRouter(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				Tag:       "redirect-out",
+				Condition: NewInboundTagMatcher([]string{"redirect", "tproxy"}),
+			},
+		},
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	dest := v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)
+	tag, err := r.TakeDetour(&router.Context{
+		InboundTag:  "redirect",
+		Destination: dest,
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("redirect-out")
+
+	_, err = r.TakeDetour(&router.Context{
+		InboundTag:  "socks",
+		Destination: dest,
+	})
+	assert.Error(err).Equals(ErrNoRuleApplicable)
+}
+
+func TestBalancerRouter(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				BalancerTag: "exits",
+				Condition:   NewNetworkMatcher(v2net.Network_TCP.AsList()),
+			},
+		},
+		Balancers: []*BalancerConfig{
+			{
+				Tag:          "exits",
+				OutboundTags: []string{"exit1", "exit2"},
+				Strategy:     "roundrobin",
+			},
+		},
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	ctx := &router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80),
+	}
+	for _, expected := range []string{"exit1", "exit2", "exit1"} {
+		tag, err := r.TakeDetour(ctx)
+		assert.Error(err).IsNil()
+		assert.String(tag).Equals(expected)
+	}
+}
+
+func TestUnknownBalancer(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				BalancerTag: "exits",
+				Condition:   NewNetworkMatcher(v2net.Network_TCP.AsList()),
+			},
+		},
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	space.BindApp(router.APP_ID, NewRouter(config, space))
+	assert.Error(space.Initialize()).Equals(ErrUnknownBalancer)
+}
+
+func TestReloadRouter(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				Tag:       "blocked",
+				Condition: NewPlainDomainMatcher("ads"),
+			},
+		},
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	adsCtx := &router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("ads.v2ray.com"), 80),
+	}
+	trackerCtx := &router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("tracker.v2ray.com"), 80),
+	}
+	tag, err := r.TakeDetour(adsCtx)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("blocked")
+	_, err = r.TakeDetour(trackerCtx)
+	assert.Error(err).Equals(ErrNoRuleApplicable)
+
+	assert.Error(r.Reload(&RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				Tag:       "blocked",
+				Condition: NewPlainDomainMatcher("tracker"),
+			},
+		},
+	})).IsNil()
+	_, err = r.TakeDetour(adsCtx)
+	assert.Error(err).Equals(ErrNoRuleApplicable)
+	tag, err = r.TakeDetour(trackerCtx)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("blocked")
+
+	// Invalid configs leave the current rules in place.
+	assert.Error(r.Reload(&RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				BalancerTag: "missing",
+				Condition:   NewPlainDomainMatcher("ads"),
+			},
+		},
+	})).Equals(ErrUnknownBalancer)
+	assert.Error(r.Reload(&RouterRuleConfig{Rules: []*Rule{nil}})).Equals(ErrInvalidRule)
+	tag, err = r.TakeDetour(trackerCtx)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("blocked")
+}
+
+func TestDryRun(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				Tag:       "blocked",
+				Condition: NewConditionChan().Add(NewPlainDomainMatcher("ads")).Add(NewNetworkMatcher(v2net.Network_UDP.AsList())),
+			},
+			{
+				Tag:       "direct",
+				Condition: NewSubDomainMatcher("v2ray.com"),
+			},
+		},
+		Trace: true,
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	decision, err := r.DryRun(&router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("ads.v2ray.com"), 80),
+	})
+	assert.Error(err).IsNil()
+	assert.String(decision.OutboundTag).Equals("direct")
+	assert.Int(decision.RuleIndex).Equals(1)
+	assert.Int(len(decision.Trace)).Equals(2)
+	assert.String(decision.Trace[0]).Equals("rule #0 (outbound blocked) not matched: network:udp")
+	assert.String(decision.Trace[1]).Equals("rule #1 (outbound direct) matched: domain:v2ray.com")
+
+	decision, err = r.DryRun(&router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.org"), 80),
+	})
+	assert.Error(err).Equals(ErrNoRuleApplicable)
+	assert.Int(decision.RuleIndex).Equals(-1)
+	assert.String(decision.Trace[len(decision.Trace)-1]).Equals("no rule matched")
+
+	// Trace mode routes as usual.
+	tag, err := r.TakeDetour(&router.Context{
+		Destination: v2net.UDPDestination(v2net.DomainAddress("ads.v2ray.com"), 53),
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("blocked")
+}
+
+func TestAlwaysUseIPRouter(t *testing.T) {
+	assert := assert.On(t)
+
+	cidr, err := NewCIDRMatcher("10.0.0.0/8")
+	assert.Error(err).IsNil()
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				Tag:       "private",
+				Condition: cidr,
+			},
+		},
+		DomainStrategy: UseIPIfNonMatch,
+	}
+
+	dnsConfig := &dns.Config{
+		Hosts: map[string]*v2net.AddressPB{
+			"v2ray.com": {
+				Address: &v2net.AddressPB_Ip{Ip: []byte{10, 1, 2, 3}},
+			},
+		},
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, dnsConfig))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	tag, err := r.TakeDetour(&router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80),
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("private")
+
+	config.DomainStrategy = AlwaysUseIP
+	assert.Error(r.Reload(config)).IsNil()
+	tag, err = r.TakeDetour(&router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80),
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("private")
+}
+
+// noContentHandler answers every request with an HTTP 204 response, without connecting anywhere.
+type noContentHandler struct{}
+
+func (this *noContentHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	defer link.OutboundOutput().Close()
+
+	request, err := link.OutboundInput().Read()
+	if err != nil {
+		return err
+	}
+	request.Release()
+	link.OutboundInput().Release()
+	return link.OutboundOutput().Write(alloc.NewLocalBuffer(128).Clear().AppendString("HTTP/1.1 204 No Content\r\n\r\n"))
+}
+
+func TestBalancerFallback(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				BalancerTag: "primary",
+				Condition:   NewNetworkMatcher(v2net.Network_TCP.AsList()),
+			},
+		},
+		Balancers: []*BalancerConfig{
+			{
+				Tag:          "primary",
+				OutboundTags: []string{"exit1", "exit2"},
+				Strategy:     "leastload",
+				FallbackTags: []string{"secondary", "backup", "direct"},
+			},
+			{
+				Tag:          "secondary",
+				OutboundTags: []string{"exit3"},
+			},
+		},
+		Observatory: &ObservatoryConfig{
+			ProbeURL: "http://www.v2ray.com/generate_204",
+		},
+	}
+
+	ohm := proxyman.NewDefaultOutboundHandlerManager()
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, ohm)
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+	defer r.Release()
+
+	ctx := &router.Context{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80),
+	}
+
+	// None of the outbounds exist, so the last fallback is used.
+	r.Observatory().ProbeAll()
+	tag, err := r.TakeDetour(ctx)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("direct")
+
+	ohm.SetHandler("backup", new(noContentHandler))
+	r.Observatory().ProbeAll()
+	tag, err = r.TakeDetour(ctx)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("backup")
+
+	ohm.SetHandler("exit3", new(noContentHandler))
+	r.Observatory().ProbeAll()
+	tag, err = r.TakeDetour(ctx)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("exit3")
+
+	ohm.SetHandler("exit2", new(noContentHandler))
+	r.Observatory().ProbeAll()
+	tag, err = r.TakeDetour(ctx)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("exit2")
+
+	config.Balancers[0].FallbackTags = []string{"primary"}
+	assert.Error(r.Reload(config)).Equals(ErrInvalidFallback)
+}
+
+func TestSourceRouter(t *testing.T) {
+	assert := assert.On(t)
+
+	cidr, err := NewCIDRMatcher("10.0.0.0/8")
+	assert.Error(err).IsNil()
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				Tag:       "lan",
+				Condition: NewSourceMatcher(cidr),
+			},
+			{
+				Tag:       "default",
+				Condition: NewNetworkMatcher(v2net.Network_TCP.AsList()),
+			},
+		},
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	dest := v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)
+	tag, err := r.TakeDetour(&router.Context{
+		Source:      v2net.TCPDestination(v2net.ParseAddress("10.0.0.1"), 1024),
+		Destination: dest,
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("lan")
+
+	tag, err = r.TakeDetour(&router.Context{
+		Source:      v2net.TCPDestination(v2net.ParseAddress("192.168.0.1"), 1024),
+		Destination: dest,
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("default")
+}
+
+func TestSourcePortRouter(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RouterRuleConfig{
+		Rules: []*Rule{
+			{
+				Tag:       "dns",
+				Condition: NewSourcePortMatcher(v2net.NewPortList(v2net.SinglePortRange(53))),
+			},
+			{
+				Tag:       "default",
+				Condition: NewNetworkMatcher(v2net.Network_TCP.AsList()),
+			},
+		},
+	}
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(config, space)
+	space.BindApp(router.APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	source := v2net.ParseAddress("10.0.0.1")
+	dest := v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)
+	tag, err := r.TakeDetour(&router.Context{
+		Source:      v2net.TCPDestination(source, 53),
+		Destination: dest,
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("dns")
+
+	tag, err = r.TakeDetour(&router.Context{
+		Source:      v2net.TCPDestination(source, 1024),
+		Destination: dest,
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("default")
+}
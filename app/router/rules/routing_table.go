@@ -6,7 +6,7 @@ import (
 )
 
 type RoutingEntry struct {
-	tag    string
+	rule   *Rule
 	err    error
 	expire time.Time
 }
@@ -41,13 +41,13 @@ func (this *RoutingTable) Cleanup() {
 	}
 }
 
-func (this *RoutingTable) Set(destination string, tag string, err error) {
+func (this *RoutingTable) Set(destination string, rule *Rule, err error) {
 	this.Lock()
 	defer this.Unlock()
 
 	entry := &RoutingEntry{
-		tag: tag,
-		err: err,
+		rule: rule,
+		err:  err,
 	}
 	entry.Extend()
 	this.table[destination] = entry
@@ -57,14 +57,14 @@ func (this *RoutingTable) Set(destination string, tag string, err error) {
 	}
 }
 
-func (this *RoutingTable) Get(destination string) (bool, string, error) {
+func (this *RoutingTable) Get(destination string) (bool, *Rule, error) {
 	this.RLock()
 	defer this.RUnlock()
 
 	entry, found := this.table[destination]
 	if !found {
-		return false, "", nil
+		return false, nil, nil
 	}
 	entry.Extend()
-	return true, entry.tag, entry.err
+	return true, entry.rule, entry.err
 }
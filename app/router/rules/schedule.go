@@ -0,0 +1,207 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"v2ray.com/core/app/router"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("Router|Schedule: Invalid schedule.")
+
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// TimeRange is a range of time in a day, in minutes since midnight. A range whose end is before its start spans
+// midnight, e.g. 22:00-06:00.
+type TimeRange struct {
+	From int
+	To   int
+}
+
+func (this TimeRange) Contains(minute int) bool {
+	if this.From <= this.To {
+		return minute >= this.From && minute < this.To
+	}
+	return minute >= this.From || minute < this.To
+}
+
+func (this TimeRange) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", this.From/60, this.From%60, this.To/60, this.To%60)
+}
+
+func parseClock(clock string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(clock, "%d:%d", &hour, &minute); err != nil {
+		return 0, ErrInvalidSchedule
+	}
+	if hour < 0 || hour > 24 || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, ErrInvalidSchedule
+	}
+	return hour*60 + minute, nil
+}
+
+// ParseTimeRange parses a time range in the form of "HH:MM-HH:MM".
+func ParseTimeRange(rangeStr string) (TimeRange, error) {
+	parts := strings.Split(strings.TrimSpace(rangeStr), "-")
+	if len(parts) != 2 {
+		return TimeRange{}, ErrInvalidSchedule
+	}
+	from, err := parseClock(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return TimeRange{}, err
+	}
+	to, err := parseClock(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return TimeRange{}, err
+	}
+	if from == to {
+		return TimeRange{}, ErrInvalidSchedule
+	}
+	return TimeRange{From: from, To: to}, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, ErrInvalidSchedule
+	}
+	for idx, weekday := range weekdayNames {
+		if strings.HasPrefix(name, weekday) {
+			return time.Weekday(idx), nil
+		}
+	}
+	return 0, ErrInvalidSchedule
+}
+
+// ParseWeekdays parses days of week, such as "mon", "Saturday", or ranges such as "mon-fri". A range may wrap around
+// the end of week, e.g. "fri-mon".
+func ParseWeekdays(days []string) ([]time.Weekday, error) {
+	var weekdays []time.Weekday
+	for _, day := range days {
+		parts := strings.Split(day, "-")
+		if len(parts) > 2 {
+			return nil, ErrInvalidSchedule
+		}
+		from, err := parseWeekday(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if len(parts) == 2 {
+			to, err = parseWeekday(parts[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		for weekday := from; ; weekday = (weekday + 1) % 7 {
+			weekdays = append(weekdays, weekday)
+			if weekday == to {
+				break
+			}
+		}
+	}
+	return weekdays, nil
+}
+
+// ScheduleMatcher matches connections routed in the given days of week and time ranges, in the given time zone.
+// Empty days or time ranges match any day or any time. The day of a range that spans midnight is the day it starts.
+type ScheduleMatcher struct {
+	days     [7]bool
+	anyDay   bool
+	ranges   []TimeRange
+	location *time.Location
+}
+
+// NewScheduleMatcher creates a ScheduleMatcher. location is the time zone of the schedule, or nil for local time.
+func NewScheduleMatcher(weekdays []time.Weekday, ranges []TimeRange, location *time.Location) *ScheduleMatcher {
+	if location == nil {
+		location = time.Local
+	}
+	matcher := &ScheduleMatcher{
+		anyDay:   len(weekdays) == 0,
+		ranges:   ranges,
+		location: location,
+	}
+	for _, weekday := range weekdays {
+		matcher.days[weekday] = true
+	}
+	return matcher
+}
+
+func (this *ScheduleMatcher) matchDay(weekday time.Weekday) bool {
+	return this.anyDay || this.days[weekday]
+}
+
+func (this *ScheduleMatcher) Match(t time.Time) bool {
+	t = t.In(this.location)
+	if len(this.ranges) == 0 {
+		return this.matchDay(t.Weekday())
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for _, r := range this.ranges {
+		if !r.Contains(minute) {
+			continue
+		}
+		weekday := t.Weekday()
+		if r.From > r.To && minute < r.To {
+			// After midnight in a range that started the day before.
+			weekday = (weekday + 6) % 7
+		}
+		if this.matchDay(weekday) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *ScheduleMatcher) Apply(ctx *router.Context) bool {
+	if ctx.Time.IsZero() {
+		return this.Match(time.Now())
+	}
+	return this.Match(ctx.Time)
+}
+
+func (this *ScheduleMatcher) String() string {
+	var parts []string
+	if !this.anyDay {
+		var days []string
+		for idx, name := range weekdayNames {
+			if this.days[idx] {
+				days = append(days, name)
+			}
+		}
+		parts = append(parts, strings.Join(days, ","))
+	}
+	for _, r := range this.ranges {
+		parts = append(parts, r.String())
+	}
+	return "schedule:" + strings.Join(parts, " ") + " " + this.location.String()
+}
+
+// isTimeDependent returns true if the condition contains a ScheduleMatcher, so that its result may change over time
+// and can't be cached.
+func isTimeDependent(cond Condition) bool {
+	switch cond := cond.(type) {
+	case *ScheduleMatcher:
+		return true
+	case *ConditionChan:
+		for _, c := range *cond {
+			if isTimeDependent(c) {
+				return true
+			}
+		}
+	case *AnyCondition:
+		for _, c := range *cond {
+			if isTimeDependent(c) {
+				return true
+			}
+		}
+	case *SourceMatcher:
+		return isTimeDependent(cond.condition)
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package rules_test
+
+import (
+	"testing"
+	"time"
+
+	"v2ray.com/core/app/router"
+	. "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	assert := assert.On(t)
+
+	r, err := ParseTimeRange("22:00-06:30")
+	assert.Error(err).IsNil()
+	assert.Int(r.From).Equals(22 * 60)
+	assert.Int(r.To).Equals(6*60 + 30)
+	assert.Bool(r.Contains(23 * 60)).IsTrue()
+	assert.Bool(r.Contains(6 * 60)).IsTrue()
+	assert.Bool(r.Contains(12 * 60)).IsFalse()
+	assert.String(r.String()).Equals("22:00-06:30")
+
+	for _, invalid := range []string{"", "22:00", "25:00-06:00", "10:00-10:00", "a-b"} {
+		_, err := ParseTimeRange(invalid)
+		assert.Error(err).IsNotNil()
+	}
+}
+
+func TestParseWeekdays(t *testing.T) {
+	assert := assert.On(t)
+
+	weekdays, err := ParseWeekdays([]string{"Fri-mon", "wednesday"})
+	assert.Error(err).IsNil()
+	assert.Int(len(weekdays)).Equals(5)
+	assert.Int(int(weekdays[0])).Equals(int(time.Friday))
+	assert.Int(int(weekdays[3])).Equals(int(time.Monday))
+	assert.Int(int(weekdays[4])).Equals(int(time.Wednesday))
+
+	_, err = ParseWeekdays([]string{"someday"})
+	assert.Error(err).IsNotNil()
+}
+
+func TestScheduleMatcher(t *testing.T) {
+	assert := assert.On(t)
+
+	weekdays, err := ParseWeekdays([]string{"mon-fri"})
+	assert.Error(err).IsNil()
+	night, err := ParseTimeRange("22:00-06:00")
+	assert.Error(err).IsNil()
+	matcher := NewScheduleMatcher(weekdays, []TimeRange{night}, time.UTC)
+
+	// 2017-01-02 is a Monday.
+	at := func(day int, hour int) *router.Context {
+		return &router.Context{
+			Time: time.Date(2017, 1, day, hour, 0, 0, 0, time.UTC),
+		}
+	}
+	assert.Bool(matcher.Apply(at(2, 23))).IsTrue()
+	assert.Bool(matcher.Apply(at(3, 1))).IsTrue()
+	assert.Bool(matcher.Apply(at(3, 12))).IsFalse()
+	// Saturday night after Friday night.
+	assert.Bool(matcher.Apply(at(7, 1))).IsTrue()
+	assert.Bool(matcher.Apply(at(7, 23))).IsFalse()
+	// Monday night after Sunday night.
+	assert.Bool(matcher.Apply(at(2, 1))).IsFalse()
+	assert.String(matcher.String()).Equals("schedule:mon,tue,wed,thu,fri 22:00-06:00 UTC")
+}
@@ -2,12 +2,15 @@ package app
 
 import (
 	"errors"
+	"reflect"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
 )
 
 var (
 	ErrMissingApplication = errors.New("App: Failed to found one or more applications.")
+	ErrBadFeatureCallback = errors.New("App: Feature callback must be a function of interfaces.")
 )
 
 type ID int
@@ -36,11 +39,19 @@ type Space interface {
 	HasApp(ID) bool
 	GetApp(ID) Application
 	BindApp(ID, Application)
+	// Apps returns the IDs of all bound applications, in ascending order.
+	Apps() []ID
+
+	// RequireFeatures calls the callback on initialization with the applications it requires. Each parameter of the
+	// callback is an interface, resolved to the application with the lowest ID implementing it, and initialization
+	// fails if any of them is missing. The callback may return an error.
+	RequireFeatures(callback interface{}) error
 }
 
 type spaceImpl struct {
-	cache   map[ID]Application
-	appInit []ApplicationInitializer
+	cache       map[ID]Application
+	appInit     []ApplicationInitializer
+	initialized bool
 }
 
 func NewSpace() Space {
@@ -50,7 +61,15 @@ func NewSpace() Space {
 	}
 }
 
+// InitializeApplication registers an initializer to run on Initialize. Initializers registered after the space is
+// initialized, e.g. by handlers created on reload, run immediately.
 func (this *spaceImpl) InitializeApplication(f ApplicationInitializer) {
+	if this.initialized {
+		if err := f(); err != nil {
+			log.Error("App: Failed to initialize application: ", err)
+		}
+		return
+	}
 	this.appInit = append(this.appInit, f)
 }
 
@@ -61,6 +80,8 @@ func (this *spaceImpl) Initialize() error {
 			return err
 		}
 	}
+	this.appInit = nil
+	this.initialized = true
 	return nil
 }
 
@@ -80,3 +101,57 @@ func (this *spaceImpl) GetApp(id ID) Application {
 func (this *spaceImpl) BindApp(id ID, application Application) {
 	this.cache[id] = application
 }
+
+func (this *spaceImpl) Apps() []ID {
+	ids := make([]ID, 0, len(this.cache))
+	for id := range this.cache {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+	return ids
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func (this *spaceImpl) RequireFeatures(callback interface{}) error {
+	callbackType := reflect.TypeOf(callback)
+	if callbackType == nil || callbackType.Kind() != reflect.Func {
+		return ErrBadFeatureCallback
+	}
+	for i := 0; i < callbackType.NumIn(); i++ {
+		if callbackType.In(i).Kind() != reflect.Interface {
+			return ErrBadFeatureCallback
+		}
+	}
+	if callbackType.NumOut() > 1 || (callbackType.NumOut() == 1 && callbackType.Out(0) != errorType) {
+		return ErrBadFeatureCallback
+	}
+	this.InitializeApplication(func() error {
+		return this.resolve(reflect.ValueOf(callback))
+	})
+	return nil
+}
+
+func (this *spaceImpl) resolve(callback reflect.Value) error {
+	ids := this.Apps()
+	args := make([]reflect.Value, callback.Type().NumIn())
+	for idx := range args {
+		argType := callback.Type().In(idx)
+		for _, id := range ids {
+			application := this.cache[id]
+			if application != nil && reflect.TypeOf(application).Implements(argType) {
+				args[idx] = reflect.ValueOf(application)
+				break
+			}
+		}
+		if !args[idx].IsValid() {
+			log.Error("App: No application implements ", argType)
+			return ErrMissingApplication
+		}
+	}
+	results := callback.Call(args)
+	if len(results) == 1 && !results[0].IsNil() {
+		return results[0].Interface().(error)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package stats
+
+// Config enables traffic counters. Each enabled kind of counters counts uplink and downlink bytes separately.
+type Config struct {
+	// Users enables counters by user email. Users without emails are not counted.
+	Users bool
+	// Inbounds enables counters by inbound tag.
+	Inbounds bool
+	// Outbounds enables counters by outbound tag. Connections sent through the default outbound without a tag are
+	// not counted.
+	Outbounds bool
+	// UserDevices enables counting the distinct source IPs of the open connections of each user with email.
+	UserDevices bool
+	// DeviceLimit is the maximum number of source IPs of each user. Connections from more IPs are rejected. Zero means
+	// no limit. It requires UserDevices.
+	DeviceLimit int
+}
@@ -0,0 +1,33 @@
+// +build json
+
+package stats
+
+import (
+	"errors"
+
+	"v2ray.com/core/common/loader"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Users       bool `json:"users"`
+		Inbounds    bool `json:"inbounds"`
+		Outbounds   bool `json:"outbounds"`
+		UserDevices bool `json:"userDevices"`
+		DeviceLimit int  `json:"deviceLimit"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Stats: Failed to parse config: ", err)
+	}
+	this.Users = jsonConfig.Users
+	this.Inbounds = jsonConfig.Inbounds
+	this.Outbounds = jsonConfig.Outbounds
+	if jsonConfig.DeviceLimit < 0 {
+		return errors.New("Stats: Device limit must not be negative.")
+	}
+	// A device limit implies counting devices.
+	this.UserDevices = jsonConfig.UserDevices || jsonConfig.DeviceLimit > 0
+	this.DeviceLimit = jsonConfig.DeviceLimit
+	return nil
+}
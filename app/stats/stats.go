@@ -0,0 +1,287 @@
+// Package stats counts traffic of users, inbounds and outbounds.
+package stats
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	APP_ID = app.ID(8)
+)
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "stats",
+		Config: (*Config)(nil),
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewManager(config.(*Config)), nil
+		},
+	})
+}
+
+// Counter is a counter of bytes, safe for concurrent use.
+type Counter struct {
+	value int64
+}
+
+func (this *Counter) Value() int64 {
+	return atomic.LoadInt64(&this.value)
+}
+
+// Set sets the value of the counter, and returns the previous value.
+func (this *Counter) Set(value int64) int64 {
+	return atomic.SwapInt64(&this.value, value)
+}
+
+// Add adds delta to the counter, and returns the new value.
+func (this *Counter) Add(delta int64) int64 {
+	return atomic.AddInt64(&this.value, delta)
+}
+
+// Names of counters, e.g. "user>>>love@v2ray.com>>>traffic>>>uplink".
+func counterName(kind string, name string, direction string) string {
+	return kind + ">>>" + name + ">>>traffic>>>" + direction
+}
+
+func UserUplinkCounterName(email string) string {
+	return counterName("user", email, "uplink")
+}
+
+func UserDownlinkCounterName(email string) string {
+	return counterName("user", email, "downlink")
+}
+
+// UserDevicesCounterName is the name of the number of source IPs of a user, e.g. "user>>>love@v2ray.com>>>online>>>devices".
+// Unlike traffic counters, it goes down as connections close.
+func UserDevicesCounterName(email string) string {
+	return "user>>>" + email + ">>>online>>>devices"
+}
+
+// UserQuotaCounterName is the name of the bytes a user transferred in the current quota period, e.g.
+// "user>>>love@v2ray.com>>>quota>>>used". Resetting it lifts the user from over quota.
+func UserQuotaCounterName(email string) string {
+	return "user>>>" + email + ">>>quota>>>used"
+}
+
+// InboundUDPSessionsCounterName is the name of the number of open UDP sessions of an inbound, e.g.
+// "inbound>>>socks>>>udp>>>sessions". It goes down as sessions close.
+func InboundUDPSessionsCounterName(tag string) string {
+	return "inbound>>>" + tag + ">>>udp>>>sessions"
+}
+
+func InboundUplinkCounterName(tag string) string {
+	return counterName("inbound", tag, "uplink")
+}
+
+func InboundDownlinkCounterName(tag string) string {
+	return counterName("inbound", tag, "downlink")
+}
+
+func OutboundUplinkCounterName(tag string) string {
+	return counterName("outbound", tag, "uplink")
+}
+
+func OutboundDownlinkCounterName(tag string) string {
+	return counterName("outbound", tag, "downlink")
+}
+
+// Manager holds all counters by name, and the numbers of open connections on outbounds.
+type Manager struct {
+	sync.RWMutex
+	config      *Config
+	counters    map[string]*Counter
+	connections map[string]*Counter
+	// devices are the numbers of open connections by source IP of each user.
+	devices     map[string]map[string]int
+	devicesLock sync.Mutex
+}
+
+func NewManager(config *Config) *Manager {
+	return &Manager{
+		config:      config,
+		counters:    make(map[string]*Counter),
+		connections: make(map[string]*Counter),
+		devices:     make(map[string]map[string]int),
+	}
+}
+
+func (this *Manager) connectionCounter(outboundTag string) *Counter {
+	this.RLock()
+	counter, found := this.connections[outboundTag]
+	this.RUnlock()
+	if found {
+		return counter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	counter, found = this.connections[outboundTag]
+	if !found {
+		counter = new(Counter)
+		this.connections[outboundTag] = counter
+	}
+	return counter
+}
+
+// OnConnectionOpen and OnConnectionClose track the open connections on an outbound. The tag is empty for the default
+// outbound.
+func (this *Manager) OnConnectionOpen(outboundTag string) {
+	this.connectionCounter(outboundTag).Add(1)
+}
+
+func (this *Manager) OnConnectionClose(outboundTag string) {
+	this.connectionCounter(outboundTag).Add(-1)
+}
+
+// Connections returns the numbers of open connections by outbound tag.
+func (this *Manager) Connections() map[string]int64 {
+	this.RLock()
+	defer this.RUnlock()
+
+	connections := make(map[string]int64, len(this.connections))
+	for tag, counter := range this.connections {
+		connections[tag] = counter.Value()
+	}
+	return connections
+}
+
+// OpenDevice records an open connection of the user with the given email from the source IP, and returns a function to
+// call once the connection closes. It returns false if the user is connected from as many IPs as the device limit and
+// the IP is new. Users are not counted if UserDevices is disabled.
+func (this *Manager) OpenDevice(email string, ip string) (func(), bool) {
+	if !this.config.UserDevices || len(email) == 0 {
+		return func() {}, true
+	}
+
+	this.devicesLock.Lock()
+	defer this.devicesLock.Unlock()
+
+	ips, found := this.devices[email]
+	if !found {
+		ips = make(map[string]int)
+		this.devices[email] = ips
+	}
+	if ips[ip] == 0 && this.config.DeviceLimit > 0 && len(ips) >= this.config.DeviceLimit {
+		return nil, false
+	}
+	ips[ip]++
+	// Setting the value rather than adding to it corrects the counter after it is reset through the API.
+	this.RegisterCounter(UserDevicesCounterName(email)).Set(int64(len(ips)))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			this.closeDevice(email, ip)
+		})
+	}, true
+}
+
+func (this *Manager) closeDevice(email string, ip string) {
+	this.devicesLock.Lock()
+	defer this.devicesLock.Unlock()
+
+	ips := this.devices[email]
+	ips[ip]--
+	if ips[ip] <= 0 {
+		delete(ips, ip)
+	}
+	if len(ips) == 0 {
+		delete(this.devices, email)
+	}
+	this.RegisterCounter(UserDevicesCounterName(email)).Set(int64(len(ips)))
+}
+
+// InboundsEnabled returns whether counters of inbounds are enabled.
+func (this *Manager) InboundsEnabled() bool {
+	return this.config.Inbounds
+}
+
+func (this *Manager) Release() {
+
+}
+
+// RegisterCounter returns the counter with the given name, which is created if not found.
+func (this *Manager) RegisterCounter(name string) *Counter {
+	this.RLock()
+	counter, found := this.counters[name]
+	this.RUnlock()
+	if found {
+		return counter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	counter, found = this.counters[name]
+	if !found {
+		counter = new(Counter)
+		this.counters[name] = counter
+	}
+	return counter
+}
+
+// GetCounter returns the counter with the given name, or nil if not found.
+func (this *Manager) GetCounter(name string) *Counter {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.counters[name]
+}
+
+// Query returns the values of counters whose names contain pattern, sorted by name. All counters are returned if the
+// pattern is empty. Counters are reset to zero if reset is true.
+func (this *Manager) Query(pattern string, reset bool) ([]string, []int64) {
+	this.RLock()
+	names := make([]string, 0, len(this.counters))
+	for name := range this.counters {
+		if strings.Contains(name, pattern) {
+			names = append(names, name)
+		}
+	}
+	this.RUnlock()
+	sort.Strings(names)
+
+	values := make([]int64, len(names))
+	for idx, name := range names {
+		counter := this.GetCounter(name)
+		if reset {
+			values[idx] = counter.Set(0)
+		} else {
+			values[idx] = counter.Value()
+		}
+	}
+	return names, values
+}
+
+// counterPair returns the uplink and downlink counters with the given names, or nils if not enabled.
+func (this *Manager) counterPair(enabled bool, uplink string, downlink string) (ray.Counter, ray.Counter) {
+	if !enabled {
+		return nil, nil
+	}
+	return this.RegisterCounter(uplink), this.RegisterCounter(downlink)
+}
+
+// Count wraps the given ray with the counters of the user and the inbound of the session, when enabled.
+func (this *Manager) Count(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, r ray.Ray) ray.Ray {
+	if user := session.User; user != nil && len(user.Email) > 0 {
+		uplink, downlink := this.counterPair(this.config.Users, UserUplinkCounterName(user.Email), UserDownlinkCounterName(user.Email))
+		r = ray.NewCountedRay(r, uplink, downlink)
+	}
+	if meta != nil && len(meta.Tag) > 0 {
+		uplink, downlink := this.counterPair(this.config.Inbounds, InboundUplinkCounterName(meta.Tag), InboundDownlinkCounterName(meta.Tag))
+		r = ray.NewCountedRay(r, uplink, downlink)
+	}
+	return r
+}
+
+// CountOutbound returns the uplink and downlink counters of the outbound with the given tag, or nils if not enabled.
+func (this *Manager) CountOutbound(tag string) (ray.Counter, ray.Counter) {
+	return this.counterPair(this.config.Outbounds && len(tag) > 0, OutboundUplinkCounterName(tag), OutboundDownlinkCounterName(tag))
+}
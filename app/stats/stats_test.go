@@ -0,0 +1,75 @@
+package stats_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/app/stats"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+func TestCount(t *testing.T) {
+	assert := assert.On(t)
+
+	manager := NewManager(&Config{Users: true, Inbounds: true})
+	meta := &proxy.InboundHandlerMeta{Tag: "in"}
+	session := &proxy.SessionInfo{User: &protocol.User{Email: "love@v2ray.com"}}
+	link := manager.Count(meta, session, ray.NewRay())
+
+	link.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().AppendString("abcd"))
+	buffer, err := link.OutboundInput().Read()
+	assert.Error(err).IsNil()
+	buffer.Release()
+	link.OutboundOutput().Write(alloc.NewLocalBuffer(32).Clear().AppendString("ab"))
+	buffer, err = link.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	buffer.Release()
+
+	assert.Int64(manager.GetCounter(UserUplinkCounterName("love@v2ray.com")).Value()).Equals(4)
+	assert.Int64(manager.GetCounter(UserDownlinkCounterName("love@v2ray.com")).Value()).Equals(2)
+	assert.Int64(manager.GetCounter(InboundUplinkCounterName("in")).Value()).Equals(4)
+	assert.Int64(manager.GetCounter(InboundDownlinkCounterName("in")).Value()).Equals(2)
+
+	uplink, downlink := manager.CountOutbound("out")
+	assert.Bool(uplink == nil && downlink == nil).IsTrue()
+
+	names, values := manager.Query("user>>>", true)
+	assert.Int(len(names)).Equals(2)
+	assert.String(names[0]).Equals(UserDownlinkCounterName("love@v2ray.com"))
+	assert.Int64(values[0]).Equals(2)
+	assert.Int64(values[1]).Equals(4)
+	assert.Int64(manager.GetCounter(UserUplinkCounterName("love@v2ray.com")).Value()).Equals(0)
+}
+
+func TestDevices(t *testing.T) {
+	assert := assert.On(t)
+
+	manager := NewManager(&Config{UserDevices: true, DeviceLimit: 2})
+	email := "love@v2ray.com"
+
+	release1, ok := manager.OpenDevice(email, "1.2.3.4")
+	assert.Bool(ok).IsTrue()
+	release2, ok := manager.OpenDevice(email, "1.2.3.4")
+	assert.Bool(ok).IsTrue()
+	release3, ok := manager.OpenDevice(email, "5.6.7.8")
+	assert.Bool(ok).IsTrue()
+	assert.Int64(manager.GetCounter(UserDevicesCounterName(email)).Value()).Equals(2)
+
+	_, ok = manager.OpenDevice(email, "9.10.11.12")
+	assert.Bool(ok).IsFalse()
+
+	release1()
+	release1()
+	assert.Int64(manager.GetCounter(UserDevicesCounterName(email)).Value()).Equals(2)
+	release2()
+	assert.Int64(manager.GetCounter(UserDevicesCounterName(email)).Value()).Equals(1)
+
+	release4, ok := manager.OpenDevice(email, "9.10.11.12")
+	assert.Bool(ok).IsTrue()
+	release3()
+	release4()
+	assert.Int64(manager.GetCounter(UserDevicesCounterName(email)).Value()).Equals(0)
+}
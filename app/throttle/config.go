@@ -0,0 +1,20 @@
+package throttle
+
+// Limit is a bandwidth limit in both directions. A zero rate means unlimited.
+type Limit struct {
+	// Uplink is the rate of traffic from client to server, in bytes per second.
+	Uplink uint64
+	// Downlink is the rate of traffic from server to client, in bytes per second.
+	Downlink uint64
+	// Shared makes all connections of the same user (or inbound) share one bucket, instead of having one each.
+	Shared bool
+}
+
+type Config struct {
+	// Levels are limits by user level.
+	Levels map[uint32]*Limit
+	// Inbounds are limits by inbound tag.
+	Inbounds map[string]*Limit
+	// Outbounds are limits by outbound tag. They are always shared by all connections on the outbound.
+	Outbounds map[string]*Limit
+}
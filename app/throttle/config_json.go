@@ -0,0 +1,50 @@
+// +build json
+
+package throttle
+
+import (
+	"errors"
+	"strconv"
+
+	"v2ray.com/core/common/loader"
+)
+
+func (this *Limit) UnmarshalJSON(data []byte) error {
+	type JsonLimit struct {
+		Uplink   uint64 `json:"uplink"`
+		Downlink uint64 `json:"downlink"`
+		Shared   bool   `json:"shared"`
+	}
+	jsonLimit := new(JsonLimit)
+	if err := loader.DecodeJSON(data, jsonLimit); err != nil {
+		return loader.WrapError("Throttle: Failed to parse limit: ", err)
+	}
+	// Rates are configured in KB/s.
+	this.Uplink = jsonLimit.Uplink * 1024
+	this.Downlink = jsonLimit.Downlink * 1024
+	this.Shared = jsonLimit.Shared
+	return nil
+}
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Levels    map[string]*Limit `json:"levels"`
+		Inbounds  map[string]*Limit `json:"inbounds"`
+		Outbounds map[string]*Limit `json:"outbounds"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Throttle: Failed to parse config: ", err)
+	}
+	this.Levels = make(map[uint32]*Limit, len(jsonConfig.Levels))
+	for key, limit := range jsonConfig.Levels {
+		level, err := strconv.ParseUint(key, 10, 32)
+		if err != nil {
+			return errors.New("Throttle: Invalid user level: " + key)
+		}
+		this.Levels[uint32(level)] = limit
+	}
+	this.Inbounds = jsonConfig.Inbounds
+	this.Outbounds = jsonConfig.Outbounds
+	return nil
+}
@@ -0,0 +1,64 @@
+// +build json
+
+package throttle_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "v2ray.com/core/app/throttle"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestConfigParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "levels": {
+      "0": {"uplink": 128, "downlink": 1024, "shared": true}
+    },
+    "inbounds": {
+      "public": {"downlink": 512}
+    },
+    "outbounds": {
+      "metered": {"uplink": 256, "downlink": 256}
+    }
+  }`
+	config := new(Config)
+	err := json.Unmarshal([]byte(rawJson), config)
+	assert.Error(err).IsNil()
+
+	level0 := config.Levels[0]
+	assert.Pointer(level0).IsNotNil()
+	assert.Int64(int64(level0.Uplink)).Equals(128 * 1024)
+	assert.Int64(int64(level0.Downlink)).Equals(1024 * 1024)
+	assert.Bool(level0.Shared).IsTrue()
+
+	public := config.Inbounds["public"]
+	assert.Pointer(public).IsNotNil()
+	assert.Int64(int64(public.Uplink)).Equals(0)
+	assert.Int64(int64(public.Downlink)).Equals(512 * 1024)
+	assert.Bool(public.Shared).IsFalse()
+
+	metered := config.Outbounds["metered"]
+	assert.Pointer(metered).IsNotNil()
+	assert.Int64(int64(metered.Uplink)).Equals(256 * 1024)
+
+	// Outbound limits are shared by all connections on the outbound.
+	throttler := NewThrottler(config)
+	uplink, downlink := throttler.ThrottleOutbound("metered")
+	assert.Pointer(uplink).IsNotNil()
+	assert.Pointer(downlink).IsNotNil()
+	sharedUplink, _ := throttler.ThrottleOutbound("metered")
+	assert.Bool(uplink == sharedUplink).IsTrue()
+	uplink, downlink = throttler.ThrottleOutbound("direct")
+	assert.Bool(uplink == nil && downlink == nil).IsTrue()
+}
+
+func TestInvalidLevel(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(`{"levels": {"a": {}}}`), config)
+	assert.Error(err).IsNotNil()
+}
@@ -0,0 +1,161 @@
+package throttle
+
+import (
+	"strconv"
+	"sync"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/common/ratelimit"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	APP_ID = app.ID(7)
+)
+
+type buckets struct {
+	uplink   *ratelimit.TokenBucket
+	downlink *ratelimit.TokenBucket
+	// refs is the number of connections using the shared buckets.
+	refs int
+}
+
+func newBuckets(limit *Limit) *buckets {
+	b := new(buckets)
+	if limit.Uplink > 0 {
+		b.uplink = ratelimit.NewTokenBucket(limit.Uplink, 0)
+	}
+	if limit.Downlink > 0 {
+		b.downlink = ratelimit.NewTokenBucket(limit.Downlink, 0)
+	}
+	return b
+}
+
+// Throttler applies bandwidth limits to connections, based on user levels and inbound tags.
+type Throttler struct {
+	sync.Mutex
+	config *Config
+	shared map[string]*buckets
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "throttle",
+		Config: (*Config)(nil),
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewThrottler(config.(*Config)), nil
+		},
+	})
+}
+
+func NewThrottler(config *Config) *Throttler {
+	return &Throttler{
+		config: config,
+		shared: make(map[string]*buckets),
+	}
+}
+
+func (this *Throttler) Release() {
+	this.Lock()
+	defer this.Unlock()
+
+	this.shared = make(map[string]*buckets)
+}
+
+// acquireBuckets returns the buckets of the key for a connection, and a function to release them once the connection
+// finishes. Shared buckets are dropped when the last connection using them is released, so that buckets of users gone
+// away don't pile up.
+func (this *Throttler) acquireBuckets(key string, limit *Limit) (*buckets, func()) {
+	if !limit.Shared {
+		return newBuckets(limit), func() {}
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	b, found := this.shared[key]
+	if !found {
+		b = newBuckets(limit)
+		this.shared[key] = b
+	}
+	b.refs++
+	var once sync.Once
+	return b, func() {
+		once.Do(func() {
+			this.releaseBuckets(key, b)
+		})
+	}
+}
+
+func (this *Throttler) releaseBuckets(key string, b *buckets) {
+	this.Lock()
+	defer this.Unlock()
+
+	b.refs--
+	if b.refs <= 0 && this.shared[key] == b {
+		delete(this.shared, key)
+	}
+}
+
+// getBuckets returns the buckets of the key, which are kept as long as the throttler. It is for outbounds, whose
+// number is limited by the config.
+func (this *Throttler) getBuckets(key string, limit *Limit) *buckets {
+	if !limit.Shared {
+		return newBuckets(limit)
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	b, found := this.shared[key]
+	if !found {
+		b = newBuckets(limit)
+		this.shared[key] = b
+	}
+	return b
+}
+
+// Throttle wraps the given ray with the limits applicable to the session, and returns a function to release the limits
+// once the connection finishes. Limits of the user level and the inbound tag are both applied, when present.
+func (this *Throttler) Throttle(meta *proxy.InboundHandlerMeta, session *proxy.SessionInfo, r ray.Ray) (ray.Ray, func()) {
+	releases := make([]func(), 0, 2)
+	if user := session.User; user != nil {
+		if limit, found := this.config.Levels[user.Level]; found {
+			key := "user:" + user.Email
+			if len(user.Email) == 0 {
+				key = "level:" + strconv.Itoa(int(user.Level))
+			}
+			b, release := this.acquireBuckets(key, limit)
+			releases = append(releases, release)
+			r = ray.NewThrottledRay(r, b.uplink, b.downlink)
+		}
+	}
+	if meta != nil && len(meta.Tag) > 0 {
+		if limit, found := this.config.Inbounds[meta.Tag]; found {
+			b, release := this.acquireBuckets("inbound:"+meta.Tag, limit)
+			releases = append(releases, release)
+			r = ray.NewThrottledRay(r, b.uplink, b.downlink)
+		}
+	}
+	return r, func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+}
+
+// ThrottleOutbound returns the buckets that limit the aggregate traffic of the outbound with the given tag, or nils if
+// it is not limited. Uplink limits the traffic sent through the outbound, and downlink the traffic received.
+func (this *Throttler) ThrottleOutbound(tag string) (*ratelimit.TokenBucket, *ratelimit.TokenBucket) {
+	if len(tag) == 0 {
+		return nil, nil
+	}
+	limit, found := this.config.Outbounds[tag]
+	if !found {
+		return nil, nil
+	}
+	shared := *limit
+	shared.Shared = true
+	b := this.getBuckets("outbound:"+tag, &shared)
+	return b.uplink, b.downlink
+}
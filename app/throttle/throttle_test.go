@@ -0,0 +1,34 @@
+package throttle
+
+import (
+	"testing"
+
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+func TestSharedBucketsReleased(t *testing.T) {
+	assert := assert.On(t)
+
+	throttler := NewThrottler(&Config{
+		Levels: map[uint32]*Limit{
+			1: {Uplink: 1024, Shared: true},
+		},
+	})
+	session := &proxy.SessionInfo{
+		User: &protocol.User{Level: 1, Email: "love@v2ray.com"},
+	}
+
+	_, release1 := throttler.Throttle(nil, session, ray.NewRay())
+	_, release2 := throttler.Throttle(nil, session, ray.NewRay())
+	assert.Int(len(throttler.shared)).Equals(1)
+
+	release1()
+	release1()
+	assert.Int(len(throttler.shared)).Equals(1)
+
+	release2()
+	assert.Int(len(throttler.shared)).Equals(0)
+}
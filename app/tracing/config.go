@@ -0,0 +1,16 @@
+package tracing
+
+const (
+	DefaultServiceName = "v2ray"
+)
+
+type Config struct {
+	// Endpoint is the URL of an OTLP/HTTP collector that receives traces, e.g. "http://localhost:4318/v1/traces".
+	Endpoint string
+	// Headers are sent with each export, e.g. for authentication.
+	Headers map[string]string
+	// ServiceName is the service.name of the exported spans, DefaultServiceName if empty.
+	ServiceName string
+	// SampleRate is the ratio of connections that are traced, between 0 and 1.
+	SampleRate float64
+}
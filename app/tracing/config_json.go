@@ -0,0 +1,44 @@
+// +build json
+
+package tracing
+
+import (
+	"errors"
+	"net/url"
+
+	"v2ray.com/core/common/loader"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Endpoint    string            `json:"endpoint"`
+		Headers     map[string]string `json:"headers"`
+		ServiceName string            `json:"serviceName"`
+		SampleRate  *float64          `json:"sampleRate"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Tracing: Failed to parse config: ", err)
+	}
+	if len(jsonConfig.Endpoint) == 0 {
+		return errors.New("Tracing: Endpoint is not specified.")
+	}
+	endpoint, err := url.Parse(jsonConfig.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return errors.New("Tracing: Invalid endpoint: " + jsonConfig.Endpoint)
+	}
+	this.Endpoint = jsonConfig.Endpoint
+	this.Headers = jsonConfig.Headers
+	this.ServiceName = DefaultServiceName
+	if len(jsonConfig.ServiceName) > 0 {
+		this.ServiceName = jsonConfig.ServiceName
+	}
+	this.SampleRate = 1
+	if jsonConfig.SampleRate != nil {
+		if *jsonConfig.SampleRate < 0 || *jsonConfig.SampleRate > 1 {
+			return errors.New("Tracing: Sample rate must be between 0 and 1.")
+		}
+		this.SampleRate = *jsonConfig.SampleRate
+	}
+	return nil
+}
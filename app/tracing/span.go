@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+type SpanKind int
+
+// Span kinds as in OTLP.
+const (
+	SpanKindInternal = SpanKind(1)
+	SpanKindServer   = SpanKind(2)
+	SpanKindClient   = SpanKind(3)
+)
+
+type attribute struct {
+	key   string
+	value string
+}
+
+type spanEvent struct {
+	name string
+	time time.Time
+}
+
+// Span is a stage of a traced connection. All methods are no-op on a nil Span, which is returned for connections that
+// are not traced, so callers don't need to check.
+type Span struct {
+	sync.Mutex
+	tracer     *Tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes []attribute
+	events     []spanEvent
+	err        string
+}
+
+func newSpan(tracer *Tracer, traceID [16]byte, parentID [8]byte, name string, kind SpanKind) *Span {
+	span := &Span{
+		tracer:   tracer,
+		traceID:  traceID,
+		parentID: parentID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+	}
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// Child starts a span of the same trace under this one.
+func (this *Span) Child(name string, kind SpanKind) *Span {
+	if this == nil {
+		return nil
+	}
+	return newSpan(this.tracer, this.traceID, this.spanID, name, kind)
+}
+
+func (this *Span) SetAttribute(key string, value string) {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	this.attributes = append(this.attributes, attribute{key: key, value: value})
+	this.Unlock()
+}
+
+// AddEvent records a point in time during the span, e.g. the first byte from the server.
+func (this *Span) AddEvent(name string) {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	this.events = append(this.events, spanEvent{name: name, time: time.Now()})
+	this.Unlock()
+}
+
+// SetError marks the span as failed.
+func (this *Span) SetError(err error) {
+	if this == nil || err == nil {
+		return
+	}
+	this.Lock()
+	this.err = err.Error()
+	this.Unlock()
+}
+
+// End finishes the span and queues it for export. Calls after the first one are ignored.
+func (this *Span) End() {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	if !this.end.IsZero() {
+		this.Unlock()
+		return
+	}
+	this.end = time.Now()
+	this.Unlock()
+
+	this.tracer.queue(this)
+}
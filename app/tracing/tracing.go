@@ -0,0 +1,306 @@
+// Package tracing traces the stages of connections, and exports them to an OpenTelemetry collector over OTLP/HTTP.
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	mrand "math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/common/log"
+)
+
+const (
+	APP_ID = app.ID(12)
+
+	exportInterval = 5 * time.Second
+	exportTimeout  = 10 * time.Second
+	exportBatch    = 512
+	// maxPendingSpans limits the spans kept while the collector is unavailable.
+	maxPendingSpans = 8192
+	scopeName       = "v2ray.com/core"
+)
+
+var (
+	ErrAlreadyStarted = errors.New("Tracing: Tracer is already started.")
+)
+
+// Tracer starts spans of sampled connections, and exports ended spans in batches in background.
+type Tracer struct {
+	sync.Mutex
+	config  *Config
+	client  *http.Client
+	pending []*Span
+	dropped int
+	started bool
+	flush   chan bool
+	done    chan bool
+	stopped chan bool
+	// logger is the logger of the instance. Nil for the default logger.
+	logger *log.Logger
+}
+
+func init() {
+	app.MustRegisterFeature(&app.Feature{
+		ID:     APP_ID,
+		Name:   "tracing",
+		Config: (*Config)(nil),
+		After:  []app.ID{applog.APP_ID},
+		Create: func(space app.Space, config interface{}) (app.Application, error) {
+			return NewTracer(config.(*Config), applog.FromSpace(space)), nil
+		},
+	})
+}
+
+func NewTracer(config *Config, logger *log.Logger) *Tracer {
+	return &Tracer{
+		config: config,
+		client: &http.Client{Timeout: exportTimeout},
+		logger: logger,
+	}
+}
+
+// StartTrace starts the root span of a new trace, or returns nil if the trace is not sampled or the tracer is not
+// running.
+func (this *Tracer) StartTrace(name string, kind SpanKind) *Span {
+	if this == nil {
+		return nil
+	}
+	this.Lock()
+	started := this.started
+	this.Unlock()
+	if !started || this.config.SampleRate <= 0 || (this.config.SampleRate < 1 && mrand.Float64() >= this.config.SampleRate) {
+		return nil
+	}
+	var traceID [16]byte
+	rand.Read(traceID[:])
+	return newSpan(this, traceID, [8]byte{}, name, kind)
+}
+
+func (this *Tracer) queue(span *Span) {
+	this.Lock()
+	defer this.Unlock()
+
+	if !this.started {
+		return
+	}
+	if len(this.pending) >= maxPendingSpans {
+		this.dropped++
+		return
+	}
+	this.pending = append(this.pending, span)
+	if len(this.pending) >= exportBatch {
+		select {
+		case this.flush <- true:
+		default:
+		}
+	}
+}
+
+func (this *Tracer) Start() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.started {
+		return ErrAlreadyStarted
+	}
+	this.started = true
+	this.flush = make(chan bool, 1)
+	this.done = make(chan bool)
+	this.stopped = make(chan bool)
+	go this.run(this.flush, this.done, this.stopped)
+	return nil
+}
+
+func (this *Tracer) run(flush chan bool, done chan bool, stopped chan bool) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			this.export()
+			return
+		case <-ticker.C:
+		case <-flush:
+		}
+		this.export()
+	}
+}
+
+func (this *Tracer) export() {
+	this.Lock()
+	spans := this.pending
+	dropped := this.dropped
+	this.pending = nil
+	this.dropped = 0
+	this.Unlock()
+
+	if dropped > 0 {
+		this.logger.Warning("Tracing: Dropped ", dropped, " spans.")
+	}
+	for len(spans) > 0 {
+		batch := spans
+		if len(batch) > exportBatch {
+			batch = batch[:exportBatch]
+		}
+		spans = spans[len(batch):]
+		if err := this.send(batch); err != nil {
+			this.logger.Warning("Tracing: Failed to export ", len(batch), " spans: ", err)
+		}
+	}
+}
+
+func (this *Tracer) send(spans []*Span) error {
+	content, err := json.Marshal(this.encode(spans))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", this.config.Endpoint, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range this.config.Headers {
+		request.Header.Set(key, value)
+	}
+	response, err := this.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return errors.New("Tracing: Unexpected status of collector: " + response.Status)
+	}
+	return nil
+}
+
+// Types below are the JSON encoding of ExportTraceServiceRequest in OTLP.
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string `json:"timeUnixNano"`
+	Name         string `json:"name"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func (this *Tracer) encode(spans []*Span) *otlpTraces {
+	serviceName := this.config.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = DefaultServiceName
+	}
+	encoded := make([]otlpSpan, len(spans))
+	for idx, span := range spans {
+		span.Lock()
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.traceID[:]),
+			SpanID:            hex.EncodeToString(span.spanID[:]),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: unixNano(span.start),
+			EndTimeUnixNano:   unixNano(span.end),
+		}
+		if span.parentID != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(span.parentID[:])
+		}
+		for _, attr := range span.attributes {
+			s.Attributes = append(s.Attributes, otlpKeyValue{Key: attr.key, Value: otlpValue{StringValue: attr.value}})
+		}
+		for _, event := range span.events {
+			s.Events = append(s.Events, otlpEvent{TimeUnixNano: unixNano(event.time), Name: event.name})
+		}
+		if len(span.err) > 0 {
+			// STATUS_CODE_ERROR
+			s.Status = otlpStatus{Code: 2, Message: span.err}
+		}
+		span.Unlock()
+		encoded[idx] = s
+	}
+	return &otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: serviceName}}},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
+
+// Release stops the tracer after exporting the pending spans.
+func (this *Tracer) Release() {
+	this.Lock()
+	if !this.started {
+		this.Unlock()
+		return
+	}
+	this.started = false
+	done, stopped := this.done, this.stopped
+	this.Unlock()
+
+	close(done)
+	<-stopped
+}
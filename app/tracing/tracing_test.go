@@ -0,0 +1,92 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "v2ray.com/core/app/tracing"
+	"v2ray.com/core/testing/assert"
+)
+
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Events       []struct {
+		Name string `json:"name"`
+	} `json:"events"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+type exportRequest struct {
+	ResourceSpans []struct {
+		ScopeSpans []struct {
+			Spans []exportedSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func TestExport(t *testing.T) {
+	assert := assert.On(t)
+
+	requests := make(chan *exportRequest, 4)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := new(exportRequest)
+		if r.Header.Get("X-Token") == "secret" && json.NewDecoder(r.Body).Decode(request) == nil {
+			requests <- request
+		}
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(&Config{
+		Endpoint:   collector.URL,
+		Headers:    map[string]string{"X-Token": "secret"},
+		SampleRate: 1,
+	}, nil)
+	assert.Pointer(tracer.StartTrace("connection", SpanKindServer)).IsNil()
+	assert.Error(tracer.Start()).IsNil()
+
+	root := tracer.StartTrace("connection", SpanKindServer)
+	child := root.Child("outbound", SpanKindClient)
+	child.AddEvent("first_byte")
+	child.SetError(errors.New("closed"))
+	child.End()
+	root.End()
+	tracer.Release()
+
+	request := <-requests
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Int(len(spans)).Equals(2)
+	assert.String(spans[0].Name).Equals("outbound")
+	assert.Int(spans[0].Kind).Equals(3)
+	assert.String(spans[0].ParentSpanID).Equals(spans[1].SpanID)
+	assert.String(spans[0].TraceID).Equals(spans[1].TraceID)
+	assert.String(spans[0].Events[0].Name).Equals("first_byte")
+	assert.Int(spans[0].Status.Code).Equals(2)
+	assert.String(spans[1].Name).Equals("connection")
+	assert.String(spans[1].ParentSpanID).Equals("")
+}
+
+func TestNotSampled(t *testing.T) {
+	assert := assert.On(t)
+
+	tracer := NewTracer(&Config{
+		Endpoint:   "http://127.0.0.1:1/v1/traces",
+		SampleRate: 0,
+	}, nil)
+	assert.Error(tracer.Start()).IsNil()
+	defer tracer.Release()
+
+	span := tracer.StartTrace("connection", SpanKindServer)
+	assert.Pointer(span).IsNil()
+	// Methods of a nil span are no-op.
+	span.Child("route", SpanKindInternal).End()
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// BuildInfo describes the build of the running V2Ray, so that operators can verify that the intended build is deployed.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Codename  string `json:"codename"`
+	Build     string `json:"build"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	// Tags are the build tags that change the features of V2Ray, e.g. "json".
+	Tags []string `json:"tags"`
+	// Features are the protocols and config formats compiled in, e.g. "inbound:vmess" and "config:yaml".
+	Features []string `json:"features"`
+}
+
+var (
+	buildTags    []string
+	featureMutex sync.Mutex
+	features     = make(map[string]bool)
+)
+
+func registerBuildTag(tag string) {
+	buildTags = append(buildTags, tag)
+}
+
+// RegisterFeature records a feature compiled in, e.g. "inbound:vmess". It is called by registries on init.
+func RegisterFeature(name string) {
+	featureMutex.Lock()
+	defer featureMutex.Unlock()
+
+	features[name] = true
+}
+
+func GetBuildInfo() *BuildInfo {
+	tags := append([]string(nil), buildTags...)
+	sort.Strings(tags)
+
+	featureMutex.Lock()
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	featureMutex.Unlock()
+	sort.Strings(names)
+
+	return &BuildInfo{
+		Version:   version,
+		Codename:  codename,
+		Build:     build,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Tags:      tags,
+		Features:  names,
+	}
+}
@@ -0,0 +1,7 @@
+// +build json
+
+package core
+
+func init() {
+	registerBuildTag("json")
+}
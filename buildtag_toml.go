@@ -0,0 +1,7 @@
+// +build toml
+
+package core
+
+func init() {
+	registerBuildTag("toml")
+}
@@ -0,0 +1,7 @@
+// +build yaml
+
+package core
+
+func init() {
+	registerBuildTag("yaml")
+}
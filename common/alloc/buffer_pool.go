@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
 type Pool interface {
@@ -12,12 +13,16 @@ type Pool interface {
 }
 
 type BufferPool struct {
+	// allocated is the number of buffers allocated beyond the pool. It's the first field to be 64-bit aligned.
+	allocated uint64
 	chain     chan []byte
 	allocator *sync.Pool
+	size      uint32
 }
 
 func NewBufferPool(bufferSize, poolSize uint32) *BufferPool {
 	pool := &BufferPool{
+		size:  bufferSize,
 		chain: make(chan []byte, poolSize),
 		allocator: &sync.Pool{
 			New: func() interface{} { return make([]byte, bufferSize) },
@@ -34,11 +39,33 @@ func (p *BufferPool) Allocate() *Buffer {
 	select {
 	case b = <-p.chain:
 	default:
+		atomic.AddUint64(&p.allocated, 1)
 		b = p.allocator.Get().([]byte)
 	}
 	return CreateBuffer(b, p)
 }
 
+// PoolStats is a snapshot of the usage of a BufferPool.
+type PoolStats struct {
+	// BufferSize is the size of each buffer in bytes.
+	BufferSize uint32
+	// Capacity is the number of buffers kept by the pool.
+	Capacity uint32
+	// Idle is the number of buffers in the pool that are not in use.
+	Idle uint32
+	// Overflows is the number of buffers allocated because the pool was empty.
+	Overflows uint64
+}
+
+func (p *BufferPool) Stats() PoolStats {
+	return PoolStats{
+		BufferSize: p.size,
+		Capacity:   uint32(cap(p.chain)),
+		Idle:       uint32(len(p.chain)),
+		Overflows:  atomic.LoadUint64(&p.allocated),
+	}
+}
+
 func (p *BufferPool) Free(buffer *Buffer) {
 	rawBuffer := buffer.head
 	if rawBuffer == nil {
@@ -82,3 +109,12 @@ func init() {
 	mediumPool = NewBufferPool(mediumBufferByteSize, totalByteSize/4*3/mediumBufferByteSize)
 	largePool = NewBufferPool(largeBufferByteSize, totalByteSize/4/largeBufferByteSize)
 }
+
+// GetPoolStats returns the stats of the shared pools, by their names "small", "medium" and "large".
+func GetPoolStats() map[string]PoolStats {
+	return map[string]PoolStats{
+		"small":  smallPool.Stats(),
+		"medium": mediumPool.Stats(),
+		"large":  largePool.Stats(),
+	}
+}
@@ -58,3 +58,21 @@ func TestBufferString(t *testing.T) {
 	buffer.AppendString("Test String")
 	assert.String(buffer.String()).Equals("Test String")
 }
+
+func TestBufferUnpool(t *testing.T) {
+	assert := assert.On(t)
+
+	buffer := NewLargeBuffer().Clear().Append([]byte("abcd"))
+	assert.Bool(buffer.IsPooled()).IsTrue()
+	assert.Bool(MediumClass.Fits(buffer)).IsFalse()
+	assert.Bool(LargeClass.Fits(buffer)).IsTrue()
+
+	unpooled := buffer.Unpool()
+	assert.Bool(unpooled.IsPooled()).IsFalse()
+	assert.Bool(SmallClass.Fits(unpooled)).IsTrue()
+	assert.String(unpooled.String()).Equals("abcd")
+
+	// The space before the content is kept, so that headers can be prepended.
+	unpooled.SliceBack(4)
+	assert.Int(unpooled.Len()).Equals(8)
+}
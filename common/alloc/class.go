@@ -0,0 +1,74 @@
+package alloc
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownSizeClass = errors.New("Alloc: Unknown buffer size class.")
+)
+
+// SizeClass identifies one of the shared pools by the size of its buffers. The zero value is the largest class.
+type SizeClass int
+
+const (
+	LargeClass SizeClass = iota
+	MediumClass
+	SmallClass
+)
+
+// ParseSizeClass returns the class of the given name, i.e. "small", "medium" or "large".
+func ParseSizeClass(name string) (SizeClass, error) {
+	switch strings.ToLower(name) {
+	case "small":
+		return SmallClass, nil
+	case "medium":
+		return MediumClass, nil
+	case "large":
+		return LargeClass, nil
+	default:
+		return LargeClass, ErrUnknownSizeClass
+	}
+}
+
+func (this SizeClass) pool() *BufferPool {
+	switch this {
+	case SmallClass:
+		return smallPool
+	case MediumClass:
+		return mediumPool
+	default:
+		return largePool
+	}
+}
+
+// Size returns the number of bytes that a buffer of this class holds.
+func (this SizeClass) Size() int {
+	return int(this.pool().size) - defaultOffset
+}
+
+// NewBuffer allocates a buffer from the pool of this class.
+func (this SizeClass) NewBuffer() *Buffer {
+	return this.pool().Allocate()
+}
+
+// Fits returns true if the buffer is not pooled, or from the pool of this class or a smaller one.
+func (this SizeClass) Fits(b *Buffer) bool {
+	return !b.IsPooled() || len(b.head) <= int(this.pool().size)
+}
+
+// IsPooled returns true if the buffer is from a shared pool.
+func (b *Buffer) IsPooled() bool {
+	return b.pool != nil
+}
+
+// Unpool returns a buffer that is not pooled with the same content, and releases this one to its pool.
+func (b *Buffer) Unpool() *Buffer {
+	if !b.IsPooled() {
+		return b
+	}
+	unpooled := NewLocalBuffer(defaultOffset + b.Len()).Clear().Append(b.Value)
+	b.Release()
+	return unpooled
+}
@@ -0,0 +1,144 @@
+// Package ban temporarily bans sources that fail authentication too often, e.g. active probers and brute force.
+package ban
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/errors"
+)
+
+var (
+	ErrBanned = errors.New("Source is banned.").Path("Ban").WithCode(errors.CodePermissionDenied).WithSeverity(errors.SeverityInfo)
+)
+
+const (
+	DefaultWindow      = time.Minute
+	DefaultDuration    = time.Minute
+	DefaultMaxDuration = 24 * time.Hour
+)
+
+// Config is how sources are banned. A source is banned once it fails Failures times within Window. The first ban lasts
+// Duration, and each following one twice as long as the previous, up to MaxDuration. A source that stays out of bans
+// for MaxDuration starts over. Zero Failures disables banning.
+type Config struct {
+	Failures    int
+	Window      time.Duration
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+type source struct {
+	start    time.Time
+	failures int
+	bans     uint
+	until    time.Time
+}
+
+// List tracks failures and bans by source IP. Each instance has its own list, owned by its policy manager. A nil List
+// bans nothing.
+type List struct {
+	sync.RWMutex
+	config  Config
+	sources map[string]*source
+	cleaned time.Time
+}
+
+func NewList() *List {
+	return &List{
+		sources: make(map[string]*source),
+	}
+}
+
+// Configure replaces the config, and lifts all bans.
+func (this *List) Configure(config Config) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.config = config
+	this.sources = make(map[string]*source)
+}
+
+func (this *List) clean(now time.Time) {
+	if now.Sub(this.cleaned) < this.config.Window {
+		return
+	}
+	for key, s := range this.sources {
+		if now.Sub(s.start) > this.config.Window && now.Sub(s.until) > this.config.MaxDuration {
+			delete(this.sources, key)
+		}
+	}
+	this.cleaned = now
+}
+
+// Fail counts a failure of the IP at the given time. It returns the duration of the ban if the IP is banned for this
+// failure, or 0 otherwise.
+func (this *List) Fail(ip net.IP, now time.Time) time.Duration {
+	if this == nil {
+		return 0
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	if this.config.Failures <= 0 {
+		return 0
+	}
+	this.clean(now)
+
+	key := ip.String()
+	s, found := this.sources[key]
+	if !found {
+		s = &source{start: now}
+		this.sources[key] = s
+	}
+	if now.Before(s.until) {
+		return 0
+	}
+	if s.bans > 0 && now.Sub(s.until) > this.config.MaxDuration {
+		s.bans = 0
+	}
+	if now.Sub(s.start) > this.config.Window {
+		s.start = now
+		s.failures = 0
+	}
+	s.failures++
+	if s.failures < this.config.Failures {
+		return 0
+	}
+
+	// Shifts the max duration right instead of the duration left, which would overflow after a few dozen bans.
+	duration := this.config.MaxDuration
+	if s.bans < 63 && this.config.Duration <= duration>>s.bans {
+		duration = this.config.Duration << s.bans
+		s.bans++
+	}
+	s.failures = 0
+	s.until = now.Add(duration)
+	return duration
+}
+
+// IsBanned returns true if the IP is banned at the given time.
+func (this *List) IsBanned(ip net.IP, now time.Time) bool {
+	if this == nil {
+		return false
+	}
+	this.RLock()
+	defer this.RUnlock()
+
+	s, found := this.sources[ip.String()]
+	return found && now.Before(s.until)
+}
+
+// IsBannedAddr is the same as IsBanned at the current time, for the IP of a TCP or UDP address. Addresses of other
+// kinds are not banned.
+func (this *List) IsBannedAddr(addr net.Addr) bool {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return this.IsBanned(addr.IP, time.Now())
+	case *net.UDPAddr:
+		return this.IsBanned(addr.IP, time.Now())
+	default:
+		return false
+	}
+}
@@ -0,0 +1,85 @@
+package ban_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/common/ban"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestBanDoubles(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewList()
+	list.Configure(Config{
+		Failures:    2,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: 3 * time.Minute,
+	})
+	ip := net.ParseIP("1.2.3.4")
+	now := time.Now()
+
+	assert.Int64(int64(list.Fail(ip, now))).Equals(0)
+	assert.Bool(list.IsBanned(ip, now)).IsFalse()
+	assert.Int64(int64(list.Fail(ip, now))).Equals(int64(time.Minute))
+	assert.Bool(list.IsBanned(ip, now)).IsTrue()
+	assert.Bool(list.IsBanned(net.ParseIP("1.2.3.5"), now)).IsFalse()
+
+	now = now.Add(2 * time.Minute)
+	assert.Bool(list.IsBanned(ip, now)).IsFalse()
+	list.Fail(ip, now)
+	assert.Int64(int64(list.Fail(ip, now))).Equals(int64(2 * time.Minute))
+
+	now = now.Add(3 * time.Minute)
+	list.Fail(ip, now)
+	assert.Int64(int64(list.Fail(ip, now))).Equals(int64(3 * time.Minute))
+
+	// Bans start over once the source stays out of them long enough.
+	now = now.Add(7 * time.Minute)
+	list.Fail(ip, now)
+	assert.Int64(int64(list.Fail(ip, now))).Equals(int64(time.Minute))
+}
+
+func TestBanWindow(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewList()
+	ip := net.ParseIP("::1")
+	now := time.Now()
+	assert.Int64(int64(list.Fail(ip, now))).Equals(0)
+
+	list.Configure(Config{
+		Failures:    2,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: time.Hour,
+	})
+	list.Fail(ip, now)
+	assert.Int64(int64(list.Fail(ip, now.Add(2*time.Minute)))).Equals(0)
+	assert.Bool(list.IsBanned(ip, now.Add(2*time.Minute))).IsFalse()
+}
+
+func TestBanCapped(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewList()
+	list.Configure(Config{
+		Failures:    1,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: 24 * time.Hour,
+	})
+	ip := net.ParseIP("1.2.3.4")
+	now := time.Now()
+
+	for i := 0; i < 40; i++ {
+		duration := list.Fail(ip, now)
+		assert.Bool(duration > 0).IsTrue()
+		assert.Bool(duration <= 24*time.Hour).IsTrue()
+		now = now.Add(duration)
+	}
+	assert.Int64(int64(list.Fail(ip, now))).Equals(int64(24 * time.Hour))
+}
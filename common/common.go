@@ -3,14 +3,14 @@
 package common
 
 import (
-	"errors"
+	"v2ray.com/core/common/errors"
 )
 
 var (
-	ErrObjectReleased   = errors.New("Object already released.")
-	ErrBadConfiguration = errors.New("Bad configuration.")
-	ErrObjectNotFound   = errors.New("Object not found.")
-	ErrDuplicatedName   = errors.New("Duplicated name.")
+	ErrObjectReleased   = errors.New("Object already released.").WithCode(errors.CodeClosed)
+	ErrBadConfiguration = errors.New("Bad configuration.").WithCode(errors.CodeBadConfiguration)
+	ErrObjectNotFound   = errors.New("Object not found.").WithCode(errors.CodeNotFound)
+	ErrDuplicatedName   = errors.New("Duplicated name.").WithCode(errors.CodeBadConfiguration)
 )
 
 // Releasable interface is for those types that can release its members.
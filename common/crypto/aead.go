@@ -0,0 +1,187 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"io"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/errors"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/serial"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// AEADMaxChunkSize is the max size of the payload of an AEAD chunk. Larger buffers are split into chunks.
+	AEADMaxChunkSize = 16 * 1024
+)
+
+var (
+	ErrChunkAuthentication = errors.New("Failed to authenticate chunk.").WithCode(errors.CodeProtocol)
+	ErrChunkSize           = errors.New("Invalid chunk size.").WithCode(errors.CodeProtocol)
+)
+
+// NewChaCha20Poly1305 creates a ChaCha20-Poly1305 AEAD, with a nonce of 12 bytes.
+// Caller must ensure the length of key is 32 bytes.
+func NewChaCha20Poly1305(key []byte) cipher.AEAD {
+	aead, _ := chacha20poly1305.New(key)
+	return aead
+}
+
+// NewXChaCha20Poly1305 creates a XChaCha20-Poly1305 AEAD, with a nonce of 24 bytes, which is large enough to be
+// generated randomly.
+// Caller must ensure the length of key is 32 bytes.
+func NewXChaCha20Poly1305(key []byte) cipher.AEAD {
+	aead, _ := chacha20poly1305.NewX(key)
+	return aead
+}
+
+// NonceGenerator gives the nonce of each chunk of a stream.
+type NonceGenerator interface {
+	Next() []byte
+}
+
+// IncreasingNonce is a nonce that starts from an initial value and is increased by one for each chunk, as a little
+// endian integer.
+type IncreasingNonce struct {
+	nonce []byte
+	used  bool
+}
+
+// NewIncreasingNonce creates a nonce starting from initial. The initial value is copied.
+func NewIncreasingNonce(initial []byte) *IncreasingNonce {
+	return &IncreasingNonce{
+		nonce: append([]byte(nil), initial...),
+	}
+}
+
+func (this *IncreasingNonce) Next() []byte {
+	if this.used {
+		for i := range this.nonce {
+			this.nonce[i]++
+			if this.nonce[i] != 0 {
+				break
+			}
+		}
+	}
+	this.used = true
+	return this.nonce
+}
+
+// AEADChunkWriter seals buffers into chunks of a 2-byte length and the sealed payload. An empty buffer is sealed into
+// an empty chunk, which marks the end of the stream. Buffers that fit in one chunk are sealed in place, without
+// allocating.
+type AEADChunkWriter struct {
+	writer v2io.Writer
+	aead   cipher.AEAD
+	nonce  NonceGenerator
+}
+
+func NewAEADChunkWriter(writer v2io.Writer, aead cipher.AEAD, nonce NonceGenerator) *AEADChunkWriter {
+	return &AEADChunkWriter{
+		writer: writer,
+		aead:   aead,
+		nonce:  nonce,
+	}
+}
+
+func (this *AEADChunkWriter) writeChunk(payload []byte) error {
+	size := len(payload) + this.aead.Overhead()
+	chunk := alloc.NewBufferWithSize(size + 2).Clear()
+	chunk.AppendUint16(uint16(size))
+	chunk.Value = this.aead.Seal(chunk.Value, this.nonce.Next(), payload, nil)
+	return this.writer.Write(chunk)
+}
+
+// Write implements v2io.Writer.Write(). It takes the ownership of the buffer.
+func (this *AEADChunkWriter) Write(buffer *alloc.Buffer) error {
+	if this.writer == nil {
+		buffer.Release()
+		return common.ErrObjectReleased
+	}
+	if buffer.Len() <= AEADMaxChunkSize && cap(buffer.Value)-buffer.Len() >= this.aead.Overhead() {
+		buffer.Value = this.aead.Seal(buffer.Value[:0], this.nonce.Next(), buffer.Value, nil)
+		buffer.PrependUint16(uint16(buffer.Len()))
+		return this.writer.Write(buffer)
+	}
+
+	defer buffer.Release()
+	if buffer.IsEmpty() {
+		return this.writeChunk(nil)
+	}
+	for payload := buffer.Value; len(payload) > 0; {
+		size := len(payload)
+		if size > AEADMaxChunkSize {
+			size = AEADMaxChunkSize
+		}
+		if err := this.writeChunk(payload[:size]); err != nil {
+			return err
+		}
+		payload = payload[size:]
+	}
+	return nil
+}
+
+func (this *AEADChunkWriter) Release() {
+	if this.writer != nil {
+		this.writer.Release()
+	}
+	this.writer = nil
+	this.aead = nil
+}
+
+// AEADChunkReader reads chunks written by AEADChunkWriter. It returns io.EOF once it reads an empty chunk.
+type AEADChunkReader struct {
+	reader io.Reader
+	aead   cipher.AEAD
+	nonce  NonceGenerator
+}
+
+func NewAEADChunkReader(reader io.Reader, aead cipher.AEAD, nonce NonceGenerator) *AEADChunkReader {
+	return &AEADChunkReader{
+		reader: reader,
+		aead:   aead,
+		nonce:  nonce,
+	}
+}
+
+// Read implements v2io.Reader.Read().
+func (this *AEADChunkReader) Read() (*alloc.Buffer, error) {
+	if this.reader == nil {
+		return nil, common.ErrObjectReleased
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(this.reader, header[:]); err != nil {
+		return nil, err
+	}
+	size := int(serial.BytesToUint16(header[:]))
+	if size < this.aead.Overhead() || size > AEADMaxChunkSize+this.aead.Overhead() {
+		return nil, ErrChunkSize
+	}
+
+	buffer := alloc.NewBufferWithSize(size).Clear()
+	buffer.Value = buffer.Value[:size]
+	if _, err := io.ReadFull(this.reader, buffer.Value); err != nil {
+		buffer.Release()
+		return nil, io.ErrUnexpectedEOF
+	}
+	payload, err := this.aead.Open(buffer.Value[:0], this.nonce.Next(), buffer.Value, nil)
+	if err != nil {
+		buffer.Release()
+		return nil, ErrChunkAuthentication
+	}
+	if len(payload) == 0 {
+		buffer.Release()
+		return nil, io.EOF
+	}
+	buffer.Value = payload
+	return buffer, nil
+}
+
+func (this *AEADChunkReader) Release() {
+	this.reader = nil
+	this.aead = nil
+}
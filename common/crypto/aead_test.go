@@ -0,0 +1,83 @@
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"io"
+	"testing"
+
+	"v2ray.com/core/common/alloc"
+	. "v2ray.com/core/common/crypto"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/testing/assert"
+)
+
+func testAEADChunkStream(t *testing.T, aead cipher.AEAD) {
+	assert := assert.On(t)
+
+	nonce := make([]byte, aead.NonceSize())
+	nonce[0] = 0xff
+	cache := new(bytes.Buffer)
+	writer := NewAEADChunkWriter(v2io.NewAdaptiveWriter(cache), aead, NewIncreasingNonce(nonce))
+
+	payload := make([]byte, AEADMaxChunkSize+100)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	assert.Error(writer.Write(alloc.NewLocalBuffer(len(payload)).Clear().Append(payload))).IsNil()
+	assert.Error(writer.Write(alloc.NewLocalBuffer(32).Clear().AppendString("abcd"))).IsNil()
+	assert.Error(writer.Write(alloc.NewLocalBuffer(32).Clear())).IsNil()
+	assert.Int(cache.Len()).Equals(len(payload) + 4 + 4*(2+aead.Overhead()))
+
+	reader := NewAEADChunkReader(cache, aead, NewIncreasingNonce(nonce))
+	buffer, err := reader.Read()
+	assert.Error(err).IsNil()
+	assert.Bool(bytes.Equal(buffer.Value, payload[:AEADMaxChunkSize])).IsTrue()
+
+	buffer, err = reader.Read()
+	assert.Error(err).IsNil()
+	assert.Bool(bytes.Equal(buffer.Value, payload[AEADMaxChunkSize:])).IsTrue()
+
+	buffer, err = reader.Read()
+	assert.Error(err).IsNil()
+	assert.String(buffer.String()).Equals("abcd")
+
+	_, err = reader.Read()
+	assert.Error(err).Equals(io.EOF)
+}
+
+func TestChaCha20Poly1305ChunkStream(t *testing.T) {
+	testAEADChunkStream(t, NewChaCha20Poly1305(make([]byte, 32)))
+}
+
+func TestXChaCha20Poly1305ChunkStream(t *testing.T) {
+	testAEADChunkStream(t, NewXChaCha20Poly1305(make([]byte, 32)))
+}
+
+func TestAesGcmChunkStream(t *testing.T) {
+	testAEADChunkStream(t, NewAesGcm(make([]byte, 16)))
+}
+
+func TestAEADChunkTampered(t *testing.T) {
+	assert := assert.On(t)
+
+	aead := NewChaCha20Poly1305(make([]byte, 32))
+	nonce := make([]byte, aead.NonceSize())
+	cache := new(bytes.Buffer)
+	writer := NewAEADChunkWriter(v2io.NewAdaptiveWriter(cache), aead, NewIncreasingNonce(nonce))
+	assert.Error(writer.Write(alloc.NewLocalBuffer(32).Clear().AppendString("abcd"))).IsNil()
+
+	data := cache.Bytes()
+	data[3] ^= 1
+	_, err := NewAEADChunkReader(cache, aead, NewIncreasingNonce(nonce)).Read()
+	assert.Error(err).Equals(ErrChunkAuthentication)
+}
+
+func TestIncreasingNonce(t *testing.T) {
+	assert := assert.On(t)
+
+	nonce := NewIncreasingNonce([]byte{0xff, 0xff, 0x01, 0x00})
+	assert.Bool(bytes.Equal(nonce.Next(), []byte{0xff, 0xff, 0x01, 0x00})).IsTrue()
+	assert.Bool(bytes.Equal(nonce.Next(), []byte{0x00, 0x00, 0x02, 0x00})).IsTrue()
+	assert.Bool(bytes.Equal(nonce.Next(), []byte{0x01, 0x00, 0x02, 0x00})).IsTrue()
+}
@@ -18,3 +18,12 @@ func NewAesEncryptionStream(key []byte, iv []byte) cipher.Stream {
 	aesBlock, _ := aes.NewCipher(key)
 	return cipher.NewCFBEncrypter(aesBlock, iv)
 }
+
+// NewAesGcm creates an AES-GCM AEAD based on the given key, with a nonce of 12 bytes. It is accelerated by the AES and
+// CLMUL instructions of CPUs that have them.
+// Caller must ensure the length of key is either 16, 24 or 32 bytes.
+func NewAesGcm(key []byte) cipher.AEAD {
+	aesBlock, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(aesBlock)
+	return aead
+}
@@ -48,3 +48,26 @@ func BenchmarkAESDecryption(b *testing.B) {
 
 	benchmarkStream(b, c)
 }
+
+func benchmarkAEAD(b *testing.B, c cipher.AEAD) {
+	b.SetBytes(benchSize)
+	input := make([]byte, benchSize)
+	output := make([]byte, 0, benchSize+c.Overhead())
+	nonce := make([]byte, c.NonceSize())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Seal(output, nonce, input, nil)
+	}
+}
+
+func BenchmarkChaCha20Poly1305(b *testing.B) {
+	benchmarkAEAD(b, NewChaCha20Poly1305(make([]byte, 32)))
+}
+
+func BenchmarkXChaCha20Poly1305(b *testing.B) {
+	benchmarkAEAD(b, NewXChaCha20Poly1305(make([]byte, 32)))
+}
+
+func BenchmarkAesGcm(b *testing.B) {
+	benchmarkAEAD(b, NewAesGcm(make([]byte, 16)))
+}
@@ -0,0 +1,215 @@
+// Package errors provides an error type with codes, severity, component path and wrapping. It also offers Is, As and
+// Unwrap of the standard library, so it can be imported in place of it.
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code tells what kind of failure an error is, so that callers can handle it without comparing messages.
+type Code int
+
+const (
+	CodeUnknown Code = iota
+	// CodeBadConfiguration is for invalid configs.
+	CodeBadConfiguration
+	// CodeNotFound is for missing objects, such as users or handlers.
+	CodeNotFound
+	// CodeAuthFailed is for clients that fail authentication.
+	CodeAuthFailed
+	// CodePermissionDenied is for clients that are not allowed, such as banned sources.
+	CodePermissionDenied
+	// CodeProtocol is for malformed or unsupported protocol data.
+	CodeProtocol
+	// CodeNetworkUnreachable is for destinations that can't be connected.
+	CodeNetworkUnreachable
+	// CodeTimeout is for operations that take too long.
+	CodeTimeout
+	// CodeClosed is for objects that are closed or released.
+	CodeClosed
+	// CodeCanceled is for operations that are canceled.
+	CodeCanceled
+)
+
+var codeNames = map[Code]string{
+	CodeUnknown:            "unknown",
+	CodeBadConfiguration:   "bad_configuration",
+	CodeNotFound:           "not_found",
+	CodeAuthFailed:         "auth_failed",
+	CodePermissionDenied:   "permission_denied",
+	CodeProtocol:           "protocol",
+	CodeNetworkUnreachable: "network_unreachable",
+	CodeTimeout:            "timeout",
+	CodeClosed:             "closed",
+	CodeCanceled:           "canceled",
+}
+
+func (this Code) String() string {
+	if name, found := codeNames[this]; found {
+		return name
+	}
+	return "code(" + fmt.Sprint(int(this)) + ")"
+}
+
+// Severity is the log level that an error deserves.
+type Severity int
+
+const (
+	SeverityDebug Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+)
+
+// Error is an error with a code, a severity, the path of the component where it happens, and optionally the error that
+// causes it. Base, WithCode, WithSeverity and Path return modified copies, so errors declared as package variables
+// can be built upon safely.
+type Error struct {
+	message  string
+	code     Code
+	severity Severity
+	path     []string
+	inner    error
+	parent   *Error
+}
+
+// New creates an error with the given message, which is formatted as by fmt.Sprint. Its severity is
+// SeverityWarning.
+func New(msg ...interface{}) *Error {
+	return &Error{
+		message:  fmt.Sprint(msg...),
+		severity: SeverityWarning,
+	}
+}
+
+func (this *Error) copy() *Error {
+	err := *this
+	err.parent = this
+	return &err
+}
+
+// Base returns a copy of the error caused by err. The code of the cause is taken if this one has none.
+func (this *Error) Base(err error) *Error {
+	e := this.copy()
+	e.inner = err
+	if e.code == CodeUnknown {
+		e.code = CodeOf(err)
+	}
+	return e
+}
+
+// WithCode returns a copy of the error with the given code.
+func (this *Error) WithCode(code Code) *Error {
+	e := this.copy()
+	e.code = code
+	return e
+}
+
+// WithSeverity returns a copy of the error with the given severity.
+func (this *Error) WithSeverity(severity Severity) *Error {
+	e := this.copy()
+	e.severity = severity
+	return e
+}
+
+// Path returns a copy of the error in the given component, e.g. Path("Proxy", "VMess", "Inbound").
+func (this *Error) Path(path ...string) *Error {
+	e := this.copy()
+	e.path = path
+	return e
+}
+
+// Code returns the code of the error.
+func (this *Error) Code() Code {
+	return this.code
+}
+
+// Severity returns the severity of the error.
+func (this *Error) Severity() Severity {
+	return this.severity
+}
+
+// Error implements error. The message is prefixed by the path, as "Proxy|VMess|Inbound: message", and followed by the
+// cause if any.
+func (this *Error) Error() string {
+	message := this.message
+	if len(this.path) > 0 {
+		message = strings.Join(this.path, "|") + ": " + message
+	}
+	if this.inner != nil {
+		message += " > " + this.inner.Error()
+	}
+	return message
+}
+
+// Unwrap returns the error that causes this one.
+func (this *Error) Unwrap() error {
+	return this.inner
+}
+
+// Is reports whether this error is a copy of target, so that ErrFoo.Base(err) still matches ErrFoo. Errors are
+// otherwise only equal to themselves. Use HasCode to compare errors by their codes.
+func (this *Error) Is(target error) bool {
+	for parent := this.parent; parent != nil; parent = parent.parent {
+		if parent == target {
+			return true
+		}
+	}
+	return false
+}
+
+// CodeOf returns the code of the first error in the chain of err that has one, or CodeUnknown.
+func CodeOf(err error) Code {
+	var e *Error
+	for err != nil {
+		if errors.As(err, &e) {
+			if e.code != CodeUnknown {
+				return e.code
+			}
+			err = e.inner
+			continue
+		}
+		break
+	}
+	return CodeUnknown
+}
+
+// HasCode returns true if the code of err, as returned by CodeOf, is the given one.
+func HasCode(err error, code Code) bool {
+	return CodeOf(err) == code
+}
+
+// SeverityOf returns the highest severity in the chain of err. Errors of other types are SeverityWarning.
+func SeverityOf(err error) Severity {
+	severity := SeverityDebug
+	found := false
+	var e *Error
+	for err != nil && errors.As(err, &e) {
+		if !found || e.severity > severity {
+			severity = e.severity
+		}
+		found = true
+		err = e.inner
+	}
+	if !found {
+		return SeverityWarning
+	}
+	return severity
+}
+
+// Is is errors.Is of the standard library.
+func Is(err error, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As is errors.As of the standard library.
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+// Unwrap is errors.Unwrap of the standard library.
+func Unwrap(err error) error {
+	return errors.Unwrap(err)
+}
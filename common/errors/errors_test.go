@@ -0,0 +1,64 @@
+package errors_test
+
+import (
+	"io"
+	"testing"
+
+	. "v2ray.com/core/common/errors"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestErrorMessage(t *testing.T) {
+	assert := assert.On(t)
+
+	err := New("Failed to read ", 3, " bytes.").Path("Proxy", "VMess").Base(io.EOF)
+	assert.String(err.Error()).Equals("Proxy|VMess: Failed to read 3 bytes. > EOF")
+	assert.Bool(Unwrap(err) == io.EOF).IsTrue()
+	assert.Bool(Is(err, io.EOF)).IsTrue()
+}
+
+func TestErrorCode(t *testing.T) {
+	assert := assert.On(t)
+
+	authFailed := New("Invalid user.").WithCode(CodeAuthFailed).WithSeverity(SeverityInfo)
+	wrapped := New("Handshake failed.").Path("Proxy").Base(authFailed)
+
+	assert.Bool(CodeOf(wrapped) == CodeAuthFailed).IsTrue()
+	assert.Bool(HasCode(wrapped, CodeAuthFailed)).IsTrue()
+	assert.Bool(HasCode(wrapped, CodeNetworkUnreachable)).IsFalse()
+	assert.Bool(Is(wrapped, authFailed)).IsTrue()
+	assert.Bool(Is(wrapped, New("Any authentication error.").WithCode(CodeAuthFailed))).IsFalse()
+	assert.Bool(SeverityOf(wrapped) == SeverityWarning).IsTrue()
+	assert.Bool(SeverityOf(authFailed) == SeverityInfo).IsTrue()
+	assert.Bool(SeverityOf(io.EOF) == SeverityWarning).IsTrue()
+	assert.Bool(CodeOf(io.EOF) == CodeUnknown).IsTrue()
+	assert.String(CodeAuthFailed.String()).Equals("auth_failed")
+
+	var target *Error
+	assert.Bool(As(wrapped, &target)).IsTrue()
+	assert.Bool(target == wrapped).IsTrue()
+
+	// Errors are only equal to themselves and the errors they are copied from.
+	plain := New("Plain.")
+	assert.Bool(Is(plain, New("Plain."))).IsFalse()
+	assert.Bool(Is(plain, plain)).IsTrue()
+}
+
+func TestErrorCopies(t *testing.T) {
+	assert := assert.On(t)
+
+	errClosed := New("Closed.").WithCode(CodeClosed)
+	errReleased := New("Released.").WithCode(CodeClosed)
+	assert.Bool(Is(errClosed, errReleased)).IsFalse()
+
+	err := errClosed.Path("Proxy").WithSeverity(SeverityInfo).Base(io.EOF)
+	assert.Bool(Is(err, errClosed)).IsTrue()
+	assert.Bool(Is(err, io.EOF)).IsTrue()
+	assert.Bool(Is(err, errReleased)).IsFalse()
+	assert.String(err.Error()).Equals("Proxy: Closed. > EOF")
+
+	// The original error is untouched.
+	assert.String(errClosed.Error()).Equals("Closed.")
+	assert.Bool(SeverityOf(errClosed) == SeverityWarning).IsTrue()
+	assert.Bool(Unwrap(errClosed) == nil).IsTrue()
+}
@@ -0,0 +1,95 @@
+// Package event is a bus of notable events in a running V2Ray instance, e.g. outbounds becoming unhealthy, so that they
+// can be sent to operators.
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+type Type string
+
+const (
+	// OutboundUnhealthy is published when an outbound fails a health probe after being healthy.
+	OutboundUnhealthy = Type("outbound.unhealthy")
+	// OutboundHealthy is published when an unhealthy outbound passes a health probe again.
+	OutboundHealthy = Type("outbound.healthy")
+	// AuthFailure is published by inbounds for each authentication failure. The events app counts them into
+	// AuthFailures, and doesn't send them to operators.
+	AuthFailure = Type("inbound.auth_failure")
+	// AuthFailures is published when a source fails authentication on an inbound too many times.
+	AuthFailures = Type("inbound.auth_failures")
+	// SourceBanned is published when a source is banned for failing authentication too many times.
+	SourceBanned = Type("inbound.source_banned")
+	// CertificateExpiring is published when a TLS certificate in use is about to expire.
+	CertificateExpiring = Type("certificate.expiring")
+)
+
+type Event struct {
+	Type    Type              `json:"type"`
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Handler handles published events. It is called on the path of publishers, so it must not block.
+type Handler func(event *Event)
+
+type subscription struct {
+	handler Handler
+}
+
+// Bus delivers events of an instance to its subscribers. Each instance has its own bus, owned by its events app.
+// Events published on a nil Bus are dropped.
+type Bus struct {
+	access        sync.RWMutex
+	subscriptions []*subscription
+}
+
+func NewBus() *Bus {
+	return new(Bus)
+}
+
+// Subscribe adds a handler of all events, and returns a function that removes the handler.
+func (this *Bus) Subscribe(handler Handler) func() {
+	sub := &subscription{handler: handler}
+
+	this.access.Lock()
+	this.subscriptions = append(this.subscriptions, sub)
+	this.access.Unlock()
+
+	return func() {
+		this.access.Lock()
+		defer this.access.Unlock()
+
+		for idx, s := range this.subscriptions {
+			if s == sub {
+				this.subscriptions = append(this.subscriptions[:idx:idx], this.subscriptions[idx+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish sends an event of the given type to all handlers.
+func (this *Bus) Publish(eventType Type, message string, fields map[string]string) {
+	if this == nil {
+		return
+	}
+	this.access.RLock()
+	subs := this.subscriptions
+	this.access.RUnlock()
+
+	if len(subs) == 0 {
+		return
+	}
+	event := &Event{
+		Type:    eventType,
+		Time:    time.Now(),
+		Message: message,
+		Fields:  fields,
+	}
+	for _, sub := range subs {
+		sub.handler(event)
+	}
+}
@@ -0,0 +1,45 @@
+package event_test
+
+import (
+	"testing"
+	"time"
+
+	. "v2ray.com/core/common/event"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestPublish(t *testing.T) {
+	assert := assert.On(t)
+
+	bus := NewBus()
+	var received []*Event
+	unsubscribe := bus.Subscribe(func(event *Event) {
+		received = append(received, event)
+	})
+	bus.Publish(OutboundUnhealthy, "Outbound [proxy] is unhealthy.", map[string]string{"outbound": "proxy"})
+	unsubscribe()
+	bus.Publish(OutboundHealthy, "Outbound [proxy] is healthy.", nil)
+
+	// Events on another bus, or on none, are not received.
+	NewBus().Publish(OutboundUnhealthy, "Outbound [direct] is unhealthy.", nil)
+	var nilBus *Bus
+	nilBus.Publish(OutboundUnhealthy, "Outbound [direct] is unhealthy.", nil)
+
+	assert.Int(len(received)).Equals(1)
+	assert.String(string(received[0].Type)).Equals(string(OutboundUnhealthy))
+	assert.String(received[0].Fields["outbound"]).Equals("proxy")
+}
+
+func TestThreshold(t *testing.T) {
+	assert := assert.On(t)
+
+	threshold := NewThreshold(3, time.Minute)
+	assert.Int(threshold.Hit("a")).Equals(0)
+	assert.Int(threshold.Hit("a")).Equals(0)
+	assert.Int(threshold.Hit("b")).Equals(0)
+	assert.Int(threshold.Hit("a")).Equals(3)
+	assert.Int(threshold.Hit("a")).Equals(0)
+
+	threshold.Set(0, time.Minute)
+	assert.Int(threshold.Hit("a")).Equals(0)
+}
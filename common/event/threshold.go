@@ -0,0 +1,69 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// Threshold counts occurrences by key, e.g. failures by source IP, and reports when a key reaches the limit within a
+// window. Each key is reported at most once per window.
+type Threshold struct {
+	sync.Mutex
+	limit   int
+	window  time.Duration
+	entries map[string]*thresholdEntry
+	cleaned time.Time
+}
+
+type thresholdEntry struct {
+	start time.Time
+	count int
+}
+
+func NewThreshold(limit int, window time.Duration) *Threshold {
+	return &Threshold{
+		limit:   limit,
+		window:  window,
+		entries: make(map[string]*thresholdEntry),
+		cleaned: time.Now(),
+	}
+}
+
+// Set changes the limit and the window. A limit of zero disables reports.
+func (this *Threshold) Set(limit int, window time.Duration) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.limit = limit
+	this.window = window
+	this.entries = make(map[string]*thresholdEntry)
+}
+
+// Hit counts an occurrence of the key, and returns the count if it reaches the limit. It returns 0 otherwise.
+func (this *Threshold) Hit(key string) int {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.limit <= 0 {
+		return 0
+	}
+	now := time.Now()
+	if now.Sub(this.cleaned) > this.window {
+		for k, entry := range this.entries {
+			if now.Sub(entry.start) > this.window {
+				delete(this.entries, k)
+			}
+		}
+		this.cleaned = now
+	}
+	entry, found := this.entries[key]
+	if !found || now.Sub(entry.start) > this.window {
+		entry = &thresholdEntry{start: now}
+		this.entries[key] = entry
+	}
+	entry.count++
+	if entry.count == this.limit {
+		return entry.count
+	}
+	return 0
+}
@@ -29,7 +29,7 @@ func (this *JSONConfigLoader) LoadWithID(raw []byte, id string) (interface{}, er
 		return nil, err
 	}
 
-	if err := json.Unmarshal(raw, config); err != nil {
+	if err := DecodeJSON(raw, config); err != nil {
 		return nil, err
 	}
 	return config, nil
@@ -50,7 +50,15 @@ func (this *JSONConfigLoader) Load(raw []byte) (interface{}, string, error) {
 		return nil, "", err
 	}
 	rawConfig := json.RawMessage(raw)
-	if len(this.configKey) > 0 {
+	if len(this.configKey) == 0 && strictJSON {
+		// The ID is not a field of the config.
+		delete(obj, this.idKey)
+		config, err := json.Marshal(obj)
+		if err != nil {
+			return nil, "", err
+		}
+		rawConfig = config
+	} else if len(this.configKey) > 0 {
 		configValue, found := obj[this.configKey]
 		if !found {
 			log.Error(this.configKey, " not found in JSON content.")
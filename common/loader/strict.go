@@ -0,0 +1,317 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	strictJSON      = true
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// SetStrictJSON sets whether unknown fields in JSON configs are reported as errors. It is enabled by default.
+func SetStrictJSON(strict bool) {
+	strictJSON = strict
+}
+
+// StrictJSON returns whether unknown fields in JSON configs are reported as errors.
+func StrictJSON() bool {
+	return strictJSON
+}
+
+// UnknownFieldError is returned for a field in a JSON config that doesn't match any field of the config.
+type UnknownFieldError struct {
+	// Path is the keys and array indices from the outermost config to the field, e.g. ["inbound", "settings", "[0]"].
+	Path []string
+	// Suggestion is the closest valid key, or empty if no key is close enough.
+	Suggestion string
+}
+
+func (this *UnknownFieldError) Field() string {
+	var field string
+	for idx, segment := range this.Path {
+		if idx > 0 && !strings.HasPrefix(segment, "[") {
+			field += "."
+		}
+		field += segment
+	}
+	return field
+}
+
+func (this *UnknownFieldError) Error() string {
+	message := "Config: Unknown field \"" + this.Field() + "\"."
+	if len(this.Suggestion) > 0 {
+		message += " Did you mean \"" + this.Suggestion + "\"?"
+	}
+	return message
+}
+
+// Under returns the error with its path prefixed by the given segments.
+func (this *UnknownFieldError) Under(prefix ...string) *UnknownFieldError {
+	return &UnknownFieldError{
+		Path:       append(append([]string(nil), prefix...), this.Path...),
+		Suggestion: this.Suggestion,
+	}
+}
+
+// WrapError returns an error of message followed by err. An *UnknownFieldError is returned as is, so that decoders of
+// enclosing configs are able to complete its path.
+func WrapError(message string, err error) error {
+	if _, ok := err.(*UnknownFieldError); ok {
+		return err
+	}
+	return errors.New(message + err.Error())
+}
+
+// closestKey returns the key in candidates closest to key in edit distance, or an empty string if none is close
+// enough to be a typo.
+func closestKey(key string, candidates []string) string {
+	best := ""
+	bestDistance := len(key)/2 + 1
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(candidate))
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+func editDistance(a string, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			next := diagonal + cost
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if row[j-1]+1 < next {
+				next = row[j-1] + 1
+			}
+			diagonal = row[j]
+			row[j] = next
+		}
+	}
+	return row[len(b)]
+}
+
+// nestedConfig is a part of a JSON document that is decoded by its own UnmarshalJSON.
+type nestedConfig struct {
+	path     []string
+	document interface{}
+}
+
+// DecodeJSON decodes data into v as json.Unmarshal does. In strict mode, a field in data that doesn't match any field
+// of v is reported as an *UnknownFieldError, which includes fields in the configs decoded by DecodeJSON in the
+// UnmarshalJSON of v.
+func DecodeJSON(data []byte, v interface{}) error {
+	if !strictJSON {
+		return json.Unmarshal(data, v)
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return json.Unmarshal(data, v)
+	}
+	var nested []*nestedConfig
+	if err := checkFields(document, reflect.TypeOf(v), nil, &nested); err != nil {
+		return err
+	}
+
+	err := json.Unmarshal(data, v)
+	if unknown, ok := err.(*UnknownFieldError); ok {
+		for _, config := range nested {
+			if hasPath(config.document, unknown.Path) {
+				return unknown.Under(config.path...)
+			}
+		}
+	}
+	return err
+}
+
+// CheckJSONFields returns an *UnknownFieldError if the JSON object in data has a field other than keys. It is for
+// objects decoded into several configs, e.g. a user with its account. The path of the error is prefixed by path.
+func CheckJSONFields(data []byte, keys []string, path ...string) error {
+	if !strictJSON {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	for _, key := range sortedKeys(object) {
+		if indexOfKey(keys, key) < 0 {
+			return &UnknownFieldError{
+				Path:       append(append([]string(nil), path...), key),
+				Suggestion: closestKey(key, keys),
+			}
+		}
+	}
+	return nil
+}
+
+func indexOfKey(keys []string, key string) int {
+	for idx, candidate := range keys {
+		if strings.EqualFold(candidate, key) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// jsonFields returns the JSON keys of the fields in a struct type, and the types of the fields.
+func jsonFields(t reflect.Type) ([]string, []reflect.Type) {
+	var keys []string
+	var types []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && len(name) == 0 {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embeddedKeys, embeddedTypes := jsonFields(fieldType)
+				keys = append(keys, embeddedKeys...)
+				types = append(types, embeddedTypes...)
+				continue
+			}
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		keys = append(keys, name)
+		types = append(types, field.Type)
+	}
+	return keys, types
+}
+
+// checkFields checks the fields of a JSON document against type t. Parts decoded by their own UnmarshalJSON are
+// collected into nested instead.
+func checkFields(document interface{}, t reflect.Type, path []string, nested *[]*nestedConfig) error {
+	for t.Kind() == reflect.Ptr {
+		if t.Implements(unmarshalerType) {
+			*nested = append(*nested, &nestedConfig{path: path, document: document})
+			return nil
+		}
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		*nested = append(*nested, &nestedConfig{path: path, document: document})
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := document.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		keys, types := jsonFields(t)
+		for _, key := range sortedKeys(object) {
+			value := object[key]
+			idx := indexOfKey(keys, key)
+			fieldPath := append(append([]string(nil), path...), key)
+			if idx < 0 {
+				return &UnknownFieldError{
+					Path:       fieldPath,
+					Suggestion: closestKey(key, keys),
+				}
+			}
+			if err := checkFields(value, types[idx], fieldPath, nested); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := document.([]interface{})
+		if !ok {
+			return nil
+		}
+		for idx, value := range array {
+			elementPath := append(append([]string(nil), path...), "["+strconv.Itoa(idx)+"]")
+			if err := checkFields(value, t.Elem(), elementPath, nested); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		object, ok := document.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(object) {
+			value := object[key]
+			valuePath := append(append([]string(nil), path...), key)
+			if err := checkFields(value, t.Elem(), valuePath, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasPath returns whether path leads to a field in a JSON document.
+func hasPath(document interface{}, path []string) bool {
+	for _, segment := range path {
+		switch value := document.(type) {
+		case map[string]interface{}:
+			found := false
+			for key, field := range value {
+				if strings.EqualFold(key, segment) {
+					document = field
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case []interface{}:
+			if !strings.HasPrefix(segment, "[") || !strings.HasSuffix(segment, "]") {
+				return false
+			}
+			idx, err := strconv.Atoi(segment[1 : len(segment)-1])
+			if err != nil || idx < 0 || idx >= len(value) {
+				return false
+			}
+			document = value[idx]
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package loader_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "v2ray.com/core/common/loader"
+	"v2ray.com/core/testing/assert"
+)
+
+type testStrictUser struct {
+	Name string
+}
+
+func (this *testStrictUser) UnmarshalJSON(data []byte) error {
+	type JsonUser struct {
+		Name string `json:"name"`
+	}
+	jsonUser := new(JsonUser)
+	if err := DecodeJSON(data, jsonUser); err != nil {
+		return WrapError("Test: Failed to parse user: ", err)
+	}
+	this.Name = jsonUser.Name
+	return nil
+}
+
+type testStrictConfig struct {
+	Port    int                        `json:"port"`
+	Users   []*testStrictUser          `json:"users"`
+	Servers map[string]*testStrictUser `json:"servers"`
+	Extra   json.RawMessage            `json:"extra"`
+}
+
+func TestDecodeJSONStrict(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(testStrictConfig)
+	err := DecodeJSON([]byte(`{"Port": 1, "users": [{"name": "a"}], "servers": {"s": {"name": "b"}}, "extra": {"x": 1}}`), config)
+	assert.Error(err).IsNil()
+	assert.Int(config.Port).Equals(1)
+	assert.String(config.Users[0].Name).Equals("a")
+	assert.String(config.Servers["s"].Name).Equals("b")
+
+	err = DecodeJSON([]byte(`{"prot": 1}`), new(testStrictConfig))
+	unknown, ok := err.(*UnknownFieldError)
+	assert.Bool(ok).IsTrue()
+	assert.String(unknown.Field()).Equals("prot")
+	assert.String(unknown.Suggestion).Equals("port")
+
+	err = DecodeJSON([]byte(`{"users": [{"name": "a"}, {"nmae": "b"}]}`), new(testStrictConfig))
+	unknown, ok = err.(*UnknownFieldError)
+	assert.Bool(ok).IsTrue()
+	assert.String(unknown.Field()).Equals("users[1].nmae")
+	assert.String(unknown.Suggestion).Equals("name")
+
+	err = DecodeJSON([]byte(`{"servers": {"s": {"password": "b"}}}`), new(testStrictConfig))
+	unknown, ok = err.(*UnknownFieldError)
+	assert.Bool(ok).IsTrue()
+	assert.String(unknown.Field()).Equals("servers.s.password")
+	assert.String(unknown.Suggestion).Equals("")
+	assert.String(err.Error()).Equals(`Config: Unknown field "servers.s.password".`)
+
+	SetStrictJSON(false)
+	defer SetStrictJSON(true)
+	assert.Error(DecodeJSON([]byte(`{"prot": 1}`), new(testStrictConfig))).IsNil()
+}
+
+func TestCheckJSONFields(t *testing.T) {
+	assert := assert.On(t)
+
+	keys := []string{"email", "id"}
+	assert.Error(CheckJSONFields([]byte(`{"email": "a", "ID": "b"}`), keys)).IsNil()
+
+	err := CheckJSONFields([]byte(`{"emial": "a"}`), keys, "users", "[0]")
+	assert.String(err.Error()).Equals(`Config: Unknown field "users[0].emial". Did you mean "email"?`)
+}
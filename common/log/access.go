@@ -1,6 +1,8 @@
 package log
 
 import (
+	"time"
+
 	"v2ray.com/core/common/log/internal"
 )
 
@@ -12,27 +14,148 @@ const (
 	AccessRejected = AccessStatus("rejected")
 )
 
-var (
-	accessLoggerInstance internal.LogWriter = new(internal.NoOpLogWriter)
-)
+// AccessRecord is an access log in JSON. Accepted connections are recorded when they are closed, so that the outbound
+// and the traffic are known.
+type AccessRecord struct {
+	Time        time.Time    `json:"time"`
+	Source      string       `json:"source"`
+	Destination string       `json:"destination,omitempty"`
+	Hostname    string       `json:"hostname,omitempty"`
+	Status      AccessStatus `json:"status"`
+	Reason      string       `json:"reason,omitempty"`
+	InboundTag  string       `json:"inbound,omitempty"`
+	OutboundTag string       `json:"outbound,omitempty"`
+	User        string       `json:"user,omitempty"`
+	// SniffedDomain is the HTTP host or TLS server name sniffed from the first payload.
+	SniffedDomain string `json:"domain,omitempty"`
+	Uplink        int64  `json:"uplink"`
+	Downlink      int64  `json:"downlink"`
+	// Duration is in seconds.
+	Duration float64 `json:"duration"`
+}
 
 // InitAccessLogger initializes the access logger to write into the give file.
-func InitAccessLogger(file string) error {
-	logger, err := internal.NewFileLogWriter(file)
+func (this *Logger) InitAccessLogger(file string) error {
+	logger, err := internal.NewFileLogWriter(file, this.rotation)
 	if err != nil {
-		Error("Failed to create access logger on file (", file, "): ", file, err)
+		this.Error("Failed to create access logger on file (", file, "): ", file, err)
 		return err
 	}
-	accessLoggerInstance = logger
+	this.accessLogger = logger
+	this.jsonAccess = false
 	return nil
 }
 
-// Access writes an access log.
-func Access(from, to interface{}, status AccessStatus, reason interface{}) {
-	accessLoggerInstance.Log(&internal.AccessLog{
+// InitAccessLogHandler sends access logs to the handler as info logs, e.g. to write them to the system log. Accepted
+// connections are sent as AccessRecords in JSON if json is true.
+func (this *Logger) InitAccessLogHandler(handler ErrorLogHandler, json bool) {
+	this.accessLogger = &handlerLogWriter{handler: handler}
+	this.jsonAccess = json
+}
+
+// InitJSONAccessLogger initializes the access logger to write AccessRecords into the given file, one JSON object per
+// line.
+func (this *Logger) InitJSONAccessLogger(file string) error {
+	logger, err := internal.NewRawFileLogWriter(file, this.rotation)
+	if err != nil {
+		this.Error("Failed to create access logger on file (", file, "): ", err)
+		return err
+	}
+	this.accessLogger = logger
+	this.jsonAccess = true
+	return nil
+}
+
+// AccessRecordEnabled returns true if access logs are in JSON, so that accepted connections are recorded by
+// AccessRecordLog when they are closed.
+func (this *Logger) AccessRecordEnabled() bool {
+	return this.orDefault().jsonAccess
+}
+
+// AccessHostname returns the hostname of the given destination found by the hostname lookup, if any.
+func (this *Logger) AccessHostname(to interface{}) string {
+	if lookup := this.orDefault().hostnameLookup; lookup != nil {
+		return lookup(to)
+	}
+	return ""
+}
+
+// AccessRecordLog writes an access log in JSON.
+func (this *Logger) AccessRecordLog(record *AccessRecord) {
+	this.orDefault().accessLogger.Log(&internal.JSONLog{Value: record})
+}
+
+// SetAccessHostnameLookup sets the function that returns the hostname of access destinations, which is logged after
+// the destination if not empty. It is called on the path of connections, so it must not block. Nil disables hostnames.
+func (this *Logger) SetAccessHostnameLookup(lookup func(to interface{}) string) {
+	this.hostnameLookup = lookup
+}
+
+// Access writes an access log. If access logs are in JSON, accepted connections are left to AccessRecordLog.
+func (this *Logger) Access(from, to interface{}, status AccessStatus, reason interface{}) {
+	this = this.orDefault()
+	if this.jsonAccess {
+		if status == AccessAccepted {
+			return
+		}
+		record := &AccessRecord{
+			Time:   time.Now(),
+			Source: internal.InterfaceToString(from),
+			Status: status,
+		}
+		if reason != nil {
+			record.Reason = internal.InterfaceToString(reason)
+		}
+		this.AccessRecordLog(record)
+		return
+	}
+	if hostname := this.AccessHostname(to); len(hostname) > 0 {
+		to = internal.InterfaceToString(to) + " (" + hostname + ")"
+	}
+	this.accessLogger.Log(&internal.AccessLog{
 		From:   from,
 		To:     to,
 		Status: string(status),
 		Reason: reason,
 	})
 }
+
+// InitAccessLogger initializes the access logger of the default logger to write into the give file.
+func InitAccessLogger(file string) error {
+	return Default().InitAccessLogger(file)
+}
+
+// InitAccessLogHandler sends access logs of the default logger to the handler.
+func InitAccessLogHandler(handler ErrorLogHandler, json bool) {
+	Default().InitAccessLogHandler(handler, json)
+}
+
+// InitJSONAccessLogger initializes the access logger of the default logger to write AccessRecords into the given file.
+func InitJSONAccessLogger(file string) error {
+	return Default().InitJSONAccessLogger(file)
+}
+
+// AccessRecordEnabled returns true if access logs of the default logger are in JSON.
+func AccessRecordEnabled() bool {
+	return Default().AccessRecordEnabled()
+}
+
+// AccessHostname returns the hostname of the given destination found by the hostname lookup of the default logger.
+func AccessHostname(to interface{}) string {
+	return Default().AccessHostname(to)
+}
+
+// AccessRecordLog writes an access log in JSON into the default logger.
+func AccessRecordLog(record *AccessRecord) {
+	Default().AccessRecordLog(record)
+}
+
+// SetAccessHostnameLookup sets the hostname lookup of the default logger.
+func SetAccessHostnameLookup(lookup func(to interface{}) string) {
+	Default().SetAccessHostnameLookup(lookup)
+}
+
+// Access writes an access log into the default logger.
+func Access(from, to interface{}, status AccessStatus, reason interface{}) {
+	Default().Access(from, to, status, reason)
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -70,3 +71,20 @@ func (this *AccessLog) Release() {
 func (this *AccessLog) String() string {
 	return strings.Join([]string{InterfaceToString(this.From), this.Status, InterfaceToString(this.To), InterfaceToString(this.Reason)}, " ")
 }
+
+// JSONLog is a log entry written as a JSON object.
+type JSONLog struct {
+	Value interface{}
+}
+
+func (this *JSONLog) Release() {
+	this.Value = nil
+}
+
+func (this *JSONLog) String() string {
+	content, err := json.Marshal(this.Value)
+	if err != nil {
+		return `{"error":` + fmt.Sprintf("%q", err.Error()) + `}`
+	}
+	return string(content)
+}
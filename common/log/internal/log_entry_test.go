@@ -23,3 +23,15 @@ func TestAccessLog(t *testing.T) {
 	assert.String(entryStr).Contains("test_reason")
 	assert.String(entryStr).Contains("Accepted")
 }
+
+func TestJSONLog(t *testing.T) {
+	assert := assert.On(t)
+
+	entry := &JSONLog{
+		Value: struct {
+			Source string `json:"source"`
+			Uplink int64  `json:"uplink"`
+		}{"test_from", 1024},
+	}
+	assert.String(entry.String()).Equals(`{"source":"test_from","uplink":1024}`)
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"v2ray.com/core/common/platform"
@@ -46,18 +47,26 @@ func (this *StdOutLogWriter) Close() {
 }
 
 type FileLogWriter struct {
+	// access guards closed, so that logs written after Close are dropped, e.g. by connections that outlive their
+	// instance.
+	access sync.RWMutex
+	closed bool
 	queue  chan string
 	logger *log.Logger
-	file   *os.File
+	file   *rotatingFile
 	cancel *signal.CancelSignal
 }
 
 func (this *FileLogWriter) Log(log LogEntry) {
-	select {
-	case this.queue <- log.String():
-	default:
-		// We don't expect this to happen, but don't want to block main thread as well.
+	this.access.RLock()
+	if !this.closed {
+		select {
+		case this.queue <- log.String():
+		default:
+			// We don't expect this to happen, but don't want to block main thread as well.
+		}
 	}
+	this.access.RUnlock()
 	log.Release()
 }
 
@@ -73,19 +82,37 @@ func (this *FileLogWriter) run() {
 }
 
 func (this *FileLogWriter) Close() {
+	this.access.Lock()
+	if this.closed {
+		this.access.Unlock()
+		return
+	}
+	this.closed = true
 	close(this.queue)
+	this.access.Unlock()
+
 	<-this.cancel.WaitForDone()
 	this.file.Close()
 }
 
-func NewFileLogWriter(path string) (*FileLogWriter, error) {
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+// NewFileLogWriter returns a FileLogWriter that writes into the file of the given path, which is rotated as configured.
+func NewFileLogWriter(path string, rotation FileRotation) (*FileLogWriter, error) {
+	return newFileLogWriter(path, rotation, log.Ldate|log.Ltime)
+}
+
+// NewRawFileLogWriter returns a FileLogWriter that writes entries without timestamps, e.g. for entries in JSON.
+func NewRawFileLogWriter(path string, rotation FileRotation) (*FileLogWriter, error) {
+	return newFileLogWriter(path, rotation, 0)
+}
+
+func newFileLogWriter(path string, rotation FileRotation, flags int) (*FileLogWriter, error) {
+	file, err := openRotatingFile(path, rotation)
 	if err != nil {
 		return nil, err
 	}
 	logger := &FileLogWriter{
 		queue:  make(chan string, 16),
-		logger: log.New(file, "", log.Ldate|log.Ltime),
+		logger: log.New(file, "", flags),
 		file:   file,
 		cancel: signal.NewCloseSignal(),
 	}
@@ -1 +1,83 @@
 package internal_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/common/log/internal"
+	"v2ray.com/core/testing/assert"
+)
+
+func writeLogs(assert *assert.Assert, path string, rotation FileRotation, entries ...string) []string {
+	writer, err := NewRawFileLogWriter(path, rotation)
+	assert.Error(err).IsNil()
+	for _, entry := range entries {
+		writer.Log(&JSONLog{Value: entry})
+		// Rotated files are named by time in milliseconds.
+		time.Sleep(10 * time.Millisecond)
+	}
+	writer.Close()
+
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	assert.Error(err).IsNil()
+	backups := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.Name() != filepath.Base(path) {
+			backups = append(backups, file.Name())
+		}
+	}
+	return backups
+}
+
+func TestFileLogRotationBySize(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-log")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	backups := writeLogs(assert, path, FileRotation{MaxSize: 16, MaxBackups: 2}, "first", "second", "third", "fourth")
+	assert.Int(len(backups)).Equals(2)
+
+	content, err := ioutil.ReadFile(path)
+	assert.Error(err).IsNil()
+	assert.String(string(content)).Equals("\"fourth\"\n")
+}
+
+func TestFileLogRotationByAge(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-log")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "error.log")
+	backups := writeLogs(assert, path, FileRotation{MaxAge: time.Millisecond, Compress: true}, "first", "second")
+	assert.Int(len(backups)).Equals(1)
+	assert.Bool(strings.HasSuffix(backups[0], ".gz")).IsTrue()
+}
+
+func TestFileLogAfterClose(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-log")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	writer, err := NewRawFileLogWriter(path, FileRotation{})
+	assert.Error(err).IsNil()
+	writer.Log(&JSONLog{Value: "first"})
+	writer.Close()
+	writer.Log(&JSONLog{Value: "second"})
+	writer.Close()
+
+	content, err := ioutil.ReadFile(path)
+	assert.Error(err).IsNil()
+	assert.String(string(content)).Equals("\"first\"\n")
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "20060102-150405.000"
+	compressedSuffix = ".gz"
+)
+
+// FileRotation configures the rotation of log files. Zero values disable the corresponding limit.
+type FileRotation struct {
+	// MaxSize is the size in bytes after which the file is rotated.
+	MaxSize int64
+	// MaxAge is the time after which the file is rotated.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep. All of them are kept if zero.
+	MaxBackups int
+	// Compress enables compressing rotated files with gzip.
+	Compress bool
+}
+
+// rotatingFile is a log file that is renamed to a backup with the time as suffix, and reopened, once it grows larger or
+// older than allowed.
+type rotatingFile struct {
+	path     string
+	rotation FileRotation
+	file     *os.File
+	size     int64
+	opened   time.Time
+	// cleaning serializes compressing and removing backups, which run in background.
+	cleaning sync.Mutex
+	cleaners sync.WaitGroup
+}
+
+func openRotatingFile(path string, rotation FileRotation) (*rotatingFile, error) {
+	file := &rotatingFile{
+		path:     path,
+		rotation: rotation,
+	}
+	if err := file.open(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (this *rotatingFile) open() error {
+	file, err := os.OpenFile(this.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	this.file = file
+	this.size = info.Size()
+	this.opened = time.Now()
+	return nil
+}
+
+func (this *rotatingFile) shouldRotate(size int) bool {
+	if this.size == 0 {
+		return false
+	}
+	if this.rotation.MaxSize > 0 && this.size+int64(size) > this.rotation.MaxSize {
+		return true
+	}
+	return this.rotation.MaxAge > 0 && time.Since(this.opened) >= this.rotation.MaxAge
+}
+
+func (this *rotatingFile) Write(b []byte) (int, error) {
+	if this.shouldRotate(len(b)) {
+		if err := this.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := this.file.Write(b)
+	this.size += int64(n)
+	return n, err
+}
+
+func (this *rotatingFile) rotate() error {
+	this.file.Close()
+	backup := this.path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(this.path, backup); err != nil {
+		// Keep writing into the current file rather than losing logs.
+		return this.open()
+	}
+	if err := this.open(); err != nil {
+		return err
+	}
+	this.cleaners.Add(1)
+	go this.clean(backup)
+	return nil
+}
+
+func (this *rotatingFile) clean(backup string) {
+	defer this.cleaners.Done()
+	this.cleaning.Lock()
+	defer this.cleaning.Unlock()
+
+	if this.rotation.Compress {
+		compressFile(backup)
+	}
+	if this.rotation.MaxBackups > 0 {
+		backups := this.backups()
+		for len(backups) > this.rotation.MaxBackups {
+			os.Remove(backups[0])
+			backups = backups[1:]
+		}
+	}
+}
+
+// backups returns the rotated files of this file, from the oldest to the newest.
+func (this *rotatingFile) backups() []string {
+	dir, base := filepath.Split(this.path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	file, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	names, err := file.Readdirnames(-1)
+	file.Close()
+	if err != nil {
+		return nil
+	}
+	backups := make([]string, 0, len(names))
+	for _, name := range names {
+		if !strings.HasPrefix(name, base+".") {
+			continue
+		}
+		suffix := strings.TrimSuffix(strings.TrimPrefix(name, base+"."), compressedSuffix)
+		if _, err := time.Parse(backupTimeFormat, suffix); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	// The time format sorts in time order.
+	sort.Strings(backups)
+	return backups
+}
+
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+compressedSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(target)
+	if _, err := io.Copy(writer, source); err != nil {
+		target.Close()
+		os.Remove(path + compressedSuffix)
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		target.Close()
+		os.Remove(path + compressedSuffix)
+		return err
+	}
+	if err := target.Close(); err != nil {
+		os.Remove(path + compressedSuffix)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close closes the file after the rotated files are cleaned.
+func (this *rotatingFile) Close() error {
+	this.cleaners.Wait()
+	return this.file.Close()
+}
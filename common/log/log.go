@@ -1,6 +1,12 @@
 package log
 
 import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common/errors"
 	"v2ray.com/core/common/log/internal"
 )
 
@@ -14,84 +20,398 @@ const (
 	NoneLevel    = LogLevel(999)
 )
 
+// Rotation configures the rotation of log files. Zero values disable the corresponding limit.
+type Rotation struct {
+	// MaxSize is the size in bytes after which a log file is rotated.
+	MaxSize int64
+	// MaxAge is the time after which a log file is rotated.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep for each log file. All of them are kept if zero.
+	MaxBackups int
+	// Compress enables compressing rotated files with gzip.
+	Compress bool
+}
+
+// Logger writes error logs and access logs of an instance. Instances in one process have their own loggers, so
+// that their log files and levels don't interfere. Methods of a nil Logger use the default logger.
+type Logger struct {
+	stream internal.LogWriter
+	// ownStream is false if stream is shared with the logger that this one is created from, so that it is not closed.
+	ownStream bool
+
+	debugLogger   internal.LogWriter
+	infoLogger    internal.LogWriter
+	warningLogger internal.LogWriter
+	errorLogger   internal.LogWriter
+
+	rotation internal.FileRotation
+
+	// moduleLevels are the levels of modules that override the global level, by lower case module names.
+	moduleLevels map[string]LogLevel
+
+	accessLogger   internal.LogWriter
+	hostnameLookup func(to interface{}) string
+	jsonAccess     bool
+}
+
+// NewLogger returns a Logger that writes all error logs to stdout, and no access logs.
+func NewLogger() *Logger {
+	logger := newLogger(internal.NewStdOutLogWriter())
+	logger.ownStream = true
+	return logger
+}
+
+// NewLoggerFrom returns a Logger that writes all error logs into the destination of parent, until it is initialized
+// with its own, and no access logs. Closing it doesn't close the destination of parent.
+func NewLoggerFrom(parent *Logger) *Logger {
+	return newLogger(parent.orDefault().stream)
+}
+
+func newLogger(stream internal.LogWriter) *Logger {
+	return &Logger{
+		stream:        stream,
+		debugLogger:   stream,
+		infoLogger:    stream,
+		warningLogger: stream,
+		errorLogger:   stream,
+		accessLogger:  new(internal.NoOpLogWriter),
+	}
+}
+
 var (
-	streamLoggerInstance internal.LogWriter = internal.NewStdOutLogWriter()
+	defaultLogger atomic.Value
 
-	debugLogger   internal.LogWriter = streamLoggerInstance
-	infoLogger    internal.LogWriter = streamLoggerInstance
-	warningLogger internal.LogWriter = streamLoggerInstance
-	errorLogger   internal.LogWriter = streamLoggerInstance
+	instanceAccess sync.Mutex
+	// baseLogger is the default logger unless there is exactly one instance with a logger.
+	baseLogger *Logger
+	// instanceLoggers are the loggers of the instances in the process, nil for instances without one.
+	instanceLoggers []*Logger
 )
 
-func SetLogLevel(level LogLevel) {
-	debugLogger = new(internal.NoOpLogWriter)
+func init() {
+	baseLogger = NewLogger()
+	defaultLogger.Store(baseLogger)
+}
+
+// Default returns the default logger, which is used by code that doesn't belong to an instance, and by the functions
+// of this package.
+func Default() *Logger {
+	return defaultLogger.Load().(*Logger)
+}
+
+// Base returns the logger that is the default one while no single instance logger takes its place. Loggers of
+// instances write into its destination until they have their own.
+func Base() *Logger {
+	instanceAccess.Lock()
+	defer instanceAccess.Unlock()
+
+	return baseLogger
+}
+
+// SetDefault replaces the base logger. It is the default logger, unless the only instance in the process has a
+// logger. The previous one is not closed.
+func SetDefault(logger *Logger) {
+	if logger == nil {
+		return
+	}
+	instanceAccess.Lock()
+	defer instanceAccess.Unlock()
+
+	baseLogger = logger
+	updateDefault()
+}
+
+// AddInstance registers an instance with its logger, which is nil if the instance has none. While an instance is the
+// only one in the process, its logger is the default logger, so that logs of code shared by instances follow its
+// config.
+func AddInstance(logger *Logger) {
+	instanceAccess.Lock()
+	defer instanceAccess.Unlock()
+
+	instanceLoggers = append(instanceLoggers, logger)
+	updateDefault()
+}
+
+// RemoveInstance unregisters an instance added by AddInstance with the given logger.
+func RemoveInstance(logger *Logger) {
+	instanceAccess.Lock()
+	defer instanceAccess.Unlock()
+
+	for idx, instanceLogger := range instanceLoggers {
+		if instanceLogger == logger {
+			instanceLoggers = append(instanceLoggers[:idx], instanceLoggers[idx+1:]...)
+			break
+		}
+	}
+	updateDefault()
+}
+
+func updateDefault() {
+	if len(instanceLoggers) == 1 && instanceLoggers[0] != nil {
+		defaultLogger.Store(instanceLoggers[0])
+		return
+	}
+	defaultLogger.Store(baseLogger)
+}
+
+func (this *Logger) orDefault() *Logger {
+	if this == nil {
+		return Default()
+	}
+	return this
+}
+
+// SetRotation sets the rotation of log files initialized afterwards. Nil disables rotation.
+func (this *Logger) SetRotation(rotation *Rotation) {
+	if rotation == nil {
+		this.rotation = internal.FileRotation{}
+		return
+	}
+	this.rotation = internal.FileRotation{
+		MaxSize:    rotation.MaxSize,
+		MaxAge:     rotation.MaxAge,
+		MaxBackups: rotation.MaxBackups,
+		Compress:   rotation.Compress,
+	}
+}
+
+func (this *Logger) SetLogLevel(level LogLevel) {
+	this.debugLogger = new(internal.NoOpLogWriter)
 	if level <= DebugLevel {
-		debugLogger = streamLoggerInstance
+		this.debugLogger = this.stream
 	}
 
-	infoLogger = new(internal.NoOpLogWriter)
+	this.infoLogger = new(internal.NoOpLogWriter)
 	if level <= InfoLevel {
-		infoLogger = streamLoggerInstance
+		this.infoLogger = this.stream
 	}
 
-	warningLogger = new(internal.NoOpLogWriter)
+	this.warningLogger = new(internal.NoOpLogWriter)
 	if level <= WarningLevel {
-		warningLogger = streamLoggerInstance
+		this.warningLogger = this.stream
 	}
 
-	errorLogger = new(internal.NoOpLogWriter)
+	this.errorLogger = new(internal.NoOpLogWriter)
 	if level <= ErrorLevel {
-		errorLogger = streamLoggerInstance
+		this.errorLogger = this.stream
 	}
 
 	if level == NoneLevel {
-		accessLoggerInstance = new(internal.NoOpLogWriter)
+		this.accessLogger = new(internal.NoOpLogWriter)
 	}
 }
 
-func InitErrorLogger(file string) error {
-	logger, err := internal.NewFileLogWriter(file)
+func (this *Logger) InitErrorLogger(file string) error {
+	logger, err := internal.NewFileLogWriter(file, this.rotation)
 	if err != nil {
-		Error("Failed to create error logger on file (", file, "): ", err)
+		this.Error("Failed to create error logger on file (", file, "): ", err)
 		return err
 	}
-	streamLoggerInstance = logger
+	this.stream = logger
+	this.ownStream = true
 	return nil
 }
 
-// Debug outputs a debug log with given format and optional arguments.
-func Debug(v ...interface{}) {
-	debugLogger.Log(&internal.ErrorLog{
-		Prefix: "[Debug]",
+// SetModuleLogLevels sets the levels of modules, which override the global level. Modules are the prefixes of log
+// messages before the colon, e.g. "Router" and "KCP|Connection", matched case-insensitively. A module also covers its
+// submodules, e.g. "KCP" for "KCP|Connection", unless they have their own levels. Nil clears all module levels.
+func (this *Logger) SetModuleLogLevels(levels map[string]LogLevel) {
+	copied := make(map[string]LogLevel, len(levels))
+	for module, level := range levels {
+		copied[strings.ToLower(module)] = level
+	}
+	this.moduleLevels = copied
+}
+
+// moduleLogLevel returns the level of the module of a log message, if set.
+func moduleLogLevel(levels map[string]LogLevel, v []interface{}) (LogLevel, bool) {
+	if len(v) == 0 {
+		return 0, false
+	}
+	message, ok := v[0].(string)
+	if !ok {
+		return 0, false
+	}
+	index := strings.Index(message, ":")
+	if index <= 0 {
+		return 0, false
+	}
+	module := strings.ToLower(message[:index])
+	for {
+		if level, found := levels[module]; found {
+			return level, true
+		}
+		index := strings.LastIndex(module, "|")
+		if index <= 0 {
+			return 0, false
+		}
+		module = module[:index]
+	}
+}
+
+// writeLog writes a log of the given level into logger, which is chosen by the global level, unless the module of the
+// log has its own level.
+func (this *Logger) writeLog(level LogLevel, logger internal.LogWriter, prefix string, v []interface{}) {
+	if levels := this.moduleLevels; len(levels) > 0 {
+		if moduleLevel, found := moduleLogLevel(levels, v); found {
+			if level < moduleLevel {
+				return
+			}
+			logger = this.stream
+		}
+	}
+	logger.Log(&internal.ErrorLog{
+		Prefix: prefix,
 		Values: v,
 	})
 }
 
+// ErrorLogHandler handles an error log of the given level, e.g. by writing it to the system log.
+type ErrorLogHandler func(level LogLevel, message string)
+
+var (
+	logLevelPrefixes = []struct {
+		prefix string
+		level  LogLevel
+	}{
+		{"[Debug]", DebugLevel},
+		{"[Info]", InfoLevel},
+		{"[Warning]", WarningLevel},
+		{"[Error]", ErrorLevel},
+	}
+)
+
+type handlerLogWriter struct {
+	handler ErrorLogHandler
+}
+
+func (this *handlerLogWriter) Log(entry internal.LogEntry) {
+	message := entry.String()
+	entry.Release()
+	level := InfoLevel
+	for _, prefix := range logLevelPrefixes {
+		if strings.HasPrefix(message, prefix.prefix) {
+			level = prefix.level
+			message = message[len(prefix.prefix):]
+			break
+		}
+	}
+	this.handler(level, message)
+}
+
+func (this *handlerLogWriter) Close() {
+}
+
+// InitErrorLogHandler sends error logs to the handler instead of stdout or a file. It takes effect on the next
+// SetLogLevel.
+func (this *Logger) InitErrorLogHandler(handler ErrorLogHandler) {
+	this.stream = &handlerLogWriter{handler: handler}
+	this.ownStream = true
+}
+
+// Debug outputs a debug log with given format and optional arguments.
+func (this *Logger) Debug(v ...interface{}) {
+	this = this.orDefault()
+	this.writeLog(DebugLevel, this.debugLogger, "[Debug]", v)
+}
+
 // Info outputs an info log with given format and optional arguments.
-func Info(v ...interface{}) {
-	infoLogger.Log(&internal.ErrorLog{
-		Prefix: "[Info]",
-		Values: v,
-	})
+func (this *Logger) Info(v ...interface{}) {
+	this = this.orDefault()
+	this.writeLog(InfoLevel, this.infoLogger, "[Info]", v)
 }
 
 // Warning outputs a warning log with given format and optional arguments.
-func Warning(v ...interface{}) {
-	warningLogger.Log(&internal.ErrorLog{
-		Prefix: "[Warning]",
-		Values: v,
-	})
+func (this *Logger) Warning(v ...interface{}) {
+	this = this.orDefault()
+	this.writeLog(WarningLevel, this.warningLogger, "[Warning]", v)
 }
 
 // Error outputs an error log with given format and optional arguments.
+func (this *Logger) Error(v ...interface{}) {
+	this = this.orDefault()
+	this.writeLog(ErrorLevel, this.errorLogger, "[Error]", v)
+}
+
+// Err outputs a log of err at the level of its severity, after the given message.
+func (this *Logger) Err(err error, v ...interface{}) {
+	v = append(v, err)
+	switch errors.SeverityOf(err) {
+	case errors.SeverityDebug:
+		this.Debug(v...)
+	case errors.SeverityInfo:
+		this.Info(v...)
+	case errors.SeverityError:
+		this.Error(v...)
+	default:
+		this.Warning(v...)
+	}
+}
+
+// Close closes the log files of the logger. Logs written into closed files afterwards are dropped.
+func (this *Logger) Close() {
+	if this.ownStream {
+		this.stream.Close()
+	}
+	this.accessLogger.Close()
+}
+
+// Release closes the logger, when the logger is bound in the space of an instance.
+func (this *Logger) Release() {
+	this.Close()
+}
+
+// SetRotation sets the rotation of log files of the base logger initialized afterwards.
+func SetRotation(rotation *Rotation) {
+	Base().SetRotation(rotation)
+}
+
+// SetLogLevel sets the level of the base logger.
+func SetLogLevel(level LogLevel) {
+	Base().SetLogLevel(level)
+}
+
+// InitErrorLogger sends error logs of the base logger to the file.
+func InitErrorLogger(file string) error {
+	return Base().InitErrorLogger(file)
+}
+
+// SetModuleLogLevels sets the levels of modules of the base logger.
+func SetModuleLogLevels(levels map[string]LogLevel) {
+	Base().SetModuleLogLevels(levels)
+}
+
+// InitErrorLogHandler sends error logs of the base logger to the handler.
+func InitErrorLogHandler(handler ErrorLogHandler) {
+	Base().InitErrorLogHandler(handler)
+}
+
+// Debug outputs a debug log into the default logger.
+func Debug(v ...interface{}) {
+	Default().Debug(v...)
+}
+
+// Info outputs an info log into the default logger.
+func Info(v ...interface{}) {
+	Default().Info(v...)
+}
+
+// Warning outputs a warning log into the default logger.
+func Warning(v ...interface{}) {
+	Default().Warning(v...)
+}
+
+// Error outputs an error log into the default logger.
 func Error(v ...interface{}) {
-	errorLogger.Log(&internal.ErrorLog{
-		Prefix: "[Error]",
-		Values: v,
-	})
+	Default().Error(v...)
+}
+
+// Err outputs a log of err into the default logger, at the level of its severity.
+func Err(err error, v ...interface{}) {
+	Default().Err(err, v...)
 }
 
+// Close closes the base logger.
 func Close() {
-	streamLoggerInstance.Close()
-	accessLoggerInstance.Close()
+	Base().Close()
 }
@@ -0,0 +1,94 @@
+package log_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/common/log"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestModuleLogLevels(t *testing.T) {
+	assert := assert.On(t)
+
+	var messages []string
+	InitErrorLogHandler(func(level LogLevel, message string) {
+		messages = append(messages, message)
+	})
+	SetLogLevel(WarningLevel)
+	SetModuleLogLevels(map[string]LogLevel{
+		"Router":       DebugLevel,
+		"kcp":          ErrorLevel,
+		"KCP|Listener": InfoLevel,
+	})
+	defer SetModuleLogLevels(nil)
+
+	Debug("Router: a")
+	Debug("Router|GeoIP: b")
+	Info("DNS: c")
+	Warning("KCP|Connection: d")
+	Info("KCP|Listener: e")
+	Warning("Point: f")
+	Error("KCP: g")
+
+	assert.Int(len(messages)).Equals(5)
+	assert.String(messages[0]).Equals("Router: a")
+	assert.String(messages[1]).Equals("Router|GeoIP: b")
+	assert.String(messages[2]).Equals("KCP|Listener: e")
+	assert.String(messages[3]).Equals("Point: f")
+	assert.String(messages[4]).Equals("KCP: g")
+}
+
+func TestLoggerIsolation(t *testing.T) {
+	assert := assert.On(t)
+
+	var first, second []string
+	logger1 := NewLogger()
+	logger1.InitErrorLogHandler(func(level LogLevel, message string) {
+		first = append(first, message)
+	})
+	logger1.SetLogLevel(InfoLevel)
+	logger2 := NewLoggerFrom(logger1)
+	logger2.SetLogLevel(ErrorLevel)
+
+	logger1.Info("Point: a")
+	logger2.Info("Point: b")
+	logger2.Error("Point: c")
+	assert.Int(len(first)).Equals(2)
+	assert.String(first[0]).Equals("Point: a")
+	assert.String(first[1]).Equals("Point: c")
+
+	// A logger with its own destination doesn't write into the one it is created from.
+	logger2.InitErrorLogHandler(func(level LogLevel, message string) {
+		second = append(second, message)
+	})
+	logger2.SetLogLevel(InfoLevel)
+	logger2.Info("Point: d")
+	assert.Int(len(first)).Equals(2)
+	assert.Int(len(second)).Equals(1)
+}
+
+func TestInstanceDefault(t *testing.T) {
+	assert := assert.On(t)
+
+	base := Default()
+	logger1 := NewLogger()
+	logger2 := NewLogger()
+
+	AddInstance(logger1)
+	assert.Bool(Default() == logger1).IsTrue()
+	assert.Bool(Base() == base).IsTrue()
+
+	// Logs outside instances can't tell which of two instances they belong to.
+	AddInstance(logger2)
+	assert.Bool(Default() == base).IsTrue()
+
+	RemoveInstance(logger1)
+	assert.Bool(Default() == logger2).IsTrue()
+	RemoveInstance(logger2)
+	assert.Bool(Default() == base).IsTrue()
+
+	// An instance without a logger logs into the base logger.
+	AddInstance(nil)
+	assert.Bool(Default() == base).IsTrue()
+	RemoveInstance(nil)
+}
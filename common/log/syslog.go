@@ -0,0 +1,177 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSyslogUnavailable = errors.New("Log: Local syslog is unavailable.")
+
+	// localSyslogPaths are the sockets of the local syslog daemon on common systems.
+	localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+	syslogFacilities = map[string]int{
+		"kern":     0,
+		"user":     1,
+		"mail":     2,
+		"daemon":   3,
+		"auth":     4,
+		"syslog":   5,
+		"lpr":      6,
+		"news":     7,
+		"uucp":     8,
+		"cron":     9,
+		"authpriv": 10,
+		"ftp":      11,
+		"local0":   16,
+		"local1":   17,
+		"local2":   18,
+		"local3":   19,
+		"local4":   20,
+		"local5":   21,
+		"local6":   22,
+		"local7":   23,
+	}
+)
+
+const (
+	SyslogFacilityDaemon = 3
+
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// SyslogFacility returns the facility code of the given name, e.g. "daemon" or "local0".
+func SyslogFacility(name string) (int, bool) {
+	facility, found := syslogFacilities[strings.ToLower(name)]
+	return facility, found
+}
+
+// SyslogWriter sends logs in RFC 5424 to the local syslog daemon, or a remote one over UDP or TCP.
+type SyslogWriter struct {
+	sync.Mutex
+	network  string
+	address  string
+	tag      string
+	facility int
+	hostname string
+	conn     net.Conn
+}
+
+// NewSyslogWriter returns a SyslogWriter that sends logs of the given facility, with tag as the app name. Network is
+// "udp" or "tcp" for remote syslog, or empty for the local one.
+func NewSyslogWriter(network string, address string, tag string, facility int) (*SyslogWriter, error) {
+	hostname, err := os.Hostname()
+	if err != nil || len(hostname) == 0 {
+		hostname = "-"
+	}
+	if len(tag) == 0 {
+		tag = "v2ray"
+	}
+	writer := &SyslogWriter{
+		network:  network,
+		address:  address,
+		tag:      tag,
+		facility: facility,
+		hostname: hostname,
+	}
+	if err := writer.connect(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (this *SyslogWriter) connect() error {
+	if this.conn != nil {
+		this.conn.Close()
+		this.conn = nil
+	}
+	if len(this.network) > 0 {
+		conn, err := net.Dial(this.network, this.address)
+		if err != nil {
+			return err
+		}
+		this.conn = conn
+		return nil
+	}
+	for _, path := range localSyslogPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				this.conn = conn
+				return nil
+			}
+		}
+	}
+	return ErrSyslogUnavailable
+}
+
+func syslogSeverity(level LogLevel) int {
+	switch level {
+	case DebugLevel:
+		return 7
+	case InfoLevel:
+		return 6
+	case WarningLevel:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// format returns the message in RFC 5424, without structured data.
+func (this *SyslogWriter) format(level LogLevel, message string) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d - - %s", this.facility*8+syslogSeverity(level),
+		time.Now().Format(syslogTimeFormat), this.hostname, this.tag, os.Getpid(), strings.TrimSpace(message))
+}
+
+func (this *SyslogWriter) send(message string) error {
+	if this.conn == nil {
+		return ErrSyslogUnavailable
+	}
+	if this.network == "tcp" {
+		// Messages over TCP are framed by octet counting, as in RFC 6587.
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+	_, err := this.conn.Write([]byte(message))
+	return err
+}
+
+// Write sends a log of the given level. It reconnects once if the connection is broken.
+func (this *SyslogWriter) Write(level LogLevel, message string) error {
+	message = this.format(level, message)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.send(message); err == nil {
+		return nil
+	}
+	if err := this.connect(); err != nil {
+		return err
+	}
+	return this.send(message)
+}
+
+// Handler returns an ErrorLogHandler that writes logs into syslog.
+func (this *SyslogWriter) Handler() ErrorLogHandler {
+	return func(level LogLevel, message string) {
+		this.Write(level, message)
+	}
+}
+
+func (this *SyslogWriter) Close() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.conn == nil {
+		return nil
+	}
+	err := this.conn.Close()
+	this.conn = nil
+	return err
+}
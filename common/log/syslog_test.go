@@ -0,0 +1,38 @@
+package log_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/common/log"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestSyslogWriter(t *testing.T) {
+	assert := assert.On(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	facility, found := SyslogFacility("local0")
+	assert.Bool(found).IsTrue()
+
+	writer, err := NewSyslogWriter("udp", conn.LocalAddr().String(), "test", facility)
+	assert.Error(err).IsNil()
+	defer writer.Close()
+
+	writer.Handler()(WarningLevel, " Something happened.")
+
+	buffer := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buffer)
+	assert.Error(err).IsNil()
+	message := string(buffer[:n])
+	// local0 (16) * 8 + warning (4)
+	assert.Bool(strings.HasPrefix(message, "<132>1 ")).IsTrue()
+	assert.String(message).Contains(" test ")
+	assert.Bool(strings.HasSuffix(message, " - - Something happened.")).IsTrue()
+}
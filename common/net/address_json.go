@@ -25,3 +25,12 @@ func (this *AddressPB) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *AddressPB) MarshalJSON() ([]byte, error) {
+	address := this.AsAddress()
+	if address.Family().IsDomain() {
+		return json.Marshal(address.Domain())
+	}
+	return json.Marshal(address.IP().String())
+}
@@ -0,0 +1,95 @@
+package net
+
+import (
+	"net"
+	"strings"
+
+	"v2ray.com/core/common/errors"
+)
+
+var (
+	ErrSourceNotAllowed = errors.New("Source address is not allowed.").Path("Net").WithCode(errors.CodePermissionDenied).WithSeverity(errors.SeverityInfo)
+)
+
+// IPFilter allows or denies source IPs by lists of CIDRs.
+type IPFilter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, errors.New("Net: Invalid IP: " + cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.New("Net: Invalid CIDR: " + cidr)
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// NewIPFilter returns a filter that allows IPs in the allow list, or any IP if the list is empty, except the ones in
+// the deny list. Entries are CIDRs or single IPs. It returns nil if both lists are empty.
+func NewIPFilter(allow []string, deny []string) (*IPFilter, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	allowNets, err := parseCIDRs(allow)
+	if err != nil {
+		return nil, err
+	}
+	denyNets, err := parseCIDRs(deny)
+	if err != nil {
+		return nil, err
+	}
+	return &IPFilter{
+		allow: allowNets,
+		deny:  denyNets,
+	}, nil
+}
+
+func containsIP(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows returns true if the IP passes the filter. A nil filter allows all IPs.
+func (this *IPFilter) Allows(ip net.IP) bool {
+	if this == nil {
+		return true
+	}
+	if containsIP(this.deny, ip) {
+		return false
+	}
+	return len(this.allow) == 0 || containsIP(this.allow, ip)
+}
+
+// AllowsAddr is the same as Allows, for the IP of a TCP or UDP address. Addresses of other kinds are allowed.
+func (this *IPFilter) AllowsAddr(addr net.Addr) bool {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return this.Allows(addr.IP)
+	case *net.UDPAddr:
+		return this.Allows(addr.IP)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,35 @@
+package net_test
+
+import (
+	"net"
+	"testing"
+
+	. "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestIPFilter(t *testing.T) {
+	assert := assert.On(t)
+
+	filter, err := NewIPFilter([]string{"192.168.0.0/16", "fd00::/8", "10.0.0.1"}, []string{"192.168.1.0/24"})
+	assert.Error(err).IsNil()
+
+	assert.Bool(filter.Allows(net.ParseIP("192.168.2.3"))).IsTrue()
+	assert.Bool(filter.Allows(net.ParseIP("192.168.1.3"))).IsFalse()
+	assert.Bool(filter.Allows(net.ParseIP("10.0.0.1"))).IsTrue()
+	assert.Bool(filter.Allows(net.ParseIP("10.0.0.2"))).IsFalse()
+	assert.Bool(filter.Allows(net.ParseIP("fd12::1"))).IsTrue()
+	assert.Bool(filter.AllowsAddr(&net.TCPAddr{IP: net.ParseIP("8.8.8.8"), Port: 53})).IsFalse()
+
+	deny, err := NewIPFilter(nil, []string{"8.8.8.8"})
+	assert.Error(err).IsNil()
+	assert.Bool(deny.Allows(net.ParseIP("8.8.4.4"))).IsTrue()
+	assert.Bool(deny.Allows(net.ParseIP("8.8.8.8"))).IsFalse()
+
+	none, err := NewIPFilter(nil, nil)
+	assert.Error(err).IsNil()
+	assert.Bool(none.Allows(net.ParseIP("8.8.8.8"))).IsTrue()
+
+	_, err = NewIPFilter([]string{"256.0.0.0/8"}, nil)
+	assert.Error(err).IsNotNil()
+}
@@ -10,7 +10,7 @@ func ParseNetwork(nwStr string) Network {
 	if network, found := Network_value[nwStr]; found {
 		return Network(network)
 	}
-	switch strings.ToLower(nwStr) {
+	switch strings.ToLower(strings.TrimSpace(nwStr)) {
 	case "tcp":
 		return Network_TCP
 	case "udp":
@@ -70,7 +70,7 @@ func NewNetworkList(networks collect.StringList) *NetworkList {
 // HashNetwork returns true if the given network is in this NetworkList.
 func (this *NetworkList) HasNetwork(network Network) bool {
 	for _, value := range this.Network {
-		if string(value) == string(network) {
+		if value == network {
 			return true
 		}
 	}
@@ -16,3 +16,12 @@ func (this *NetworkList) UnmarshalJSON(data []byte) error {
 	*this = *NewNetworkList(strlist)
 	return nil
 }
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *NetworkList) MarshalJSON() ([]byte, error) {
+	networks := make([]string, len(this.Network))
+	for idx, network := range this.Network {
+		networks[idx] = network.String()
+	}
+	return json.Marshal(networks)
+}
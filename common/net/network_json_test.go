@@ -37,3 +37,13 @@ func TestInvalidNetworkJson(t *testing.T) {
 	err := json.Unmarshal([]byte("0"), &list)
 	assert.Error(err).IsNotNil()
 }
+
+func TestNetworkListWithSpaces(t *testing.T) {
+	assert := assert.On(t)
+
+	var list NetworkList
+	err := json.Unmarshal([]byte("\"tcp, udp\""), &list)
+	assert.Error(err).IsNil()
+	assert.Bool(list.HasNetwork(Network_TCP)).IsTrue()
+	assert.Bool(list.HasNetwork(Network_UDP)).IsTrue()
+}
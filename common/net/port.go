@@ -67,3 +67,28 @@ func (this PortRange) ToPort() Port {
 func (this PortRange) Contains(port Port) bool {
 	return this.FromPort() <= port && port <= this.ToPort()
 }
+
+// SinglePortRange returns a PortRange contains a single port.
+func SinglePortRange(port Port) *PortRange {
+	return &PortRange{
+		From: uint32(port),
+		To:   uint32(port),
+	}
+}
+
+// NewPortList creates a PortList from the given ranges.
+func NewPortList(ranges ...*PortRange) *PortList {
+	return &PortList{
+		Range: ranges,
+	}
+}
+
+// Contains returns true if the given port is within any range of this PortList.
+func (this *PortList) Contains(port Port) bool {
+	for _, r := range this.Range {
+		if r.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
@@ -24,22 +24,41 @@ func (m *PortRange) String() string            { return proto.CompactTextString(
 func (*PortRange) ProtoMessage()               {}
 func (*PortRange) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{0} }
 
+// PortList is a list of port ranges.
+type PortList struct {
+	Range []*PortRange `protobuf:"bytes,1,rep,name=range" json:"range,omitempty"`
+}
+
+func (m *PortList) Reset()                    { *m = PortList{} }
+func (m *PortList) String() string            { return proto.CompactTextString(m) }
+func (*PortList) ProtoMessage()               {}
+func (*PortList) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{1} }
+
+func (m *PortList) GetRange() []*PortRange {
+	if m != nil {
+		return m.Range
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*PortRange)(nil), "v2ray.core.common.net.PortRange")
+	proto.RegisterType((*PortList)(nil), "v2ray.core.common.net.PortList")
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/common/net/port.proto", fileDescriptor3) }
 
 var fileDescriptor3 = []byte{
-	// 148 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x52, 0x29, 0x33, 0x2a, 0x4a,
+	// 175 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x52, 0x29, 0x33, 0x2a, 0x4a,
 	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x4f, 0xce, 0xcf, 0xcd, 0xcd,
 	0xcf, 0xd3, 0xcf, 0x4b, 0x2d, 0xd1, 0x2f, 0xc8, 0x2f, 0x2a, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9,
 	0x17, 0x12, 0x85, 0xa9, 0x2a, 0x4a, 0xd5, 0x83, 0xa8, 0xd0, 0xcb, 0x4b, 0x2d, 0x51, 0xd2, 0xe7,
 	0xe2, 0x0c, 0xc8, 0x2f, 0x2a, 0x09, 0x4a, 0xcc, 0x4b, 0x4f, 0x15, 0x12, 0xe2, 0x62, 0x71, 0x2b,
-	0xca, 0xcf, 0x95, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0d, 0x62, 0x49, 0x2b, 0xca, 0xcf, 0x15, 0xe2,
-	0xe3, 0x62, 0x0a, 0xc9, 0x97, 0x60, 0x02, 0x8b, 0x30, 0x95, 0xe4, 0x3b, 0x69, 0x73, 0x49, 0x26,
-	0xe7, 0xe7, 0xea, 0x61, 0x35, 0xcd, 0x09, 0x6c, 0x56, 0x00, 0xc8, 0xbe, 0x28, 0xe6, 0xbc, 0xd4,
-	0x92, 0x24, 0x36, 0xb0, 0xdd, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x28, 0x7b, 0x9b, 0xb3,
-	0xa3, 0x00, 0x00, 0x00,
+	0xca, 0xcf, 0x95, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0d, 0x02, 0xb3, 0x85, 0xf8, 0xb8, 0x98, 0x42,
+	0xf2, 0x25, 0x98, 0xc0, 0x22, 0x4c, 0x21, 0xf9, 0x4a, 0x4e, 0x5c, 0x1c, 0x20, 0x0d, 0x3e, 0x99,
+	0xc5, 0x25, 0x42, 0x66, 0x5c, 0xac, 0x45, 0x20, 0x8d, 0x12, 0x8c, 0x0a, 0xcc, 0x1a, 0xdc, 0x46,
+	0x0a, 0x7a, 0x58, 0xed, 0xd0, 0x83, 0x5b, 0x10, 0x04, 0x51, 0xee, 0xa4, 0xcd, 0x25, 0x99, 0x9c,
+	0x9f, 0x8b, 0x5d, 0xb5, 0x13, 0xd8, 0x3d, 0x01, 0x20, 0x37, 0x47, 0x31, 0xe7, 0xa5, 0x96, 0x24,
+	0xb1, 0x81, 0xdd, 0x6f, 0x0c, 0x18, 0x00, 0x91, 0x70, 0x0f, 0x6b, 0xe7, 0x00, 0x00, 0x00,
 }
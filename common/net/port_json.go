@@ -4,6 +4,7 @@ package net
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 
 	"v2ray.com/core/common/log"
@@ -24,7 +25,11 @@ func parseStringPort(data []byte) (Port, Port, error) {
 	if err != nil {
 		return Port(0), Port(0), err
 	}
-	pair := strings.SplitN(s, "-", 2)
+	return parsePortRange(s)
+}
+
+func parsePortRange(s string) (Port, Port, error) {
+	pair := strings.SplitN(strings.TrimSpace(s), "-", 2)
 	if len(pair) == 0 {
 		return Port(0), Port(0), ErrInvalidPortRange
 	}
@@ -33,11 +38,11 @@ func parseStringPort(data []byte) (Port, Port, error) {
 		return port, port, err
 	}
 
-	fromPort, err := PortFromString(pair[0])
+	fromPort, err := PortFromString(strings.TrimSpace(pair[0]))
 	if err != nil {
 		return Port(0), Port(0), err
 	}
-	toPort, err := PortFromString(pair[1])
+	toPort, err := PortFromString(strings.TrimSpace(pair[1]))
 	if err != nil {
 		return Port(0), Port(0), err
 	}
@@ -67,3 +72,66 @@ func (this *PortRange) UnmarshalJSON(data []byte) error {
 	log.Error("Invalid port range: ", string(data))
 	return ErrInvalidPortRange
 }
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *PortRange) MarshalJSON() ([]byte, error) {
+	if this.From == this.To {
+		return json.Marshal(this.From)
+	}
+	return json.Marshal(strconv.FormatUint(uint64(this.From), 10) + "-" + strconv.FormatUint(uint64(this.To), 10))
+}
+
+func (this *PortList) appendRanges(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		from, to, err := parsePortRange(item)
+		if err != nil {
+			return err
+		}
+		if from > to {
+			log.Error("Invalid port range ", from, " -> ", to)
+			return ErrInvalidPortRange
+		}
+		this.Range = append(this.Range, &PortRange{From: uint32(from), To: uint32(to)})
+	}
+	return nil
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON. A PortList may be a port, a string of
+// comma-separated ports and ranges such as "53,443,1000-2000", or an array of them.
+func (this *PortList) UnmarshalJSON(data []byte) error {
+	this.Range = nil
+	if port, err := parseIntPort(data); err == nil {
+		this.Range = append(this.Range, SinglePortRange(port))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if err := this.appendRanges(s); err != nil {
+			log.Error("Invalid port list: ", s)
+			return ErrInvalidPortRange
+		}
+		return nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Error("Invalid port list: ", string(data))
+		return ErrInvalidPortRange
+	}
+	for _, item := range list {
+		if port, err := parseIntPort(item); err == nil {
+			this.Range = append(this.Range, SinglePortRange(port))
+			continue
+		}
+		if err := json.Unmarshal(item, &s); err != nil {
+			log.Error("Invalid port list: ", string(data))
+			return ErrInvalidPortRange
+		}
+		if err := this.appendRanges(s); err != nil {
+			log.Error("Invalid port list: ", s)
+			return ErrInvalidPortRange
+		}
+	}
+	return nil
+}
@@ -70,3 +70,30 @@ func TestOverRangeStringPort(t *testing.T) {
 	err = json.Unmarshal([]byte("\"700-600\""), &portRange)
 	assert.Error(err).Equals(ErrInvalidPortRange)
 }
+
+func TestPortList(t *testing.T) {
+	assert := assert.On(t)
+
+	var portList PortList
+	assert.Error(json.Unmarshal([]byte("\"53,443, 1000-2000\""), &portList)).IsNil()
+	assert.Int(len(portList.Range)).Equals(3)
+	assert.Bool(portList.Contains(Port(53))).IsTrue()
+	assert.Bool(portList.Contains(Port(443))).IsTrue()
+	assert.Bool(portList.Contains(Port(1500))).IsTrue()
+	assert.Bool(portList.Contains(Port(80))).IsFalse()
+	assert.Bool(portList.Contains(Port(2001))).IsFalse()
+
+	assert.Error(json.Unmarshal([]byte("[80, \"8000-8080,8443\"]"), &portList)).IsNil()
+	assert.Int(len(portList.Range)).Equals(3)
+	assert.Bool(portList.Contains(Port(80))).IsTrue()
+	assert.Bool(portList.Contains(Port(8443))).IsTrue()
+	assert.Bool(portList.Contains(Port(443))).IsFalse()
+
+	assert.Error(json.Unmarshal([]byte("8080"), &portList)).IsNil()
+	assert.Int(len(portList.Range)).Equals(1)
+	assert.Bool(portList.Contains(Port(8080))).IsTrue()
+
+	assert.Error(json.Unmarshal([]byte("\"53,2000-1000\""), &portList)).Equals(ErrInvalidPortRange)
+	assert.Error(json.Unmarshal([]byte("\"53,,443\""), &portList)).Equals(ErrInvalidPortRange)
+	assert.Error(json.Unmarshal([]byte("[true]"), &portList)).Equals(ErrInvalidPortRange)
+}
@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	AssetLocationEnv = "V2RAY_LOCATION_ASSET"
+)
+
+// GetAssetLocation returns the path of the given asset file, such as geoip.dat. Assets are looked up in the directory
+// from environment variable V2RAY_LOCATION_ASSET, or the directory of the executable.
+func GetAssetLocation(file string) string {
+	dir := os.Getenv(AssetLocationEnv)
+	if len(dir) == 0 {
+		exec, err := filepath.Abs(os.Args[0])
+		if err != nil {
+			exec = os.Args[0]
+		}
+		dir = filepath.Dir(exec)
+	}
+	return filepath.Join(dir, file)
+}
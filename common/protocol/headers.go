@@ -31,13 +31,29 @@ func (this *RequestOption) Clear(option RequestOption) {
 	*this = (*this & (^option))
 }
 
+// Security is the encryption of the data of a request and its response.
+type Security byte
+
+const (
+	// SecurityLegacy is AES-128-CFB, with chunks authenticated by FNV hashes if RequestOptionChunkStream is set.
+	SecurityLegacy           = Security(0x00)
+	SecurityAES128GCM        = Security(0x03)
+	SecurityChaCha20Poly1305 = Security(0x04)
+)
+
+// IsAEAD returns whether the data is sealed in AEAD chunks.
+func (this Security) IsAEAD() bool {
+	return this == SecurityAES128GCM || this == SecurityChaCha20Poly1305
+}
+
 type RequestHeader struct {
-	Version byte
-	User    *User
-	Command RequestCommand
-	Option  RequestOption
-	Address v2net.Address
-	Port    v2net.Port
+	Version  byte
+	User     *User
+	Command  RequestCommand
+	Option   RequestOption
+	Security Security
+	Address  v2net.Address
+	Port     v2net.Port
 }
 
 func (this *RequestHeader) Destination() v2net.Destination {
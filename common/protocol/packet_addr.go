@@ -0,0 +1,112 @@
+package protocol
+
+import (
+	"errors"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+)
+
+// PacketAddrDomain is the destination of UDP sessions in packet-addressed encoding. Such a session carries
+// datagrams for any number of remote addresses, and each datagram is prefixed with its own target (outbound)
+// or source (inbound) address. This allows a single session to behave like a full-cone NAT mapping.
+//
+// Sessions are opened by SOCKS inbounds with packetAddr enabled, and served by freedom outbounds, either directly or
+// behind a VMess server of this version, as VMess carries them as UDP to this domain. Other outbounds do not support
+// them. The dispatcher routes a session by the target of its first datagram, and drops the datagrams whose targets
+// are routed to other outbounds.
+const PacketAddrDomain = "sp.packet-addr.v2fly.arpa"
+
+const (
+	packetAddrTypeIPv4   = byte(0x01)
+	packetAddrTypeDomain = byte(0x03)
+	packetAddrTypeIPv6   = byte(0x04)
+)
+
+var (
+	ErrInvalidPacketAddr = errors.New("Invalid packet address.")
+)
+
+// PacketAddrDestination returns the destination of packet-addressed UDP sessions.
+func PacketAddrDestination() v2net.Destination {
+	return v2net.UDPDestination(v2net.DomainAddress(PacketAddrDomain), v2net.Port(0))
+}
+
+// IsPacketAddrDestination returns true if the given destination is the one of packet-addressed UDP sessions.
+func IsPacketAddrDestination(dest v2net.Destination) bool {
+	return dest.Network == v2net.Network_UDP && dest.Address.Family().IsDomain() && dest.Address.Domain() == PacketAddrDomain
+}
+
+// EncodePacketAddr returns a new buffer with the address of dest followed by the payload. The payload is released. It
+// returns ErrInvalidPacketAddr if the domain of dest is longer than 255 bytes.
+func EncodePacketAddr(dest v2net.Destination, payload *alloc.Buffer) (*alloc.Buffer, error) {
+	if dest.Address.Family().IsDomain() && len(dest.Address.Domain()) > 255 {
+		payload.Release()
+		return nil, ErrInvalidPacketAddr
+	}
+	buffer := alloc.NewBufferWithSize(payload.Len() + 262).Clear()
+	switch dest.Address.Family() {
+	case v2net.AddressFamilyIPv4:
+		buffer.AppendBytes(packetAddrTypeIPv4).Append(dest.Address.IP())
+	case v2net.AddressFamilyIPv6:
+		buffer.AppendBytes(packetAddrTypeIPv6).Append(dest.Address.IP())
+	case v2net.AddressFamilyDomain:
+		buffer.AppendBytes(packetAddrTypeDomain, byte(len(dest.Address.Domain()))).AppendString(dest.Address.Domain())
+	}
+	buffer.AppendUint16(dest.Port.Value())
+	buffer.Append(payload.Value)
+	payload.Release()
+	return buffer, nil
+}
+
+// DecodePacketAddr removes the address from the head of the payload, and returns it as an UDP destination.
+func DecodePacketAddr(payload *alloc.Buffer) (v2net.Destination, error) {
+	dest, length, err := parsePacketAddr(payload.Value)
+	if err != nil {
+		return v2net.Destination{}, err
+	}
+	payload.SliceFrom(length)
+	return dest, nil
+}
+
+// PeekPacketAddr returns the address at the head of the payload as an UDP destination, and leaves the payload as is.
+func PeekPacketAddr(payload *alloc.Buffer) (v2net.Destination, error) {
+	dest, _, err := parsePacketAddr(payload.Value)
+	return dest, err
+}
+
+// parsePacketAddr parses the address at the head of b, and returns it with its length in b.
+func parsePacketAddr(b []byte) (v2net.Destination, int, error) {
+	if len(b) < 1 {
+		return v2net.Destination{}, 0, ErrInvalidPacketAddr
+	}
+	var address v2net.Address
+	var addrLen int
+	switch b[0] {
+	case packetAddrTypeIPv4:
+		addrLen = 1 + 4
+		if len(b) < addrLen+2 {
+			return v2net.Destination{}, 0, ErrInvalidPacketAddr
+		}
+		address = v2net.IPAddress(b[1:addrLen])
+	case packetAddrTypeIPv6:
+		addrLen = 1 + 16
+		if len(b) < addrLen+2 {
+			return v2net.Destination{}, 0, ErrInvalidPacketAddr
+		}
+		address = v2net.IPAddress(b[1:addrLen])
+	case packetAddrTypeDomain:
+		if len(b) < 2 {
+			return v2net.Destination{}, 0, ErrInvalidPacketAddr
+		}
+		addrLen = 2 + int(b[1])
+		if len(b) < addrLen+2 {
+			return v2net.Destination{}, 0, ErrInvalidPacketAddr
+		}
+		address = v2net.ParseAddress(string(b[2:addrLen]))
+	default:
+		return v2net.Destination{}, 0, ErrInvalidPacketAddr
+	}
+	port := v2net.PortFromBytes(b[addrLen : addrLen+2])
+	return v2net.UDPDestination(address, port), addrLen + 2, nil
+}
@@ -0,0 +1,51 @@
+package protocol_test
+
+import (
+	"strings"
+	"testing"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/common/protocol"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestPacketAddrEncoding(t *testing.T) {
+	assert := assert.On(t)
+
+	destinations := []v2net.Destination{
+		v2net.UDPDestination(v2net.IPAddress([]byte{1, 2, 3, 4}), v2net.Port(53)),
+		v2net.UDPDestination(v2net.IPAddress([]byte{0x20, 0x01, 0x48, 0x60, 0x48, 0x60, 0, 0, 0, 0, 0, 0, 0, 0, 0x88, 0x88}), v2net.Port(443)),
+		v2net.UDPDestination(v2net.DomainAddress("v2ray.com"), v2net.Port(3478)),
+	}
+	for _, dest := range destinations {
+		payload := alloc.NewLocalBuffer(2048).Clear().AppendString("datagram")
+		encoded, err := EncodePacketAddr(dest, payload)
+		assert.Error(err).IsNil()
+
+		decoded, err := DecodePacketAddr(encoded)
+		assert.Error(err).IsNil()
+		assert.Destination(decoded).EqualsString(dest.String())
+		assert.String(encoded.String()).Equals("datagram")
+	}
+}
+
+func TestInvalidPacketAddr(t *testing.T) {
+	assert := assert.On(t)
+
+	_, err := EncodePacketAddr(v2net.UDPDestination(v2net.DomainAddress(strings.Repeat("a", 256)), v2net.Port(53)), alloc.NewLocalBuffer(2048).Clear())
+	assert.Error(err).Equals(ErrInvalidPacketAddr)
+
+	for _, b := range [][]byte{{}, {1, 127, 0, 0}, {3, 10, 'v'}, {5, 0, 0}} {
+		_, err := DecodePacketAddr(alloc.NewLocalBuffer(2048).Clear().Append(b))
+		assert.Error(err).Equals(ErrInvalidPacketAddr)
+	}
+}
+
+func TestPacketAddrDestination(t *testing.T) {
+	assert := assert.On(t)
+
+	assert.Bool(IsPacketAddrDestination(PacketAddrDestination())).IsTrue()
+	assert.Bool(IsPacketAddrDestination(v2net.TCPDestination(v2net.DomainAddress(PacketAddrDomain), 0))).IsFalse()
+	assert.Bool(IsPacketAddrDestination(v2net.UDPDestination(v2net.DomainAddress("v2ray.com"), 0))).IsFalse()
+}
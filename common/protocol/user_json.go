@@ -2,7 +2,11 @@
 
 package protocol
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/ptypes"
+)
 
 func (u *User) UnmarshalJSON(data []byte) error {
 	type rawUser struct {
@@ -19,3 +23,29 @@ func (u *User) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON. Fields of the account are in the same object as the
+// user, as parsers of protocols decode them from the same object.
+func (u *User) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{})
+	if u.Account != nil {
+		account := new(ptypes.DynamicAny)
+		if err := ptypes.UnmarshalAny(u.Account, account); err != nil {
+			return nil, err
+		}
+		rawAccount, err := json.Marshal(account.Message)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(rawAccount, &object); err != nil {
+			return nil, err
+		}
+	}
+	if len(u.Email) > 0 {
+		object["email"] = u.Email
+	}
+	if u.Level > 0 {
+		object["level"] = u.Level
+	}
+	return json.Marshal(object)
+}
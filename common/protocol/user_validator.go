@@ -8,5 +8,7 @@ type UserValidator interface {
 	common.Releasable
 
 	Add(user *User) error
+	// Remove removes the user with the given email, and returns false if no such user is found.
+	Remove(email string) bool
 	Get(timeHash []byte) (*User, Timestamp, bool)
 }
@@ -0,0 +1,55 @@
+// Package ratelimit provides a token bucket for limiting the throughput of data streams.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket limits the rate of byte consumption. It is safe for concurrent use, so it can be shared by multiple streams.
+type TokenBucket struct {
+	sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewTokenBucket creates a TokenBucket that refills at rate bytes per second and holds at most capacity bytes.
+// If capacity is zero, it defaults to one second worth of tokens.
+func NewTokenBucket(rate uint64, capacity uint64) *TokenBucket {
+	if capacity == 0 {
+		capacity = rate
+	}
+	return &TokenBucket{
+		rate:     float64(rate),
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		last:     time.Now(),
+	}
+}
+
+// Reserve takes n tokens from the bucket and returns the duration the caller has to wait before using them.
+func (this *TokenBucket) Reserve(n int) time.Duration {
+	this.Lock()
+	defer this.Unlock()
+
+	now := time.Now()
+	this.tokens += now.Sub(this.last).Seconds() * this.rate
+	if this.tokens > this.capacity {
+		this.tokens = this.capacity
+	}
+	this.last = now
+	this.tokens -= float64(n)
+	if this.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-this.tokens / this.rate * float64(time.Second))
+}
+
+// Wait blocks until n tokens are available.
+func (this *TokenBucket) Wait(n int) {
+	if delay := this.Reserve(n); delay > 0 {
+		time.Sleep(delay)
+	}
+}
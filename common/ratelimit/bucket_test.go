@@ -0,0 +1,33 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	. "v2ray.com/core/common/ratelimit"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	assert := assert.On(t)
+
+	bucket := NewTokenBucket(1024, 2048)
+	assert.Int64(int64(bucket.Reserve(2048))).Equals(0)
+
+	delay := bucket.Reserve(512)
+	assert.Bool(delay > time.Millisecond*400).IsTrue()
+	assert.Bool(delay <= time.Millisecond*500).IsTrue()
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	assert := assert.On(t)
+
+	bucket := NewTokenBucket(10240, 1024)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		bucket.Wait(1024)
+	}
+	elapsed := time.Since(start)
+	assert.Bool(elapsed >= time.Millisecond*250).IsTrue()
+	assert.Bool(elapsed < time.Second).IsTrue()
+}
@@ -0,0 +1,132 @@
+// Package task runs maintenance work in background.
+package task
+
+import (
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/log"
+)
+
+// Periodic runs a task at an interval until it is closed. Runs never overlap, as the next run is scheduled once the
+// previous one finishes. A panic in the task is recovered and logged, and the task keeps running.
+type Periodic struct {
+	// Name of the task in logs.
+	Name string
+	// Interval is the time between the end of a run and the start of the next one.
+	Interval time.Duration
+	// Jitter is the max random time added to each interval, so that tasks started together don't run together.
+	Jitter time.Duration
+	// Execute is the task. Errors are logged, and don't stop the task.
+	Execute func() error
+
+	access     sync.Mutex
+	running    bool
+	generation int
+	timer      *time.Timer
+	executing  sync.Mutex
+}
+
+// Start schedules the first run after the interval. It does nothing if the task is running already.
+func (this *Periodic) Start() {
+	this.access.Lock()
+	defer this.access.Unlock()
+
+	if this.running {
+		return
+	}
+	this.running = true
+	this.generation++
+	this.schedule(this.generation)
+}
+
+// Run runs the task immediately, and schedules the next run as Start does.
+func (this *Periodic) Run() {
+	this.access.Lock()
+	if this.running {
+		this.access.Unlock()
+		return
+	}
+	this.running = true
+	this.generation++
+	generation := this.generation
+	this.access.Unlock()
+
+	go this.run(generation)
+}
+
+// IsRunning returns whether the task is started and not closed.
+func (this *Periodic) IsRunning() bool {
+	this.access.Lock()
+	defer this.access.Unlock()
+
+	return this.running
+}
+
+// isCurrent returns whether runs of the generation are still wanted, i.e. the task is not stopped since.
+func (this *Periodic) isCurrent(generation int) bool {
+	this.access.Lock()
+	defer this.access.Unlock()
+
+	return this.running && this.generation == generation
+}
+
+// schedule must be called with access locked.
+func (this *Periodic) schedule(generation int) {
+	interval := this.Interval
+	if this.Jitter > 0 {
+		interval += time.Duration(dice.Roll(int(this.Jitter)))
+	}
+	this.timer = time.AfterFunc(interval, func() {
+		this.run(generation)
+	})
+}
+
+func (this *Periodic) run(generation int) {
+	this.executing.Lock()
+	if this.isCurrent(generation) {
+		this.execute()
+	}
+	this.executing.Unlock()
+
+	this.access.Lock()
+	defer this.access.Unlock()
+	if this.running && this.generation == generation {
+		this.schedule(generation)
+	}
+}
+
+func (this *Periodic) execute() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Task: ", this.Name, " panicked: ", r)
+		}
+	}()
+
+	if err := this.Execute(); err != nil {
+		log.Warning("Task: ", this.Name, " failed: ", err)
+	}
+}
+
+// Close stops the task, and waits for the current run to finish if there is one. It must not be called by the task
+// itself, which should call Stop instead.
+func (this *Periodic) Close() {
+	this.Stop()
+
+	this.executing.Lock()
+	this.executing.Unlock()
+}
+
+// Stop stops the task without waiting for the current run. The task may be started again.
+func (this *Periodic) Stop() {
+	this.access.Lock()
+	defer this.access.Unlock()
+
+	this.running = false
+	this.generation++
+	if this.timer != nil {
+		this.timer.Stop()
+		this.timer = nil
+	}
+}
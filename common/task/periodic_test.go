@@ -0,0 +1,65 @@
+package task_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/common/task"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestPeriodic(t *testing.T) {
+	assert := assert.On(t)
+
+	var runs int32
+	task := &Periodic{
+		Name:     "test",
+		Interval: 50 * time.Millisecond,
+		Execute: func() error {
+			if atomic.AddInt32(&runs, 1) == 1 {
+				panic("first run")
+			}
+			return errors.New("failure")
+		},
+	}
+	task.Start()
+	task.Start()
+	time.Sleep(280 * time.Millisecond)
+	task.Close()
+	assert.Bool(task.IsRunning()).IsFalse()
+
+	// Panics and errors don't stop the task.
+	count := atomic.LoadInt32(&runs)
+	assert.Bool(count >= 3 && count <= 5).IsTrue()
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Int64(int64(atomic.LoadInt32(&runs))).Equals(int64(count))
+}
+
+func TestPeriodicStopItself(t *testing.T) {
+	assert := assert.On(t)
+
+	var runs int32
+	task := &Periodic{
+		Interval: 20 * time.Millisecond,
+		Jitter:   10 * time.Millisecond,
+	}
+	task.Execute = func() error {
+		if atomic.AddInt32(&runs, 1) == 2 {
+			task.Stop()
+		}
+		return nil
+	}
+	task.Run()
+	time.Sleep(200 * time.Millisecond)
+	assert.Bool(task.IsRunning()).IsFalse()
+	assert.Int64(int64(atomic.LoadInt32(&runs))).Equals(2)
+
+	// A stopped task can be started again.
+	task.Run()
+	time.Sleep(10 * time.Millisecond)
+	task.Close()
+	assert.Int64(int64(atomic.LoadInt32(&runs))).Equals(3)
+}
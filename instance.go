@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+var (
+	ErrNoInstanceCreator = errors.New("Core: No instance creator is registered. Import v2ray.com/core/shell/point.")
+	ErrDuplicatedCreator = errors.New("Core: Instance creator is already registered.")
+)
+
+// Instance is a V2Ray server running in the process, for Go applications and mobile wrappers that embed V2Ray as a
+// library.
+type Instance interface {
+	// Start starts the inbounds and services of the instance.
+	Start() error
+	// Close stops the instance. Connections through it are canceled.
+	Close()
+	// Dial opens a connection to the destination through the outbounds of the instance, as the router tells. The
+	// connection is dispatched asynchronously, so errors of the outbound show up as errors on reading. The connection
+	// lives as long as ctx, and canceling ctx closes it.
+	Dial(ctx context.Context, destination v2net.Destination) (net.Conn, error)
+}
+
+// InstanceCreator creates an instance from a config.
+type InstanceCreator func(config interface{}) (Instance, error)
+
+var (
+	instanceCreatorAccess sync.Mutex
+	instanceCreator       InstanceCreator
+)
+
+// RegisterInstanceCreator registers the creator of instances. It is called by package v2ray.com/core/shell/point on
+// init.
+func RegisterInstanceCreator(creator InstanceCreator) error {
+	instanceCreatorAccess.Lock()
+	defer instanceCreatorAccess.Unlock()
+
+	if instanceCreator != nil {
+		return ErrDuplicatedCreator
+	}
+	instanceCreator = creator
+	return nil
+}
+
+// New creates an instance from the config, which is a *point.Config of package v2ray.com/core/shell/point, e.g. from
+// point.LoadConfig. The instance is not started.
+func New(config interface{}) (Instance, error) {
+	instanceCreatorAccess.Lock()
+	creator := instanceCreator
+	instanceCreatorAccess.Unlock()
+
+	if creator == nil {
+		return nil, ErrNoInstanceCreator
+	}
+	return creator(config)
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"net"
+	"time"
+
+	"v2ray.com/core/common/event"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	DefaultAuthFailureLimit  = 10
+	DefaultAuthFailureWindow = time.Minute
+)
+
+// SourceAddress returns the address of a remote address of a connection, e.g. "1.2.3.4:5678".
+func SourceAddress(addr net.Addr) v2net.Address {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return v2net.ParseAddress(addr.String())
+	}
+	return v2net.ParseAddress(host)
+}
+
+// ReportAuthFailure reports that a client failed authentication on an inbound. The source is banned from all inbounds
+// of the instance if it fails too often.
+func ReportAuthFailure(meta *InboundHandlerMeta, source v2net.Address) {
+	if source.Family().Either(v2net.AddressFamilyIPv4, v2net.AddressFamilyIPv6) {
+		if duration := meta.Bans.Fail(source.IP(), time.Now()); duration > 0 {
+			log.Warning("Proxy: Banned ", source, " for ", duration, " after repeated authentication failures.")
+			meta.Events.Publish(event.SourceBanned, "Banned "+source.String()+" for "+duration.String()+".", map[string]string{
+				"inbound":  meta.Tag,
+				"source":   source.String(),
+				"duration": duration.String(),
+			})
+		}
+	}
+
+	meta.Events.Publish(event.AuthFailure, "Authentication failure from "+source.String()+".", map[string]string{
+		"inbound": meta.Tag,
+		"source":  source.String(),
+	})
+}
+
+// clientIdentifier is a connection that identifies its client as a user, e.g. by a TLS client certificate.
+type clientIdentifier interface {
+	ClientEmail() string
+}
+
+// CertificateUser returns the user that the client of the connection is identified as by its certificate, or nil if
+// it is not identified.
+func CertificateUser(conn net.Conn) *protocol.User {
+	identifier, ok := conn.(clientIdentifier)
+	if !ok {
+		return nil
+	}
+	email := identifier.ClientEmail()
+	if len(email) == 0 {
+		return nil
+	}
+	return &protocol.User{
+		Email: email,
+	}
+}
@@ -1,6 +1,8 @@
 package blackhole
 
 import (
+	"context"
+
 	"v2ray.com/core/app"
 	"v2ray.com/core/common/alloc"
 	v2net "v2ray.com/core/common/net"
@@ -27,7 +29,7 @@ func NewBlackHole(space app.Space, config *Config, meta *proxy.OutboundHandlerMe
 	}, nil
 }
 
-func (this *BlackHole) Dispatch(destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
+func (this *BlackHole) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
 	payload.Release()
 
 	this.response.WriteTo(ray.OutboundOutput())
@@ -16,8 +16,8 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		Response json.RawMessage `json:"response"`
 	}
 	jsonConfig := new(JSONConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Blackhole: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Blackhole: Failed to parse config: ", err)
 	}
 
 	if jsonConfig.Response != nil {
@@ -38,6 +38,23 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type JSONResponse struct {
+		Type string `json:"type"`
+	}
+	type JSONConfig struct {
+		Response *JSONResponse `json:"response,omitempty"`
+	}
+	jsonConfig := new(JSONConfig)
+	if this.Response != nil {
+		jsonConfig.Response = &JSONResponse{
+			Type: strings.ToLower(this.Response.Type.String()),
+		}
+	}
+	return json.Marshal(jsonConfig)
+}
+
 var (
 	configLoader = loader.NewJSONConfigLoader(cache, "type", "")
 )
@@ -0,0 +1,35 @@
+package proxy
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	sessionKey contextKey = iota
+	inboundMetaKey
+)
+
+// ContextWithSession returns a context that carries the session and the meta of the inbound it came from. Meta may be
+// nil for sessions that don't come from an inbound.
+func ContextWithSession(ctx context.Context, meta *InboundHandlerMeta, session *SessionInfo) context.Context {
+	ctx = context.WithValue(ctx, sessionKey, session)
+	if meta != nil {
+		ctx = context.WithValue(ctx, inboundMetaKey, meta)
+	}
+	return ctx
+}
+
+// SessionFromContext returns the session carried by the context, or nil if there is none.
+func SessionFromContext(ctx context.Context) *SessionInfo {
+	session, _ := ctx.Value(sessionKey).(*SessionInfo)
+	return session
+}
+
+// InboundMetaFromContext returns the meta of the inbound that the session of the context came from, or nil if there
+// is none.
+func InboundMetaFromContext(ctx context.Context) *InboundHandlerMeta {
+	meta, _ := ctx.Value(inboundMetaKey).(*InboundHandlerMeta)
+	return meta
+}
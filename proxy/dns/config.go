@@ -0,0 +1,34 @@
+package dns
+
+import (
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	DefaultTTL = 60
+)
+
+func (this *Config) GetTTL() uint32 {
+	if this.Ttl == 0 {
+		return DefaultTTL
+	}
+	return this.Ttl
+}
+
+func (this *Config) HasNetwork(network v2net.Network) bool {
+	if this.NetworkList == nil {
+		return network == v2net.Network_UDP
+	}
+	return this.NetworkList.HasNetwork(network)
+}
+
+// ListenNetworks implements internet.NetworkListener.
+func (this *Config) ListenNetworks() []v2net.Network {
+	var networks []v2net.Network
+	for _, network := range []v2net.Network{v2net.Network_TCP, v2net.Network_UDP} {
+		if this.HasNetwork(network) {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
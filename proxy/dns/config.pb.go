@@ -0,0 +1,73 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/proxy/dns/config.proto
+// DO NOT EDIT!
+
+/*
+Package dns is a generated protocol buffer package.
+
+It is generated from these files:
+
+	v2ray.com/core/proxy/dns/config.proto
+
+It has these top-level messages:
+
+	Config
+*/
+package dns
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import v2ray_core_common_net "v2ray.com/core/common/net"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Config struct {
+	// Networks to serve DNS on. Default to UDP only.
+	NetworkList *v2ray_core_common_net.NetworkList `protobuf:"bytes,1,opt,name=network_list,json=networkList" json:"network_list,omitempty"`
+	// TTL in seconds of answers. Default to 60.
+	Ttl uint32 `protobuf:"varint,2,opt,name=ttl" json:"ttl,omitempty"`
+}
+
+func (m *Config) Reset()                    { *m = Config{} }
+func (m *Config) String() string            { return proto.CompactTextString(m) }
+func (*Config) ProtoMessage()               {}
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Config) GetNetworkList() *v2ray_core_common_net.NetworkList {
+	if m != nil {
+		return m.NetworkList
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Config)(nil), "v2ray.core.proxy.dns.Config")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/proxy/dns/config.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 187 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x52, 0x2d, 0x33, 0x2a, 0x4a,
+	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x2f, 0x28, 0xca, 0xaf, 0xa8,
+	0xd4, 0x4f, 0xc9, 0x2b, 0xd6, 0x4f, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0xd7, 0x2b, 0x28, 0xca, 0x2f,
+	0xc9, 0x17, 0x12, 0x81, 0x29, 0x2b, 0x4a, 0xd5, 0x03, 0x2b, 0xd1, 0x4b, 0xc9, 0x2b, 0x96, 0x52,
+	0x47, 0xd3, 0x9c, 0x9c, 0x9f, 0x9b, 0x9b, 0x9f, 0xa7, 0x9f, 0x97, 0x5a, 0x02, 0xc2, 0xe5, 0xf9,
+	0x45, 0xd9, 0x10, 0xed, 0x4a, 0x89, 0x5c, 0x6c, 0xce, 0x60, 0xe3, 0x84, 0x5c, 0xb9, 0x78, 0xa0,
+	0x52, 0xf1, 0x39, 0x99, 0xc5, 0x25, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0xdc, 0x46, 0x4a, 0x7a, 0x48,
+	0xe6, 0x43, 0x4c, 0xd1, 0xcb, 0x4b, 0x2d, 0xd1, 0xf3, 0x83, 0x28, 0xf5, 0xc9, 0x2c, 0x2e, 0x09,
+	0xe2, 0xce, 0x43, 0x70, 0x84, 0x04, 0xb8, 0x98, 0x4b, 0x4a, 0x72, 0x24, 0x98, 0x14, 0x18, 0x35,
+	0x78, 0x83, 0x40, 0x4c, 0x27, 0x1d, 0x2e, 0x89, 0xe4, 0xfc, 0x5c, 0x3d, 0x6c, 0xee, 0x74, 0xe2,
+	0x86, 0x58, 0x1e, 0x00, 0x72, 0x4b, 0x14, 0x73, 0x4a, 0x5e, 0x71, 0x12, 0x1b, 0xd8, 0x5d, 0xc6,
+	0x80, 0x01, 0x00, 0x99, 0xdc, 0x84, 0x29, 0xff, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,41 @@
+// +build json
+
+package dns
+
+import (
+	"encoding/json"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy/registry"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		NetworkList *v2net.NetworkList `json:"network"`
+		TTL         uint32             `json:"ttl"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("DNS|Server: Failed to parse config: ", err)
+	}
+	this.NetworkList = jsonConfig.NetworkList
+	this.Ttl = jsonConfig.TTL
+	return nil
+}
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type JsonConfig struct {
+		NetworkList *v2net.NetworkList `json:"network,omitempty"`
+		TTL         uint32             `json:"ttl,omitempty"`
+	}
+	return json.Marshal(&JsonConfig{
+		NetworkList: this.NetworkList,
+		TTL:         this.Ttl,
+	})
+}
+
+func init() {
+	registry.RegisterInboundConfig("dns", func() interface{} { return new(Config) })
+}
@@ -0,0 +1,28 @@
+// +build json
+
+package dns_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy/dns"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestConfigParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	config := new(Config)
+	assert.Error(json.Unmarshal([]byte(`{"network": "tcp,udp", "ttl": 300}`), config)).IsNil()
+	assert.Bool(config.HasNetwork(v2net.Network_TCP)).IsTrue()
+	assert.Bool(config.HasNetwork(v2net.Network_UDP)).IsTrue()
+	assert.Int(int(config.GetTTL())).Equals(300)
+
+	config = new(Config)
+	assert.Error(json.Unmarshal([]byte(`{}`), config)).IsNil()
+	assert.Bool(config.HasNetwork(v2net.Network_TCP)).IsFalse()
+	assert.Bool(config.HasNetwork(v2net.Network_UDP)).IsTrue()
+	assert.Int(int(config.GetTTL())).Equals(DefaultTTL)
+}
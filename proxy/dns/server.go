@@ -0,0 +1,314 @@
+package dns
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app"
+	v2dns "v2ray.com/core/app/dns"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/proxy/registry"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/udp"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	tcpIdleTimeout = time.Second * 30
+)
+
+// Server is an inbound that answers DNS queries from clients with the DNS app. A and AAAA queries are resolved by the
+// DNS app, with fake IPs if fake DNS is enabled, and so are PTR queries if the DNS app supports reverse lookups.
+// Queries of other types are answered without records.
+type Server struct {
+	sync.RWMutex
+	config      *Config
+	meta        *proxy.InboundHandlerMeta
+	dnsServer   v2dns.Server
+	fakeDNS     v2dns.FakeDNSEngine
+	reverse     v2dns.ReverseResolver
+	lookup      v2dns.LookupServer
+	accepting   bool
+	tcpListener *internet.TCPHub
+	udpHub      *udp.UDPHub
+}
+
+func NewServer(config *Config, space app.Space, meta *proxy.InboundHandlerMeta) *Server {
+	s := &Server{
+		config: config,
+		meta:   meta,
+	}
+	space.InitializeApplication(func() error {
+		if !space.HasApp(v2dns.APP_ID) {
+			log.Error("DNS|Server: DNS app is not found in the space.")
+			return app.ErrMissingApplication
+		}
+		s.dnsServer = space.GetApp(v2dns.APP_ID).(v2dns.Server)
+		if fakeDNS, ok := s.dnsServer.(v2dns.FakeDNSEngine); ok {
+			s.fakeDNS = fakeDNS
+		}
+		if reverse, ok := s.dnsServer.(v2dns.ReverseResolver); ok {
+			s.reverse = reverse
+		}
+		if lookup, ok := s.dnsServer.(v2dns.LookupServer); ok {
+			s.lookup = lookup
+		}
+		return nil
+	})
+	return s
+}
+
+func (this *Server) Port() v2net.Port {
+	return this.meta.Port
+}
+
+// Accepting implements proxy.AcceptingReporter.
+func (this *Server) Accepting() bool {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.accepting
+}
+
+func (this *Server) Close() {
+	this.Lock()
+	defer this.Unlock()
+
+	this.accepting = false
+	if this.tcpListener != nil {
+		this.tcpListener.Close()
+		this.tcpListener = nil
+	}
+	if this.udpHub != nil {
+		this.udpHub.Close()
+		this.udpHub = nil
+	}
+}
+
+func (this *Server) Start() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.accepting {
+		return nil
+	}
+
+	if this.config.HasNetwork(v2net.Network_TCP) {
+		tcpListener, err := internet.ListenTCP(this.meta.Address, this.meta.Port, this.handleTCPConnection, this.meta.StreamSettings)
+		if err != nil {
+			this.meta.Logger.Error("DNS|Server: Failed to listen on TCP ", this.meta.Address, ":", this.meta.Port, ": ", err)
+			return err
+		}
+		this.tcpListener = tcpListener
+	}
+	if this.config.HasNetwork(v2net.Network_UDP) {
+		udpHub, err := udp.ListenUDP(this.meta.Address, this.meta.Port, udp.ListenOption{
+			Callback:     this.handleUDPPacket,
+			SourceFilter: this.meta.SourceFilter,
+			Bans:         this.meta.Bans,
+		})
+		if err != nil {
+			this.meta.Logger.Error("DNS|Server: Failed to listen on UDP ", this.meta.Address, ":", this.meta.Port, ": ", err)
+			if this.tcpListener != nil {
+				this.tcpListener.Close()
+				this.tcpListener = nil
+			}
+			return err
+		}
+		this.udpHub = udpHub
+	}
+	this.accepting = true
+	return nil
+}
+
+// Answer builds the response to a query. It returns nil if the query is not a valid DNS query.
+func (this *Server) Answer(query *dns.Msg) *dns.Msg {
+	if query.Response || query.Opcode != dns.OpcodeQuery || len(query.Question) != 1 {
+		return nil
+	}
+	response := new(dns.Msg).SetReply(query)
+	response.RecursionAvailable = true
+
+	question := query.Question[0]
+	if question.Qclass == dns.ClassINET && question.Qtype == dns.TypePTR && this.reverse != nil {
+		return this.answerPTR(question, response)
+	}
+	if question.Qclass != dns.ClassINET || (question.Qtype != dns.TypeA && question.Qtype != dns.TypeAAAA) {
+		this.meta.Logger.Debug("DNS|Server: Answering ", question.Name, " of type ", dns.TypeToString[question.Qtype], " without records.")
+		return response
+	}
+
+	domain := strings.TrimSuffix(question.Name, ".")
+	var ips []net.IP
+	if this.fakeDNS != nil {
+		if fakeIP := this.fakeDNS.GetFakeIP(domain); fakeIP != nil {
+			ips = []net.IP{fakeIP}
+		}
+	}
+	if ips == nil {
+		nameError := false
+		if this.lookup != nil {
+			ips, nameError = this.lookup.Lookup(domain)
+		} else {
+			ips = this.dnsServer.Get(domain)
+		}
+		if nameError {
+			this.meta.Logger.Debug("DNS|Server: Domain ", domain, " does not exist.")
+			response.Rcode = dns.RcodeNameError
+			return response
+		}
+		if ips == nil {
+			this.meta.Logger.Info("DNS|Server: Failed to resolve ", domain)
+			response.Rcode = dns.RcodeServerFailure
+			return response
+		}
+	}
+
+	ttl := this.config.GetTTL()
+	for _, ip := range ips {
+		header := dns.RR_Header{Name: question.Name, Rrtype: question.Qtype, Class: dns.ClassINET, Ttl: ttl}
+		if ip4 := ip.To4(); ip4 != nil {
+			if question.Qtype == dns.TypeA {
+				response.Answer = append(response.Answer, &dns.A{Hdr: header, A: ip4})
+			}
+		} else if question.Qtype == dns.TypeAAAA {
+			response.Answer = append(response.Answer, &dns.AAAA{Hdr: header, AAAA: ip})
+		}
+	}
+	return response
+}
+
+func (this *Server) answerPTR(question dns.Question, response *dns.Msg) *dns.Msg {
+	ip := v2dns.ParseReverseName(question.Name)
+	if ip == nil {
+		response.Rcode = dns.RcodeNameError
+		return response
+	}
+	domains := this.reverse.ReverseLookup(ip)
+	if domains == nil {
+		response.Rcode = dns.RcodeNameError
+		return response
+	}
+	ttl := this.config.GetTTL()
+	for _, domain := range domains {
+		response.Answer = append(response.Answer, &dns.PTR{
+			Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: ttl},
+			Ptr: dns.Fqdn(domain),
+		})
+	}
+	return response
+}
+
+func (this *Server) handleUDPPacket(payload *alloc.Buffer, session *proxy.SessionInfo) {
+	query := new(dns.Msg)
+	err := query.Unpack(payload.Value)
+	payload.Release()
+	if err != nil {
+		this.meta.Logger.Info("DNS|Server: Invalid query from ", session.Source, ": ", err)
+		return
+	}
+	go func() {
+		response := this.Answer(query)
+		if response == nil {
+			return
+		}
+		truncate(query, response)
+		data, err := response.Pack()
+		if err != nil {
+			this.meta.Logger.Warning("DNS|Server: Failed to pack response: ", err)
+			return
+		}
+
+		this.RLock()
+		defer this.RUnlock()
+
+		if !this.accepting {
+			return
+		}
+		this.udpHub.WriteTo(data, session.Source)
+	}()
+}
+
+// truncate removes the records that don't fit in a UDP response, and sets TC so that the client retries over TCP. UDP
+// responses are limited to 512 bytes, or the size the client advertises with EDNS.
+func truncate(query *dns.Msg, response *dns.Msg) {
+	size := dns.MinMsgSize
+	if opt := query.IsEdns0(); opt != nil && int(opt.UDPSize()) > size {
+		size = int(opt.UDPSize())
+	}
+	response.Truncate(size)
+}
+
+func readTCPMessage(reader io.Reader) (*dns.Msg, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	msg := new(dns.Msg)
+	if err := msg.Unpack(data); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeTCPMessage(writer io.Writer, msg *dns.Msg) error {
+	data, err := msg.Pack()
+	if err != nil {
+		return err
+	}
+	buffer := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(buffer, uint16(len(data)))
+	copy(buffer[2:], data)
+	_, err = writer.Write(buffer)
+	return err
+}
+
+// handleTCPConnection answers the queries on a connection in order, until the client closes it or stays idle.
+func (this *Server) handleTCPConnection(conn internet.Connection) {
+	defer conn.Close()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			if err != io.EOF {
+				this.meta.Logger.Info("DNS|Server: Failed to read query from ", conn.RemoteAddr(), ": ", err)
+			}
+			return
+		}
+		response := this.Answer(query)
+		if response == nil {
+			return
+		}
+		if err := writeTCPMessage(conn, response); err != nil {
+			this.meta.Logger.Info("DNS|Server: Failed to write response to ", conn.RemoteAddr(), ": ", err)
+			return
+		}
+	}
+}
+
+type Factory struct{}
+
+func (this *Factory) StreamCapability() internet.StreamConnectionType {
+	return internet.StreamConnectionTypeRawTCP
+}
+
+func (this *Factory) Create(space app.Space, rawConfig interface{}, meta *proxy.InboundHandlerMeta) (proxy.InboundHandler, error) {
+	return NewServer(rawConfig.(*Config), space, meta), nil
+}
+
+func init() {
+	registry.MustRegisterInboundHandlerCreator("dns", new(Factory))
+}
@@ -0,0 +1,163 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	dispatchers "v2ray.com/core/app/dispatcher/impl"
+	v2dns "v2ray.com/core/app/dns"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/dice"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/dns"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+
+	_ "v2ray.com/core/transport/internet/tcp"
+)
+
+func TestDNSServer(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	space.BindApp(v2dns.APP_ID, v2dns.NewCacheServer(space, &v2dns.Config{
+		Hosts: map[string]*v2net.AddressPB{
+			"www.v2ray.com": {
+				Address: &v2net.AddressPB_Ip{
+					Ip: []byte{10, 0, 0, 1},
+				},
+			},
+		},
+	}))
+
+	port := v2net.Port(dice.Roll(20000) + 10000)
+	server := NewServer(&Config{
+		NetworkList: &v2net.NetworkList{
+			Network: []v2net.Network{v2net.Network_TCP, v2net.Network_UDP},
+		},
+		Ttl: 10,
+	}, space, &proxy.InboundHandlerMeta{
+		Address: v2net.LocalHostIP,
+		Port:    port,
+		StreamSettings: &internet.StreamSettings{
+			Type: internet.StreamConnectionTypeRawTCP,
+		}})
+	defer server.Close()
+
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+
+	for _, network := range []string{"udp", "tcp"} {
+		client := &dns.Client{Net: network}
+
+		query := new(dns.Msg).SetQuestion("www.v2ray.com.", dns.TypeA)
+		response, _, err := client.Exchange(query, "127.0.0.1:"+port.String())
+		assert.Error(err).IsNil()
+		assert.Int(response.Rcode).Equals(dns.RcodeSuccess)
+		assert.Int(len(response.Answer)).Equals(1)
+		a := response.Answer[0].(*dns.A)
+		assert.IP(a.A.To4()).Equals(net.IP([]byte{10, 0, 0, 1}))
+		assert.Int(int(a.Hdr.Ttl)).Equals(10)
+
+		query = new(dns.Msg).SetQuestion("www.v2ray.com.", dns.TypeAAAA)
+		response, _, err = client.Exchange(query, "127.0.0.1:"+port.String())
+		assert.Error(err).IsNil()
+		assert.Int(response.Rcode).Equals(dns.RcodeSuccess)
+		assert.Int(len(response.Answer)).Equals(0)
+
+		query = new(dns.Msg).SetQuestion("www.v2ray.com.", dns.TypeMX)
+		response, _, err = client.Exchange(query, "127.0.0.1:"+port.String())
+		assert.Error(err).IsNil()
+		assert.Int(response.Rcode).Equals(dns.RcodeSuccess)
+		assert.Int(len(response.Answer)).Equals(0)
+	}
+}
+
+// lookupServer resolves domains in ips, and answers that the other domains don't exist, except that it fails to resolve
+// fail.v2ray.com.
+type lookupServer struct {
+	ips map[string][]net.IP
+}
+
+func (this *lookupServer) Get(domain string) []net.IP {
+	ips, _ := this.Lookup(domain)
+	return ips
+}
+
+func (this *lookupServer) Lookup(domain string) ([]net.IP, bool) {
+	if domain == "fail.v2ray.com" {
+		return nil, false
+	}
+	if ips, found := this.ips[domain]; found {
+		return ips, false
+	}
+	return []net.IP{}, true
+}
+
+func (this *lookupServer) Release() {}
+
+func TestDNSServerResponseCodes(t *testing.T) {
+	assert := assert.On(t)
+
+	// The IPs of many.v2ray.com don't fit in 512 bytes.
+	many := make([]net.IP, 64)
+	for i := range many {
+		many[i] = net.IP([]byte{10, 0, 0, byte(i)})
+	}
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	space.BindApp(v2dns.APP_ID, &lookupServer{
+		ips: map[string][]net.IP{
+			"many.v2ray.com": many,
+		},
+	})
+
+	port := v2net.Port(dice.Roll(20000) + 10000)
+	server := NewServer(&Config{
+		NetworkList: &v2net.NetworkList{
+			Network: []v2net.Network{v2net.Network_TCP, v2net.Network_UDP},
+		},
+	}, space, &proxy.InboundHandlerMeta{
+		Address: v2net.LocalHostIP,
+		Port:    port,
+		StreamSettings: &internet.StreamSettings{
+			Type: internet.StreamConnectionTypeRawTCP,
+		}})
+	defer server.Close()
+
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+
+	response := server.Answer(new(dns.Msg).SetQuestion("nx.v2ray.com.", dns.TypeA))
+	assert.Int(response.Rcode).Equals(dns.RcodeNameError)
+	response = server.Answer(new(dns.Msg).SetQuestion("fail.v2ray.com.", dns.TypeA))
+	assert.Int(response.Rcode).Equals(dns.RcodeServerFailure)
+
+	udpClient := &dns.Client{Net: "udp"}
+	response, _, err := udpClient.Exchange(new(dns.Msg).SetQuestion("many.v2ray.com.", dns.TypeA), "127.0.0.1:"+port.String())
+	assert.Error(err).IsNil()
+	assert.Bool(response.Truncated).IsTrue()
+	assert.Bool(len(response.Answer) < len(many)).IsTrue()
+
+	// Clients advertising a larger size with EDNS get all records.
+	query := new(dns.Msg).SetQuestion("many.v2ray.com.", dns.TypeA)
+	query.SetEdns0(4096, false)
+	udpClient.UDPSize = 4096
+	response, _, err = udpClient.Exchange(query, "127.0.0.1:"+port.String())
+	assert.Error(err).IsNil()
+	assert.Bool(response.Truncated).IsFalse()
+	assert.Int(len(response.Answer)).Equals(len(many))
+
+	tcpClient := &dns.Client{Net: "tcp"}
+	response, _, err = tcpClient.Exchange(new(dns.Msg).SetQuestion("many.v2ray.com.", dns.TypeA), "127.0.0.1:"+port.String())
+	assert.Error(err).IsNil()
+	assert.Bool(response.Truncated).IsFalse()
+	assert.Int(len(response.Answer)).Equals(len(many))
+}
@@ -2,6 +2,7 @@ package dokodemo
 
 import (
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
 )
 
 func (this *Config) GetPredefinedAddress() v2net.Address {
@@ -11,3 +12,20 @@ func (this *Config) GetPredefinedAddress() v2net.Address {
 	}
 	return addr
 }
+
+// ListenNetworks implements internet.NetworkListener.
+func (this *Config) ListenNetworks() []v2net.Network {
+	if this.NetworkList == nil {
+		return nil
+	}
+	return this.NetworkList.Network
+}
+
+// RequiredCapabilities implements internet.CapabilityRequirer. Original destinations of UDP packets are received with
+// IP_TRANSPARENT.
+func (this *Config) RequiredCapabilities() []internet.Capability {
+	if this.FollowRedirect && this.NetworkList != nil && this.NetworkList.HasNetwork(v2net.Network_UDP) {
+		return []internet.Capability{internet.CapabilityTransparent}
+	}
+	return nil
+}
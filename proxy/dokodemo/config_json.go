@@ -4,8 +4,8 @@ package dokodemo
 
 import (
 	"encoding/json"
-	"errors"
 
+	"v2ray.com/core/common/loader"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy/registry"
 )
@@ -19,8 +19,8 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		Redirect     bool               `json:"followRedirect"`
 	}
 	rawConfig := new(DokodemoConfig)
-	if err := json.Unmarshal(data, rawConfig); err != nil {
-		return errors.New("Dokodemo: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, rawConfig); err != nil {
+		return loader.WrapError("Dokodemo: Failed to parse config: ", err)
 	}
 	if rawConfig.Host != nil {
 		this.Address = rawConfig.Host
@@ -32,6 +32,24 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type DokodemoConfig struct {
+		Host         *v2net.AddressPB   `json:"address,omitempty"`
+		PortValue    uint32             `json:"port,omitempty"`
+		NetworkList  *v2net.NetworkList `json:"network,omitempty"`
+		TimeoutValue uint32             `json:"timeout,omitempty"`
+		Redirect     bool               `json:"followRedirect,omitempty"`
+	}
+	return json.Marshal(&DokodemoConfig{
+		Host:         this.Address,
+		PortValue:    this.Port,
+		NetworkList:  this.NetworkList,
+		TimeoutValue: this.Timeout,
+		Redirect:     this.FollowRedirect,
+	})
+}
+
 func init() {
 	registry.RegisterInboundConfig("dokodemo-door", func() interface{} { return new(Config) })
 }
@@ -1,6 +1,7 @@
 package dokodemo
 
 import (
+	"context"
 	"sync"
 
 	"v2ray.com/core/app"
@@ -26,6 +27,7 @@ type DokodemoDoor struct {
 	tcpListener      *internet.TCPHub
 	udpHub           *udp.UDPHub
 	udpServer        *udp.UDPServer
+	udpOptions       *udp.SessionOptions
 	meta             *proxy.InboundHandlerMeta
 }
 
@@ -42,6 +44,7 @@ func NewDokodemoDoor(config *Config, space app.Space, meta *proxy.InboundHandler
 			return app.ErrMissingApplication
 		}
 		d.packetDispatcher = space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+		d.udpOptions = udp.SessionOptionsFromSpace(space)
 		return nil
 	})
 	return d
@@ -51,6 +54,11 @@ func (this *DokodemoDoor) Port() v2net.Port {
 	return this.meta.Port
 }
 
+// Accepting implements proxy.AcceptingReporter.
+func (this *DokodemoDoor) Accepting() bool {
+	return this.accepting
+}
+
 func (this *DokodemoDoor) Close() {
 	this.accepting = false
 	if this.tcpListener != nil {
@@ -89,14 +97,16 @@ func (this *DokodemoDoor) Start() error {
 }
 
 func (this *DokodemoDoor) ListenUDP() error {
-	this.udpServer = udp.NewUDPServer(this.meta, this.packetDispatcher)
+	this.udpServer = udp.NewUDPServer(this.meta, this.packetDispatcher, this.udpOptions)
 	udpHub, err := udp.ListenUDP(
 		this.meta.Address, this.meta.Port, udp.ListenOption{
 			Callback:            this.handleUDPPackets,
 			ReceiveOriginalDest: this.config.FollowRedirect,
+			SourceFilter:        this.meta.SourceFilter,
+			Bans:                this.meta.Bans,
 		})
 	if err != nil {
-		log.Error("Dokodemo failed to listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+		this.meta.Logger.Error("Dokodemo failed to listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
 		return err
 	}
 	this.udpMutex.Lock()
@@ -110,7 +120,7 @@ func (this *DokodemoDoor) handleUDPPackets(payload *alloc.Buffer, session *proxy
 		session.Destination = v2net.UDPDestination(this.address, this.port)
 	}
 	if session.Destination.Network == v2net.Network_Unknown {
-		log.Info("Dokodemo: Unknown destination, stop forwarding...")
+		this.meta.Logger.Info("Dokodemo: Unknown destination, stop forwarding...")
 		return
 	}
 	this.udpServer.Dispatch(session, payload, this.handleUDPResponse)
@@ -129,7 +139,7 @@ func (this *DokodemoDoor) handleUDPResponse(dest v2net.Destination, payload *all
 func (this *DokodemoDoor) ListenTCP() error {
 	tcpListener, err := internet.ListenTCP(this.meta.Address, this.meta.Port, this.HandleTCPConnection, this.meta.StreamSettings)
 	if err != nil {
-		log.Error("Dokodemo: Failed to listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+		this.meta.Logger.Error("Dokodemo: Failed to listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
 		return err
 	}
 	this.tcpMutex.Lock()
@@ -145,7 +155,7 @@ func (this *DokodemoDoor) HandleTCPConnection(conn internet.Connection) {
 	if this.config.FollowRedirect {
 		originalDest := GetOriginalDestination(conn)
 		if originalDest.Network != v2net.Network_Unknown {
-			log.Info("Dokodemo: Following redirect to: ", originalDest)
+			this.meta.Logger.Info("Dokodemo: Following redirect to: ", originalDest)
 			dest = originalDest
 		}
 	}
@@ -154,14 +164,19 @@ func (this *DokodemoDoor) HandleTCPConnection(conn internet.Connection) {
 	}
 
 	if dest.Network == v2net.Network_Unknown {
-		log.Info("Dokodemo: Unknown destination, stop forwarding...")
+		this.meta.Logger.Info("Dokodemo: Unknown destination, stop forwarding...")
 		return
 	}
-	log.Info("Dokodemo: Handling request to ", dest)
+	this.meta.Logger.Info("Dokodemo: Handling request to ", dest)
+
+	// The outbound stops once the connection is handled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	ray := this.packetDispatcher.DispatchToOutbound(this.meta, &proxy.SessionInfo{
+	ray := this.packetDispatcher.DispatchToOutbound(ctx, this.meta, &proxy.SessionInfo{
 		Source:      v2net.DestinationFromAddr(conn.RemoteAddr()),
 		Destination: dest,
+		User:        proxy.CertificateUser(conn),
 	})
 	defer ray.InboundOutput().Release()
 
@@ -1,11 +1,13 @@
 package proxy
 
 import (
-	"errors"
+	"v2ray.com/core/common/errors"
 )
 
 var (
-	ErrInvalidAuthentication  = errors.New("Invalid authentication.")
-	ErrInvalidProtocolVersion = errors.New("Invalid protocol version.")
-	ErrAlreadyListening       = errors.New("Already listening on another port.")
+	ErrInvalidAuthentication  = errors.New("Invalid authentication.").WithCode(errors.CodeAuthFailed).WithSeverity(errors.SeverityInfo)
+	ErrInvalidProtocolVersion = errors.New("Invalid protocol version.").WithCode(errors.CodeProtocol)
+	ErrAlreadyListening       = errors.New("Already listening on another port.").WithCode(errors.CodeBadConfiguration)
+	ErrHandlerClosed          = errors.New("Handler is closed.").WithCode(errors.CodeClosed)
+	ErrUserNotFound           = errors.New("User not found.").WithCode(errors.CodeNotFound)
 )
@@ -31,17 +31,23 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 type Config_DomainStrategy int32
 
 const (
-	Config_AS_IS  Config_DomainStrategy = 0
-	Config_USE_IP Config_DomainStrategy = 1
+	Config_AS_IS   Config_DomainStrategy = 0
+	Config_USE_IP  Config_DomainStrategy = 1
+	Config_USE_IP4 Config_DomainStrategy = 2
+	Config_USE_IP6 Config_DomainStrategy = 3
 )
 
 var Config_DomainStrategy_name = map[int32]string{
 	0: "AS_IS",
 	1: "USE_IP",
+	2: "USE_IP4",
+	3: "USE_IP6",
 }
 var Config_DomainStrategy_value = map[string]int32{
-	"AS_IS":  0,
-	"USE_IP": 1,
+	"AS_IS":   0,
+	"USE_IP":  1,
+	"USE_IP4": 2,
+	"USE_IP6": 3,
 }
 
 func (x Config_DomainStrategy) String() string {
@@ -67,18 +73,19 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/proxy/freedom/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 199 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xd2, 0x2c, 0x33, 0x2a, 0x4a,
+	// 209 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xd2, 0x2c, 0x33, 0x2a, 0x4a,
 	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x2f, 0x28, 0xca, 0xaf, 0xa8,
 	0xd4, 0x4f, 0x2b, 0x4a, 0x4d, 0x4d, 0x01, 0x0b, 0xe5, 0xa5, 0x65, 0xa6, 0xeb, 0x15, 0x14, 0xe5,
-	0x97, 0xe4, 0x0b, 0x49, 0xc0, 0x94, 0x16, 0xa5, 0xea, 0x81, 0x95, 0xe9, 0x41, 0x95, 0x29, 0x2d,
-	0x61, 0xe4, 0x62, 0x73, 0x06, 0x2b, 0x15, 0x0a, 0xe7, 0xe2, 0x4b, 0xc9, 0xcf, 0x4d, 0xcc, 0xcc,
+	0x97, 0xe4, 0x0b, 0x49, 0xc0, 0x94, 0x16, 0xa5, 0xea, 0x81, 0x95, 0xe9, 0x41, 0x95, 0x29, 0xed,
+	0x63, 0xe4, 0x62, 0x73, 0x06, 0x2b, 0x15, 0x0a, 0xe7, 0xe2, 0x4b, 0xc9, 0xcf, 0x4d, 0xcc, 0xcc,
 	0x0b, 0x2e, 0x29, 0x4a, 0x2c, 0x49, 0x4d, 0xaf, 0x94, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x33, 0xd2,
 	0xd7, 0xc3, 0xa5, 0x5b, 0x0f, 0xa2, 0x53, 0xcf, 0x05, 0x45, 0x5b, 0x10, 0x9a, 0x31, 0x42, 0x12,
 	0x5c, 0xec, 0x25, 0x99, 0xb9, 0xa9, 0xf9, 0xa5, 0x25, 0x12, 0x4c, 0x0a, 0x8c, 0x1a, 0xbc, 0x41,
-	0x30, 0xae, 0x92, 0x3a, 0x17, 0x1f, 0xaa, 0x5e, 0x21, 0x4e, 0x2e, 0x56, 0xc7, 0xe0, 0x78, 0xcf,
-	0x60, 0x01, 0x06, 0x21, 0x2e, 0x2e, 0xb6, 0xd0, 0x60, 0xd7, 0x78, 0xcf, 0x00, 0x01, 0x46, 0x27,
-	0x13, 0x2e, 0x99, 0xe4, 0xfc, 0x5c, 0x9c, 0x0e, 0x71, 0xe2, 0x86, 0xb8, 0x24, 0x00, 0xe4, 0xdb,
-	0x28, 0x76, 0xa8, 0x68, 0x12, 0x1b, 0xd8, 0xf7, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x36,
-	0x2d, 0x5d, 0x28, 0x2a, 0x01, 0x00, 0x00,
+	0x30, 0xae, 0x92, 0x23, 0x17, 0x1f, 0xaa, 0x5e, 0x21, 0x4e, 0x2e, 0x56, 0xc7, 0xe0, 0x78, 0xcf,
+	0x60, 0x01, 0x06, 0x21, 0x2e, 0x2e, 0xb6, 0xd0, 0x60, 0xd7, 0x78, 0xcf, 0x00, 0x01, 0x46, 0x21,
+	0x6e, 0x2e, 0x76, 0x08, 0xdb, 0x44, 0x80, 0x09, 0xc1, 0x31, 0x13, 0x60, 0x76, 0x32, 0xe1, 0x92,
+	0x49, 0xce, 0xcf, 0xc5, 0xe9, 0x44, 0x27, 0x6e, 0x88, 0x1b, 0x03, 0x40, 0xe1, 0x10, 0xc5, 0x0e,
+	0x15, 0x4d, 0x62, 0x03, 0x87, 0x8b, 0x31, 0x60, 0x00, 0x77, 0x22, 0x10, 0x66, 0x44, 0x01, 0x00,
+	0x00,
 }
@@ -4,9 +4,9 @@ package freedom
 
 import (
 	"encoding/json"
-	"errors"
 	"strings"
 
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/proxy/registry"
 )
 
@@ -16,18 +16,43 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		Timeout        uint32 `json:"timeout"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Freedom: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Freedom: Failed to parse config: ", err)
 	}
 	this.DomainStrategy = Config_AS_IS
 	domainStrategy := strings.ToLower(jsonConfig.DomainStrategy)
-	if domainStrategy == "useip" || domainStrategy == "use_ip" {
+	switch domainStrategy {
+	case "useip", "use_ip":
 		this.DomainStrategy = Config_USE_IP
+	case "useipv4", "use_ip4":
+		this.DomainStrategy = Config_USE_IP4
+	case "useipv6", "use_ip6":
+		this.DomainStrategy = Config_USE_IP6
 	}
 	this.Timeout = jsonConfig.Timeout
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type JsonConfig struct {
+		DomainStrategy string `json:"domainStrategy,omitempty"`
+		Timeout        uint32 `json:"timeout,omitempty"`
+	}
+	jsonConfig := &JsonConfig{
+		Timeout: this.Timeout,
+	}
+	switch this.DomainStrategy {
+	case Config_USE_IP:
+		jsonConfig.DomainStrategy = "useip"
+	case Config_USE_IP4:
+		jsonConfig.DomainStrategy = "useipv4"
+	case Config_USE_IP6:
+		jsonConfig.DomainStrategy = "useipv6"
+	}
+	return json.Marshal(jsonConfig)
+}
+
 func init() {
 	registry.RegisterOutboundConfig("freedom", func() interface{} { return new(Config) })
 }
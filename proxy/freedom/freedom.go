@@ -1,7 +1,9 @@
 package freedom
 
 import (
+	"context"
 	"io"
+	"net"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dns"
@@ -10,6 +12,7 @@ import (
 	v2io "v2ray.com/core/common/io"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/common/retry"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/proxy/registry"
@@ -32,7 +35,7 @@ func NewFreedomConnection(config *Config, space app.Space, meta *proxy.OutboundH
 		meta:           meta,
 	}
 	space.InitializeApplication(func() error {
-		if config.DomainStrategy == Config_USE_IP {
+		if config.DomainStrategy != Config_AS_IS {
 			if !space.HasApp(dns.APP_ID) {
 				log.Error("Freedom: DNS server is not found in the space.")
 				return app.ErrMissingApplication
@@ -44,15 +47,29 @@ func NewFreedomConnection(config *Config, space app.Space, meta *proxy.OutboundH
 	return f
 }
 
+// filterIPs returns the IPs of the family in domain strategy.
+func (this *FreedomConnection) filterIPs(ips []net.IP) []net.IP {
+	if this.domainStrategy != Config_USE_IP4 && this.domainStrategy != Config_USE_IP6 {
+		return ips
+	}
+	filtered := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (this.domainStrategy == Config_USE_IP4) {
+			filtered = append(filtered, ip)
+		}
+	}
+	return filtered
+}
+
 // Private: Visible for testing.
 func (this *FreedomConnection) ResolveIP(destination v2net.Destination) v2net.Destination {
 	if !destination.Address.Family().IsDomain() {
 		return destination
 	}
 
-	ips := this.dns.Get(destination.Address.Domain())
+	ips := this.filterIPs(this.dns.Get(destination.Address.Domain()))
 	if len(ips) == 0 {
-		log.Info("Freedom: DNS returns nil answer. Keep domain as is.")
+		this.meta.Logger.Info("Freedom: DNS returns nil answer. Keep domain as is.")
 		return destination
 	}
 
@@ -63,34 +80,42 @@ func (this *FreedomConnection) ResolveIP(destination v2net.Destination) v2net.De
 	} else {
 		newDest = v2net.UDPDestination(v2net.IPAddress(ip), destination.Port)
 	}
-	log.Info("Freedom: Changing destination from ", destination, " to ", newDest)
+	this.meta.Logger.Info("Freedom: Changing destination from ", destination, " to ", newDest)
 	return newDest
 }
 
-func (this *FreedomConnection) Dispatch(destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
-	log.Info("Freedom: Opening connection to ", destination)
+func (this *FreedomConnection) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
+	if protocol.IsPacketAddrDestination(destination) {
+		return this.dispatchPacketAddr(ctx, payload, ray)
+	}
+
+	this.meta.Logger.Info("Freedom: Opening connection to ", destination)
 
 	defer payload.Release()
 	defer ray.OutboundInput().Release()
 	defer ray.OutboundOutput().Close()
 
 	var conn internet.Connection
-	if this.domainStrategy == Config_USE_IP && destination.Address.Family().IsDomain() {
+	if this.domainStrategy != Config_AS_IS && destination.Address.Family().IsDomain() {
 		destination = this.ResolveIP(destination)
 	}
+	var dialErr error
 	err := retry.Timed(5, 100).On(func() error {
-		rawConn, err := internet.Dial(this.meta.Address, destination, this.meta.StreamSettings)
+		rawConn, err := internet.DialContext(ctx, this.meta.Address, destination, this.meta.StreamSettings)
 		if err != nil {
+			dialErr = err
 			return err
 		}
 		conn = rawConn
 		return nil
 	})
 	if err != nil {
-		log.Warning("Freedom: Failed to open connection to ", destination, ": ", err)
-		return err
+		// The error of the last attempt tells why, e.g. a timeout or a cancellation.
+		this.meta.Logger.Err(dialErr, "Freedom: Failed to open connection to ", destination, ": ")
+		return dialErr
 	}
 	defer conn.Close()
+	defer internet.AbortOnDone(ctx, conn)()
 
 	input := ray.OutboundInput()
 	output := ray.OutboundOutput()
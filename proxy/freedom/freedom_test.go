@@ -1,6 +1,7 @@
 package freedom_test
 
 import (
+	"context"
 	"testing"
 
 	"v2ray.com/core/app"
@@ -11,11 +12,14 @@ import (
 	"v2ray.com/core/app/router"
 	"v2ray.com/core/app/router/rules"
 	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/errors"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy"
 	. "v2ray.com/core/proxy/freedom"
 	"v2ray.com/core/testing/assert"
 	"v2ray.com/core/testing/servers/tcp"
+	"v2ray.com/core/testing/servers/udp"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/ray"
 )
@@ -50,7 +54,7 @@ func TestSinglePacket(t *testing.T) {
 	data2Send := "Data to be sent to remote"
 	payload := alloc.NewLocalBuffer(2048).Clear().Append([]byte(data2Send))
 
-	go freedom.Dispatch(v2net.TCPDestination(v2net.LocalHostIP, tcpServer.Port), payload, traffic)
+	go freedom.Dispatch(context.Background(), v2net.TCPDestination(v2net.LocalHostIP, tcpServer.Port), payload, traffic)
 	traffic.InboundInput().Close()
 
 	respPayload, err := traffic.InboundOutput().Read()
@@ -60,6 +64,59 @@ func TestSinglePacket(t *testing.T) {
 	tcpServer.Close()
 }
 
+func TestPacketAddr(t *testing.T) {
+	assert := assert.On(t)
+
+	var servers []*udp.Server
+	var dests []v2net.Destination
+	for i := 0; i < 2; i++ {
+		prefix := []byte{byte('A' + i)}
+		server := &udp.Server{
+			MsgProcessor: func(data []byte) []byte {
+				return append(append([]byte(nil), prefix...), data...)
+			},
+		}
+		dest, err := server.Start()
+		assert.Error(err).IsNil()
+		defer server.Close()
+		servers = append(servers, server)
+		dests = append(dests, dest)
+	}
+
+	space := app.NewSpace()
+	freedom := NewFreedomConnection(
+		&Config{},
+		space,
+		&proxy.OutboundHandlerMeta{
+			Address: v2net.AnyIP,
+			StreamSettings: &internet.StreamSettings{
+				Type: internet.StreamConnectionTypeRawTCP,
+			},
+		})
+	space.Initialize()
+
+	traffic := ray.NewRay()
+	payload, err := protocol.EncodePacketAddr(dests[0], alloc.NewLocalBuffer(2048).Clear().AppendString("0"))
+	assert.Error(err).IsNil()
+	go freedom.Dispatch(context.Background(), protocol.PacketAddrDestination(), payload, traffic)
+	payload, err = protocol.EncodePacketAddr(dests[1], alloc.NewLocalBuffer(2048).Clear().AppendString("1"))
+	assert.Error(err).IsNil()
+	traffic.InboundInput().Write(payload)
+
+	responses := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		response, err := traffic.InboundOutput().Read()
+		assert.Error(err).IsNil()
+		source, err := protocol.DecodePacketAddr(response)
+		assert.Error(err).IsNil()
+		responses[source.String()] = response.String()
+	}
+	assert.String(responses[dests[0].String()]).Equals("A0")
+	assert.String(responses[dests[1].String()]).Equals("B1")
+
+	traffic.InboundInput().Close()
+}
+
 func TestUnreachableDestination(t *testing.T) {
 	assert := assert.On(t)
 
@@ -76,8 +133,10 @@ func TestUnreachableDestination(t *testing.T) {
 	data2Send := "Data to be sent to remote"
 	payload := alloc.NewLocalBuffer(2048).Clear().Append([]byte(data2Send))
 
-	err := freedom.Dispatch(v2net.TCPDestination(v2net.IPAddress([]byte{127, 0, 0, 1}), 128), payload, traffic)
+	err := freedom.Dispatch(context.Background(), v2net.TCPDestination(v2net.IPAddress([]byte{127, 0, 0, 1}), 128), payload, traffic)
 	assert.Error(err).IsNotNil()
+	// The connection is refused, which doesn't mean that the network is unreachable.
+	assert.Bool(errors.HasCode(err, errors.CodeNetworkUnreachable)).IsFalse()
 }
 
 func TestIPResolution(t *testing.T) {
@@ -115,3 +174,36 @@ func TestIPResolution(t *testing.T) {
 	assert.Destination(ipDest).IsTCP()
 	assert.Address(ipDest.Address).Equals(v2net.LocalHostIP)
 }
+
+func TestIPResolutionStrategy(t *testing.T) {
+	assert := assert.On(t)
+
+	resolve := func(strategy Config_DomainStrategy) v2net.Destination {
+		space := app.NewSpace()
+		space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+		space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+		space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{
+			Hosts: map[string]*v2net.AddressPB{
+				"v2ray.com": &v2net.AddressPB{
+					Address: &v2net.AddressPB_Ip{
+						Ip: []byte{127, 0, 0, 1},
+					},
+				},
+			},
+		}))
+		freedom := NewFreedomConnection(
+			&Config{DomainStrategy: strategy},
+			space,
+			&proxy.OutboundHandlerMeta{
+				Address: v2net.AnyIP,
+				StreamSettings: &internet.StreamSettings{
+					Type: internet.StreamConnectionTypeRawTCP,
+				},
+			})
+		assert.Error(space.Initialize()).IsNil()
+		return freedom.ResolveIP(v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), v2net.Port(80)))
+	}
+
+	assert.Address(resolve(Config_USE_IP4).Address).Equals(v2net.LocalHostIP)
+	assert.Address(resolve(Config_USE_IP6).Address).Equals(v2net.DomainAddress("v2ray.com"))
+}
@@ -0,0 +1,122 @@
+package freedom
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	packetAddrIdleTimeout = time.Second * 16
+)
+
+// dispatchPacketAddr serves a packet-addressed UDP session with a single socket. All remote addresses see the same
+// local port, and responses from any of them are sent back, which makes the mapping full cone.
+func (this *FreedomConnection) dispatchPacketAddr(ctx context.Context, payload *alloc.Buffer, ray ray.OutboundRay) error {
+	defer ray.OutboundInput().Release()
+	defer ray.OutboundOutput().Close()
+
+	conn, err := internet.ListenPacketFromSource(this.meta.Address)
+	if err != nil {
+		payload.Release()
+		this.meta.Logger.Warning("Freedom: Failed to open UDP socket: ", err)
+		return err
+	}
+	defer conn.Close()
+	this.meta.Logger.Info("Freedom: Opening packet-addressed UDP session on ", conn.LocalAddr())
+
+	input := ray.OutboundInput()
+	output := ray.OutboundOutput()
+
+	lastActivity := time.Now().UnixNano()
+	inputDone := make(chan bool)
+	go func() {
+		defer close(inputDone)
+
+		if !payload.IsEmpty() {
+			this.writePacket(conn, payload)
+		} else {
+			payload.Release()
+		}
+		for {
+			buffer, err := input.Read()
+			if err != nil {
+				return
+			}
+			atomic.StoreInt64(&lastActivity, time.Now().UnixNano())
+			this.writePacket(conn, buffer)
+		}
+	}()
+
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-stopWatching:
+		}
+	}()
+
+	for ctx.Err() == nil {
+		buffer := alloc.NewBuffer()
+		conn.SetReadDeadline(time.Now().Add(packetAddrIdleTimeout))
+		nBytes, addr, err := conn.ReadFrom(buffer.Value)
+		if err != nil {
+			buffer.Release()
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() && ctx.Err() == nil {
+				select {
+				case <-inputDone:
+				default:
+					if time.Since(time.Unix(0, atomic.LoadInt64(&lastActivity))) < packetAddrIdleTimeout {
+						continue
+					}
+				}
+			}
+			break
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			buffer.Release()
+			continue
+		}
+		buffer.Slice(0, nBytes)
+		source := v2net.UDPDestination(v2net.IPAddress(udpAddr.IP), v2net.Port(udpAddr.Port))
+		encoded, err := protocol.EncodePacketAddr(source, buffer)
+		if err != nil {
+			continue
+		}
+		if err := output.Write(encoded); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (this *FreedomConnection) writePacket(conn net.PacketConn, payload *alloc.Buffer) {
+	defer payload.Release()
+
+	dest, err := protocol.DecodePacketAddr(payload)
+	if err != nil {
+		this.meta.Logger.Warning("Freedom: Dropping invalid packet-addressed payload: ", err)
+		return
+	}
+	if this.domainStrategy != Config_AS_IS && dest.Address.Family().IsDomain() {
+		dest = this.ResolveIP(dest)
+	}
+	addr, err := internet.ResolveUDPAddr(dest, this.meta.StreamSettings)
+	if err != nil {
+		this.meta.Logger.Info("Freedom: Failed to resolve ", dest, ": ", err)
+		return
+	}
+	if _, err := conn.WriteTo(payload.Value, addr); err != nil {
+		this.meta.Logger.Info("Freedom: Failed to write UDP packet to ", dest, ": ", err)
+	}
+}
@@ -4,8 +4,8 @@ package http
 
 import (
 	"encoding/json"
-	"errors"
 
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/proxy/registry"
 )
 
@@ -15,14 +15,24 @@ func (this *ServerConfig) UnmarshalJSON(data []byte) error {
 		Timeout uint32 `json:"timeout"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("HTTP: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("HTTP: Failed to parse config: ", err)
 	}
 	this.Timeout = jsonConfig.Timeout
 
 	return nil
 }
 
+// MarshalJSON implements json.Marshaler
+func (this *ServerConfig) MarshalJSON() ([]byte, error) {
+	type JsonConfig struct {
+		Timeout uint32 `json:"timeout,omitempty"`
+	}
+	return json.Marshal(&JsonConfig{
+		Timeout: this.Timeout,
+	})
+}
+
 func init() {
 	registry.RegisterInboundConfig("http", func() interface{} { return new(ServerConfig) })
 }
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"net"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/common"
 	v2io "v2ray.com/core/common/io"
 	"v2ray.com/core/common/log"
@@ -27,6 +29,7 @@ type Server struct {
 	accepting        bool
 	packetDispatcher dispatcher.PacketDispatcher
 	config           *ServerConfig
+	policy           *policy.Policy
 	tcpListener      *internet.TCPHub
 	meta             *proxy.InboundHandlerMeta
 }
@@ -35,6 +38,7 @@ func NewServer(config *ServerConfig, packetDispatcher dispatcher.PacketDispatche
 	return &Server{
 		packetDispatcher: packetDispatcher,
 		config:           config,
+		policy:           policy.DefaultPolicy(0),
 		meta:             meta,
 	}
 }
@@ -43,6 +47,11 @@ func (this *Server) Port() v2net.Port {
 	return this.meta.Port
 }
 
+// Accepting implements proxy.AcceptingReporter.
+func (this *Server) Accepting() bool {
+	return this.accepting
+}
+
 func (this *Server) Close() {
 	this.accepting = false
 	if this.tcpListener != nil {
@@ -60,7 +69,7 @@ func (this *Server) Start() error {
 
 	tcpListener, err := internet.ListenTCP(this.meta.Address, this.meta.Port, this.handleConnection, this.meta.StreamSettings)
 	if err != nil {
-		log.Error("HTTP: Failed listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+		this.meta.Logger.Error("HTTP: Failed listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
 		return err
 	}
 	this.Lock()
@@ -95,17 +104,18 @@ func parseHost(rawHost string, defaultPort v2net.Port) (v2net.Destination, error
 
 func (this *Server) handleConnection(conn internet.Connection) {
 	defer conn.Close()
-	timedReader := v2net.NewTimeOutReader(this.config.Timeout, conn)
+	timedReader := v2net.NewTimeOutReader(policy.Seconds(this.policy.Handshake), conn)
 	reader := bufio.NewReaderSize(timedReader, 2048)
 
 	request, err := http.ReadRequest(reader)
 	if err != nil {
 		if err != io.EOF {
-			log.Warning("HTTP: Failed to read http request: ", err)
+			this.meta.Logger.Warning("HTTP: Failed to read http request: ", err)
 		}
 		return
 	}
-	log.Info("HTTP: Request to Method [", request.Method, "] Host [", request.Host, "] with URL [", request.URL, "]")
+	timedReader.SetTimeOut(this.config.Timeout)
+	this.meta.Logger.Info("HTTP: Request to Method [", request.Method, "] Host [", request.Host, "] with URL [", request.URL, "]")
 	defaultPort := v2net.Port(80)
 	if strings.ToLower(request.URL.Scheme) == "https" {
 		defaultPort = v2net.Port(443)
@@ -116,22 +126,27 @@ func (this *Server) handleConnection(conn internet.Connection) {
 	}
 	dest, err := parseHost(host, defaultPort)
 	if err != nil {
-		log.Warning("HTTP: Malformed proxy host (", host, "): ", err)
+		this.meta.Logger.Warning("HTTP: Malformed proxy host (", host, "): ", err)
 		return
 	}
-	log.Access(conn.RemoteAddr(), request.URL, log.AccessAccepted, "")
+	this.meta.Logger.Access(conn.RemoteAddr(), request.URL, log.AccessAccepted, "")
 	session := &proxy.SessionInfo{
 		Source:      v2net.DestinationFromAddr(conn.RemoteAddr()),
 		Destination: dest,
+		User:        proxy.CertificateUser(conn),
 	}
+	// The outbound stops once the connection is handled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	if strings.ToUpper(request.Method) == "CONNECT" {
-		this.handleConnect(request, session, reader, conn)
+		this.handleConnect(ctx, request, session, reader, conn)
 	} else {
-		this.handlePlainHTTP(request, session, reader, conn)
+		this.handlePlainHTTP(ctx, request, session, reader, conn)
 	}
 }
 
-func (this *Server) handleConnect(request *http.Request, session *proxy.SessionInfo, reader io.Reader, writer io.Writer) {
+func (this *Server) handleConnect(ctx context.Context, request *http.Request, session *proxy.SessionInfo, reader io.Reader, writer io.Writer) {
 	response := &http.Response{
 		Status:        "200 OK",
 		StatusCode:    200,
@@ -145,7 +160,7 @@ func (this *Server) handleConnect(request *http.Request, session *proxy.SessionI
 	}
 	response.Write(writer)
 
-	ray := this.packetDispatcher.DispatchToOutbound(this.meta, session)
+	ray := this.packetDispatcher.DispatchToOutbound(ctx, this.meta, session)
 	this.transport(reader, writer, ray)
 }
 
@@ -214,7 +229,7 @@ func (this *Server) GenerateResponse(statusCode int, status string) *http.Respon
 	}
 }
 
-func (this *Server) handlePlainHTTP(request *http.Request, session *proxy.SessionInfo, reader *bufio.Reader, writer io.Writer) {
+func (this *Server) handlePlainHTTP(ctx context.Context, request *http.Request, session *proxy.SessionInfo, reader *bufio.Reader, writer io.Writer) {
 	if len(request.URL.Host) <= 0 {
 		response := this.GenerateResponse(400, "Bad Request")
 		response.Write(writer)
@@ -225,7 +240,7 @@ func (this *Server) handlePlainHTTP(request *http.Request, session *proxy.Sessio
 	request.Host = request.URL.Host
 	StripHopByHopHeaders(request)
 
-	ray := this.packetDispatcher.DispatchToOutbound(this.meta, session)
+	ray := this.packetDispatcher.DispatchToOutbound(ctx, this.meta, session)
 	defer ray.InboundInput().Close()
 	defer ray.InboundOutput().Release()
 
@@ -236,7 +251,7 @@ func (this *Server) handlePlainHTTP(request *http.Request, session *proxy.Sessio
 		requestWriter := v2io.NewBufferedWriter(v2io.NewChainWriter(ray.InboundInput()))
 		err := request.Write(requestWriter)
 		if err != nil {
-			log.Warning("HTTP: Failed to write request: ", err)
+			this.meta.Logger.Warning("HTTP: Failed to write request: ", err)
 			return
 		}
 		requestWriter.Flush()
@@ -248,13 +263,13 @@ func (this *Server) handlePlainHTTP(request *http.Request, session *proxy.Sessio
 		responseReader := bufio.NewReader(v2io.NewChanReader(ray.InboundOutput()))
 		response, err := http.ReadResponse(responseReader, request)
 		if err != nil {
-			log.Warning("HTTP: Failed to read response: ", err)
+			this.meta.Logger.Warning("HTTP: Failed to read response: ", err)
 			response = this.GenerateResponse(503, "Service Unavailable")
 		}
 		responseWriter := v2io.NewBufferedWriter(writer)
 		err = response.Write(responseWriter)
 		if err != nil {
-			log.Warning("HTTP: Failed to write response: ", err)
+			this.meta.Logger.Warning("HTTP: Failed to write response: ", err)
 			return
 		}
 		responseWriter.Flush()
@@ -272,10 +287,12 @@ func (this *ServerFactory) Create(space app.Space, rawConfig interface{}, meta *
 	if !space.HasApp(dispatcher.APP_ID) {
 		return nil, common.ErrBadConfiguration
 	}
-	return NewServer(
+	server := NewServer(
 		rawConfig.(*ServerConfig),
 		space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher),
-		meta), nil
+		meta)
+	server.policy = policy.FromSpace(space).ForLevel(0)
+	return server, nil
 }
 
 func init() {
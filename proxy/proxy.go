@@ -2,7 +2,12 @@
 package proxy
 
 import (
+	"context"
+
 	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ban"
+	"v2ray.com/core/common/event"
+	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/transport/internet"
@@ -28,12 +33,25 @@ type InboundHandlerMeta struct {
 	Port                   v2net.Port
 	AllowPassiveConnection bool
 	StreamSettings         *internet.StreamSettings
+	// DefaultOutboundTag is the outbound for connections of this inbound that no routing rule matches. Empty for the
+	// default outbound.
+	DefaultOutboundTag string
+	// SourceFilter rejects connections and packets from disallowed sources. Nil allows all.
+	SourceFilter *v2net.IPFilter
+	// Logger writes access logs and error logs of the inbound. Nil for the default logger.
+	Logger *log.Logger
+	// Bans rejects banned sources, and bans sources that fail authentication too often. Nil bans nothing.
+	Bans *ban.List
+	// Events receives events of the inbound, e.g. authentication failures. Nil drops them.
+	Events *event.Bus
 }
 
 type OutboundHandlerMeta struct {
 	Tag            string
 	Address        v2net.Address
 	StreamSettings *internet.StreamSettings
+	// Logger writes error logs of the outbound. Nil for the default logger.
+	Logger *log.Logger
 }
 
 // An InboundHandler handles inbound network connections to V2Ray.
@@ -46,8 +64,21 @@ type InboundHandler interface {
 	Port() v2net.Port
 }
 
+// An AcceptingReporter is an InboundHandler that reports whether it is accepting connections, e.g. for health checks.
+type AcceptingReporter interface {
+	Accepting() bool
+}
+
+// A UserManager is an InboundHandler whose users can be added and removed while it is running.
+type UserManager interface {
+	AddUser(user *protocol.User) error
+	// RemoveUser removes the user with the given email.
+	RemoveUser(email string) error
+}
+
 // An OutboundHandler handles outbound network connection for V2Ray.
 type OutboundHandler interface {
-	// Dispatch sends one or more Packets to its destination.
-	Dispatch(destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error
+	// Dispatch sends one or more Packets to its destination. The handler stops once ctx is done, e.g. when the
+	// connection is closed or V2Ray shuts down.
+	Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error
 }
@@ -1,8 +1,14 @@
 package registry
 
 import (
+	"v2ray.com/core"
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/events"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet"
 )
@@ -17,6 +23,7 @@ func RegisterInboundHandlerCreator(name string, creator InboundHandlerFactory) e
 		return common.ErrDuplicatedName
 	}
 	inboundFactories[name] = creator
+	core.RegisterFeature("inbound:" + name)
 	return nil
 }
 
@@ -31,6 +38,7 @@ func RegisterOutboundHandlerCreator(name string, creator OutboundHandlerFactory)
 		return common.ErrDuplicatedName
 	}
 	outboundFactories[name] = creator
+	core.RegisterFeature("outbound:" + name)
 	return nil
 }
 
@@ -40,7 +48,31 @@ func MustRegisterOutboundHandlerCreator(name string, creator OutboundHandlerFact
 	}
 }
 
+// allowedStreamTypes returns the stream types that handlers of the given capability accept in their settings. Custom
+// transports and plugins carry streams as raw TCP does, so they are allowed for handlers capable of raw TCP.
+func allowedStreamTypes(capability internet.StreamConnectionType) internet.StreamConnectionType {
+	if capability&internet.StreamConnectionTypeRawTCP != 0 {
+		capability |= internet.StreamConnectionTypeCustom | internet.StreamConnectionTypePlugin
+	}
+	return capability
+}
+
+// CreateInboundHandler creates an inbound handler of the settings in JSON.
 func CreateInboundHandler(name string, space app.Space, rawConfig []byte, meta *proxy.InboundHandlerMeta) (proxy.InboundHandler, error) {
+	var proxyConfig interface{}
+	if len(rawConfig) > 0 {
+		config, err := CreateInboundConfig(name, rawConfig)
+		if err != nil {
+			return nil, err
+		}
+		proxyConfig = config
+	}
+	return CreateInboundHandlerWithConfig(name, space, proxyConfig, meta)
+}
+
+// CreateInboundHandlerWithConfig creates an inbound handler of decoded settings, e.g. from a protobuf config. Nil
+// config takes the defaults of the protocol.
+func CreateInboundHandlerWithConfig(name string, space app.Space, proxyConfig interface{}, meta *proxy.InboundHandlerMeta) (proxy.InboundHandler, error) {
 	creator, found := inboundFactories[name]
 	if !found {
 		return nil, common.ErrObjectNotFound
@@ -50,20 +82,41 @@ func CreateInboundHandler(name string, space app.Space, rawConfig []byte, meta *
 			Type: creator.StreamCapability(),
 		}
 	} else {
-		meta.StreamSettings.Type &= creator.StreamCapability()
+		meta.StreamSettings.Type &= allowedStreamTypes(creator.StreamCapability())
+	}
+	// Inbounds log accesses, ban sources and publish events in the instance they belong to.
+	if meta.Logger == nil {
+		meta.Logger = applog.FromSpace(space)
+	}
+	if meta.Bans == nil {
+		meta.Bans = policy.FromSpace(space).Bans()
+	}
+	if meta.Events == nil {
+		meta.Events = events.FromSpace(space)
 	}
+	meta.StreamSettings.SourceFilter = meta.SourceFilter
+	meta.StreamSettings.Bans = meta.Bans
+	meta.StreamSettings.Logger = meta.Logger
 
+	return creator.Create(space, proxyConfig, meta)
+}
+
+// CreateOutboundHandler creates an outbound handler of the settings in JSON.
+func CreateOutboundHandler(name string, space app.Space, rawConfig []byte, meta *proxy.OutboundHandlerMeta) (proxy.OutboundHandler, error) {
+	var proxyConfig interface{}
 	if len(rawConfig) > 0 {
-		proxyConfig, err := CreateInboundConfig(name, rawConfig)
+		config, err := CreateOutboundConfig(name, rawConfig)
 		if err != nil {
 			return nil, err
 		}
-		return creator.Create(space, proxyConfig, meta)
+		proxyConfig = config
 	}
-	return creator.Create(space, nil, meta)
+	return CreateOutboundHandlerWithConfig(name, space, proxyConfig, meta)
 }
 
-func CreateOutboundHandler(name string, space app.Space, rawConfig []byte, meta *proxy.OutboundHandlerMeta) (proxy.OutboundHandler, error) {
+// CreateOutboundHandlerWithConfig creates an outbound handler of decoded settings, e.g. from a protobuf config. Nil
+// config takes the defaults of the protocol.
+func CreateOutboundHandlerWithConfig(name string, space app.Space, proxyConfig interface{}, meta *proxy.OutboundHandlerMeta) (proxy.OutboundHandler, error) {
 	creator, found := outboundFactories[name]
 	if !found {
 		return nil, common.ErrObjectNotFound
@@ -73,16 +126,27 @@ func CreateOutboundHandler(name string, space app.Space, rawConfig []byte, meta
 			Type: creator.StreamCapability(),
 		}
 	} else {
-		meta.StreamSettings.Type &= creator.StreamCapability()
+		meta.StreamSettings.Type &= allowedStreamTypes(creator.StreamCapability())
 	}
-
-	if len(rawConfig) > 0 {
-		proxyConfig, err := CreateOutboundConfig(name, rawConfig)
-		if err != nil {
-			return nil, err
-		}
-		return creator.Create(space, proxyConfig, meta)
+	if meta.Logger == nil {
+		meta.Logger = applog.FromSpace(space)
+	}
+	meta.StreamSettings.Logger = meta.Logger
+	if resolver, ok := meta.StreamSettings.Resolver.(*internet.InternalResolver); ok {
+		bindInternalResolver(space, resolver)
 	}
 
-	return creator.Create(space, nil, meta)
+	return creator.Create(space, proxyConfig, meta)
+}
+
+// bindInternalResolver resolves the destinations of an outbound handler via the DNS app in the space.
+func bindInternalResolver(space app.Space, resolver *internet.InternalResolver) {
+	space.InitializeApplication(func() error {
+		if !space.HasApp(dns.APP_ID) {
+			log.Error("Proxy|Registry: DNS server is not found in the space.")
+			return app.ErrMissingApplication
+		}
+		resolver.Bind(space.GetApp(dns.APP_ID).(dns.Server).Get)
+		return nil
+	})
 }
@@ -0,0 +1,52 @@
+package registry_test
+
+import (
+	"testing"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/registry"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+)
+
+type rawTCPFactory struct{}
+
+func (rawTCPFactory) StreamCapability() internet.StreamConnectionType {
+	return internet.StreamConnectionTypeRawTCP
+}
+
+func (rawTCPFactory) Create(space app.Space, config interface{}, meta *proxy.OutboundHandlerMeta) (proxy.OutboundHandler, error) {
+	return nil, nil
+}
+
+func TestCustomStreamType(t *testing.T) {
+	assert := assert.On(t)
+
+	MustRegisterOutboundHandlerCreator("test_raw_tcp", rawTCPFactory{})
+
+	meta := &proxy.OutboundHandlerMeta{
+		StreamSettings: &internet.StreamSettings{
+			Type:   internet.StreamConnectionTypeRawTCP | internet.StreamConnectionTypeCustom | internet.StreamConnectionTypeKCP,
+			Custom: "test",
+		},
+	}
+	_, err := CreateOutboundHandler("test_raw_tcp", app.NewSpace(), nil, meta)
+	assert.Error(err).IsNil()
+	assert.Bool(meta.StreamSettings.IsCapableOf(internet.StreamConnectionTypeCustom)).IsTrue()
+	assert.Bool(meta.StreamSettings.IsCapableOf(internet.StreamConnectionTypeKCP)).IsFalse()
+
+	meta = &proxy.OutboundHandlerMeta{
+		StreamSettings: &internet.StreamSettings{
+			Type: internet.StreamConnectionTypeRawTCP | internet.StreamConnectionTypePlugin,
+		},
+	}
+	_, err = CreateOutboundHandler("test_raw_tcp", app.NewSpace(), nil, meta)
+	assert.Error(err).IsNil()
+	assert.Bool(meta.StreamSettings.IsCapableOf(internet.StreamConnectionTypePlugin)).IsTrue()
+
+	meta = new(proxy.OutboundHandlerMeta)
+	_, err = CreateOutboundHandler("test_raw_tcp", app.NewSpace(), nil, meta)
+	assert.Error(err).IsNil()
+	assert.Bool(meta.StreamSettings.IsCapableOf(internet.StreamConnectionTypeCustom)).IsFalse()
+}
@@ -4,10 +4,10 @@ package shadowsocks
 
 import (
 	"encoding/json"
-	"errors"
 	"strings"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy/registry"
@@ -24,8 +24,8 @@ func (this *ServerConfig) UnmarshalJSON(data []byte) error {
 		Email    string `json:"email"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Shadowsocks: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Shadowsocks: Failed to parse config: ", err)
 	}
 
 	this.UdpEnabled = jsonConfig.UDP
@@ -66,6 +66,40 @@ func (this *ServerConfig) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *ServerConfig) MarshalJSON() ([]byte, error) {
+	type JsonConfig struct {
+		Cipher   string `json:"method"`
+		Password string `json:"password"`
+		UDP      bool   `json:"udp,omitempty"`
+		Level    uint32 `json:"level,omitempty"`
+		Email    string `json:"email,omitempty"`
+	}
+	jsonConfig := &JsonConfig{
+		UDP: this.UdpEnabled,
+	}
+	if this.User != nil {
+		jsonConfig.Level = this.User.Level
+		jsonConfig.Email = this.User.Email
+		account := new(Account)
+		if err := ptypes.UnmarshalAny(this.User.Account, account); err != nil {
+			return nil, err
+		}
+		jsonConfig.Password = account.Password
+		switch account.CipherType {
+		case CipherType_AES_256_CFB:
+			jsonConfig.Cipher = "aes-256-cfb"
+		case CipherType_AES_128_CFB:
+			jsonConfig.Cipher = "aes-128-cfb"
+		case CipherType_CHACHA20:
+			jsonConfig.Cipher = "chacha20"
+		case CipherType_CHACHA20_IEFT:
+			jsonConfig.Cipher = "chacha20-ietf"
+		}
+	}
+	return json.Marshal(jsonConfig)
+}
+
 func init() {
 	registry.RegisterInboundConfig("shadowsocks", func() interface{} { return new(ServerConfig) })
 }
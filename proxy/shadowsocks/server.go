@@ -2,12 +2,14 @@
 package shadowsocks
 
 import (
+	"context"
 	"crypto/rand"
 	"io"
 	"sync"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/crypto"
@@ -24,6 +26,7 @@ import (
 type Server struct {
 	packetDispatcher dispatcher.PacketDispatcher
 	config           *ServerConfig
+	policy           *policy.Policy
 	cipher           Cipher
 	cipherKey        []byte
 	meta             *proxy.InboundHandlerMeta
@@ -31,6 +34,7 @@ type Server struct {
 	tcpHub           *internet.TCPHub
 	udpHub           *udp.UDPHub
 	udpServer        *udp.UDPServer
+	udpOptions       *udp.SessionOptions
 }
 
 func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandlerMeta) (*Server, error) {
@@ -50,6 +54,7 @@ func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandler
 		meta:      meta,
 		cipher:    cipher,
 		cipherKey: account.GetCipherKey(),
+		policy:    policy.FromSpace(space).ForLevel(config.GetUser().Level),
 	}
 
 	space.InitializeApplication(func() error {
@@ -57,6 +62,7 @@ func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandler
 			return app.ErrMissingApplication
 		}
 		s.packetDispatcher = space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+		s.udpOptions = udp.SessionOptionsFromSpace(space)
 		return nil
 	})
 
@@ -67,6 +73,11 @@ func (this *Server) Port() v2net.Port {
 	return this.meta.Port
 }
 
+// Accepting implements proxy.AcceptingReporter.
+func (this *Server) Accepting() bool {
+	return this.accepting
+}
+
 func (this *Server) Close() {
 	this.accepting = false
 	// TODO: synchronization
@@ -89,16 +100,20 @@ func (this *Server) Start() error {
 
 	tcpHub, err := internet.ListenTCP(this.meta.Address, this.meta.Port, this.handleConnection, this.meta.StreamSettings)
 	if err != nil {
-		log.Error("Shadowsocks: Failed to listen TCP on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+		this.meta.Logger.Error("Shadowsocks: Failed to listen TCP on ", this.meta.Address, ":", this.meta.Port, ": ", err)
 		return err
 	}
 	this.tcpHub = tcpHub
 
 	if this.config.UdpEnabled {
-		this.udpServer = udp.NewUDPServer(this.meta, this.packetDispatcher)
-		udpHub, err := udp.ListenUDP(this.meta.Address, this.meta.Port, udp.ListenOption{Callback: this.handlerUDPPayload})
+		this.udpServer = udp.NewUDPServer(this.meta, this.packetDispatcher, this.udpOptions)
+		udpHub, err := udp.ListenUDP(this.meta.Address, this.meta.Port, udp.ListenOption{
+			Callback:     this.handlerUDPPayload,
+			SourceFilter: this.meta.SourceFilter,
+			Bans:         this.meta.Bans,
+		})
 		if err != nil {
-			log.Error("Shadowsocks: Failed to listen UDP on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+			this.meta.Logger.Error("Shadowsocks: Failed to listen UDP on ", this.meta.Address, ":", this.meta.Port, ": ", err)
 			return err
 		}
 		this.udpHub = udpHub
@@ -119,7 +134,7 @@ func (this *Server) handlerUDPPayload(payload *alloc.Buffer, session *proxy.Sess
 
 	stream, err := this.cipher.NewDecodingStream(this.cipherKey, iv)
 	if err != nil {
-		log.Error("Shadowsocks: Failed to create decoding stream: ", err)
+		this.meta.Logger.Error("Shadowsocks: Failed to create decoding stream: ", err)
 		return
 	}
 
@@ -128,16 +143,17 @@ func (this *Server) handlerUDPPayload(payload *alloc.Buffer, session *proxy.Sess
 	request, err := ReadRequest(reader, NewAuthenticator(HeaderKeyGenerator(this.cipherKey, iv)), true)
 	if err != nil {
 		if err != io.EOF {
-			log.Access(source, "", log.AccessRejected, err)
-			log.Warning("Shadowsocks: Invalid request from ", source, ": ", err)
+			this.meta.Logger.Access(source, "", log.AccessRejected, err)
+			this.meta.Logger.Warning("Shadowsocks: Invalid request from ", source, ": ", err)
+			proxy.ReportAuthFailure(this.meta, source.Address)
 		}
 		return
 	}
 	//defer request.Release()
 
 	dest := v2net.UDPDestination(request.Address, request.Port)
-	log.Access(source, dest, log.AccessAccepted, "")
-	log.Info("Shadowsocks: Tunnelling request to ", dest)
+	this.meta.Logger.Access(source, dest, log.AccessAccepted, "")
+	this.meta.Logger.Info("Shadowsocks: Tunnelling request to ", dest)
 
 	this.udpServer.Dispatch(&proxy.SessionInfo{Source: source, Destination: dest}, request.DetachUDPPayload(), func(destination v2net.Destination, payload *alloc.Buffer) {
 		defer payload.Release()
@@ -150,7 +166,7 @@ func (this *Server) handlerUDPPayload(payload *alloc.Buffer, session *proxy.Sess
 
 		stream, err := this.cipher.NewEncodingStream(this.cipherKey, respIv)
 		if err != nil {
-			log.Error("Shadowsocks: Failed to create encoding stream: ", err)
+			this.meta.Logger.Error("Shadowsocks: Failed to create encoding stream: ", err)
 			return
 		}
 
@@ -186,7 +202,7 @@ func (this *Server) handleConnection(conn internet.Connection) {
 	buffer := alloc.NewSmallBuffer()
 	defer buffer.Release()
 
-	timedReader := v2net.NewTimeOutReader(16, conn)
+	timedReader := v2net.NewTimeOutReader(policy.Seconds(this.policy.Handshake), conn)
 	defer timedReader.Release()
 
 	bufferedReader := v2io.NewBufferedReader(timedReader)
@@ -196,8 +212,8 @@ func (this *Server) handleConnection(conn internet.Connection) {
 	_, err := io.ReadFull(bufferedReader, buffer.Value[:ivLen])
 	if err != nil {
 		if err != io.EOF {
-			log.Access(conn.RemoteAddr(), "", log.AccessRejected, err)
-			log.Warning("Shadowsocks: Failed to read IV: ", err)
+			this.meta.Logger.Access(conn.RemoteAddr(), "", log.AccessRejected, err)
+			this.meta.Logger.Warning("Shadowsocks: Failed to read IV: ", err)
 		}
 		return
 	}
@@ -206,7 +222,7 @@ func (this *Server) handleConnection(conn internet.Connection) {
 
 	stream, err := this.cipher.NewDecodingStream(this.cipherKey, iv)
 	if err != nil {
-		log.Error("Shadowsocks: Failed to create decoding stream: ", err)
+		this.meta.Logger.Error("Shadowsocks: Failed to create decoding stream: ", err)
 		return
 	}
 
@@ -214,36 +230,42 @@ func (this *Server) handleConnection(conn internet.Connection) {
 
 	request, err := ReadRequest(reader, NewAuthenticator(HeaderKeyGenerator(this.cipherKey, iv)), false)
 	if err != nil {
-		log.Access(conn.RemoteAddr(), "", log.AccessRejected, err)
-		log.Warning("Shadowsocks: Invalid request from ", conn.RemoteAddr(), ": ", err)
+		this.meta.Logger.Access(conn.RemoteAddr(), "", log.AccessRejected, err)
+		this.meta.Logger.Warning("Shadowsocks: Invalid request from ", conn.RemoteAddr(), ": ", err)
+		proxy.ReportAuthFailure(this.meta, proxy.SourceAddress(conn.RemoteAddr()))
 		return
 	}
 	defer request.Release()
 	bufferedReader.SetCached(false)
 
-	userSettings := this.config.GetUser().GetSettings()
-	timedReader.SetTimeOut(userSettings.PayloadReadTimeout)
+	timedReader.SetTimeOut(policy.Seconds(this.policy.ConnectionIdle))
 
 	dest := v2net.TCPDestination(request.Address, request.Port)
-	log.Access(conn.RemoteAddr(), dest, log.AccessAccepted, "")
-	log.Info("Shadowsocks: Tunnelling request to ", dest)
+	this.meta.Logger.Access(conn.RemoteAddr(), dest, log.AccessAccepted, "")
+	this.meta.Logger.Info("Shadowsocks: Tunnelling request to ", dest)
+
+	// The outbound stops once the connection is handled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	ray := this.packetDispatcher.DispatchToOutbound(this.meta, &proxy.SessionInfo{
+	ray := this.packetDispatcher.DispatchToOutbound(ctx, this.meta, &proxy.SessionInfo{
 		Source:      v2net.DestinationFromAddr(conn.RemoteAddr()),
 		Destination: dest,
+		User:        this.config.GetUser(),
 	})
 	defer ray.InboundOutput().Release()
 
-	var writeFinish sync.Mutex
-	writeFinish.Lock()
+	var writeFinish sync.WaitGroup
+	writeFinish.Add(1)
 	go func() {
+		defer writeFinish.Done()
 		if payload, err := ray.InboundOutput().Read(); err == nil {
 			payload.SliceBack(ivLen)
 			rand.Read(payload.Value[:ivLen])
 
 			stream, err := this.cipher.NewEncodingStream(this.cipherKey, payload.Value[:ivLen])
 			if err != nil {
-				log.Error("Shadowsocks: Failed to create encoding stream: ", err)
+				this.meta.Logger.Error("Shadowsocks: Failed to create encoding stream: ", err)
 				return
 			}
 			stream.XORKeyStream(payload.Value[ivLen:], payload.Value[ivLen:])
@@ -258,7 +280,6 @@ func (this *Server) handleConnection(conn internet.Connection) {
 			writer.Release()
 			v2writer.Release()
 		}
-		writeFinish.Unlock()
 	}()
 
 	var payloadReader v2io.Reader
@@ -273,7 +294,7 @@ func (this *Server) handleConnection(conn internet.Connection) {
 	ray.InboundInput().Close()
 	payloadReader.Release()
 
-	writeFinish.Lock()
+	writeFinish.Wait()
 }
 
 type ServerFactory struct{}
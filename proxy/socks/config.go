@@ -44,6 +44,14 @@ func (this *ServerConfig) HasAccount(username, password string) bool {
 	return storedPassed == password
 }
 
+// ListenNetworks implements internet.NetworkListener. UDP is listened on for UDP ASSOCIATE if enabled.
+func (this *ServerConfig) ListenNetworks() []v2net.Network {
+	if this.UdpEnabled {
+		return []v2net.Network{v2net.Network_TCP, v2net.Network_UDP}
+	}
+	return []v2net.Network{v2net.Network_TCP}
+}
+
 func (this *ServerConfig) GetNetAddress() v2net.Address {
 	if this.Address == nil {
 		return v2net.LocalHostIP
@@ -63,6 +71,7 @@ func (this *ServerConfig) UnmarshalJSON(data []byte) error {
 		UDP        bool             `json:"udp"`
 		Host       *v2net.AddressPB `json:"ip"`
 		Timeout    uint32           `json:"timeout"`
+		PacketAddr bool             `json:"packetAddr"`
 	}
 
 	rawConfig := new(SocksConfig)
@@ -86,6 +95,7 @@ func (this *ServerConfig) UnmarshalJSON(data []byte) error {
 	}
 
 	this.UdpEnabled = rawConfig.UDP
+	this.PacketAddr = rawConfig.PacketAddr
 	if rawConfig.Host != nil {
 		this.Address = rawConfig.Host
 	}
@@ -69,6 +69,9 @@ type ServerConfig struct {
 	Address    *v2ray_core_common_net.AddressPB `protobuf:"bytes,3,opt,name=address" json:"address,omitempty"`
 	UdpEnabled bool                             `protobuf:"varint,4,opt,name=udp_enabled,json=udpEnabled" json:"udp_enabled,omitempty"`
 	Timeout    uint32                           `protobuf:"varint,5,opt,name=timeout" json:"timeout,omitempty"`
+	// Sends UDP datagrams in a single packet-addressed session, which only freedom outbounds, directly or behind VMess,
+	// support.
+	PacketAddr bool `protobuf:"varint,6,opt,name=packet_addr,json=packetAddr" json:"packet_addr,omitempty"`
 }
 
 func (m *ServerConfig) Reset()                    { *m = ServerConfig{} }
@@ -116,32 +119,33 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/proxy/socks/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 428 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x91, 0x51, 0x6b, 0xdb, 0x30,
-	0x10, 0xc7, 0xe7, 0x64, 0x69, 0xdc, 0x73, 0x3a, 0x82, 0x18, 0xc3, 0xf8, 0x65, 0x26, 0x30, 0x66,
-	0xf6, 0x20, 0x97, 0xec, 0x65, 0x74, 0x0c, 0xe6, 0x74, 0x85, 0x3d, 0xb5, 0xc6, 0xe9, 0x18, 0xec,
-	0x25, 0xa8, 0xf2, 0x6d, 0x0d, 0xb5, 0x25, 0x21, 0xc9, 0xd9, 0xfc, 0x31, 0xf7, 0x8d, 0x46, 0x2c,
-	0xbb, 0xac, 0x25, 0x7d, 0xd3, 0xe9, 0xfe, 0xf7, 0xbf, 0xbb, 0xdf, 0xc1, 0xdb, 0xdd, 0x52, 0xb3,
-	0x96, 0x72, 0x59, 0xa7, 0x5c, 0x6a, 0x4c, 0x95, 0x96, 0x7f, 0xda, 0xd4, 0x48, 0x7e, 0x67, 0x52,
-	0x2e, 0xc5, 0xcf, 0xed, 0x2f, 0xaa, 0xb4, 0xb4, 0x92, 0xbc, 0x1a, 0x84, 0x1a, 0x69, 0x27, 0xa2,
-	0x9d, 0x28, 0x7a, 0x6c, 0xc0, 0x65, 0x5d, 0x4b, 0x91, 0x0a, 0xb4, 0x29, 0x2b, 0x4b, 0x8d, 0xc6,
-	0x38, 0x83, 0xe8, 0xf4, 0xb0, 0xb0, 0x4b, 0x72, 0x59, 0xa5, 0x06, 0xf5, 0x0e, 0xf5, 0xc6, 0x28,
-	0xe4, 0xae, 0x62, 0x91, 0xc1, 0x34, 0xe3, 0x5c, 0x36, 0xc2, 0x92, 0x08, 0xfc, 0xc6, 0xa0, 0x16,
-	0xac, 0xc6, 0xd0, 0x8b, 0xbd, 0xe4, 0xb8, 0xb8, 0x8f, 0xf7, 0x39, 0xc5, 0x8c, 0xf9, 0x2d, 0x75,
-	0x19, 0x8e, 0x5c, 0x6e, 0x88, 0x17, 0x7f, 0x47, 0x30, 0x5b, 0x77, 0xc6, 0xe7, 0xdd, 0x32, 0xe4,
-	0x13, 0x1c, 0xb3, 0xc6, 0xde, 0x6e, 0x6c, 0xab, 0x9c, 0xd3, 0x8b, 0x65, 0x4c, 0x0f, 0xaf, 0x46,
-	0xb3, 0xc6, 0xde, 0x5e, 0xb7, 0x0a, 0x0b, 0x9f, 0xf5, 0x2f, 0x72, 0x09, 0x3e, 0x73, 0x23, 0x99,
-	0x70, 0x14, 0x8f, 0x93, 0x60, 0xb9, 0x7c, 0xaa, 0xfa, 0xff, 0xb6, 0xb4, 0xdf, 0xc3, 0x5c, 0x08,
-	0xab, 0xdb, 0xe2, 0xde, 0x83, 0x9c, 0xc1, 0xb4, 0xa7, 0x14, 0x8e, 0x63, 0x2f, 0x09, 0x1e, 0x0e,
-	0xe3, 0x10, 0x51, 0x81, 0x96, 0x66, 0x4e, 0x95, 0xaf, 0x8a, 0xa1, 0x80, 0xbc, 0x86, 0xa0, 0x29,
-	0xd5, 0x06, 0x05, 0xbb, 0xa9, 0xb0, 0x0c, 0x9f, 0xc7, 0x5e, 0xe2, 0x17, 0xd0, 0x94, 0xea, 0xc2,
-	0xfd, 0x90, 0x10, 0xa6, 0x76, 0x5b, 0xa3, 0x6c, 0x6c, 0x38, 0x89, 0xbd, 0xe4, 0xa4, 0x18, 0xc2,
-	0xe8, 0x23, 0x9c, 0x3c, 0x98, 0x88, 0xcc, 0x61, 0x7c, 0x87, 0x6d, 0x8f, 0x76, 0xff, 0x24, 0x2f,
-	0x61, 0xb2, 0x63, 0x55, 0x83, 0x3d, 0x52, 0x17, 0x9c, 0x8d, 0x3e, 0x78, 0x8b, 0x1c, 0x66, 0xe7,
-	0xd5, 0x16, 0x85, 0xed, 0x91, 0x7e, 0x86, 0x23, 0x77, 0xbb, 0xd0, 0xeb, 0x88, 0x24, 0x07, 0x56,
-	0x18, 0xae, 0xdc, 0x53, 0x59, 0x2b, 0xe4, 0xf9, 0xaa, 0xe8, 0xeb, 0xde, 0xbd, 0x01, 0x7f, 0x60,
-	0x4d, 0x02, 0x98, 0x5e, 0x5e, 0x6d, 0xb2, 0x6f, 0xd7, 0x5f, 0xe7, 0xcf, 0xc8, 0x0c, 0xfc, 0x3c,
-	0x5b, 0xaf, 0xbf, 0x5f, 0x15, 0x5f, 0xe6, 0xde, 0xea, 0x14, 0x22, 0x2e, 0xeb, 0x27, 0x78, 0xaf,
-	0x02, 0x37, 0x4e, 0xbe, 0xef, 0xf4, 0x63, 0xd2, 0xfd, 0xdd, 0x1c, 0x75, 0x7d, 0xdf, 0xff, 0x0b,
-	0x00, 0x00, 0xff, 0xff, 0xb4, 0x25, 0x49, 0xd6, 0xe6, 0x02, 0x00, 0x00,
+	// 440 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0x51, 0x6b, 0xdb, 0x30,
+	0x10, 0x9e, 0x93, 0x25, 0x71, 0xcf, 0xe9, 0x08, 0x62, 0x0c, 0xe3, 0x97, 0x99, 0xc0, 0x98, 0xd9,
+	0x83, 0x5d, 0xb2, 0x97, 0xd1, 0x31, 0x98, 0xd3, 0x15, 0xf6, 0xd4, 0x1a, 0xa7, 0x63, 0xb0, 0x17,
+	0xa3, 0xca, 0xb7, 0x35, 0x24, 0x96, 0x84, 0x24, 0x67, 0xf3, 0xdf, 0xde, 0x2f, 0x18, 0xb6, 0xec,
+	0xb2, 0x96, 0xf4, 0x4d, 0x77, 0xf7, 0x7d, 0xdf, 0xdd, 0x7d, 0x27, 0x78, 0x7b, 0x58, 0x29, 0xda,
+	0xc4, 0x4c, 0x54, 0x09, 0x13, 0x0a, 0x13, 0xa9, 0xc4, 0x9f, 0x26, 0xd1, 0x82, 0xed, 0x74, 0xc2,
+	0x04, 0xff, 0xb9, 0xfd, 0x15, 0x4b, 0x25, 0x8c, 0x20, 0xaf, 0x06, 0xa0, 0xc2, 0xb8, 0x03, 0xc5,
+	0x1d, 0x28, 0x78, 0x2c, 0xc0, 0x44, 0x55, 0x09, 0x9e, 0x70, 0x34, 0x09, 0x2d, 0x4b, 0x85, 0x5a,
+	0x5b, 0x81, 0xe0, 0xec, 0x38, 0xb0, 0x2b, 0x32, 0xb1, 0x4f, 0x34, 0xaa, 0x03, 0xaa, 0x42, 0x4b,
+	0x64, 0x96, 0xb1, 0x4c, 0x61, 0x96, 0x32, 0x26, 0x6a, 0x6e, 0x48, 0x00, 0x6e, 0xad, 0x51, 0x71,
+	0x5a, 0xa1, 0xef, 0x84, 0x4e, 0x74, 0x92, 0xdf, 0xc7, 0x6d, 0x4d, 0x52, 0xad, 0x7f, 0x0b, 0x55,
+	0xfa, 0x23, 0x5b, 0x1b, 0xe2, 0xe5, 0xdf, 0x11, 0xcc, 0x37, 0x9d, 0xf0, 0x45, 0xb7, 0x0c, 0xf9,
+	0x04, 0x27, 0xb4, 0x36, 0x77, 0x85, 0x69, 0xa4, 0x55, 0x7a, 0xb1, 0x0a, 0xe3, 0xe3, 0xab, 0xc5,
+	0x69, 0x6d, 0xee, 0x6e, 0x1a, 0x89, 0xb9, 0x4b, 0xfb, 0x17, 0xb9, 0x02, 0x97, 0xda, 0x91, 0xb4,
+	0x3f, 0x0a, 0xc7, 0x91, 0xb7, 0x5a, 0x3d, 0xc5, 0xfe, 0xbf, 0x6d, 0xdc, 0xef, 0xa1, 0x2f, 0xb9,
+	0x51, 0x4d, 0x7e, 0xaf, 0x41, 0xce, 0x61, 0xd6, 0xbb, 0xe4, 0x8f, 0x43, 0x27, 0xf2, 0x1e, 0x0e,
+	0x63, 0x2d, 0x8a, 0x39, 0x9a, 0x38, 0xb5, 0xa8, 0x6c, 0x9d, 0x0f, 0x04, 0xf2, 0x1a, 0xbc, 0xba,
+	0x94, 0x05, 0x72, 0x7a, 0xbb, 0xc7, 0xd2, 0x7f, 0x1e, 0x3a, 0x91, 0x9b, 0x43, 0x5d, 0xca, 0x4b,
+	0x9b, 0x21, 0x3e, 0xcc, 0xcc, 0xb6, 0x42, 0x51, 0x1b, 0x7f, 0x12, 0x3a, 0xd1, 0x69, 0x3e, 0x84,
+	0x2d, 0x55, 0x52, 0xb6, 0x43, 0x53, 0xb4, 0x62, 0xfe, 0xd4, 0x52, 0x6d, 0xaa, 0xed, 0x14, 0x7c,
+	0x84, 0xd3, 0x07, 0x23, 0x93, 0x05, 0x8c, 0x77, 0xd8, 0xf4, 0xde, 0xb7, 0x4f, 0xf2, 0x12, 0x26,
+	0x07, 0xba, 0xaf, 0xb1, 0xf7, 0xdc, 0x06, 0xe7, 0xa3, 0x0f, 0xce, 0x32, 0x83, 0xf9, 0xc5, 0x7e,
+	0x8b, 0xdc, 0xf4, 0x9e, 0x7f, 0x86, 0xa9, 0x3d, 0xae, 0xef, 0x74, 0x96, 0x45, 0x47, 0x76, 0x1c,
+	0xbe, 0x41, 0x6f, 0xdb, 0x46, 0x22, 0xcb, 0xd6, 0x79, 0xcf, 0x7b, 0xf7, 0x06, 0xdc, 0xe1, 0x18,
+	0xc4, 0x83, 0xd9, 0xd5, 0x75, 0x91, 0x7e, 0xbb, 0xf9, 0xba, 0x78, 0x46, 0xe6, 0xe0, 0x66, 0xe9,
+	0x66, 0xf3, 0xfd, 0x3a, 0xff, 0xb2, 0x70, 0xd6, 0x67, 0x10, 0x30, 0x51, 0x3d, 0x71, 0x90, 0xb5,
+	0x67, 0xc7, 0xc9, 0xda, 0x4e, 0x3f, 0x26, 0x5d, 0xee, 0x76, 0xda, 0xf5, 0x7d, 0xff, 0x6f, 0x00,
+	0x60, 0x84, 0xd1, 0x30, 0x07, 0x03, 0x00, 0x00,
 }
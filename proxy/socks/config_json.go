@@ -5,26 +5,78 @@ package socks
 import (
 	"encoding/json"
 	"errors"
+	"sort"
+	"strconv"
 
+	"v2ray.com/core/common/loader"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy/registry"
 )
 
+// userKeys are the fields of a user, which is decoded into both a protocol.User and a Account.
+var userKeys = []string{"email", "level", "user", "pass"}
+
 func (this *Account) UnmarshalJSON(data []byte) error {
 	type JsonConfig struct {
 		Username string `json:"user"`
 		Password string `json:"pass"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Socks: Failed to parse account: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Socks: Failed to parse account: ", err)
 	}
 	this.Username = jsonConfig.Username
 	this.Password = jsonConfig.Password
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *Account) MarshalJSON() ([]byte, error) {
+	type JsonConfig struct {
+		Username string `json:"user"`
+		Password string `json:"pass"`
+	}
+	return json.Marshal(&JsonConfig{
+		Username: this.Username,
+		Password: this.Password,
+	})
+}
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON. Accounts are sorted by their user names.
+func (this *ServerConfig) MarshalJSON() ([]byte, error) {
+	type SocksConfig struct {
+		AuthMethod string           `json:"auth"`
+		Accounts   []*Account       `json:"accounts,omitempty"`
+		UDP        bool             `json:"udp,omitempty"`
+		Host       *v2net.AddressPB `json:"ip,omitempty"`
+		Timeout    uint32           `json:"timeout,omitempty"`
+		PacketAddr bool             `json:"packetAddr,omitempty"`
+	}
+	rawConfig := &SocksConfig{
+		AuthMethod: AuthMethodNoAuth,
+		UDP:        this.UdpEnabled,
+		Host:       this.Address,
+		Timeout:    this.Timeout,
+		PacketAddr: this.PacketAddr,
+	}
+	if this.AuthType == AuthType_PASSWORD {
+		rawConfig.AuthMethod = AuthMethodUserPass
+	}
+	usernames := make([]string, 0, len(this.Accounts))
+	for username := range this.Accounts {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	for _, username := range usernames {
+		rawConfig.Accounts = append(rawConfig.Accounts, &Account{
+			Username: username,
+			Password: this.Accounts[username],
+		})
+	}
+	return json.Marshal(rawConfig)
+}
+
 func (this *ClientConfig) UnmarshalJSON(data []byte) error {
 	type ServerConfig struct {
 		Address *v2net.AddressPB  `json:"address"`
@@ -35,8 +87,8 @@ func (this *ClientConfig) UnmarshalJSON(data []byte) error {
 		Servers []*ServerConfig `json:"servers"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Socks|Client: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Socks|Client: Failed to parse config: ", err)
 	}
 	this.Server = make([]*protocol.ServerSpecPB, len(jsonConfig.Servers))
 	for idx, serverConfig := range jsonConfig.Servers {
@@ -44,7 +96,11 @@ func (this *ClientConfig) UnmarshalJSON(data []byte) error {
 			Address: serverConfig.Address,
 			Port:    uint32(serverConfig.Port),
 		}
-		for _, rawUser := range serverConfig.Users {
+		for userIdx, rawUser := range serverConfig.Users {
+			userPath := []string{"servers", "[" + strconv.Itoa(idx) + "]", "users", "[" + strconv.Itoa(userIdx) + "]"}
+			if err := loader.CheckJSONFields(rawUser, userKeys, userPath...); err != nil {
+				return err
+			}
 			user := new(protocol.User)
 			if err := json.Unmarshal(rawUser, user); err != nil {
 				return errors.New("Socks|Client: Failed to parse user: " + err.Error())
@@ -65,6 +121,29 @@ func (this *ClientConfig) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *ClientConfig) MarshalJSON() ([]byte, error) {
+	type ServerConfig struct {
+		Address *v2net.AddressPB `json:"address"`
+		Port    uint32           `json:"port"`
+		Users   []*protocol.User `json:"users,omitempty"`
+	}
+	type JsonConfig struct {
+		Servers []*ServerConfig `json:"servers"`
+	}
+	jsonConfig := &JsonConfig{
+		Servers: make([]*ServerConfig, len(this.Server)),
+	}
+	for idx, server := range this.Server {
+		jsonConfig.Servers[idx] = &ServerConfig{
+			Address: server.Address,
+			Port:    server.Port,
+			Users:   server.User,
+		}
+	}
+	return json.Marshal(jsonConfig)
+}
+
 func init() {
 	registry.RegisterOutboundConfig("socks", func() interface{} { return new(ClientConfig) })
 }
@@ -19,3 +19,16 @@ func TestDefaultIPAddress(t *testing.T) {
 	assert.Error(err).IsNil()
 	assert.Address(socksConfig.(*ServerConfig).GetNetAddress()).EqualsString("127.0.0.1")
 }
+
+func TestPacketAddrOption(t *testing.T) {
+	assert := assert.On(t)
+
+	socksConfig, err := registry.CreateInboundConfig("socks", []byte(`{
+    "auth": "noauth",
+    "udp": true,
+    "packetAddr": true
+  }`))
+	assert.Error(err).IsNil()
+	assert.Bool(socksConfig.(*ServerConfig).UdpEnabled).IsTrue()
+	assert.Bool(socksConfig.(*ServerConfig).PacketAddr).IsTrue()
+}
@@ -1,6 +1,7 @@
 package socks
 
 import (
+	"context"
 	"errors"
 	"io"
 	"sync"
@@ -8,9 +9,11 @@ import (
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/policy"
 	v2io "v2ray.com/core/common/io"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	v2protocol "v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/proxy/registry"
 	"v2ray.com/core/proxy/socks/protocol"
@@ -30,10 +33,12 @@ type Server struct {
 	accepting        bool
 	packetDispatcher dispatcher.PacketDispatcher
 	config           *ServerConfig
+	policy           *policy.Policy
 	tcpListener      *internet.TCPHub
 	udpHub           *udp.UDPHub
 	udpAddress       v2net.Destination
 	udpServer        *udp.UDPServer
+	udpOptions       *udp.SessionOptions
 	meta             *proxy.InboundHandlerMeta
 }
 
@@ -41,6 +46,7 @@ type Server struct {
 func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandlerMeta) *Server {
 	s := &Server{
 		config: config,
+		policy: policy.FromSpace(space).ForLevel(0),
 		meta:   meta,
 	}
 	space.InitializeApplication(func() error {
@@ -49,6 +55,7 @@ func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandler
 			return app.ErrMissingApplication
 		}
 		s.packetDispatcher = space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+		s.udpOptions = udp.SessionOptionsFromSpace(space)
 		return nil
 	})
 	return s
@@ -59,6 +66,11 @@ func (this *Server) Port() v2net.Port {
 	return this.meta.Port
 }
 
+// Accepting implements proxy.AcceptingReporter.
+func (this *Server) Accepting() bool {
+	return this.accepting
+}
+
 // Close implements InboundHandler.Close().
 func (this *Server) Close() {
 	this.accepting = false
@@ -88,7 +100,7 @@ func (this *Server) Start() error {
 		this.handleConnection,
 		this.meta.StreamSettings)
 	if err != nil {
-		log.Error("Socks: failed to listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+		this.meta.Logger.Error("Socks: failed to listen on ", this.meta.Address, ":", this.meta.Port, ": ", err)
 		return err
 	}
 	this.accepting = true
@@ -104,7 +116,7 @@ func (this *Server) Start() error {
 func (this *Server) handleConnection(connection internet.Connection) {
 	defer connection.Close()
 
-	timedReader := v2net.NewTimeOutReader(this.config.Timeout, connection)
+	timedReader := v2net.NewTimeOutReader(policy.Seconds(this.policy.Handshake), connection)
 	reader := v2io.NewBufferedReader(timedReader)
 	defer reader.Release()
 
@@ -114,20 +126,26 @@ func (this *Server) handleConnection(connection internet.Connection) {
 	auth, auth4, err := protocol.ReadAuthentication(reader)
 	if err != nil && err != protocol.Socks4Downgrade {
 		if err != io.EOF {
-			log.Warning("Socks: failed to read authentication: ", err)
+			this.meta.Logger.Warning("Socks: failed to read authentication: ", err)
 		}
 		return
 	}
 
+	// The outbound stops once the connection is handled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	clientAddr := v2net.DestinationFromAddr(connection.RemoteAddr())
 	if err != nil && err == protocol.Socks4Downgrade {
-		this.handleSocks4(clientAddr, reader, writer, auth4)
+		this.handleSocks4(ctx, clientAddr, proxy.CertificateUser(connection), timedReader, reader, writer, auth4)
 	} else {
-		this.handleSocks5(clientAddr, reader, writer, auth)
+		this.handleSocks5(ctx, clientAddr, proxy.CertificateUser(connection), timedReader, reader, writer, auth)
 	}
 }
 
-func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.BufferedReader, writer *v2io.BufferedWriter, auth protocol.Socks5AuthenticationRequest) error {
+// handleSocks5 handles a SOCKS 5 connection. User is the user identified by the connection, which is replaced by the
+// account in password authentication.
+func (this *Server) handleSocks5(ctx context.Context, clientAddr v2net.Destination, user *v2protocol.User, timedReader *v2net.TimeOutReader, reader *v2io.BufferedReader, writer *v2io.BufferedWriter, auth protocol.Socks5AuthenticationRequest) error {
 	expectedAuthMethod := protocol.AuthNotRequired
 	if this.config.AuthType == AuthType_PASSWORD {
 		expectedAuthMethod = protocol.AuthUserPass
@@ -138,10 +156,10 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 		err := protocol.WriteAuthentication(writer, authResponse)
 		writer.Flush()
 		if err != nil {
-			log.Warning("Socks: failed to write authentication: ", err)
+			this.meta.Logger.Warning("Socks: failed to write authentication: ", err)
 			return err
 		}
-		log.Warning("Socks: client doesn't support any allowed auth methods.")
+		this.meta.Logger.Warning("Socks: client doesn't support any allowed auth methods.")
 		return ErrUnsupportedAuthMethod
 	}
 
@@ -149,13 +167,13 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 	protocol.WriteAuthentication(writer, authResponse)
 	err := writer.Flush()
 	if err != nil {
-		log.Error("Socks: failed to write authentication: ", err)
+		this.meta.Logger.Error("Socks: failed to write authentication: ", err)
 		return err
 	}
 	if this.config.AuthType == AuthType_PASSWORD {
 		upRequest, err := protocol.ReadUserPassRequest(reader)
 		if err != nil {
-			log.Warning("Socks: failed to read username and password: ", err)
+			this.meta.Logger.Warning("Socks: failed to read username and password: ", err)
 			return err
 		}
 		status := byte(0)
@@ -166,22 +184,30 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 		err = protocol.WriteUserPassResponse(writer, upResponse)
 		writer.Flush()
 		if err != nil {
-			log.Error("Socks: failed to write user pass response: ", err)
+			this.meta.Logger.Error("Socks: failed to write user pass response: ", err)
 			return err
 		}
 		if status != byte(0) {
-			log.Warning("Socks: Invalid user account: ", upRequest.AuthDetail())
-			log.Access(clientAddr, "", log.AccessRejected, proxy.ErrInvalidAuthentication)
+			this.meta.Logger.Warning("Socks: Invalid user account: ", upRequest.AuthDetail())
+			this.meta.Logger.Access(clientAddr, "", log.AccessRejected, proxy.ErrInvalidAuthentication)
+			proxy.ReportAuthFailure(this.meta, clientAddr.Address)
 			return proxy.ErrInvalidAuthentication
 		}
+		// SOCKS accounts have no email, so the username identifies the user in routing.
+		user = &v2protocol.User{
+			Email: upRequest.Username(),
+		}
 	}
 
 	request, err := protocol.ReadRequest(reader)
 	if err != nil {
-		log.Warning("Socks: failed to read request: ", err)
+		this.meta.Logger.Warning("Socks: failed to read request: ", err)
 		return err
 	}
 
+	// The handshake is over once the request is read.
+	timedReader.SetTimeOut(this.config.Timeout)
+
 	if request.Command == protocol.CmdUdpAssociate && this.config.UdpEnabled {
 		return this.handleUDP(reader, writer)
 	}
@@ -195,10 +221,10 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 		response.Write(writer)
 		writer.Flush()
 		if err != nil {
-			log.Error("Socks: failed to write response: ", err)
+			this.meta.Logger.Error("Socks: failed to write response: ", err)
 			return err
 		}
-		log.Warning("Socks: Unsupported socks command ", request.Command)
+		this.meta.Logger.Warning("Socks: Unsupported socks command ", request.Command)
 		return ErrUnsupportedSocksCommand
 	}
 
@@ -211,7 +237,7 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 
 	response.Write(writer)
 	if err != nil {
-		log.Error("Socks: failed to write response: ", err)
+		this.meta.Logger.Error("Socks: failed to write response: ", err)
 		return err
 	}
 
@@ -222,11 +248,12 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 	session := &proxy.SessionInfo{
 		Source:      clientAddr,
 		Destination: dest,
+		User:        user,
 	}
-	log.Info("Socks: TCP Connect request to ", dest)
-	log.Access(clientAddr, dest, log.AccessAccepted, "")
+	this.meta.Logger.Info("Socks: TCP Connect request to ", dest)
+	this.meta.Logger.Access(clientAddr, dest, log.AccessAccepted, "")
 
-	this.transport(reader, writer, session)
+	this.transport(ctx, reader, writer, session)
 	return nil
 }
 
@@ -250,7 +277,7 @@ func (this *Server) handleUDP(reader io.Reader, writer *v2io.BufferedWriter) err
 	err := writer.Flush()
 
 	if err != nil {
-		log.Error("Socks: failed to write response: ", err)
+		this.meta.Logger.Error("Socks: failed to write response: ", err)
 		return err
 	}
 
@@ -262,7 +289,7 @@ func (this *Server) handleUDP(reader io.Reader, writer *v2io.BufferedWriter) err
 	return nil
 }
 
-func (this *Server) handleSocks4(clientAddr v2net.Destination, reader *v2io.BufferedReader, writer *v2io.BufferedWriter, auth protocol.Socks4AuthenticationRequest) error {
+func (this *Server) handleSocks4(ctx context.Context, clientAddr v2net.Destination, user *v2protocol.User, timedReader *v2net.TimeOutReader, reader *v2io.BufferedReader, writer *v2io.BufferedWriter, auth protocol.Socks4AuthenticationRequest) error {
 	result := protocol.Socks4RequestGranted
 	if auth.Command == protocol.CmdBind {
 		result = protocol.Socks4RequestRejected
@@ -272,11 +299,12 @@ func (this *Server) handleSocks4(clientAddr v2net.Destination, reader *v2io.Buff
 	socks4Response.Write(writer)
 
 	if result == protocol.Socks4RequestRejected {
-		log.Warning("Socks: Unsupported socks 4 command ", auth.Command)
-		log.Access(clientAddr, "", log.AccessRejected, ErrUnsupportedSocksCommand)
+		this.meta.Logger.Warning("Socks: Unsupported socks 4 command ", auth.Command)
+		this.meta.Logger.Access(clientAddr, "", log.AccessRejected, ErrUnsupportedSocksCommand)
 		return ErrUnsupportedSocksCommand
 	}
 
+	timedReader.SetTimeOut(this.config.Timeout)
 	reader.SetCached(false)
 	writer.SetCached(false)
 
@@ -284,14 +312,15 @@ func (this *Server) handleSocks4(clientAddr v2net.Destination, reader *v2io.Buff
 	session := &proxy.SessionInfo{
 		Source:      clientAddr,
 		Destination: dest,
+		User:        user,
 	}
-	log.Access(clientAddr, dest, log.AccessAccepted, "")
-	this.transport(reader, writer, session)
+	this.meta.Logger.Access(clientAddr, dest, log.AccessAccepted, "")
+	this.transport(ctx, reader, writer, session)
 	return nil
 }
 
-func (this *Server) transport(reader io.Reader, writer io.Writer, session *proxy.SessionInfo) {
-	ray := this.packetDispatcher.DispatchToOutbound(this.meta, session)
+func (this *Server) transport(ctx context.Context, reader io.Reader, writer io.Writer, session *proxy.SessionInfo) {
+	ray := this.packetDispatcher.DispatchToOutbound(ctx, this.meta, session)
 	input := ray.InboundInput()
 	output := ray.InboundOutput()
 
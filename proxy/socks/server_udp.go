@@ -10,10 +10,14 @@ import (
 )
 
 func (this *Server) listenUDP() error {
-	this.udpServer = udp.NewUDPServer(this.meta, this.packetDispatcher)
-	udpHub, err := udp.ListenUDP(this.meta.Address, this.meta.Port, udp.ListenOption{Callback: this.handleUDPPayload})
+	this.udpServer = udp.NewUDPServer(this.meta, this.packetDispatcher, this.udpOptions)
+	udpHub, err := udp.ListenUDP(this.meta.Address, this.meta.Port, udp.ListenOption{
+		Callback:     this.handleUDPPayload,
+		SourceFilter: this.meta.SourceFilter,
+		Bans:         this.meta.Bans,
+	})
 	if err != nil {
-		log.Error("Socks: Failed to listen on udp ", this.meta.Address, ":", this.meta.Port)
+		this.meta.Logger.Error("Socks: Failed to listen on udp ", this.meta.Address, ":", this.meta.Port)
 		return err
 	}
 	this.udpMutex.Lock()
@@ -25,12 +29,12 @@ func (this *Server) listenUDP() error {
 
 func (this *Server) handleUDPPayload(payload *alloc.Buffer, session *proxy.SessionInfo) {
 	source := session.Source
-	log.Info("Socks: Client UDP connection from ", source)
+	this.meta.Logger.Info("Socks: Client UDP connection from ", source)
 	request, err := protocol.ReadUDPRequest(payload.Value)
 	payload.Release()
 
 	if err != nil {
-		log.Error("Socks: Failed to parse UDP request: ", err)
+		this.meta.Logger.Error("Socks: Failed to parse UDP request: ", err)
 		return
 	}
 	if request.Data.Len() == 0 {
@@ -38,37 +42,47 @@ func (this *Server) handleUDPPayload(payload *alloc.Buffer, session *proxy.Sessi
 		return
 	}
 	if request.Fragment != 0 {
-		log.Warning("Socks: Dropping fragmented UDP packets.")
+		this.meta.Logger.Warning("Socks: Dropping fragmented UDP packets.")
 		// TODO handle fragments
 		request.Data.Release()
 		return
 	}
 
-	log.Info("Socks: Send packet to ", request.Destination(), " with ", request.Data.Len(), " bytes")
-	log.Access(source, request.Destination, log.AccessAccepted, "")
-	this.udpServer.Dispatch(&proxy.SessionInfo{Source: source, Destination: request.Destination()}, request.Data, func(destination v2net.Destination, payload *alloc.Buffer) {
-		response := &protocol.Socks5UDPRequest{
-			Fragment: 0,
-			Address:  request.Destination().Address,
-			Port:     request.Destination().Port,
-			Data:     payload,
-		}
-		log.Info("Socks: Writing back UDP response with ", payload.Len(), " bytes to ", destination)
+	this.meta.Logger.Info("Socks: Send packet to ", request.Destination(), " with ", request.Data.Len(), " bytes")
+	this.meta.Logger.Access(source, request.Destination, log.AccessAccepted, "")
+	udpSession := &proxy.SessionInfo{Source: source, Destination: request.Destination()}
+	if this.config.PacketAddr {
+		this.udpServer.DispatchPacketAddr(udpSession, request.Data, this.writeUDPResponse)
+		return
+	}
+	this.udpServer.Dispatch(udpSession, request.Data, func(destination v2net.Destination, payload *alloc.Buffer) {
+		this.writeUDPResponse(destination, request.Destination(), payload)
+	})
+}
+
+// writeUDPResponse sends the payload back to the client at destination, as if it comes from the given address.
+func (this *Server) writeUDPResponse(destination v2net.Destination, from v2net.Destination, payload *alloc.Buffer) {
+	response := &protocol.Socks5UDPRequest{
+		Fragment: 0,
+		Address:  from.Address,
+		Port:     from.Port,
+		Data:     payload,
+	}
+	this.meta.Logger.Info("Socks: Writing back UDP response with ", payload.Len(), " bytes from ", from, " to ", destination)
 
-		udpMessage := alloc.NewLocalBuffer(2048).Clear()
-		response.Write(udpMessage)
+	udpMessage := alloc.NewLocalBuffer(2048).Clear()
+	response.Write(udpMessage)
 
-		this.udpMutex.RLock()
-		if !this.accepting {
-			this.udpMutex.RUnlock()
-			return
-		}
-		nBytes, err := this.udpHub.WriteTo(udpMessage.Value, destination)
+	this.udpMutex.RLock()
+	if !this.accepting {
 		this.udpMutex.RUnlock()
-		udpMessage.Release()
-		response.Data.Release()
-		if err != nil {
-			log.Error("Socks: failed to write UDP message (", nBytes, " bytes) to ", destination, ": ", err)
-		}
-	})
+		return
+	}
+	nBytes, err := this.udpHub.WriteTo(udpMessage.Value, destination)
+	this.udpMutex.RUnlock()
+	udpMessage.Release()
+	response.Data.Release()
+	if err != nil {
+		this.meta.Logger.Error("Socks: failed to write UDP message (", nBytes, " bytes) to ", destination, ": ", err)
+	}
 }
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -31,7 +32,7 @@ func (this *InboundConnectionHandler) Close() {
 }
 
 func (this *InboundConnectionHandler) Communicate(destination v2net.Destination) error {
-	ray := this.PacketDispatcher.DispatchToOutbound(&proxy.InboundHandlerMeta{
+	ray := this.PacketDispatcher.DispatchToOutbound(context.Background(), &proxy.InboundHandlerMeta{
 		AllowPassiveConnection: false,
 	}, &proxy.SessionInfo{
 		Source:      v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(0)),
@@ -41,31 +42,27 @@ func (this *InboundConnectionHandler) Communicate(destination v2net.Destination)
 	input := ray.InboundInput()
 	output := ray.InboundOutput()
 
-	readFinish := &sync.Mutex{}
-	writeFinish := &sync.Mutex{}
-
-	readFinish.Lock()
-	writeFinish.Lock()
+	var finish sync.WaitGroup
+	finish.Add(2)
 
 	go func() {
+		defer finish.Done()
 		v2reader := v2io.NewAdaptiveReader(this.ConnInput)
 		defer v2reader.Release()
 
 		v2io.Pipe(v2reader, input)
 		input.Close()
-		readFinish.Unlock()
 	}()
 
 	go func() {
+		defer finish.Done()
 		v2writer := v2io.NewAdaptiveWriter(this.ConnOutput)
 		defer v2writer.Release()
 
 		v2io.Pipe(output, v2writer)
 		output.Release()
-		writeFinish.Unlock()
 	}()
 
-	readFinish.Lock()
-	writeFinish.Lock()
+	finish.Wait()
 	return nil
 }
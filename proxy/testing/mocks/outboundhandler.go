@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -18,7 +19,7 @@ type OutboundConnectionHandler struct {
 	ConnOutput  io.Writer
 }
 
-func (this *OutboundConnectionHandler) Dispatch(destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
+func (this *OutboundConnectionHandler) Dispatch(ctx context.Context, destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
 	input := ray.OutboundInput()
 	output := ray.OutboundOutput()
 
@@ -26,20 +27,19 @@ func (this *OutboundConnectionHandler) Dispatch(destination v2net.Destination, p
 	this.ConnOutput.Write(payload.Value)
 	payload.Release()
 
-	writeFinish := &sync.Mutex{}
-
-	writeFinish.Lock()
+	var writeFinish sync.WaitGroup
+	writeFinish.Add(1)
 
 	go func() {
 		v2writer := v2io.NewAdaptiveWriter(this.ConnOutput)
 		defer v2writer.Release()
 
 		v2io.Pipe(input, v2writer)
-		writeFinish.Unlock()
+		writeFinish.Done()
 		input.Release()
 	}()
 
-	writeFinish.Lock()
+	writeFinish.Wait()
 
 	v2reader := v2io.NewAdaptiveReader(this.ConnInput)
 	defer v2reader.Release()
@@ -1,6 +1,8 @@
 package vmess
 
 import (
+	"runtime"
+
 	"v2ray.com/core/common/dice"
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/protocol"
@@ -10,6 +12,8 @@ import (
 type Account struct {
 	ID       *protocol.ID
 	AlterIDs []*protocol.ID
+	// Security is the encryption of the data of requests sent with this account.
+	Security protocol.Security
 }
 
 func NewAccount() protocol.AsAccount {
@@ -42,5 +46,24 @@ func (this *AccountPB) AsAccount() (protocol.Account, error) {
 	return &Account{
 		ID:       protoId,
 		AlterIDs: protocol.NewAlterIDs(protoId, uint16(this.AlterId)),
+		Security: this.Security.AsSecurity(),
 	}, nil
 }
+
+// AsSecurity returns the security of the type. AUTO is AES-128-GCM on architectures where AES is accelerated by
+// hardware, and ChaCha20-Poly1305 on others.
+func (this SecurityType) AsSecurity() protocol.Security {
+	switch this {
+	case SecurityType_AES128_GCM:
+		return protocol.SecurityAES128GCM
+	case SecurityType_CHACHA20_POLY1305:
+		return protocol.SecurityChaCha20Poly1305
+	case SecurityType_AUTO:
+		switch runtime.GOARCH {
+		case "amd64", "arm64", "s390x", "ppc64le":
+			return protocol.SecurityAES128GCM
+		}
+		return protocol.SecurityChaCha20Poly1305
+	}
+	return protocol.SecurityLegacy
+}
@@ -28,9 +28,37 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type SecurityType int32
+
+const (
+	SecurityType_LEGACY            SecurityType = 0
+	SecurityType_AUTO              SecurityType = 2
+	SecurityType_AES128_GCM        SecurityType = 3
+	SecurityType_CHACHA20_POLY1305 SecurityType = 4
+)
+
+var SecurityType_name = map[int32]string{
+	0: "LEGACY",
+	2: "AUTO",
+	3: "AES128_GCM",
+	4: "CHACHA20_POLY1305",
+}
+var SecurityType_value = map[string]int32{
+	"LEGACY":            0,
+	"AUTO":              2,
+	"AES128_GCM":        3,
+	"CHACHA20_POLY1305": 4,
+}
+
+func (x SecurityType) String() string {
+	return proto.EnumName(SecurityType_name, int32(x))
+}
+func (SecurityType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
 type AccountPB struct {
-	Id      string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	AlterId uint32 `protobuf:"varint,2,opt,name=alter_id,json=alterId" json:"alter_id,omitempty"`
+	Id       string       `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	AlterId  uint32       `protobuf:"varint,2,opt,name=alter_id,json=alterId" json:"alter_id,omitempty"`
+	Security SecurityType `protobuf:"varint,3,opt,name=security,enum=v2ray.core.proxy.vmess.SecurityType" json:"security,omitempty"`
 }
 
 func (m *AccountPB) Reset()                    { *m = AccountPB{} }
@@ -40,20 +68,27 @@ func (*AccountPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0
 
 func init() {
 	proto.RegisterType((*AccountPB)(nil), "v2ray.core.proxy.vmess.AccountPB")
+	proto.RegisterEnum("v2ray.core.proxy.vmess.SecurityType", SecurityType_name, SecurityType_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/proxy/vmess/account.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 153 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xd2, 0x28, 0x33, 0x2a, 0x4a,
+	// 250 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xd2, 0x28, 0x33, 0x2a, 0x4a,
 	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x2f, 0x28, 0xca, 0xaf, 0xa8,
 	0xd4, 0x2f, 0xcb, 0x4d, 0x2d, 0x2e, 0xd6, 0x4f, 0x4c, 0x4e, 0xce, 0x2f, 0xcd, 0x2b, 0xd1, 0x2b,
 	0x28, 0xca, 0x2f, 0xc9, 0x17, 0x12, 0x83, 0xa9, 0x2c, 0x4a, 0xd5, 0x03, 0xab, 0xd2, 0x03, 0xab,
-	0x52, 0x32, 0xe3, 0xe2, 0x74, 0x84, 0x28, 0x0c, 0x70, 0x12, 0xe2, 0xe3, 0x62, 0xca, 0x4c, 0x91,
+	0x52, 0xaa, 0xe0, 0xe2, 0x74, 0x84, 0x28, 0x0c, 0x70, 0x12, 0xe2, 0xe3, 0x62, 0xca, 0x4c, 0x91,
 	0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x62, 0xca, 0x4c, 0x11, 0x92, 0xe4, 0xe2, 0x48, 0xcc, 0x29,
 	0x49, 0x2d, 0x8a, 0xcf, 0x4c, 0x91, 0x60, 0x52, 0x60, 0xd4, 0xe0, 0x0d, 0x62, 0x07, 0xf3, 0x3d,
-	0x53, 0x9c, 0x0c, 0xb9, 0xa4, 0x92, 0xf3, 0x73, 0xf5, 0xb0, 0x9b, 0xea, 0xc4, 0x03, 0x33, 0x13,
-	0x64, 0x77, 0x14, 0x2b, 0x58, 0x30, 0x89, 0x0d, 0xec, 0x12, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff,
-	0xff, 0xe2, 0x1e, 0x29, 0x36, 0xb5, 0x00, 0x00, 0x00,
+	0x53, 0x84, 0x1c, 0xb8, 0x38, 0x8a, 0x53, 0x93, 0x4b, 0x8b, 0x32, 0x4b, 0x2a, 0x25, 0x98, 0x15,
+	0x18, 0x35, 0xf8, 0x8c, 0x54, 0xf4, 0xb0, 0x5b, 0xa1, 0x17, 0x0c, 0x55, 0x17, 0x52, 0x59, 0x90,
+	0x1a, 0x04, 0xd7, 0xa5, 0xe5, 0xcd, 0xc5, 0x83, 0x2c, 0x23, 0xc4, 0xc5, 0xc5, 0xe6, 0xe3, 0xea,
+	0xee, 0xe8, 0x1c, 0x29, 0xc0, 0x20, 0xc4, 0xc1, 0xc5, 0xe2, 0x18, 0x1a, 0xe2, 0x2f, 0xc0, 0x24,
+	0xc4, 0xc7, 0xc5, 0xe5, 0xe8, 0x1a, 0x6c, 0x68, 0x64, 0x11, 0xef, 0xee, 0xec, 0x2b, 0xc0, 0x2c,
+	0x24, 0xca, 0x25, 0xe8, 0xec, 0xe1, 0xe8, 0xec, 0xe1, 0x68, 0x64, 0x10, 0x1f, 0xe0, 0xef, 0x13,
+	0x69, 0x68, 0x6c, 0x60, 0x2a, 0xc0, 0xe2, 0x64, 0xc8, 0x25, 0x95, 0x9c, 0x9f, 0x8b, 0xc3, 0x05,
+	0x4e, 0x3c, 0x30, 0x2f, 0x82, 0x82, 0x22, 0x8a, 0x15, 0x2c, 0x98, 0xc4, 0x06, 0x0e, 0x18, 0x63,
+	0xc0, 0x00, 0x07, 0xfb, 0x7a, 0x6d, 0x44, 0x01, 0x00, 0x00,
 }
@@ -4,19 +4,61 @@ package vmess
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
+
+	"v2ray.com/core/common/uuid"
 )
 
 func (u *AccountPB) UnmarshalJSON(data []byte) error {
 	type JsonConfig struct {
 		ID       string `json:"id"`
 		AlterIds uint16 `json:"alterId"`
+		Security string `json:"security"`
 	}
 	var rawConfig JsonConfig
 	if err := json.Unmarshal(data, &rawConfig); err != nil {
 		return err
 	}
+	if _, err := uuid.ParseString(rawConfig.ID); err != nil {
+		return errors.New("VMess: Invalid ID: " + rawConfig.ID)
+	}
 	u.Id = rawConfig.ID
 	u.AlterId = uint32(rawConfig.AlterIds)
+	switch strings.ToLower(rawConfig.Security) {
+	case "", "legacy", "aes-128-cfb":
+		u.Security = SecurityType_LEGACY
+	case "auto":
+		u.Security = SecurityType_AUTO
+	case "aes-128-gcm":
+		u.Security = SecurityType_AES128_GCM
+	case "chacha20-poly1305":
+		u.Security = SecurityType_CHACHA20_POLY1305
+	default:
+		return errors.New("VMess: Unknown security type: " + rawConfig.Security)
+	}
 
 	return nil
 }
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (u *AccountPB) MarshalJSON() ([]byte, error) {
+	type JsonConfig struct {
+		ID       string `json:"id"`
+		AlterIds uint32 `json:"alterId,omitempty"`
+		Security string `json:"security,omitempty"`
+	}
+	rawConfig := &JsonConfig{
+		ID:       u.Id,
+		AlterIds: u.AlterId,
+	}
+	switch u.Security {
+	case SecurityType_AUTO:
+		rawConfig.Security = "auto"
+	case SecurityType_AES128_GCM:
+		rawConfig.Security = "aes-128-gcm"
+	case SecurityType_CHACHA20_POLY1305:
+		rawConfig.Security = "chacha20-poly1305"
+	}
+	return json.Marshal(rawConfig)
+}
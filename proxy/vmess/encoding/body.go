@@ -0,0 +1,83 @@
+package encoding
+
+import (
+	"crypto/cipher"
+	"crypto/md5"
+	"io"
+
+	"v2ray.com/core/common/crypto"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/protocol"
+	vmessio "v2ray.com/core/proxy/vmess/io"
+)
+
+// newAEAD creates the AEAD of the security from a body key of 16 bytes. ChaCha20-Poly1305 takes a key of 32 bytes,
+// which is derived from the body key by MD5.
+func newAEAD(security protocol.Security, key []byte) cipher.AEAD {
+	if security == protocol.SecurityChaCha20Poly1305 {
+		first := md5.Sum(key)
+		second := md5.Sum(first[:])
+		return crypto.NewChaCha20Poly1305(append(first[:], second[:]...))
+	}
+	return crypto.NewAesGcm(key)
+}
+
+func newAEADChunkWriter(security protocol.Security, key []byte, iv []byte, writer io.Writer) v2io.Writer {
+	aead := newAEAD(security, key)
+	return crypto.NewAEADChunkWriter(v2io.NewAdaptiveWriter(writer), aead, crypto.NewIncreasingNonce(iv[:aead.NonceSize()]))
+}
+
+func newAEADChunkReader(security protocol.Security, key []byte, iv []byte, reader io.Reader) v2io.Reader {
+	aead := newAEAD(security, key)
+	return crypto.NewAEADChunkReader(reader, aead, crypto.NewIncreasingNonce(iv[:aead.NonceSize()]))
+}
+
+// EncodeRequestBodyStream returns the writer of the body of the request, as its security and options tell.
+func (this *ClientSession) EncodeRequestBodyStream(request *protocol.RequestHeader, writer io.Writer) v2io.Writer {
+	if request.Security.IsAEAD() {
+		return newAEADChunkWriter(request.Security, this.requestBodyKey, this.requestBodyIV, writer)
+	}
+	var streamWriter v2io.Writer = v2io.NewAdaptiveWriter(this.EncodeRequestBody(writer))
+	if request.Option.Has(protocol.RequestOptionChunkStream) {
+		streamWriter = vmessio.NewAuthChunkWriter(streamWriter)
+	}
+	return streamWriter
+}
+
+// DecodeResponseBodyStream returns the reader of the body of the response. It must be called after
+// DecodeResponseHeader.
+func (this *ClientSession) DecodeResponseBodyStream(request *protocol.RequestHeader, reader io.Reader) v2io.Reader {
+	if request.Security.IsAEAD() {
+		return newAEADChunkReader(request.Security, this.responseBodyKey, this.responseBodyIV, reader)
+	}
+	decryptReader := this.DecodeResponseBody(reader)
+	if request.Option.Has(protocol.RequestOptionChunkStream) {
+		return vmessio.NewAuthChunkReader(decryptReader)
+	}
+	return v2io.NewAdaptiveReader(decryptReader)
+}
+
+// DecodeRequestBodyStream returns the reader of the body of the request, as its security and options tell.
+func (this *ServerSession) DecodeRequestBodyStream(request *protocol.RequestHeader, reader io.Reader) v2io.Reader {
+	if request.Security.IsAEAD() {
+		return newAEADChunkReader(request.Security, this.requestBodyKey, this.requestBodyIV, reader)
+	}
+	decryptReader := this.DecodeRequestBody(reader)
+	if request.Option.Has(protocol.RequestOptionChunkStream) {
+		return vmessio.NewAuthChunkReader(decryptReader)
+	}
+	return v2io.NewAdaptiveReader(decryptReader)
+}
+
+// EncodeResponseBodyStream returns the writer of the body of the response. It must be called after
+// EncodeResponseHeader.
+func (this *ServerSession) EncodeResponseBodyStream(request *protocol.RequestHeader, writer io.Writer) v2io.Writer {
+	if request.Security.IsAEAD() {
+		return newAEADChunkWriter(request.Security, this.responseBodyKey, this.responseBodyIV, writer)
+	}
+	var streamWriter v2io.Writer = v2io.NewAdaptiveWriter(this.EncodeResponseBody(writer))
+	if request.Option.Has(protocol.RequestOptionChunkStream) {
+		streamWriter = vmessio.NewAuthChunkWriter(streamWriter)
+	}
+	return streamWriter
+}
@@ -65,7 +65,7 @@ func (this *ClientSession) EncodeRequestHeader(header *protocol.RequestHeader, w
 	buffer = append(buffer, Version)
 	buffer = append(buffer, this.requestBodyIV...)
 	buffer = append(buffer, this.requestBodyKey...)
-	buffer = append(buffer, this.responseHeader, byte(header.Option), byte(0), byte(0), byte(header.Command))
+	buffer = append(buffer, this.responseHeader, byte(header.Option), byte(header.Security), byte(0), byte(header.Command))
 	buffer = header.Port.Bytes(buffer)
 
 	switch header.Address.Family() {
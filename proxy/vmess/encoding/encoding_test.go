@@ -1,6 +1,8 @@
 package encoding_test
 
 import (
+	"bytes"
+	"io"
 	"testing"
 
 	"v2ray.com/core/common/alloc"
@@ -52,6 +54,67 @@ func TestRequestSerialization(t *testing.T) {
 	assert.Byte(expectedRequest.Version).Equals(actualRequest.Version)
 	assert.Byte(byte(expectedRequest.Command)).Equals(byte(actualRequest.Command))
 	assert.Byte(byte(expectedRequest.Option)).Equals(byte(actualRequest.Option))
+	assert.Byte(byte(expectedRequest.Security)).Equals(byte(actualRequest.Security))
 	assert.Address(expectedRequest.Address).Equals(actualRequest.Address)
 	assert.Port(expectedRequest.Port).Equals(actualRequest.Port)
 }
+
+func testBodySerialization(t *testing.T, security protocol.Security) {
+	assert := assert.On(t)
+
+	user := &protocol.User{
+		Email: "test@v2ray.com",
+	}
+	anyAccount, err := ptypes.MarshalAny(&vmess.AccountPB{
+		Id: uuid.New().String(),
+	})
+	assert.Error(err).IsNil()
+	user.Account = anyAccount
+
+	request := &protocol.RequestHeader{
+		Version:  1,
+		User:     user,
+		Command:  protocol.RequestCommandTCP,
+		Option:   protocol.RequestOptionChunkStream,
+		Security: security,
+		Address:  v2net.DomainAddress("www.v2ray.com"),
+		Port:     v2net.Port(443),
+	}
+
+	cache := new(bytes.Buffer)
+	client := NewClientSession(protocol.DefaultIDHash)
+	client.EncodeRequestHeader(request, cache)
+	requestWriter := client.EncodeRequestBodyStream(request, cache)
+	assert.Error(requestWriter.Write(alloc.NewBuffer().Clear().AppendString("request"))).IsNil()
+	assert.Error(requestWriter.Write(alloc.NewBuffer().Clear())).IsNil()
+
+	userValidator := vmess.NewTimedUserValidator(protocol.DefaultIDHash)
+	userValidator.Add(user)
+	server := NewServerSession(userValidator)
+	actualRequest, err := server.DecodeRequestHeader(cache)
+	assert.Error(err).IsNil()
+	assert.Byte(byte(actualRequest.Security)).Equals(byte(security))
+
+	requestReader := server.DecodeRequestBodyStream(actualRequest, cache)
+	data, err := requestReader.Read()
+	assert.Error(err).IsNil()
+	assert.String(data.String()).Equals("request")
+	_, err = requestReader.Read()
+	assert.Error(err).Equals(io.EOF)
+
+	server.EncodeResponseHeader(&protocol.ResponseHeader{}, cache)
+	responseWriter := server.EncodeResponseBodyStream(actualRequest, cache)
+	assert.Error(responseWriter.Write(alloc.NewBuffer().Clear().AppendString("response"))).IsNil()
+
+	_, err = client.DecodeResponseHeader(cache)
+	assert.Error(err).IsNil()
+	data, err = client.DecodeResponseBodyStream(request, cache).Read()
+	assert.Error(err).IsNil()
+	assert.String(data.String()).Equals("response")
+}
+
+func TestBodySerialization(t *testing.T) {
+	testBodySerialization(t, protocol.SecurityLegacy)
+	testBodySerialization(t, protocol.SecurityAES128GCM)
+	testBodySerialization(t, protocol.SecurityChaCha20Poly1305)
+}
@@ -87,7 +87,8 @@ func (this *ServerSession) DecodeRequestHeader(reader io.Reader) (*protocol.Requ
 	this.requestBodyIV = append([]byte(nil), buffer[1:17]...)   // 16 bytes
 	this.requestBodyKey = append([]byte(nil), buffer[17:33]...) // 16 bytes
 	this.responseHeader = buffer[33]                            // 1 byte
-	request.Option = protocol.RequestOption(buffer[34])         // 1 byte + 2 bytes reserved
+	request.Option = protocol.RequestOption(buffer[34])         // 1 byte
+	request.Security = protocol.Security(buffer[35] & 0x0f)     // 1 byte + 1 byte reserved
 	request.Command = protocol.RequestCommand(buffer[37])
 
 	request.Port = v2net.PortFromBytes(buffer[38:40])
@@ -143,6 +144,11 @@ func (this *ServerSession) DecodeRequestHeader(reader io.Reader) (*protocol.Requ
 		return nil, transport.ErrCorruptedPacket
 	}
 
+	if request.Security != protocol.SecurityLegacy && !request.Security.IsAEAD() {
+		log.Info("VMess: Unknown security type ", request.Security)
+		return nil, transport.ErrCorruptedPacket
+	}
+
 	return request, nil
 }
 
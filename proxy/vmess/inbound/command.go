@@ -1,7 +1,6 @@
 package inbound
 
 import (
-	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy/vmess"
 )
@@ -17,7 +16,7 @@ func (this *VMessInboundHandler) generateCommand(request *protocol.RequestHeader
 					availableMin = 255
 				}
 
-				log.Info("VMessIn: Pick detour handler for port ", inboundHandler.Port(), " for ", availableMin, " minutes.")
+				this.meta.Logger.Info("VMessIn: Pick detour handler for port ", inboundHandler.Port(), " for ", availableMin, " minutes.")
 				user := inboundHandler.GetUser(request.User.Email)
 				if user == nil {
 					return nil
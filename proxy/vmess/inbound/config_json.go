@@ -5,8 +5,10 @@ package inbound
 import (
 	"encoding/json"
 	"errors"
+	"strconv"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy/registry"
@@ -15,13 +17,16 @@ import (
 	"github.com/golang/protobuf/ptypes"
 )
 
+// userKeys are the fields of a user, which is decoded into both a protocol.User and a vmess.AccountPB.
+var userKeys = []string{"email", "level", "id", "alterId", "security"}
+
 func (this *DetourConfig) UnmarshalJSON(data []byte) error {
 	type JsonDetourConfig struct {
 		ToTag string `json:"to"`
 	}
 	jsonConfig := new(JsonDetourConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("VMess|Inbound: Failed to parse detour config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("VMess|Inbound: Failed to parse detour config: ", err)
 	}
 	this.To = jsonConfig.ToTag
 	return nil
@@ -37,8 +42,8 @@ func (this *DefaultConfig) UnmarshalJSON(data []byte) error {
 		Level    byte   `json:"level"`
 	}
 	jsonConfig := new(JsonDefaultConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("VMess|Inbound: Failed to parse default config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("VMess|Inbound: Failed to parse default config: ", err)
 	}
 	this.AlterId = uint32(jsonConfig.AlterIDs)
 	if this.AlterId == 0 {
@@ -56,8 +61,8 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		DetourConfig *DetourConfig     `json:"detour"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("VMess|Inbound: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("VMess|Inbound: Failed to parse config: ", err)
 	}
 	this.Default = jsonConfig.Defaults
 	if this.Default == nil {
@@ -73,6 +78,9 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 	}
 	this.User = make([]*protocol.User, len(jsonConfig.Users))
 	for idx, rawData := range jsonConfig.Users {
+		if err := loader.CheckJSONFields(rawData, userKeys, "clients", "["+strconv.Itoa(idx)+"]"); err != nil {
+			return err
+		}
 		user := new(protocol.User)
 		if err := json.Unmarshal(rawData, user); err != nil {
 			return errors.New("VMess|Inbound: Invalid user: " + err.Error())
@@ -93,6 +101,37 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type JsonDetourConfig struct {
+		ToTag string `json:"to"`
+	}
+	type JsonDefaultConfig struct {
+		AlterIDs uint32 `json:"alterId,omitempty"`
+		Level    uint32 `json:"level,omitempty"`
+	}
+	type JsonConfig struct {
+		Users        []*protocol.User   `json:"clients"`
+		Defaults     *JsonDefaultConfig `json:"default,omitempty"`
+		DetourConfig *JsonDetourConfig  `json:"detour,omitempty"`
+	}
+	jsonConfig := &JsonConfig{
+		Users: this.User,
+	}
+	if this.Default != nil {
+		jsonConfig.Defaults = &JsonDefaultConfig{
+			AlterIDs: this.Default.AlterId,
+			Level:    this.Default.Level,
+		}
+	}
+	if this.Detour != nil {
+		jsonConfig.DetourConfig = &JsonDetourConfig{
+			ToTag: this.Detour.To,
+		}
+	}
+	return json.Marshal(jsonConfig)
+}
+
 func init() {
 	registry.RegisterInboundConfig("vmess", func() interface{} { return new(Config) })
 }
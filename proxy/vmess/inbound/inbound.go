@@ -1,11 +1,13 @@
 package inbound
 
 import (
+	"context"
 	"io"
 	"sync"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/alloc"
@@ -18,7 +20,6 @@ import (
 	"v2ray.com/core/proxy/registry"
 	"v2ray.com/core/proxy/vmess"
 	"v2ray.com/core/proxy/vmess/encoding"
-	vmessio "v2ray.com/core/proxy/vmess/io"
 	"v2ray.com/core/transport/internet"
 
 	"github.com/golang/protobuf/ptypes"
@@ -70,11 +71,30 @@ func (this *userByEmail) Get(email string) (*protocol.User, bool) {
 	return user, found
 }
 
+// Add keeps the user, unless there is already a user with the same email.
+func (this *userByEmail) Add(user *protocol.User) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	if _, found := this.cache[user.Email]; found {
+		return false
+	}
+	this.cache[user.Email] = user
+	return true
+}
+
+func (this *userByEmail) Remove(email string) {
+	this.Lock()
+	delete(this.cache, email)
+	this.Unlock()
+}
+
 // Inbound connection handler that handles messages in VMess format.
 type VMessInboundHandler struct {
 	sync.RWMutex
 	packetDispatcher      dispatcher.PacketDispatcher
 	inboundHandlerManager proxyman.InboundHandlerManager
+	policy                *policy.Manager
 	clients               protocol.UserValidator
 	usersByEmail          *userByEmail
 	accepting             bool
@@ -87,6 +107,11 @@ func (this *VMessInboundHandler) Port() v2net.Port {
 	return this.meta.Port
 }
 
+// Accepting implements proxy.AcceptingReporter.
+func (this *VMessInboundHandler) Accepting() bool {
+	return this.accepting
+}
+
 func (this *VMessInboundHandler) Close() {
 	this.accepting = false
 	if this.listener != nil {
@@ -114,6 +139,39 @@ func (this *VMessInboundHandler) GetUser(email string) *protocol.User {
 	return user
 }
 
+func (this *VMessInboundHandler) AddUser(user *protocol.User) error {
+	this.RLock()
+	defer this.RUnlock()
+
+	if this.clients == nil {
+		return proxy.ErrHandlerClosed
+	}
+	if len(user.Email) > 0 && !this.usersByEmail.Add(user) {
+		return common.ErrDuplicatedName
+	}
+	if err := this.clients.Add(user); err != nil {
+		if len(user.Email) > 0 {
+			this.usersByEmail.Remove(user.Email)
+		}
+		return err
+	}
+	return nil
+}
+
+func (this *VMessInboundHandler) RemoveUser(email string) error {
+	this.RLock()
+	defer this.RUnlock()
+
+	if this.clients == nil {
+		return proxy.ErrHandlerClosed
+	}
+	if !this.clients.Remove(email) {
+		return proxy.ErrUserNotFound
+	}
+	this.usersByEmail.Remove(email)
+	return nil
+}
+
 func (this *VMessInboundHandler) Start() error {
 	if this.accepting {
 		return nil
@@ -121,7 +179,7 @@ func (this *VMessInboundHandler) Start() error {
 
 	tcpListener, err := internet.ListenTCP(this.meta.Address, this.meta.Port, this.HandleConnection, this.meta.StreamSettings)
 	if err != nil {
-		log.Error("VMess|Inbound: Unable to listen tcp ", this.meta.Address, ":", this.meta.Port, ": ", err)
+		this.meta.Logger.Error("VMess|Inbound: Unable to listen tcp ", this.meta.Address, ":", this.meta.Port, ": ", err)
 		return err
 	}
 	this.accepting = true
@@ -138,7 +196,8 @@ func (this *VMessInboundHandler) HandleConnection(connection internet.Connection
 		return
 	}
 
-	connReader := v2net.NewTimeOutReader(8, connection)
+	// The user is unknown until the header is decoded, so the handshake is limited by the policy of level 0.
+	connReader := v2net.NewTimeOutReader(policy.Seconds(this.policy.ForLevel(0).Handshake), connection)
 	defer connReader.Release()
 
 	reader := v2io.NewBufferedReader(connReader)
@@ -157,41 +216,41 @@ func (this *VMessInboundHandler) HandleConnection(connection internet.Connection
 
 	if err != nil {
 		if err != io.EOF {
-			log.Access(connection.RemoteAddr(), "", log.AccessRejected, err)
-			log.Warning("VMessIn: Invalid request from ", connection.RemoteAddr(), ": ", err)
+			this.meta.Logger.Access(connection.RemoteAddr(), "", log.AccessRejected, err)
+			this.meta.Logger.Warning("VMessIn: Invalid request from ", connection.RemoteAddr(), ": ", err)
+			proxy.ReportAuthFailure(this.meta, proxy.SourceAddress(connection.RemoteAddr()))
 		}
 		connection.SetReusable(false)
 		return
 	}
-	log.Access(connection.RemoteAddr(), request.Destination(), log.AccessAccepted, "")
-	log.Info("VMessIn: Received request for ", request.Destination())
+	this.meta.Logger.Access(connection.RemoteAddr(), request.Destination(), log.AccessAccepted, "")
+	this.meta.Logger.Info("VMessIn: Received request for ", request.Destination())
 
 	connection.SetReusable(request.Option.Has(protocol.RequestOptionConnectionReuse))
 
-	ray := this.packetDispatcher.DispatchToOutbound(this.meta, &proxy.SessionInfo{
+	// The outbound stops once the connection is handled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ray := this.packetDispatcher.DispatchToOutbound(ctx, this.meta, &proxy.SessionInfo{
 		Source:      v2net.DestinationFromAddr(connection.RemoteAddr()),
 		Destination: request.Destination(),
+		User:        request.User,
 	})
 	input := ray.InboundInput()
 	output := ray.InboundOutput()
 	defer input.Close()
 	defer output.Release()
 
-	var readFinish sync.Mutex
-	readFinish.Lock()
+	var readFinish sync.WaitGroup
+	readFinish.Add(1)
 
-	userSettings := request.User.GetSettings()
-	connReader.SetTimeOut(userSettings.PayloadReadTimeout)
+	connReader.SetTimeOut(policy.Seconds(this.policy.ForLevel(request.User.Level).ConnectionIdle))
 	reader.SetCached(false)
 
 	go func() {
-		bodyReader := session.DecodeRequestBody(reader)
-		var requestReader v2io.Reader
-		if request.Option.Has(protocol.RequestOptionChunkStream) {
-			requestReader = vmessio.NewAuthChunkReader(bodyReader)
-		} else {
-			requestReader = v2io.NewAdaptiveReader(bodyReader)
-		}
+		defer readFinish.Done()
+		requestReader := session.DecodeRequestBodyStream(request, reader)
 		err := v2io.Pipe(requestReader, input)
 		if err != io.EOF {
 			connection.SetReusable(false)
@@ -199,7 +258,6 @@ func (this *VMessInboundHandler) HandleConnection(connection internet.Connection
 
 		requestReader.Release()
 		input.Close()
-		readFinish.Unlock()
 	}()
 
 	writer := v2io.NewBufferedWriter(connection)
@@ -215,11 +273,7 @@ func (this *VMessInboundHandler) HandleConnection(connection internet.Connection
 
 	session.EncodeResponseHeader(response, writer)
 
-	bodyWriter := session.EncodeResponseBody(writer)
-	var v2writer v2io.Writer = v2io.NewAdaptiveWriter(bodyWriter)
-	if request.Option.Has(protocol.RequestOptionChunkStream) {
-		v2writer = vmessio.NewAuthChunkWriter(v2writer)
-	}
+	v2writer := session.EncodeResponseBodyStream(request, writer)
 
 	// Optimize for small response packet
 	if data, err := output.Read(); err == nil {
@@ -244,7 +298,7 @@ func (this *VMessInboundHandler) HandleConnection(connection internet.Connection
 	writer.Flush()
 	v2writer.Release()
 
-	readFinish.Lock()
+	readFinish.Wait()
 }
 
 type Factory struct{}
@@ -266,6 +320,7 @@ func (this *Factory) Create(space app.Space, rawConfig interface{}, meta *proxy.
 
 	handler := &VMessInboundHandler{
 		packetDispatcher: space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher),
+		policy:           policy.FromSpace(space),
 		clients:          allowedClients,
 		detours:          config.Detour,
 		usersByEmail:     NewUserByEmail(config.User, config.Default),
@@ -4,9 +4,10 @@ package outbound
 
 import (
 	"encoding/json"
-	"errors"
+	"strconv"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
@@ -17,6 +18,9 @@ import (
 	"github.com/golang/protobuf/ptypes"
 )
 
+// userKeys are the fields of a user, which is decoded into both a protocol.User and a vmess.AccountPB.
+var userKeys = []string{"email", "level", "id", "alterId", "security"}
+
 func (this *Config) UnmarshalJSON(data []byte) error {
 	type RawConfigTarget struct {
 		Address *v2net.AddressPB  `json:"address"`
@@ -27,9 +31,9 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		Receivers []*RawConfigTarget `json:"vnext"`
 	}
 	rawOutbound := &RawOutbound{}
-	err := json.Unmarshal(data, rawOutbound)
+	err := loader.DecodeJSON(data, rawOutbound)
 	if err != nil {
-		return errors.New("VMessOut: Failed to parse config: " + err.Error())
+		return loader.WrapError("VMessOut: Failed to parse config: ", err)
 	}
 	if len(rawOutbound.Receivers) == 0 {
 		log.Error("VMessOut: 0 VMess receiver configured.")
@@ -54,7 +58,11 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 			Address: rec.Address,
 			Port:    uint32(rec.Port),
 		}
-		for _, rawUser := range rec.Users {
+		for userIdx, rawUser := range rec.Users {
+			userPath := []string{"vnext", "[" + strconv.Itoa(idx) + "]", "users", "[" + strconv.Itoa(userIdx) + "]"}
+			if err := loader.CheckJSONFields(rawUser, userKeys, userPath...); err != nil {
+				return err
+			}
 			user := new(protocol.User)
 			if err := json.Unmarshal(rawUser, user); err != nil {
 				log.Error("VMess|Outbound: Invalid user: ", err)
@@ -79,6 +87,29 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type RawConfigTarget struct {
+		Address *v2net.AddressPB `json:"address"`
+		Port    uint32           `json:"port"`
+		Users   []*protocol.User `json:"users"`
+	}
+	type RawOutbound struct {
+		Receivers []*RawConfigTarget `json:"vnext"`
+	}
+	rawOutbound := &RawOutbound{
+		Receivers: make([]*RawConfigTarget, len(this.Receiver)),
+	}
+	for idx, spec := range this.Receiver {
+		rawOutbound.Receivers[idx] = &RawConfigTarget{
+			Address: spec.Address,
+			Port:    spec.Port,
+			Users:   spec.User,
+		}
+	}
+	return json.Marshal(rawOutbound)
+}
+
 func init() {
 	registry.RegisterOutboundConfig("vmess", func() interface{} { return new(Config) })
 }
@@ -36,3 +36,42 @@ func TestConfigTargetParsing(t *testing.T) {
 	spec := protocol.NewServerSpecFromPB(vmess.NewAccount, *specPB)
 	assert.Destination(spec.Destination()).EqualsString("tcp:127.0.0.1:80")
 }
+
+func TestConfigInvalidID(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "vnext": [{
+      "address": "127.0.0.1",
+      "port": 80,
+      "users": [{"id": "e641f5ad-9397-41e3-bf1a"}]
+    }]
+  }`
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(rawJson), &config)
+	assert.Error(err).IsNotNil()
+}
+
+func TestConfigSecurity(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "vnext": [{
+      "address": "127.0.0.1",
+      "port": 80,
+      "users": [{"id": "e641f5ad-9397-41e3-bf1a-e8740dfed019", "security": "chacha20-poly1305"}]
+    }]
+  }`
+
+	config := new(Config)
+	err := json.Unmarshal([]byte(rawJson), &config)
+	assert.Error(err).IsNil()
+	account, err := config.Receiver[0].User[0].GetTypedAccount(&vmess.AccountPB{})
+	assert.Error(err).IsNil()
+	assert.Byte(byte(account.(*vmess.Account).Security)).Equals(byte(protocol.SecurityChaCha20Poly1305))
+
+	err = json.Unmarshal([]byte(`{"vnext": [{"address": "127.0.0.1", "port": 80,
+      "users": [{"id": "e641f5ad-9397-41e3-bf1a-e8740dfed019", "security": "rc4"}]}]}`), new(Config))
+	assert.Error(err).IsNotNil()
+}
@@ -1,13 +1,13 @@
 package outbound
 
 import (
+	"context"
 	"io"
 	"sync"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/common/alloc"
 	v2io "v2ray.com/core/common/io"
-	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/common/retry"
@@ -15,7 +15,6 @@ import (
 	"v2ray.com/core/proxy/registry"
 	"v2ray.com/core/proxy/vmess"
 	"v2ray.com/core/proxy/vmess/encoding"
-	vmessio "v2ray.com/core/proxy/vmess/io"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/ray"
 )
@@ -26,7 +25,7 @@ type VMessOutboundHandler struct {
 	meta         *proxy.OutboundHandlerMeta
 }
 
-func (this *VMessOutboundHandler) Dispatch(target v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
+func (this *VMessOutboundHandler) Dispatch(ctx context.Context, target v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
 	defer ray.OutboundInput().Release()
 	defer ray.OutboundOutput().Close()
 
@@ -35,7 +34,7 @@ func (this *VMessOutboundHandler) Dispatch(target v2net.Destination, payload *al
 
 	err := retry.Timed(5, 100).On(func() error {
 		rec = this.serverPicker.PickServer()
-		rawConn, err := internet.Dial(this.meta.Address, rec.Destination(), this.meta.StreamSettings)
+		rawConn, err := internet.DialContext(ctx, this.meta.Address, rec.Destination(), this.meta.StreamSettings)
 		if err != nil {
 			return err
 		}
@@ -44,10 +43,10 @@ func (this *VMessOutboundHandler) Dispatch(target v2net.Destination, payload *al
 		return nil
 	})
 	if err != nil {
-		log.Error("VMess|Outbound: Failed to find an available destination:", err)
+		this.meta.Logger.Error("VMess|Outbound: Failed to find an available destination:", err)
 		return err
 	}
-	log.Info("VMess|Outbound: Tunneling request to ", target, " via ", rec.Destination())
+	this.meta.Logger.Info("VMess|Outbound: Tunneling request to ", target, " via ", rec.Destination())
 
 	command := protocol.RequestCommandTCP
 	if target.Network == v2net.Network_UDP {
@@ -61,10 +60,15 @@ func (this *VMessOutboundHandler) Dispatch(target v2net.Destination, payload *al
 		Port:    target.Port,
 		Option:  protocol.RequestOptionChunkStream,
 	}
+	if account, err := request.User.GetTypedAccount(&vmess.AccountPB{}); err == nil {
+		request.Security = account.(*vmess.Account).Security
+	}
 
 	defer conn.Close()
 
 	conn.SetReusable(true)
+	// A connection aborted on cancellation is not reused.
+	defer internet.AbortOnDone(ctx, conn)()
 	if conn.Reusable() { // Conn reuse may be disabled on transportation layer
 		request.Option.Set(protocol.RequestOptionConnectionReuse)
 	}
@@ -72,32 +76,24 @@ func (this *VMessOutboundHandler) Dispatch(target v2net.Destination, payload *al
 	input := ray.OutboundInput()
 	output := ray.OutboundOutput()
 
-	var requestFinish, responseFinish sync.Mutex
-	requestFinish.Lock()
-	responseFinish.Lock()
-
 	session := encoding.NewClientSession(protocol.DefaultIDHash)
 
-	go this.handleRequest(session, conn, request, payload, input, &requestFinish)
-	go this.handleResponse(session, conn, request, rec.Destination(), output, &responseFinish)
-
-	requestFinish.Lock()
-	responseFinish.Lock()
-	return nil
+	var finish sync.WaitGroup
+	finish.Add(2)
+	go this.handleRequest(session, conn, request, payload, input, &finish)
+	go this.handleResponse(session, conn, request, rec.Destination(), output, &finish)
+	finish.Wait()
+	return ctx.Err()
 }
 
-func (this *VMessOutboundHandler) handleRequest(session *encoding.ClientSession, conn internet.Connection, request *protocol.RequestHeader, payload *alloc.Buffer, input v2io.Reader, finish *sync.Mutex) {
-	defer finish.Unlock()
+func (this *VMessOutboundHandler) handleRequest(session *encoding.ClientSession, conn internet.Connection, request *protocol.RequestHeader, payload *alloc.Buffer, input v2io.Reader, finish *sync.WaitGroup) {
+	defer finish.Done()
 
 	writer := v2io.NewBufferedWriter(conn)
 	defer writer.Release()
 	session.EncodeRequestHeader(request, writer)
 
-	bodyWriter := session.EncodeRequestBody(writer)
-	var streamWriter v2io.Writer = v2io.NewAdaptiveWriter(bodyWriter)
-	if request.Option.Has(protocol.RequestOptionChunkStream) {
-		streamWriter = vmessio.NewAuthChunkWriter(streamWriter)
-	}
+	streamWriter := session.EncodeRequestBodyStream(request, writer)
 	if !payload.IsEmpty() {
 		if err := streamWriter.Write(payload); err != nil {
 			conn.SetReusable(false)
@@ -120,8 +116,8 @@ func (this *VMessOutboundHandler) handleRequest(session *encoding.ClientSession,
 	return
 }
 
-func (this *VMessOutboundHandler) handleResponse(session *encoding.ClientSession, conn internet.Connection, request *protocol.RequestHeader, dest v2net.Destination, output v2io.Writer, finish *sync.Mutex) {
-	defer finish.Unlock()
+func (this *VMessOutboundHandler) handleResponse(session *encoding.ClientSession, conn internet.Connection, request *protocol.RequestHeader, dest v2net.Destination, output v2io.Writer, finish *sync.WaitGroup) {
+	defer finish.Done()
 
 	reader := v2io.NewBufferedReader(conn)
 	defer reader.Release()
@@ -129,7 +125,7 @@ func (this *VMessOutboundHandler) handleResponse(session *encoding.ClientSession
 	header, err := session.DecodeResponseHeader(reader)
 	if err != nil {
 		conn.SetReusable(false)
-		log.Warning("VMess|Outbound: Failed to read response from ", request.Destination(), ": ", err)
+		this.meta.Logger.Warning("VMess|Outbound: Failed to read response from ", request.Destination(), ": ", err)
 		return
 	}
 	go this.handleCommand(dest, header.Command)
@@ -139,14 +135,7 @@ func (this *VMessOutboundHandler) handleResponse(session *encoding.ClientSession
 	}
 
 	reader.SetCached(false)
-	decryptReader := session.DecodeResponseBody(reader)
-
-	var bodyReader v2io.Reader
-	if request.Option.Has(protocol.RequestOptionChunkStream) {
-		bodyReader = vmessio.NewAuthChunkReader(decryptReader)
-	} else {
-		bodyReader = v2io.NewAdaptiveReader(decryptReader)
-	}
+	bodyReader := session.DecodeResponseBodyStream(request, reader)
 
 	err = v2io.Pipe(bodyReader, output)
 	if err != io.EOF {
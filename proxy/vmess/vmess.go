@@ -6,11 +6,12 @@
 package vmess
 
 import (
+	"errors"
 	"sync"
 	"time"
 
 	"v2ray.com/core/common/protocol"
-	"v2ray.com/core/common/signal"
+	"v2ray.com/core/common/task"
 )
 
 const (
@@ -18,6 +19,10 @@ const (
 	cacheDurationSec  = 120
 )
 
+var (
+	ErrValidatorReleased = errors.New("VMess: User validator is released.")
+)
+
 type idEntry struct {
 	id             *protocol.ID
 	userIdx        int
@@ -32,7 +37,7 @@ type TimedUserValidator struct {
 	userHash   map[[16]byte]*indexTimePair
 	ids        []*idEntry
 	hasher     protocol.IDHash
-	cancel     *signal.CancelSignal
+	refresh    *task.Periodic
 }
 
 type indexTimePair struct {
@@ -47,9 +52,13 @@ func NewTimedUserValidator(hasher protocol.IDHash) protocol.UserValidator {
 		ids:        make([]*idEntry, 0, 512),
 		hasher:     hasher,
 		running:    true,
-		cancel:     signal.NewCloseSignal(),
 	}
-	go tus.updateUserHash(updateIntervalSec * time.Second)
+	tus.refresh = &task.Periodic{
+		Name:     "VMess user hash refresh",
+		Interval: updateIntervalSec * time.Second,
+		Execute:  tus.updateUserHash,
+	}
+	tus.refresh.Start()
 	return tus
 }
 
@@ -58,8 +67,7 @@ func (this *TimedUserValidator) Release() {
 		return
 	}
 
-	this.cancel.Cancel()
-	<-this.cancel.WaitForDone()
+	this.refresh.Close()
 
 	this.Lock()
 	defer this.Unlock()
@@ -73,10 +81,10 @@ func (this *TimedUserValidator) Release() {
 	this.userHash = nil
 	this.ids = nil
 	this.hasher = nil
-	this.cancel = nil
 }
 
-func (this *TimedUserValidator) generateNewHashes(nowSec protocol.Timestamp, idx int, entry *idEntry) {
+// generateNewHashes must be called with the lock held.
+func (this *TimedUserValidator) generateNewHashes(nowSec protocol.Timestamp, entry *idEntry) {
 	var hashValue [16]byte
 	var hashValueRemoval [16]byte
 	idHash := this.hasher(entry.id.Bytes())
@@ -89,65 +97,104 @@ func (this *TimedUserValidator) generateNewHashes(nowSec protocol.Timestamp, idx
 		idHash.Sum(hashValueRemoval[:0])
 		idHash.Reset()
 
-		this.Lock()
-		this.userHash[hashValue] = &indexTimePair{idx, entry.lastSec}
+		this.userHash[hashValue] = &indexTimePair{entry.userIdx, entry.lastSec}
 		delete(this.userHash, hashValueRemoval)
-		this.Unlock()
 
 		entry.lastSec++
 		entry.lastSecRemoval++
 	}
 }
 
-func (this *TimedUserValidator) updateUserHash(interval time.Duration) {
-L:
-	for {
-		select {
-		case now := <-time.After(interval):
-			nowSec := protocol.Timestamp(now.Unix() + cacheDurationSec)
-			for _, entry := range this.ids {
-				this.generateNewHashes(nowSec, entry.userIdx, entry)
-			}
-		case <-this.cancel.WaitForCancel():
-			break L
+func (this *TimedUserValidator) updateUserHash() error {
+	this.RLock()
+	ids := make([]*idEntry, len(this.ids))
+	copy(ids, this.ids)
+	this.RUnlock()
+
+	nowSec := protocol.Timestamp(time.Now().Unix() + cacheDurationSec)
+	for _, entry := range ids {
+		// The lock is taken for each entry, so that Get is not blocked for long. Entries removed in between are
+		// skipped, and the index of the others is read under the lock.
+		this.Lock()
+		if this.running && entry.userIdx >= 0 {
+			this.generateNewHashes(nowSec, entry)
 		}
+		this.Unlock()
 	}
-	this.cancel.Done()
+	return nil
 }
 
 func (this *TimedUserValidator) Add(user *protocol.User) error {
-	idx := len(this.validUsers)
-	this.validUsers = append(this.validUsers, user)
 	rawAccount, err := user.GetTypedAccount(&AccountPB{})
 	if err != nil {
 		return err
 	}
 	account := rawAccount.(*Account)
 
-	nowSec := time.Now().Unix()
+	this.Lock()
+	defer this.Unlock()
 
-	entry := &idEntry{
-		id:             account.ID,
-		userIdx:        idx,
-		lastSec:        protocol.Timestamp(nowSec - cacheDurationSec),
-		lastSecRemoval: protocol.Timestamp(nowSec - cacheDurationSec*3),
+	if !this.running {
+		return ErrValidatorReleased
 	}
-	this.generateNewHashes(protocol.Timestamp(nowSec+cacheDurationSec), idx, entry)
-	this.ids = append(this.ids, entry)
-	for _, alterid := range account.AlterIDs {
+
+	idx := len(this.validUsers)
+	this.validUsers = append(this.validUsers, user)
+
+	nowSec := time.Now().Unix()
+	for _, id := range append([]*protocol.ID{account.ID}, account.AlterIDs...) {
 		entry := &idEntry{
-			id:             alterid,
+			id:             id,
 			userIdx:        idx,
 			lastSec:        protocol.Timestamp(nowSec - cacheDurationSec),
 			lastSecRemoval: protocol.Timestamp(nowSec - cacheDurationSec*3),
 		}
-		this.generateNewHashes(protocol.Timestamp(nowSec+cacheDurationSec), idx, entry)
+		this.generateNewHashes(protocol.Timestamp(nowSec+cacheDurationSec), entry)
 		this.ids = append(this.ids, entry)
 	}
 
 	return nil
 }
 
+func (this *TimedUserValidator) Remove(email string) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	idx := -1
+	for i, user := range this.validUsers {
+		if user.Email == email {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return false
+	}
+	this.validUsers = append(this.validUsers[:idx], this.validUsers[idx+1:]...)
+
+	ids := make([]*idEntry, 0, len(this.ids))
+	for _, entry := range this.ids {
+		if entry.userIdx == idx {
+			entry.userIdx = -1
+			continue
+		}
+		if entry.userIdx > idx {
+			entry.userIdx--
+		}
+		ids = append(ids, entry)
+	}
+	this.ids = ids
+
+	for hash, pair := range this.userHash {
+		if pair.index == idx {
+			delete(this.userHash, hash)
+		} else if pair.index > idx {
+			pair.index--
+		}
+	}
+	return true
+}
+
 func (this *TimedUserValidator) Get(userHash []byte) (*protocol.User, protocol.Timestamp, bool) {
 	defer this.RUnlock()
 	this.RLock()
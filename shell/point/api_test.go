@@ -0,0 +1,141 @@
+// +build json
+
+package point_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/api"
+	_ "v2ray.com/core/proxy/vmess/inbound"
+	. "v2ray.com/core/shell/point"
+	"v2ray.com/core/testing/assert"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestHandlerAPI(t *testing.T) {
+	assert := assert.On(t)
+
+	port := pickPort()
+	apiPort := pickPort()
+	rawConfig := fmt.Sprintf(`{
+    "inbound": {
+      "port": %d,
+      "listen": "127.0.0.1",
+      "protocol": "dokodemo-door",
+      "settings": {"address": "127.0.0.1", "port": 53, "network": "tcp"}
+    },
+    "outbound": {
+      "protocol": "freedom",
+      "settings": {}
+    },
+    "api": {"port": %d},
+    "stats": {"users": true}
+  }`, port, apiPort)
+	config := new(Config)
+	assert.Error(json.Unmarshal([]byte(rawConfig), config)).IsNil()
+	vPoint, err := NewPoint(config)
+	assert.Error(err).IsNil()
+	assert.Error(vPoint.Start()).IsNil()
+	defer vPoint.Close()
+
+	client := api.NewClient(fmt.Sprintf("127.0.0.1:%d", apiPort), 5*time.Second)
+	call := func(name string, request proto.Message, response proto.Message) error {
+		return client.Call(api.HandlerServiceName, name, request, response)
+	}
+
+	inboundPort := pickPort()
+	inbound := fmt.Sprintf(`{
+    "port": %d,
+    "listen": "127.0.0.1",
+    "protocol": "vmess",
+    "settings": {"clients": [{"id": "a3482e88-686a-4a58-8126-99c9df64b7bf", "email": "love@v2ray.com"}]},
+    "tag": "vmess"
+  }`, inboundPort)
+	assert.Error(call("AddInbound", &api.AddInboundRequest{Inbound: []byte(inbound)}, new(api.AddInboundResponse))).IsNil()
+	assert.Bool(canDial(inboundPort)).IsTrue()
+	handler, _ := vPoint.GetHandler("vmess")
+	assert.Pointer(handler).IsNotNil()
+
+	err = call("AddInbound", &api.AddInboundRequest{Inbound: []byte(inbound)}, new(api.AddInboundResponse))
+	assert.Error(err).IsNotNil()
+	assert.Int(err.(*api.Status).Code).Equals(6)
+
+	assert.Error(call("AlterInbound", &api.AlterInboundRequest{
+		Tag:      "vmess",
+		AddUsers: [][]byte{[]byte(`{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "email": "test@v2ray.com"}`)},
+	}, new(api.AlterInboundResponse))).IsNil()
+	assert.Error(call("AlterInbound", &api.AlterInboundRequest{
+		Tag:          "vmess",
+		RemoveEmails: []string{"test@v2ray.com"},
+	}, new(api.AlterInboundResponse))).IsNil()
+	err = call("AlterInbound", &api.AlterInboundRequest{
+		Tag:          "vmess",
+		RemoveEmails: []string{"test@v2ray.com"},
+	}, new(api.AlterInboundResponse))
+	assert.Error(err).IsNotNil()
+	assert.Int(err.(*api.Status).Code).Equals(5)
+
+	assert.Error(call("RemoveInbound", &api.RemoveInboundRequest{Tag: "vmess"}, new(api.RemoveInboundResponse))).IsNil()
+	assert.Bool(canDial(inboundPort)).IsFalse()
+
+	outbound := `{"protocol": "freedom", "settings": {}, "tag": "direct"}`
+	assert.Error(call("AddOutbound", &api.AddOutboundRequest{Outbound: []byte(outbound)}, new(api.AddOutboundResponse))).IsNil()
+	assert.Error(call("RemoveOutbound", &api.RemoveOutboundRequest{Tag: "direct"}, new(api.RemoveOutboundResponse))).IsNil()
+	err = call("RemoveOutbound", &api.RemoveOutboundRequest{Tag: "direct"}, new(api.RemoveOutboundResponse))
+	assert.Error(err).IsNotNil()
+	assert.Int(err.(*api.Status).Code).Equals(5)
+
+	err = client.Call(api.StatsServiceName, "GetStats", &api.GetStatsRequest{Name: "user>>>love@v2ray.com>>>traffic>>>uplink"}, new(api.GetStatsResponse))
+	assert.Error(err).IsNotNil()
+	assert.Int(err.(*api.Status).Code).Equals(5)
+	stats := new(api.QueryStatsResponse)
+	assert.Error(client.Call(api.StatsServiceName, "QueryStats", &api.QueryStatsRequest{}, stats)).IsNil()
+	assert.Int(len(stats.Stat)).Equals(0)
+}
+
+func TestVersionAPI(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	port := pickPort()
+	apiPort := pickPort()
+	file := filepath.Join(dir, "config.json")
+	assert.Error(ioutil.WriteFile(file, []byte(fmt.Sprintf(`{
+    "inbound": {
+      "port": %d,
+      "listen": "127.0.0.1",
+      "protocol": "dokodemo-door",
+      "settings": {"address": "127.0.0.1", "port": 53, "network": "tcp"}
+    },
+    "outbound": {"protocol": "freedom", "settings": {}},
+    "api": {"port": %d}
+  }`, port, apiPort)), 0600)).IsNil()
+	config, err := LoadConfig(file)
+	assert.Error(err).IsNil()
+	assert.Int(len(config.Hash)).Equals(64)
+
+	vPoint, err := NewPoint(config)
+	assert.Error(err).IsNil()
+	assert.Error(vPoint.Start()).IsNil()
+	defer vPoint.Close()
+
+	client := api.NewClient(fmt.Sprintf("127.0.0.1:%d", apiPort), 5*time.Second)
+	response := new(api.GetVersionResponse)
+	assert.Error(client.Call(api.VersionServiceName, "GetVersion", new(api.GetVersionRequest), response)).IsNil()
+	assert.String(response.Version).Equals(core.Version())
+	assert.String(response.ConfigHash).Equals(config.Hash)
+	assert.Int64(response.StartTime).Equals(vPoint.StartTime().Unix())
+	assert.String(fmt.Sprint(response.Tags)).Contains("json")
+	assert.String(fmt.Sprint(response.Features)).Contains("inbound:dokodemo-door")
+}
@@ -0,0 +1,48 @@
+package point
+
+import (
+	"crypto/x509"
+
+	"v2ray.com/core/transport/internet"
+)
+
+// certificates returns the TLS certificates of all inbounds and outbounds in the config, without duplicates.
+func certificates(config *Config) []*x509.Certificate {
+	settings := make([]*internet.StreamSettings, 0, 2+len(config.InboundDetours)+len(config.OutboundDetours))
+	if config.InboundConfig != nil {
+		settings = append(settings, config.InboundConfig.StreamSettings)
+	}
+	if config.OutboundConfig != nil {
+		settings = append(settings, config.OutboundConfig.StreamSettings)
+	}
+	for _, detour := range config.InboundDetours {
+		settings = append(settings, detour.StreamSettings)
+	}
+	for _, detour := range config.OutboundDetours {
+		settings = append(settings, detour.StreamSettings)
+	}
+
+	certs := make([]*x509.Certificate, 0)
+	seen := make(map[string]bool)
+	for _, s := range settings {
+		if s == nil || s.TLSSettings == nil {
+			continue
+		}
+		for _, cert := range s.TLSSettings.Certs {
+			leaf := cert.Leaf
+			if leaf == nil && len(cert.Certificate) > 0 {
+				parsed, err := x509.ParseCertificate(cert.Certificate[0])
+				if err != nil {
+					continue
+				}
+				leaf = parsed
+			}
+			if leaf == nil || seen[string(leaf.Raw)] {
+				continue
+			}
+			seen[string(leaf.Raw)] = true
+			certs = append(certs, leaf)
+		}
+	}
+	return certs
+}
@@ -1,11 +1,27 @@
 package point
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/app/debug"
 	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/events"
+	"v2ray.com/core/app/health"
+	"v2ray.com/core/app/metrics"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/app/router"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/app/throttle"
+	"v2ray.com/core/app/tracing"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/transport"
 	"v2ray.com/core/transport/internet"
 )
@@ -17,6 +33,11 @@ type InboundConnectionConfig struct {
 	Protocol               string
 	Settings               []byte
 	AllowPassiveConnection bool
+	DefaultOutboundTag     string
+	// SourceFilter restricts the sources of connections. Nil allows all.
+	SourceFilter *v2net.IPFilter
+	// ProxySettings are the decoded settings of the protocol, e.g. from a protobuf config. Settings are ignored if set.
+	ProxySettings interface{}
 }
 
 type OutboundConnectionConfig struct {
@@ -24,14 +45,83 @@ type OutboundConnectionConfig struct {
 	SendThrough    v2net.Address
 	StreamSettings *internet.StreamSettings
 	Settings       []byte
+	// ProxySettings are the decoded settings of the protocol, e.g. from a protobuf config. Settings are ignored if set.
+	ProxySettings interface{}
 }
 
 type LogConfig struct {
 	AccessLog string
 	ErrorLog  string
 	LogLevel  log.LogLevel
+	// ModuleLevels are the levels of modules that override LogLevel, e.g. "Router" and "KCP".
+	ModuleLevels map[string]log.LogLevel
+	// AccessHostnames enables logging the hostnames of IP destinations in access logs, looked up by the DNS app.
+	AccessHostnames bool
+	// AccessFormat is the format of access logs, either AccessFormatText or AccessFormatJSON.
+	AccessFormat string
+	// Rotation is the rotation of access and error log files. Log files are not rotated if nil.
+	Rotation *log.Rotation
+	// Syslog sends error logs to syslog instead of ErrorLog if not nil.
+	Syslog *SyslogConfig
+	// EventLog sends error logs to the Windows Event Log instead of ErrorLog if not nil.
+	EventLog *EventLogConfig
+}
+
+type SyslogConfig struct {
+	// Network is "udp" or "tcp" for remote syslog, or empty for the local one.
+	Network  string
+	Address  string
+	Tag      string
+	Facility int
+	// Access enables sending access logs to syslog as well.
+	Access bool
+}
+
+type EventLogConfig struct {
+	// Source is the event source, which is registered by installing the service.
+	Source string
+	// Access enables sending access logs to the event log as well.
+	Access bool
+}
+
+const (
+	AccessFormatText = "text"
+	AccessFormatJSON = "json"
+)
+
+// NewLogger returns a logger of the instance that writes access logs and error logs as configured. Error logs go to the
+// destination of the base logger if none is configured.
+func (this *LogConfig) NewLogger() (*log.Logger, error) {
+	logger := log.NewLoggerFrom(log.Base())
+	logger.SetRotation(this.Rotation)
+	if len(this.AccessLog) > 0 {
+		initAccessLogger := logger.InitAccessLogger
+		if this.AccessFormat == AccessFormatJSON {
+			initAccessLogger = logger.InitJSONAccessLogger
+		}
+		if err := initAccessLogger(this.AccessLog); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.ErrorLog) > 0 {
+		if err := logger.InitErrorLogger(this.ErrorLog); err != nil {
+			logger.Close()
+			return nil, err
+		}
+	}
+	if err := this.initSystemLogger(logger); err != nil {
+		logger.Close()
+		return nil, err
+	}
+	logger.SetLogLevel(this.LogLevel)
+	logger.SetModuleLogLevels(this.ModuleLevels)
+	return logger, nil
 }
 
+const (
+	DefaultRefreshMinute = int(9999)
+)
+
 const (
 	AllocationStrategyAlways   = "always"
 	AllocationStrategyRandom   = "random"
@@ -53,6 +143,11 @@ type InboundDetourConfig struct {
 	StreamSettings         *internet.StreamSettings
 	Settings               []byte
 	AllowPassiveConnection bool
+	DefaultOutboundTag     string
+	// SourceFilter restricts the sources of connections. Nil allows all.
+	SourceFilter *v2net.IPFilter
+	// ProxySettings are the decoded settings of the protocol, e.g. from a protobuf config. Settings are ignored if set.
+	ProxySettings interface{}
 }
 
 type OutboundDetourConfig struct {
@@ -61,6 +156,8 @@ type OutboundDetourConfig struct {
 	StreamSettings *internet.StreamSettings
 	Tag            string
 	Settings       []byte
+	// ProxySettings are the decoded settings of the protocol, e.g. from a protobuf config. Settings are ignored if set.
+	ProxySettings interface{}
 }
 
 type Config struct {
@@ -73,17 +170,259 @@ type Config struct {
 	InboundDetours  []*InboundDetourConfig
 	OutboundDetours []*OutboundDetourConfig
 	TransportConfig *transport.Config
+	ThrottleConfig  *throttle.Config
+	ApiConfig       *api.Config
+	StatsConfig     *stats.Config
+	MetricsConfig   *metrics.Config
+	DebugConfig     *debug.Config
+	EventsConfig    *events.Config
+	TracingConfig   *tracing.Config
+	HealthConfig    *health.Config
+	PolicyConfig    *policy.Config
+	// Apps are configs of other apps registered as features, e.g. by programs embedding V2Ray.
+	Apps []interface{}
+	// Hash is the SHA-256 in hex of the effective config, which is the same for configs of the same content.
+	Hash string
 }
 
-type ConfigLoader func(init string) (*Config, error)
+// defaultDNSConfig returns the DNS config if none is set, which queries the name server on localhost.
+func defaultDNSConfig() *dns.Config {
+	return &dns.Config{
+		NameServers: []*v2net.DestinationPB{{
+			Network: v2net.Network_UDP,
+			Address: &v2net.AddressPB{
+				Address: &v2net.AddressPB_Domain{
+					Domain: "localhost",
+				},
+			},
+			Port: 53,
+		}},
+	}
+}
+
+// appConfigs returns the configs of all apps in the config.
+func (this *Config) appConfigs() []interface{} {
+	configs := make([]interface{}, 0, 12+len(this.Apps))
+	if this.DNSConfig != nil {
+		configs = append(configs, this.DNSConfig)
+	}
+	if this.RouterConfig != nil {
+		configs = append(configs, this.RouterConfig)
+	}
+	if this.PolicyConfig != nil {
+		configs = append(configs, this.PolicyConfig)
+	}
+	if this.ThrottleConfig != nil {
+		configs = append(configs, this.ThrottleConfig)
+	}
+	if this.StatsConfig != nil {
+		configs = append(configs, this.StatsConfig)
+	}
+	if this.TracingConfig != nil {
+		configs = append(configs, this.TracingConfig)
+	}
+	if this.DebugConfig != nil {
+		configs = append(configs, this.DebugConfig)
+	}
+	if this.EventsConfig != nil {
+		configs = append(configs, this.EventsConfig)
+	}
+	if this.ApiConfig != nil {
+		configs = append(configs, this.ApiConfig)
+	}
+	if this.MetricsConfig != nil {
+		configs = append(configs, this.MetricsConfig)
+	}
+	if this.HealthConfig != nil {
+		configs = append(configs, this.HealthConfig)
+	}
+	return append(configs, this.Apps...)
+}
+
+// ConfigDecoder decodes a config file into a document of JSON values, i.e. map[string]interface{}, []interface{} and
+// scalars, which is then parsed the same way as a JSON config.
+type ConfigDecoder func(data []byte) (interface{}, error)
+
+type configFormat struct {
+	name       string
+	extensions []string
+	decoder    ConfigDecoder
+}
+
+var (
+	configFormats      = make(map[string]*configFormat)
+	forcedConfigFormat *configFormat
+)
+
+// RegisterConfigFormat registers the decoder of a config format, e.g. "yaml", for files with the given extensions.
+func RegisterConfigFormat(name string, extensions []string, decoder ConfigDecoder) error {
+	if _, found := configFormats[name]; found {
+		return common.ErrDuplicatedName
+	}
+	configFormats[name] = &configFormat{
+		name:       name,
+		extensions: extensions,
+		decoder:    decoder,
+	}
+	core.RegisterFeature("config:" + name)
+	return nil
+}
+
+// SetConfigFormat sets the format of all config files. The format of each file is detected by its extension if name
+// is empty or "auto".
+func SetConfigFormat(name string) error {
+	if len(name) == 0 || name == "auto" {
+		forcedConfigFormat = nil
+		return nil
+	}
+	format, found := configFormats[name]
+	if !found {
+		log.Error("Point: Unknown config format: ", name)
+		return common.ErrObjectNotFound
+	}
+	forcedConfigFormat = format
+	return nil
+}
+
+// configFormatOf returns the format of a config file, or nil if its extension is unknown.
+func configFormatOf(file string) *configFormat {
+	if forcedConfigFormat != nil {
+		return forcedConfigFormat
+	}
+	ext := strings.ToLower(filepath.Ext(file))
+	for _, format := range configFormats {
+		for _, formatExt := range format.extensions {
+			if ext == formatExt {
+				return format
+			}
+		}
+	}
+	return nil
+}
+
+// expandConfigFiles replaces directories in files with the config files in them, in alphabetical order. Files in
+// directories are included if their formats are known.
+func expandConfigFiles(files []string) ([]string, error) {
+	expanded := make([]string, 0, len(files))
+	for _, file := range files {
+		file = os.ExpandEnv(file)
+		if isRemoteConfig(file) {
+			expanded = append(expanded, file)
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			expanded = append(expanded, file)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(file, "*"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() && configFormatOf(match) != nil {
+				expanded = append(expanded, match)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// isConfigPB returns whether all config files are in protobuf, which are loaded by LoadConfigPB instead of
+// configLoader.
+func isConfigPB(files []string) bool {
+	expanded, err := expandConfigFiles(files)
+	if err != nil || len(expanded) == 0 {
+		return false
+	}
+	for _, file := range expanded {
+		if format := configFormatOf(file); format == nil || format.name != ConfigFormatPB {
+			return false
+		}
+	}
+	return true
+}
+
+// ConfigLoader loads a Config from one or more files. Later files override earlier ones.
+type ConfigLoader func(files ...string) (*Config, error)
 
 var (
 	configLoader ConfigLoader
+	configDumper func(files ...string) ([]byte, error)
+	// configPreflighter loads config files, and returns problems found in files referenced by them together with the
+	// ones found by Preflight.
+	configPreflighter  func(files ...string) (*Config, []error)
+	configMigrator     func(file string) ([]byte, []string, error)
+	configLinkImporter func(links ...string) ([]byte, []string, error)
+	configLinkExporter func(address string, files ...string) ([]string, error)
+	// inboundDetourDecoder and outboundDetourDecoder decode the configs of inbounds and outbounds added by the API.
+	inboundDetourDecoder  func(data []byte) (*InboundDetourConfig, error)
+	outboundDetourDecoder func(data []byte) (*OutboundDetourConfig, error)
+	// inboundUserDecoder decodes users of an inbound protocol in the JSON format of users in inbound settings.
+	inboundUserDecoder func(protocolName string, users [][]byte) ([]*protocol.User, error)
 )
 
-func LoadConfig(init string) (*Config, error) {
+func LoadConfig(files ...string) (*Config, error) {
+	if isConfigPB(files) {
+		return LoadConfigPB(files...)
+	}
 	if configLoader == nil {
 		return nil, common.ErrBadConfiguration
 	}
-	return configLoader(init)
+	return configLoader(files...)
+}
+
+// DumpConfig loads config files as LoadConfig does, and returns the effective config in canonical JSON.
+func DumpConfig(files ...string) ([]byte, error) {
+	if configDumper == nil {
+		return nil, common.ErrBadConfiguration
+	}
+	return configDumper(files...)
+}
+
+// MigrateConfig rewrites the fields of a config file in forms of older releases, e.g. deprecated field names, and
+// returns the updated config in JSON with explanations of each change. The updated config is validated.
+func MigrateConfig(file string) ([]byte, []string, error) {
+	if configMigrator == nil {
+		return nil, nil, common.ErrBadConfiguration
+	}
+	return configMigrator(file)
+}
+
+// ImportLinks converts share links, e.g. vmess:// links, into a config of outbound detours in JSON, which can be merged
+// with other config files. Settings in the links that can't be set on outbounds are explained in warnings.
+func ImportLinks(links ...string) ([]byte, []string, error) {
+	if configLinkImporter == nil {
+		return nil, nil, common.ErrBadConfiguration
+	}
+	return configLinkImporter(links...)
+}
+
+// ExportLinks returns the share links of outbounds in config files. If address is not empty, links of inbounds are
+// returned as well, for clients to connect to them at the address.
+func ExportLinks(address string, files ...string) ([]string, error) {
+	if configLinkExporter == nil {
+		return nil, common.ErrBadConfiguration
+	}
+	return configLinkExporter(address, files...)
+}
+
+// PreflightConfig loads config files as LoadConfig does, and checks whether the server can be brought up with them,
+// e.g. certificate files are readable and ports are bindable. All problems found are returned at once.
+func PreflightConfig(files ...string) (*Config, []error) {
+	if configPreflighter != nil && !isConfigPB(files) {
+		return configPreflighter(files...)
+	}
+	config, err := LoadConfig(files...)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if errs := Preflight(config); len(errs) > 0 {
+		return nil, errs
+	}
+	return config, nil
 }
@@ -0,0 +1,1151 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/shell/point/config.proto
+// DO NOT EDIT!
+
+/*
+Package point is a generated protocol buffer package.
+
+It is generated from these files:
+
+	v2ray.com/core/shell/point/config.proto
+
+It has these top-level messages:
+
+	LogRotationConfigPB
+	LogSyslogConfigPB
+	LogEventLogConfigPB
+	LogConfigPB
+	CertificatePB
+	ACMEConfigPB
+	TLSConfigPB
+	RealityConfigPB
+	PluginConfigPB
+	StreamConfigPB
+	InboundConfigPB
+	OutboundConfigPB
+	AllocationConfigPB
+	InboundDetourConfigPB
+	OutboundDetourConfigPB
+	RoutingConfigPB
+	TCPConfigPB
+	WebSocketConfigPB
+	TransportConfigPB
+	ThrottleLimitPB
+	ThrottleConfigPB
+	PolicyPB
+	BanConfigPB
+	PolicyConfigPB
+	ApiConfigPB
+	StatsConfigPB
+	MetricsConfigPB
+	DebugConfigPB
+	HealthConfigPB
+	TracingConfigPB
+	EventsWebhookConfigPB
+	EventsExecConfigPB
+	EventsConfigPB
+	ConfigPB
+*/
+package point
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import google_protobuf "github.com/golang/protobuf/ptypes/any"
+import v2ray_core_app_dns "v2ray.com/core/app/dns"
+import v2ray_core_common_net "v2ray.com/core/common/net"
+import v2ray_core_common_net1 "v2ray.com/core/common/net"
+import v2ray_core_common_net3 "v2ray.com/core/common/net"
+import v2ray_core_transport_internet_kcp "v2ray.com/core/transport/internet/kcp"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Size is in MB and age is in hours.
+type LogRotationConfigPB struct {
+	MaxSize    uint32 `protobuf:"varint,1,opt,name=max_size,json=maxSize" json:"max_size,omitempty"`
+	MaxAge     uint32 `protobuf:"varint,2,opt,name=max_age,json=maxAge" json:"max_age,omitempty"`
+	MaxBackups uint32 `protobuf:"varint,3,opt,name=max_backups,json=maxBackups" json:"max_backups,omitempty"`
+	Compress   bool   `protobuf:"varint,4,opt,name=compress" json:"compress,omitempty"`
+}
+
+func (m *LogRotationConfigPB) Reset()                    { *m = LogRotationConfigPB{} }
+func (m *LogRotationConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*LogRotationConfigPB) ProtoMessage()               {}
+func (*LogRotationConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+// Address is empty for the local syslog.
+type LogSyslogConfigPB struct {
+	Address  string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Tag      string `protobuf:"bytes,2,opt,name=tag" json:"tag,omitempty"`
+	Facility string `protobuf:"bytes,3,opt,name=facility" json:"facility,omitempty"`
+	Access   bool   `protobuf:"varint,4,opt,name=access" json:"access,omitempty"`
+}
+
+func (m *LogSyslogConfigPB) Reset()                    { *m = LogSyslogConfigPB{} }
+func (m *LogSyslogConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*LogSyslogConfigPB) ProtoMessage()               {}
+func (*LogSyslogConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type LogEventLogConfigPB struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Access bool   `protobuf:"varint,2,opt,name=access" json:"access,omitempty"`
+}
+
+func (m *LogEventLogConfigPB) Reset()                    { *m = LogEventLogConfigPB{} }
+func (m *LogEventLogConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*LogEventLogConfigPB) ProtoMessage()               {}
+func (*LogEventLogConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type LogConfigPB struct {
+	Access          string               `protobuf:"bytes,1,opt,name=access" json:"access,omitempty"`
+	Error           string               `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+	Level           string               `protobuf:"bytes,3,opt,name=level" json:"level,omitempty"`
+	AccessHostnames bool                 `protobuf:"varint,4,opt,name=access_hostnames,json=accessHostnames" json:"access_hostnames,omitempty"`
+	AccessFormat    string               `protobuf:"bytes,5,opt,name=access_format,json=accessFormat" json:"access_format,omitempty"`
+	Rotation        *LogRotationConfigPB `protobuf:"bytes,6,opt,name=rotation" json:"rotation,omitempty"`
+	Syslog          *LogSyslogConfigPB   `protobuf:"bytes,7,opt,name=syslog" json:"syslog,omitempty"`
+	EventLog        *LogEventLogConfigPB `protobuf:"bytes,8,opt,name=event_log,json=eventLog" json:"event_log,omitempty"`
+	// Levels of modules by their names.
+	Levels map[string]string `protobuf:"bytes,9,rep,name=levels" json:"levels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *LogConfigPB) Reset()                    { *m = LogConfigPB{} }
+func (m *LogConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*LogConfigPB) ProtoMessage()               {}
+func (*LogConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *LogConfigPB) GetRotation() *LogRotationConfigPB {
+	if m != nil {
+		return m.Rotation
+	}
+	return nil
+}
+
+func (m *LogConfigPB) GetSyslog() *LogSyslogConfigPB {
+	if m != nil {
+		return m.Syslog
+	}
+	return nil
+}
+
+func (m *LogConfigPB) GetEventLog() *LogEventLogConfigPB {
+	if m != nil {
+		return m.EventLog
+	}
+	return nil
+}
+
+func (m *LogConfigPB) GetLevels() map[string]string {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+type CertificatePB struct {
+	CertificateFile string `protobuf:"bytes,1,opt,name=certificate_file,json=certificateFile" json:"certificate_file,omitempty"`
+	KeyFile         string `protobuf:"bytes,2,opt,name=key_file,json=keyFile" json:"key_file,omitempty"`
+}
+
+func (m *CertificatePB) Reset()                    { *m = CertificatePB{} }
+func (m *CertificatePB) String() string            { return proto.CompactTextString(m) }
+func (*CertificatePB) ProtoMessage()               {}
+func (*CertificatePB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type ACMEConfigPB struct {
+	Domains      []string `protobuf:"bytes,1,rep,name=domains" json:"domains,omitempty"`
+	Email        string   `protobuf:"bytes,2,opt,name=email" json:"email,omitempty"`
+	CacheDir     string   `protobuf:"bytes,3,opt,name=cache_dir,json=cacheDir" json:"cache_dir,omitempty"`
+	DirectoryUrl string   `protobuf:"bytes,4,opt,name=directory_url,json=directoryUrl" json:"directory_url,omitempty"`
+	HttpAddress  string   `protobuf:"bytes,5,opt,name=http_address,json=httpAddress" json:"http_address,omitempty"`
+}
+
+func (m *ACMEConfigPB) Reset()                    { *m = ACMEConfigPB{} }
+func (m *ACMEConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*ACMEConfigPB) ProtoMessage()               {}
+func (*ACMEConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type TLSConfigPB struct {
+	AllowInsecure            bool             `protobuf:"varint,1,opt,name=allow_insecure,json=allowInsecure" json:"allow_insecure,omitempty"`
+	Certificates             []*CertificatePB `protobuf:"bytes,2,rep,name=certificates" json:"certificates,omitempty"`
+	DisableSessionResumption bool             `protobuf:"varint,3,opt,name=disable_session_resumption,json=disableSessionResumption" json:"disable_session_resumption,omitempty"`
+	SessionCacheSize         int32            `protobuf:"varint,4,opt,name=session_cache_size,json=sessionCacheSize" json:"session_cache_size,omitempty"`
+	// Versions are e.g. "1.2". Empty means the default of crypto/tls.
+	MinVersion    string            `protobuf:"bytes,5,opt,name=min_version,json=minVersion" json:"min_version,omitempty"`
+	MaxVersion    string            `protobuf:"bytes,6,opt,name=max_version,json=maxVersion" json:"max_version,omitempty"`
+	EchConfigList []byte            `protobuf:"bytes,7,opt,name=ech_config_list,json=echConfigList,proto3" json:"ech_config_list,omitempty"`
+	ClientCaFiles []string          `protobuf:"bytes,8,rep,name=client_ca_files,json=clientCaFiles" json:"client_ca_files,omitempty"`
+	ClientEmails  map[string]string `protobuf:"bytes,9,rep,name=client_emails,json=clientEmails" json:"client_emails,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Acme          *ACMEConfigPB     `protobuf:"bytes,10,opt,name=acme" json:"acme,omitempty"`
+}
+
+func (m *TLSConfigPB) Reset()                    { *m = TLSConfigPB{} }
+func (m *TLSConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*TLSConfigPB) ProtoMessage()               {}
+func (*TLSConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *TLSConfigPB) GetCertificates() []*CertificatePB {
+	if m != nil {
+		return m.Certificates
+	}
+	return nil
+}
+
+func (m *TLSConfigPB) GetClientEmails() map[string]string {
+	if m != nil {
+		return m.ClientEmails
+	}
+	return nil
+}
+
+func (m *TLSConfigPB) GetAcme() *ACMEConfigPB {
+	if m != nil {
+		return m.Acme
+	}
+	return nil
+}
+
+type RealityConfigPB struct {
+	// Server side.
+	Dest        string   `protobuf:"bytes,1,opt,name=dest" json:"dest,omitempty"`
+	ServerNames []string `protobuf:"bytes,2,rep,name=server_names,json=serverNames" json:"server_names,omitempty"`
+	PrivateKey  []byte   `protobuf:"bytes,3,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	ShortIds    [][]byte `protobuf:"bytes,4,rep,name=short_ids,json=shortIds,proto3" json:"short_ids,omitempty"`
+	// In seconds.
+	MaxTimeDiff uint32 `protobuf:"varint,5,opt,name=max_time_diff,json=maxTimeDiff" json:"max_time_diff,omitempty"`
+	// Client side.
+	ServerName string `protobuf:"bytes,6,opt,name=server_name,json=serverName" json:"server_name,omitempty"`
+	PublicKey  []byte `protobuf:"bytes,7,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	ShortId    []byte `protobuf:"bytes,8,opt,name=short_id,json=shortId,proto3" json:"short_id,omitempty"`
+}
+
+func (m *RealityConfigPB) Reset()                    { *m = RealityConfigPB{} }
+func (m *RealityConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*RealityConfigPB) ProtoMessage()               {}
+func (*RealityConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+type PluginConfigPB struct {
+	Address string   `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Command string   `protobuf:"bytes,2,opt,name=command" json:"command,omitempty"`
+	Args    []string `protobuf:"bytes,3,rep,name=args" json:"args,omitempty"`
+	// In seconds.
+	StartTimeout uint32 `protobuf:"varint,4,opt,name=start_timeout,json=startTimeout" json:"start_timeout,omitempty"`
+	Options      string `protobuf:"bytes,5,opt,name=options" json:"options,omitempty"`
+}
+
+func (m *PluginConfigPB) Reset()                    { *m = PluginConfigPB{} }
+func (m *PluginConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*PluginConfigPB) ProtoMessage()               {}
+func (*PluginConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+type StreamConfigPB struct {
+	Network  *v2ray_core_common_net1.NetworkList `protobuf:"bytes,1,opt,name=network" json:"network,omitempty"`
+	Security string                              `protobuf:"bytes,2,opt,name=security" json:"security,omitempty"`
+	Tls      *TLSConfigPB                        `protobuf:"bytes,3,opt,name=tls" json:"tls,omitempty"`
+	Reality  *RealityConfigPB                    `protobuf:"bytes,4,opt,name=reality" json:"reality,omitempty"`
+	Custom   string                              `protobuf:"bytes,5,opt,name=custom" json:"custom,omitempty"`
+	Plugin   *PluginConfigPB                     `protobuf:"bytes,6,opt,name=plugin" json:"plugin,omitempty"`
+	Resolver string                              `protobuf:"bytes,7,opt,name=resolver" json:"resolver,omitempty"`
+}
+
+func (m *StreamConfigPB) Reset()                    { *m = StreamConfigPB{} }
+func (m *StreamConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*StreamConfigPB) ProtoMessage()               {}
+func (*StreamConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *StreamConfigPB) GetNetwork() *v2ray_core_common_net1.NetworkList {
+	if m != nil {
+		return m.Network
+	}
+	return nil
+}
+
+func (m *StreamConfigPB) GetTls() *TLSConfigPB {
+	if m != nil {
+		return m.Tls
+	}
+	return nil
+}
+
+func (m *StreamConfigPB) GetReality() *RealityConfigPB {
+	if m != nil {
+		return m.Reality
+	}
+	return nil
+}
+
+func (m *StreamConfigPB) GetPlugin() *PluginConfigPB {
+	if m != nil {
+		return m.Plugin
+	}
+	return nil
+}
+
+type InboundConfigPB struct {
+	Port               uint32                           `protobuf:"varint,1,opt,name=port" json:"port,omitempty"`
+	Listen             *v2ray_core_common_net.AddressPB `protobuf:"bytes,2,opt,name=listen" json:"listen,omitempty"`
+	Protocol           string                           `protobuf:"bytes,3,opt,name=protocol" json:"protocol,omitempty"`
+	Settings           *google_protobuf.Any             `protobuf:"bytes,4,opt,name=settings" json:"settings,omitempty"`
+	StreamSettings     *StreamConfigPB                  `protobuf:"bytes,5,opt,name=stream_settings,json=streamSettings" json:"stream_settings,omitempty"`
+	AllowPassive       bool                             `protobuf:"varint,6,opt,name=allow_passive,json=allowPassive" json:"allow_passive,omitempty"`
+	DefaultOutboundTag string                           `protobuf:"bytes,7,opt,name=default_outbound_tag,json=defaultOutboundTag" json:"default_outbound_tag,omitempty"`
+	AllowSources       []string                         `protobuf:"bytes,8,rep,name=allow_sources,json=allowSources" json:"allow_sources,omitempty"`
+	DenySources        []string                         `protobuf:"bytes,9,rep,name=deny_sources,json=denySources" json:"deny_sources,omitempty"`
+}
+
+func (m *InboundConfigPB) Reset()                    { *m = InboundConfigPB{} }
+func (m *InboundConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*InboundConfigPB) ProtoMessage()               {}
+func (*InboundConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *InboundConfigPB) GetListen() *v2ray_core_common_net.AddressPB {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+func (m *InboundConfigPB) GetSettings() *google_protobuf.Any {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+func (m *InboundConfigPB) GetStreamSettings() *StreamConfigPB {
+	if m != nil {
+		return m.StreamSettings
+	}
+	return nil
+}
+
+type OutboundConfigPB struct {
+	Protocol       string                           `protobuf:"bytes,1,opt,name=protocol" json:"protocol,omitempty"`
+	SendThrough    *v2ray_core_common_net.AddressPB `protobuf:"bytes,2,opt,name=send_through,json=sendThrough" json:"send_through,omitempty"`
+	Settings       *google_protobuf.Any             `protobuf:"bytes,3,opt,name=settings" json:"settings,omitempty"`
+	StreamSettings *StreamConfigPB                  `protobuf:"bytes,4,opt,name=stream_settings,json=streamSettings" json:"stream_settings,omitempty"`
+}
+
+func (m *OutboundConfigPB) Reset()                    { *m = OutboundConfigPB{} }
+func (m *OutboundConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*OutboundConfigPB) ProtoMessage()               {}
+func (*OutboundConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *OutboundConfigPB) GetSendThrough() *v2ray_core_common_net.AddressPB {
+	if m != nil {
+		return m.SendThrough
+	}
+	return nil
+}
+
+func (m *OutboundConfigPB) GetSettings() *google_protobuf.Any {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+func (m *OutboundConfigPB) GetStreamSettings() *StreamConfigPB {
+	if m != nil {
+		return m.StreamSettings
+	}
+	return nil
+}
+
+type AllocationConfigPB struct {
+	Strategy    string `protobuf:"bytes,1,opt,name=strategy" json:"strategy,omitempty"`
+	Concurrency uint32 `protobuf:"varint,2,opt,name=concurrency" json:"concurrency,omitempty"`
+	// In minutes.
+	Refresh uint32 `protobuf:"varint,3,opt,name=refresh" json:"refresh,omitempty"`
+}
+
+func (m *AllocationConfigPB) Reset()                    { *m = AllocationConfigPB{} }
+func (m *AllocationConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*AllocationConfigPB) ProtoMessage()               {}
+func (*AllocationConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+type InboundDetourConfigPB struct {
+	Protocol           string                            `protobuf:"bytes,1,opt,name=protocol" json:"protocol,omitempty"`
+	Port               *v2ray_core_common_net3.PortRange `protobuf:"bytes,2,opt,name=port" json:"port,omitempty"`
+	Listen             *v2ray_core_common_net.AddressPB  `protobuf:"bytes,3,opt,name=listen" json:"listen,omitempty"`
+	Settings           *google_protobuf.Any              `protobuf:"bytes,4,opt,name=settings" json:"settings,omitempty"`
+	Tag                string                            `protobuf:"bytes,5,opt,name=tag" json:"tag,omitempty"`
+	Allocate           *AllocationConfigPB               `protobuf:"bytes,6,opt,name=allocate" json:"allocate,omitempty"`
+	StreamSettings     *StreamConfigPB                   `protobuf:"bytes,7,opt,name=stream_settings,json=streamSettings" json:"stream_settings,omitempty"`
+	AllowPassive       bool                              `protobuf:"varint,8,opt,name=allow_passive,json=allowPassive" json:"allow_passive,omitempty"`
+	DefaultOutboundTag string                            `protobuf:"bytes,9,opt,name=default_outbound_tag,json=defaultOutboundTag" json:"default_outbound_tag,omitempty"`
+	AllowSources       []string                          `protobuf:"bytes,10,rep,name=allow_sources,json=allowSources" json:"allow_sources,omitempty"`
+	DenySources        []string                          `protobuf:"bytes,11,rep,name=deny_sources,json=denySources" json:"deny_sources,omitempty"`
+}
+
+func (m *InboundDetourConfigPB) Reset()                    { *m = InboundDetourConfigPB{} }
+func (m *InboundDetourConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*InboundDetourConfigPB) ProtoMessage()               {}
+func (*InboundDetourConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *InboundDetourConfigPB) GetPort() *v2ray_core_common_net3.PortRange {
+	if m != nil {
+		return m.Port
+	}
+	return nil
+}
+
+func (m *InboundDetourConfigPB) GetListen() *v2ray_core_common_net.AddressPB {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+func (m *InboundDetourConfigPB) GetSettings() *google_protobuf.Any {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+func (m *InboundDetourConfigPB) GetAllocate() *AllocationConfigPB {
+	if m != nil {
+		return m.Allocate
+	}
+	return nil
+}
+
+func (m *InboundDetourConfigPB) GetStreamSettings() *StreamConfigPB {
+	if m != nil {
+		return m.StreamSettings
+	}
+	return nil
+}
+
+type OutboundDetourConfigPB struct {
+	Protocol       string                           `protobuf:"bytes,1,opt,name=protocol" json:"protocol,omitempty"`
+	SendThrough    *v2ray_core_common_net.AddressPB `protobuf:"bytes,2,opt,name=send_through,json=sendThrough" json:"send_through,omitempty"`
+	Tag            string                           `protobuf:"bytes,3,opt,name=tag" json:"tag,omitempty"`
+	Settings       *google_protobuf.Any             `protobuf:"bytes,4,opt,name=settings" json:"settings,omitempty"`
+	StreamSettings *StreamConfigPB                  `protobuf:"bytes,5,opt,name=stream_settings,json=streamSettings" json:"stream_settings,omitempty"`
+}
+
+func (m *OutboundDetourConfigPB) Reset()                    { *m = OutboundDetourConfigPB{} }
+func (m *OutboundDetourConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*OutboundDetourConfigPB) ProtoMessage()               {}
+func (*OutboundDetourConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *OutboundDetourConfigPB) GetSendThrough() *v2ray_core_common_net.AddressPB {
+	if m != nil {
+		return m.SendThrough
+	}
+	return nil
+}
+
+func (m *OutboundDetourConfigPB) GetSettings() *google_protobuf.Any {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+func (m *OutboundDetourConfigPB) GetStreamSettings() *StreamConfigPB {
+	if m != nil {
+		return m.StreamSettings
+	}
+	return nil
+}
+
+type RoutingConfigPB struct {
+	Strategy string `protobuf:"bytes,1,opt,name=strategy" json:"strategy,omitempty"`
+	// Settings of the strategy in JSON, as strategies registered by router.RegisterRouterConfig take.
+	Settings []byte `protobuf:"bytes,2,opt,name=settings,proto3" json:"settings,omitempty"`
+}
+
+func (m *RoutingConfigPB) Reset()                    { *m = RoutingConfigPB{} }
+func (m *RoutingConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*RoutingConfigPB) ProtoMessage()               {}
+func (*RoutingConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+type TCPConfigPB struct {
+	ConnectionReuse bool `protobuf:"varint,1,opt,name=connection_reuse,json=connectionReuse" json:"connection_reuse,omitempty"`
+	Mptcp           bool `protobuf:"varint,2,opt,name=mptcp" json:"mptcp,omitempty"`
+}
+
+func (m *TCPConfigPB) Reset()                    { *m = TCPConfigPB{} }
+func (m *TCPConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*TCPConfigPB) ProtoMessage()               {}
+func (*TCPConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+type WebSocketConfigPB struct {
+	ConnectionReuse bool   `protobuf:"varint,1,opt,name=connection_reuse,json=connectionReuse" json:"connection_reuse,omitempty"`
+	Path            string `protobuf:"bytes,2,opt,name=path" json:"path,omitempty"`
+	Pto             string `protobuf:"bytes,3,opt,name=pto" json:"pto,omitempty"`
+	Cert            string `protobuf:"bytes,4,opt,name=cert" json:"cert,omitempty"`
+	PrivKey         string `protobuf:"bytes,5,opt,name=priv_key,json=privKey" json:"priv_key,omitempty"`
+	BrowserDialer   string `protobuf:"bytes,6,opt,name=browser_dialer,json=browserDialer" json:"browser_dialer,omitempty"`
+}
+
+func (m *WebSocketConfigPB) Reset()                    { *m = WebSocketConfigPB{} }
+func (m *WebSocketConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*WebSocketConfigPB) ProtoMessage()               {}
+func (*WebSocketConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+type TransportConfigPB struct {
+	Tcp       *TCPConfigPB                              `protobuf:"bytes,1,opt,name=tcp" json:"tcp,omitempty"`
+	Kcp       *v2ray_core_transport_internet_kcp.Config `protobuf:"bytes,2,opt,name=kcp" json:"kcp,omitempty"`
+	Websocket *WebSocketConfigPB                        `protobuf:"bytes,3,opt,name=websocket" json:"websocket,omitempty"`
+}
+
+func (m *TransportConfigPB) Reset()                    { *m = TransportConfigPB{} }
+func (m *TransportConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*TransportConfigPB) ProtoMessage()               {}
+func (*TransportConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *TransportConfigPB) GetTcp() *TCPConfigPB {
+	if m != nil {
+		return m.Tcp
+	}
+	return nil
+}
+
+func (m *TransportConfigPB) GetKcp() *v2ray_core_transport_internet_kcp.Config {
+	if m != nil {
+		return m.Kcp
+	}
+	return nil
+}
+
+func (m *TransportConfigPB) GetWebsocket() *WebSocketConfigPB {
+	if m != nil {
+		return m.Websocket
+	}
+	return nil
+}
+
+// Rates are in KB/s.
+type ThrottleLimitPB struct {
+	Uplink   uint64 `protobuf:"varint,1,opt,name=uplink" json:"uplink,omitempty"`
+	Downlink uint64 `protobuf:"varint,2,opt,name=downlink" json:"downlink,omitempty"`
+	Shared   bool   `protobuf:"varint,3,opt,name=shared" json:"shared,omitempty"`
+}
+
+func (m *ThrottleLimitPB) Reset()                    { *m = ThrottleLimitPB{} }
+func (m *ThrottleLimitPB) String() string            { return proto.CompactTextString(m) }
+func (*ThrottleLimitPB) ProtoMessage()               {}
+func (*ThrottleLimitPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+type ThrottleConfigPB struct {
+	Levels    map[uint32]*ThrottleLimitPB `protobuf:"bytes,1,rep,name=levels" json:"levels,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Inbounds  map[string]*ThrottleLimitPB `protobuf:"bytes,2,rep,name=inbounds" json:"inbounds,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Outbounds map[string]*ThrottleLimitPB `protobuf:"bytes,3,rep,name=outbounds" json:"outbounds,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *ThrottleConfigPB) Reset()                    { *m = ThrottleConfigPB{} }
+func (m *ThrottleConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*ThrottleConfigPB) ProtoMessage()               {}
+func (*ThrottleConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *ThrottleConfigPB) GetLevels() map[uint32]*ThrottleLimitPB {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+func (m *ThrottleConfigPB) GetInbounds() map[string]*ThrottleLimitPB {
+	if m != nil {
+		return m.Inbounds
+	}
+	return nil
+}
+
+func (m *ThrottleConfigPB) GetOutbounds() map[string]*ThrottleLimitPB {
+	if m != nil {
+		return m.Outbounds
+	}
+	return nil
+}
+
+// Fields of policies are the effective values, not defaults of levels. Times are in seconds except the quota period
+// in days.
+type PolicyPB struct {
+	Handshake       uint32 `protobuf:"varint,1,opt,name=handshake" json:"handshake,omitempty"`
+	ConnectionIdle  uint32 `protobuf:"varint,2,opt,name=connection_idle,json=connectionIdle" json:"connection_idle,omitempty"`
+	UplinkOnly      uint32 `protobuf:"varint,3,opt,name=uplink_only,json=uplinkOnly" json:"uplink_only,omitempty"`
+	DownlinkOnly    uint32 `protobuf:"varint,4,opt,name=downlink_only,json=downlinkOnly" json:"downlink_only,omitempty"`
+	BufferSize      uint32 `protobuf:"varint,5,opt,name=buffer_size,json=bufferSize" json:"buffer_size,omitempty"`
+	PooledBuffers   uint32 `protobuf:"varint,6,opt,name=pooled_buffers,json=pooledBuffers" json:"pooled_buffers,omitempty"`
+	BufferClass     string `protobuf:"bytes,7,opt,name=buffer_class,json=bufferClass" json:"buffer_class,omitempty"`
+	MaxConnections  uint32 `protobuf:"varint,8,opt,name=max_connections,json=maxConnections" json:"max_connections,omitempty"`
+	ConnectionQueue uint32 `protobuf:"varint,9,opt,name=connection_queue,json=connectionQueue" json:"connection_queue,omitempty"`
+	Quota           uint64 `protobuf:"varint,10,opt,name=quota" json:"quota,omitempty"`
+	QuotaPeriod     uint32 `protobuf:"varint,11,opt,name=quota_period,json=quotaPeriod" json:"quota_period,omitempty"`
+	OverQuotaRate   uint64 `protobuf:"varint,12,opt,name=over_quota_rate,json=overQuotaRate" json:"over_quota_rate,omitempty"`
+	UdpIdle         uint32 `protobuf:"varint,13,opt,name=udp_idle,json=udpIdle" json:"udp_idle,omitempty"`
+	UdpSessions     uint32 `protobuf:"varint,14,opt,name=udp_sessions,json=udpSessions" json:"udp_sessions,omitempty"`
+}
+
+func (m *PolicyPB) Reset()                    { *m = PolicyPB{} }
+func (m *PolicyPB) String() string            { return proto.CompactTextString(m) }
+func (*PolicyPB) ProtoMessage()               {}
+func (*PolicyPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+// Times are in seconds, and zero times take the defaults.
+type BanConfigPB struct {
+	Failures    uint32 `protobuf:"varint,1,opt,name=failures" json:"failures,omitempty"`
+	Window      uint32 `protobuf:"varint,2,opt,name=window" json:"window,omitempty"`
+	Duration    uint32 `protobuf:"varint,3,opt,name=duration" json:"duration,omitempty"`
+	MaxDuration uint32 `protobuf:"varint,4,opt,name=max_duration,json=maxDuration" json:"max_duration,omitempty"`
+}
+
+func (m *BanConfigPB) Reset()                    { *m = BanConfigPB{} }
+func (m *BanConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*BanConfigPB) ProtoMessage()               {}
+func (*BanConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+type PolicyConfigPB struct {
+	Levels map[uint32]*PolicyPB `protobuf:"bytes,1,rep,name=levels" json:"levels,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Ban    *BanConfigPB         `protobuf:"bytes,2,opt,name=ban" json:"ban,omitempty"`
+}
+
+func (m *PolicyConfigPB) Reset()                    { *m = PolicyConfigPB{} }
+func (m *PolicyConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*PolicyConfigPB) ProtoMessage()               {}
+func (*PolicyConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *PolicyConfigPB) GetLevels() map[uint32]*PolicyPB {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+func (m *PolicyConfigPB) GetBan() *BanConfigPB {
+	if m != nil {
+		return m.Ban
+	}
+	return nil
+}
+
+type ApiConfigPB struct {
+	Listen *v2ray_core_common_net.AddressPB `protobuf:"bytes,1,opt,name=listen" json:"listen,omitempty"`
+	Port   uint32                           `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+}
+
+func (m *ApiConfigPB) Reset()                    { *m = ApiConfigPB{} }
+func (m *ApiConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*ApiConfigPB) ProtoMessage()               {}
+func (*ApiConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *ApiConfigPB) GetListen() *v2ray_core_common_net.AddressPB {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+type StatsConfigPB struct {
+	Users       bool   `protobuf:"varint,1,opt,name=users" json:"users,omitempty"`
+	Inbounds    bool   `protobuf:"varint,2,opt,name=inbounds" json:"inbounds,omitempty"`
+	Outbounds   bool   `protobuf:"varint,3,opt,name=outbounds" json:"outbounds,omitempty"`
+	UserDevices bool   `protobuf:"varint,4,opt,name=user_devices,json=userDevices" json:"user_devices,omitempty"`
+	DeviceLimit uint32 `protobuf:"varint,5,opt,name=device_limit,json=deviceLimit" json:"device_limit,omitempty"`
+}
+
+func (m *StatsConfigPB) Reset()                    { *m = StatsConfigPB{} }
+func (m *StatsConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*StatsConfigPB) ProtoMessage()               {}
+func (*StatsConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+type MetricsConfigPB struct {
+	Listen *v2ray_core_common_net.AddressPB `protobuf:"bytes,1,opt,name=listen" json:"listen,omitempty"`
+	Port   uint32                           `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	Path   string                           `protobuf:"bytes,3,opt,name=path" json:"path,omitempty"`
+}
+
+func (m *MetricsConfigPB) Reset()                    { *m = MetricsConfigPB{} }
+func (m *MetricsConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*MetricsConfigPB) ProtoMessage()               {}
+func (*MetricsConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *MetricsConfigPB) GetListen() *v2ray_core_common_net.AddressPB {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+type DebugConfigPB struct {
+	Port    uint32 `protobuf:"varint,1,opt,name=port" json:"port,omitempty"`
+	Enabled bool   `protobuf:"varint,2,opt,name=enabled" json:"enabled,omitempty"`
+}
+
+func (m *DebugConfigPB) Reset()                    { *m = DebugConfigPB{} }
+func (m *DebugConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*DebugConfigPB) ProtoMessage()               {}
+func (*DebugConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+type HealthConfigPB struct {
+	Listen    *v2ray_core_common_net.AddressPB `protobuf:"bytes,1,opt,name=listen" json:"listen,omitempty"`
+	Port      uint32                           `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	Path      string                           `protobuf:"bytes,3,opt,name=path" json:"path,omitempty"`
+	Outbounds []string                         `protobuf:"bytes,4,rep,name=outbounds" json:"outbounds,omitempty"`
+}
+
+func (m *HealthConfigPB) Reset()                    { *m = HealthConfigPB{} }
+func (m *HealthConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*HealthConfigPB) ProtoMessage()               {}
+func (*HealthConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *HealthConfigPB) GetListen() *v2ray_core_common_net.AddressPB {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+type TracingConfigPB struct {
+	Endpoint    string            `protobuf:"bytes,1,opt,name=endpoint" json:"endpoint,omitempty"`
+	Headers     map[string]string `protobuf:"bytes,2,rep,name=headers" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ServiceName string            `protobuf:"bytes,3,opt,name=service_name,json=serviceName" json:"service_name,omitempty"`
+	SampleRate  float64           `protobuf:"fixed64,4,opt,name=sample_rate,json=sampleRate" json:"sample_rate,omitempty"`
+}
+
+func (m *TracingConfigPB) Reset()                    { *m = TracingConfigPB{} }
+func (m *TracingConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*TracingConfigPB) ProtoMessage()               {}
+func (*TracingConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+func (m *TracingConfigPB) GetHeaders() map[string]string {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+type EventsWebhookConfigPB struct {
+	Url   string   `protobuf:"bytes,1,opt,name=url" json:"url,omitempty"`
+	Types []string `protobuf:"bytes,2,rep,name=types" json:"types,omitempty"`
+}
+
+func (m *EventsWebhookConfigPB) Reset()                    { *m = EventsWebhookConfigPB{} }
+func (m *EventsWebhookConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*EventsWebhookConfigPB) ProtoMessage()               {}
+func (*EventsWebhookConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+type EventsExecConfigPB struct {
+	Command string   `protobuf:"bytes,1,opt,name=command" json:"command,omitempty"`
+	Args    []string `protobuf:"bytes,2,rep,name=args" json:"args,omitempty"`
+	Types   []string `protobuf:"bytes,3,rep,name=types" json:"types,omitempty"`
+}
+
+func (m *EventsExecConfigPB) Reset()                    { *m = EventsExecConfigPB{} }
+func (m *EventsExecConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*EventsExecConfigPB) ProtoMessage()               {}
+func (*EventsExecConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+type EventsConfigPB struct {
+	Webhooks []*EventsWebhookConfigPB `protobuf:"bytes,1,rep,name=webhooks" json:"webhooks,omitempty"`
+	Execs    []*EventsExecConfigPB    `protobuf:"bytes,2,rep,name=execs" json:"execs,omitempty"`
+	// Zero limit disables the event. Window is in seconds, and zero takes the default.
+	AuthFailureLimit  uint32 `protobuf:"varint,3,opt,name=auth_failure_limit,json=authFailureLimit" json:"auth_failure_limit,omitempty"`
+	AuthFailureWindow uint32 `protobuf:"varint,4,opt,name=auth_failure_window,json=authFailureWindow" json:"auth_failure_window,omitempty"`
+	// In days, and zero takes the default.
+	CertificateExpiry uint32 `protobuf:"varint,5,opt,name=certificate_expiry,json=certificateExpiry" json:"certificate_expiry,omitempty"`
+}
+
+func (m *EventsConfigPB) Reset()                    { *m = EventsConfigPB{} }
+func (m *EventsConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*EventsConfigPB) ProtoMessage()               {}
+func (*EventsConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+func (m *EventsConfigPB) GetWebhooks() []*EventsWebhookConfigPB {
+	if m != nil {
+		return m.Webhooks
+	}
+	return nil
+}
+
+func (m *EventsConfigPB) GetExecs() []*EventsExecConfigPB {
+	if m != nil {
+		return m.Execs
+	}
+	return nil
+}
+
+type ConfigPB struct {
+	Port            uint32                     `protobuf:"varint,1,opt,name=port" json:"port,omitempty"`
+	Log             *LogConfigPB               `protobuf:"bytes,2,opt,name=log" json:"log,omitempty"`
+	Routing         *RoutingConfigPB           `protobuf:"bytes,3,opt,name=routing" json:"routing,omitempty"`
+	Dns             *v2ray_core_app_dns.Config `protobuf:"bytes,4,opt,name=dns" json:"dns,omitempty"`
+	Inbound         *InboundConfigPB           `protobuf:"bytes,5,opt,name=inbound" json:"inbound,omitempty"`
+	Outbound        *OutboundConfigPB          `protobuf:"bytes,6,opt,name=outbound" json:"outbound,omitempty"`
+	InboundDetours  []*InboundDetourConfigPB   `protobuf:"bytes,7,rep,name=inbound_detours,json=inboundDetours" json:"inbound_detours,omitempty"`
+	OutboundDetours []*OutboundDetourConfigPB  `protobuf:"bytes,8,rep,name=outbound_detours,json=outboundDetours" json:"outbound_detours,omitempty"`
+	Transport       *TransportConfigPB         `protobuf:"bytes,9,opt,name=transport" json:"transport,omitempty"`
+	Throttle        *ThrottleConfigPB          `protobuf:"bytes,10,opt,name=throttle" json:"throttle,omitempty"`
+	Api             *ApiConfigPB               `protobuf:"bytes,11,opt,name=api" json:"api,omitempty"`
+	Stats           *StatsConfigPB             `protobuf:"bytes,12,opt,name=stats" json:"stats,omitempty"`
+	Metrics         *MetricsConfigPB           `protobuf:"bytes,13,opt,name=metrics" json:"metrics,omitempty"`
+	Debug           *DebugConfigPB             `protobuf:"bytes,14,opt,name=debug" json:"debug,omitempty"`
+	Events          *EventsConfigPB            `protobuf:"bytes,15,opt,name=events" json:"events,omitempty"`
+	Tracing         *TracingConfigPB           `protobuf:"bytes,16,opt,name=tracing" json:"tracing,omitempty"`
+	Health          *HealthConfigPB            `protobuf:"bytes,17,opt,name=health" json:"health,omitempty"`
+	Policy          *PolicyConfigPB            `protobuf:"bytes,18,opt,name=policy" json:"policy,omitempty"`
+}
+
+func (m *ConfigPB) Reset()                    { *m = ConfigPB{} }
+func (m *ConfigPB) String() string            { return proto.CompactTextString(m) }
+func (*ConfigPB) ProtoMessage()               {}
+func (*ConfigPB) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *ConfigPB) GetLog() *LogConfigPB {
+	if m != nil {
+		return m.Log
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetRouting() *RoutingConfigPB {
+	if m != nil {
+		return m.Routing
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetDns() *v2ray_core_app_dns.Config {
+	if m != nil {
+		return m.Dns
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetInbound() *InboundConfigPB {
+	if m != nil {
+		return m.Inbound
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetOutbound() *OutboundConfigPB {
+	if m != nil {
+		return m.Outbound
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetInboundDetours() []*InboundDetourConfigPB {
+	if m != nil {
+		return m.InboundDetours
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetOutboundDetours() []*OutboundDetourConfigPB {
+	if m != nil {
+		return m.OutboundDetours
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetTransport() *TransportConfigPB {
+	if m != nil {
+		return m.Transport
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetThrottle() *ThrottleConfigPB {
+	if m != nil {
+		return m.Throttle
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetApi() *ApiConfigPB {
+	if m != nil {
+		return m.Api
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetStats() *StatsConfigPB {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetMetrics() *MetricsConfigPB {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetDebug() *DebugConfigPB {
+	if m != nil {
+		return m.Debug
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetEvents() *EventsConfigPB {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetTracing() *TracingConfigPB {
+	if m != nil {
+		return m.Tracing
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetHealth() *HealthConfigPB {
+	if m != nil {
+		return m.Health
+	}
+	return nil
+}
+
+func (m *ConfigPB) GetPolicy() *PolicyConfigPB {
+	if m != nil {
+		return m.Policy
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*LogRotationConfigPB)(nil), "v2ray.core.shell.point.LogRotationConfigPB")
+	proto.RegisterType((*LogSyslogConfigPB)(nil), "v2ray.core.shell.point.LogSyslogConfigPB")
+	proto.RegisterType((*LogEventLogConfigPB)(nil), "v2ray.core.shell.point.LogEventLogConfigPB")
+	proto.RegisterType((*LogConfigPB)(nil), "v2ray.core.shell.point.LogConfigPB")
+	proto.RegisterType((*CertificatePB)(nil), "v2ray.core.shell.point.CertificatePB")
+	proto.RegisterType((*ACMEConfigPB)(nil), "v2ray.core.shell.point.ACMEConfigPB")
+	proto.RegisterType((*TLSConfigPB)(nil), "v2ray.core.shell.point.TLSConfigPB")
+	proto.RegisterType((*RealityConfigPB)(nil), "v2ray.core.shell.point.RealityConfigPB")
+	proto.RegisterType((*PluginConfigPB)(nil), "v2ray.core.shell.point.PluginConfigPB")
+	proto.RegisterType((*StreamConfigPB)(nil), "v2ray.core.shell.point.StreamConfigPB")
+	proto.RegisterType((*InboundConfigPB)(nil), "v2ray.core.shell.point.InboundConfigPB")
+	proto.RegisterType((*OutboundConfigPB)(nil), "v2ray.core.shell.point.OutboundConfigPB")
+	proto.RegisterType((*AllocationConfigPB)(nil), "v2ray.core.shell.point.AllocationConfigPB")
+	proto.RegisterType((*InboundDetourConfigPB)(nil), "v2ray.core.shell.point.InboundDetourConfigPB")
+	proto.RegisterType((*OutboundDetourConfigPB)(nil), "v2ray.core.shell.point.OutboundDetourConfigPB")
+	proto.RegisterType((*RoutingConfigPB)(nil), "v2ray.core.shell.point.RoutingConfigPB")
+	proto.RegisterType((*TCPConfigPB)(nil), "v2ray.core.shell.point.TCPConfigPB")
+	proto.RegisterType((*WebSocketConfigPB)(nil), "v2ray.core.shell.point.WebSocketConfigPB")
+	proto.RegisterType((*TransportConfigPB)(nil), "v2ray.core.shell.point.TransportConfigPB")
+	proto.RegisterType((*ThrottleLimitPB)(nil), "v2ray.core.shell.point.ThrottleLimitPB")
+	proto.RegisterType((*ThrottleConfigPB)(nil), "v2ray.core.shell.point.ThrottleConfigPB")
+	proto.RegisterType((*PolicyPB)(nil), "v2ray.core.shell.point.PolicyPB")
+	proto.RegisterType((*BanConfigPB)(nil), "v2ray.core.shell.point.BanConfigPB")
+	proto.RegisterType((*PolicyConfigPB)(nil), "v2ray.core.shell.point.PolicyConfigPB")
+	proto.RegisterType((*ApiConfigPB)(nil), "v2ray.core.shell.point.ApiConfigPB")
+	proto.RegisterType((*StatsConfigPB)(nil), "v2ray.core.shell.point.StatsConfigPB")
+	proto.RegisterType((*MetricsConfigPB)(nil), "v2ray.core.shell.point.MetricsConfigPB")
+	proto.RegisterType((*DebugConfigPB)(nil), "v2ray.core.shell.point.DebugConfigPB")
+	proto.RegisterType((*HealthConfigPB)(nil), "v2ray.core.shell.point.HealthConfigPB")
+	proto.RegisterType((*TracingConfigPB)(nil), "v2ray.core.shell.point.TracingConfigPB")
+	proto.RegisterType((*EventsWebhookConfigPB)(nil), "v2ray.core.shell.point.EventsWebhookConfigPB")
+	proto.RegisterType((*EventsExecConfigPB)(nil), "v2ray.core.shell.point.EventsExecConfigPB")
+	proto.RegisterType((*EventsConfigPB)(nil), "v2ray.core.shell.point.EventsConfigPB")
+	proto.RegisterType((*ConfigPB)(nil), "v2ray.core.shell.point.ConfigPB")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/shell/point/config.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 2924 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x39, 0x4b, 0x73, 0x24, 0x47,
+	0xd1, 0x31, 0x1a, 0x3d, 0x66, 0x72, 0x34, 0x92, 0xb6, 0xbd, 0xde, 0xaf, 0x57, 0x1f, 0x84, 0xd7,
+	0xbd, 0x5e, 0x7b, 0x6d, 0xec, 0x91, 0x43, 0x78, 0x8d, 0xf1, 0x03, 0xa3, 0xc7, 0xae, 0x77, 0x41,
+	0x5e, 0xcb, 0x25, 0xd9, 0x06, 0x3b, 0x88, 0x8e, 0x9a, 0xee, 0x9a, 0x99, 0x0e, 0xf5, 0x74, 0xb5,
+	0xab, 0xaa, 0x25, 0x8d, 0x4f, 0x5c, 0x20, 0x38, 0x11, 0xdc, 0x38, 0x11, 0x1c, 0x08, 0x2e, 0xfc,
+	0x04, 0x82, 0x1f, 0xc1, 0x81, 0x03, 0x3f, 0x83, 0x0b, 0x5c, 0x08, 0x22, 0x88, 0xac, 0x47, 0x4f,
+	0xcf, 0xac, 0x66, 0x56, 0x6b, 0x6c, 0xb8, 0x4d, 0x66, 0x65, 0x66, 0x65, 0xe7, 0xab, 0x32, 0x73,
+	0xe0, 0x85, 0xd3, 0x6d, 0x41, 0x47, 0x9d, 0x88, 0x0f, 0xb7, 0x22, 0x2e, 0xd8, 0x96, 0x1c, 0xb0,
+	0x34, 0xdd, 0xca, 0x79, 0x92, 0xa9, 0xad, 0x88, 0x67, 0xbd, 0xa4, 0xdf, 0xc9, 0x05, 0x57, 0xdc,
+	0xbb, 0xe6, 0x08, 0x05, 0xeb, 0x68, 0xa2, 0x8e, 0x26, 0xda, 0xbc, 0xde, 0xe7, 0xbc, 0x9f, 0xb2,
+	0x2d, 0x4d, 0xd5, 0x2d, 0x7a, 0x5b, 0x34, 0x1b, 0x19, 0x96, 0xcd, 0x9b, 0x53, 0xb2, 0x69, 0x9e,
+	0x6f, 0xc5, 0x99, 0x9c, 0x90, 0xbb, 0x39, 0xad, 0x40, 0xc4, 0x87, 0x43, 0x9e, 0x6d, 0x65, 0x4c,
+	0x6d, 0xd1, 0x38, 0x16, 0x4c, 0xca, 0xc7, 0x13, 0x66, 0x4c, 0x9d, 0x71, 0x71, 0x62, 0x09, 0x9f,
+	0x9b, 0x4d, 0x98, 0x73, 0xa1, 0x2c, 0xd5, 0xf6, 0x14, 0x95, 0x12, 0x34, 0x93, 0x78, 0xbe, 0x95,
+	0x64, 0x8a, 0x09, 0xa4, 0x3e, 0x89, 0xf2, 0x09, 0x5d, 0x83, 0x9f, 0xd7, 0xe0, 0xa9, 0x03, 0xde,
+	0x27, 0x5c, 0x51, 0x95, 0xf0, 0x6c, 0x4f, 0x9f, 0x1d, 0xee, 0x7a, 0xd7, 0xa1, 0x31, 0xa4, 0xe7,
+	0xa1, 0x4c, 0xbe, 0x60, 0x7e, 0xed, 0x46, 0xed, 0x76, 0x9b, 0xac, 0x0c, 0xe9, 0xf9, 0x51, 0xf2,
+	0x05, 0xf3, 0xfe, 0x0f, 0xf0, 0x67, 0x48, 0xfb, 0xcc, 0x5f, 0xd0, 0x27, 0xcb, 0x43, 0x7a, 0xbe,
+	0xd3, 0x67, 0xde, 0x33, 0xd0, 0xc2, 0x83, 0x2e, 0x8d, 0x4e, 0x8a, 0x5c, 0xfa, 0x75, 0x7d, 0x08,
+	0x43, 0x7a, 0xbe, 0x6b, 0x30, 0xde, 0x26, 0x34, 0x22, 0x3e, 0xcc, 0xd1, 0x02, 0xfe, 0xe2, 0x8d,
+	0xda, 0xed, 0x06, 0x29, 0xe1, 0x40, 0xc2, 0x95, 0x03, 0xde, 0x3f, 0x1a, 0xc9, 0x94, 0xf7, 0x4b,
+	0x2d, 0x7c, 0x58, 0xb1, 0x16, 0xd3, 0x4a, 0x34, 0x89, 0x03, 0xbd, 0x0d, 0xa8, 0x2b, 0xda, 0xd7,
+	0x0a, 0x34, 0x09, 0xfe, 0x44, 0xe1, 0x3d, 0x1a, 0x25, 0x69, 0xa2, 0x46, 0xfa, 0xea, 0x26, 0x29,
+	0x61, 0xef, 0x1a, 0x2c, 0xd3, 0x28, 0x1a, 0x5f, 0x6b, 0xa1, 0xe0, 0xae, 0xfe, 0xf8, 0xbb, 0xa7,
+	0x2c, 0x53, 0x07, 0x95, 0x6b, 0xaf, 0xc1, 0xb2, 0xe4, 0x85, 0x88, 0x98, 0xbd, 0xd5, 0x42, 0x15,
+	0x31, 0x0b, 0x13, 0x62, 0xfe, 0x59, 0x87, 0xd6, 0x14, 0xbf, 0xa5, 0xb3, 0xfc, 0x06, 0xf2, 0xae,
+	0xc2, 0x12, 0x13, 0x82, 0x0b, 0xab, 0xb6, 0x01, 0x10, 0x9b, 0xb2, 0x53, 0x96, 0x5a, 0xad, 0x0d,
+	0xe0, 0xbd, 0x08, 0x1b, 0x86, 0x2b, 0x1c, 0x70, 0xa9, 0x32, 0x3a, 0x64, 0x4e, 0xf9, 0x75, 0x83,
+	0xbf, 0xef, 0xd0, 0xde, 0x4d, 0x68, 0x5b, 0xd2, 0x1e, 0x17, 0x43, 0xaa, 0xfc, 0x25, 0x2d, 0x68,
+	0xd5, 0x20, 0xef, 0x69, 0x9c, 0xf7, 0x1e, 0x34, 0x84, 0x75, 0xb2, 0xbf, 0x7c, 0xa3, 0x76, 0xbb,
+	0xb5, 0xfd, 0xad, 0xce, 0xc5, 0xf1, 0xdf, 0xb9, 0x20, 0x1e, 0x48, 0xc9, 0xec, 0xed, 0xc0, 0xb2,
+	0xd4, 0x5e, 0xf2, 0x57, 0xb4, 0x98, 0x17, 0xe7, 0x88, 0x99, 0x74, 0x27, 0xb1, 0x8c, 0xde, 0x7d,
+	0x68, 0x32, 0xb4, 0x79, 0x88, 0x52, 0x1a, 0x8f, 0x55, 0x66, 0xda, 0x3f, 0xa4, 0xc1, 0x2c, 0xc6,
+	0x7b, 0x0f, 0x96, 0xb5, 0xb9, 0xa4, 0xdf, 0xbc, 0x51, 0xbf, 0xdd, 0xda, 0xde, 0x9a, 0x23, 0xc6,
+	0xb1, 0x77, 0x0e, 0x34, 0xc7, 0xdd, 0x4c, 0x89, 0x11, 0xb1, 0xec, 0x9b, 0xdf, 0x85, 0x56, 0x05,
+	0x8d, 0xe1, 0x75, 0xc2, 0x46, 0xd6, 0x7d, 0xf8, 0x13, 0xbd, 0x74, 0x4a, 0xd3, 0x82, 0x39, 0xdf,
+	0x69, 0xe0, 0xcd, 0x85, 0x37, 0x6a, 0xc1, 0x47, 0xd0, 0xde, 0x63, 0x42, 0x25, 0xbd, 0x24, 0xa2,
+	0x8a, 0x1d, 0xee, 0xa2, 0xeb, 0xa2, 0x31, 0x22, 0xec, 0x25, 0xa9, 0x0b, 0xa4, 0xf5, 0x0a, 0xfe,
+	0x5e, 0x92, 0x32, 0x4c, 0xb3, 0x13, 0x36, 0x32, 0x24, 0x46, 0xf0, 0xca, 0x09, 0x1b, 0xe1, 0x51,
+	0xf0, 0xbb, 0x1a, 0xac, 0xee, 0xec, 0xbd, 0x7f, 0xb7, 0x9a, 0x0c, 0x31, 0x1f, 0xd2, 0x24, 0xc3,
+	0xb0, 0xaa, 0x23, 0xa9, 0x05, 0x75, 0x5c, 0x0d, 0x69, 0x92, 0x96, 0x71, 0x85, 0x80, 0xf7, 0xff,
+	0xd0, 0x8c, 0x68, 0x34, 0x60, 0x61, 0x9c, 0x08, 0x97, 0x11, 0x1a, 0xb1, 0x9f, 0x08, 0x8c, 0x99,
+	0x38, 0x11, 0x2c, 0x52, 0x5c, 0x8c, 0xc2, 0x42, 0xa4, 0x3a, 0xb6, 0x9a, 0x64, 0xb5, 0x44, 0x7e,
+	0x24, 0x52, 0xef, 0x59, 0x58, 0x1d, 0x28, 0x95, 0x87, 0x2e, 0x07, 0x4d, 0x5c, 0xb5, 0x10, 0xb7,
+	0x63, 0x50, 0xc1, 0x5f, 0x16, 0xa1, 0x75, 0x7c, 0x70, 0x54, 0x2a, 0x79, 0x0b, 0xd6, 0x68, 0x9a,
+	0xf2, 0xb3, 0x30, 0xc9, 0x24, 0x8b, 0x0a, 0x61, 0xbe, 0xbc, 0x41, 0xda, 0x1a, 0xfb, 0xc0, 0x22,
+	0xbd, 0x07, 0xb0, 0x5a, 0x31, 0x05, 0xe6, 0x13, 0x7a, 0xef, 0xd6, 0x2c, 0xef, 0x4d, 0xd8, 0x97,
+	0x4c, 0xb0, 0x7a, 0x6f, 0xc3, 0x66, 0x9c, 0x48, 0xda, 0x4d, 0x59, 0x28, 0x99, 0x94, 0x09, 0xcf,
+	0x42, 0xc1, 0x64, 0x31, 0xcc, 0x75, 0xa8, 0xd7, 0xf5, 0xed, 0xbe, 0xa5, 0x38, 0x32, 0x04, 0xa4,
+	0x3c, 0xf7, 0x5e, 0x06, 0xcf, 0x71, 0x19, 0x63, 0xe9, 0x8a, 0x87, 0xc6, 0x58, 0x22, 0x1b, 0xf6,
+	0x64, 0x0f, 0x0f, 0x74, 0xe9, 0xc3, 0x0a, 0x97, 0x64, 0xe1, 0x29, 0x13, 0x88, 0xb7, 0xf6, 0x80,
+	0x61, 0x92, 0x7d, 0x6c, 0x30, 0xae, 0x04, 0x3a, 0x82, 0x65, 0x4b, 0x40, 0xcf, 0x1d, 0xc1, 0xf3,
+	0xb0, 0xce, 0xa2, 0x41, 0x68, 0x6a, 0x70, 0x98, 0x26, 0x52, 0xe9, 0x34, 0x5a, 0x25, 0x6d, 0x16,
+	0x0d, 0x8c, 0x15, 0x0f, 0x12, 0xa9, 0x90, 0x2e, 0x4a, 0x13, 0xcc, 0x91, 0x88, 0xea, 0xf0, 0x90,
+	0x7e, 0x43, 0x3b, 0xbd, 0x6d, 0xd0, 0x7b, 0x14, 0x83, 0x44, 0x7a, 0x9f, 0x82, 0x45, 0x84, 0xda,
+	0xe9, 0x2e, 0x0f, 0xee, 0xcc, 0xb2, 0x64, 0xc5, 0x57, 0x9d, 0x3d, 0xcd, 0x78, 0x57, 0xf3, 0x99,
+	0x6c, 0x58, 0x8d, 0x2a, 0x28, 0xef, 0x0d, 0x58, 0xa4, 0xd1, 0x90, 0xf9, 0xa0, 0x33, 0xf4, 0xb9,
+	0x59, 0x22, 0xab, 0x41, 0x4a, 0x34, 0xc7, 0xe6, 0xbb, 0x70, 0xe5, 0x11, 0xe1, 0x4f, 0x94, 0x53,
+	0x3f, 0x5b, 0x80, 0x75, 0xc2, 0x28, 0x16, 0xef, 0x32, 0xb4, 0x3c, 0x58, 0x8c, 0x99, 0x54, 0x56,
+	0x80, 0xfe, 0x8d, 0x11, 0x2a, 0x99, 0x38, 0x65, 0x22, 0x34, 0x15, 0x72, 0x41, 0xdb, 0xa8, 0x65,
+	0x70, 0x0f, 0x11, 0x85, 0x2e, 0xc9, 0x45, 0x72, 0x8a, 0x99, 0x88, 0xd7, 0xd7, 0xb5, 0xb5, 0xc1,
+	0xa2, 0x7e, 0xc8, 0x46, 0x98, 0x27, 0x72, 0xc0, 0x85, 0x0a, 0x93, 0x18, 0x4b, 0x6c, 0xfd, 0xf6,
+	0x2a, 0x69, 0x68, 0xc4, 0x83, 0x58, 0x7a, 0x01, 0xb4, 0xd1, 0xa1, 0x2a, 0x19, 0x62, 0x1e, 0xf5,
+	0x7a, 0xda, 0xe7, 0x6d, 0x82, 0x5e, 0x3e, 0x4e, 0x86, 0x6c, 0x3f, 0xe9, 0xf5, 0xf0, 0x86, 0x8a,
+	0x12, 0xce, 0xe9, 0x63, 0x1d, 0xbc, 0x6f, 0x02, 0xe4, 0x45, 0x37, 0x4d, 0x22, 0xad, 0x81, 0xf1,
+	0x77, 0xd3, 0x60, 0x50, 0x81, 0xeb, 0xd0, 0x70, 0x0a, 0xe8, 0x6a, 0xb8, 0x4a, 0x56, 0xec, 0xfd,
+	0xc1, 0xaf, 0x6b, 0xb0, 0x76, 0x98, 0x16, 0xfd, 0x24, 0xbb, 0xc4, 0x9b, 0xe8, 0xc3, 0x0a, 0x36,
+	0x06, 0x34, 0x8b, 0x5d, 0x2d, 0xb1, 0x20, 0x9a, 0x8e, 0x8a, 0x3e, 0x3e, 0xc9, 0x68, 0x1e, 0xfd,
+	0x1b, 0x2b, 0x80, 0x54, 0x54, 0x28, 0xfd, 0x6d, 0xbc, 0x50, 0x3a, 0xe8, 0xdb, 0x64, 0x55, 0x23,
+	0x8f, 0x0d, 0x0e, 0x45, 0x72, 0x9d, 0x28, 0x2e, 0xf9, 0x1d, 0x18, 0xfc, 0x75, 0x01, 0xd6, 0x8e,
+	0x94, 0x60, 0x74, 0x58, 0x6a, 0xf6, 0x36, 0xac, 0xd8, 0xb6, 0x45, 0x6b, 0xd6, 0xda, 0x0e, 0xaa,
+	0x21, 0x63, 0x7a, 0x96, 0x4e, 0xc6, 0x54, 0xe7, 0xa1, 0xa1, 0xc2, 0x40, 0x27, 0x8e, 0x05, 0xdf,
+	0x6f, 0x5d, 0x1c, 0xf0, 0xfd, 0x36, 0xea, 0x97, 0xb0, 0x77, 0x07, 0xea, 0x2a, 0x35, 0x1d, 0x45,
+	0x6b, 0xfb, 0xe6, 0x25, 0x62, 0x9b, 0x20, 0xbd, 0xb7, 0x03, 0x2b, 0xc2, 0x04, 0x91, 0xfe, 0xb8,
+	0xd6, 0xf6, 0x0b, 0xb3, 0x58, 0xa7, 0x62, 0x8d, 0x38, 0x3e, 0x7c, 0xca, 0xa3, 0x42, 0x2a, 0x3e,
+	0xb4, 0xdf, 0x6f, 0x21, 0xef, 0x7b, 0xb0, 0x9c, 0x6b, 0xbf, 0xd8, 0xc7, 0xf4, 0xf9, 0x59, 0x92,
+	0x27, 0xbd, 0x47, 0x2c, 0x17, 0x7e, 0xad, 0x60, 0x92, 0xa7, 0xa7, 0x4c, 0xe8, 0x80, 0x68, 0x92,
+	0x12, 0x0e, 0x7e, 0x53, 0x87, 0xf5, 0x07, 0x59, 0x97, 0x17, 0x59, 0x5c, 0x0d, 0x7e, 0xec, 0xe4,
+	0x6c, 0x2f, 0xa6, 0x7f, 0x7b, 0x6f, 0xc0, 0x32, 0x16, 0x10, 0x96, 0x69, 0x7b, 0xb5, 0xb6, 0x6f,
+	0xcc, 0x30, 0xb7, 0xad, 0xd5, 0x78, 0xbb, 0xa1, 0xc7, 0xdb, 0x75, 0xfb, 0x17, 0x71, 0xd7, 0x75,
+	0x94, 0xb0, 0xf7, 0x2a, 0xfa, 0x41, 0xa9, 0x24, 0xeb, 0x4b, 0x6b, 0xb5, 0xab, 0x1d, 0xd3, 0x10,
+	0x77, 0x5c, 0x43, 0xdc, 0xd9, 0xc9, 0x46, 0xa4, 0xa4, 0xf2, 0x3e, 0x80, 0x75, 0xa9, 0x23, 0x21,
+	0x2c, 0x19, 0x97, 0xe6, 0x1b, 0x65, 0x32, 0x70, 0xc8, 0x9a, 0x61, 0x3f, 0x72, 0x02, 0x6f, 0x82,
+	0x79, 0x2e, 0xc2, 0x9c, 0x4a, 0x99, 0x9c, 0x9a, 0x94, 0x6a, 0x90, 0x55, 0x8d, 0x3c, 0x34, 0x38,
+	0xef, 0x55, 0xb8, 0x1a, 0xb3, 0x1e, 0x2d, 0x52, 0x15, 0xf2, 0x42, 0x69, 0x6b, 0x85, 0xd8, 0x12,
+	0x1a, 0x6b, 0x7a, 0xf6, 0xec, 0x03, 0x7b, 0x74, 0x4c, 0xfb, 0x63, 0xb1, 0xa6, 0x9d, 0x73, 0x15,
+	0xd5, 0x88, 0x3d, 0x32, 0x38, 0xac, 0x28, 0x31, 0xcb, 0x46, 0x25, 0x4d, 0xd3, 0x54, 0x14, 0xc4,
+	0x59, 0x92, 0xe0, 0x1f, 0x35, 0xd8, 0x70, 0x72, 0x4b, 0x07, 0x55, 0x4d, 0x5a, 0x9b, 0x32, 0xe9,
+	0x1e, 0x56, 0x29, 0x54, 0x6f, 0x20, 0x78, 0xd1, 0x1f, 0x5c, 0xda, 0x5d, 0x2d, 0xe4, 0x3a, 0x36,
+	0x4c, 0x13, 0x7e, 0xa9, 0x7f, 0x59, 0xbf, 0x2c, 0xfe, 0x27, 0x7e, 0x09, 0x52, 0xf0, 0x76, 0xd2,
+	0x94, 0x47, 0x93, 0xa3, 0x02, 0x26, 0xae, 0x12, 0x54, 0xb1, 0xbe, 0x2b, 0xee, 0x25, 0xec, 0xdd,
+	0x80, 0x56, 0xc4, 0xb3, 0xa8, 0x10, 0x82, 0x65, 0xd1, 0xc8, 0xce, 0x0b, 0x55, 0x14, 0x56, 0x18,
+	0xc1, 0x7a, 0x82, 0xc9, 0x81, 0x1d, 0x18, 0x1c, 0x18, 0xfc, 0x76, 0x11, 0x9e, 0xb6, 0x69, 0xb0,
+	0xcf, 0x14, 0x2f, 0xc4, 0xa5, 0x6c, 0xfd, 0x9a, 0x4d, 0x94, 0xf9, 0x36, 0x3e, 0xe4, 0x42, 0x11,
+	0x9a, 0xf5, 0xd9, 0x23, 0xa9, 0x54, 0x7f, 0xc2, 0x54, 0x7a, 0xf2, 0x74, 0xb1, 0xa3, 0xcb, 0xd2,
+	0x78, 0x74, 0xb9, 0x07, 0x0d, 0x6a, 0xec, 0xca, 0x6c, 0x39, 0x79, 0x69, 0xe6, 0x63, 0xfb, 0x88,
+	0xfd, 0x49, 0xc9, 0x7b, 0x91, 0xc3, 0x57, 0xbe, 0xda, 0x44, 0x6c, 0x3c, 0x41, 0x22, 0x36, 0x2f,
+	0x9f, 0x88, 0x70, 0x89, 0x44, 0x6c, 0x3d, 0x9a, 0x88, 0xbf, 0x5c, 0x80, 0x6b, 0x4e, 0xee, 0x13,
+	0x84, 0xc8, 0x57, 0x92, 0x8e, 0xd6, 0x8b, 0xf5, 0xb1, 0x17, 0xff, 0xf7, 0x85, 0x33, 0x78, 0x00,
+	0xeb, 0x84, 0x17, 0xf8, 0xfb, 0x52, 0xd9, 0xb9, 0x59, 0xd1, 0x78, 0x41, 0x37, 0x1e, 0x25, 0x1c,
+	0x3c, 0x84, 0xd6, 0xf1, 0xde, 0x61, 0x29, 0x06, 0x67, 0x1a, 0x9e, 0x65, 0x2c, 0x52, 0xa6, 0xc1,
+	0x2e, 0xa4, 0xeb, 0xec, 0xd7, 0xc7, 0x78, 0x82, 0x68, 0xec, 0xea, 0x86, 0xb9, 0x8a, 0x72, 0x3b,
+	0x24, 0x1b, 0x20, 0xf8, 0x63, 0x0d, 0xae, 0x7c, 0xc2, 0xba, 0x47, 0x3c, 0x3a, 0x61, 0xea, 0xcb,
+	0x88, 0xc5, 0x17, 0x90, 0xaa, 0x81, 0xed, 0x0d, 0xf4, 0x6f, 0x74, 0x42, 0xae, 0xb8, 0x73, 0x42,
+	0xae, 0x38, 0x52, 0xe1, 0x74, 0x60, 0xc7, 0x19, 0xfd, 0x1b, 0xfb, 0x2b, 0x6c, 0xf7, 0x74, 0xf3,
+	0x65, 0xbb, 0x18, 0x84, 0xb1, 0xf5, 0xba, 0x05, 0x6b, 0x5d, 0xc1, 0xcf, 0x24, 0x13, 0x61, 0x9c,
+	0xd0, 0x94, 0x09, 0xdb, 0xbd, 0xb5, 0x2d, 0x76, 0x5f, 0x23, 0x83, 0x3f, 0xd7, 0xe0, 0xca, 0xb1,
+	0xdb, 0xa6, 0x94, 0xca, 0x63, 0x57, 0x12, 0xe5, 0x7e, 0xed, 0x31, 0x5d, 0xc9, 0xd8, 0x8a, 0x04,
+	0xe9, 0xbd, 0xb7, 0xa0, 0x7e, 0x62, 0xad, 0x33, 0x35, 0x3d, 0x97, 0x0b, 0x9b, 0x8e, 0x5b, 0xd8,
+	0x74, 0x4e, 0xa2, 0xbc, 0x63, 0xd8, 0x09, 0x72, 0x79, 0xef, 0x41, 0xf3, 0x8c, 0x75, 0xa5, 0xb6,
+	0xa2, 0x5f, 0x7f, 0x54, 0x44, 0xf5, 0xe6, 0x47, 0xcc, 0x4d, 0xc6, 0xbc, 0xc1, 0x4f, 0x60, 0x1d,
+	0x43, 0x59, 0xa9, 0x94, 0x1d, 0x24, 0xc3, 0x44, 0x99, 0xb5, 0x45, 0x91, 0xa7, 0x49, 0x66, 0xda,
+	0xb7, 0x45, 0x62, 0x21, 0x0c, 0x93, 0x98, 0x9f, 0x65, 0xfa, 0x64, 0x41, 0x9f, 0x94, 0x30, 0xf2,
+	0xc8, 0x01, 0x15, 0x2c, 0xb6, 0x93, 0x96, 0x85, 0x82, 0x3f, 0x2d, 0xc2, 0x86, 0x93, 0x5f, 0x1a,
+	0xec, 0xa0, 0x9c, 0xd6, 0x6b, 0x7a, 0x4a, 0x79, 0x6d, 0xa6, 0xcd, 0xa6, 0x38, 0x2f, 0x1a, 0xd9,
+	0x3d, 0x02, 0x8d, 0xc4, 0x3c, 0x0f, 0x6e, 0x7e, 0x7c, 0xfd, 0xd2, 0xf2, 0xec, 0xbb, 0x62, 0x25,
+	0x96, 0x72, 0xbc, 0x8f, 0xa0, 0xe9, 0x6a, 0x98, 0xe9, 0x96, 0x5b, 0xdb, 0xdf, 0xb9, 0xb4, 0x50,
+	0x57, 0x8a, 0xac, 0xd4, 0xb1, 0xa4, 0xcd, 0xee, 0xcc, 0xed, 0x42, 0xdb, 0x4c, 0x42, 0xef, 0x54,
+	0x27, 0xa1, 0x39, 0x7d, 0xea, 0x94, 0xcb, 0x2a, 0x23, 0xd3, 0x66, 0x0c, 0xed, 0x89, 0xaf, 0xba,
+	0x60, 0xde, 0xfa, 0x0a, 0x6e, 0x61, 0xb0, 0x36, 0xf9, 0x99, 0x5f, 0xcb, 0x35, 0xc1, 0xdf, 0xeb,
+	0xd0, 0x38, 0xe4, 0x69, 0x12, 0x8d, 0x0e, 0x77, 0xbd, 0x6f, 0x40, 0x73, 0x40, 0xb3, 0x58, 0x0e,
+	0xe8, 0x89, 0x5b, 0x46, 0x8e, 0x11, 0xde, 0x0b, 0x50, 0x29, 0x15, 0x61, 0x12, 0xa7, 0x6e, 0x2d,
+	0xb9, 0x36, 0x46, 0x3f, 0x88, 0x53, 0x3d, 0xbc, 0x9b, 0x80, 0x0e, 0x79, 0x96, 0x8e, 0xdc, 0x7a,
+	0xd2, 0xa0, 0x3e, 0xc8, 0xd2, 0x91, 0xde, 0x89, 0xd8, 0xb8, 0x36, 0x24, 0x76, 0x22, 0x72, 0x48,
+	0x4d, 0xf4, 0x0c, 0xb4, 0xba, 0x45, 0xaf, 0xc7, 0x84, 0xd9, 0x14, 0x98, 0x71, 0x10, 0x0c, 0x4a,
+	0xef, 0x08, 0x6e, 0xc1, 0x5a, 0xce, 0x79, 0xca, 0xe2, 0xd0, 0x20, 0xa5, 0x2e, 0x29, 0x6d, 0xd2,
+	0x36, 0xd8, 0x5d, 0x83, 0xc4, 0xe7, 0xcd, 0xca, 0x89, 0x52, 0x2a, 0xa5, 0x6d, 0x5b, 0xad, 0xec,
+	0x3d, 0x44, 0xe1, 0x97, 0xe1, 0xec, 0x39, 0xfe, 0x0c, 0xa9, 0xdf, 0xdf, 0x36, 0x59, 0x1b, 0xd2,
+	0xf3, 0xbd, 0x31, 0x76, 0xaa, 0x8a, 0x7e, 0x5e, 0xb0, 0x82, 0xe9, 0xd7, 0xb7, 0x5d, 0xad, 0xa2,
+	0x1f, 0x22, 0x1a, 0x8b, 0xf3, 0xe7, 0x05, 0x57, 0x54, 0x0f, 0xf5, 0x8b, 0xc4, 0x00, 0xa8, 0x8c,
+	0xfe, 0x11, 0xe6, 0x4c, 0x24, 0x3c, 0xf6, 0x5b, 0xa6, 0x4f, 0xd3, 0xb8, 0x43, 0x8d, 0xc2, 0x85,
+	0x04, 0xc7, 0x11, 0xd7, 0xd0, 0xe1, 0x03, 0xe2, 0xaf, 0x6a, 0x11, 0x6d, 0x44, 0x7f, 0x88, 0x58,
+	0x82, 0x3d, 0xc8, 0x75, 0x68, 0x14, 0x71, 0x6e, 0xfc, 0xd0, 0x36, 0x0d, 0x5d, 0x11, 0xe7, 0xda,
+	0x01, 0xcf, 0xc2, 0x2a, 0x1e, 0xd9, 0xad, 0x8a, 0xf4, 0xd7, 0xcc, 0x2d, 0x45, 0x9c, 0xdb, 0xbd,
+	0x8c, 0x0c, 0x7e, 0x5a, 0x83, 0xd6, 0x2e, 0x9d, 0xe8, 0x2d, 0x7b, 0x34, 0x49, 0x0b, 0xc1, 0xa4,
+	0xf5, 0x7c, 0x09, 0x63, 0xe9, 0x39, 0x4b, 0xb2, 0x98, 0x9f, 0xb9, 0x35, 0xb4, 0x81, 0x74, 0xb9,
+	0x2a, 0x04, 0x2d, 0xd7, 0x3f, 0x6d, 0x52, 0xc2, 0xa8, 0x02, 0x9a, 0xb4, 0x3c, 0x5f, 0x2c, 0xa7,
+	0xf9, 0x7d, 0x8b, 0x0a, 0xfe, 0x86, 0x23, 0xb7, 0x0e, 0xbd, 0x52, 0x8b, 0x1f, 0x4c, 0xd5, 0xad,
+	0xed, 0x99, 0xc3, 0xde, 0x04, 0xdf, 0x85, 0x55, 0xeb, 0x0e, 0xd4, 0xbb, 0xd4, 0x4d, 0x6c, 0x33,
+	0x1f, 0x8d, 0x8a, 0x0d, 0x08, 0xd2, 0x6f, 0x7e, 0xf6, 0xb8, 0x0a, 0xf2, 0xfa, 0x64, 0xd2, 0xdd,
+	0x98, 0xaf, 0xe2, 0x64, 0xb6, 0x7d, 0x06, 0xad, 0x9d, 0x3c, 0x29, 0x3f, 0x77, 0xdc, 0x0c, 0xd7,
+	0x9e, 0xb0, 0x19, 0xf6, 0x2a, 0xcd, 0xb7, 0x9d, 0x52, 0x83, 0xdf, 0xd7, 0xa0, 0x7d, 0xa4, 0xa8,
+	0x92, 0xa5, 0xfc, 0xab, 0xb0, 0x54, 0x48, 0x4c, 0x0c, 0xf3, 0xd2, 0x1b, 0x00, 0xdd, 0x56, 0x29,
+	0xe7, 0x78, 0x50, 0xc2, 0x58, 0x01, 0xaa, 0x65, 0x19, 0x0f, 0xc7, 0x08, 0x1d, 0x57, 0xfa, 0x05,
+	0x67, 0xa7, 0x49, 0x54, 0xae, 0xc9, 0x5b, 0x88, 0xdb, 0x37, 0x28, 0xd3, 0x4c, 0xe2, 0xcf, 0x30,
+	0xc5, 0x62, 0xe3, 0xb6, 0x38, 0x06, 0xa7, 0xeb, 0x4f, 0x20, 0x61, 0xfd, 0x7d, 0xa6, 0x44, 0x12,
+	0xc9, 0xaf, 0xc7, 0x10, 0x65, 0x03, 0x53, 0x1f, 0x37, 0x30, 0xc1, 0x3b, 0xd0, 0xde, 0x67, 0xdd,
+	0xa2, 0x3f, 0x77, 0xce, 0xf7, 0x61, 0x85, 0x65, 0xb8, 0xbe, 0x8c, 0xad, 0x61, 0x1c, 0x18, 0xfc,
+	0xaa, 0x06, 0x6b, 0xf7, 0x19, 0x4d, 0xd5, 0xe0, 0xbf, 0xa7, 0xf3, 0xa4, 0x33, 0x16, 0x75, 0x57,
+	0x3e, 0x46, 0x04, 0xff, 0xaa, 0xc1, 0xfa, 0xb1, 0xa0, 0xd1, 0x54, 0x0f, 0xca, 0xb2, 0x58, 0xc7,
+	0x9f, 0xeb, 0x41, 0x1d, 0xec, 0x3d, 0x84, 0x95, 0x01, 0xa3, 0x31, 0x13, 0xee, 0x11, 0x9f, 0xdd,
+	0x14, 0x4c, 0x4a, 0xed, 0xdc, 0x37, 0x6c, 0x26, 0xbd, 0x9c, 0x10, 0xb7, 0x11, 0x44, 0x57, 0xeb,
+	0x6d, 0x9c, 0xd1, 0xbc, 0x65, 0x71, 0x7a, 0x1d, 0x87, 0xfb, 0x3a, 0x3a, 0xcc, 0x53, 0x66, 0xca,
+	0x18, 0x86, 0x4b, 0x8d, 0x80, 0x41, 0x61, 0x0d, 0xdb, 0x7c, 0x13, 0x56, 0xab, 0xc2, 0x9f, 0x68,
+	0x73, 0xf9, 0x2e, 0x3c, 0xad, 0xff, 0xaf, 0x90, 0x9f, 0xb0, 0xee, 0x80, 0xf3, 0x93, 0xd2, 0x08,
+	0x1b, 0x50, 0xc7, 0x3d, 0xbb, 0x15, 0x52, 0x88, 0x14, 0x85, 0xa8, 0x51, 0x5e, 0x6e, 0x2d, 0x0d,
+	0x10, 0xfc, 0x08, 0x3c, 0x23, 0xe0, 0xee, 0x39, 0x8b, 0xaa, 0x5b, 0x3f, 0xb7, 0xdb, 0xab, 0x5d,
+	0xbc, 0xdb, 0x5b, 0xa8, 0xec, 0xf6, 0x4a, 0xc9, 0xf5, 0xaa, 0xe4, 0x3f, 0x2c, 0xc0, 0x9a, 0x11,
+	0x5d, 0x8a, 0x7d, 0x00, 0x8d, 0x33, 0xa3, 0xa7, 0xab, 0x6d, 0xaf, 0xcc, 0x32, 0xff, 0x85, 0x5f,
+	0x45, 0x4a, 0x76, 0xef, 0xfb, 0xb0, 0xc4, 0xce, 0x59, 0xe4, 0xdc, 0xf8, 0xd2, 0x7c, 0x39, 0xd5,
+	0x8f, 0x23, 0x86, 0x11, 0x77, 0xf1, 0xb4, 0x50, 0x83, 0xd0, 0x56, 0x78, 0x9b, 0xaa, 0xa6, 0x84,
+	0x6f, 0xe0, 0xc9, 0x3d, 0x73, 0xa0, 0xf3, 0xd5, 0xeb, 0xc0, 0x53, 0x13, 0xd4, 0xf6, 0x2d, 0x30,
+	0x15, 0xfd, 0x4a, 0x85, 0xfc, 0x13, 0x7d, 0xe0, 0xbd, 0x02, 0x5e, 0xf5, 0x5f, 0x19, 0x76, 0x9e,
+	0x27, 0x62, 0x64, 0x0b, 0xc1, 0x95, 0xca, 0xc9, 0x5d, 0x7d, 0x10, 0xfc, 0xa2, 0x09, 0x8d, 0xb9,
+	0x59, 0x79, 0x07, 0xea, 0xf8, 0xf7, 0xd5, 0x63, 0x0a, 0x79, 0xf5, 0x6f, 0x2b, 0xa4, 0xd7, 0x3b,
+	0x49, 0x33, 0xa2, 0xf9, 0xf5, 0xf9, 0xed, 0xd1, 0xd4, 0x24, 0x47, 0x1c, 0x9f, 0xf7, 0x32, 0xd4,
+	0xe3, 0xcc, 0xcd, 0x98, 0x9b, 0x55, 0x76, 0x9a, 0xe7, 0x9d, 0x38, 0x93, 0xe5, 0xc4, 0x10, 0x67,
+	0x7a, 0x09, 0x6a, 0xeb, 0xa8, 0xbf, 0x34, 0xff, 0xc2, 0xa9, 0x9d, 0x23, 0x71, 0x7c, 0xde, 0x3e,
+	0x34, 0x5c, 0x82, 0xdb, 0xfd, 0xc4, 0xed, 0x59, 0x32, 0xa6, 0xf7, 0x62, 0xa4, 0xe4, 0xf4, 0x3e,
+	0x86, 0x75, 0x2b, 0x30, 0x8c, 0xf5, 0xb0, 0x8e, 0x4d, 0xcf, 0xdc, 0x90, 0xbb, 0x70, 0xfb, 0x43,
+	0xd6, 0x92, 0x2a, 0x5a, 0x7a, 0x3f, 0x86, 0x8d, 0x72, 0xef, 0xe0, 0x04, 0x37, 0xb4, 0xe0, 0xce,
+	0xe3, 0xb4, 0x9c, 0x92, 0xbc, 0xce, 0x27, 0xf0, 0x12, 0xa7, 0xad, 0x72, 0x26, 0xf3, 0x9b, 0xf3,
+	0xa7, 0xad, 0x47, 0xe6, 0x43, 0x32, 0xe6, 0x45, 0x0b, 0x2a, 0xdb, 0xed, 0xfa, 0x30, 0xdf, 0x82,
+	0xd3, 0x63, 0x05, 0x29, 0x39, 0x31, 0xe4, 0x68, 0x9e, 0xf8, 0xad, 0xf9, 0x21, 0x57, 0x79, 0xca,
+	0x09, 0xd2, 0x7b, 0x6f, 0xc1, 0x92, 0xc4, 0x07, 0x58, 0x37, 0x6c, 0x73, 0xfe, 0x65, 0x9b, 0x78,
+	0xa5, 0x89, 0xe1, 0xc1, 0xf0, 0x19, 0x9a, 0x67, 0xd1, 0x6f, 0xcf, 0x0f, 0x9f, 0xa9, 0xd7, 0x93,
+	0x38, 0x3e, 0xbc, 0x3f, 0xc6, 0x47, 0xce, 0x5f, 0x9b, 0x7f, 0xff, 0xc4, 0x4b, 0x48, 0x0c, 0x0f,
+	0x2e, 0xda, 0xf5, 0xbf, 0xbd, 0xd2, 0x5f, 0x9f, 0xbf, 0x1a, 0x99, 0xac, 0x6c, 0xc4, 0x72, 0xa1,
+	0xfe, 0xca, 0x3c, 0x1c, 0xfe, 0xc6, 0x63, 0xc6, 0x91, 0xc9, 0xf7, 0x85, 0x38, 0x3e, 0x54, 0x61,
+	0xa0, 0x1f, 0x59, 0xff, 0xca, 0x7c, 0x15, 0x26, 0x9f, 0x62, 0x62, 0xb9, 0x90, 0x3f, 0xd7, 0x5d,
+	0x97, 0xef, 0xcd, 0xe7, 0x9f, 0x6c, 0x1f, 0x89, 0xe5, 0xda, 0x7d, 0x15, 0x36, 0x23, 0x3e, 0x9c,
+	0xc1, 0xb4, 0xdb, 0xb2, 0xf4, 0x82, 0x2b, 0xfe, 0xe9, 0x92, 0xc6, 0x75, 0x97, 0xf5, 0xc2, 0xe9,
+	0xdb, 0xff, 0x1e, 0x00, 0xf3, 0x10, 0x3c, 0xed, 0x0b, 0x23, 0x00, 0x00,
+}
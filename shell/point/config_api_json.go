@@ -0,0 +1,55 @@
+// +build json
+
+package point
+
+import (
+	"encoding/json"
+
+	"v2ray.com/core/common/protocol"
+	proxyregistry "v2ray.com/core/proxy/registry"
+)
+
+func jsonDecodeInboundDetour(data []byte) (*InboundDetourConfig, error) {
+	config := new(InboundDetourConfig)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func jsonDecodeOutboundDetour(data []byte) (*OutboundDetourConfig, error) {
+	config := new(OutboundDetourConfig)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// jsonDecodeInboundUsers decodes users as "clients" in the settings of an inbound.
+func jsonDecodeInboundUsers(protocolName string, users [][]byte) ([]*protocol.User, error) {
+	clients := make([]json.RawMessage, len(users))
+	for idx, user := range users {
+		clients[idx] = user
+	}
+	settings, err := json.Marshal(map[string]interface{}{"clients": clients})
+	if err != nil {
+		return nil, err
+	}
+	config, err := proxyregistry.CreateInboundConfig(protocolName, settings)
+	if err != nil {
+		return nil, err
+	}
+	userConfig, ok := config.(interface {
+		GetUser() []*protocol.User
+	})
+	if !ok {
+		return nil, ErrUsersNotSupported
+	}
+	return userConfig.GetUser(), nil
+}
+
+func init() {
+	inboundDetourDecoder = jsonDecodeInboundDetour
+	outboundDetourDecoder = jsonDecodeOutboundDetour
+	inboundUserDecoder = jsonDecodeInboundUsers
+}
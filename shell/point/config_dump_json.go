@@ -0,0 +1,109 @@
+// +build json
+
+package point
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+var (
+	logLevelNames = map[log.LogLevel]string{
+		log.DebugLevel:   "debug",
+		log.InfoLevel:    "info",
+		log.WarningLevel: "warning",
+		log.ErrorLevel:   "error",
+		log.NoneLevel:    "none",
+	}
+)
+
+// objectField returns the object in the given field of object, and creates it if it doesn't exist.
+func objectField(object map[string]interface{}, key string) map[string]interface{} {
+	field, ok := object[key].(map[string]interface{})
+	if !ok {
+		field = make(map[string]interface{})
+		object[key] = field
+	}
+	return field
+}
+
+func portRangeValue(portRange v2net.PortRange) interface{} {
+	if portRange.From == portRange.To {
+		return portRange.From
+	}
+	return fmt.Sprint(portRange.From, "-", portRange.To)
+}
+
+// fillConfigDefaults sets the fields of a config document that are omitted or in legacy forms to the values in config,
+// which is parsed from the document.
+func fillConfigDefaults(document map[string]interface{}, config *Config) {
+	logConfig := objectField(document, "log")
+	logLevel := log.WarningLevel
+	if config.LogConfig != nil {
+		logLevel = config.LogConfig.LogLevel
+	}
+	logConfig["loglevel"] = logLevelNames[logLevel]
+
+	inbound := objectField(document, "inbound")
+	inbound["port"] = inboundPort(config)
+	inbound["listen"] = config.InboundConfig.ListenOn.String()
+	delete(document, "port")
+
+	if detours, ok := document["inboundDetour"].([]interface{}); ok && len(detours) == len(config.InboundDetours) {
+		for idx, rawDetour := range detours {
+			detour, ok := rawDetour.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			detourConfig := config.InboundDetours[idx]
+			detour["port"] = portRangeValue(detourConfig.PortRange)
+			detour["listen"] = detourConfig.ListenOn.String()
+			detour["allocate"] = map[string]interface{}{
+				"strategy":    detourConfig.Allocation.Strategy,
+				"concurrency": detourConfig.Allocation.Concurrency,
+				"refresh":     detourConfig.Allocation.Refresh,
+			}
+		}
+	}
+
+	if _, found := document["dns"]; !found {
+		document["dns"] = map[string]interface{}{
+			"servers": []interface{}{"localhost"},
+		}
+	}
+}
+
+// jsonDumpConfig returns the effective config of config files in JSON, with merges, includes and environment
+// variables applied, and defaults of point settings filled in. Keys are sorted. Secret files are not read, so that
+// secrets are not dumped.
+func jsonDumpConfig(files ...string) ([]byte, error) {
+	document, err := loadConfigDocument(files...)
+	if err != nil {
+		return nil, err
+	}
+	rawDocument, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	// The document to parse is a copy, as secrets are read into it.
+	parsed, err := decodeJSONConfig(rawDocument)
+	if err != nil {
+		return nil, err
+	}
+	config, err := parseConfigDocument(parsed)
+	if err != nil {
+		return nil, err
+	}
+	if object, ok := document.(map[string]interface{}); ok {
+		fillConfigDefaults(object, config)
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func init() {
+	configDumper = jsonDumpConfig
+}
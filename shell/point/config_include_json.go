@@ -0,0 +1,134 @@
+// +build json
+
+package point
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+const (
+	// includeKey is the key of the include directive. An object of {"$include": "users.json"} is replaced by the
+	// document in users.json, with the other fields of the object merged into it.
+	includeKey = "$include"
+)
+
+// decodeConfigFile reads a config file into a JSON document, with its include directives resolved. Files of unknown
+// extensions are read as JSON. Includes of remote configs are relative to the working directory.
+func decodeConfigFile(file string, including []string) (interface{}, error) {
+	absFile, dir := file, "."
+	if !isRemoteConfig(file) {
+		var err error
+		absFile, err = filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Dir(file)
+	}
+	for _, parent := range including {
+		if parent == absFile {
+			return nil, errors.New("Point: Config file is included recursively: " + file)
+		}
+	}
+
+	rawConfig, err := readConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	decode := decodeJSONConfig
+	if format := configFormatOf(file); format != nil {
+		decode = format.decoder
+	}
+	document, err := decode(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+	including = append(append([]string(nil), including...), absFile)
+	return resolveIncludes(normalizeDocument(document), dir, including)
+}
+
+// loadIncludes loads the files in an include directive, which is either a path or a list of paths. Relative paths are
+// relative to the directory of the including file. Multiple files are merged in order with mergeJSON.
+func loadIncludes(value interface{}, dir string, including []string) (interface{}, error) {
+	var files []string
+	switch value := value.(type) {
+	case string:
+		files = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			file, ok := item.(string)
+			if !ok {
+				return nil, errors.New("Point: Invalid " + includeKey + " directive.")
+			}
+			files = append(files, file)
+		}
+	default:
+		return nil, errors.New("Point: Invalid " + includeKey + " directive.")
+	}
+
+	var merged interface{}
+	for _, file := range files {
+		if !filepath.IsAbs(file) && !isRemoteConfig(file) {
+			file = filepath.Join(dir, file)
+		}
+		document, err := decodeConfigFile(file, including)
+		if err != nil {
+			return nil, errors.New("Point: Failed to include " + file + ": " + err.Error())
+		}
+		merged = mergeJSON(merged, document)
+	}
+	return merged, nil
+}
+
+// resolveIncludes replaces include directives in a JSON document with the documents they refer to. An element of an
+// array that only includes another array is replaced by the elements of the included array.
+func resolveIncludes(document interface{}, dir string, including []string) (interface{}, error) {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if key == includeKey {
+				continue
+			}
+			resolved, err := resolveIncludes(field, dir, including)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = resolved
+		}
+		directive, found := value[includeKey]
+		if !found {
+			return value, nil
+		}
+		delete(value, includeKey)
+		included, err := loadIncludes(directive, dir, including)
+		if err != nil {
+			return nil, err
+		}
+		if len(value) == 0 {
+			return included, nil
+		}
+		if _, ok := included.(map[string]interface{}); !ok {
+			return nil, errors.New("Point: Only objects can be included along with other fields.")
+		}
+		return mergeJSON(included, value), nil
+	case []interface{}:
+		resolved := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			object, ok := item.(map[string]interface{})
+			_, isInclude := object[includeKey]
+			includeOnly := ok && isInclude && len(object) == 1
+			resolvedItem, err := resolveIncludes(item, dir, including)
+			if err != nil {
+				return nil, err
+			}
+			if items, isArray := resolvedItem.([]interface{}); includeOnly && isArray {
+				resolved = append(resolved, items...)
+				continue
+			}
+			resolved = append(resolved, resolvedItem)
+		}
+		return resolved, nil
+	default:
+		return document, nil
+	}
+}
@@ -3,24 +3,48 @@
 package point
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"os"
 	"strings"
 
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/app/debug"
 	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/events"
+	"v2ray.com/core/app/health"
+	"v2ray.com/core/app/metrics"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/app/router"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/app/throttle"
+	"v2ray.com/core/app/tracing"
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	proxyregistry "v2ray.com/core/proxy/registry"
 	"v2ray.com/core/transport"
 	"v2ray.com/core/transport/internet"
 )
 
-const (
-	DefaultRefreshMinute = int(9999)
-)
+// checkSettings reports unknown fields in the settings of a protocol in strict mode. Other problems of the settings are
+// reported when the handler is created.
+func checkSettings(createConfig func(string, []byte) (interface{}, error), protocol string, settings []byte) error {
+	if !loader.StrictJSON() || len(settings) == 0 {
+		return nil
+	}
+	if _, err := createConfig(protocol, settings); err != nil {
+		if unknown, ok := err.(*loader.UnknownFieldError); ok {
+			return unknown.Under("settings")
+		}
+	}
+	return nil
+}
 
 func (this *Config) UnmarshalJSON(data []byte) error {
 	type JsonConfig struct {
@@ -33,10 +57,19 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		InboundDetours  []*InboundDetourConfig    `json:"inboundDetour"`
 		OutboundDetours []*OutboundDetourConfig   `json:"outboundDetour"`
 		Transport       *transport.Config         `json:"transport"`
+		Throttle        *throttle.Config          `json:"throttle"`
+		Api             *api.Config               `json:"api"`
+		Stats           *stats.Config             `json:"stats"`
+		Metrics         *metrics.Config           `json:"metrics"`
+		Debug           *debug.Config             `json:"debug"`
+		Events          *events.Config            `json:"events"`
+		Tracing         *tracing.Config           `json:"tracing"`
+		Health          *health.Config            `json:"health"`
+		Policy          *policy.Config            `json:"policy"`
 	}
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Point: Failed to parse config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Point: Failed to parse config: ", err)
 	}
 	this.Port = jsonConfig.Port
 	this.LogConfig = jsonConfig.LogConfig
@@ -54,36 +87,38 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 	this.InboundDetours = jsonConfig.InboundDetours
 	this.OutboundDetours = jsonConfig.OutboundDetours
 	if jsonConfig.DNSConfig == nil {
-		jsonConfig.DNSConfig = &dns.Config{
-			NameServers: []*v2net.DestinationPB{{
-				Network: v2net.Network_UDP,
-				Address: &v2net.AddressPB{
-					Address: &v2net.AddressPB_Domain{
-						Domain: "localhost",
-					},
-				},
-				Port: 53,
-			}},
-		}
+		jsonConfig.DNSConfig = defaultDNSConfig()
 	}
 	this.DNSConfig = jsonConfig.DNSConfig
 	this.TransportConfig = jsonConfig.Transport
+	this.ThrottleConfig = jsonConfig.Throttle
+	this.ApiConfig = jsonConfig.Api
+	this.StatsConfig = jsonConfig.Stats
+	this.MetricsConfig = jsonConfig.Metrics
+	this.DebugConfig = jsonConfig.Debug
+	this.EventsConfig = jsonConfig.Events
+	this.TracingConfig = jsonConfig.Tracing
+	this.HealthConfig = jsonConfig.Health
+	this.PolicyConfig = jsonConfig.Policy
 	return nil
 }
 
 func (this *InboundConnectionConfig) UnmarshalJSON(data []byte) error {
 	type JsonConfig struct {
-		Port          uint16                   `json:"port"`
-		Listen        *v2net.AddressPB         `json:"listen"`
-		Protocol      string                   `json:"protocol"`
-		StreamSetting *internet.StreamSettings `json:"streamSettings"`
-		Settings      json.RawMessage          `json:"settings"`
-		AllowPassive  bool                     `json:"allowPassive"`
+		Port            uint16                   `json:"port"`
+		Listen          *v2net.AddressPB         `json:"listen"`
+		Protocol        string                   `json:"protocol"`
+		StreamSetting   *internet.StreamSettings `json:"streamSettings"`
+		Settings        json.RawMessage          `json:"settings"`
+		AllowPassive    bool                     `json:"allowPassive"`
+		DefaultOutbound string                   `json:"defaultOutboundTag"`
+		AllowSources    []string                 `json:"allowSources"`
+		DenySources     []string                 `json:"denySources"`
 	}
 
 	jsonConfig := new(JsonConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Point: Failed to parse inbound config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Point: Failed to parse inbound config: ", err)
 	}
 	this.Port = v2net.Port(jsonConfig.Port)
 	this.ListenOn = v2net.AnyIP
@@ -99,7 +134,16 @@ func (this *InboundConnectionConfig) UnmarshalJSON(data []byte) error {
 
 	this.Protocol = jsonConfig.Protocol
 	this.Settings = jsonConfig.Settings
+	if err := checkSettings(proxyregistry.CreateInboundConfig, this.Protocol, this.Settings); err != nil {
+		return err
+	}
 	this.AllowPassiveConnection = jsonConfig.AllowPassive
+	this.DefaultOutboundTag = jsonConfig.DefaultOutbound
+	filter, err := v2net.NewIPFilter(jsonConfig.AllowSources, jsonConfig.DenySources)
+	if err != nil {
+		return err
+	}
+	this.SourceFilter = filter
 	return nil
 }
 
@@ -111,11 +155,14 @@ func (this *OutboundConnectionConfig) UnmarshalJSON(data []byte) error {
 		Settings      json.RawMessage          `json:"settings"`
 	}
 	jsonConfig := new(JsonConnectionConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Point: Failed to parse outbound config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Point: Failed to parse outbound config: ", err)
 	}
 	this.Protocol = jsonConfig.Protocol
 	this.Settings = jsonConfig.Settings
+	if err := checkSettings(proxyregistry.CreateOutboundConfig, this.Protocol, this.Settings); err != nil {
+		return err
+	}
 
 	if jsonConfig.SendThrough != nil {
 		address := jsonConfig.SendThrough.AsAddress()
@@ -130,76 +177,157 @@ func (this *OutboundConnectionConfig) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// jsonLogConfig is the log config in JSON, which is also encoded from a LogConfigPB.
+type jsonLogConfig struct {
+	AccessLog       string              `json:"access,omitempty"`
+	ErrorLog        string              `json:"error,omitempty"`
+	LogLevel        string              `json:"loglevel,omitempty"`
+	AccessHostnames bool                `json:"accessHostnames,omitempty"`
+	AccessFormat    string              `json:"accessFormat,omitempty"`
+	Levels          map[string]string   `json:"levels,omitempty"`
+	Rotation        *jsonRotationConfig `json:"rotation,omitempty"`
+	Syslog          *jsonSyslogConfig   `json:"syslog,omitempty"`
+	EventLog        *jsonEventLogConfig `json:"eventLog,omitempty"`
+}
+
+type jsonRotationConfig struct {
+	MaxSize    uint32 `json:"maxSize,omitempty"`
+	MaxAge     uint32 `json:"maxAge,omitempty"`
+	MaxBackups uint32 `json:"maxBackups,omitempty"`
+	Compress   bool   `json:"compress,omitempty"`
+}
+
+type jsonSyslogConfig struct {
+	Address  string `json:"address,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	Facility string `json:"facility,omitempty"`
+	Access   bool   `json:"access,omitempty"`
+}
+
+type jsonEventLogConfig struct {
+	Source string `json:"source,omitempty"`
+	Access bool   `json:"access,omitempty"`
+}
+
+func newJSONLogConfig(config *LogConfigPB) *jsonLogConfig {
+	jsonConfig := &jsonLogConfig{
+		AccessLog:       config.Access,
+		ErrorLog:        config.Error,
+		LogLevel:        config.Level,
+		AccessHostnames: config.AccessHostnames,
+		AccessFormat:    config.AccessFormat,
+		Levels:          config.Levels,
+	}
+	if rotation := config.Rotation; rotation != nil {
+		jsonConfig.Rotation = &jsonRotationConfig{
+			MaxSize:    rotation.MaxSize,
+			MaxAge:     rotation.MaxAge,
+			MaxBackups: rotation.MaxBackups,
+			Compress:   rotation.Compress,
+		}
+	}
+	if syslog := config.Syslog; syslog != nil {
+		jsonConfig.Syslog = &jsonSyslogConfig{
+			Address:  syslog.Address,
+			Tag:      syslog.Tag,
+			Facility: syslog.Facility,
+			Access:   syslog.Access,
+		}
+	}
+	if eventLog := config.EventLog; eventLog != nil {
+		jsonConfig.EventLog = &jsonEventLogConfig{
+			Source: eventLog.Source,
+			Access: eventLog.Access,
+		}
+	}
+	return jsonConfig
+}
+
+func (this *jsonLogConfig) toPB() *LogConfigPB {
+	config := &LogConfigPB{
+		Access:          this.AccessLog,
+		Error:           this.ErrorLog,
+		Level:           this.LogLevel,
+		AccessHostnames: this.AccessHostnames,
+		AccessFormat:    this.AccessFormat,
+		Levels:          this.Levels,
+	}
+	if rotation := this.Rotation; rotation != nil {
+		config.Rotation = &LogRotationConfigPB{
+			MaxSize:    rotation.MaxSize,
+			MaxAge:     rotation.MaxAge,
+			MaxBackups: rotation.MaxBackups,
+			Compress:   rotation.Compress,
+		}
+	}
+	if syslog := this.Syslog; syslog != nil {
+		config.Syslog = &LogSyslogConfigPB{
+			Address:  syslog.Address,
+			Tag:      syslog.Tag,
+			Facility: syslog.Facility,
+			Access:   syslog.Access,
+		}
+	}
+	if eventLog := this.EventLog; eventLog != nil {
+		config.EventLog = &LogEventLogConfigPB{
+			Source: eventLog.Source,
+			Access: eventLog.Access,
+		}
+	}
+	return config
+}
+
 func (this *LogConfig) UnmarshalJSON(data []byte) error {
-	type JsonLogConfig struct {
-		AccessLog string `json:"access"`
-		ErrorLog  string `json:"error"`
-		LogLevel  string `json:"loglevel"`
-	}
-	jsonConfig := new(JsonLogConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Point: Failed to parse log config: " + err.Error())
-	}
-	this.AccessLog = jsonConfig.AccessLog
-	this.ErrorLog = jsonConfig.ErrorLog
-
-	level := strings.ToLower(jsonConfig.LogLevel)
-	switch level {
-	case "debug":
-		this.LogLevel = log.DebugLevel
-	case "info":
-		this.LogLevel = log.InfoLevel
-	case "error":
-		this.LogLevel = log.ErrorLevel
-	case "none":
-		this.LogLevel = log.NoneLevel
-	default:
-		this.LogLevel = log.WarningLevel
+	jsonConfig := new(jsonLogConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Point: Failed to parse log config: ", err)
+	}
+	logConfig, err := jsonConfig.toPB().Build()
+	if err != nil {
+		return err
 	}
+	*this = *logConfig
 	return nil
 }
 
+// jsonAllocationConfig is the allocation config of an inbound detour in JSON.
+type jsonAllocationConfig struct {
+	Strategy    string `json:"strategy,omitempty"`
+	Concurrency uint32 `json:"concurrency,omitempty"`
+	RefreshMin  uint32 `json:"refresh,omitempty"`
+}
+
 func (this *InboundDetourAllocationConfig) UnmarshalJSON(data []byte) error {
-	type JsonInboundDetourAllocationConfig struct {
-		Strategy    string `json:"strategy"`
-		Concurrency int    `json:"concurrency"`
-		RefreshMin  int    `json:"refresh"`
-	}
-	jsonConfig := new(JsonInboundDetourAllocationConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Point: Failed to parse inbound detour allocation config: " + err.Error())
-	}
-	this.Strategy = jsonConfig.Strategy
-	this.Concurrency = jsonConfig.Concurrency
-	this.Refresh = jsonConfig.RefreshMin
-	if this.Strategy == AllocationStrategyRandom {
-		if this.Refresh == 0 {
-			this.Refresh = 5
-		}
-		if this.Concurrency == 0 {
-			this.Concurrency = 3
-		}
+	jsonConfig := new(jsonAllocationConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Point: Failed to parse inbound detour allocation config: ", err)
 	}
-	if this.Refresh == 0 {
-		this.Refresh = DefaultRefreshMinute
+	config := &AllocationConfigPB{
+		Strategy:    jsonConfig.Strategy,
+		Concurrency: jsonConfig.Concurrency,
+		Refresh:     jsonConfig.RefreshMin,
 	}
+	*this = *config.Build()
 	return nil
 }
 
 func (this *InboundDetourConfig) UnmarshalJSON(data []byte) error {
 	type JsonInboundDetourConfig struct {
-		Protocol      string                         `json:"protocol"`
-		PortRange     *v2net.PortRange               `json:"port"`
-		ListenOn      *v2net.AddressPB               `json:"listen"`
-		Settings      json.RawMessage                `json:"settings"`
-		Tag           string                         `json:"tag"`
-		Allocation    *InboundDetourAllocationConfig `json:"allocate"`
-		StreamSetting *internet.StreamSettings       `json:"streamSettings"`
-		AllowPassive  bool                           `json:"allowPassive"`
+		Protocol        string                         `json:"protocol"`
+		PortRange       *v2net.PortRange               `json:"port"`
+		ListenOn        *v2net.AddressPB               `json:"listen"`
+		Settings        json.RawMessage                `json:"settings"`
+		Tag             string                         `json:"tag"`
+		Allocation      *InboundDetourAllocationConfig `json:"allocate"`
+		StreamSetting   *internet.StreamSettings       `json:"streamSettings"`
+		AllowPassive    bool                           `json:"allowPassive"`
+		DefaultOutbound string                         `json:"defaultOutboundTag"`
+		AllowSources    []string                       `json:"allowSources"`
+		DenySources     []string                       `json:"denySources"`
 	}
 	jsonConfig := new(JsonInboundDetourConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Point: Failed to parse inbound detour config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Point: Failed to parse inbound detour config: ", err)
 	}
 	if jsonConfig.PortRange == nil {
 		log.Error("Point: Port range not specified in InboundDetour.")
@@ -215,6 +343,9 @@ func (this *InboundDetourConfig) UnmarshalJSON(data []byte) error {
 	this.Protocol = jsonConfig.Protocol
 	this.PortRange = *jsonConfig.PortRange
 	this.Settings = jsonConfig.Settings
+	if err := checkSettings(proxyregistry.CreateInboundConfig, this.Protocol, this.Settings); err != nil {
+		return err
+	}
 	this.Tag = jsonConfig.Tag
 	this.Allocation = jsonConfig.Allocation
 	if this.Allocation == nil {
@@ -227,6 +358,12 @@ func (this *InboundDetourConfig) UnmarshalJSON(data []byte) error {
 		this.StreamSettings = jsonConfig.StreamSetting
 	}
 	this.AllowPassiveConnection = jsonConfig.AllowPassive
+	this.DefaultOutboundTag = jsonConfig.DefaultOutbound
+	filter, err := v2net.NewIPFilter(jsonConfig.AllowSources, jsonConfig.DenySources)
+	if err != nil {
+		return err
+	}
+	this.SourceFilter = filter
 	return nil
 }
 
@@ -239,12 +376,15 @@ func (this *OutboundDetourConfig) UnmarshalJSON(data []byte) error {
 		StreamSetting *internet.StreamSettings `json:"streamSettings"`
 	}
 	jsonConfig := new(JsonOutboundDetourConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
-		return errors.New("Point: Failed to parse outbound detour config: " + err.Error())
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Point: Failed to parse outbound detour config: ", err)
 	}
 	this.Protocol = jsonConfig.Protocol
 	this.Tag = jsonConfig.Tag
 	this.Settings = jsonConfig.Settings
+	if err := checkSettings(proxyregistry.CreateOutboundConfig, this.Protocol, this.Settings); err != nil {
+		return err
+	}
 
 	if jsonConfig.SendThrough != nil {
 		address := jsonConfig.SendThrough.AsAddress()
@@ -260,24 +400,228 @@ func (this *OutboundDetourConfig) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func JsonLoadConfig(file string) (*Config, error) {
-	fixedFile := os.ExpandEnv(file)
-	rawConfig, err := ioutil.ReadFile(fixedFile)
+// mergeJSON merges the JSON document override into base. Objects are merged by key, arrays are appended, and other
+// values in override replace those in base. Documents are decoded with UseNumber.
+func mergeJSON(base interface{}, override interface{}) interface{} {
+	switch overrideValue := override.(type) {
+	case map[string]interface{}:
+		baseValue, ok := base.(map[string]interface{})
+		if !ok {
+			return override
+		}
+		for key, value := range overrideValue {
+			if baseField, found := baseValue[key]; found {
+				baseValue[key] = mergeJSON(baseField, value)
+			} else {
+				baseValue[key] = value
+			}
+		}
+		return baseValue
+	case []interface{}:
+		baseValue, ok := base.([]interface{})
+		if !ok {
+			return override
+		}
+		return append(baseValue, overrideValue...)
+	default:
+		return override
+	}
+}
+
+// normalizeDocument converts the maps and slices of a decoded document into map[string]interface{} and []interface{},
+// as decoders of other formats may produce e.g. map[interface{}]interface{}.
+func normalizeDocument(document interface{}) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			value[key] = normalizeDocument(field)
+		}
+		return value
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(value))
+		for key, field := range value {
+			normalized[fmt.Sprint(key)] = normalizeDocument(field)
+		}
+		return normalized
+	case []interface{}:
+		for idx, element := range value {
+			value[idx] = normalizeDocument(element)
+		}
+		return value
+	case []map[string]interface{}:
+		normalized := make([]interface{}, len(value))
+		for idx, element := range value {
+			normalized[idx] = normalizeDocument(element)
+		}
+		return normalized
+	default:
+		return document
+	}
+}
+
+func decodeJSONConfig(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// expandEnv replaces ${NAME} in s with the value of the environment variable NAME, and ${NAME:-default} with the
+// default if NAME is unset or empty. "$${" is kept as a literal "${".
+func expandEnv(s string) (string, error) {
+	var expanded bytes.Buffer
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			expanded.WriteString(s)
+			return expanded.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			expanded.WriteString(s[:start])
+			expanded.WriteString("{")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return "", errors.New("Point: Unclosed environment variable in " + s)
+		}
+		expanded.WriteString(s[:start])
+		name := s[start+2 : start+end]
+		defaultValue := ""
+		hasDefault := false
+		if idx := strings.Index(name, ":-"); idx >= 0 {
+			name, defaultValue, hasDefault = name[:idx], name[idx+2:], true
+		}
+		value, found := os.LookupEnv(name)
+		switch {
+		case len(value) > 0:
+		case hasDefault:
+			value = defaultValue
+		case !found:
+			return "", errors.New("Point: Environment variable " + name + " is not set.")
+		}
+		expanded.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
+
+// expandEnvDocument expands environment variables in the string values of a document. A string of a single variable
+// whose value is a number or a boolean becomes that value, so that e.g. ports can be set with variables.
+func expandEnvDocument(document interface{}) (interface{}, error) {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			expanded, err := expandEnvDocument(field)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = expanded
+		}
+		return value, nil
+	case []interface{}:
+		for idx, element := range value {
+			expanded, err := expandEnvDocument(element)
+			if err != nil {
+				return nil, err
+			}
+			value[idx] = expanded
+		}
+		return value, nil
+	case string:
+		expanded, err := expandEnv(value)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(value, "${") && strings.Index(value, "}") == len(value)-1 {
+			switch expanded {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+			var number float64
+			if err := json.Unmarshal([]byte(expanded), &number); err == nil {
+				return json.Number(expanded), nil
+			}
+		}
+		return expanded, nil
+	default:
+		return document, nil
+	}
+}
+
+// loadConfigDocument reads config files, or directories of them, and merges them in order with mergeJSON. Files in
+// formats other than JSON are converted to JSON documents first, and their include directives are resolved before the
+// merge.
+func loadConfigDocument(files ...string) (interface{}, error) {
+	files, err := expandConfigFiles(files)
+	if err != nil {
+		log.Error("Point: Failed to list config files: ", err)
+		return nil, err
+	}
+	if len(files) == 0 {
+		log.Error("Point: No config file is found.")
+		return nil, common.ErrBadConfiguration
+	}
+
+	var merged interface{}
+	for _, file := range files {
+		document, err := decodeConfigFile(file, nil)
+		if err != nil {
+			log.Error("Point: Failed to parse server config file (", file, "): ", err)
+			return nil, err
+		}
+		merged = mergeJSON(merged, document)
+	}
+
+	merged, err = expandEnvDocument(merged)
+	if err != nil {
+		log.Error("Point: Failed to expand environment variables in config: ", err)
+		return nil, err
+	}
+	return merged, nil
+}
+
+// parseConfigDocument parses a Config from a JSON document, after reading secrets from their files.
+func parseConfigDocument(document interface{}) (*Config, error) {
+	document, err := resolveSecretFiles(document)
 	if err != nil {
-		log.Error("Point: Failed to read server config file (", file, "): ", file, err)
+		log.Error("Point: Failed to read secrets: ", err)
 		return nil, err
 	}
 
+	rawConfig, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
 	jsonConfig := &Config{}
 	err = json.Unmarshal(rawConfig, jsonConfig)
 	if err != nil {
 		log.Error("Point: Failed to load server config: ", err)
 		return nil, err
 	}
+	// Keys of objects are sorted by json.Marshal, so the hash doesn't depend on their order in files.
+	hash := sha256.Sum256(rawConfig)
+	jsonConfig.Hash = hex.EncodeToString(hash[:])
 
 	return jsonConfig, err
 }
 
+// JsonLoadConfig loads a Config from config files as loadConfigDocument does. Secrets are read from their files, so
+// that they are read again on reload.
+func JsonLoadConfig(files ...string) (*Config, error) {
+	merged, err := loadConfigDocument(files...)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigDocument(merged)
+}
+
 func init() {
+	RegisterConfigFormat("json", []string{".json"}, decodeJSONConfig)
 	configLoader = JsonLoadConfig
 }
@@ -3,15 +3,27 @@
 package point_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
 
 	_ "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	_ "v2ray.com/core/proxy/blackhole"
+	_ "v2ray.com/core/proxy/freedom"
+	proxyregistry "v2ray.com/core/proxy/registry"
+	_ "v2ray.com/core/proxy/vmess/inbound"
 	. "v2ray.com/core/shell/point"
 
 	"v2ray.com/core/testing/assert"
+
+	"github.com/golang/protobuf/proto"
 )
 
 func TestClientSampleConfig(t *testing.T) {
@@ -73,3 +85,422 @@ func TestDefaultValueOfRandomAllocation(t *testing.T) {
 	assert.Int(inboundDetourConfig.Allocation.Concurrency).Equals(3)
 	assert.Int(inboundDetourConfig.Allocation.Refresh).Equals(5)
 }
+
+func TestDefaultOutboundOfInbound(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "protocol": "socks",
+    "port": 1080,
+    "tag": "rescue",
+    "settings": {},
+    "defaultOutboundTag": "direct"
+  }`
+
+	inboundDetourConfig := new(InboundDetourConfig)
+	err := json.Unmarshal([]byte(rawJson), inboundDetourConfig)
+	assert.Error(err).IsNil()
+	assert.String(inboundDetourConfig.DefaultOutboundTag).Equals("direct")
+
+	inboundConfig := new(InboundConnectionConfig)
+	err = json.Unmarshal([]byte(`{"protocol": "socks", "port": 1080, "settings": {}}`), inboundConfig)
+	assert.Error(err).IsNil()
+	assert.String(inboundConfig.DefaultOutboundTag).Equals("")
+}
+
+func TestSourcesOfInbound(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "protocol": "dokodemo-door",
+    "port": 1080,
+    "settings": {"address": "127.0.0.1", "port": 22},
+    "allowSources": ["192.168.0.0/16"],
+    "denySources": ["192.168.1.1"]
+  }`
+
+	inboundDetourConfig := new(InboundDetourConfig)
+	err := json.Unmarshal([]byte(rawJson), inboundDetourConfig)
+	assert.Error(err).IsNil()
+	assert.Bool(inboundDetourConfig.SourceFilter.Allows(net.ParseIP("192.168.2.1"))).IsTrue()
+	assert.Bool(inboundDetourConfig.SourceFilter.Allows(net.ParseIP("192.168.1.1"))).IsFalse()
+	assert.Bool(inboundDetourConfig.SourceFilter.Allows(net.ParseIP("8.8.8.8"))).IsFalse()
+
+	inboundConfig := new(InboundConnectionConfig)
+	err = json.Unmarshal([]byte(`{"protocol": "socks", "port": 1080, "settings": {}, "allowSources": ["bad"]}`), inboundConfig)
+	assert.Error(err).IsNotNil()
+}
+
+func TestLoadMultipleConfigs(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		assert.Error(ioutil.WriteFile(file, []byte(content), 0600)).IsNil()
+		return file
+	}
+	base := writeFile("base.json", `{
+    "log": {"loglevel": "warning"},
+    "inbound": {"port": 1080, "protocol": "socks", "settings": {"auth": "noauth"}},
+    "outbound": {"protocol": "freedom", "settings": {}},
+    "outboundDetour": [{"protocol": "blackhole", "tag": "blocked", "settings": {}}]
+  }`)
+	siteDir := filepath.Join(dir, "site")
+	assert.Error(os.Mkdir(siteDir, 0700)).IsNil()
+	writeFile(filepath.Join("site", "01.json"), `{
+    "inbound": {"port": 1081},
+    "outboundDetour": [{"protocol": "freedom", "tag": "direct", "settings": {}}]
+  }`)
+	writeFile(filepath.Join("site", "02.json"), `{"log": {"loglevel": "debug"}}`)
+
+	config, err := LoadConfig(base, siteDir)
+	assert.Error(err).IsNil()
+	assert.Port(config.InboundConfig.Port).Equals(v2net.Port(1081))
+	assert.String(config.InboundConfig.Protocol).Equals("socks")
+	assert.Int(len(config.OutboundDetours)).Equals(2)
+	assert.String(config.OutboundDetours[0].Tag).Equals("blocked")
+	assert.String(config.OutboundDetours[1].Tag).Equals("direct")
+	assert.Bool(config.LogConfig.LogLevel == log.DebugLevel).IsTrue()
+}
+
+func TestConfigFormat(t *testing.T) {
+	assert := assert.On(t)
+
+	assert.Error(RegisterConfigFormat("test", []string{".test"}, func(data []byte) (interface{}, error) {
+		return map[interface{}]interface{}{
+			"inbound": map[interface{}]interface{}{
+				"port":     json.Number(string(data)),
+				"protocol": "socks",
+				"settings": map[interface{}]interface{}{},
+			},
+			"outbound": map[interface{}]interface{}{
+				"protocol": "freedom",
+				"settings": map[interface{}]interface{}{},
+			},
+		}, nil
+	})).IsNil()
+	assert.Error(RegisterConfigFormat("test", nil, nil)).IsNotNil()
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.test")
+	assert.Error(ioutil.WriteFile(file, []byte("1080"), 0600)).IsNil()
+	config, err := LoadConfig(dir)
+	assert.Error(err).IsNil()
+	assert.Port(config.InboundConfig.Port).Equals(v2net.Port(1080))
+	assert.String(config.OutboundConfig.Protocol).Equals("freedom")
+
+	jsonFile := filepath.Join(dir, "config.conf")
+	assert.Error(ioutil.WriteFile(jsonFile, []byte("1081"), 0600)).IsNil()
+	_, err = LoadConfig(jsonFile)
+	assert.Error(err).IsNotNil()
+
+	assert.Error(SetConfigFormat("test")).IsNil()
+	defer SetConfigFormat("auto")
+	config, err = LoadConfig(jsonFile)
+	assert.Error(err).IsNil()
+	assert.Port(config.InboundConfig.Port).Equals(v2net.Port(1081))
+
+	assert.Error(SetConfigFormat("unknown")).IsNotNil()
+}
+
+func TestEnvInConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	assert.Error(os.Setenv("V2RAY_TEST_PORT", "1082")).IsNil()
+	assert.Error(os.Setenv("V2RAY_TEST_PROTOCOL", "freedom")).IsNil()
+	defer os.Unsetenv("V2RAY_TEST_PORT")
+	defer os.Unsetenv("V2RAY_TEST_PROTOCOL")
+	os.Unsetenv("V2RAY_TEST_UNSET")
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	assert.Error(ioutil.WriteFile(file, []byte(`{
+    "inbound": {"port": "${V2RAY_TEST_PORT}", "protocol": "socks", "settings": {}},
+    "outbound": {"protocol": "${V2RAY_TEST_PROTOCOL}", "settings": {}},
+    "outboundDetour": [{"protocol": "blackhole", "tag": "${V2RAY_TEST_UNSET:-blocked}-$${V2RAY_TEST_PORT}", "settings": {}}]
+  }`), 0600)).IsNil()
+	config, err := LoadConfig(file)
+	assert.Error(err).IsNil()
+	assert.Port(config.InboundConfig.Port).Equals(v2net.Port(1082))
+	assert.String(config.OutboundConfig.Protocol).Equals("freedom")
+	assert.String(config.OutboundDetours[0].Tag).Equals("blocked-${V2RAY_TEST_PORT}")
+
+	assert.Error(ioutil.WriteFile(file, []byte(`{
+    "inbound": {"port": "${V2RAY_TEST_UNSET}", "protocol": "socks", "settings": {}},
+    "outbound": {"protocol": "freedom", "settings": {}}
+  }`), 0600)).IsNil()
+	_, err = LoadConfig(file)
+	assert.Error(err).IsNotNil()
+}
+
+func TestConfigInclude(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+	assert.Error(os.Mkdir(filepath.Join(dir, "detours"), 0700)).IsNil()
+
+	files := map[string]string{
+		"config.json": `{
+    "inbound": {"port": 1080, "protocol": "socks", "settings": {}},
+    "outbound": {"$include": "outbound.json", "settings": {"timeout": 10}},
+    "outboundDetour": [
+      {"protocol": "blackhole", "tag": "a", "settings": {}},
+      {"$include": "detours/detours.json"}
+    ]
+  }`,
+		"outbound.json":           `{"protocol": "freedom", "settings": {"domainStrategy": "AsIs"}}`,
+		"detours/detours.json":    `[{"protocol": "freedom", "tag": "b", "settings": {}}, {"$include": "detour.json"}]`,
+		"detours/detour.json":     `{"protocol": "freedom", "tag": "c", "settings": {}}`,
+		"recursive.json":          `{"inbound": {"$include": "recursive.json"}}`,
+		"included_not_found.json": `{"inbound": {"$include": "not_found.json"}}`,
+	}
+	for name, content := range files {
+		assert.Error(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).IsNil()
+	}
+
+	config, err := LoadConfig(filepath.Join(dir, "config.json"))
+	assert.Error(err).IsNil()
+	assert.String(config.OutboundConfig.Protocol).Equals("freedom")
+	assert.String(string(config.OutboundConfig.Settings)).Equals(`{"domainStrategy":"AsIs","timeout":10}`)
+	assert.Int(len(config.OutboundDetours)).Equals(3)
+	assert.String(config.OutboundDetours[1].Tag).Equals("b")
+	assert.String(config.OutboundDetours[2].Tag).Equals("c")
+
+	_, err = LoadConfig(filepath.Join(dir, "recursive.json"))
+	assert.Error(err).IsNotNil()
+	_, err = LoadConfig(filepath.Join(dir, "included_not_found.json"))
+	assert.Error(err).IsNotNil()
+}
+
+func TestSecretFiles(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	idFile := filepath.Join(dir, "id")
+	assert.Error(ioutil.WriteFile(idFile, []byte("d17a1af7-efa5-42ca-b7e9-6a35282d737f\n"), 0600)).IsNil()
+	file := filepath.Join(dir, "config.json")
+	assert.Error(ioutil.WriteFile(file, []byte(`{
+    "inbound": {"port": 1080, "protocol": "vmess", "settings": {"clients": [{"idFile": "`+idFile+`"}]}},
+    "outbound": {"protocol": "freedom", "settings": {}}
+  }`), 0600)).IsNil()
+	config, err := LoadConfig(file)
+	assert.Error(err).IsNil()
+	assert.String(string(config.InboundConfig.Settings)).Equals(`{"clients":[{"id":"d17a1af7-efa5-42ca-b7e9-6a35282d737f"}]}`)
+
+	assert.Error(ioutil.WriteFile(file, []byte(`{
+    "inbound": {"port": 1080, "protocol": "vmess", "settings": {"clients": [{"idFile": "`+idFile+`", "id": "a"}]}},
+    "outbound": {"protocol": "freedom", "settings": {}}
+  }`), 0600)).IsNil()
+	_, err = LoadConfig(file)
+	assert.Error(err).IsNotNil()
+
+	assert.Error(os.Remove(idFile)).IsNil()
+	_, err = LoadConfig(file)
+	assert.Error(err).IsNotNil()
+}
+
+func compactJSON(data []byte) string {
+	buffer := new(bytes.Buffer)
+	if err := json.Compact(buffer, data); err != nil {
+		panic(err)
+	}
+	return buffer.String()
+}
+
+func TestDumpConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	idFile := filepath.Join(dir, "id")
+	assert.Error(ioutil.WriteFile(idFile, []byte("d17a1af7-efa5-42ca-b7e9-6a35282d737f"), 0600)).IsNil()
+	file := filepath.Join(dir, "config.json")
+	assert.Error(ioutil.WriteFile(file, []byte(`{
+    "port": 1080,
+    "inbound": {"protocol": "vmess", "settings": {"clients": [{"idFile": "`+idFile+`"}]}},
+    "outbound": {"protocol": "freedom", "settings": {}},
+    "inboundDetour": [{"port": "2000-2010", "protocol": "socks", "settings": {}, "allocate": {"strategy": "random"}}]
+  }`), 0600)).IsNil()
+
+	content, err := DumpConfig(file)
+	assert.Error(err).IsNil()
+	var dumped struct {
+		Port    *int `json:"port"`
+		Inbound struct {
+			Port     int             `json:"port"`
+			Listen   string          `json:"listen"`
+			Settings json.RawMessage `json:"settings"`
+		} `json:"inbound"`
+		InboundDetour []struct {
+			Port     string                         `json:"port"`
+			Allocate *InboundDetourAllocationConfig `json:"allocate"`
+		} `json:"inboundDetour"`
+		Log struct {
+			LogLevel string `json:"loglevel"`
+		} `json:"log"`
+		DNS json.RawMessage `json:"dns"`
+	}
+	assert.Error(json.Unmarshal(content, &dumped)).IsNil()
+	assert.Bool(dumped.Port == nil).IsTrue()
+	assert.Int(dumped.Inbound.Port).Equals(1080)
+	assert.String(dumped.Inbound.Listen).Equals("0.0.0.0")
+	assert.String(compactJSON(dumped.Inbound.Settings)).Equals(`{"clients":[{"idFile":"` + idFile + `"}]}`)
+	assert.String(dumped.InboundDetour[0].Port).Equals("2000-2010")
+	assert.Int(dumped.InboundDetour[0].Allocate.Concurrency).Equals(3)
+	assert.Int(dumped.InboundDetour[0].Allocate.Refresh).Equals(5)
+	assert.String(dumped.Log.LogLevel).Equals("warning")
+	assert.String(compactJSON(dumped.DNS)).Equals(`{"servers":["localhost"]}`)
+}
+
+func TestConfigPB(t *testing.T) {
+	assert := assert.On(t)
+
+	GOPATH := os.Getenv("GOPATH")
+	jsonFile := filepath.Join(GOPATH, "src", "v2ray.com", "core", "tools", "release", "config", "vpoint_vmess_freedom.json")
+
+	configPB, err := ConvertConfigToPB(jsonFile)
+	assert.Error(err).IsNil()
+	rawConfig, err := proto.Marshal(configPB)
+	assert.Error(err).IsNil()
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+	pbFile := filepath.Join(dir, "config.pb")
+	assert.Error(ioutil.WriteFile(pbFile, rawConfig, 0600)).IsNil()
+
+	expected, err := LoadConfig(jsonFile)
+	assert.Error(err).IsNil()
+	actual, err := LoadConfig(pbFile)
+	assert.Error(err).IsNil()
+	assert.Port(actual.InboundConfig.Port).Equals(expected.InboundConfig.Port)
+	assert.String(actual.InboundConfig.Protocol).Equals(expected.InboundConfig.Protocol)
+	expectedSettings, err := proxyregistry.CreateInboundConfig(expected.InboundConfig.Protocol, expected.InboundConfig.Settings)
+	assert.Error(err).IsNil()
+	assert.Bool(proto.Equal(actual.InboundConfig.ProxySettings.(proto.Message), expectedSettings.(proto.Message))).IsTrue()
+	assert.Int(len(actual.InboundDetours)).Equals(len(expected.InboundDetours))
+	assert.Int(len(actual.OutboundDetours)).Equals(len(expected.OutboundDetours))
+
+	rawJSON, err := configPB.ToJSON()
+	assert.Error(err).IsNil()
+	assert.Error(json.Unmarshal(rawJSON, new(Config))).IsNil()
+	convertedFile := filepath.Join(dir, "converted.json")
+	assert.Error(ioutil.WriteFile(convertedFile, rawJSON, 0600)).IsNil()
+	converted, err := ConvertConfigToPB(convertedFile)
+	assert.Error(err).IsNil()
+	assert.Bool(proto.Equal(converted, configPB)).IsTrue()
+
+	unknownFile := filepath.Join(dir, "unknown.json")
+	assert.Error(ioutil.WriteFile(unknownFile, []byte(`{
+    "inbound": {"port": 1080, "protocl": "socks"},
+    "outbound": {"protocol": "freedom"}
+  }`), 0600)).IsNil()
+	_, err = ConvertConfigToPB(unknownFile)
+	assert.Error(err).IsNotNil()
+}
+
+func TestStrictConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	rawConfig := `{
+    "inbound": {"port": 1080, "protocol": "dokodemo-door", "settings": {"address": "127.0.0.1", "port": 53}},
+    "outbound": {"protocol": "freedom", "settings": {}},
+    "inboundDetour": [{"port": 1081, "protocol": "dokodemo-door", "settings": {"adress": "127.0.0.1", "port": 53}}]
+  }`
+	err := json.Unmarshal([]byte(rawConfig), new(Config))
+	unknown, ok := err.(*loader.UnknownFieldError)
+	assert.Bool(ok).IsTrue()
+	assert.String(unknown.Field()).Equals("inboundDetour[0].settings.adress")
+	assert.String(unknown.Suggestion).Equals("address")
+
+	err = json.Unmarshal([]byte(`{"inbound": {"port": 1080, "protocl": "socks"}, "outbound": {"protocol": "freedom"}}`), new(Config))
+	assert.String(err.Error()).Equals(`Config: Unknown field "inbound.protocl". Did you mean "protocol"?`)
+
+	loader.SetStrictJSON(false)
+	defer loader.SetStrictJSON(true)
+	assert.Error(json.Unmarshal([]byte(rawConfig), new(Config))).IsNil()
+}
+
+func TestMigrateConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	assert.Error(ioutil.WriteFile(file, []byte(`{
+    "port": 1080,
+    "inbound": {"protocol": "vmess", "settings": {"clients": [], "features": {"detour": {"to": "dynamic"}}}},
+    "outbound": {"protocol": "freedom", "settings": {}},
+    "inboundDetour": [{"port": "2000-2010", "protocol": "vmess", "tag": "dynamic", "settings": {}, "allocate": {"strategy": "random"}}],
+    "transport": {"connectionReuse": true}
+  }`), 0600)).IsNil()
+
+	content, explanations, err := MigrateConfig(file)
+	assert.Error(err).IsNil()
+	assert.Int(len(explanations)).Equals(3)
+	assert.String(explanations[0]).Equals("port: Moved to inbound.port.")
+
+	assert.Error(ioutil.WriteFile(file, content, 0600)).IsNil()
+	config, err := LoadConfig(file)
+	assert.Error(err).IsNil()
+	assert.Port(config.InboundConfig.Port).Equals(1080)
+	assert.String(compactJSON(config.InboundConfig.Settings)).Equals(`{"clients":[],"detour":{"to":"dynamic"}}`)
+
+	_, explanations, err = MigrateConfig(file)
+	assert.Error(err).IsNil()
+	assert.Int(len(explanations)).Equals(0)
+}
+
+func TestShareLinks(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	assert.Error(ioutil.WriteFile(file, []byte(`{
+    "inbound": {"port": 1080, "protocol": "shadowsocks", "settings": {"method": "aes-128-cfb", "password": "pass"}},
+    "outbound": {"protocol": "vmess", "settings": {"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "d17a1af7-efa5-42ca-b7e9-6a35282d737f", "alterId": 8}]}]}}
+  }`), 0600)).IsNil()
+
+	links, err := ExportLinks("", file)
+	assert.Error(err).IsNil()
+	assert.Int(len(links)).Equals(1)
+	links, err = ExportLinks("1.2.3.4", file)
+	assert.Error(err).IsNil()
+	assert.Int(len(links)).Equals(2)
+	assert.String(links[0]).Equals("ss://YWVzLTEyOC1jZmI6cGFzcw@1.2.3.4:1080#inbound")
+
+	content, warnings, err := ImportLinks(links[1])
+	assert.Error(err).IsNil()
+	assert.Int(len(warnings)).Equals(0)
+	detourFile := filepath.Join(dir, "detours.json")
+	assert.Error(ioutil.WriteFile(detourFile, content, 0600)).IsNil()
+	config, err := LoadConfig(file, detourFile)
+	assert.Error(err).IsNil()
+	assert.Int(len(config.OutboundDetours)).Equals(1)
+	assert.String(config.OutboundDetours[0].Tag).Equals("outbound")
+
+	_, _, err = ImportLinks(links[0])
+	assert.Error(err).Equals(ErrShadowsocksOutboundNotSupported)
+}
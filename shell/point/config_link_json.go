@@ -0,0 +1,282 @@
+// +build json
+
+package point
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"v2ray.com/core/shell/point/sharelink"
+)
+
+var (
+	ErrShadowsocksOutboundNotSupported = errors.New("Point: Shadowsocks outbound is not supported.")
+)
+
+// vmessLinkToOutbound converts a VMess share link into an outbound detour. Settings of the link that are global in
+// transport settings are reported in warnings instead.
+func vmessLinkToOutbound(vmess *sharelink.VMess, tag string) (map[string]interface{}, []string, error) {
+	var warnings []string
+	switch vmess.Network {
+	case "tcp", "kcp", "ws":
+	default:
+		return nil, nil, errors.New("Point: Network of " + tag + " is not supported: " + vmess.Network)
+	}
+	if vmess.Type != "none" {
+		if vmess.Network == "kcp" {
+			warnings = append(warnings, tag+": Set transport.kcpSettings.header.type to \""+vmess.Type+"\" for its header.")
+		} else {
+			warnings = append(warnings, tag+": Header type \""+vmess.Type+"\" is not supported, and is ignored.")
+		}
+	}
+	if vmess.Network == "ws" && len(vmess.Path) > 0 {
+		warnings = append(warnings, tag+": Set transport.wsSettings.Path to \""+vmess.Path+"\" for its WebSocket path.")
+	}
+	if len(vmess.Host) > 0 {
+		warnings = append(warnings, tag+": Host \""+vmess.Host+"\" is not supported, and is ignored.")
+	}
+
+	streamSettings := map[string]interface{}{
+		"network": vmess.Network,
+	}
+	if vmess.TLS {
+		streamSettings["security"] = "tls"
+	}
+	return map[string]interface{}{
+		"protocol": "vmess",
+		"tag":      tag,
+		"settings": map[string]interface{}{
+			"vnext": []interface{}{
+				map[string]interface{}{
+					"address": vmess.Address,
+					"port":    vmess.Port,
+					"users": []interface{}{
+						map[string]interface{}{
+							"id":      vmess.ID,
+							"alterId": vmess.AlterID,
+						},
+					},
+				},
+			},
+		},
+		"streamSettings": streamSettings,
+	}, warnings, nil
+}
+
+// jsonImportLinks converts share links into a config of outbound detours, which can be merged with other config files.
+// Tags of the outbounds are the names in the links.
+func jsonImportLinks(links ...string) ([]byte, []string, error) {
+	var outbounds []interface{}
+	var warnings []string
+	tags := make(map[string]bool)
+	for idx, link := range links {
+		server, err := sharelink.Parse(link)
+		if err != nil {
+			return nil, nil, err
+		}
+		vmess, ok := server.(*sharelink.VMess)
+		if !ok {
+			return nil, nil, ErrShadowsocksOutboundNotSupported
+		}
+		tag := vmess.Name
+		if len(tag) == 0 {
+			tag = "vmess-" + vmess.Address
+		}
+		for baseTag, suffix := tag, 2; tags[tag]; suffix++ {
+			tag = baseTag + "-" + strconv.Itoa(suffix)
+		}
+		tags[tag] = true
+		outbound, linkWarnings, err := vmessLinkToOutbound(vmess, tag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Point: Failed to import link %d: %v", idx+1, err)
+		}
+		outbounds = append(outbounds, outbound)
+		warnings = append(warnings, linkWarnings...)
+	}
+	content, err := json.MarshalIndent(map[string]interface{}{"outboundDetour": outbounds}, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, warnings, nil
+}
+
+// intOf returns the integer of a number in a config document, which is decoded from JSON, YAML or TOML.
+func intOf(value interface{}) (int, bool) {
+	switch number := value.(type) {
+	case json.Number:
+		n, err := number.Int64()
+		return int(n), err == nil
+	case int:
+		return number, true
+	case int64:
+		return int(number), true
+	case uint64:
+		return int(number), true
+	case float64:
+		return int(number), number == float64(int(number))
+	default:
+		return 0, false
+	}
+}
+
+// streamOf returns the network and TLS of streamSettings in a config document.
+func streamOf(object map[string]interface{}) (string, bool) {
+	settings, _ := object["streamSettings"].(map[string]interface{})
+	network := "tcp"
+	switch value := settings["network"].(type) {
+	case string:
+		network = strings.TrimSpace(strings.Split(value, ",")[0])
+	case []interface{}:
+		if len(value) > 0 {
+			network, _ = value[0].(string)
+		}
+	}
+	security, _ := settings["security"].(string)
+	return strings.ToLower(network), strings.ToLower(security) == "tls"
+}
+
+// linkExporter collects share links from a config document.
+type linkExporter struct {
+	// wsPath and kcpHeader are the transport settings shared by all links.
+	wsPath    string
+	kcpHeader string
+	links     []string
+}
+
+func (this *linkExporter) addVMess(name string, object map[string]interface{}, address string, port int, users []interface{}) {
+	network, tls := streamOf(object)
+	for _, rawUser := range users {
+		user, ok := rawUser.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := user["id"].(string)
+		alterID, _ := intOf(user["alterId"])
+		vmess := &sharelink.VMess{
+			Name:    name,
+			Address: address,
+			Port:    port,
+			ID:      id,
+			AlterID: alterID,
+			Network: network,
+			Type:    "none",
+			TLS:     tls,
+		}
+		if email, ok := user["email"].(string); ok && len(users) > 1 {
+			vmess.Name += " (" + email + ")"
+		}
+		switch network {
+		case "ws":
+			vmess.Path = this.wsPath
+		case "kcp":
+			if len(this.kcpHeader) > 0 {
+				vmess.Type = this.kcpHeader
+			}
+		}
+		this.links = append(this.links, vmess.String())
+	}
+}
+
+func (this *linkExporter) addOutbound(name string, outbound map[string]interface{}) {
+	if outbound["protocol"] != "vmess" {
+		return
+	}
+	if tag, ok := outbound["tag"].(string); ok && len(tag) > 0 {
+		name = tag
+	}
+	settings, _ := outbound["settings"].(map[string]interface{})
+	servers, _ := settings["vnext"].([]interface{})
+	for _, rawServer := range servers {
+		server, ok := rawServer.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		address, _ := server["address"].(string)
+		port, _ := intOf(server["port"])
+		users, _ := server["users"].([]interface{})
+		this.addVMess(name, outbound, address, port, users)
+	}
+}
+
+// addInbound adds the links of an inbound to connect to it at the address. Inbounds on port ranges are skipped.
+func (this *linkExporter) addInbound(name string, inbound map[string]interface{}, address string) {
+	port, ok := intOf(inbound["port"])
+	if !ok {
+		return
+	}
+	if tag, ok := inbound["tag"].(string); ok && len(tag) > 0 {
+		name = tag
+	}
+	settings, _ := inbound["settings"].(map[string]interface{})
+	switch inbound["protocol"] {
+	case "vmess":
+		clients, _ := settings["clients"].([]interface{})
+		this.addVMess(name, inbound, address, port, clients)
+	case "shadowsocks":
+		method, _ := settings["method"].(string)
+		password, _ := settings["password"].(string)
+		ss := &sharelink.Shadowsocks{
+			Name:     name,
+			Address:  address,
+			Port:     port,
+			Method:   strings.ToLower(method),
+			Password: password,
+		}
+		this.links = append(this.links, ss.String())
+	}
+}
+
+// jsonExportLinks returns the share links of VMess outbounds in config files. If address is not empty, links of VMess
+// and Shadowsocks inbounds are returned as well, for clients to connect to them at the address.
+func jsonExportLinks(address string, files ...string) ([]string, error) {
+	document, err := loadConfigDocument(files...)
+	if err != nil {
+		return nil, err
+	}
+	document, err = resolveSecretFiles(document)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("Point: Config is not an object.")
+	}
+
+	exporter := new(linkExporter)
+	if transport, ok := object["transport"].(map[string]interface{}); ok {
+		if wsSettings, ok := transport["wsSettings"].(map[string]interface{}); ok {
+			exporter.wsPath, _ = wsSettings["Path"].(string)
+		}
+		if kcpSettings, ok := transport["kcpSettings"].(map[string]interface{}); ok {
+			if header, ok := kcpSettings["header"].(map[string]interface{}); ok {
+				exporter.kcpHeader, _ = header["type"].(string)
+			}
+		}
+	}
+
+	if len(address) > 0 {
+		inbounds, paths := inboundsOf(object)
+		for idx, inbound := range inbounds {
+			exporter.addInbound(paths[idx], inbound, address)
+		}
+	}
+	if outbound, ok := object["outbound"].(map[string]interface{}); ok {
+		exporter.addOutbound("outbound", outbound)
+	}
+	if detours, ok := object["outboundDetour"].([]interface{}); ok {
+		for idx, rawDetour := range detours {
+			if detour, ok := rawDetour.(map[string]interface{}); ok {
+				exporter.addOutbound(fmt.Sprint("outboundDetour[", idx, "]"), detour)
+			}
+		}
+	}
+	return exporter.links, nil
+}
+
+func init() {
+	configLinkImporter = jsonImportLinks
+	configLinkExporter = jsonExportLinks
+}
@@ -0,0 +1,179 @@
+// +build json
+
+package point
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// configMigration rewrites a legacy form in a config document, and returns explanations of what it changed.
+type configMigration func(document map[string]interface{}) []string
+
+var (
+	configMigrations = []configMigration{
+		migrateInboundPort,
+		migrateVMessFeatures,
+		migrateConnectionReuse,
+	}
+)
+
+// migrateInboundPort moves the port of Point server into the inbound.
+func migrateInboundPort(document map[string]interface{}) []string {
+	port, found := document["port"]
+	if !found {
+		return nil
+	}
+	delete(document, "port")
+	inbound := objectField(document, "inbound")
+	if _, found := inbound["port"]; found {
+		return []string{"port: Removed, as it is overridden by inbound.port."}
+	}
+	inbound["port"] = port
+	return []string{"port: Moved to inbound.port."}
+}
+
+// inboundsOf returns the inbound and inbound detours in a config document with their paths.
+func inboundsOf(document map[string]interface{}) ([]map[string]interface{}, []string) {
+	var inbounds []map[string]interface{}
+	var paths []string
+	if inbound, ok := document["inbound"].(map[string]interface{}); ok {
+		inbounds = append(inbounds, inbound)
+		paths = append(paths, "inbound")
+	}
+	if detours, ok := document["inboundDetour"].([]interface{}); ok {
+		for idx, rawDetour := range detours {
+			if detour, ok := rawDetour.(map[string]interface{}); ok {
+				inbounds = append(inbounds, detour)
+				paths = append(paths, fmt.Sprint("inboundDetour[", idx, "]"))
+			}
+		}
+	}
+	return inbounds, paths
+}
+
+// migrateVMessFeatures moves the detour of VMess inbounds out of "features".
+func migrateVMessFeatures(document map[string]interface{}) []string {
+	var explanations []string
+	inbounds, paths := inboundsOf(document)
+	for idx, inbound := range inbounds {
+		settings, ok := inbound["settings"].(map[string]interface{})
+		if inbound["protocol"] != "vmess" || !ok {
+			continue
+		}
+		features, ok := settings["features"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := paths[idx] + ".settings"
+		delete(settings, "features")
+		detour, found := features["detour"]
+		switch {
+		case !found:
+			explanations = append(explanations, path+".features: Removed, as it has no effect.")
+		case settings["detour"] != nil:
+			explanations = append(explanations, path+".features.detour: Removed, as it is overridden by "+path+".detour.")
+		default:
+			settings["detour"] = detour
+			explanations = append(explanations, path+".features.detour: Moved to "+path+".detour.")
+		}
+	}
+	return explanations
+}
+
+// migrateConnectionReuse moves connectionReuse of transport into the settings of TCP, which it was applied to.
+func migrateConnectionReuse(document map[string]interface{}) []string {
+	transport, ok := document["transport"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	reuse, found := transport["connectionReuse"]
+	if !found {
+		return nil
+	}
+	delete(transport, "connectionReuse")
+	tcpSettings := objectField(transport, "tcpSettings")
+	if _, found := tcpSettings["connectionReuse"]; found {
+		return []string{"transport.connectionReuse: Removed, as it is overridden by transport.tcpSettings.connectionReuse."}
+	}
+	tcpSettings["connectionReuse"] = reuse
+	return []string{"transport.connectionReuse: Moved to transport.tcpSettings.connectionReuse."}
+}
+
+// copyDocument returns a deep copy of a JSON document.
+func copyDocument(document interface{}) (interface{}, error) {
+	rawDocument, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONConfig(rawDocument)
+}
+
+// jsonMigrateConfig rewrites legacy forms in a config file, and returns the updated config in JSON with explanations of
+// what changed. Includes and environment variables are kept as they are, and resolved only to validate the result.
+func jsonMigrateConfig(file string) ([]byte, []string, error) {
+	rawConfig, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	decode := decodeJSONConfig
+	if format := configFormatOf(file); format != nil {
+		decode = format.decoder
+	}
+	document, err := decode(rawConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	document = normalizeDocument(document)
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.New("Point: Config is not an object.")
+	}
+
+	var explanations []string
+	for _, migrate := range configMigrations {
+		explanations = append(explanations, migrate(object)...)
+	}
+
+	validated, err := copyDocument(object)
+	if err != nil {
+		return nil, nil, err
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	validated, err = resolveIncludes(validated, filepath.Dir(file), []string{absFile})
+	if err != nil {
+		return nil, nil, err
+	}
+	validated, err = expandEnvDocument(validated)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := parseConfigDocument(validated)
+	if err != nil {
+		return nil, nil, errors.New("Point: Migrated config is invalid: " + err.Error())
+	}
+	if errs := ValidateConfig(config); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for idx, err := range errs {
+			messages[idx] = err.Error()
+		}
+		return nil, nil, errors.New("Point: Migrated config is invalid: " + strings.Join(messages, " "))
+	}
+
+	content, err := json.MarshalIndent(object, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, explanations, nil
+}
+
+func init() {
+	configMigrator = jsonMigrateConfig
+}
@@ -0,0 +1,890 @@
+package point
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/app/debug"
+	"v2ray.com/core/app/events"
+	"v2ray.com/core/app/health"
+	"v2ray.com/core/app/metrics"
+	"v2ray.com/core/app/policy"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/app/throttle"
+	"v2ray.com/core/app/tracing"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ban"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/plugin"
+	"v2ray.com/core/transport/internet/reality"
+	"v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/internet/ws"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+const (
+	// ConfigFormatPB is the format of configs in protobuf, i.e. serialized ConfigPB.
+	ConfigFormatPB = "pb"
+)
+
+var (
+	ErrConfigPBNotMergeable = errors.New("Point: Protobuf configs can't be merged or included with configs of other formats.")
+)
+
+// ReadConfigPB reads config files in protobuf, or directories of them, and merges them in order as proto.Merge does.
+func ReadConfigPB(files ...string) (*ConfigPB, error) {
+	files, err := expandConfigFiles(files)
+	if err != nil {
+		log.Error("Point: Failed to list config files: ", err)
+		return nil, err
+	}
+	if len(files) == 0 {
+		log.Error("Point: No config file is found.")
+		return nil, common.ErrBadConfiguration
+	}
+	merged := new(ConfigPB)
+	for _, file := range files {
+		rawConfig, err := readConfigFile(file)
+		if err != nil {
+			log.Error("Point: Failed to read server config file (", file, "): ", err)
+			return nil, err
+		}
+		config := new(ConfigPB)
+		if err := proto.Unmarshal(rawConfig, config); err != nil {
+			log.Error("Point: Failed to parse server config file (", file, "): ", err)
+			return nil, err
+		}
+		proto.Merge(merged, config)
+	}
+	return merged, nil
+}
+
+// LoadConfigPB loads a Config from config files in protobuf as ReadConfigPB does. Settings of protocols are decoded
+// from their messages, and routing settings by the JSON parser of the strategy.
+func LoadConfigPB(files ...string) (*Config, error) {
+	configPB, err := ReadConfigPB(files...)
+	if err != nil {
+		return nil, err
+	}
+	config, err := configPB.Build()
+	if err != nil {
+		log.Error("Point: Failed to load server config: ", err)
+		return nil, err
+	}
+	buffer := proto.NewBuffer(nil)
+	buffer.SetDeterministic(true)
+	if err := buffer.Marshal(configPB); err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(buffer.Bytes())
+	config.Hash = hex.EncodeToString(hash[:])
+	return config, nil
+}
+
+// Build returns the Config of the message. The inbound and the outbound are required.
+func (this *ConfigPB) Build() (*Config, error) {
+	port, err := v2net.PortFromInt(this.Port)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{
+		Port: port,
+	}
+	if this.Inbound == nil {
+		return nil, errors.New("Point: Inbound config is not specified.")
+	}
+	if config.InboundConfig, err = this.Inbound.Build(); err != nil {
+		return nil, err
+	}
+	if this.Outbound == nil {
+		return nil, errors.New("Point: Outbound config is not specified.")
+	}
+	if config.OutboundConfig, err = this.Outbound.Build(); err != nil {
+		return nil, err
+	}
+	for _, detourPB := range this.InboundDetours {
+		detour, err := detourPB.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.InboundDetours = append(config.InboundDetours, detour)
+	}
+	for _, detourPB := range this.OutboundDetours {
+		detour, err := detourPB.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.OutboundDetours = append(config.OutboundDetours, detour)
+	}
+	if this.Log != nil {
+		if config.LogConfig, err = this.Log.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Routing != nil {
+		if config.RouterConfig, err = this.Routing.Build(); err != nil {
+			return nil, err
+		}
+	}
+	config.DNSConfig = this.Dns
+	if config.DNSConfig == nil {
+		config.DNSConfig = defaultDNSConfig()
+	}
+	if this.Transport != nil {
+		config.TransportConfig = this.Transport.Build()
+	}
+	if this.Throttle != nil {
+		config.ThrottleConfig = this.Throttle.Build()
+	}
+	if this.Api != nil {
+		if config.ApiConfig, err = this.Api.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Stats != nil {
+		config.StatsConfig = this.Stats.Build()
+	}
+	if this.Metrics != nil {
+		if config.MetricsConfig, err = this.Metrics.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Debug != nil {
+		if config.DebugConfig, err = this.Debug.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Events != nil {
+		if config.EventsConfig, err = this.Events.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Tracing != nil {
+		if config.TracingConfig, err = this.Tracing.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Health != nil {
+		if config.HealthConfig, err = this.Health.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Policy != nil {
+		if config.PolicyConfig, err = this.Policy.Build(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (this *LogConfigPB) Build() (*LogConfig, error) {
+	config := &LogConfig{
+		AccessLog:       this.Access,
+		ErrorLog:        this.Error,
+		AccessHostnames: this.AccessHostnames,
+	}
+
+	switch format := strings.ToLower(this.AccessFormat); format {
+	case "", AccessFormatText:
+		config.AccessFormat = AccessFormatText
+	case AccessFormatJSON:
+		config.AccessFormat = AccessFormatJSON
+	default:
+		return nil, errors.New("Point: Unknown access log format: " + format)
+	}
+
+	if rotation := this.Rotation; rotation != nil {
+		// Size is in MB and age is in hours.
+		config.Rotation = &log.Rotation{
+			MaxSize:    int64(rotation.MaxSize) * 1024 * 1024,
+			MaxAge:     time.Duration(rotation.MaxAge) * time.Hour,
+			MaxBackups: int(rotation.MaxBackups),
+			Compress:   rotation.Compress,
+		}
+	}
+
+	if syslog := this.Syslog; syslog != nil {
+		config.Syslog = &SyslogConfig{
+			Tag:      syslog.Tag,
+			Facility: log.SyslogFacilityDaemon,
+			Access:   syslog.Access,
+		}
+		if len(syslog.Address) > 0 {
+			network, address, err := parseSyslogAddress(syslog.Address)
+			if err != nil {
+				return nil, err
+			}
+			config.Syslog.Network = network
+			config.Syslog.Address = address
+		}
+		if len(syslog.Facility) > 0 {
+			facility, found := log.SyslogFacility(syslog.Facility)
+			if !found {
+				return nil, errors.New("Point: Unknown syslog facility: " + syslog.Facility)
+			}
+			config.Syslog.Facility = facility
+		}
+	}
+	if eventLog := this.EventLog; eventLog != nil {
+		config.EventLog = &EventLogConfig{
+			Source: eventLog.Source,
+			Access: eventLog.Access,
+		}
+		if len(config.EventLog.Source) == 0 {
+			config.EventLog.Source = "v2ray"
+		}
+	}
+	if config.Syslog != nil && config.EventLog != nil {
+		return nil, errors.New("Point: Syslog and event log can't be both set.")
+	}
+	if (config.Syslog != nil || config.EventLog != nil) && len(config.ErrorLog) > 0 {
+		return nil, errors.New("Point: Error log file can't be set with syslog or event log.")
+	}
+
+	level, found := parseLogLevel(this.Level)
+	if !found {
+		level = log.WarningLevel
+	}
+	config.LogLevel = level
+
+	if len(this.Levels) > 0 {
+		config.ModuleLevels = make(map[string]log.LogLevel, len(this.Levels))
+		for module, value := range this.Levels {
+			level, found := parseLogLevel(value)
+			if !found {
+				return nil, errors.New("Point: Unknown log level of " + module + ": " + value)
+			}
+			config.ModuleLevels[module] = level
+		}
+	}
+	return config, nil
+}
+
+func parseLogLevel(level string) (log.LogLevel, bool) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return log.DebugLevel, true
+	case "info":
+		return log.InfoLevel, true
+	case "warning":
+		return log.WarningLevel, true
+	case "error":
+		return log.ErrorLevel, true
+	case "none":
+		return log.NoneLevel, true
+	default:
+		return log.WarningLevel, false
+	}
+}
+
+// parseSyslogAddress parses a remote syslog address in the form of "udp://host:port" or "tcp://host:port". The network
+// is UDP if omitted, and the port is 514 if omitted.
+func parseSyslogAddress(value string) (string, string, error) {
+	network := "udp"
+	address := value
+	if index := strings.Index(value, "://"); index >= 0 {
+		network = strings.ToLower(value[:index])
+		address = value[index+3:]
+	}
+	if network != "udp" && network != "tcp" {
+		return "", "", errors.New("Point: Unknown syslog network: " + network)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "514")
+	}
+	return network, address, nil
+}
+
+func (this *TLSConfigPB) Build() (*internet.TLSSettings, error) {
+	minVersion, err := internet.ParseTLSVersion(this.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	maxVersion, err := internet.ParseTLSVersion(this.MaxVersion)
+	if err != nil {
+		return nil, err
+	}
+	settings := &internet.TLSSettings{
+		AllowInsecure:            this.AllowInsecure,
+		DisableSessionResumption: this.DisableSessionResumption,
+		SessionCacheSize:         int(this.SessionCacheSize),
+		MinVersion:               minVersion,
+		MaxVersion:               maxVersion,
+		ECHConfigList:            this.EchConfigList,
+		ClientEmails:             this.ClientEmails,
+	}
+	certFiles := make([]*internet.CertificateFile, len(this.Certificates))
+	for idx, cert := range this.Certificates {
+		certFiles[idx] = &internet.CertificateFile{
+			CertFile: cert.CertificateFile,
+			KeyFile:  cert.KeyFile,
+		}
+	}
+	if err := settings.LoadCertificates(certFiles); err != nil {
+		return nil, err
+	}
+	if len(this.ClientCaFiles) > 0 {
+		if err := settings.LoadClientCAs(this.ClientCaFiles); err != nil {
+			return nil, err
+		}
+	}
+	if acme := this.Acme; acme != nil {
+		settings.ACME = &internet.ACMESettings{
+			Domains:      acme.Domains,
+			Email:        acme.Email,
+			CacheDir:     acme.CacheDir,
+			DirectoryURL: acme.DirectoryUrl,
+			HTTPAddress:  acme.HttpAddress,
+		}
+	}
+	return settings, settings.Validate()
+}
+
+func (this *RealityConfigPB) Build() (*reality.Config, error) {
+	config := &reality.Config{
+		Dest:        this.Dest,
+		ServerNames: this.ServerNames,
+		PrivateKey:  this.PrivateKey,
+		ShortIds:    this.ShortIds,
+		MaxTimeDiff: time.Duration(this.MaxTimeDiff) * time.Second,
+		ServerName:  this.ServerName,
+		PublicKey:   this.PublicKey,
+		ShortId:     this.ShortId,
+	}
+	return config, config.Validate()
+}
+
+func (this *PluginConfigPB) Build() (*plugin.Config, error) {
+	config := &plugin.Config{
+		Address:      this.Address,
+		Command:      this.Command,
+		Args:         this.Args,
+		StartTimeout: time.Duration(this.StartTimeout) * time.Second,
+		Options:      this.Options,
+	}
+	return config, config.Validate()
+}
+
+func (this *StreamConfigPB) Build() (*internet.StreamSettings, error) {
+	settings := &internet.StreamSettings{
+		Custom: this.Custom,
+	}
+	var err error
+	if this.Tls != nil {
+		if settings.TLSSettings, err = this.Tls.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Reality != nil {
+		if settings.RealitySettings, err = this.Reality.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Plugin != nil {
+		if settings.PluginSettings, err = this.Plugin.Build(); err != nil {
+			return nil, err
+		}
+	}
+	if err := settings.Build(this.Network, this.Security); err != nil {
+		return nil, err
+	}
+	if settings.Resolver, err = internet.ParseResolver(this.Resolver); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// buildStreamSettings returns nil if settings are not set, so that handlers take the defaults of their protocols.
+func buildStreamSettings(settings *StreamConfigPB) (*internet.StreamSettings, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	return settings.Build()
+}
+
+// buildListen returns the address to listen on, which is any IP if not set.
+func buildListen(address *v2net.AddressPB) (v2net.Address, error) {
+	if address == nil {
+		return v2net.AnyIP, nil
+	}
+	if address.AsAddress().Family().IsDomain() {
+		return nil, errors.New("Point: Unable to listen on domain address: " + address.AsAddress().Domain())
+	}
+	return address.AsAddress(), nil
+}
+
+// buildSendThrough returns the address to send through, which is nil if not set.
+func buildSendThrough(address *v2net.AddressPB) (v2net.Address, error) {
+	if address == nil {
+		return nil, nil
+	}
+	if address.AsAddress().Family().IsDomain() {
+		return nil, errors.New("Point: Unable to send through: " + address.AsAddress().String())
+	}
+	return address.AsAddress(), nil
+}
+
+// decodeProxySettings decodes the settings of a protocol, which are nil if not set.
+func decodeProxySettings(settings *any.Any) (interface{}, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	message := new(ptypes.DynamicAny)
+	if err := ptypes.UnmarshalAny(settings, message); err != nil {
+		return nil, errors.New("Point: Failed to decode settings: " + err.Error())
+	}
+	return message.Message, nil
+}
+
+func (this *InboundConfigPB) Build() (*InboundConnectionConfig, error) {
+	port, err := v2net.PortFromInt(this.Port)
+	if err != nil {
+		return nil, err
+	}
+	config := &InboundConnectionConfig{
+		Port:                   port,
+		Protocol:               this.Protocol,
+		AllowPassiveConnection: this.AllowPassive,
+		DefaultOutboundTag:     this.DefaultOutboundTag,
+	}
+	if config.ListenOn, err = buildListen(this.Listen); err != nil {
+		return nil, err
+	}
+	if config.ProxySettings, err = decodeProxySettings(this.Settings); err != nil {
+		return nil, err
+	}
+	if config.StreamSettings, err = buildStreamSettings(this.StreamSettings); err != nil {
+		return nil, err
+	}
+	if config.SourceFilter, err = v2net.NewIPFilter(this.AllowSources, this.DenySources); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (this *OutboundConfigPB) Build() (*OutboundConnectionConfig, error) {
+	config := &OutboundConnectionConfig{
+		Protocol: this.Protocol,
+	}
+	var err error
+	if config.SendThrough, err = buildSendThrough(this.SendThrough); err != nil {
+		return nil, err
+	}
+	if config.ProxySettings, err = decodeProxySettings(this.Settings); err != nil {
+		return nil, err
+	}
+	if config.StreamSettings, err = buildStreamSettings(this.StreamSettings); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (this *AllocationConfigPB) Build() *InboundDetourAllocationConfig {
+	config := &InboundDetourAllocationConfig{
+		Strategy:    this.Strategy,
+		Concurrency: int(this.Concurrency),
+		Refresh:     int(this.Refresh),
+	}
+	if config.Strategy == AllocationStrategyRandom {
+		if config.Refresh == 0 {
+			config.Refresh = 5
+		}
+		if config.Concurrency == 0 {
+			config.Concurrency = 3
+		}
+	}
+	if config.Refresh == 0 {
+		config.Refresh = DefaultRefreshMinute
+	}
+	return config
+}
+
+func (this *InboundDetourConfigPB) Build() (*InboundDetourConfig, error) {
+	if this.Port == nil {
+		log.Error("Point: Port range not specified in InboundDetour.")
+		return nil, common.ErrBadConfiguration
+	}
+	if this.Port.From > this.Port.To || this.Port.To > 65535 {
+		return nil, v2net.ErrInvalidPortRange
+	}
+	config := &InboundDetourConfig{
+		Protocol:               this.Protocol,
+		PortRange:              *this.Port,
+		Tag:                    this.Tag,
+		AllowPassiveConnection: this.AllowPassive,
+		DefaultOutboundTag:     this.DefaultOutboundTag,
+	}
+	var err error
+	if config.ListenOn, err = buildListen(this.Listen); err != nil {
+		return nil, err
+	}
+	if config.ProxySettings, err = decodeProxySettings(this.Settings); err != nil {
+		return nil, err
+	}
+	if this.Allocate != nil {
+		config.Allocation = this.Allocate.Build()
+	} else {
+		config.Allocation = &InboundDetourAllocationConfig{
+			Strategy: AllocationStrategyAlways,
+			Refresh:  DefaultRefreshMinute,
+		}
+	}
+	if config.StreamSettings, err = buildStreamSettings(this.StreamSettings); err != nil {
+		return nil, err
+	}
+	if config.SourceFilter, err = v2net.NewIPFilter(this.AllowSources, this.DenySources); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (this *OutboundDetourConfigPB) Build() (*OutboundDetourConfig, error) {
+	config := &OutboundDetourConfig{
+		Protocol: this.Protocol,
+		Tag:      this.Tag,
+	}
+	var err error
+	if config.SendThrough, err = buildSendThrough(this.SendThrough); err != nil {
+		return nil, err
+	}
+	if config.ProxySettings, err = decodeProxySettings(this.Settings); err != nil {
+		return nil, err
+	}
+	if config.StreamSettings, err = buildStreamSettings(this.StreamSettings); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (this *RoutingConfigPB) Build() (*router.Config, error) {
+	settings, err := router.CreateRouterConfig(this.Strategy, this.Settings)
+	if err != nil {
+		log.Error("Router: Failed to load router settings: ", err)
+		return nil, err
+	}
+	return &router.Config{
+		Strategy: this.Strategy,
+		Settings: settings,
+	}, nil
+}
+
+func (this *TransportConfigPB) Build() *transport.Config {
+	var tcpConfig *tcp.Config
+	if this.Tcp != nil {
+		tcpConfig = &tcp.Config{
+			ConnectionReuse: this.Tcp.ConnectionReuse,
+			MultipathTCP:    this.Tcp.Mptcp,
+		}
+	}
+	var wsConfig *ws.Config
+	if this.Websocket != nil {
+		wsConfig = &ws.Config{
+			ConnectionReuse: this.Websocket.ConnectionReuse,
+			Path:            this.Websocket.Path,
+			Pto:             this.Websocket.Pto,
+			Cert:            this.Websocket.Cert,
+			PrivKey:         this.Websocket.PrivKey,
+			BrowserDialer:   this.Websocket.BrowserDialer,
+		}
+	}
+	return transport.NewConfig(tcpConfig, this.Kcp, wsConfig)
+}
+
+func (this *ThrottleLimitPB) Build() *throttle.Limit {
+	// Rates are configured in KB/s.
+	return &throttle.Limit{
+		Uplink:   this.Uplink * 1024,
+		Downlink: this.Downlink * 1024,
+		Shared:   this.Shared,
+	}
+}
+
+func (this *ThrottleConfigPB) Build() *throttle.Config {
+	config := &throttle.Config{
+		Levels: make(map[uint32]*throttle.Limit, len(this.Levels)),
+	}
+	for level, limit := range this.Levels {
+		config.Levels[level] = limit.Build()
+	}
+	if this.Inbounds != nil {
+		config.Inbounds = make(map[string]*throttle.Limit, len(this.Inbounds))
+		for tag, limit := range this.Inbounds {
+			config.Inbounds[tag] = limit.Build()
+		}
+	}
+	if this.Outbounds != nil {
+		config.Outbounds = make(map[string]*throttle.Limit, len(this.Outbounds))
+		for tag, limit := range this.Outbounds {
+			config.Outbounds[tag] = limit.Build()
+		}
+	}
+	return config
+}
+
+func (this *PolicyPB) Build() (*policy.Policy, error) {
+	if this.BufferSize == 0 {
+		return nil, errors.New("Policy: Buffer size must be positive.")
+	}
+	if this.UdpIdle == 0 {
+		return nil, errors.New("Policy: UDP idle timeout must be positive.")
+	}
+	config := &policy.Policy{
+		Handshake:       time.Duration(this.Handshake) * time.Second,
+		ConnectionIdle:  time.Duration(this.ConnectionIdle) * time.Second,
+		UplinkOnly:      time.Duration(this.UplinkOnly) * time.Second,
+		DownlinkOnly:    time.Duration(this.DownlinkOnly) * time.Second,
+		BufferSize:      int(this.BufferSize),
+		PooledBuffers:   int(this.PooledBuffers),
+		MaxConnections:  int(this.MaxConnections),
+		ConnectionQueue: time.Duration(this.ConnectionQueue) * time.Second,
+		Quota:           int64(this.Quota),
+		QuotaPeriod:     time.Duration(this.QuotaPeriod) * 24 * time.Hour,
+		OverQuotaRate:   this.OverQuotaRate,
+		UDPIdle:         time.Duration(this.UdpIdle) * time.Second,
+		UDPSessions:     int(this.UdpSessions),
+	}
+	if len(this.BufferClass) > 0 {
+		class, err := alloc.ParseSizeClass(this.BufferClass)
+		if err != nil {
+			return nil, errors.New("Policy: Unknown buffer class: " + this.BufferClass)
+		}
+		config.BufferClass = class
+	}
+	return config, nil
+}
+
+func (this *BanConfigPB) Build() (*ban.Config, error) {
+	if this.Failures == 0 {
+		return nil, errors.New("Policy: Number of failures to ban must be positive.")
+	}
+	config := &ban.Config{
+		Failures:    int(this.Failures),
+		Window:      ban.DefaultWindow,
+		Duration:    ban.DefaultDuration,
+		MaxDuration: ban.DefaultMaxDuration,
+	}
+	if this.Window > 0 {
+		config.Window = time.Duration(this.Window) * time.Second
+	}
+	if this.Duration > 0 {
+		config.Duration = time.Duration(this.Duration) * time.Second
+	}
+	if this.MaxDuration > 0 {
+		config.MaxDuration = time.Duration(this.MaxDuration) * time.Second
+	}
+	if config.MaxDuration < config.Duration {
+		return nil, errors.New("Policy: Max ban duration must not be shorter than the ban duration.")
+	}
+	return config, nil
+}
+
+func (this *PolicyConfigPB) Build() (*policy.Config, error) {
+	config := &policy.Config{
+		Levels: make(map[uint32]*policy.Policy, len(this.Levels)),
+	}
+	for level, policyPB := range this.Levels {
+		levelPolicy, err := policyPB.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Levels[level] = levelPolicy
+	}
+	if this.Ban != nil {
+		banConfig, err := this.Ban.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Ban = banConfig
+	}
+	return config, nil
+}
+
+// buildAppListen returns the address that an app listens on, which is defaultAddress if not set.
+func buildAppListen(name string, address *v2net.AddressPB, defaultAddress v2net.Address) (v2net.Address, error) {
+	if address == nil {
+		return defaultAddress, nil
+	}
+	if address.AsAddress().Family().IsDomain() {
+		return nil, errors.New(name + ": Unable to listen on domain address: " + address.AsAddress().Domain())
+	}
+	return address.AsAddress(), nil
+}
+
+// buildAppPort returns the port of an app, which is required.
+func buildAppPort(name string, port uint32) (v2net.Port, error) {
+	if port == 0 {
+		return 0, errors.New(name + ": Port is not specified.")
+	}
+	return v2net.PortFromInt(port)
+}
+
+// buildAppPath returns the HTTP path of an app, which is defaultPath if not set.
+func buildAppPath(name string, path string, defaultPath string) (string, error) {
+	if len(path) == 0 {
+		return defaultPath, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", errors.New(name + ": Path must start with /: " + path)
+	}
+	return path, nil
+}
+
+func (this *ApiConfigPB) Build() (*api.Config, error) {
+	config := new(api.Config)
+	var err error
+	if config.Port, err = buildAppPort("API", this.Port); err != nil {
+		return nil, err
+	}
+	if config.Listen, err = buildAppListen("API", this.Listen, v2net.LocalHostIP); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (this *StatsConfigPB) Build() *stats.Config {
+	return &stats.Config{
+		Users:     this.Users,
+		Inbounds:  this.Inbounds,
+		Outbounds: this.Outbounds,
+		// A device limit implies counting devices.
+		UserDevices: this.UserDevices || this.DeviceLimit > 0,
+		DeviceLimit: int(this.DeviceLimit),
+	}
+}
+
+func (this *MetricsConfigPB) Build() (*metrics.Config, error) {
+	config := new(metrics.Config)
+	var err error
+	if config.Port, err = buildAppPort("Metrics", this.Port); err != nil {
+		return nil, err
+	}
+	if config.Listen, err = buildAppListen("Metrics", this.Listen, v2net.LocalHostIP); err != nil {
+		return nil, err
+	}
+	if config.Path, err = buildAppPath("Metrics", this.Path, metrics.DefaultPath); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (this *DebugConfigPB) Build() (*debug.Config, error) {
+	port, err := buildAppPort("Debug", this.Port)
+	if err != nil {
+		return nil, err
+	}
+	return &debug.Config{
+		Port:    port,
+		Enabled: this.Enabled,
+	}, nil
+}
+
+func (this *HealthConfigPB) Build() (*health.Config, error) {
+	config := &health.Config{
+		Outbounds: this.Outbounds,
+	}
+	var err error
+	if config.Port, err = buildAppPort("Health", this.Port); err != nil {
+		return nil, err
+	}
+	if config.Listen, err = buildAppListen("Health", this.Listen, v2net.AnyIP); err != nil {
+		return nil, err
+	}
+	if config.Path, err = buildAppPath("Health", this.Path, health.DefaultPath); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (this *TracingConfigPB) Build() (*tracing.Config, error) {
+	if len(this.Endpoint) == 0 {
+		return nil, errors.New("Tracing: Endpoint is not specified.")
+	}
+	endpoint, err := url.Parse(this.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, errors.New("Tracing: Invalid endpoint: " + this.Endpoint)
+	}
+	if this.SampleRate < 0 || this.SampleRate > 1 {
+		return nil, errors.New("Tracing: Sample rate must be between 0 and 1.")
+	}
+	config := &tracing.Config{
+		Endpoint:    this.Endpoint,
+		Headers:     this.Headers,
+		ServiceName: this.ServiceName,
+		SampleRate:  this.SampleRate,
+	}
+	if len(config.ServiceName) == 0 {
+		config.ServiceName = tracing.DefaultServiceName
+	}
+	return config, nil
+}
+
+func (this *EventsConfigPB) Build() (*events.Config, error) {
+	config := &events.Config{
+		Webhooks:          make([]*events.WebhookConfig, len(this.Webhooks)),
+		Execs:             make([]*events.ExecConfig, len(this.Execs)),
+		AuthFailureLimit:  int(this.AuthFailureLimit),
+		AuthFailureWindow: proxy.DefaultAuthFailureWindow,
+		CertificateExpiry: events.DefaultCertificateExpiry,
+	}
+	if this.AuthFailureWindow > 0 {
+		config.AuthFailureWindow = time.Duration(this.AuthFailureWindow) * time.Second
+	}
+	if this.CertificateExpiry > 0 {
+		// Expiry is in days.
+		config.CertificateExpiry = time.Duration(this.CertificateExpiry) * 24 * time.Hour
+	}
+	for idx, webhook := range this.Webhooks {
+		target, err := url.Parse(webhook.Url)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+			return nil, errors.New("Events: Invalid webhook URL: " + webhook.Url)
+		}
+		types, err := events.ParseTypes(webhook.Types)
+		if err != nil {
+			return nil, err
+		}
+		config.Webhooks[idx] = &events.WebhookConfig{
+			URL:   webhook.Url,
+			Types: types,
+		}
+	}
+	for idx, execConfig := range this.Execs {
+		if len(execConfig.Command) == 0 {
+			return nil, errors.New("Events: Command is not specified.")
+		}
+		types, err := events.ParseTypes(execConfig.Types)
+		if err != nil {
+			return nil, err
+		}
+		config.Execs[idx] = &events.ExecConfig{
+			Command: execConfig.Command,
+			Args:    execConfig.Args,
+			Types:   types,
+		}
+	}
+	return config, nil
+}
+
+// decodePBConfig rejects configs in protobuf among files of other formats, as they are loaded by LoadConfigPB only
+// when all files are in protobuf.
+func decodePBConfig(data []byte) (interface{}, error) {
+	return nil, ErrConfigPBNotMergeable
+}
+
+func init() {
+	RegisterConfigFormat(ConfigFormatPB, []string{".pb"}, decodePBConfig)
+}
@@ -0,0 +1,1242 @@
+// +build json
+
+package point
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/app/debug"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/events"
+	"v2ray.com/core/app/health"
+	"v2ray.com/core/app/metrics"
+	"v2ray.com/core/app/policy"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/app/throttle"
+	"v2ray.com/core/app/tracing"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/event"
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	proxyregistry "v2ray.com/core/proxy/registry"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/kcp"
+	"v2ray.com/core/transport/internet/plugin"
+	"v2ray.com/core/transport/internet/reality"
+	"v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/internet/ws"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+// jsonConfigPB is a config in JSON as it is converted to and from a ConfigPB. Settings of protocols, DNS, mKCP and apps
+// are parsed by their own parsers, so that the conversion rejects what loading the config would.
+type jsonConfigPB struct {
+	Port            uint32                      `json:"port,omitempty"`
+	Log             *jsonLogConfig              `json:"log,omitempty"`
+	Routing         *jsonRoutingConfig          `json:"routing,omitempty"`
+	DNS             *dns.Config                 `json:"dns,omitempty"`
+	Inbound         *jsonInboundConfig          `json:"inbound,omitempty"`
+	Outbound        *jsonOutboundConfig         `json:"outbound,omitempty"`
+	InboundDetours  []*jsonInboundDetourConfig  `json:"inboundDetour,omitempty"`
+	OutboundDetours []*jsonOutboundDetourConfig `json:"outboundDetour,omitempty"`
+	Transport       *jsonTransportConfig        `json:"transport,omitempty"`
+	Throttle        json.RawMessage             `json:"throttle,omitempty"`
+	Api             json.RawMessage             `json:"api,omitempty"`
+	Stats           json.RawMessage             `json:"stats,omitempty"`
+	Metrics         json.RawMessage             `json:"metrics,omitempty"`
+	Debug           json.RawMessage             `json:"debug,omitempty"`
+	Events          json.RawMessage             `json:"events,omitempty"`
+	Tracing         json.RawMessage             `json:"tracing,omitempty"`
+	Health          json.RawMessage             `json:"health,omitempty"`
+	Policy          json.RawMessage             `json:"policy,omitempty"`
+}
+
+type jsonRoutingConfig struct {
+	Strategy string          `json:"strategy"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+type jsonInboundConfig struct {
+	Port            uint32            `json:"port,omitempty"`
+	Listen          *v2net.AddressPB  `json:"listen,omitempty"`
+	Protocol        string            `json:"protocol"`
+	StreamSetting   *jsonStreamConfig `json:"streamSettings,omitempty"`
+	Settings        json.RawMessage   `json:"settings,omitempty"`
+	AllowPassive    bool              `json:"allowPassive,omitempty"`
+	DefaultOutbound string            `json:"defaultOutboundTag,omitempty"`
+	AllowSources    []string          `json:"allowSources,omitempty"`
+	DenySources     []string          `json:"denySources,omitempty"`
+}
+
+type jsonOutboundConfig struct {
+	Protocol      string            `json:"protocol"`
+	SendThrough   *v2net.AddressPB  `json:"sendThrough,omitempty"`
+	StreamSetting *jsonStreamConfig `json:"streamSettings,omitempty"`
+	Settings      json.RawMessage   `json:"settings,omitempty"`
+}
+
+type jsonInboundDetourConfig struct {
+	Protocol        string                `json:"protocol"`
+	PortRange       *v2net.PortRange      `json:"port"`
+	ListenOn        *v2net.AddressPB      `json:"listen,omitempty"`
+	Settings        json.RawMessage       `json:"settings,omitempty"`
+	Tag             string                `json:"tag,omitempty"`
+	Allocation      *jsonAllocationConfig `json:"allocate,omitempty"`
+	StreamSetting   *jsonStreamConfig     `json:"streamSettings,omitempty"`
+	AllowPassive    bool                  `json:"allowPassive,omitempty"`
+	DefaultOutbound string                `json:"defaultOutboundTag,omitempty"`
+	AllowSources    []string              `json:"allowSources,omitempty"`
+	DenySources     []string              `json:"denySources,omitempty"`
+}
+
+type jsonOutboundDetourConfig struct {
+	Protocol      string            `json:"protocol"`
+	SendThrough   *v2net.AddressPB  `json:"sendThrough,omitempty"`
+	Tag           string            `json:"tag,omitempty"`
+	Settings      json.RawMessage   `json:"settings,omitempty"`
+	StreamSetting *jsonStreamConfig `json:"streamSettings,omitempty"`
+}
+
+type jsonStreamConfig struct {
+	Network         *v2net.NetworkList `json:"network,omitempty"`
+	Security        string             `json:"security,omitempty"`
+	TLSSettings     *jsonTLSConfig     `json:"tlsSettings,omitempty"`
+	RealitySettings json.RawMessage    `json:"realitySettings,omitempty"`
+	Custom          string             `json:"custom,omitempty"`
+	PluginSettings  json.RawMessage    `json:"plugin,omitempty"`
+	Resolver        string             `json:"resolver,omitempty"`
+}
+
+type jsonCertificateConfig struct {
+	CertFile string `json:"certificateFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+type jsonACMEConfig struct {
+	Domains      []string `json:"domains"`
+	Email        string   `json:"email,omitempty"`
+	CacheDir     string   `json:"cacheDir,omitempty"`
+	DirectoryURL string   `json:"directoryUrl,omitempty"`
+	HTTPAddress  string   `json:"httpAddress,omitempty"`
+}
+
+type jsonTLSConfig struct {
+	Insecure         bool                     `json:"allowInsecure,omitempty"`
+	Certs            []*jsonCertificateConfig `json:"certificates,omitempty"`
+	SessionTickets   *bool                    `json:"sessionTickets,omitempty"`
+	SessionCacheSize int32                    `json:"sessionCacheSize,omitempty"`
+	MinVersion       string                   `json:"minVersion,omitempty"`
+	MaxVersion       string                   `json:"maxVersion,omitempty"`
+	ECHConfigList    string                   `json:"echConfigList,omitempty"`
+	ClientCAFiles    []string                 `json:"clientCAFiles,omitempty"`
+	ClientEmails     map[string]string        `json:"clientEmails,omitempty"`
+	ACME             *jsonACMEConfig          `json:"acme,omitempty"`
+}
+
+type jsonRealityConfig struct {
+	Dest        string   `json:"dest,omitempty"`
+	ServerNames []string `json:"serverNames,omitempty"`
+	PrivateKey  string   `json:"privateKey,omitempty"`
+	ShortIds    []string `json:"shortIds,omitempty"`
+	MaxTimeDiff uint32   `json:"maxTimeDiff,omitempty"`
+	ServerName  string   `json:"serverName,omitempty"`
+	PublicKey   string   `json:"publicKey,omitempty"`
+	ShortId     string   `json:"shortId,omitempty"`
+}
+
+type jsonPluginConfig struct {
+	Address      string   `json:"address"`
+	Command      string   `json:"command,omitempty"`
+	Args         []string `json:"args,omitempty"`
+	StartTimeout uint32   `json:"startTimeout,omitempty"`
+	Options      string   `json:"options,omitempty"`
+}
+
+type jsonTransportConfig struct {
+	TCPConfig json.RawMessage `json:"tcpSettings,omitempty"`
+	KCPConfig *kcp.Config     `json:"kcpSettings,omitempty"`
+	WSConfig  json.RawMessage `json:"wsSettings,omitempty"`
+}
+
+// ConvertConfigToPB loads config files as LoadConfig does, and converts them to a ConfigPB. Secrets in files are read
+// into the config. Files in the config, e.g. certificates, are not read, so that they are only required where the
+// config is loaded. Configs in protobuf are merged as they are.
+func ConvertConfigToPB(files ...string) (*ConfigPB, error) {
+	if isConfigPB(files) {
+		return ReadConfigPB(files...)
+	}
+	document, err := loadConfigDocument(files...)
+	if err != nil {
+		return nil, err
+	}
+	document, err = resolveSecretFiles(document)
+	if err != nil {
+		log.Error("Point: Failed to read secrets: ", err)
+		return nil, err
+	}
+	rawConfig, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	jsonConfig := new(jsonConfigPB)
+	if err := loader.DecodeJSON(rawConfig, jsonConfig); err != nil {
+		return nil, loader.WrapError("Point: Failed to parse config: ", err)
+	}
+	return jsonConfig.toPB()
+}
+
+func (this *jsonConfigPB) toPB() (*ConfigPB, error) {
+	config := &ConfigPB{
+		Port: this.Port,
+		Dns:  this.DNS,
+	}
+	var err error
+	if this.Log != nil {
+		config.Log = this.Log.toPB()
+	}
+	if this.Routing != nil {
+		if config.Routing, err = this.Routing.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Inbound != nil {
+		if config.Inbound, err = this.Inbound.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	if this.Outbound != nil {
+		if config.Outbound, err = this.Outbound.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	for _, jsonDetour := range this.InboundDetours {
+		detour, err := jsonDetour.toPB()
+		if err != nil {
+			return nil, err
+		}
+		config.InboundDetours = append(config.InboundDetours, detour)
+	}
+	for _, jsonDetour := range this.OutboundDetours {
+		detour, err := jsonDetour.toPB()
+		if err != nil {
+			return nil, err
+		}
+		config.OutboundDetours = append(config.OutboundDetours, detour)
+	}
+	if this.Transport != nil {
+		if config.Transport, err = this.Transport.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Throttle) > 0 {
+		if config.Throttle, err = throttleConfigToPB(this.Throttle); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Api) > 0 {
+		if config.Api, err = apiConfigToPB(this.Api); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Stats) > 0 {
+		if config.Stats, err = statsConfigToPB(this.Stats); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Metrics) > 0 {
+		if config.Metrics, err = metricsConfigToPB(this.Metrics); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Debug) > 0 {
+		if config.Debug, err = debugConfigToPB(this.Debug); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Events) > 0 {
+		if config.Events, err = eventsConfigToPB(this.Events); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Tracing) > 0 {
+		if config.Tracing, err = tracingConfigToPB(this.Tracing); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Health) > 0 {
+		if config.Health, err = healthConfigToPB(this.Health); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.Policy) > 0 {
+		if config.Policy, err = policyConfigToPB(this.Policy); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (this *jsonRoutingConfig) toPB() (*RoutingConfigPB, error) {
+	if _, err := router.CreateRouterConfig(this.Strategy, this.Settings); err != nil {
+		log.Error("Router: Failed to load router settings: ", err)
+		if unknown, ok := err.(*loader.UnknownFieldError); ok {
+			return nil, unknown.Under("routing", "settings")
+		}
+		return nil, err
+	}
+	return &RoutingConfigPB{
+		Strategy: this.Strategy,
+		Settings: this.Settings,
+	}, nil
+}
+
+// proxySettingsToPB parses the settings of a protocol by createConfig, and returns them as an Any.
+func proxySettingsToPB(createConfig func(string, []byte) (interface{}, error), protocol string, settings []byte) (*any.Any, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	config, err := createConfig(protocol, settings)
+	if err != nil {
+		if unknown, ok := err.(*loader.UnknownFieldError); ok {
+			return nil, unknown.Under("settings")
+		}
+		return nil, err
+	}
+	message, ok := config.(proto.Message)
+	if !ok {
+		return nil, errors.New("Point: Settings of " + protocol + " can't be converted.")
+	}
+	return ptypes.MarshalAny(message)
+}
+
+func (this *jsonInboundConfig) toPB() (*InboundConfigPB, error) {
+	config := &InboundConfigPB{
+		Port:               this.Port,
+		Listen:             this.Listen,
+		Protocol:           this.Protocol,
+		AllowPassive:       this.AllowPassive,
+		DefaultOutboundTag: this.DefaultOutbound,
+		AllowSources:       this.AllowSources,
+		DenySources:        this.DenySources,
+	}
+	var err error
+	if config.Settings, err = proxySettingsToPB(proxyregistry.CreateInboundConfig, this.Protocol, this.Settings); err != nil {
+		return nil, err
+	}
+	if this.StreamSetting != nil {
+		if config.StreamSettings, err = this.StreamSetting.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (this *jsonOutboundConfig) toPB() (*OutboundConfigPB, error) {
+	config := &OutboundConfigPB{
+		Protocol:    this.Protocol,
+		SendThrough: this.SendThrough,
+	}
+	var err error
+	if config.Settings, err = proxySettingsToPB(proxyregistry.CreateOutboundConfig, this.Protocol, this.Settings); err != nil {
+		return nil, err
+	}
+	if this.StreamSetting != nil {
+		if config.StreamSettings, err = this.StreamSetting.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (this *jsonInboundDetourConfig) toPB() (*InboundDetourConfigPB, error) {
+	config := &InboundDetourConfigPB{
+		Protocol:           this.Protocol,
+		Port:               this.PortRange,
+		Listen:             this.ListenOn,
+		Tag:                this.Tag,
+		AllowPassive:       this.AllowPassive,
+		DefaultOutboundTag: this.DefaultOutbound,
+		AllowSources:       this.AllowSources,
+		DenySources:        this.DenySources,
+	}
+	if this.Allocation != nil {
+		config.Allocate = &AllocationConfigPB{
+			Strategy:    this.Allocation.Strategy,
+			Concurrency: this.Allocation.Concurrency,
+			Refresh:     this.Allocation.RefreshMin,
+		}
+	}
+	var err error
+	if config.Settings, err = proxySettingsToPB(proxyregistry.CreateInboundConfig, this.Protocol, this.Settings); err != nil {
+		return nil, err
+	}
+	if this.StreamSetting != nil {
+		if config.StreamSettings, err = this.StreamSetting.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (this *jsonOutboundDetourConfig) toPB() (*OutboundDetourConfigPB, error) {
+	config := &OutboundDetourConfigPB{
+		Protocol:    this.Protocol,
+		SendThrough: this.SendThrough,
+		Tag:         this.Tag,
+	}
+	var err error
+	if config.Settings, err = proxySettingsToPB(proxyregistry.CreateOutboundConfig, this.Protocol, this.Settings); err != nil {
+		return nil, err
+	}
+	if this.StreamSetting != nil {
+		if config.StreamSettings, err = this.StreamSetting.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (this *jsonStreamConfig) toPB() (*StreamConfigPB, error) {
+	config := &StreamConfigPB{
+		Network:  this.Network,
+		Security: this.Security,
+		Custom:   this.Custom,
+		Resolver: this.Resolver,
+	}
+	if _, err := internet.ParseResolver(this.Resolver); err != nil {
+		return nil, err
+	}
+	var err error
+	if this.TLSSettings != nil {
+		if config.Tls, err = this.TLSSettings.toPB(); err != nil {
+			return nil, err
+		}
+	}
+	if len(this.RealitySettings) > 0 {
+		realityConfig := new(reality.Config)
+		if err := json.Unmarshal(this.RealitySettings, realityConfig); err != nil {
+			return nil, err
+		}
+		config.Reality = &RealityConfigPB{
+			Dest:        realityConfig.Dest,
+			ServerNames: realityConfig.ServerNames,
+			PrivateKey:  realityConfig.PrivateKey,
+			ShortIds:    realityConfig.ShortIds,
+			MaxTimeDiff: uint32(realityConfig.MaxTimeDiff / time.Second),
+			ServerName:  realityConfig.ServerName,
+			PublicKey:   realityConfig.PublicKey,
+			ShortId:     realityConfig.ShortId,
+		}
+	}
+	if len(this.PluginSettings) > 0 {
+		pluginConfig := new(plugin.Config)
+		if err := json.Unmarshal(this.PluginSettings, pluginConfig); err != nil {
+			return nil, err
+		}
+		config.Plugin = &PluginConfigPB{
+			Address:      pluginConfig.Address,
+			Command:      pluginConfig.Command,
+			Args:         pluginConfig.Args,
+			StartTimeout: uint32(pluginConfig.StartTimeout / time.Second),
+			Options:      pluginConfig.Options,
+		}
+	}
+	return config, nil
+}
+
+func (this *jsonTLSConfig) toPB() (*TLSConfigPB, error) {
+	if _, err := internet.ParseTLSVersion(this.MinVersion); err != nil {
+		return nil, err
+	}
+	if _, err := internet.ParseTLSVersion(this.MaxVersion); err != nil {
+		return nil, err
+	}
+	config := &TLSConfigPB{
+		AllowInsecure:            this.Insecure,
+		DisableSessionResumption: this.SessionTickets != nil && !*this.SessionTickets,
+		SessionCacheSize:         this.SessionCacheSize,
+		MinVersion:               this.MinVersion,
+		MaxVersion:               this.MaxVersion,
+		ClientCaFiles:            this.ClientCAFiles,
+		ClientEmails:             this.ClientEmails,
+	}
+	if len(this.ECHConfigList) > 0 {
+		echConfigList, err := base64.StdEncoding.DecodeString(this.ECHConfigList)
+		if err != nil {
+			return nil, errors.New("Internet|TLS: Failed to decode ECHConfigList: " + err.Error())
+		}
+		config.EchConfigList = echConfigList
+	}
+	for _, cert := range this.Certs {
+		config.Certificates = append(config.Certificates, &CertificatePB{
+			CertificateFile: cert.CertFile,
+			KeyFile:         cert.KeyFile,
+		})
+	}
+	if acme := this.ACME; acme != nil {
+		config.Acme = &ACMEConfigPB{
+			Domains:      acme.Domains,
+			Email:        acme.Email,
+			CacheDir:     acme.CacheDir,
+			DirectoryUrl: acme.DirectoryURL,
+			HttpAddress:  acme.HTTPAddress,
+		}
+	}
+	return config, nil
+}
+
+func (this *jsonTransportConfig) toPB() (*TransportConfigPB, error) {
+	config := &TransportConfigPB{
+		Kcp: this.KCPConfig,
+	}
+	if len(this.TCPConfig) > 0 {
+		tcpConfig := new(tcp.Config)
+		if err := json.Unmarshal(this.TCPConfig, tcpConfig); err != nil {
+			return nil, err
+		}
+		config.Tcp = &TCPConfigPB{
+			ConnectionReuse: tcpConfig.ConnectionReuse,
+			Mptcp:           tcpConfig.MultipathTCP,
+		}
+	}
+	if len(this.WSConfig) > 0 {
+		wsConfig := new(ws.Config)
+		if err := json.Unmarshal(this.WSConfig, wsConfig); err != nil {
+			return nil, err
+		}
+		config.Websocket = &WebSocketConfigPB{
+			ConnectionReuse: wsConfig.ConnectionReuse,
+			Path:            wsConfig.Path,
+			Pto:             wsConfig.Pto,
+			Cert:            wsConfig.Cert,
+			PrivKey:         wsConfig.PrivKey,
+			BrowserDialer:   wsConfig.BrowserDialer,
+		}
+	}
+	return config, nil
+}
+
+// addressToPB returns the AddressPB of address, or nil if address is nil.
+func addressToPB(address v2net.Address) *v2net.AddressPB {
+	if address == nil {
+		return nil
+	}
+	if address.Family().IsDomain() {
+		return &v2net.AddressPB{
+			Address: &v2net.AddressPB_Domain{
+				Domain: address.Domain(),
+			},
+		}
+	}
+	return &v2net.AddressPB{
+		Address: &v2net.AddressPB_Ip{
+			Ip: []byte(address.IP()),
+		},
+	}
+}
+
+func throttleLimitToPB(limit *throttle.Limit) *ThrottleLimitPB {
+	// Rates are configured in KB/s.
+	return &ThrottleLimitPB{
+		Uplink:   limit.Uplink / 1024,
+		Downlink: limit.Downlink / 1024,
+		Shared:   limit.Shared,
+	}
+}
+
+func throttleConfigToPB(data []byte) (*ThrottleConfigPB, error) {
+	throttleConfig := new(throttle.Config)
+	if err := json.Unmarshal(data, throttleConfig); err != nil {
+		return nil, err
+	}
+	config := new(ThrottleConfigPB)
+	if len(throttleConfig.Levels) > 0 {
+		config.Levels = make(map[uint32]*ThrottleLimitPB, len(throttleConfig.Levels))
+		for level, limit := range throttleConfig.Levels {
+			config.Levels[level] = throttleLimitToPB(limit)
+		}
+	}
+	if len(throttleConfig.Inbounds) > 0 {
+		config.Inbounds = make(map[string]*ThrottleLimitPB, len(throttleConfig.Inbounds))
+		for tag, limit := range throttleConfig.Inbounds {
+			config.Inbounds[tag] = throttleLimitToPB(limit)
+		}
+	}
+	if len(throttleConfig.Outbounds) > 0 {
+		config.Outbounds = make(map[string]*ThrottleLimitPB, len(throttleConfig.Outbounds))
+		for tag, limit := range throttleConfig.Outbounds {
+			config.Outbounds[tag] = throttleLimitToPB(limit)
+		}
+	}
+	return config, nil
+}
+
+func apiConfigToPB(data []byte) (*ApiConfigPB, error) {
+	apiConfig := new(api.Config)
+	if err := json.Unmarshal(data, apiConfig); err != nil {
+		return nil, err
+	}
+	return &ApiConfigPB{
+		Listen: addressToPB(apiConfig.Listen),
+		Port:   uint32(apiConfig.Port),
+	}, nil
+}
+
+func statsConfigToPB(data []byte) (*StatsConfigPB, error) {
+	statsConfig := new(stats.Config)
+	if err := json.Unmarshal(data, statsConfig); err != nil {
+		return nil, err
+	}
+	return &StatsConfigPB{
+		Users:       statsConfig.Users,
+		Inbounds:    statsConfig.Inbounds,
+		Outbounds:   statsConfig.Outbounds,
+		UserDevices: statsConfig.UserDevices,
+		DeviceLimit: uint32(statsConfig.DeviceLimit),
+	}, nil
+}
+
+func metricsConfigToPB(data []byte) (*MetricsConfigPB, error) {
+	metricsConfig := new(metrics.Config)
+	if err := json.Unmarshal(data, metricsConfig); err != nil {
+		return nil, err
+	}
+	return &MetricsConfigPB{
+		Listen: addressToPB(metricsConfig.Listen),
+		Port:   uint32(metricsConfig.Port),
+		Path:   metricsConfig.Path,
+	}, nil
+}
+
+func debugConfigToPB(data []byte) (*DebugConfigPB, error) {
+	debugConfig := new(debug.Config)
+	if err := json.Unmarshal(data, debugConfig); err != nil {
+		return nil, err
+	}
+	return &DebugConfigPB{
+		Port:    uint32(debugConfig.Port),
+		Enabled: debugConfig.Enabled,
+	}, nil
+}
+
+func typesToStrings(types []event.Type) []string {
+	if len(types) == 0 {
+		return nil
+	}
+	names := make([]string, len(types))
+	for idx, t := range types {
+		names[idx] = string(t)
+	}
+	return names
+}
+
+func eventsConfigToPB(data []byte) (*EventsConfigPB, error) {
+	eventsConfig := new(events.Config)
+	if err := json.Unmarshal(data, eventsConfig); err != nil {
+		return nil, err
+	}
+	config := &EventsConfigPB{
+		AuthFailureLimit:  uint32(eventsConfig.AuthFailureLimit),
+		AuthFailureWindow: uint32(eventsConfig.AuthFailureWindow / time.Second),
+		CertificateExpiry: uint32(eventsConfig.CertificateExpiry / (24 * time.Hour)),
+	}
+	for _, webhook := range eventsConfig.Webhooks {
+		config.Webhooks = append(config.Webhooks, &EventsWebhookConfigPB{
+			Url:   webhook.URL,
+			Types: typesToStrings(webhook.Types),
+		})
+	}
+	for _, execConfig := range eventsConfig.Execs {
+		config.Execs = append(config.Execs, &EventsExecConfigPB{
+			Command: execConfig.Command,
+			Args:    execConfig.Args,
+			Types:   typesToStrings(execConfig.Types),
+		})
+	}
+	return config, nil
+}
+
+func tracingConfigToPB(data []byte) (*TracingConfigPB, error) {
+	tracingConfig := new(tracing.Config)
+	if err := json.Unmarshal(data, tracingConfig); err != nil {
+		return nil, err
+	}
+	return &TracingConfigPB{
+		Endpoint:    tracingConfig.Endpoint,
+		Headers:     tracingConfig.Headers,
+		ServiceName: tracingConfig.ServiceName,
+		SampleRate:  tracingConfig.SampleRate,
+	}, nil
+}
+
+func healthConfigToPB(data []byte) (*HealthConfigPB, error) {
+	healthConfig := new(health.Config)
+	if err := json.Unmarshal(data, healthConfig); err != nil {
+		return nil, err
+	}
+	return &HealthConfigPB{
+		Listen:    addressToPB(healthConfig.Listen),
+		Port:      uint32(healthConfig.Port),
+		Path:      healthConfig.Path,
+		Outbounds: healthConfig.Outbounds,
+	}, nil
+}
+
+// sizeClassNames are the names of buffer classes in policies, with the largest class as the default.
+var sizeClassNames = map[alloc.SizeClass]string{
+	alloc.MediumClass: "medium",
+	alloc.SmallClass:  "small",
+}
+
+func policyConfigToPB(data []byte) (*PolicyConfigPB, error) {
+	policyConfig := new(policy.Config)
+	if err := json.Unmarshal(data, policyConfig); err != nil {
+		return nil, err
+	}
+	config := new(PolicyConfigPB)
+	if len(policyConfig.Levels) > 0 {
+		config.Levels = make(map[uint32]*PolicyPB, len(policyConfig.Levels))
+	}
+	for level, levelPolicy := range policyConfig.Levels {
+		config.Levels[level] = &PolicyPB{
+			Handshake:       policy.Seconds(levelPolicy.Handshake),
+			ConnectionIdle:  policy.Seconds(levelPolicy.ConnectionIdle),
+			UplinkOnly:      policy.Seconds(levelPolicy.UplinkOnly),
+			DownlinkOnly:    policy.Seconds(levelPolicy.DownlinkOnly),
+			BufferSize:      uint32(levelPolicy.BufferSize),
+			PooledBuffers:   uint32(levelPolicy.PooledBuffers),
+			BufferClass:     sizeClassNames[levelPolicy.BufferClass],
+			MaxConnections:  uint32(levelPolicy.MaxConnections),
+			ConnectionQueue: policy.Seconds(levelPolicy.ConnectionQueue),
+			Quota:           uint64(levelPolicy.Quota),
+			QuotaPeriod:     uint32(levelPolicy.QuotaPeriod / (24 * time.Hour)),
+			OverQuotaRate:   levelPolicy.OverQuotaRate,
+			UdpIdle:         policy.Seconds(levelPolicy.UDPIdle),
+			UdpSessions:     uint32(levelPolicy.UDPSessions),
+		}
+	}
+	if ban := policyConfig.Ban; ban != nil {
+		config.Ban = &BanConfigPB{
+			Failures:    uint32(ban.Failures),
+			Window:      uint32(ban.Window / time.Second),
+			Duration:    uint32(ban.Duration / time.Second),
+			MaxDuration: uint32(ban.MaxDuration / time.Second),
+		}
+	}
+	return config, nil
+}
+
+// ToJSON converts the config to a config in JSON, which is loaded as the config.
+func (this *ConfigPB) ToJSON() ([]byte, error) {
+	jsonConfig, err := newJSONConfigPB(this)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(jsonConfig, "", "  ")
+}
+
+func newJSONConfigPB(config *ConfigPB) (*jsonConfigPB, error) {
+	jsonConfig := &jsonConfigPB{
+		Port: config.Port,
+		DNS:  config.Dns,
+	}
+	var err error
+	if config.Log != nil {
+		jsonConfig.Log = newJSONLogConfig(config.Log)
+	}
+	if config.Routing != nil {
+		jsonConfig.Routing = &jsonRoutingConfig{
+			Strategy: config.Routing.Strategy,
+			Settings: config.Routing.Settings,
+		}
+	}
+	if config.Inbound != nil {
+		if jsonConfig.Inbound, err = newJSONInboundConfig(config.Inbound); err != nil {
+			return nil, err
+		}
+	}
+	if config.Outbound != nil {
+		if jsonConfig.Outbound, err = newJSONOutboundConfig(config.Outbound); err != nil {
+			return nil, err
+		}
+	}
+	for _, detour := range config.InboundDetours {
+		jsonDetour, err := newJSONInboundDetourConfig(detour)
+		if err != nil {
+			return nil, err
+		}
+		jsonConfig.InboundDetours = append(jsonConfig.InboundDetours, jsonDetour)
+	}
+	for _, detour := range config.OutboundDetours {
+		jsonDetour, err := newJSONOutboundDetourConfig(detour)
+		if err != nil {
+			return nil, err
+		}
+		jsonConfig.OutboundDetours = append(jsonConfig.OutboundDetours, jsonDetour)
+	}
+	if config.Transport != nil {
+		if jsonConfig.Transport, err = newJSONTransportConfig(config.Transport); err != nil {
+			return nil, err
+		}
+	}
+	if config.Throttle != nil {
+		if jsonConfig.Throttle, err = json.Marshal(newJSONThrottleConfig(config.Throttle)); err != nil {
+			return nil, err
+		}
+	}
+	if config.Api != nil {
+		if jsonConfig.Api, err = json.Marshal(&jsonAppConfig{
+			Listen: config.Api.Listen,
+			Port:   config.Api.Port,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if config.Stats != nil {
+		if jsonConfig.Stats, err = json.Marshal(&jsonStatsConfig{
+			Users:       config.Stats.Users,
+			Inbounds:    config.Stats.Inbounds,
+			Outbounds:   config.Stats.Outbounds,
+			UserDevices: config.Stats.UserDevices,
+			DeviceLimit: config.Stats.DeviceLimit,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if config.Metrics != nil {
+		if jsonConfig.Metrics, err = json.Marshal(&jsonAppConfig{
+			Listen: config.Metrics.Listen,
+			Port:   config.Metrics.Port,
+			Path:   config.Metrics.Path,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if config.Debug != nil {
+		if jsonConfig.Debug, err = json.Marshal(&jsonDebugConfig{
+			Port:    config.Debug.Port,
+			Enabled: config.Debug.Enabled,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if config.Events != nil {
+		if jsonConfig.Events, err = json.Marshal(newJSONEventsConfig(config.Events)); err != nil {
+			return nil, err
+		}
+	}
+	if config.Tracing != nil {
+		if jsonConfig.Tracing, err = json.Marshal(&jsonTracingConfig{
+			Endpoint:    config.Tracing.Endpoint,
+			Headers:     config.Tracing.Headers,
+			ServiceName: config.Tracing.ServiceName,
+			SampleRate:  config.Tracing.SampleRate,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if config.Health != nil {
+		if jsonConfig.Health, err = json.Marshal(&jsonHealthConfig{
+			Listen:    config.Health.Listen,
+			Port:      config.Health.Port,
+			Path:      config.Health.Path,
+			Outbounds: config.Health.Outbounds,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if config.Policy != nil {
+		if jsonConfig.Policy, err = json.Marshal(newJSONPolicyConfig(config.Policy)); err != nil {
+			return nil, err
+		}
+	}
+	return jsonConfig, nil
+}
+
+// proxySettingsToJSON returns the settings of a protocol in JSON.
+func proxySettingsToJSON(settings *any.Any) (json.RawMessage, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	config, err := decodeProxySettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(config)
+}
+
+func newJSONInboundConfig(config *InboundConfigPB) (*jsonInboundConfig, error) {
+	jsonConfig := &jsonInboundConfig{
+		Port:            config.Port,
+		Listen:          config.Listen,
+		Protocol:        config.Protocol,
+		AllowPassive:    config.AllowPassive,
+		DefaultOutbound: config.DefaultOutboundTag,
+		AllowSources:    config.AllowSources,
+		DenySources:     config.DenySources,
+	}
+	var err error
+	if jsonConfig.Settings, err = proxySettingsToJSON(config.Settings); err != nil {
+		return nil, err
+	}
+	if config.StreamSettings != nil {
+		jsonConfig.StreamSetting = newJSONStreamConfig(config.StreamSettings)
+	}
+	return jsonConfig, nil
+}
+
+func newJSONOutboundConfig(config *OutboundConfigPB) (*jsonOutboundConfig, error) {
+	jsonConfig := &jsonOutboundConfig{
+		Protocol:    config.Protocol,
+		SendThrough: config.SendThrough,
+	}
+	var err error
+	if jsonConfig.Settings, err = proxySettingsToJSON(config.Settings); err != nil {
+		return nil, err
+	}
+	if config.StreamSettings != nil {
+		jsonConfig.StreamSetting = newJSONStreamConfig(config.StreamSettings)
+	}
+	return jsonConfig, nil
+}
+
+func newJSONInboundDetourConfig(config *InboundDetourConfigPB) (*jsonInboundDetourConfig, error) {
+	jsonConfig := &jsonInboundDetourConfig{
+		Protocol:        config.Protocol,
+		PortRange:       config.Port,
+		ListenOn:        config.Listen,
+		Tag:             config.Tag,
+		AllowPassive:    config.AllowPassive,
+		DefaultOutbound: config.DefaultOutboundTag,
+		AllowSources:    config.AllowSources,
+		DenySources:     config.DenySources,
+	}
+	if allocation := config.Allocate; allocation != nil {
+		jsonConfig.Allocation = &jsonAllocationConfig{
+			Strategy:    allocation.Strategy,
+			Concurrency: allocation.Concurrency,
+			RefreshMin:  allocation.Refresh,
+		}
+	}
+	var err error
+	if jsonConfig.Settings, err = proxySettingsToJSON(config.Settings); err != nil {
+		return nil, err
+	}
+	if config.StreamSettings != nil {
+		jsonConfig.StreamSetting = newJSONStreamConfig(config.StreamSettings)
+	}
+	return jsonConfig, nil
+}
+
+func newJSONOutboundDetourConfig(config *OutboundDetourConfigPB) (*jsonOutboundDetourConfig, error) {
+	jsonConfig := &jsonOutboundDetourConfig{
+		Protocol:    config.Protocol,
+		SendThrough: config.SendThrough,
+		Tag:         config.Tag,
+	}
+	var err error
+	if jsonConfig.Settings, err = proxySettingsToJSON(config.Settings); err != nil {
+		return nil, err
+	}
+	if config.StreamSettings != nil {
+		jsonConfig.StreamSetting = newJSONStreamConfig(config.StreamSettings)
+	}
+	return jsonConfig, nil
+}
+
+func newJSONStreamConfig(config *StreamConfigPB) *jsonStreamConfig {
+	jsonConfig := &jsonStreamConfig{
+		Network:  config.Network,
+		Security: config.Security,
+		Custom:   config.Custom,
+		Resolver: config.Resolver,
+	}
+	if config.Tls != nil {
+		jsonConfig.TLSSettings = newJSONTLSConfig(config.Tls)
+	}
+	if realityConfig := config.Reality; realityConfig != nil {
+		jsonReality := &jsonRealityConfig{
+			Dest:        realityConfig.Dest,
+			ServerNames: realityConfig.ServerNames,
+			MaxTimeDiff: realityConfig.MaxTimeDiff,
+			ServerName:  realityConfig.ServerName,
+		}
+		if len(realityConfig.PrivateKey) > 0 {
+			jsonReality.PrivateKey = base64.RawURLEncoding.EncodeToString(realityConfig.PrivateKey)
+		}
+		if len(realityConfig.PublicKey) > 0 {
+			jsonReality.PublicKey = base64.RawURLEncoding.EncodeToString(realityConfig.PublicKey)
+		}
+		for _, shortId := range realityConfig.ShortIds {
+			jsonReality.ShortIds = append(jsonReality.ShortIds, hex.EncodeToString(shortId))
+		}
+		if len(realityConfig.ShortId) > 0 {
+			jsonReality.ShortId = hex.EncodeToString(realityConfig.ShortId)
+		}
+		jsonConfig.RealitySettings, _ = json.Marshal(jsonReality)
+	}
+	if pluginConfig := config.Plugin; pluginConfig != nil {
+		jsonConfig.PluginSettings, _ = json.Marshal(&jsonPluginConfig{
+			Address:      pluginConfig.Address,
+			Command:      pluginConfig.Command,
+			Args:         pluginConfig.Args,
+			StartTimeout: pluginConfig.StartTimeout,
+			Options:      pluginConfig.Options,
+		})
+	}
+	return jsonConfig
+}
+
+func newJSONTLSConfig(config *TLSConfigPB) *jsonTLSConfig {
+	jsonConfig := &jsonTLSConfig{
+		Insecure:         config.AllowInsecure,
+		SessionCacheSize: config.SessionCacheSize,
+		MinVersion:       config.MinVersion,
+		MaxVersion:       config.MaxVersion,
+		ClientCAFiles:    config.ClientCaFiles,
+		ClientEmails:     config.ClientEmails,
+	}
+	if config.DisableSessionResumption {
+		sessionTickets := false
+		jsonConfig.SessionTickets = &sessionTickets
+	}
+	if len(config.EchConfigList) > 0 {
+		jsonConfig.ECHConfigList = base64.StdEncoding.EncodeToString(config.EchConfigList)
+	}
+	for _, cert := range config.Certificates {
+		jsonConfig.Certs = append(jsonConfig.Certs, &jsonCertificateConfig{
+			CertFile: cert.CertificateFile,
+			KeyFile:  cert.KeyFile,
+		})
+	}
+	if acme := config.Acme; acme != nil {
+		jsonConfig.ACME = &jsonACMEConfig{
+			Domains:      acme.Domains,
+			Email:        acme.Email,
+			CacheDir:     acme.CacheDir,
+			DirectoryURL: acme.DirectoryUrl,
+			HTTPAddress:  acme.HttpAddress,
+		}
+	}
+	return jsonConfig
+}
+
+type jsonTCPConfig struct {
+	ConnectionReuse bool `json:"connectionReuse"`
+	MultipathTCP    bool `json:"mptcp,omitempty"`
+}
+
+type jsonWebSocketConfig struct {
+	ConnectionReuse bool   `json:"connectionReuse"`
+	Path            string `json:"Path,omitempty"`
+	Pto             string `json:"Pto,omitempty"`
+	Cert            string `json:"Cert,omitempty"`
+	PrivKey         string `json:"PrivKey,omitempty"`
+	BrowserDialer   string `json:"browserDialer,omitempty"`
+}
+
+func newJSONTransportConfig(config *TransportConfigPB) (*jsonTransportConfig, error) {
+	jsonConfig := &jsonTransportConfig{
+		KCPConfig: config.Kcp,
+	}
+	var err error
+	if tcpConfig := config.Tcp; tcpConfig != nil {
+		if jsonConfig.TCPConfig, err = json.Marshal(&jsonTCPConfig{
+			ConnectionReuse: tcpConfig.ConnectionReuse,
+			MultipathTCP:    tcpConfig.Mptcp,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if wsConfig := config.Websocket; wsConfig != nil {
+		if jsonConfig.WSConfig, err = json.Marshal(&jsonWebSocketConfig{
+			ConnectionReuse: wsConfig.ConnectionReuse,
+			Path:            wsConfig.Path,
+			Pto:             wsConfig.Pto,
+			Cert:            wsConfig.Cert,
+			PrivKey:         wsConfig.PrivKey,
+			BrowserDialer:   wsConfig.BrowserDialer,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return jsonConfig, nil
+}
+
+type jsonThrottleLimit struct {
+	Uplink   uint64 `json:"uplink,omitempty"`
+	Downlink uint64 `json:"downlink,omitempty"`
+	Shared   bool   `json:"shared,omitempty"`
+}
+
+type jsonThrottleConfig struct {
+	Levels    map[string]*jsonThrottleLimit `json:"levels,omitempty"`
+	Inbounds  map[string]*jsonThrottleLimit `json:"inbounds,omitempty"`
+	Outbounds map[string]*jsonThrottleLimit `json:"outbounds,omitempty"`
+}
+
+func newJSONThrottleLimits(limits map[string]*ThrottleLimitPB) map[string]*jsonThrottleLimit {
+	if len(limits) == 0 {
+		return nil
+	}
+	jsonLimits := make(map[string]*jsonThrottleLimit, len(limits))
+	for key, limit := range limits {
+		jsonLimits[key] = &jsonThrottleLimit{
+			Uplink:   limit.Uplink,
+			Downlink: limit.Downlink,
+			Shared:   limit.Shared,
+		}
+	}
+	return jsonLimits
+}
+
+func newJSONThrottleConfig(config *ThrottleConfigPB) *jsonThrottleConfig {
+	levels := make(map[string]*ThrottleLimitPB, len(config.Levels))
+	for level, limit := range config.Levels {
+		levels[strconv.FormatUint(uint64(level), 10)] = limit
+	}
+	return &jsonThrottleConfig{
+		Levels:    newJSONThrottleLimits(levels),
+		Inbounds:  newJSONThrottleLimits(config.Inbounds),
+		Outbounds: newJSONThrottleLimits(config.Outbounds),
+	}
+}
+
+// jsonAppConfig is the config of an app that serves HTTP, e.g. api or metrics.
+type jsonAppConfig struct {
+	Listen *v2net.AddressPB `json:"listen,omitempty"`
+	Port   uint32           `json:"port"`
+	Path   string           `json:"path,omitempty"`
+}
+
+type jsonStatsConfig struct {
+	Users       bool   `json:"users,omitempty"`
+	Inbounds    bool   `json:"inbounds,omitempty"`
+	Outbounds   bool   `json:"outbounds,omitempty"`
+	UserDevices bool   `json:"userDevices,omitempty"`
+	DeviceLimit uint32 `json:"deviceLimit,omitempty"`
+}
+
+type jsonDebugConfig struct {
+	Port    uint32 `json:"port"`
+	Enabled bool   `json:"enabled,omitempty"`
+}
+
+type jsonHealthConfig struct {
+	Listen    *v2net.AddressPB `json:"listen,omitempty"`
+	Port      uint32           `json:"port"`
+	Path      string           `json:"path,omitempty"`
+	Outbounds []string         `json:"outbounds,omitempty"`
+}
+
+type jsonTracingConfig struct {
+	Endpoint    string            `json:"endpoint"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ServiceName string            `json:"serviceName,omitempty"`
+	SampleRate  float64           `json:"sampleRate"`
+}
+
+type jsonWebhookConfig struct {
+	URL   string   `json:"url"`
+	Types []string `json:"types,omitempty"`
+}
+
+type jsonExecConfig struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	Types   []string `json:"types,omitempty"`
+}
+
+type jsonAuthFailuresConfig struct {
+	Limit  uint32 `json:"limit"`
+	Window uint32 `json:"window,omitempty"`
+}
+
+type jsonEventsConfig struct {
+	Webhooks          []*jsonWebhookConfig    `json:"webhooks,omitempty"`
+	Execs             []*jsonExecConfig       `json:"exec,omitempty"`
+	AuthFailures      *jsonAuthFailuresConfig `json:"authFailures,omitempty"`
+	CertificateExpiry uint32                  `json:"certificateExpiry,omitempty"`
+}
+
+func newJSONEventsConfig(config *EventsConfigPB) *jsonEventsConfig {
+	jsonConfig := &jsonEventsConfig{
+		CertificateExpiry: config.CertificateExpiry,
+	}
+	for _, webhook := range config.Webhooks {
+		jsonConfig.Webhooks = append(jsonConfig.Webhooks, &jsonWebhookConfig{
+			URL:   webhook.Url,
+			Types: webhook.Types,
+		})
+	}
+	for _, execConfig := range config.Execs {
+		jsonConfig.Execs = append(jsonConfig.Execs, &jsonExecConfig{
+			Command: execConfig.Command,
+			Args:    execConfig.Args,
+			Types:   execConfig.Types,
+		})
+	}
+	if config.AuthFailureLimit > 0 {
+		jsonConfig.AuthFailures = &jsonAuthFailuresConfig{
+			Limit:  config.AuthFailureLimit,
+			Window: config.AuthFailureWindow,
+		}
+	}
+	return jsonConfig
+}
+
+type jsonPolicyConfig struct {
+	Levels map[string]*policy.JsonPolicy `json:"levels,omitempty"`
+	Ban    *policy.JsonBan               `json:"ban,omitempty"`
+}
+
+func newJSONPolicyConfig(config *PolicyConfigPB) *jsonPolicyConfig {
+	jsonConfig := new(jsonPolicyConfig)
+	if len(config.Levels) > 0 {
+		jsonConfig.Levels = make(map[string]*policy.JsonPolicy, len(config.Levels))
+	}
+	for level, levelPolicy := range config.Levels {
+		handshake := levelPolicy.Handshake
+		connectionIdle := levelPolicy.ConnectionIdle
+		uplinkOnly := levelPolicy.UplinkOnly
+		downlinkOnly := levelPolicy.DownlinkOnly
+		bufferSize := int(levelPolicy.BufferSize)
+		udpIdle := levelPolicy.UdpIdle
+		jsonConfig.Levels[strconv.FormatUint(uint64(level), 10)] = &policy.JsonPolicy{
+			Handshake:     &handshake,
+			ConnIdle:      &connectionIdle,
+			UplinkOnly:    &uplinkOnly,
+			DownlinkOnly:  &downlinkOnly,
+			BufferSize:    &bufferSize,
+			PooledBuffers: int(levelPolicy.PooledBuffers),
+			BufferClass:   levelPolicy.BufferClass,
+			MaxConns:      int(levelPolicy.MaxConnections),
+			ConnQueue:     levelPolicy.ConnectionQueue,
+			Quota:         int64(levelPolicy.Quota),
+			QuotaPeriod:   levelPolicy.QuotaPeriod,
+			OverQuotaRate: levelPolicy.OverQuotaRate,
+			UDPIdle:       &udpIdle,
+			UDPSessions:   int(levelPolicy.UdpSessions),
+		}
+	}
+	if ban := config.Ban; ban != nil {
+		jsonConfig.Ban = &policy.JsonBan{
+			Failures:    int(ban.Failures),
+			Window:      ban.Window,
+			Duration:    ban.Duration,
+			MaxDuration: ban.MaxDuration,
+		}
+	}
+	return jsonConfig
+}
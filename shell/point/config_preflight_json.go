@@ -0,0 +1,73 @@
+// +build json
+
+package point
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+// checkCertificate loads the certificate of a TLS certificate config, i.e. an object of certificateFile and keyFile.
+func checkCertificate(object map[string]interface{}) error {
+	certFile, _ := object["certificateFile"].(string)
+	keyFile, _ := object["keyFile"].(string)
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		return errors.New("Point: Failed to load certificate file " + certFile + ": " + err.Error())
+	}
+	return nil
+}
+
+// removeBadCertificates checks the certificates in a JSON document, and returns a copy of the document without the
+// ones failed to load, so that the rest of the document can still be parsed and checked.
+func removeBadCertificates(document interface{}) (interface{}, []error) {
+	var errs []error
+	switch value := document.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(value))
+		for key, field := range value {
+			checked, fieldErrs := removeBadCertificates(field)
+			errs = append(errs, fieldErrs...)
+			object[key] = checked
+		}
+		return object, errs
+	case []interface{}:
+		array := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			if object, ok := item.(map[string]interface{}); ok {
+				if _, found := object["certificateFile"]; found {
+					if err := checkCertificate(object); err != nil {
+						errs = append(errs, err)
+						continue
+					}
+				}
+			}
+			checked, itemErrs := removeBadCertificates(item)
+			errs = append(errs, itemErrs...)
+			array = append(array, checked)
+		}
+		return array, errs
+	default:
+		return document, nil
+	}
+}
+
+func jsonPreflightConfig(files ...string) (*Config, []error) {
+	document, err := loadConfigDocument(files...)
+	if err != nil {
+		return nil, []error{err}
+	}
+	document, errs := removeBadCertificates(document)
+	config, err := parseConfigDocument(document)
+	if err != nil {
+		return nil, append(errs, err)
+	}
+	errs = append(errs, Preflight(config)...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return config, nil
+}
+
+func init() {
+	configPreflighter = jsonPreflightConfig
+}
@@ -0,0 +1,171 @@
+package point
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	// signatureSuffix is appended to the URL of a remote config for its detached signature.
+	signatureSuffix = ".sig"
+
+	remoteConfigTimeout = 30 * time.Second
+	maxRemoteConfigSize = 16 * 1024 * 1024
+)
+
+var (
+	ErrRemoteConfigKeyNotSet = errors.New("Point: Public key of remote config is not set.")
+	ErrInvalidSignature      = errors.New("Point: Invalid signature of remote config.")
+
+	configPublicKey    ed25519.PublicKey
+	configCacheDir     string
+	remoteConfigClient = &http.Client{Timeout: remoteConfigTimeout}
+)
+
+// decodeKeyString decodes a key or a signature in base64, in the standard or URL encoding, with or without padding.
+func decodeKeyString(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	return base64.RawStdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "/").Replace(s))
+}
+
+// SetConfigPublicKey sets the Ed25519 public key in base64, which signatures of remote configs are verified with.
+func SetConfigPublicKey(key string) error {
+	publicKey, err := decodeKeyString(key)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return errors.New("Point: Invalid public key of remote config: " + key)
+	}
+	configPublicKey = publicKey
+	return nil
+}
+
+// SetConfigCacheDir sets the directory where the last verified remote configs are kept. Remote configs are not cached
+// if it is empty.
+func SetConfigCacheDir(dir string) {
+	configCacheDir = dir
+}
+
+// isRemoteConfig returns true if a config file is an HTTPS URL.
+func isRemoteConfig(file string) bool {
+	return strings.HasPrefix(file, "https://")
+}
+
+// verifyConfig verifies a config against its detached signature, which is either raw bytes or base64.
+func verifyConfig(content []byte, signature []byte) error {
+	if len(configPublicKey) == 0 {
+		return ErrRemoteConfigKeyNotSet
+	}
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := decodeKeyString(string(signature))
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		signature = decoded
+	}
+	if !ed25519.Verify(configPublicKey, content, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func fetch(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("Point: Failed to fetch " + url + ": " + resp.Status)
+	}
+	content, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxRemoteConfigSize))
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// cacheFile returns the file in the cache directory for a remote config.
+func cacheFile(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(configCacheDir, hex.EncodeToString(hash[:16]))
+}
+
+// readCachedConfig reads the cached copy of a remote config, and verifies it again.
+func readCachedConfig(url string) ([]byte, error) {
+	file := cacheFile(url)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := ioutil.ReadFile(file + signatureSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyConfig(content, signature); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func writeCachedConfig(url string, content []byte, signature []byte) error {
+	if err := os.MkdirAll(configCacheDir, 0700); err != nil {
+		return err
+	}
+	file := cacheFile(url)
+	if err := ioutil.WriteFile(file+signatureSuffix, signature, 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0600)
+}
+
+// fetchRemoteConfig fetches a remote config and its signature at the URL followed by ".sig", and verifies it. The
+// last verified config is cached, and used if the fetch fails.
+func fetchRemoteConfig(url string) ([]byte, error) {
+	if len(configPublicKey) == 0 {
+		return nil, ErrRemoteConfigKeyNotSet
+	}
+	content, err := fetch(remoteConfigClient, url)
+	var signature []byte
+	if err == nil {
+		signature, err = fetch(remoteConfigClient, url+signatureSuffix)
+	}
+	if err == nil {
+		err = verifyConfig(content, signature)
+	}
+	if err != nil {
+		if len(configCacheDir) == 0 {
+			return nil, err
+		}
+		log.Warning("Point: Failed to fetch remote config (", url, "): ", err, ". The last verified one is used.")
+		cached, cacheErr := readCachedConfig(url)
+		if cacheErr != nil {
+			log.Error("Point: Failed to read cached config of ", url, ": ", cacheErr)
+			return nil, err
+		}
+		return cached, nil
+	}
+	if len(configCacheDir) > 0 {
+		if err := writeCachedConfig(url, content, signature); err != nil {
+			log.Warning("Point: Failed to cache remote config (", url, "): ", err)
+		}
+	}
+	return content, nil
+}
+
+// readConfigFile reads a config file, or fetches it if it is an HTTPS URL.
+func readConfigFile(file string) ([]byte, error) {
+	if isRemoteConfig(file) {
+		return fetchRemoteConfig(file)
+	}
+	return ioutil.ReadFile(file)
+}
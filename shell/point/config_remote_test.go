@@ -0,0 +1,74 @@
+package point
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"v2ray.com/core/testing/assert"
+)
+
+func TestFetchRemoteConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.Error(err).IsNil()
+	content := []byte(`{"inbound": {"port": 1080}}`)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(private, content))
+
+	available := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/config.json":
+			w.Write(content)
+		case "/config.json.sig":
+			w.Write([]byte(signature))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	remoteConfigClient = server.Client()
+
+	dir, err := ioutil.TempDir("", "v2ray")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+	SetConfigCacheDir(dir)
+	defer SetConfigCacheDir("")
+
+	url := server.URL + "/config.json"
+	_, err = readConfigFile(url)
+	assert.Error(err).Equals(ErrRemoteConfigKeyNotSet)
+
+	assert.Error(SetConfigPublicKey(base64.RawURLEncoding.EncodeToString(public))).IsNil()
+	defer func() { configPublicKey = nil }()
+	fetched, err := readConfigFile(url)
+	assert.Error(err).IsNil()
+	assert.Bytes(fetched).Equals(content)
+
+	// The cached config is used when the server is unavailable.
+	available = false
+	fetched, err = readConfigFile(url)
+	assert.Error(err).IsNil()
+	assert.Bytes(fetched).Equals(content)
+
+	// A config with a bad signature is rejected, and the cached one is used.
+	available = true
+	content = []byte(`{"inbound": {"port": 1081}}`)
+	fetched, err = readConfigFile(url)
+	assert.Error(err).IsNil()
+	assert.Bytes(fetched).Equals([]byte(`{"inbound": {"port": 1080}}`))
+
+	SetConfigCacheDir("")
+	_, err = readConfigFile(url)
+	assert.Error(err).Equals(ErrInvalidSignature)
+}
@@ -0,0 +1,68 @@
+// +build json
+
+package point
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+var (
+	// secretKeys are the fields of secrets, e.g. VMess user IDs and Shadowsocks passwords. Each of them may be read from
+	// a file instead, by a field of the key followed by "File", e.g. {"passwordFile": "/etc/v2ray/password"}.
+	secretKeys = []string{"id", "password", "pass", "privateKey"}
+)
+
+// readSecretFile reads a secret from a file. Line breaks at the end of the file are removed.
+func readSecretFile(file string) (string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// resolveSecretFiles replaces the secret file fields in a JSON document with the secrets in the files. Relative paths
+// are relative to the working directory, as certificate files are.
+func resolveSecretFiles(document interface{}) (interface{}, error) {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			resolved, err := resolveSecretFiles(field)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = resolved
+		}
+		for _, key := range secretKeys {
+			fileKey := key + "File"
+			rawFile, found := value[fileKey]
+			if !found {
+				continue
+			}
+			if _, found := value[key]; found {
+				return nil, errors.New("Point: Both " + key + " and " + fileKey + " are specified.")
+			}
+			file, ok := rawFile.(string)
+			if !ok {
+				return nil, errors.New("Point: " + fileKey + " is not a string.")
+			}
+			secret, err := readSecretFile(file)
+			if err != nil {
+				return nil, errors.New("Point: Failed to read " + fileKey + ": " + err.Error())
+			}
+			delete(value, fileKey)
+			value[key] = secret
+		}
+	case []interface{}:
+		for idx, item := range value {
+			resolved, err := resolveSecretFiles(item)
+			if err != nil {
+				return nil, err
+			}
+			value[idx] = resolved
+		}
+	}
+	return document, nil
+}
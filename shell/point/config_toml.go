@@ -0,0 +1,19 @@
+// +build json,toml
+
+package point
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+func decodeTOMLConfig(data []byte) (interface{}, error) {
+	document := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func init() {
+	RegisterConfigFormat("toml", []string{".toml"}, decodeTOMLConfig)
+}
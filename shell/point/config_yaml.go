@@ -0,0 +1,19 @@
+// +build json,yaml
+
+package point
+
+import (
+	"gopkg.in/yaml.v2"
+)
+
+func decodeYAMLConfig(data []byte) (interface{}, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func init() {
+	RegisterConfigFormat("yaml", []string{".yaml", ".yml"}, decodeYAMLConfig)
+}
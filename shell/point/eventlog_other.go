@@ -0,0 +1,17 @@
+// +build !windows
+
+package point
+
+import (
+	"errors"
+
+	"v2ray.com/core/common/log"
+)
+
+var (
+	errEventLogUnsupported = errors.New("Point: Windows Event Log is only available on Windows.")
+)
+
+func openEventLog(source string) (log.ErrorLogHandler, error) {
+	return nil, errEventLogUnsupported
+}
@@ -0,0 +1,31 @@
+// +build windows
+
+package point
+
+import (
+	"golang.org/x/sys/windows/svc/eventlog"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	// eventLogEventID is the ID of all events that V2Ray writes to the event log.
+	eventLogEventID = 1
+)
+
+func openEventLog(source string) (log.ErrorLogHandler, error) {
+	eventLog, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return func(level log.LogLevel, message string) {
+		switch level {
+		case log.ErrorLevel:
+			eventLog.Error(eventLogEventID, message)
+		case log.WarningLevel:
+			eventLog.Warning(eventLogEventID, message)
+		default:
+			eventLog.Info(eventLogEventID, message)
+		}
+	}, nil
+}
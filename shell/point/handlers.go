@@ -0,0 +1,272 @@
+package point
+
+import (
+	"errors"
+	"time"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet/plugin"
+)
+
+var (
+	ErrUsersNotSupported = errors.New("Point: Protocol doesn't support users.")
+	ErrUsersNotAlterable = errors.New("Point: Users of inbounds with random allocation can't be altered.")
+)
+
+// withConfig returns a shallow copy of the current config for changes, as configs may be shared with callers.
+func (this *Point) withConfig() *Config {
+	config := *this.config
+	return &config
+}
+
+func findInboundDetourByTag(configs []*InboundDetourConfig, tag string) int {
+	for idx, config := range configs {
+		if config.Tag == tag {
+			return idx
+		}
+	}
+	return -1
+}
+
+func findOutboundDetourByTag(configs []*OutboundDetourConfig, tag string) int {
+	for idx, config := range configs {
+		if config.Tag == tag {
+			return idx
+		}
+	}
+	return -1
+}
+
+// AddInbound adds and starts an inbound detour while the server is running. The detour must have a unique tag.
+// Inbounds added this way are removed by reloads, unless they are in the reloaded config as well.
+func (this *Point) AddInbound(config *InboundDetourConfig) error {
+	this.reload.Lock()
+	defer this.reload.Unlock()
+
+	if len(config.Tag) == 0 {
+		this.logger.Error("Point: Tag of inbound is not specified.")
+		return common.ErrBadConfiguration
+	}
+	if findInboundDetourByTag(this.config.InboundDetours, config.Tag) >= 0 {
+		this.logger.Error("Point: Inbound ", config.Tag, " already exists.")
+		return common.ErrDuplicatedName
+	}
+	detourHandler, err := newInboundDetourHandler(this.space, config)
+	if err != nil {
+		return err
+	}
+	if err := detourHandler.Start(); err != nil {
+		detourHandler.Close()
+		return err
+	}
+
+	newConfig := this.withConfig()
+	newConfig.InboundDetours = append(append([]*InboundDetourConfig(nil), this.config.InboundDetours...), config)
+
+	this.Lock()
+	this.idh = append(append([]InboundDetourHandler(nil), this.idh...), detourHandler)
+	this.taggedIdh[config.Tag] = detourHandler
+	this.config = newConfig
+	this.Unlock()
+
+	this.logger.Info("Point: Inbound ", config.Tag, " added.")
+	return nil
+}
+
+// RemoveInbound stops and removes the inbound detour with the given tag. Connections already accepted finish normally.
+func (this *Point) RemoveInbound(tag string) error {
+	this.reload.Lock()
+	defer this.reload.Unlock()
+
+	idx := findInboundDetourByTag(this.config.InboundDetours, tag)
+	if idx < 0 {
+		this.logger.Error("Point: Inbound ", tag, " not found.")
+		return common.ErrObjectNotFound
+	}
+	detourHandler := this.idh[idx]
+	detourHandler.Close()
+
+	newConfig := this.withConfig()
+	newConfig.InboundDetours = append(append([]*InboundDetourConfig(nil), this.config.InboundDetours[:idx]...), this.config.InboundDetours[idx+1:]...)
+
+	this.Lock()
+	this.idh = append(append([]InboundDetourHandler(nil), this.idh[:idx]...), this.idh[idx+1:]...)
+	delete(this.taggedIdh, tag)
+	this.config = newConfig
+	this.Unlock()
+
+	this.logger.Info("Point: Inbound ", tag, " removed.")
+	return nil
+}
+
+// AddOutbound adds an outbound detour while the server is running. The detour must have a unique tag.
+func (this *Point) AddOutbound(config *OutboundDetourConfig) error {
+	this.reload.Lock()
+	defer this.reload.Unlock()
+
+	if len(config.Tag) == 0 {
+		this.logger.Error("Point: Tag of outbound is not specified.")
+		return common.ErrBadConfiguration
+	}
+	if findOutboundDetourByTag(this.config.OutboundDetours, config.Tag) >= 0 {
+		this.logger.Error("Point: Outbound ", config.Tag, " already exists.")
+		return common.ErrDuplicatedName
+	}
+	detourHandler, err := newOutboundDetourHandler(this.space, config)
+	if err != nil {
+		return err
+	}
+
+	newConfig := this.withConfig()
+	newConfig.OutboundDetours = append(append([]*OutboundDetourConfig(nil), this.config.OutboundDetours...), config)
+
+	this.Lock()
+	this.odh[config.Tag] = detourHandler
+	this.config = newConfig
+	this.Unlock()
+	this.ohm.SetHandler(config.Tag, detourHandler)
+
+	this.logger.Info("Point: Outbound ", config.Tag, " added.")
+	return nil
+}
+
+// RemoveOutbound removes the outbound detour with the given tag. Connections already dispatched finish normally.
+func (this *Point) RemoveOutbound(tag string) error {
+	this.reload.Lock()
+	defer this.reload.Unlock()
+
+	idx := findOutboundDetourByTag(this.config.OutboundDetours, tag)
+	if idx < 0 {
+		this.logger.Error("Point: Outbound ", tag, " not found.")
+		return common.ErrObjectNotFound
+	}
+	this.ohm.RemoveHandler(tag)
+	if p := pluginOf(this.config.OutboundDetours[idx].StreamSettings); p != nil {
+		plugin.Stop(p)
+	}
+
+	newConfig := this.withConfig()
+	newConfig.OutboundDetours = append(append([]*OutboundDetourConfig(nil), this.config.OutboundDetours[:idx]...), this.config.OutboundDetours[idx+1:]...)
+
+	this.Lock()
+	delete(this.odh, tag)
+	this.config = newConfig
+	this.Unlock()
+
+	this.logger.Info("Point: Outbound ", tag, " removed.")
+	return nil
+}
+
+// AlterInbound adds users to and removes users by email from the inbound detour with the given tag, while it keeps
+// running. The changes are not written to the config, so they are undone when a reload replaces the inbound.
+func (this *Point) AlterInbound(tag string, addUsers []*protocol.User, removeEmails []string) error {
+	this.reload.Lock()
+	defer this.reload.Unlock()
+
+	this.RLock()
+	detourHandler, found := this.taggedIdh[tag]
+	this.RUnlock()
+	if !found {
+		this.logger.Error("Point: Inbound ", tag, " not found.")
+		return common.ErrObjectNotFound
+	}
+	always, ok := detourHandler.(*InboundDetourHandlerAlways)
+	if !ok {
+		return ErrUsersNotAlterable
+	}
+	managers := make([]proxy.UserManager, 0, len(always.ich))
+	for _, ich := range always.ich {
+		manager, ok := ich.(proxy.UserManager)
+		if !ok {
+			return ErrUsersNotSupported
+		}
+		managers = append(managers, manager)
+	}
+
+	for _, manager := range managers {
+		for _, user := range addUsers {
+			if err := manager.AddUser(user); err != nil {
+				return err
+			}
+		}
+		for _, email := range removeEmails {
+			if err := manager.RemoveUser(email); err != nil {
+				return err
+			}
+		}
+	}
+	this.logger.Info("Point: Users of inbound ", tag, " altered.")
+	return nil
+}
+
+// apiHandlerManager is the api.HandlerManager of a Point, which decodes configs and users in JSON.
+type apiHandlerManager struct {
+	point *Point
+}
+
+func (this *apiHandlerManager) AddInbound(data []byte) error {
+	if inboundDetourDecoder == nil {
+		return common.ErrBadConfiguration
+	}
+	config, err := inboundDetourDecoder(data)
+	if err != nil {
+		return errors.New("Point: Invalid inbound: " + err.Error())
+	}
+	return this.point.AddInbound(config)
+}
+
+func (this *apiHandlerManager) RemoveInbound(tag string) error {
+	return this.point.RemoveInbound(tag)
+}
+
+func (this *apiHandlerManager) AddOutbound(data []byte) error {
+	if outboundDetourDecoder == nil {
+		return common.ErrBadConfiguration
+	}
+	config, err := outboundDetourDecoder(data)
+	if err != nil {
+		return errors.New("Point: Invalid outbound: " + err.Error())
+	}
+	return this.point.AddOutbound(config)
+}
+
+func (this *apiHandlerManager) RemoveOutbound(tag string) error {
+	return this.point.RemoveOutbound(tag)
+}
+
+func (this *apiHandlerManager) AlterInbound(tag string, addUsers [][]byte, removeEmails []string) error {
+	var users []*protocol.User
+	if len(addUsers) > 0 {
+		this.point.RLock()
+		idx := findInboundDetourByTag(this.point.config.InboundDetours, tag)
+		var protocolName string
+		if idx >= 0 {
+			protocolName = this.point.config.InboundDetours[idx].Protocol
+		}
+		this.point.RUnlock()
+		if idx < 0 {
+			log.Error("Point: Inbound ", tag, " not found.")
+			return common.ErrObjectNotFound
+		}
+		if inboundUserDecoder == nil {
+			return common.ErrBadConfiguration
+		}
+		decoded, err := inboundUserDecoder(protocolName, addUsers)
+		if err != nil {
+			return errors.New("Point: Invalid users: " + err.Error())
+		}
+		users = decoded
+	}
+	return this.point.AlterInbound(tag, users, removeEmails)
+}
+
+func (this *apiHandlerManager) StartTime() time.Time {
+	return this.point.StartTime()
+}
+
+func (this *apiHandlerManager) ConfigHash() string {
+	return this.point.ConfigHash()
+}
@@ -0,0 +1,73 @@
+package point
+
+import (
+	"net"
+	"sync"
+
+	"v2ray.com/core/app/dns"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	maxAccessHostnames = 4096
+)
+
+// accessHostnames looks up the hostnames of IP destinations in access logs. Lookups run in background, so the hostname
+// of an IP is logged from its next access on.
+type accessHostnames struct {
+	sync.Mutex
+	resolver dns.ReverseResolver
+	names    map[string]string
+	pending  map[string]bool
+}
+
+func newAccessHostnames(resolver dns.ReverseResolver) *accessHostnames {
+	return &accessHostnames{
+		resolver: resolver,
+		names:    make(map[string]string),
+		pending:  make(map[string]bool),
+	}
+}
+
+func (this *accessHostnames) Lookup(to interface{}) string {
+	var address v2net.Address
+	switch to := to.(type) {
+	case v2net.Destination:
+		address = to.Address
+	case v2net.Address:
+		address = to
+	}
+	if address == nil || address.Family().IsDomain() {
+		return ""
+	}
+	ip := address.IP()
+	key := ip.String()
+
+	this.Lock()
+	defer this.Unlock()
+
+	if name, found := this.names[key]; found {
+		return name
+	}
+	if !this.pending[key] {
+		this.pending[key] = true
+		go this.resolve(ip, key)
+	}
+	return ""
+}
+
+func (this *accessHostnames) resolve(ip net.IP, key string) {
+	name := ""
+	if domains := this.resolver.ReverseLookup(ip); len(domains) > 0 {
+		name = domains[0]
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	delete(this.pending, key)
+	if len(this.names) >= maxAccessHostnames {
+		this.names = make(map[string]string)
+	}
+	this.names[key] = name
+}
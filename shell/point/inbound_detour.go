@@ -9,3 +9,12 @@ type InboundDetourHandler interface {
 	Close()
 	GetConnectionHandler() (proxy.InboundHandler, int)
 }
+
+// handlerAccepting returns whether an inbound handler is accepting connections. Handlers that don't report it are
+// considered accepting.
+func handlerAccepting(handler interface{}) bool {
+	if reporter, ok := handler.(proxy.AcceptingReporter); ok {
+		return reporter.Accepting()
+	}
+	return true
+}
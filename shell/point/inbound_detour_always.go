@@ -6,7 +6,6 @@ import (
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/retry"
 	"v2ray.com/core/proxy"
-	proxyregistry "v2ray.com/core/proxy/registry"
 )
 
 // Handler for inbound detour connections.
@@ -24,13 +23,14 @@ func NewInboundDetourHandlerAlways(space app.Space, config *InboundDetourConfig)
 	ports := config.PortRange
 	handler.ich = make([]proxy.InboundHandler, 0, ports.To-ports.From+1)
 	for i := ports.FromPort(); i <= ports.ToPort(); i++ {
-		ichConfig := config.Settings
-		ich, err := proxyregistry.CreateInboundHandler(config.Protocol, space, ichConfig, &proxy.InboundHandlerMeta{
+		ich, err := createInboundHandler(config.Protocol, space, config.ProxySettings, config.Settings, &proxy.InboundHandlerMeta{
 			Address:                config.ListenOn,
 			Port:                   i,
 			Tag:                    config.Tag,
 			StreamSettings:         config.StreamSettings,
 			AllowPassiveConnection: config.AllowPassiveConnection,
+			DefaultOutboundTag:     config.DefaultOutboundTag,
+			SourceFilter:           config.SourceFilter,
 		})
 		if err != nil {
 			log.Error("Failed to create inbound connection handler: ", err)
@@ -52,6 +52,16 @@ func (this *InboundDetourHandlerAlways) Close() {
 	}
 }
 
+// Accepting returns whether the handlers on all ports are accepting connections.
+func (this *InboundDetourHandlerAlways) Accepting() bool {
+	for _, ich := range this.ich {
+		if !handlerAccepting(ich) {
+			return false
+		}
+	}
+	return true
+}
+
 // Starts the inbound connection handler.
 func (this *InboundDetourHandlerAlways) Start() error {
 	for _, ich := range this.ich {
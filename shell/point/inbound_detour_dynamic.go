@@ -10,7 +10,6 @@ import (
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/retry"
 	"v2ray.com/core/proxy"
-	proxyregistry "v2ray.com/core/proxy/registry"
 )
 
 type InboundDetourHandlerDynamic struct {
@@ -21,6 +20,7 @@ type InboundDetourHandlerDynamic struct {
 	ichs        []proxy.InboundHandler
 	ich2Recyle  []proxy.InboundHandler
 	lastRefresh time.Time
+	closed      bool
 }
 
 func NewInboundDetourHandlerDynamic(space app.Space, config *InboundDetourConfig) (*InboundDetourHandlerDynamic, error) {
@@ -32,12 +32,14 @@ func NewInboundDetourHandlerDynamic(space app.Space, config *InboundDetourConfig
 	handler.ichs = make([]proxy.InboundHandler, config.Allocation.Concurrency)
 
 	// To test configuration
-	ich, err := proxyregistry.CreateInboundHandler(config.Protocol, space, config.Settings, &proxy.InboundHandlerMeta{
+	ich, err := createInboundHandler(config.Protocol, space, config.ProxySettings, config.Settings, &proxy.InboundHandlerMeta{
 		Address:                config.ListenOn,
 		Port:                   0,
 		Tag:                    config.Tag,
 		StreamSettings:         config.StreamSettings,
 		AllowPassiveConnection: config.AllowPassiveConnection,
+		DefaultOutboundTag:     config.DefaultOutboundTag,
+		SourceFilter:           config.SourceFilter,
 	})
 	if err != nil {
 		log.Error("Point: Failed to create inbound connection handler: ", err)
@@ -74,11 +76,34 @@ func (this *InboundDetourHandlerDynamic) GetConnectionHandler() (proxy.InboundHa
 func (this *InboundDetourHandlerDynamic) Close() {
 	this.Lock()
 	defer this.Unlock()
+	this.closed = true
 	for _, ich := range this.ichs {
 		ich.Close()
 	}
 }
 
+// Accepting returns whether the handlers on the currently allocated ports are accepting connections.
+func (this *InboundDetourHandlerDynamic) Accepting() bool {
+	this.RLock()
+	defer this.RUnlock()
+
+	if this.closed || len(this.ichs) == 0 {
+		return false
+	}
+	for _, ich := range this.ichs {
+		if ich == nil || !handlerAccepting(ich) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *InboundDetourHandlerDynamic) isClosed() bool {
+	this.RLock()
+	defer this.RUnlock()
+	return this.closed
+}
+
 func (this *InboundDetourHandlerDynamic) RecyleHandles() {
 	if this.ich2Recyle != nil {
 		for _, ich := range this.ich2Recyle {
@@ -103,8 +128,9 @@ func (this *InboundDetourHandlerDynamic) refresh() error {
 	for idx := range newIchs {
 		err := retry.Timed(5, 100).On(func() error {
 			port := this.pickUnusedPort()
-			ich, err := proxyregistry.CreateInboundHandler(config.Protocol, this.space, config.Settings, &proxy.InboundHandlerMeta{
-				Address: config.ListenOn, Port: port, Tag: config.Tag, StreamSettings: config.StreamSettings})
+			ich, err := createInboundHandler(config.Protocol, this.space, config.ProxySettings, config.Settings, &proxy.InboundHandlerMeta{
+				Address: config.ListenOn, Port: port, Tag: config.Tag, StreamSettings: config.StreamSettings,
+				DefaultOutboundTag: config.DefaultOutboundTag, SourceFilter: config.SourceFilter})
 			if err != nil {
 				delete(this.portsInUse, port)
 				return err
@@ -141,6 +167,9 @@ func (this *InboundDetourHandlerDynamic) Start() error {
 	go func() {
 		for {
 			time.Sleep(time.Duration(this.config.Allocation.Refresh)*time.Minute - 1)
+			if this.isClosed() {
+				return
+			}
 			this.RecyleHandles()
 			err := this.refresh()
 			if err != nil {
@@ -0,0 +1,56 @@
+package point
+
+import (
+	"context"
+	"net"
+
+	"v2ray.com/core"
+	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	// DialInboundTag is the inbound tag of connections opened by Point.Dial, in routing rules and stats.
+	DialInboundTag = "dial"
+)
+
+var dialSource = v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(0))
+
+// Dial opens a connection to the destination through the outbounds, as the router tells. It implements core.Instance.
+// The outbound runs in ctx, so canceling ctx closes the connection after Dial returns as well.
+func (this *Point) Dial(ctx context.Context, destination v2net.Destination) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	packetDispatcher, ok := this.space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+	if !ok {
+		return nil, common.ErrObjectNotFound
+	}
+	this.logger.Info("Point: Dialing ", destination)
+	link := packetDispatcher.DispatchToOutbound(ctx, &proxy.InboundHandlerMeta{
+		Tag: DialInboundTag,
+	}, &proxy.SessionInfo{
+		Source:      dialSource,
+		Destination: destination,
+	})
+	return ray.NewConnection(link), nil
+}
+
+func init() {
+	core.RegisterInstanceCreator(func(config interface{}) (core.Instance, error) {
+		pointConfig, ok := config.(*Config)
+		if !ok || pointConfig == nil {
+			log.Error("Point: Config of an instance must be a *point.Config.")
+			return nil, common.ErrBadConfiguration
+		}
+		vpoint, err := NewPoint(pointConfig)
+		if err != nil {
+			return nil, err
+		}
+		return vpoint, nil
+	})
+}
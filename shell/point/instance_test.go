@@ -0,0 +1,174 @@
+// +build json
+
+package point_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"v2ray.com/core"
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/shell/point"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
+)
+
+func TestInstanceDial(t *testing.T) {
+	assert := assert.On(t)
+
+	tcpServer := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return append([]byte("Processed: "), data...)
+		},
+	}
+	dest, err := tcpServer.Start()
+	assert.Error(err).IsNil()
+	defer tcpServer.Close()
+
+	rawConfig := fmt.Sprintf(`{
+    "inbound": {
+      "port": %d,
+      "listen": "127.0.0.1",
+      "protocol": "dokodemo-door",
+      "settings": {"address": "127.0.0.1", "port": 53, "network": "tcp"}
+    },
+    "outbound": {
+      "protocol": "freedom",
+      "settings": {}
+    }
+  }`, pickPort())
+	config := new(Config)
+	assert.Error(json.Unmarshal([]byte(rawConfig), config)).IsNil()
+
+	_, err = core.New("config.json")
+	assert.Error(err).IsNotNil()
+
+	instance, err := core.New(config)
+	assert.Error(err).IsNil()
+	assert.Error(instance.Start()).IsNil()
+	defer instance.Close()
+
+	conn, err := instance.Dial(context.Background(), dest)
+	assert.Error(err).IsNil()
+	_, err = conn.Write([]byte("embedded"))
+	assert.Error(err).IsNil()
+	response := make([]byte, 64)
+	nBytes, err := conn.Read(response)
+	assert.Error(err).IsNil()
+	assert.String(string(response[:nBytes])).Equals("Processed: embedded")
+	assert.Error(conn.Close()).IsNil()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = instance.Dial(ctx, v2net.TCPDestination(v2net.LocalHostIP, dest.Port))
+	assert.Error(err).Equals(context.Canceled)
+
+	// Canceling the context closes an open connection.
+	ctx, cancel = context.WithCancel(context.Background())
+	conn, err = instance.Dial(ctx, dest)
+	assert.Error(err).IsNil()
+	_, err = conn.Write([]byte("canceled"))
+	assert.Error(err).IsNil()
+	nBytes, err = conn.Read(response)
+	assert.Error(err).IsNil()
+	assert.String(string(response[:nBytes])).Equals("Processed: canceled")
+	cancel()
+	closed := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(response)
+		closed <- err
+	}()
+	select {
+	case err := <-closed:
+		assert.Error(err).IsNotNil()
+	case <-time.After(time.Second * 2):
+		t.Fatal("Connection is not closed.")
+	}
+	conn.Close()
+}
+
+func newInstance(assert *assert.Assert, accessLog string) core.Instance {
+	rawConfig := fmt.Sprintf(`{
+    "log": {"access": %q, "accessFormat": "json", "loglevel": "warning"},
+    "inbound": {
+      "port": %d,
+      "listen": "127.0.0.1",
+      "protocol": "dokodemo-door",
+      "settings": {"address": "127.0.0.1", "port": 53, "network": "tcp"}
+    },
+    "outbound": {
+      "protocol": "freedom",
+      "settings": {}
+    }
+  }`, accessLog, pickPort())
+	config := new(Config)
+	assert.Error(json.Unmarshal([]byte(rawConfig), config)).IsNil()
+
+	instance, err := core.New(config)
+	assert.Error(err).IsNil()
+	assert.Error(instance.Start()).IsNil()
+	return instance
+}
+
+func readAccessLog(assert *assert.Assert, path string) string {
+	for i := 0; i < 50; i++ {
+		content, err := ioutil.ReadFile(path)
+		assert.Error(err).IsNil()
+		if len(content) > 0 {
+			return string(content)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return ""
+}
+
+func TestInstancesSideBySide(t *testing.T) {
+	assert := assert.On(t)
+
+	tcpServer := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return data
+		},
+	}
+	dest, err := tcpServer.Start()
+	assert.Error(err).IsNil()
+	defer tcpServer.Close()
+
+	dir, err := ioutil.TempDir("", "v2ray-instance")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	// Each instance logs its own accesses.
+	paths := []string{filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")}
+	instances := make([]core.Instance, len(paths))
+	for idx, path := range paths {
+		instances[idx] = newInstance(assert, path)
+	}
+	for idx, instance := range instances {
+		conn, err := instance.Dial(context.Background(), dest)
+		assert.Error(err).IsNil()
+		for i := 0; i <= idx; i++ {
+			_, err = conn.Write([]byte("ping"))
+			assert.Error(err).IsNil()
+			response := make([]byte, 4)
+			_, err = conn.Read(response)
+			assert.Error(err).IsNil()
+		}
+		assert.Error(conn.Close()).IsNil()
+	}
+	for idx, path := range paths {
+		content := readAccessLog(assert, path)
+		assert.Int(strings.Count(content, "\n")).Equals(1)
+		assert.String(content).Contains(fmt.Sprintf(`"uplink":%d`, 4*(idx+1)))
+	}
+	for _, instance := range instances {
+		instance.Close()
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"v2ray.com/core/app/api"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	apiServer  *string
+	apiTimeout *time.Duration
+	alterAdd   *string
+	alterTag   *string
+	alterRm    *string
+	statsReset *bool
+
+	errMissingArgument = errors.New("Missing argument.")
+)
+
+func addAPIFlags(flags *flag.FlagSet) {
+	apiServer = flags.String("server", "127.0.0.1:10085", "Address of the API server.")
+	apiTimeout = flags.Duration("timeout", 10*time.Second, "Timeout of each call.")
+}
+
+func callService(service string, name string, request proto.Message, response proto.Message) error {
+	client := api.NewClient(*apiServer, *apiTimeout)
+	if err := client.Call(service, name, request, response); err != nil {
+		fmt.Println("Failed to call", name+":", err)
+		return err
+	}
+	return nil
+}
+
+func callHandlerService(name string, request proto.Message, response proto.Message) error {
+	return callService(api.HandlerServiceName, name, request, response)
+}
+
+// readJSONFiles returns the content of JSON files. A file that contains an array returns its elements.
+func readJSONFiles(files []string) ([][]byte, error) {
+	var contents [][]byte
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var elements []json.RawMessage
+		if err := json.Unmarshal(content, &elements); err == nil {
+			for _, element := range elements {
+				contents = append(contents, element)
+			}
+			continue
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
+
+func addInbounds(args []string) error {
+	inbounds, err := readJSONFiles(args)
+	if err != nil {
+		return err
+	}
+	for _, inbound := range inbounds {
+		if err := callHandlerService("AddInbound", &api.AddInboundRequest{Inbound: inbound}, new(api.AddInboundResponse)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeInbounds(args []string) error {
+	for _, tag := range args {
+		if err := callHandlerService("RemoveInbound", &api.RemoveInboundRequest{Tag: tag}, new(api.RemoveInboundResponse)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addOutbounds(args []string) error {
+	outbounds, err := readJSONFiles(args)
+	if err != nil {
+		return err
+	}
+	for _, outbound := range outbounds {
+		if err := callHandlerService("AddOutbound", &api.AddOutboundRequest{Outbound: outbound}, new(api.AddOutboundResponse)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeOutbounds(args []string) error {
+	for _, tag := range args {
+		if err := callHandlerService("RemoveOutbound", &api.RemoveOutboundRequest{Tag: tag}, new(api.RemoveOutboundResponse)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func alterInbound(args []string) error {
+	if len(*alterTag) == 0 {
+		fmt.Println("Tag of the inbound is not specified.")
+		return errMissingArgument
+	}
+	request := &api.AlterInboundRequest{
+		Tag: *alterTag,
+	}
+	if len(*alterAdd) > 0 {
+		users, err := readJSONFiles([]string{*alterAdd})
+		if err != nil {
+			return err
+		}
+		request.AddUsers = users
+	}
+	if len(*alterRm) > 0 {
+		request.RemoveEmails = strings.Split(*alterRm, ",")
+	}
+	return callHandlerService("AlterInbound", request, new(api.AlterInboundResponse))
+}
+
+// queryStats prints the counters whose names contain any of the patterns in args, or all counters if args is empty.
+func queryStats(args []string) error {
+	patterns := args
+	if len(patterns) == 0 {
+		patterns = []string{""}
+	}
+	for _, pattern := range patterns {
+		response := new(api.QueryStatsResponse)
+		if err := callService(api.StatsServiceName, "QueryStats", &api.QueryStatsRequest{Pattern: pattern, Reset_: *statsReset}, response); err != nil {
+			return err
+		}
+		for _, stat := range response.Stat {
+			fmt.Printf("%s\t%d\n", stat.Name, stat.Value)
+		}
+	}
+	return nil
+}
+
+// listConnections prints the live connections, one per line.
+func listConnections(args []string) error {
+	response := new(api.ListConnectionsResponse)
+	if err := callService(api.ConnectionServiceName, "ListConnections", new(api.ListConnectionsRequest), response); err != nil {
+		return err
+	}
+	fmt.Println("ID\tSource\tDestination\tInbound\tOutbound\tUser\tDomain\tAge(s)\tUplink\tDownlink")
+	for _, conn := range response.Connection {
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n", conn.Id, conn.Source, conn.Destination, conn.Inbound,
+			conn.Outbound, conn.User, conn.Domain, conn.Age, conn.Uplink, conn.Downlink)
+	}
+	return nil
+}
+
+// closeConnections closes the connections of the IDs in args.
+func closeConnections(args []string) error {
+	if len(args) == 0 {
+		return errMissingArgument
+	}
+	for _, arg := range args {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return err
+		}
+		if err := callService(api.ConnectionServiceName, "CloseConnection", &api.CloseConnectionRequest{Id: id}, new(api.CloseConnectionResponse)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setDebug enables or disables the pprof and expvar endpoint by "on" or "off" in args.
+func setDebug(args []string) error {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		return errMissingArgument
+	}
+	response := new(api.SetDebugResponse)
+	if err := callService(api.DebugServiceName, "SetDebug", &api.SetDebugRequest{Enabled: args[0] == "on"}, response); err != nil {
+		return err
+	}
+	if response.Enabled {
+		fmt.Println("Debug endpoint is serving on", response.Address)
+	} else {
+		fmt.Println("Debug endpoint is stopped.")
+	}
+	return nil
+}
+
+// getVersion prints the build, uptime and config hash of the server in JSON.
+func getVersion(args []string) error {
+	response := new(api.GetVersionResponse)
+	if err := callService(api.VersionServiceName, "GetVersion", new(api.GetVersionRequest), response); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
+
+var (
+	apiSubcommands = []*command{
+		{
+			name:     "addinbound",
+			usage:    "Add inbounds in JSON files, each of which contains an inbound detour or an array of them.",
+			addFlags: addAPIFlags,
+			run:      addInbounds,
+		},
+		{
+			name:     "removeinbound",
+			usage:    "Remove inbounds by tags.",
+			addFlags: addAPIFlags,
+			run:      removeInbounds,
+		},
+		{
+			name:     "addoutbound",
+			usage:    "Add outbounds in JSON files, each of which contains an outbound detour or an array of them.",
+			addFlags: addAPIFlags,
+			run:      addOutbounds,
+		},
+		{
+			name:     "removeoutbound",
+			usage:    "Remove outbounds by tags.",
+			addFlags: addAPIFlags,
+			run:      removeOutbounds,
+		},
+		{
+			name:  "alterinbound",
+			usage: "Add users to or remove users from an inbound, e.g. VMess.",
+			addFlags: func(flags *flag.FlagSet) {
+				addAPIFlags(flags)
+				alterTag = flags.String("tag", "", "Tag of the inbound.")
+				alterAdd = flags.String("add", "", "JSON file of users to add, in the format of users in inbound settings.")
+				alterRm = flags.String("remove", "", "Emails of users to remove, separated by commas.")
+			},
+			run: alterInbound,
+		},
+		{
+			name:  "stats",
+			usage: "Print traffic counters whose names contain any of the given patterns, or all counters.",
+			addFlags: func(flags *flag.FlagSet) {
+				addAPIFlags(flags)
+				statsReset = flags.Bool("reset", false, "Reset the printed counters to zero.")
+			},
+			run: queryStats,
+		},
+		{
+			name:     "connections",
+			usage:    "List live connections.",
+			addFlags: addAPIFlags,
+			run:      listConnections,
+		},
+		{
+			name:     "closeconnection",
+			usage:    "Close connections by IDs.",
+			addFlags: addAPIFlags,
+			run:      closeConnections,
+		},
+		{
+			name:     "debug",
+			usage:    "Turn the pprof and expvar endpoint on or off.",
+			addFlags: addAPIFlags,
+			run:      setDebug,
+		},
+		{
+			name:     "version",
+			usage:    "Print the build, uptime and config hash of the server in JSON.",
+			addFlags: addAPIFlags,
+			run:      getVersion,
+		},
+	}
+)
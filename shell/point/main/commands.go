@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"v2ray.com/core"
+	"v2ray.com/core/shell/point"
+)
+
+// command is a subcommand of V2Ray, e.g. "v2ray test -config config.json".
+type command struct {
+	name  string
+	usage string
+	// addFlags adds the flags of the command, if any.
+	addFlags func(flags *flag.FlagSet)
+	run      func(args []string) error
+}
+
+// execute parses the flags of the command in args, and runs it with the rest of args.
+func (this *command) execute(args []string) error {
+	flags := flag.NewFlagSet(this.name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: v2ray", this.name, "[flags]")
+		fmt.Fprintln(os.Stderr, this.usage)
+		flags.PrintDefaults()
+	}
+	if this.addFlags != nil {
+		this.addFlags(flags)
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	return this.run(flags.Args())
+}
+
+var (
+	commands []*command
+
+	migrateInput  *string
+	migrateOutput *string
+
+	versionJSONFlag *bool
+
+	errInvalidCommand = errors.New("Invalid command.")
+)
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runSubcommand runs the subcommand in args[0], e.g. "install" of "v2ray service install", or prints the usage of
+// subcommands if it is not found.
+func runSubcommand(name string, subcommands []*command, args []string) error {
+	if len(args) > 0 {
+		for _, cmd := range subcommands {
+			if cmd.name == args[0] {
+				return cmd.execute(args[1:])
+			}
+		}
+	}
+	fmt.Println("Usage: v2ray", name, "<command> [flags]")
+	fmt.Println()
+	for _, cmd := range subcommands {
+		fmt.Printf("  %-16s %s\n", cmd.name, cmd.usage)
+	}
+	return errInvalidCommand
+}
+
+func printVersion() {
+	core.PrintVersion()
+	fmt.Println("Built by", runtime.Version(), "for", runtime.GOOS+"/"+runtime.GOARCH)
+}
+
+// printVersionJSON prints the build info in JSON, with the hash of the config files if they are set by flags. The hash
+// is the same as the one reported by the API of a server running the config files.
+func printVersionJSON() error {
+	info := struct {
+		*core.BuildInfo
+		ConfigHash string `json:"configHash,omitempty"`
+	}{
+		BuildInfo: core.GetBuildInfo(),
+	}
+	if len(configFile) > 0 {
+		if err := prepareConfig(); err != nil {
+			return err
+		}
+		config, err := point.LoadConfig(configFile...)
+		if err != nil {
+			return err
+		}
+		info.ConfigHash = config.Hash
+	}
+	content, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
+
+func printCommands() {
+	fmt.Println("Usage: v2ray <command> [flags]")
+	fmt.Println()
+	for _, cmd := range commands {
+		fmt.Printf("  %-9s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Println()
+	fmt.Println("Run \"v2ray <command> -h\" for the flags of a command. Without a command, v2ray runs the server.")
+}
+
+func init() {
+	commands = append([]*command{
+		{
+			name:     "run",
+			usage:    "Run the server with the config files.",
+			addFlags: addConfigFlags,
+			run: func(args []string) error {
+				core.PrintVersion()
+				return runV2Ray()
+			},
+		},
+		{
+			name:     "test",
+			usage:    "Check the config files without running the server.",
+			addFlags: addConfigFlags,
+			run: func(args []string) error {
+				core.PrintVersion()
+				return testConfig()
+			},
+		},
+		{
+			name:     "dump",
+			usage:    "Print the effective config in JSON, with config files merged and defaults filled in.",
+			addFlags: addConfigFlags,
+			run: func(args []string) error {
+				return dumpConfig()
+			},
+		},
+		{
+			name:  "migrate",
+			usage: "Update a config file written for older releases, and explain the changes.",
+			addFlags: func(flags *flag.FlagSet) {
+				migrateInput = flags.String("config", "", "Config file to migrate.")
+				migrateOutput = flags.String("o", "", "File to write the updated config to. It is printed if not set.")
+				format = flags.String("format", "auto", "Format of the config file: auto, json, yaml or toml.")
+			},
+			run: func(args []string) error {
+				if len(*migrateInput) == 0 {
+					fmt.Println("Config file is not set.")
+					return errConfigInvalid
+				}
+				return migrateConfig(*migrateInput, *migrateOutput)
+			},
+		},
+		{
+			name:  "link",
+			usage: "Import or export share links of servers: import or export.",
+			run: func(args []string) error {
+				return runSubcommand("link", linkSubcommands, args)
+			},
+		},
+		{
+			name:  "version",
+			usage: "Print the version and build info.",
+			addFlags: func(flags *flag.FlagSet) {
+				addConfigFlags(flags)
+				versionJSONFlag = flags.Bool("json", false, "Print in JSON, with the hash of config files if set.")
+			},
+			run: func(args []string) error {
+				if *versionJSONFlag {
+					return printVersionJSON()
+				}
+				printVersion()
+				return nil
+			},
+		},
+		{
+			name:  "api",
+			usage: "Call the management API of a running server: addinbound, removeinbound, addoutbound, removeoutbound, alterinbound or stats.",
+			run: func(args []string) error {
+				return runSubcommand("api", apiSubcommands, args)
+			},
+		},
+	}, generateCommands...)
+	commands = append(commands, serviceCommands...)
+	commands = append(commands, &command{
+		name:  "help",
+		usage: "Print this help.",
+		run: func(args []string) error {
+			printCommands()
+			return nil
+		},
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"v2ray.com/core/common/uuid"
+	"v2ray.com/core/transport/internet/reality"
+)
+
+var (
+	uuidCount      *int
+	privateKey     *string
+	passwordLength *int
+	signingKey     *string
+)
+
+// encodeKey encodes a key in the format of REALITY keys in config.
+func encodeKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// newUUID returns a random UUID with the version and variant bits of RFC 4122, so that it is accepted by other tools.
+func newUUID() *uuid.UUID {
+	id := uuid.New()
+	bytes := id.Bytes()
+	bytes[6] = (bytes[6] & 0x0f) | 0x40
+	bytes[8] = (bytes[8] & 0x3f) | 0x80
+	return id
+}
+
+func generateUUID(args []string) error {
+	for i := 0; i < *uuidCount; i++ {
+		fmt.Println(newUUID().String())
+	}
+	return nil
+}
+
+func generateX25519(args []string) error {
+	var private, public []byte
+	var err error
+	if len(*privateKey) > 0 {
+		private, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(*privateKey), "="))
+		if err != nil || len(private) != 32 {
+			err = errors.New("Invalid private key: " + *privateKey)
+		} else {
+			public, err = reality.PublicKey(private)
+		}
+	} else {
+		private, public, err = reality.GenerateKey()
+	}
+	if err != nil {
+		fmt.Println("Failed to generate key pair:", err)
+		return err
+	}
+	fmt.Println("Private key:", encodeKey(private))
+	fmt.Println("Public key:", encodeKey(public))
+	return nil
+}
+
+func generateEd25519(args []string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fmt.Println("Failed to generate key pair:", err)
+		return err
+	}
+	fmt.Println("Private key:", encodeKey(private.Seed()))
+	fmt.Println("Public key:", encodeKey(public))
+	return nil
+}
+
+// signConfig writes the signatures of config files in args to the files followed by ".sig", for them to be served as
+// remote configs.
+func signConfig(args []string) error {
+	seed, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(*signingKey), "="))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		err := errors.New("Invalid private key: " + *signingKey)
+		fmt.Println(err)
+		return err
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	for _, file := range args {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Println("Failed to read config file (", file, "):", err)
+			return err
+		}
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, content))
+		if err := ioutil.WriteFile(file+".sig", []byte(signature+"\n"), 0644); err != nil {
+			fmt.Println("Failed to write signature (", file, "):", err)
+			return err
+		}
+		fmt.Println("Signed", file)
+	}
+	return nil
+}
+
+func generatePassword(args []string) error {
+	if *passwordLength <= 0 {
+		err := errors.New("Invalid password length.")
+		fmt.Println(err)
+		return err
+	}
+	password := make([]byte, *passwordLength)
+	if _, err := rand.Read(password); err != nil {
+		return err
+	}
+	fmt.Println(base64.RawURLEncoding.EncodeToString(password))
+	return nil
+}
+
+var (
+	generateCommands = []*command{
+		{
+			name:  "uuid",
+			usage: "Generate random UUIDs for VMess users.",
+			addFlags: func(flags *flag.FlagSet) {
+				uuidCount = flags.Int("n", 1, "Number of UUIDs to generate.")
+			},
+			run: generateUUID,
+		},
+		{
+			name:  "x25519",
+			usage: "Generate an X25519 key pair for REALITY, or the public key of a private key.",
+			addFlags: func(flags *flag.FlagSet) {
+				privateKey = flags.String("i", "", "Private key to derive the public key from. A new key pair is generated if not set.")
+			},
+			run: generateX25519,
+		},
+		{
+			name:  "ed25519",
+			usage: "Generate an Ed25519 key pair for signing remote configs.",
+			run:   generateEd25519,
+		},
+		{
+			name:  "sign",
+			usage: "Sign config files with an Ed25519 private key, for them to be fetched by -config https://...",
+			addFlags: func(flags *flag.FlagSet) {
+				signingKey = flags.String("key", "", "Ed25519 private key.")
+			},
+			run: signConfig,
+		},
+		{
+			name:  "password",
+			usage: "Generate a random password, e.g. for Shadowsocks.",
+			addFlags: func(flags *flag.FlagSet) {
+				passwordLength = flags.Int("length", 16, "Number of random bytes in the password.")
+			},
+			run: generatePassword,
+		},
+	}
+)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"v2ray.com/core/shell/point"
+)
+
+var (
+	exportAddress *string
+)
+
+// importLinks prints the outbounds of share links in args, or in stdin one per line if args is empty.
+func importLinks(args []string) error {
+	links := args
+	if len(links) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if link := strings.TrimSpace(scanner.Text()); len(link) > 0 {
+				links = append(links, link)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	content, warnings, err := point.ImportLinks(links...)
+	if err != nil {
+		fmt.Println("Failed to import links:", err)
+		return err
+	}
+	fmt.Println(string(content))
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	return nil
+}
+
+func exportLinks(args []string) error {
+	if err := prepareConfig(); err != nil {
+		return err
+	}
+	links, err := point.ExportLinks(*exportAddress, configFile...)
+	if err != nil {
+		fmt.Println("Failed to export links:", err)
+		return err
+	}
+	for _, link := range links {
+		fmt.Println(link)
+	}
+	return nil
+}
+
+var (
+	linkSubcommands = []*command{
+		{
+			name:  "import",
+			usage: "Print share links, e.g. vmess:// links, as outbound detours. Links are read from stdin if not given.",
+			run:   importLinks,
+		},
+		{
+			name:  "export",
+			usage: "Print share links of the VMess outbounds in the config files.",
+			addFlags: func(flags *flag.FlagSet) {
+				addConfigFlags(flags)
+				exportAddress = flags.String("address", "", "Public address of this server. If set, links of VMess and Shadowsocks inbounds are printed as well.")
+			},
+			run: exportLinks,
+		},
+	}
+)
@@ -1,20 +1,25 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"v2ray.com/core"
 	_ "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/shell/point"
 
 	// The following are necessary as they register handlers in their init functions.
 	_ "v2ray.com/core/proxy/blackhole"
+	_ "v2ray.com/core/proxy/dns"
 	_ "v2ray.com/core/proxy/dokodemo"
 	_ "v2ray.com/core/proxy/freedom"
 	_ "v2ray.com/core/proxy/http"
@@ -33,23 +38,133 @@ import (
 	_ "v2ray.com/core/transport/internet/authenticators/utp"
 )
 
+// configFileList is the value of the -config flag, which may be set multiple times.
+type configFileList []string
+
+func (this *configFileList) String() string {
+	return strings.Join(*this, ",")
+}
+
+func (this *configFileList) Set(value string) error {
+	*this = append(*this, value)
+	return nil
+}
+
 var (
-	configFile string
-	logLevel   = flag.String("loglevel", "warning", "Level of log info to be printed to console, available value: debug, info, warning, error")
-	version    = flag.Bool("version", false, "Show current version of V2Ray.")
-	test       = flag.Bool("test", false, "Test config file only, without launching V2Ray server.")
+	configFile         configFileList
+	defaultConfigFile  string
+	defaultConfigCache string
+	logLevel           *string
+	format             *string
+	strict             *bool
+	configKey          *string
+	configCache        *string
+
+	// Flags without subcommands, which are kept for compatibility.
+	version     = flag.Bool("version", false, "Show current version of V2Ray.")
+	versionJSON = flag.Bool("version-json", false, "Show the build of V2Ray in JSON, with the hash of config files if -config is set.")
+	test        = flag.Bool("test", false, "Test config file only, without launching V2Ray server.")
+	dump        = flag.Bool("dump", false, "Print the effective config in JSON, with config files merged and defaults filled in, and exit.")
 )
 
+// addConfigFlags adds the flags of config files to flags.
+func addConfigFlags(flags *flag.FlagSet) {
+	flags.Var(&configFile, "config", "Config file or directory for this Point server. Multiple ones are merged in order. (default "+defaultConfigFile+")")
+	logLevel = flags.String("loglevel", "warning", "Level of log info to be printed to console, available value: debug, info, warning, error")
+	format = flags.String("format", "auto", "Format of config files: auto, json, yaml, toml or pb. Auto detects formats by file extensions.")
+	strict = flags.Bool("strict", true, "Report unknown fields in config files as errors. Set -strict=false to ignore them.")
+	configKey = flags.String("configkey", "", "Ed25519 public key in base64 to verify HTTPS config URLs with. Each URL must have a signature at the URL followed by \".sig\".")
+	configCache = flags.String("configcache", defaultConfigCache, "Directory to cache verified configs of HTTPS URLs in, which are used when fetches fail. Set it empty to disable caching.")
+}
+
 func init() {
-	defaultConfigFile := ""
 	workingDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
 	if err == nil {
 		defaultConfigFile = filepath.Join(workingDir, "config.json")
 	}
-	flag.StringVar(&configFile, "config", defaultConfigFile, "Config file for this Point server.")
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		defaultConfigCache = filepath.Join(cacheDir, "v2ray")
+	}
+	addConfigFlags(flag.CommandLine)
 }
 
-func startV2Ray() *point.Point {
+var errConfigInvalid = errors.New("Invalid config.")
+
+// prepareConfig applies the flags of config files.
+func prepareConfig() error {
+	if err := point.SetConfigFormat(*format); err != nil {
+		fmt.Println("Unknown config format: " + *format)
+		return err
+	}
+
+	loader.SetStrictJSON(*strict)
+
+	if len(*configKey) > 0 {
+		if err := point.SetConfigPublicKey(*configKey); err != nil {
+			fmt.Println(err)
+			return err
+		}
+	}
+	point.SetConfigCacheDir(*configCache)
+
+	if len(configFile) == 0 && len(defaultConfigFile) > 0 {
+		configFile = configFileList{defaultConfigFile}
+	}
+	if len(configFile) == 0 {
+		log.Error("Config file is not set.")
+		return errConfigInvalid
+	}
+	return nil
+}
+
+// dumpConfig prints the effective config of the config files.
+func dumpConfig() error {
+	if err := prepareConfig(); err != nil {
+		return err
+	}
+	content, err := point.DumpConfig(configFile...)
+	if err != nil {
+		log.Error("Failed to read config file (", configFile.String(), "): ", err)
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
+
+// migrateConfig prints the config file with fields in legacy forms updated, or writes it to the output file if set.
+// Explanations of the changes are printed to stderr, unless the config is written to a file.
+func migrateConfig(file string, output string) error {
+	if err := point.SetConfigFormat(*format); err != nil {
+		fmt.Println("Unknown config format: " + *format)
+		return err
+	}
+	content, explanations, err := point.MigrateConfig(file)
+	if err != nil {
+		fmt.Println("Failed to migrate config file (", file, "):", err)
+		return err
+	}
+	explain := os.Stderr
+	if len(output) > 0 {
+		if err := ioutil.WriteFile(output, append(content, '\n'), 0644); err != nil {
+			fmt.Println("Failed to write config file (", output, "):", err)
+			return err
+		}
+		explain = os.Stdout
+	} else {
+		fmt.Println(string(content))
+	}
+	if len(explanations) == 0 {
+		fmt.Fprintln(explain, "Config is up to date.")
+	}
+	for _, explanation := range explanations {
+		fmt.Fprintln(explain, explanation)
+	}
+	return nil
+}
+
+// startV2Ray starts a Point server with the config files. If testOnly is true, the config is checked and no server is
+// started.
+func startV2Ray(testOnly bool) (*point.Point, error) {
 	switch *logLevel {
 	case "debug":
 		log.SetLogLevel(log.DebugLevel)
@@ -61,58 +176,125 @@ func startV2Ray() *point.Point {
 		log.SetLogLevel(log.ErrorLevel)
 	default:
 		fmt.Println("Unknown log level: " + *logLevel)
-		return nil
+		return nil, errConfigInvalid
 	}
 
-	if len(configFile) == 0 {
-		log.Error("Config file is not set.")
-		return nil
+	if err := prepareConfig(); err != nil {
+		return nil, err
 	}
-	config, err := point.LoadConfig(configFile)
-	if err != nil {
-		log.Error("Failed to read config file (", configFile, "): ", configFile, err)
-		return nil
+	// Problems that would stop the server from starting are reported all at once.
+	config, errs := point.PreflightConfig(configFile...)
+	if len(errs) == 0 && testOnly {
+		errs = point.ValidateConfig(config)
 	}
-
-	if config.LogConfig != nil && len(config.LogConfig.AccessLog) > 0 {
-		log.InitAccessLogger(config.LogConfig.AccessLog)
+	if len(errs) > 0 {
+		log.Error("Failed to read config file (", configFile.String(), ").")
+		for _, err := range errs {
+			fmt.Println(err)
+		}
+		return nil, errConfigInvalid
 	}
 
 	vPoint, err := point.NewPoint(config)
 	if err != nil {
 		log.Error("Failed to create Point server: ", err)
-		return nil
+		return nil, err
 	}
 
-	if *test {
+	if testOnly {
 		fmt.Println("Configuration OK.")
-		return nil
+		return nil, nil
 	}
 
 	err = vPoint.Start()
 	if err != nil {
 		log.Error("Error starting Point server: ", err)
-		return nil
+		return nil, err
 	}
 
-	return vPoint
+	return vPoint, nil
 }
 
-func main() {
-	flag.Parse()
+// testConfig checks the config files without starting the server.
+func testConfig() error {
+	_, err := startV2Ray(true)
+	if err != nil {
+		fmt.Println("Configuration failed:", err)
+	}
+	return err
+}
 
-	core.PrintVersion()
+// runV2Ray starts the server and runs it until it is interrupted. The config is reloaded on SIGHUP. The state of the
+// server is reported to systemd if it is started with Type=notify.
+func runV2Ray() error {
+	vPoint, err := startV2Ray(false)
+	if err != nil {
+		return err
+	}
+	notifySystemd("READY=1")
+	startWatchdog()
 
-	if *version {
-		return
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGHUP)
+
+	for sig := range osSignals {
+		if sig == syscall.SIGHUP {
+			notifySystemd("RELOADING=1")
+			reloadConfig(vPoint)
+			notifySystemd("READY=1")
+			continue
+		}
+		break
+	}
+	notifySystemd("STOPPING=1")
+	vPoint.Close()
+	return nil
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			err := cmd.execute(os.Args[2:])
+			log.Close()
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	if point := startV2Ray(); point != nil {
-		osSignals := make(chan os.Signal, 1)
-		signal.Notify(osSignals, os.Interrupt, os.Kill, syscall.SIGTERM)
+	flag.Parse()
 
-		<-osSignals
-		point.Close()
+	var err error
+	switch {
+	case *dump:
+		err = dumpConfig()
+	case *versionJSON:
+		err = printVersionJSON()
+	case *version:
+		printVersion()
+	case *test:
+		core.PrintVersion()
+		err = testConfig()
+	default:
+		core.PrintVersion()
+		err = runV2Ray()
 	}
 	log.Close()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// reloadConfig reads the config file again, and applies the changes to the running server.
+func reloadConfig(vPoint *point.Point) {
+	log.Warning("Reloading config from ", configFile.String())
+	config, err := point.LoadConfig(configFile...)
+	if err != nil {
+		log.Error("Failed to read config file (", configFile.String(), "): ", err)
+		return
+	}
+	if err := vPoint.Reload(config); err != nil {
+		log.Error("Failed to reload config: ", err)
+	}
 }
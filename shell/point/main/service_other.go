@@ -0,0 +1,8 @@
+// +build !windows
+
+package main
+
+var (
+	// serviceCommands manage the system service of V2Ray, which is only supported on Windows.
+	serviceCommands []*command
+)
@@ -0,0 +1,258 @@
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"v2ray.com/core/common/log"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	defaultServiceName = "v2ray"
+	// serviceEventID is the ID of all events that V2Ray writes to the event log.
+	serviceEventID = 1
+)
+
+var (
+	serviceName *string
+
+	errServiceExists      = errors.New("The service is already installed.")
+	errNotRunAsService    = errors.New("V2Ray is not started by the service control manager.")
+	errServiceStopTimeout = errors.New("Timed out waiting for the service to stop.")
+)
+
+func addServiceNameFlag(flags *flag.FlagSet) {
+	serviceName = flags.String("name", defaultServiceName, "Name of the service.")
+}
+
+// v2rayService runs V2Ray under the service control manager (SCM).
+type v2rayService struct{}
+
+// Execute implements svc.Handler. The service reloads its config on the paramchange control, as on SIGHUP.
+func (this *v2rayService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	vPoint, err := startV2Ray(false)
+	if err != nil {
+		return true, 1
+	}
+	status <- svc.Status{
+		State:   svc.Running,
+		Accepts: svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange,
+	}
+
+	for request := range requests {
+		switch request.Cmd {
+		case svc.Interrogate:
+			status <- request.CurrentStatus
+		case svc.ParamChange:
+			reloadConfig(vPoint)
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			vPoint.Close()
+			return false, 0
+		default:
+			log.Warning("Unexpected service control request: ", request.Cmd)
+		}
+	}
+	return false, 0
+}
+
+// runService runs V2Ray as a service with logs written to the event log. It is the command that the SCM starts.
+func runService(args []string) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		fmt.Println(errNotRunAsService)
+		return errNotRunAsService
+	}
+	eventLog, err := eventlog.Open(*serviceName)
+	if err != nil {
+		return err
+	}
+	defer eventLog.Close()
+	log.InitErrorLogHandler(func(level log.LogLevel, message string) {
+		switch level {
+		case log.ErrorLevel:
+			eventLog.Error(serviceEventID, message)
+		case log.WarningLevel:
+			eventLog.Warning(serviceEventID, message)
+		default:
+			eventLog.Info(serviceEventID, message)
+		}
+	})
+	return svc.Run(*serviceName, new(v2rayService))
+}
+
+// installService registers a service that runs V2Ray with the config files, and the event source of it.
+func installService(args []string) error {
+	if err := prepareConfig(); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// Services are started in the system directory, so paths are made absolute.
+	serviceArgs := []string{"service", "run", "-name", *serviceName, "-loglevel", *logLevel, "-format", *format,
+		fmt.Sprint("-strict=", *strict)}
+	for _, file := range configFile {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		serviceArgs = append(serviceArgs, "-config", path)
+	}
+
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+	if service, err := manager.OpenService(*serviceName); err == nil {
+		service.Close()
+		fmt.Println(errServiceExists)
+		return errServiceExists
+	}
+	service, err := manager.CreateService(*serviceName, exe, mgr.Config{
+		DisplayName: "V2Ray",
+		Description: "A platform for building proxies to bypass network restrictions.",
+		StartType:   mgr.StartAutomatic,
+	}, serviceArgs...)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+	if err := eventlog.InstallAsEventCreate(*serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		service.Delete()
+		return err
+	}
+	fmt.Println("Service", *serviceName, "is installed.")
+	return nil
+}
+
+func uninstallService(args []string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(*serviceName)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+	if err := service.Delete(); err != nil {
+		return err
+	}
+	if err := eventlog.Remove(*serviceName); err != nil {
+		return err
+	}
+	fmt.Println("Service", *serviceName, "is uninstalled.")
+	return nil
+}
+
+func startService(args []string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(*serviceName)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+	return service.Start()
+}
+
+func stopService(args []string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(*serviceName)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+	status, err := service.Control(svc.Stop)
+	if err != nil {
+		return err
+	}
+	timeout := time.Now().Add(10 * time.Second)
+	for status.State != svc.Stopped {
+		if time.Now().After(timeout) {
+			return errServiceStopTimeout
+		}
+		time.Sleep(300 * time.Millisecond)
+		status, err = service.Query()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	serviceSubcommands = []*command{
+		{
+			name:  "install",
+			usage: "Install V2Ray as a service that runs with the config files.",
+			addFlags: func(flags *flag.FlagSet) {
+				addServiceNameFlag(flags)
+				addConfigFlags(flags)
+			},
+			run: installService,
+		},
+		{
+			name:     "uninstall",
+			usage:    "Uninstall the service.",
+			addFlags: addServiceNameFlag,
+			run:      uninstallService,
+		},
+		{
+			name:     "start",
+			usage:    "Start the service.",
+			addFlags: addServiceNameFlag,
+			run:      startService,
+		},
+		{
+			name:     "stop",
+			usage:    "Stop the service.",
+			addFlags: addServiceNameFlag,
+			run:      stopService,
+		},
+		{
+			name:  "run",
+			usage: "Run as the service. It is started by the service control manager.",
+			addFlags: func(flags *flag.FlagSet) {
+				addServiceNameFlag(flags)
+				addConfigFlags(flags)
+			},
+			run: runService,
+		},
+	}
+
+	serviceCommands = []*command{
+		{
+			name:  "service",
+			usage: "Manage the Windows service of V2Ray: install, uninstall, start or stop.",
+			run: func(args []string) error {
+				return runSubcommand("service", serviceSubcommands, args)
+			},
+		},
+	}
+)
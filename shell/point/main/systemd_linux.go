@@ -0,0 +1,52 @@
+// +build linux
+
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"v2ray.com/core/common/log"
+)
+
+// notifySystemd sends a state, e.g. "READY=1", to systemd as sd_notify(3) does. It does nothing if V2Ray is not
+// started by systemd with Type=notify.
+func notifySystemd(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if len(socket) == 0 {
+		return
+	}
+	if socket[0] == '@' {
+		// Abstract socket.
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		log.Warning("Failed to notify systemd: ", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Warning("Failed to notify systemd: ", err)
+	}
+}
+
+// startWatchdog keeps notifying systemd that V2Ray is alive, if the watchdog is enabled by WatchdogSec.
+func startWatchdog() {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid, err := strconv.Atoi(os.Getenv("WATCHDOG_PID")); err == nil && pid != os.Getpid() {
+		return
+	}
+	// Notifies twice in each interval, as suggested by sd_watchdog_enabled(3).
+	interval := time.Duration(usec) * time.Microsecond / 2
+	go func() {
+		for range time.Tick(interval) {
+			notifySystemd("WATCHDOG=1")
+		}
+	}()
+}
@@ -0,0 +1,7 @@
+// +build !linux
+
+package main
+
+func notifySystemd(state string) {}
+
+func startWatchdog() {}
@@ -0,0 +1,51 @@
+package point
+
+import (
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/plugin"
+)
+
+func pluginOf(settings *internet.StreamSettings) *plugin.Config {
+	if settings == nil {
+		return nil
+	}
+	return settings.PluginSettings
+}
+
+// pluginsOf returns the settings of the transport plugins of the outbounds in the config.
+func pluginsOf(config *Config) []*plugin.Config {
+	var plugins []*plugin.Config
+	if config.OutboundConfig != nil {
+		if p := pluginOf(config.OutboundConfig.StreamSettings); p != nil {
+			plugins = append(plugins, p)
+		}
+	}
+	for _, detour := range config.OutboundDetours {
+		if p := pluginOf(detour.StreamSettings); p != nil {
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// stopPlugins stops the transport plugins started for the outbounds of the config. Plugins shared with outbounds of
+// other instances keep running.
+func stopPlugins(config *Config) {
+	for _, p := range pluginsOf(config) {
+		plugin.Stop(p)
+	}
+}
+
+// stopRemovedPlugins stops the transport plugins of the outbounds in the old config that are replaced in the new one.
+// Outbounds kept on reload have the same plugin settings in both.
+func stopRemovedPlugins(old *Config, config *Config) {
+	kept := make(map[*plugin.Config]bool)
+	for _, p := range pluginsOf(config) {
+		kept[p] = true
+	}
+	for _, p := range pluginsOf(old) {
+		if !kept[p] {
+			plugin.Stop(p)
+		}
+	}
+}
@@ -5,22 +5,44 @@
 package point
 
 import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
 	"v2ray.com/core/app/dispatcher"
 	dispatchers "v2ray.com/core/app/dispatcher/impl"
 	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/events"
+	applog "v2ray.com/core/app/log"
+	"v2ray.com/core/app/policy"
 	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/app/router"
+	"v2ray.com/core/app/tracing"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/retry"
 	"v2ray.com/core/proxy"
 	proxyregistry "v2ray.com/core/proxy/registry"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	ErrRouterNotReloadable = errors.New("Point: Router doesn't support reloading.")
+	ErrRouterNoDryRun      = errors.New("Point: Router doesn't support dry run.")
 )
 
 // Point shell of V2Ray.
 type Point struct {
+	sync.RWMutex
+	// reload serializes reloads.
+	reload    sync.Mutex
+	config    *Config
 	port      v2net.Port
 	listen    v2net.Address
 	ich       proxy.InboundHandler
@@ -28,18 +50,129 @@ type Point struct {
 	idh       []InboundDetourHandler
 	taggedIdh map[string]InboundDetourHandler
 	odh       map[string]proxy.OutboundHandler
-	router    router.Router
-	space     app.Space
+	// routerStrategy is the strategy the router was created with.
+	routerStrategy string
+	ohm            *proxyman.DefaultOutboundHandlerManager
+	space          app.Space
+	// logger is the logger of the instance, or nil if the config has no log settings, when logs go to the default
+	// logger.
+	logger    *log.Logger
+	startTime time.Time
+}
+
+func init() {
+	// Points are the inbound handler managers of their spaces, so inbounds start once the apps they use are started.
+	app.MustRegisterFeature(&app.Feature{
+		ID:    proxyman.APP_ID_INBOUND_MANAGER,
+		Name:  "inboundManager",
+		After: []app.ID{applog.APP_ID, dispatcher.APP_ID, events.APP_ID, policy.APP_ID, tracing.APP_ID},
+	})
+}
+
+func inboundPort(config *Config) v2net.Port {
+	if config.InboundConfig.Port == 0 {
+		return config.Port // Backward compatibility
+	}
+	return config.InboundConfig.Port
+}
+
+// createInboundHandler creates an inbound handler of the decoded settings if set, or of the settings in JSON. Decoded
+// settings are copied, as handlers created from the same config may alter theirs.
+func createInboundHandler(protocol string, space app.Space, proxySettings interface{}, settings []byte, meta *proxy.InboundHandlerMeta) (proxy.InboundHandler, error) {
+	if proxySettings == nil {
+		return proxyregistry.CreateInboundHandler(protocol, space, settings, meta)
+	}
+	if message, ok := proxySettings.(proto.Message); ok {
+		proxySettings = proto.Clone(message)
+	}
+	return proxyregistry.CreateInboundHandlerWithConfig(protocol, space, proxySettings, meta)
+}
+
+// createOutboundHandler creates an outbound handler as createInboundHandler does.
+func createOutboundHandler(protocol string, space app.Space, proxySettings interface{}, settings []byte, meta *proxy.OutboundHandlerMeta) (proxy.OutboundHandler, error) {
+	if proxySettings == nil {
+		return proxyregistry.CreateOutboundHandler(protocol, space, settings, meta)
+	}
+	if message, ok := proxySettings.(proto.Message); ok {
+		proxySettings = proto.Clone(message)
+	}
+	return proxyregistry.CreateOutboundHandlerWithConfig(protocol, space, proxySettings, meta)
+}
+
+func newInboundHandler(space app.Space, config *InboundConnectionConfig, port v2net.Port) (proxy.InboundHandler, error) {
+	ich, err := createInboundHandler(
+		config.Protocol, space, config.ProxySettings, config.Settings, &proxy.InboundHandlerMeta{
+			Tag:                    "system.inbound",
+			Address:                config.ListenOn,
+			Port:                   port,
+			StreamSettings:         config.StreamSettings,
+			AllowPassiveConnection: config.AllowPassiveConnection,
+			DefaultOutboundTag:     config.DefaultOutboundTag,
+			SourceFilter:           config.SourceFilter,
+		})
+	if err != nil {
+		log.Error("Failed to create inbound connection handler: ", err)
+		return nil, err
+	}
+	return ich, nil
+}
+
+func newOutboundHandler(space app.Space, config *OutboundConnectionConfig) (proxy.OutboundHandler, error) {
+	och, err := createOutboundHandler(
+		config.Protocol, space, config.ProxySettings, config.Settings, &proxy.OutboundHandlerMeta{
+			Tag:            "system.outbound",
+			Address:        config.SendThrough,
+			StreamSettings: config.StreamSettings,
+		})
+	if err != nil {
+		log.Error("Failed to create outbound connection handler: ", err)
+		return nil, err
+	}
+	return och, nil
+}
+
+func newInboundDetourHandler(space app.Space, config *InboundDetourConfig) (InboundDetourHandler, error) {
+	switch config.Allocation.Strategy {
+	case AllocationStrategyAlways:
+		dh, err := NewInboundDetourHandlerAlways(space, config)
+		if err != nil {
+			log.Error("Point: Failed to create detour handler: ", err)
+			return nil, common.ErrBadConfiguration
+		}
+		return dh, nil
+	case AllocationStrategyRandom:
+		dh, err := NewInboundDetourHandlerDynamic(space, config)
+		if err != nil {
+			log.Error("Point: Failed to create detour handler: ", err)
+			return nil, common.ErrBadConfiguration
+		}
+		return dh, nil
+	default:
+		log.Error("Point: Unknown allocation strategy: ", config.Allocation.Strategy)
+		return nil, common.ErrBadConfiguration
+	}
+}
+
+func newOutboundDetourHandler(space app.Space, config *OutboundDetourConfig) (proxy.OutboundHandler, error) {
+	detourHandler, err := createOutboundHandler(
+		config.Protocol, space, config.ProxySettings, config.Settings, &proxy.OutboundHandlerMeta{
+			Tag:            config.Tag,
+			Address:        config.SendThrough,
+			StreamSettings: config.StreamSettings,
+		})
+	if err != nil {
+		log.Error("Point: Failed to create detour outbound connection handler: ", err)
+		return nil, err
+	}
+	return detourHandler, nil
 }
 
 // NewPoint returns a new Point server based on given configuration.
 // The server is not started at this point.
 func NewPoint(pConfig *Config) (*Point, error) {
 	var vpoint = new(Point)
-	vpoint.port = pConfig.InboundConfig.Port
-	if vpoint.port == 0 {
-		vpoint.port = pConfig.Port // Backward compatibility
-	}
+	vpoint.config = pConfig
+	vpoint.port = inboundPort(pConfig)
 
 	vpoint.listen = pConfig.InboundConfig.ListenOn
 
@@ -48,73 +181,41 @@ func NewPoint(pConfig *Config) (*Point, error) {
 	}
 
 	if pConfig.LogConfig != nil {
-		logConfig := pConfig.LogConfig
-		if len(logConfig.AccessLog) > 0 {
-			err := log.InitAccessLogger(logConfig.AccessLog)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		if len(logConfig.ErrorLog) > 0 {
-			err := log.InitErrorLogger(logConfig.ErrorLog)
-			if err != nil {
-				return nil, err
-			}
+		logger, err := pConfig.LogConfig.NewLogger()
+		if err != nil {
+			return nil, err
 		}
-
-		log.SetLogLevel(logConfig.LogLevel)
+		vpoint.logger = logger
 	}
 
 	vpoint.space = app.NewSpace()
 	vpoint.space.BindApp(proxyman.APP_ID_INBOUND_MANAGER, vpoint)
+	if vpoint.logger != nil {
+		vpoint.space.BindApp(applog.APP_ID, vpoint.logger)
+	}
 
 	outboundHandlerManager := proxyman.NewDefaultOutboundHandlerManager()
 	vpoint.space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundHandlerManager)
+	vpoint.ohm = outboundHandlerManager
 
-	dnsConfig := pConfig.DNSConfig
-	if dnsConfig != nil {
-		dnsServer := dns.NewCacheServer(vpoint.space, dnsConfig)
-		vpoint.space.BindApp(dns.APP_ID, dnsServer)
+	if pConfig.RouterConfig != nil {
+		vpoint.routerStrategy = pConfig.RouterConfig.Strategy
 	}
-
-	routerConfig := pConfig.RouterConfig
-	if routerConfig != nil {
-		r, err := router.CreateRouter(routerConfig.Strategy, routerConfig.Settings, vpoint.space)
-		if err != nil {
-			log.Error("Failed to create router: ", err)
-			return nil, common.ErrBadConfiguration
+	for _, config := range pConfig.appConfigs() {
+		if _, err := app.CreateFeature(vpoint.space, config); err != nil {
+			return nil, err
 		}
-		vpoint.space.BindApp(router.APP_ID, r)
-		vpoint.router = r
 	}
-
 	vpoint.space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(vpoint.space))
 
-	ichConfig := pConfig.InboundConfig.Settings
-	ich, err := proxyregistry.CreateInboundHandler(
-		pConfig.InboundConfig.Protocol, vpoint.space, ichConfig, &proxy.InboundHandlerMeta{
-			Tag:                    "system.inbound",
-			Address:                pConfig.InboundConfig.ListenOn,
-			Port:                   vpoint.port,
-			StreamSettings:         pConfig.InboundConfig.StreamSettings,
-			AllowPassiveConnection: pConfig.InboundConfig.AllowPassiveConnection,
-		})
+	ich, err := newInboundHandler(vpoint.space, pConfig.InboundConfig, vpoint.port)
 	if err != nil {
-		log.Error("Failed to create inbound connection handler: ", err)
 		return nil, err
 	}
 	vpoint.ich = ich
 
-	ochConfig := pConfig.OutboundConfig.Settings
-	och, err := proxyregistry.CreateOutboundHandler(
-		pConfig.OutboundConfig.Protocol, vpoint.space, ochConfig, &proxy.OutboundHandlerMeta{
-			Tag:            "system.outbound",
-			Address:        pConfig.OutboundConfig.SendThrough,
-			StreamSettings: pConfig.OutboundConfig.StreamSettings,
-		})
+	och, err := newOutboundHandler(vpoint.space, pConfig.OutboundConfig)
 	if err != nil {
-		log.Error("Failed to create outbound connection handler: ", err)
 		return nil, err
 	}
 	vpoint.och = och
@@ -125,26 +226,9 @@ func NewPoint(pConfig *Config) (*Point, error) {
 	if len(detours) > 0 {
 		vpoint.idh = make([]InboundDetourHandler, len(detours))
 		for idx, detourConfig := range detours {
-			allocConfig := detourConfig.Allocation
-			var detourHandler InboundDetourHandler
-			switch allocConfig.Strategy {
-			case AllocationStrategyAlways:
-				dh, err := NewInboundDetourHandlerAlways(vpoint.space, detourConfig)
-				if err != nil {
-					log.Error("Point: Failed to create detour handler: ", err)
-					return nil, common.ErrBadConfiguration
-				}
-				detourHandler = dh
-			case AllocationStrategyRandom:
-				dh, err := NewInboundDetourHandlerDynamic(vpoint.space, detourConfig)
-				if err != nil {
-					log.Error("Point: Failed to create detour handler: ", err)
-					return nil, common.ErrBadConfiguration
-				}
-				detourHandler = dh
-			default:
-				log.Error("Point: Unknown allocation strategy: ", allocConfig.Strategy)
-				return nil, common.ErrBadConfiguration
+			detourHandler, err := newInboundDetourHandler(vpoint.space, detourConfig)
+			if err != nil {
+				return nil, err
 			}
 			vpoint.idh[idx] = detourHandler
 			if len(detourConfig.Tag) > 0 {
@@ -153,53 +237,140 @@ func NewPoint(pConfig *Config) (*Point, error) {
 		}
 	}
 
-	outboundDetours := pConfig.OutboundDetours
-	if len(outboundDetours) > 0 {
-		vpoint.odh = make(map[string]proxy.OutboundHandler)
-		for _, detourConfig := range outboundDetours {
-			detourHandler, err := proxyregistry.CreateOutboundHandler(
-				detourConfig.Protocol, vpoint.space, detourConfig.Settings, &proxy.OutboundHandlerMeta{
-					Tag:            detourConfig.Tag,
-					Address:        detourConfig.SendThrough,
-					StreamSettings: detourConfig.StreamSettings,
-				})
-			if err != nil {
-				log.Error("Point: Failed to create detour outbound connection handler: ", err)
-				return nil, err
-			}
-			vpoint.odh[detourConfig.Tag] = detourHandler
-			outboundHandlerManager.SetHandler(detourConfig.Tag, detourHandler)
+	vpoint.odh = make(map[string]proxy.OutboundHandler)
+	for _, detourConfig := range pConfig.OutboundDetours {
+		detourHandler, err := newOutboundDetourHandler(vpoint.space, detourConfig)
+		if err != nil {
+			return nil, err
 		}
+		vpoint.odh[detourConfig.Tag] = detourHandler
+		outboundHandlerManager.SetHandler(detourConfig.Tag, detourHandler)
 	}
 
 	if err := vpoint.space.Initialize(); err != nil {
 		return nil, err
 	}
 
+	if pConfig.LogConfig != nil && pConfig.LogConfig.AccessHostnames {
+		if resolver, ok := vpoint.space.GetApp(dns.APP_ID).(dns.ReverseResolver); ok {
+			vpoint.logger.SetAccessHostnameLookup(newAccessHostnames(resolver).Lookup)
+		} else {
+			log.Warning("Point: Hostnames in access logs require the DNS app.")
+		}
+	}
+	// The only instance in the process logs what doesn't belong to it as well.
+	log.AddInstance(vpoint.logger)
+
 	return vpoint, nil
 }
 
+// appOrder returns the apps in the space in the order they are started.
+func (this *Point) appOrder() ([]app.ID, error) {
+	return app.StartOrder(this.space.Apps())
+}
+
+// Close releases apps in the reverse order they are started. Probes fail before inbounds are closed, so that load
+// balancers stop sending new connections, and connections still open through the dispatcher are canceled after.
 func (this *Point) Close() {
-	this.ich.Close()
-	for _, idh := range this.idh {
-		idh.Close()
+	this.RLock()
+	defer this.RUnlock()
+
+	log.RemoveInstance(this.logger)
+	order, err := this.appOrder()
+	if err != nil {
+		this.logger.Warning("Point: Failed to order apps: ", err)
+		order = this.space.Apps()
+	}
+	for idx := len(order) - 1; idx >= 0; idx-- {
+		if order[idx] == proxyman.APP_ID_INBOUND_MANAGER {
+			this.ich.Close()
+			for _, idh := range this.idh {
+				idh.Close()
+			}
+			continue
+		}
+		this.space.GetApp(order[idx]).Release()
+	}
+	stopPlugins(this.config)
+}
+
+// Logger returns the logger of the instance, or nil if its logs go to the default logger.
+func (this *Point) Logger() *log.Logger {
+	return this.logger
+}
+
+// StartTime returns the time that the server started.
+func (this *Point) StartTime() time.Time {
+	return this.startTime
+}
+
+// ConfigHash returns the hash of the running config, which changes on reload.
+func (this *Point) ConfigHash() string {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.config.Hash
+}
+
+// InboundStatus implements health.InboundReporter. The inbound in "inbound" of the config is reported as "inbound", and
+// inbound detours without tags are reported by their ports.
+func (this *Point) InboundStatus() map[string]bool {
+	this.RLock()
+	defer this.RUnlock()
+
+	status := make(map[string]bool, len(this.idh)+1)
+	status["inbound"] = handlerAccepting(this.ich)
+	for idx, idh := range this.idh {
+		name := fmt.Sprint("inboundDetour:", idx)
+		if idx < len(this.config.InboundDetours) {
+			detour := this.config.InboundDetours[idx]
+			name = fmt.Sprint("port:", detour.PortRange.From)
+			if len(detour.Tag) > 0 {
+				name = detour.Tag
+			}
+		}
+		status[name] = handlerAccepting(idh)
 	}
+	return status
 }
 
 // Start starts the Point server, and return any error during the process.
 // In the case of any errors, the state of the server is unpredicatable.
 func (this *Point) Start() error {
 	if this.port <= 0 {
-		log.Error("Point: Invalid port ", this.port)
+		this.logger.Error("Point: Invalid port ", this.port)
 		return common.ErrBadConfiguration
 	}
+	this.startTime = time.Now()
 
+	order, err := this.appOrder()
+	if err != nil {
+		this.logger.Error("Point: Failed to order apps: ", err)
+		return err
+	}
+	for _, id := range order {
+		if id == proxyman.APP_ID_INBOUND_MANAGER {
+			if err := this.startInbounds(); err != nil {
+				return err
+			}
+			continue
+		}
+		if startable, ok := this.space.GetApp(id).(app.Startable); ok {
+			if err := startable.Start(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (this *Point) startInbounds() error {
 	err := retry.Timed(100 /* times */, 100 /* ms */).On(func() error {
 		err := this.ich.Start()
 		if err != nil {
 			return err
 		}
-		log.Warning("Point: started on port ", this.port)
+		this.logger.Warning("Point: started on port ", this.port)
 		return nil
 	})
 	if err != nil {
@@ -212,19 +383,84 @@ func (this *Point) Start() error {
 			return err
 		}
 	}
+	return nil
+}
 
+// getRouter returns the router in the space, or nil if routing is not configured.
+func (this *Point) getRouter() router.Router {
+	if r, ok := this.space.GetApp(router.APP_ID).(router.Router); ok {
+		return r
+	}
 	return nil
 }
 
+// ReloadRouter replaces the routing rules with the given config, while inbounds and outbounds keep running. The
+// router strategy can't be changed on reload.
+func (this *Point) ReloadRouter(config *router.Config) error {
+	apply, err := this.prepareRouter(config)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// prepareRouter validates the routing config, and returns the function that applies it.
+func (this *Point) prepareRouter(config *router.Config) (func(), error) {
+	r := this.getRouter()
+	if r == nil || config == nil {
+		this.logger.Error("Point: Routing is not configured.")
+		return nil, common.ErrBadConfiguration
+	}
+	reloadable, ok := r.(router.Reloadable)
+	if !ok {
+		this.logger.Error("Point: Router doesn't support reloading.")
+		return nil, ErrRouterNotReloadable
+	}
+	if config.Strategy != this.routerStrategy {
+		this.logger.Error("Point: Router strategy can't be changed from ", this.routerStrategy, " to ", config.Strategy, " on reload.")
+		return nil, common.ErrBadConfiguration
+	}
+	return reloadable.PrepareReload(config.Settings)
+}
+
+// DryRunRoute routes a synthetic connection without dispatching it, and returns the outbound that would be taken
+// with the trace of rule evaluations.
+func (this *Point) DryRunRoute(ctx *router.Context) (*router.Decision, error) {
+	r := this.getRouter()
+	if r == nil {
+		this.logger.Error("Point: Routing is not configured.")
+		return nil, common.ErrBadConfiguration
+	}
+	dryRunner, ok := r.(router.DryRunner)
+	if !ok {
+		this.logger.Error("Point: Router doesn't support dry run.")
+		return nil, ErrRouterNoDryRun
+	}
+	return dryRunner.DryRun(ctx)
+}
+
 func (this *Point) GetHandler(tag string) (proxy.InboundHandler, int) {
+	this.RLock()
 	handler, found := this.taggedIdh[tag]
+	this.RUnlock()
 	if !found {
-		log.Warning("Point: Unable to find an inbound handler with tag: ", tag)
+		this.logger.Warning("Point: Unable to find an inbound handler with tag: ", tag)
 		return nil, 0
 	}
 	return handler.GetConnectionHandler()
 }
 
+// Certificates implements events.CertificateSource.
+func (this *Point) Certificates() []*x509.Certificate {
+	return certificates(this.config)
+}
+
+// HandlerManager implements api.HandlerManagerProvider.
+func (this *Point) HandlerManager() api.HandlerManager {
+	return &apiHandlerManager{point: this}
+}
+
 func (this *Point) Release() {
 
 }
@@ -0,0 +1,167 @@
+package point
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	v2net "v2ray.com/core/common/net"
+	proxyregistry "v2ray.com/core/proxy/registry"
+	"v2ray.com/core/transport/internet"
+)
+
+// checkBindable binds the port on the address and releases it immediately.
+func checkBindable(network string, address v2net.Address, port v2net.Port) error {
+	host := ""
+	if address != nil {
+		host = address.String()
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	if network == "udp" {
+		conn, err := net.ListenPacket(network, addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	return listener.Close()
+}
+
+// preflightChecker collects the problems found by Preflight.
+type preflightChecker struct {
+	config       *Config
+	errs         []error
+	capabilities map[internet.Capability][]string
+}
+
+func (this *preflightChecker) require(name string, capabilities []internet.Capability) {
+	for _, capability := range capabilities {
+		this.capabilities[capability] = append(this.capabilities[capability], name)
+	}
+}
+
+// checkPorts checks whether the ports of an inbound are bindable on the networks its config listens on, see
+// internet.NetworkListener. On the stream transport, mKCP listens on UDP, and other transports on TCP. Ports of
+// sockets passed by socket activation are already bound.
+func (this *preflightChecker) checkPorts(name string, address v2net.Address, ports v2net.PortRange, settings *internet.StreamSettings, config interface{}) {
+	networks := []v2net.Network{v2net.Network_TCP}
+	if listener, ok := config.(internet.NetworkListener); ok {
+		networks = listener.ListenNetworks()
+	}
+	checked := make(map[string]bool)
+	for _, listenNetwork := range networks {
+		network := "udp"
+		if listenNetwork == v2net.Network_TCP && (settings == nil || !settings.IsCapableOf(internet.StreamConnectionTypeKCP)) {
+			network = "tcp"
+		}
+		if checked[network] {
+			continue
+		}
+		checked[network] = true
+		for port := ports.From; port <= ports.To && port > 0; port++ {
+			if internet.HasActivatedSocket(network, address, v2net.Port(port)) {
+				continue
+			}
+			if err := checkBindable(network, address, v2net.Port(port)); err != nil {
+				this.errs = append(this.errs, fmt.Errorf("Point: Port %d of %s is not bindable on %s: %v", port, name, network, err))
+			}
+		}
+	}
+}
+
+func (this *preflightChecker) checkStream(name string, settings *internet.StreamSettings) {
+	if this.config.TransportConfig != nil {
+		this.require(name, this.config.TransportConfig.RequiredCapabilities(settings))
+	}
+}
+
+// checkSettings checks the capabilities required by the settings of a proxy, and returns its config. The config is nil
+// if the settings are invalid.
+func (this *preflightChecker) checkSettings(name string, createConfig func(string, []byte) (interface{}, error), protocol string, proxySettings interface{}, settings []byte) interface{} {
+	config := proxySettings
+	if config == nil {
+		// Settings are already checked when the config is parsed.
+		created, err := createConfig(protocol, settings)
+		if err != nil {
+			return nil
+		}
+		config = created
+	}
+	if requirer, ok := config.(internet.CapabilityRequirer); ok {
+		this.require(name, requirer.RequiredCapabilities())
+	}
+	return config
+}
+
+// Preflight checks whether the server can be brought up with a parsed config, i.e. every port of inbounds is bindable
+// and the process has the capabilities that inbounds and outbounds require, e.g. raw sockets. It returns all problems
+// found. Ports of inbound detours allocated randomly are not checked, as they are picked at runtime.
+func Preflight(config *Config) []error {
+	checker := &preflightChecker{
+		config:       config,
+		capabilities: make(map[internet.Capability][]string),
+	}
+
+	inbound := config.InboundConfig
+	port := inboundPort(config)
+	inboundSettings := checker.checkSettings("inbound", proxyregistry.CreateInboundConfig, inbound.Protocol, inbound.ProxySettings, inbound.Settings)
+	checker.checkPorts("inbound", inbound.ListenOn, v2net.PortRange{From: uint32(port), To: uint32(port)}, inbound.StreamSettings, inboundSettings)
+	checker.checkStream("inbound", inbound.StreamSettings)
+
+	for idx, detour := range config.InboundDetours {
+		name := fmt.Sprint("inboundDetour[", idx, "]")
+		if len(detour.Tag) > 0 {
+			name += " (" + detour.Tag + ")"
+		}
+		detourSettings := checker.checkSettings(name, proxyregistry.CreateInboundConfig, detour.Protocol, detour.ProxySettings, detour.Settings)
+		if detour.Allocation == nil || detour.Allocation.Strategy == AllocationStrategyAlways {
+			checker.checkPorts(name, detour.ListenOn, detour.PortRange, detour.StreamSettings, detourSettings)
+		}
+		checker.checkStream(name, detour.StreamSettings)
+	}
+
+	outbound := config.OutboundConfig
+	checker.checkStream("outbound", outbound.StreamSettings)
+	checker.checkSettings("outbound", proxyregistry.CreateOutboundConfig, outbound.Protocol, outbound.ProxySettings, outbound.Settings)
+	for idx, detour := range config.OutboundDetours {
+		name := fmt.Sprint("outboundDetour[", idx, "]")
+		if len(detour.Tag) > 0 {
+			name += " (" + detour.Tag + ")"
+		}
+		checker.checkStream(name, detour.StreamSettings)
+		checker.checkSettings(name, proxyregistry.CreateOutboundConfig, detour.Protocol, detour.ProxySettings, detour.Settings)
+	}
+
+	if config.ApiConfig != nil {
+		port := config.ApiConfig.Port
+		checker.checkPorts("api", config.ApiConfig.Listen, v2net.PortRange{From: uint32(port), To: uint32(port)}, nil, nil)
+	}
+	if config.MetricsConfig != nil {
+		port := config.MetricsConfig.Port
+		checker.checkPorts("metrics", config.MetricsConfig.Listen, v2net.PortRange{From: uint32(port), To: uint32(port)}, nil, nil)
+	}
+	if config.HealthConfig != nil {
+		port := config.HealthConfig.Port
+		checker.checkPorts("health", config.HealthConfig.Listen, v2net.PortRange{From: uint32(port), To: uint32(port)}, nil, nil)
+	}
+	if config.DebugConfig != nil && config.DebugConfig.Enabled {
+		port := config.DebugConfig.Port
+		checker.checkPorts("debug", v2net.LocalHostIP, v2net.PortRange{From: uint32(port), To: uint32(port)}, nil, nil)
+	}
+
+	for _, capability := range []internet.Capability{internet.CapabilityRawSocket, internet.CapabilityTransparent} {
+		names, found := checker.capabilities[capability]
+		if !found {
+			continue
+		}
+		if err := internet.CheckCapability(capability); err != nil {
+			checker.errs = append(checker.errs, fmt.Errorf("Point: %s is required by %s, but not available: %v", capability, strings.Join(names, ", "), err))
+		}
+	}
+	return checker.errs
+}
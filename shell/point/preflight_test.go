@@ -0,0 +1,73 @@
+// +build json
+
+package point_test
+
+import (
+	"net"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy/socks"
+	. "v2ray.com/core/shell/point"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestPreflight(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	busyPort := v2net.Port(listener.Addr().(*net.TCPAddr).Port)
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	freePort := v2net.Port(free.Addr().(*net.TCPAddr).Port)
+	free.Close()
+
+	config := &Config{
+		InboundConfig: &InboundConnectionConfig{
+			Port:     freePort,
+			ListenOn: v2net.LocalHostIP,
+		},
+		OutboundConfig: &OutboundConnectionConfig{},
+		InboundDetours: []*InboundDetourConfig{
+			{
+				PortRange: v2net.PortRange{From: uint32(busyPort), To: uint32(busyPort)},
+				ListenOn:  v2net.LocalHostIP,
+				Allocation: &InboundDetourAllocationConfig{
+					Strategy: AllocationStrategyRandom,
+				},
+			},
+		},
+	}
+	assert.Int(len(Preflight(config))).Equals(0)
+
+	config.InboundConfig.Port = busyPort
+	config.InboundDetours[0].Allocation.Strategy = AllocationStrategyAlways
+	assert.Int(len(Preflight(config))).Equals(2)
+}
+
+func TestPreflightUDP(t *testing.T) {
+	assert := assert.On(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	busyPort := v2net.Port(conn.LocalAddr().(*net.UDPAddr).Port)
+
+	// The TCP port is free, while UDP for UDP ASSOCIATE is taken.
+	settings := &socks.ServerConfig{}
+	config := &Config{
+		InboundConfig: &InboundConnectionConfig{
+			Port:          busyPort,
+			ListenOn:      v2net.LocalHostIP,
+			ProxySettings: settings,
+		},
+		OutboundConfig: &OutboundConnectionConfig{},
+	}
+	assert.Int(len(Preflight(config))).Equals(0)
+
+	settings.UdpEnabled = true
+	assert.Int(len(Preflight(config))).Equals(1)
+}
@@ -0,0 +1,272 @@
+package point
+
+import (
+	"reflect"
+
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/retry"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// findInboundDetour returns the index of an unused detour in configs that equals to config, or -1 if none is found.
+func findInboundDetour(configs []*InboundDetourConfig, config *InboundDetourConfig, used map[int]bool) int {
+	for idx, candidate := range configs {
+		if !used[idx] && reflect.DeepEqual(candidate, config) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// findOutboundDetour returns the detour in configs that equals to config, or nil if none is found.
+func findOutboundDetour(configs []*OutboundDetourConfig, config *OutboundDetourConfig) *OutboundDetourConfig {
+	for _, candidate := range configs {
+		if reflect.DeepEqual(candidate, config) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// releaseOutbound releases the outbound handler if it holds resources.
+func releaseOutbound(handler proxy.OutboundHandler) {
+	if releasable, ok := handler.(common.Releasable); ok {
+		releasable.Release()
+	}
+}
+
+// ReloadDNS replaces the DNS settings with the given config. The DNS app can't be added or removed on reload.
+func (this *Point) ReloadDNS(config *dns.Config) error {
+	apply, err := this.prepareDNS(config)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// prepareDNS validates the DNS config, and returns the function that applies it.
+func (this *Point) prepareDNS(config *dns.Config) (func(), error) {
+	if config == nil || !this.space.HasApp(dns.APP_ID) {
+		this.logger.Error("Point: DNS can't be enabled or disabled on reload.")
+		return nil, common.ErrBadConfiguration
+	}
+	reloadable, ok := this.space.GetApp(dns.APP_ID).(dns.Reloadable)
+	if !ok {
+		this.logger.Error("Point: DNS server doesn't support reloading.")
+		return nil, common.ErrBadConfiguration
+	}
+	return reloadable.PrepareReload(config)
+}
+
+// Reload applies a new config to the running server. Routing rules, DNS settings, inbounds and outbounds are replaced
+// only if their configs changed, and the rest keep running. Replaced inbounds stop accepting connections, while the
+// connections they already accepted finish normally. Log, transport, throttle, API, stats, metrics, debug,
+// events, tracing, health and policy settings require a restart. Certificates of TLS listeners are reloaded from
+// their files if changed. Nothing is changed if any part of the new config is invalid, as all new components are
+// created before any of them is applied.
+func (this *Point) Reload(config *Config) (err error) {
+	this.reload.Lock()
+	defer this.reload.Unlock()
+
+	old := this.config
+	// Kept outbounds keep the configs they are created with, so that their plugins are tracked across reloads. The
+	// config is copied for that, as it may be shared with the caller.
+	copied := *config
+	config = &copied
+	config.OutboundDetours = append([]*OutboundDetourConfig(nil), config.OutboundDetours...)
+
+	if !reflect.DeepEqual(old.LogConfig, config.LogConfig) || !reflect.DeepEqual(old.TransportConfig, config.TransportConfig) ||
+		!reflect.DeepEqual(old.ThrottleConfig, config.ThrottleConfig) || !reflect.DeepEqual(old.ApiConfig, config.ApiConfig) ||
+		!reflect.DeepEqual(old.StatsConfig, config.StatsConfig) || !reflect.DeepEqual(old.MetricsConfig, config.MetricsConfig) ||
+		!reflect.DeepEqual(old.DebugConfig, config.DebugConfig) || !reflect.DeepEqual(old.EventsConfig, config.EventsConfig) ||
+		!reflect.DeepEqual(old.TracingConfig, config.TracingConfig) || !reflect.DeepEqual(old.HealthConfig, config.HealthConfig) ||
+		!reflect.DeepEqual(old.PolicyConfig, config.PolicyConfig) {
+		this.logger.Warning("Point: Changes of log, transport, throttle, API, stats, metrics, debug, events, tracing, health and policy settings take effect after restart.")
+	}
+
+	if err := internet.ReloadCertificates(); err != nil {
+		this.logger.Warning("Point: Some certificates are not reloaded: ", err)
+	}
+
+	// Handlers created are closed if the reload fails before they are applied.
+	var ich proxy.InboundHandler
+	var newIdh []InboundDetourHandler
+	var och proxy.OutboundHandler
+	var newOdh []proxy.OutboundHandler
+	applied := false
+	defer func() {
+		if applied {
+			return
+		}
+		if ich != nil {
+			ich.Close()
+		}
+		for _, detourHandler := range newIdh {
+			detourHandler.Close()
+		}
+		if och != nil {
+			releaseOutbound(och)
+		}
+		for _, detourHandler := range newOdh {
+			releaseOutbound(detourHandler)
+		}
+	}()
+
+	port := inboundPort(config)
+	if port != this.port || !reflect.DeepEqual(old.InboundConfig, config.InboundConfig) {
+		handler, err := newInboundHandler(this.space, config.InboundConfig, port)
+		if err != nil {
+			return err
+		}
+		ich = handler
+	}
+
+	if !reflect.DeepEqual(old.OutboundConfig, config.OutboundConfig) {
+		handler, err := newOutboundHandler(this.space, config.OutboundConfig)
+		if err != nil {
+			return err
+		}
+		och = handler
+	} else {
+		config.OutboundConfig = old.OutboundConfig
+	}
+
+	kept := make(map[int]bool)
+	idh := make([]InboundDetourHandler, len(config.InboundDetours))
+	for idx, detourConfig := range config.InboundDetours {
+		if oldIdx := findInboundDetour(old.InboundDetours, detourConfig, kept); oldIdx >= 0 {
+			kept[oldIdx] = true
+			idh[idx] = this.idh[oldIdx]
+			continue
+		}
+		detourHandler, err := newInboundDetourHandler(this.space, detourConfig)
+		if err != nil {
+			return err
+		}
+		idh[idx] = detourHandler
+		newIdh = append(newIdh, detourHandler)
+	}
+
+	odh := make(map[string]proxy.OutboundHandler)
+	for idx, detourConfig := range config.OutboundDetours {
+		if oldConfig := findOutboundDetour(old.OutboundDetours, detourConfig); oldConfig != nil {
+			config.OutboundDetours[idx] = oldConfig
+			odh[detourConfig.Tag] = this.odh[detourConfig.Tag]
+			continue
+		}
+		detourHandler, err := newOutboundDetourHandler(this.space, detourConfig)
+		if err != nil {
+			return err
+		}
+		odh[detourConfig.Tag] = detourHandler
+		newOdh = append(newOdh, detourHandler)
+	}
+
+	var applyDNS, applyRouter func()
+	if !proto.Equal(old.DNSConfig, config.DNSConfig) {
+		applyDNS, err = this.prepareDNS(config.DNSConfig)
+		if err != nil {
+			return err
+		}
+	}
+	if !reflect.DeepEqual(old.RouterConfig, config.RouterConfig) {
+		applyRouter, err = this.prepareRouter(config.RouterConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Everything is validated. Nothing below fails until the new inbounds start.
+	applied = true
+	if applyDNS != nil {
+		applyDNS()
+	}
+	if applyRouter != nil {
+		applyRouter()
+	}
+	if och != nil {
+		this.ohm.SetDefaultHandler(och)
+	}
+	for tag := range this.odh {
+		if _, found := odh[tag]; !found {
+			this.ohm.RemoveHandler(tag)
+		}
+	}
+	for tag, handler := range odh {
+		this.ohm.SetHandler(tag, handler)
+	}
+	stopRemovedPlugins(old, config)
+
+	// Old inbounds are closed before the new ones start, as they may listen on the same ports.
+	if ich != nil {
+		this.ich.Close()
+	}
+	for idx, detourHandler := range this.idh {
+		if !kept[idx] {
+			detourHandler.Close()
+		}
+	}
+
+	taggedIdh := make(map[string]InboundDetourHandler)
+	for idx, detourConfig := range config.InboundDetours {
+		if len(detourConfig.Tag) > 0 {
+			taggedIdh[detourConfig.Tag] = idh[idx]
+		}
+	}
+
+	this.Lock()
+	oldOch, oldOdh := this.och, this.odh
+	if ich != nil {
+		this.ich = ich
+		this.port = port
+	}
+	if och != nil {
+		this.och = och
+	}
+	this.idh = idh
+	this.taggedIdh = taggedIdh
+	this.odh = odh
+	this.config = config
+	this.Unlock()
+
+	// Outbounds replaced or dropped are released once they are no longer picked by the dispatcher.
+	if och != nil {
+		releaseOutbound(oldOch)
+	}
+	for tag, detourHandler := range oldOdh {
+		if odh[tag] != detourHandler {
+			releaseOutbound(detourHandler)
+		}
+	}
+
+	// The config is applied at this point, so all new inbounds are started even if some of them fail.
+	if ich != nil {
+		err = retry.Timed(100 /* times */, 100 /* ms */).On(func() error {
+			return ich.Start()
+		})
+		if err != nil {
+			this.logger.Error("Point: Failed to start inbound on port ", port, ": ", err)
+		} else {
+			this.logger.Warning("Point: restarted on port ", port)
+		}
+	}
+	for _, detourHandler := range newIdh {
+		if startErr := detourHandler.Start(); startErr != nil {
+			this.logger.Error("Point: Failed to start inbound detour: ", startErr)
+			if err == nil {
+				err = startErr
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	this.logger.Warning("Point: Config reloaded.")
+	return nil
+}
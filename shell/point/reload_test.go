@@ -0,0 +1,222 @@
+// +build json
+
+package point_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"v2ray.com/core/app/router"
+	v2net "v2ray.com/core/common/net"
+	_ "v2ray.com/core/proxy/dokodemo"
+	_ "v2ray.com/core/proxy/freedom"
+	. "v2ray.com/core/shell/point"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
+	"v2ray.com/core/transport/internet/plugin"
+	_ "v2ray.com/core/transport/internet/tcp"
+)
+
+func pickPort() int {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func loadReloadConfig(port int, detourPort int) *Config {
+	rawConfig := fmt.Sprintf(`{
+    "inbound": {
+      "port": %d,
+      "listen": "127.0.0.1",
+      "protocol": "dokodemo-door",
+      "settings": {"address": "127.0.0.1", "port": 53, "network": "tcp"}
+    },
+    "outbound": {
+      "protocol": "freedom",
+      "settings": {}
+    },
+    "inboundDetour": [{
+      "port": %d,
+      "listen": "127.0.0.1",
+      "protocol": "dokodemo-door",
+      "settings": {"address": "127.0.0.1", "port": 53, "network": "tcp"},
+      "tag": "detour"
+    }]
+  }`, port, detourPort)
+	config := new(Config)
+	if err := json.Unmarshal([]byte(rawConfig), config); err != nil {
+		panic(err)
+	}
+	return config
+}
+
+func canDial(port int) bool {
+	conn, err := net.Dial("tcp4", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func TestReload(t *testing.T) {
+	assert := assert.On(t)
+
+	port := pickPort()
+	detourPort := pickPort()
+	vPoint, err := NewPoint(loadReloadConfig(port, detourPort))
+	assert.Error(err).IsNil()
+	assert.Error(vPoint.Start()).IsNil()
+	defer vPoint.Close()
+
+	assert.Bool(canDial(port)).IsTrue()
+	detour, _ := vPoint.GetHandler("detour")
+	assert.Pointer(detour).IsNotNil()
+
+	newPort := pickPort()
+	assert.Error(vPoint.Reload(loadReloadConfig(newPort, detourPort))).IsNil()
+	assert.Bool(canDial(port)).IsFalse()
+	assert.Bool(canDial(newPort)).IsTrue()
+	assert.Bool(canDial(detourPort)).IsTrue()
+
+	reloadedDetour, _ := vPoint.GetHandler("detour")
+	assert.Bool(reloadedDetour == detour).IsTrue()
+}
+
+func TestReloadInvalid(t *testing.T) {
+	assert := assert.On(t)
+
+	port := pickPort()
+	detourPort := pickPort()
+	vPoint, err := NewPoint(loadReloadConfig(port, detourPort))
+	assert.Error(err).IsNil()
+	assert.Error(vPoint.Start()).IsNil()
+	defer vPoint.Close()
+	detour, _ := vPoint.GetHandler("detour")
+
+	// Routing can't be enabled on reload, which fails after the new inbounds are created.
+	newPort := pickPort()
+	newDetourPort := pickPort()
+	config := loadReloadConfig(newPort, newDetourPort)
+	config.RouterConfig = new(router.Config)
+	assert.Error(vPoint.Reload(config)).IsNotNil()
+	assert.Bool(canDial(port)).IsTrue()
+	assert.Bool(canDial(detourPort)).IsTrue()
+	assert.Bool(canDial(newPort)).IsFalse()
+	assert.Bool(canDial(newDetourPort)).IsFalse()
+	unchangedDetour, _ := vPoint.GetHandler("detour")
+	assert.Bool(unchangedDetour == detour).IsTrue()
+
+	assert.Error(vPoint.Reload(loadReloadConfig(newPort, newDetourPort))).IsNil()
+	assert.Bool(canDial(port)).IsFalse()
+	assert.Bool(canDial(newPort)).IsTrue()
+	assert.Bool(canDial(newDetourPort)).IsTrue()
+}
+
+// TestHelperPlugin is the plugin process started by TestReloadPlugin. It connects to destinations directly.
+func TestHelperPlugin(t *testing.T) {
+	address := os.Getenv(plugin.EnvAddress)
+	if len(address) == 0 {
+		return
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		os.Exit(1)
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			os.Exit(1)
+		}
+		go func() {
+			defer conn.Close()
+			dest, _, err := plugin.ReadRequest(conn)
+			if err != nil {
+				return
+			}
+			server, err := net.Dial("tcp", dest)
+			if plugin.WriteResponse(conn, err) != nil || err != nil {
+				return
+			}
+			defer server.Close()
+			go io.Copy(server, conn)
+			io.Copy(conn, server)
+		}()
+	}
+}
+
+func loadPluginConfig(port int, pluginPort int, args string) *Config {
+	rawConfig := fmt.Sprintf(`{
+    "inbound": {
+      "port": %d,
+      "listen": "127.0.0.1",
+      "protocol": "dokodemo-door",
+      "settings": {"address": "127.0.0.1", "port": 53, "network": "tcp"}
+    },
+    "outbound": {
+      "protocol": "freedom",
+      "settings": {},
+      "streamSettings": {
+        "plugin": {"address": "127.0.0.1:%d", "command": %q, "args": [%q]}
+      }
+    }
+  }`, port, pluginPort, os.Args[0], args)
+	config := new(Config)
+	if err := json.Unmarshal([]byte(rawConfig), config); err != nil {
+		panic(err)
+	}
+	return config
+}
+
+func TestReloadPlugin(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return data
+		},
+	}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	dialThroughPlugin := func(vPoint *Point) {
+		conn, err := vPoint.Dial(context.Background(), v2net.TCPDestination(v2net.LocalHostIP, dest.Port))
+		assert.Error(err).IsNil()
+		_, err = conn.Write([]byte("plugin"))
+		assert.Error(err).IsNil()
+		response := make([]byte, 6)
+		_, err = io.ReadFull(conn, response)
+		assert.Error(err).IsNil()
+		conn.Close()
+	}
+
+	port := pickPort()
+	pluginPort := pickPort()
+	vPoint, err := NewPoint(loadPluginConfig(port, pluginPort, "-test.run=TestHelperPlugin"))
+	assert.Error(err).IsNil()
+	assert.Error(vPoint.Start()).IsNil()
+	dialThroughPlugin(vPoint)
+	assert.Bool(canDial(pluginPort)).IsTrue()
+
+	// Reloading the same outbound keeps the plugin running.
+	assert.Error(vPoint.Reload(loadPluginConfig(port, pluginPort, "-test.run=TestHelperPlugin"))).IsNil()
+	assert.Bool(canDial(pluginPort)).IsTrue()
+
+	// The plugin is stopped once its outbound changes, and the new one is started on the next connection.
+	assert.Error(vPoint.Reload(loadPluginConfig(port, pluginPort, "-test.run=TestHelperPlugin$"))).IsNil()
+	assert.Bool(canDial(pluginPort)).IsFalse()
+	dialThroughPlugin(vPoint)
+	assert.Bool(canDial(pluginPort)).IsTrue()
+
+	vPoint.Close()
+	assert.Bool(canDial(pluginPort)).IsFalse()
+}
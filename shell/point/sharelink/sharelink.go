@@ -0,0 +1,233 @@
+// Package sharelink parses and formats share links of servers, i.e. vmess:// links in the format of V2RayN and ss://
+// links in the format of SIP002, which are used by mobile clients to import servers.
+package sharelink
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownScheme = errors.New("ShareLink: Unknown scheme.")
+	ErrInvalidLink   = errors.New("ShareLink: Invalid link.")
+)
+
+// decodeBase64 decodes base64 in the standard or URL encoding, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("-", "+", "_", "/").Replace(s)
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// VMess is a VMess server in a vmess:// link.
+type VMess struct {
+	Name    string
+	Address string
+	Port    int
+	ID      string
+	AlterID int
+	// Network is the transport, e.g. "tcp", "kcp" or "ws".
+	Network string
+	// Type is the header type of the transport, e.g. "none" or "srtp".
+	Type string
+	Host string
+	Path string
+	TLS  bool
+}
+
+// vmessLink is the JSON in a vmess:// link. Numbers are written as either strings or numbers by clients.
+type vmessLink struct {
+	Version string          `json:"v"`
+	Name    string          `json:"ps"`
+	Address string          `json:"add"`
+	Port    json.RawMessage `json:"port"`
+	ID      string          `json:"id"`
+	AlterID json.RawMessage `json:"aid"`
+	Network string          `json:"net"`
+	Type    string          `json:"type"`
+	Host    string          `json:"host"`
+	Path    string          `json:"path"`
+	TLS     string          `json:"tls"`
+}
+
+// parseNumber parses a JSON number, or a string of number. An empty value is zero.
+func parseNumber(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		s = string(raw)
+	}
+	if len(s) == 0 {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// ParseVMess parses a vmess:// link.
+func ParseVMess(link string) (*VMess, error) {
+	if !strings.HasPrefix(link, "vmess://") {
+		return nil, ErrUnknownScheme
+	}
+	content, err := decodeBase64(link[len("vmess://"):])
+	if err != nil {
+		return nil, ErrInvalidLink
+	}
+	raw := new(vmessLink)
+	if err := json.Unmarshal(content, raw); err != nil {
+		return nil, ErrInvalidLink
+	}
+	port, err := parseNumber(raw.Port)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, errors.New("ShareLink: Invalid port in VMess link.")
+	}
+	alterID, err := parseNumber(raw.AlterID)
+	if err != nil || alterID < 0 {
+		return nil, errors.New("ShareLink: Invalid alterId in VMess link.")
+	}
+	if len(raw.Address) == 0 || len(raw.ID) == 0 {
+		return nil, errors.New("ShareLink: Address or ID is missing in VMess link.")
+	}
+	vmess := &VMess{
+		Name:    raw.Name,
+		Address: raw.Address,
+		Port:    port,
+		ID:      raw.ID,
+		AlterID: alterID,
+		Network: strings.ToLower(raw.Network),
+		Type:    strings.ToLower(raw.Type),
+		Host:    raw.Host,
+		Path:    raw.Path,
+		TLS:     strings.ToLower(raw.TLS) == "tls",
+	}
+	if len(vmess.Network) == 0 {
+		vmess.Network = "tcp"
+	}
+	if len(vmess.Type) == 0 {
+		vmess.Type = "none"
+	}
+	return vmess, nil
+}
+
+// String returns the vmess:// link of the server.
+func (this *VMess) String() string {
+	raw := map[string]string{
+		"v":    "2",
+		"ps":   this.Name,
+		"add":  this.Address,
+		"port": strconv.Itoa(this.Port),
+		"id":   this.ID,
+		"aid":  strconv.Itoa(this.AlterID),
+		"net":  this.Network,
+		"type": this.Type,
+		"host": this.Host,
+		"path": this.Path,
+		"tls":  "",
+	}
+	if this.TLS {
+		raw["tls"] = "tls"
+	}
+	content, _ := json.Marshal(raw)
+	return "vmess://" + base64.StdEncoding.EncodeToString(content)
+}
+
+// Shadowsocks is a Shadowsocks server in an ss:// link.
+type Shadowsocks struct {
+	Name     string
+	Address  string
+	Port     int
+	Method   string
+	Password string
+}
+
+// ParseShadowsocks parses an ss:// link, in either the format of SIP002 or the legacy one of which the part before "#"
+// is in base64.
+func ParseShadowsocks(link string) (*Shadowsocks, error) {
+	if !strings.HasPrefix(link, "ss://") {
+		return nil, ErrUnknownScheme
+	}
+	link = link[len("ss://"):]
+	ss := new(Shadowsocks)
+	if idx := strings.Index(link, "#"); idx >= 0 {
+		name, err := url.PathUnescape(link[idx+1:])
+		if err != nil {
+			return nil, ErrInvalidLink
+		}
+		ss.Name = name
+		link = link[:idx]
+	}
+	// Plugin options are not supported.
+	if idx := strings.IndexAny(link, "/?"); idx >= 0 {
+		link = link[:idx]
+	}
+
+	var userInfo, hostPort string
+	if idx := strings.LastIndex(link, "@"); idx >= 0 {
+		decoded, err := decodeBase64(link[:idx])
+		if err != nil {
+			// User info of AEAD ciphers may be percent-encoded instead.
+			unescaped, err := url.PathUnescape(link[:idx])
+			if err != nil {
+				return nil, ErrInvalidLink
+			}
+			decoded = []byte(unescaped)
+		}
+		userInfo, hostPort = string(decoded), link[idx+1:]
+	} else {
+		decoded, err := decodeBase64(link)
+		if err != nil {
+			return nil, ErrInvalidLink
+		}
+		idx := strings.LastIndex(string(decoded), "@")
+		if idx < 0 {
+			return nil, ErrInvalidLink
+		}
+		userInfo, hostPort = string(decoded[:idx]), string(decoded[idx+1:])
+	}
+
+	idx := strings.Index(userInfo, ":")
+	if idx < 0 {
+		return nil, errors.New("ShareLink: Method or password is missing in Shadowsocks link.")
+	}
+	ss.Method, ss.Password = strings.ToLower(userInfo[:idx]), userInfo[idx+1:]
+	host, portString, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, ErrInvalidLink
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, errors.New("ShareLink: Invalid port in Shadowsocks link.")
+	}
+	ss.Address, ss.Port = host, port
+	return ss, nil
+}
+
+// String returns the ss:// link of the server in the format of SIP002.
+func (this *Shadowsocks) String() string {
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte(this.Method + ":" + this.Password))
+	link := "ss://" + userInfo + "@" + net.JoinHostPort(this.Address, strconv.Itoa(this.Port))
+	if len(this.Name) > 0 {
+		link += "#" + url.PathEscape(this.Name)
+	}
+	return link
+}
+
+// Parse parses a share link, and returns a *VMess or a *Shadowsocks.
+func Parse(link string) (interface{}, error) {
+	link = strings.TrimSpace(link)
+	switch {
+	case strings.HasPrefix(link, "vmess://"):
+		return ParseVMess(link)
+	case strings.HasPrefix(link, "ss://"):
+		return ParseShadowsocks(link)
+	default:
+		return nil, fmt.Errorf("ShareLink: Unknown scheme of link: %.16s", link)
+	}
+}
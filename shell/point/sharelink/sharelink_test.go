@@ -0,0 +1,60 @@
+package sharelink_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	. "v2ray.com/core/shell/point/sharelink"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestVMessLink(t *testing.T) {
+	assert := assert.On(t)
+
+	link := "vmess://" + base64.StdEncoding.EncodeToString([]byte(`{"v":"2","ps":"server","add":"example.com","port":443,"id":"d17a1af7-efa5-42ca-b7e9-6a35282d737f","aid":"16","net":"ws","path":"/ray","tls":"tls"}`))
+	vmess, err := ParseVMess(link)
+	assert.Error(err).IsNil()
+	assert.String(vmess.Name).Equals("server")
+	assert.String(vmess.Address).Equals("example.com")
+	assert.Int(vmess.Port).Equals(443)
+	assert.Int(vmess.AlterID).Equals(16)
+	assert.String(vmess.Network).Equals("ws")
+	assert.String(vmess.Type).Equals("none")
+	assert.String(vmess.Path).Equals("/ray")
+	assert.Bool(vmess.TLS).IsTrue()
+
+	parsed, err := Parse(vmess.String())
+	assert.Error(err).IsNil()
+	assert.Pointer(parsed).IsNotNil()
+	assert.String(parsed.(*VMess).String()).Equals(vmess.String())
+
+	_, err = ParseVMess("vmess://" + base64.StdEncoding.EncodeToString([]byte(`{"add":"example.com","port":"0","id":"a"}`)))
+	assert.Error(err).IsNotNil()
+	_, err = ParseVMess("vmess://!")
+	assert.Error(err).IsNotNil()
+}
+
+func TestShadowsocksLink(t *testing.T) {
+	assert := assert.On(t)
+
+	ss, err := ParseShadowsocks("ss://YWVzLTI1Ni1jZmI6cGFzczp3b3Jk@192.168.100.1:8888#Example%20Server")
+	assert.Error(err).IsNil()
+	assert.String(ss.Method).Equals("aes-256-cfb")
+	assert.String(ss.Password).Equals("pass:word")
+	assert.String(ss.Address).Equals("192.168.100.1")
+	assert.Int(ss.Port).Equals(8888)
+	assert.String(ss.Name).Equals("Example Server")
+	assert.String(ss.String()).Equals("ss://YWVzLTI1Ni1jZmI6cGFzczp3b3Jk@192.168.100.1:8888#Example%20Server")
+
+	// Legacy format.
+	ss, err = ParseShadowsocks("ss://" + base64.StdEncoding.EncodeToString([]byte("chacha20:secret@[::1]:8388")))
+	assert.Error(err).IsNil()
+	assert.String(ss.Method).Equals("chacha20")
+	assert.String(ss.Password).Equals("secret")
+	assert.String(ss.Address).Equals("::1")
+	assert.Int(ss.Port).Equals(8388)
+	assert.String(ss.String()).Equals("ss://Y2hhY2hhMjA6c2VjcmV0@[::1]:8388")
+
+	_, err = Parse("trojan://password@example.com:443")
+	assert.Error(err).IsNotNil()
+}
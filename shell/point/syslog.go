@@ -0,0 +1,37 @@
+package point
+
+import (
+	"v2ray.com/core/common/log"
+)
+
+// initSystemLogger sends error logs of the logger, and access logs if enabled, to syslog or the Windows Event Log as
+// configured.
+func (this *LogConfig) initSystemLogger(logger *log.Logger) error {
+	var handler log.ErrorLogHandler
+	access := false
+	if config := this.Syslog; config != nil {
+		writer, err := log.NewSyslogWriter(config.Network, config.Address, config.Tag, config.Facility)
+		if err != nil {
+			log.Error("Point: Failed to connect to syslog: ", err)
+			return err
+		}
+		handler = writer.Handler()
+		access = config.Access
+	} else if config := this.EventLog; config != nil {
+		eventLogHandler, err := openEventLog(config.Source)
+		if err != nil {
+			log.Error("Point: Failed to open event log: ", err)
+			return err
+		}
+		handler = eventLogHandler
+		access = config.Access
+	}
+	if handler == nil {
+		return nil
+	}
+	logger.InitErrorLogHandler(handler)
+	if access {
+		logger.InitAccessLogHandler(handler, this.AccessFormat == AccessFormatJSON)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package point
+
+import (
+	"errors"
+	"fmt"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+// inboundPorts is the ports an inbound listens on.
+type inboundPorts struct {
+	name   string
+	listen v2net.Address
+	ports  v2net.PortRange
+}
+
+func (this *inboundPorts) conflicts(that *inboundPorts) bool {
+	if this.ports.From > that.ports.To || that.ports.From > this.ports.To {
+		return false
+	}
+	return isAnyAddress(this.listen) || isAnyAddress(that.listen) || this.listen.Equals(that.listen)
+}
+
+func isAnyAddress(address v2net.Address) bool {
+	return address == nil || (!address.Family().IsDomain() && address.IP().IsUnspecified())
+}
+
+// ValidateConfig checks a parsed config for problems that would only show up at runtime, e.g. inbounds on the same
+// ports, and returns all of them.
+func ValidateConfig(config *Config) []error {
+	var errs []error
+
+	port := inboundPort(config)
+	if port == 0 {
+		errs = append(errs, errors.New("Point: Port of inbound is not set."))
+	}
+	inbounds := []*inboundPorts{{
+		name:   "inbound",
+		listen: config.InboundConfig.ListenOn,
+		ports:  v2net.PortRange{From: uint32(port), To: uint32(port)},
+	}}
+	inboundTags := make(map[string]bool)
+	for idx, detour := range config.InboundDetours {
+		name := fmt.Sprint("inboundDetour[", idx, "]")
+		if len(detour.Tag) > 0 {
+			name += " (" + detour.Tag + ")"
+			if inboundTags[detour.Tag] {
+				errs = append(errs, errors.New("Point: Duplicated inbound tag: "+detour.Tag))
+			}
+			inboundTags[detour.Tag] = true
+		}
+		inbounds = append(inbounds, &inboundPorts{
+			name:   name,
+			listen: detour.ListenOn,
+			ports:  detour.PortRange,
+		})
+	}
+	for idx, inbound := range inbounds {
+		for _, another := range inbounds[:idx] {
+			if inbound.conflicts(another) {
+				errs = append(errs, errors.New("Point: Ports of "+inbound.name+" conflict with "+another.name+"."))
+			}
+		}
+	}
+
+	outboundTags := make(map[string]bool)
+	for _, detour := range config.OutboundDetours {
+		if len(detour.Tag) == 0 {
+			continue
+		}
+		if outboundTags[detour.Tag] {
+			errs = append(errs, errors.New("Point: Duplicated outbound tag: "+detour.Tag))
+		}
+		outboundTags[detour.Tag] = true
+	}
+	checkOutboundTag := func(name string, tag string) {
+		if len(tag) > 0 && !outboundTags[tag] {
+			errs = append(errs, errors.New("Point: Default outbound of "+name+" is not found: "+tag))
+		}
+	}
+	checkOutboundTag("inbound", config.InboundConfig.DefaultOutboundTag)
+	for idx, detour := range config.InboundDetours {
+		checkOutboundTag(inbounds[idx+1].name, detour.DefaultOutboundTag)
+	}
+	if config.HealthConfig != nil {
+		for _, tag := range config.HealthConfig.Outbounds {
+			if !outboundTags[tag] {
+				errs = append(errs, errors.New("Point: Outbound of health check is not found: "+tag))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,46 @@
+package point_test
+
+import (
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/shell/point"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &Config{
+		InboundConfig: &InboundConnectionConfig{
+			Port:     1080,
+			ListenOn: v2net.AnyIP,
+		},
+		InboundDetours: []*InboundDetourConfig{
+			{
+				PortRange: v2net.PortRange{From: 1081, To: 1090},
+				ListenOn:  v2net.LocalHostIP,
+				Tag:       "a",
+			},
+			{
+				PortRange: v2net.PortRange{From: 1090, To: 1090},
+				ListenOn:  v2net.ParseAddress("127.0.0.2"),
+				Tag:       "b",
+			},
+		},
+		OutboundDetours: []*OutboundDetourConfig{
+			{Tag: "direct"},
+			{},
+			{},
+		},
+	}
+	assert.Int(len(ValidateConfig(config))).Equals(0)
+
+	config.InboundConfig.Port = 1085
+	config.InboundDetours[1].ListenOn = v2net.LocalHostIP
+	config.InboundDetours[1].Tag = "a"
+	config.InboundDetours[1].DefaultOutboundTag = "proxy"
+	config.OutboundDetours = append(config.OutboundDetours, &OutboundDetourConfig{Tag: "direct"})
+	errs := ValidateConfig(config)
+	assert.Int(len(errs)).Equals(5)
+}
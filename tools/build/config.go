@@ -64,6 +64,12 @@ func copyConfigFiles(dir string, goOS GoOS) error {
 		if err := copyConfigFile(src, dest, goOS, false); err != nil {
 			return err
 		}
+
+		src = filepath.Join(srcDir, "systemd", "v2ray.socket")
+		dest = filepath.Join(dir, "systemd", "v2ray.socket")
+		if err := copyConfigFile(src, dest, goOS, false); err != nil {
+			return err
+		}
 	}
 
 	return nil
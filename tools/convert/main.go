@@ -0,0 +1,93 @@
+// +build json
+
+// Convert converts V2Ray configs between JSON and protobuf. Multiple configs are merged as V2Ray does at startup.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	_ "v2ray.com/core/app/router/rules"
+	"v2ray.com/core/shell/point"
+
+	// Settings of protocols, routers and mKCP headers are converted by the configs registered in these packages.
+	_ "v2ray.com/core/proxy/blackhole"
+	_ "v2ray.com/core/proxy/dns"
+	_ "v2ray.com/core/proxy/dokodemo"
+	_ "v2ray.com/core/proxy/freedom"
+	_ "v2ray.com/core/proxy/http"
+	_ "v2ray.com/core/proxy/shadowsocks"
+	_ "v2ray.com/core/proxy/socks"
+	_ "v2ray.com/core/proxy/vmess/inbound"
+	_ "v2ray.com/core/proxy/vmess/outbound"
+
+	_ "v2ray.com/core/transport/internet/authenticators/noop"
+	_ "v2ray.com/core/transport/internet/authenticators/srtp"
+	_ "v2ray.com/core/transport/internet/authenticators/utp"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type fileList []string
+
+func (this *fileList) String() string {
+	return strings.Join(*this, ",")
+}
+
+func (this *fileList) Set(value string) error {
+	*this = append(*this, value)
+	return nil
+}
+
+var (
+	inputFiles fileList
+	format     = flag.String("format", "auto", "Format of input files: auto, json, yaml, toml or pb.")
+	outputFile = flag.String("o", "", "Output file. Output is written to stdout if not set.")
+	outputType = flag.String("to", "pb", "Format of output: pb or json.")
+)
+
+func init() {
+	flag.Var(&inputFiles, "config", "Config file or directory to convert. Multiple ones are merged in order.")
+}
+
+func convert() ([]byte, error) {
+	if len(inputFiles) == 0 {
+		return nil, fmt.Errorf("config file is not set")
+	}
+	if err := point.SetConfigFormat(*format); err != nil {
+		return nil, fmt.Errorf("unknown format: %s", *format)
+	}
+	config, err := point.ConvertConfigToPB(inputFiles...)
+	if err != nil {
+		return nil, err
+	}
+	switch *outputType {
+	case "pb":
+		return proto.Marshal(config)
+	case "json":
+		return config.ToJSON()
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", *outputType)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	output, err := convert()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to convert config:", err)
+		os.Exit(1)
+	}
+	if len(*outputFile) == 0 {
+		os.Stdout.Write(output)
+		return
+	}
+	if err := ioutil.WriteFile(*outputFile, output, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write config:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,96 @@
+package geodat_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"v2ray.com/core/app/router/rules"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/tools/geodat"
+)
+
+func TestCompileGeoIP(t *testing.T) {
+	assert := assert.On(t)
+
+	locations := `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,is_in_european_union
+1814991,en,AS,Asia,CN,China,0
+2635167,en,EU,Europe,GB,"United Kingdom",1
+6255148,en,EU,Europe,,,0
+`
+	blocksIPv4 := `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+1.0.1.0/24,1814991,1814991,,0,0
+2.16.0.0/13,,2635167,,0,0
+2.56.0.0/14,6255148,6255148,,0,0
+`
+	blocksIPv6 := `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+2001:250::/35,1814991,1814991,,0,0
+`
+	list, err := CompileGeoIP(strings.NewReader(locations), strings.NewReader(blocksIPv4), strings.NewReader(blocksIPv6))
+	assert.Error(err).IsNil()
+	assert.Int(len(list.Entry)).Equals(2)
+	assert.String(list.Entry[0].CountryCode).Equals("CN")
+	assert.Int(len(list.Entry[0].Cidr)).Equals(2)
+	assert.Bytes(list.Entry[0].Cidr[0].Ip).Equals([]byte{1, 0, 1, 0})
+	assert.Uint32(list.Entry[0].Cidr[0].Prefix).Equals(24)
+	assert.Bytes(list.Entry[0].Cidr[1].Ip).Equals([]byte(net.ParseIP("2001:250::")))
+	assert.String(list.Entry[1].CountryCode).Equals("GB")
+
+	matcher, err := rules.NewGeoIPMatcher(list.Entry[1].Cidr)
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Match(net.ParseIP("2.17.0.1"))).IsTrue()
+
+	_, err = CompileGeoIP(strings.NewReader("geoname_id\n"), strings.NewReader(blocksIPv4))
+	assert.Error(err).IsNotNil()
+}
+
+func TestCompileGeoSite(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-geodat")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"google": `# Google
+google.com @cn
+full:www.google.com
+keyword:googleapis
+regexp:^google\.[a-z]+$ # regional domains
+include:youtube
+`,
+		"youtube":  "youtube.com\nyoutube.cn @cn\n",
+		"category": "include:google @cn\ninclude:youtube @-cn\n",
+	}
+	for name, content := range files {
+		assert.Error(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).IsNil()
+	}
+
+	list, err := CompileGeoSite(dir)
+	assert.Error(err).IsNil()
+	assert.Int(len(list.Entry)).Equals(3)
+	assert.String(list.Entry[0].CountryCode).Equals("CATEGORY")
+	assert.Int(len(list.Entry[0].Domain)).Equals(3)
+	assert.String(list.Entry[0].Domain[0].Value).Equals("google.com")
+	assert.String(list.Entry[0].Domain[1].Value).Equals("youtube.cn")
+	assert.String(list.Entry[0].Domain[2].Value).Equals("youtube.com")
+
+	google := list.Entry[1]
+	assert.String(google.CountryCode).Equals("GOOGLE")
+	assert.Int(len(google.Domain)).Equals(6)
+	matcher, err := rules.NewGeoSiteMatcher(google.Domain)
+	assert.Error(err).IsNil()
+	assert.Bool(matcher.Match("mail.google.com")).IsTrue()
+	assert.Bool(matcher.Match("www.google.com")).IsTrue()
+	assert.Bool(matcher.Match("maps.googleapis.com")).IsTrue()
+	assert.Bool(matcher.Match("google.de")).IsTrue()
+	assert.Bool(matcher.Match("m.youtube.com")).IsTrue()
+	assert.Bool(matcher.Match("google.org.cn")).IsFalse()
+
+	assert.Error(ioutil.WriteFile(filepath.Join(dir, "youtube"), []byte("include:google\n"), 0600)).IsNil()
+	_, err = CompileGeoSite(dir)
+	assert.Error(err).IsNotNil()
+}
@@ -0,0 +1,118 @@
+// Package geodat compiles geoip.dat and geosite.dat, the data files of country IPs and domain lists used by the router.
+package geodat
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"v2ray.com/core/app/router/rules"
+)
+
+// csvColumns reads the header of a CSV file, and returns the indices of the given columns.
+func csvColumns(reader *csv.Reader, names ...string) ([]int, error) {
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]int, len(names))
+	for idx, name := range names {
+		columns[idx] = -1
+		for column, field := range header {
+			if strings.TrimSpace(field) == name {
+				columns[idx] = column
+				break
+			}
+		}
+		if columns[idx] < 0 {
+			return nil, errors.New("GeoDat: Column not found in CSV: " + name)
+		}
+	}
+	return columns, nil
+}
+
+// readCountries reads the country codes of geoname IDs in a MaxMind location file, e.g.
+// GeoLite2-Country-Locations-en.csv.
+func readCountries(locations io.Reader) (map[string]string, error) {
+	reader := csv.NewReader(locations)
+	columns, err := csvColumns(reader, "geoname_id", "country_iso_code")
+	if err != nil {
+		return nil, err
+	}
+	countries := make(map[string]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if code := record[columns[1]]; len(code) > 0 {
+			countries[record[columns[0]]] = strings.ToUpper(code)
+		}
+	}
+	return countries, nil
+}
+
+// CompileGeoIP compiles MaxMind country CSV files into the content of geoip.dat. Locations is the location file, e.g.
+// GeoLite2-Country-Locations-en.csv, and blocks are the network files, e.g. GeoLite2-Country-Blocks-IPv4.csv. A
+// network without a country is assigned to its registered country. Entries are sorted by country code.
+func CompileGeoIP(locations io.Reader, blocks ...io.Reader) (*rules.GeoIPList, error) {
+	countries, err := readCountries(locations)
+	if err != nil {
+		return nil, err
+	}
+
+	cidrs := make(map[string][]*rules.CIDR)
+	for _, block := range blocks {
+		reader := csv.NewReader(block)
+		columns, err := csvColumns(reader, "network", "geoname_id", "registered_country_geoname_id")
+		if err != nil {
+			return nil, err
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			country, found := countries[record[columns[1]]]
+			if !found {
+				country, found = countries[record[columns[2]]]
+			}
+			if !found {
+				continue
+			}
+			_, ipNet, err := net.ParseCIDR(record[columns[0]])
+			if err != nil {
+				return nil, errors.New("GeoDat: Invalid network: " + record[columns[0]])
+			}
+			ip := ipNet.IP
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			prefix, _ := ipNet.Mask.Size()
+			cidrs[country] = append(cidrs[country], &rules.CIDR{Ip: ip, Prefix: uint32(prefix)})
+		}
+	}
+
+	codes := make([]string, 0, len(cidrs))
+	for code := range cidrs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	list := new(rules.GeoIPList)
+	for _, code := range codes {
+		list.Entry = append(list.Entry, &rules.GeoIP{
+			CountryCode: code,
+			Cidr:        cidrs[code],
+		})
+	}
+	return list, nil
+}
@@ -0,0 +1,229 @@
+package geodat
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"v2ray.com/core/app/router/rules"
+)
+
+var (
+	domainTypes = map[string]rules.Domain_Type{
+		"domain":  rules.Domain_Domain,
+		"full":    rules.Domain_Full,
+		"keyword": rules.Domain_Plain,
+		"regexp":  rules.Domain_Regex,
+	}
+)
+
+// domainInclude is an "include:" line of a domain list, with its attribute filters.
+type domainInclude struct {
+	name       string
+	attributes []string
+	excluded   []string
+}
+
+// domainList is a parsed domain list file, with its includes not resolved.
+type domainList struct {
+	domains  []*rules.Domain
+	includes []*domainInclude
+}
+
+// parseDomainList parses a domain list in the format of the community domain lists. Each line is a domain in the form
+// of "type:value @attribute ...", where type is one of domain, full, keyword and regexp, and defaults to domain. A line
+// of "include:name @attribute @-attribute" includes the domains of another list, filtered by the attributes they have
+// or don't have. Text after "#" is a comment.
+func parseDomainList(reader io.Reader) (*domainList, error) {
+	list := new(domainList)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		value := fields[0]
+		var attributes []string
+		var excluded []string
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "@") || len(field) < 2 {
+				return nil, errors.New("GeoDat: Invalid attribute: " + field)
+			}
+			if strings.HasPrefix(field, "@-") {
+				excluded = append(excluded, strings.ToLower(field[2:]))
+			} else {
+				attributes = append(attributes, strings.ToLower(field[1:]))
+			}
+		}
+
+		if strings.HasPrefix(value, "include:") {
+			list.includes = append(list.includes, &domainInclude{
+				name:       strings.ToLower(value[8:]),
+				attributes: attributes,
+				excluded:   excluded,
+			})
+			continue
+		}
+		if len(excluded) > 0 {
+			return nil, errors.New("GeoDat: Excluded attributes are only allowed in includes: " + line)
+		}
+
+		domainType := rules.Domain_Domain
+		if idx := strings.Index(value, ":"); idx >= 0 {
+			t, found := domainTypes[strings.ToLower(value[:idx])]
+			if !found {
+				return nil, errors.New("GeoDat: Unknown domain type: " + value)
+			}
+			domainType = t
+			value = value[idx+1:]
+		}
+		if domainType == rules.Domain_Regex {
+			if _, err := regexp.Compile(value); err != nil {
+				return nil, errors.New("GeoDat: Invalid regexp: " + value)
+			}
+		} else {
+			value = strings.ToLower(value)
+		}
+		domain := &rules.Domain{
+			Type:  domainType,
+			Value: value,
+		}
+		for _, attribute := range attributes {
+			domain.Attribute = append(domain.Attribute, &rules.Domain_Attribute{
+				Key:       attribute,
+				BoolValue: true,
+			})
+		}
+		list.domains = append(list.domains, domain)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func hasAttribute(domain *rules.Domain, key string) bool {
+	for _, attribute := range domain.Attribute {
+		if attribute.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *domainInclude) matches(domain *rules.Domain) bool {
+	for _, key := range this.attributes {
+		if !hasAttribute(domain, key) {
+			return false
+		}
+	}
+	for _, key := range this.excluded {
+		if hasAttribute(domain, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// geoSiteCompiler resolves the includes of domain lists.
+type geoSiteCompiler struct {
+	lists    map[string]*domainList
+	resolved map[string][]*rules.Domain
+}
+
+func (this *geoSiteCompiler) resolve(name string, including []string) ([]*rules.Domain, error) {
+	if domains, found := this.resolved[name]; found {
+		return domains, nil
+	}
+	for _, parent := range including {
+		if parent == name {
+			return nil, errors.New("GeoDat: Domain list is included recursively: " + name)
+		}
+	}
+	list, found := this.lists[name]
+	if !found {
+		return nil, errors.New("GeoDat: Domain list not found: " + name)
+	}
+
+	domains := append([]*rules.Domain(nil), list.domains...)
+	for _, include := range list.includes {
+		included, err := this.resolve(include.name, append(including, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, domain := range included {
+			if include.matches(domain) {
+				domains = append(domains, domain)
+			}
+		}
+	}
+
+	// Duplicated domains are removed, as lists are often included by several others.
+	unique := make([]*rules.Domain, 0, len(domains))
+	seen := make(map[string]bool)
+	for _, domain := range domains {
+		key := domain.Type.String() + ":" + domain.Value
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, domain)
+	}
+	this.resolved[name] = unique
+	return unique, nil
+}
+
+// CompileGeoSite compiles the domain lists in a directory into the content of geosite.dat. Each file is a list in the
+// format of parseDomainList, named by its file name. Entries are sorted by list name.
+func CompileGeoSite(dir string) (*rules.GeoSiteList, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	compiler := &geoSiteCompiler{
+		lists:    make(map[string]*domainList),
+		resolved: make(map[string][]*rules.Domain),
+	}
+	var names []string
+	for _, info := range files {
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		file, err := os.Open(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		list, err := parseDomainList(file)
+		file.Close()
+		if err != nil {
+			return nil, errors.New("GeoDat: Failed to parse " + info.Name() + ": " + err.Error())
+		}
+		name := strings.ToLower(info.Name())
+		compiler.lists[name] = list
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	siteList := new(rules.GeoSiteList)
+	for _, name := range names {
+		domains, err := compiler.resolve(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		siteList.Entry = append(siteList.Entry, &rules.GeoSite{
+			CountryCode: strings.ToUpper(name),
+			Domain:      domains,
+		})
+	}
+	return siteList, nil
+}
@@ -0,0 +1,94 @@
+// GeoDat compiles geoip.dat from MaxMind country CSV files, or geosite.dat from a directory of domain lists.
+//
+//	geodat -locations GeoLite2-Country-Locations-en.csv -blocks GeoLite2-Country-Blocks-IPv4.csv -o geoip.dat
+//	geodat -sites domain-list-community/data -o geosite.dat
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"v2ray.com/core/tools/geodat"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type fileList []string
+
+func (this *fileList) String() string {
+	return strings.Join(*this, ",")
+}
+
+func (this *fileList) Set(value string) error {
+	*this = append(*this, value)
+	return nil
+}
+
+var (
+	blockFiles    fileList
+	locationsFile = flag.String("locations", "", "MaxMind country location file, e.g. GeoLite2-Country-Locations-en.csv.")
+	sitesDir      = flag.String("sites", "", "Directory of domain lists. Each file is a list named by the file name.")
+	outputFile    = flag.String("o", "", "Output file, e.g. geoip.dat or geosite.dat.")
+)
+
+func init() {
+	flag.Var(&blockFiles, "blocks", "MaxMind country network file, e.g. GeoLite2-Country-Blocks-IPv4.csv. May be set multiple times.")
+}
+
+func compileGeoIP() (proto.Message, error) {
+	locations, err := os.Open(*locationsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer locations.Close()
+
+	blocks := make([]io.Reader, 0, len(blockFiles))
+	for _, file := range blockFiles {
+		block, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer block.Close()
+		blocks = append(blocks, block)
+	}
+	return geodat.CompileGeoIP(locations, blocks...)
+}
+
+func compile() ([]byte, error) {
+	var list proto.Message
+	var err error
+	switch {
+	case len(*sitesDir) > 0:
+		list, err = geodat.CompileGeoSite(*sitesDir)
+	case len(*locationsFile) > 0 && len(blockFiles) > 0:
+		list, err = compileGeoIP()
+	default:
+		return nil, fmt.Errorf("either -sites, or -locations and -blocks must be set")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(list)
+}
+
+func main() {
+	flag.Parse()
+
+	if len(*outputFile) == 0 {
+		fmt.Fprintln(os.Stderr, "Output file is not set.")
+		os.Exit(1)
+	}
+	output, err := compile()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to compile:", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(*outputFile, output, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write output:", err)
+		os.Exit(1)
+	}
+}
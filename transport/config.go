@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/kcp"
 	"v2ray.com/core/transport/internet/tcp"
 	"v2ray.com/core/transport/internet/ws"
@@ -13,6 +14,19 @@ type Config struct {
 	wsConfig  *ws.Config
 }
 
+// NewConfig returns a Config of the settings of transports. Nil settings are not applied, except that mKCP takes its
+// defaults.
+func NewConfig(tcpConfig *tcp.Config, kcpConfig *kcp.Config, wsConfig *ws.Config) *Config {
+	config := &Config{
+		tcpConfig: tcpConfig,
+		wsConfig:  wsConfig,
+	}
+	if kcpConfig != nil {
+		config.kcpConfig = *kcpConfig
+	}
+	return config
+}
+
 // Apply applies this Config.
 func (this *Config) Apply() error {
 	if this.tcpConfig != nil {
@@ -24,3 +38,11 @@ func (this *Config) Apply() error {
 	}
 	return nil
 }
+
+// RequiredCapabilities returns the capabilities that connections of the given stream settings require.
+func (this *Config) RequiredCapabilities(settings *internet.StreamSettings) []internet.Capability {
+	if settings != nil && settings.IsCapableOf(internet.StreamConnectionTypeKCP) && this.kcpConfig.FakeTcp {
+		return []internet.Capability{internet.CapabilityRawSocket}
+	}
+	return nil
+}
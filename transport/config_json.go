@@ -3,8 +3,7 @@
 package transport
 
 import (
-	"encoding/json"
-
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/transport/internet/kcp"
 	"v2ray.com/core/transport/internet/tcp"
 	"v2ray.com/core/transport/internet/ws"
@@ -17,7 +16,7 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		WSConfig  *ws.Config  `json:"wsSettings"`
 	}
 	jsonConfig := &JsonConfig{}
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
 		return err
 	}
 	this.tcpConfig = jsonConfig.TCPConfig
@@ -0,0 +1,105 @@
+package internet
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+
+	"v2ray.com/core/common/log"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMESettings makes TLS servers obtain and renew certificates of the domains from an ACME CA, such as
+// Let's Encrypt. TLS-ALPN-01 challenges are answered by the TLS listeners themselves, which must be reachable
+// on port 443. HTTP-01 challenges are answered on HTTPAddress if set.
+type ACMESettings struct {
+	Domains []string
+	// Email is the contact of the ACME account. Optional.
+	Email string
+	// CacheDir is the directory where the account key and certificates are kept between restarts.
+	CacheDir string
+	// DirectoryURL is the ACME directory of the CA. Empty means Let's Encrypt.
+	DirectoryURL string
+	// HTTPAddress is the address to answer HTTP-01 challenges on, such as ":80". Empty disables HTTP-01.
+	HTTPAddress string
+}
+
+var (
+	acmeManagerAccess sync.Mutex
+	acmeManagers      = make(map[string]*autocert.Manager)
+)
+
+// getACMEManager returns the ACME manager of the settings, shared by all listeners using the same domains.
+func getACMEManager(settings *ACMESettings) *autocert.Manager {
+	key := strings.Join(settings.Domains, ",") + "|" + settings.CacheDir + "|" + settings.DirectoryURL
+
+	acmeManagerAccess.Lock()
+	defer acmeManagerAccess.Unlock()
+
+	manager, found := acmeManagers[key]
+	if found {
+		return manager
+	}
+
+	manager = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(settings.Domains...),
+		Cache:      autocert.DirCache(settings.CacheDir),
+		Email:      settings.Email,
+	}
+	if len(settings.DirectoryURL) > 0 {
+		manager.Client = &acme.Client{
+			DirectoryURL: settings.DirectoryURL,
+		}
+	}
+	if len(settings.HTTPAddress) > 0 {
+		go func() {
+			if err := http.ListenAndServe(settings.HTTPAddress, manager.HTTPHandler(nil)); err != nil {
+				log.Warning("Internet|TLS: Failed to serve ACME HTTP challenges on ", settings.HTTPAddress, ": ", err)
+			}
+		}()
+	}
+	acmeManagers[key] = manager
+	return manager
+}
+
+func (this *ACMESettings) hasDomain(serverName string) bool {
+	serverName = strings.TrimSuffix(strings.ToLower(serverName), ".")
+	for _, domain := range this.Domains {
+		if strings.ToLower(domain) == serverName {
+			return true
+		}
+	}
+	return false
+}
+
+// applyACME makes config serve certificates of the ACME domains from the ACME manager. Other server names
+// are served by the certificates already in config.
+func applyACME(config *tls.Config, settings *ACMESettings) {
+	manager := getACMEManager(settings)
+	fallback := config.GetCertificate
+	config.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if settings.hasDomain(hello.ServerName) || len(config.Certificates) == 0 {
+			return manager.GetCertificate(hello)
+		}
+		if fallback != nil {
+			return fallback(hello)
+		}
+		return nil, nil
+	}
+	// TLS-ALPN-01 challenges get a config of their own, so that other clients don't see the ACME protocol
+	// in ALPN.
+	challengeConfig := &tls.Config{
+		GetCertificate: manager.GetCertificate,
+		NextProtos:     []string{acme.ALPNProto},
+	}
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto {
+			return challengeConfig, nil
+		}
+		return nil, nil
+	}
+}
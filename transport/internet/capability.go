@@ -0,0 +1,62 @@
+package internet
+
+import (
+	"errors"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+// Capability is a privilege beyond binding ports that some transports and proxies require.
+type Capability int
+
+const (
+	// CapabilityRawSocket is to open raw IP sockets, e.g. for mKCP over FakeTCP. It requires CAP_NET_RAW on Linux.
+	CapabilityRawSocket Capability = iota
+	// CapabilityTransparent is to set IP_TRANSPARENT on sockets, e.g. for receiving original destinations of UDP
+	// packets redirected by TPROXY. It requires CAP_NET_ADMIN on Linux.
+	CapabilityTransparent
+)
+
+func (this Capability) String() string {
+	switch this {
+	case CapabilityRawSocket:
+		return "raw socket"
+	case CapabilityTransparent:
+		return "IP_TRANSPARENT"
+	default:
+		return "unknown capability"
+	}
+}
+
+// CapabilityRequirer is implemented by configs that require capabilities.
+type CapabilityRequirer interface {
+	RequiredCapabilities() []Capability
+}
+
+// NetworkListener is implemented by configs of inbounds that listen on UDP besides or instead of their stream
+// transports. TCP in the networks stands for the stream transport. Inbounds of other configs listen on the stream
+// transport only.
+type NetworkListener interface {
+	ListenNetworks() []v2net.Network
+}
+
+var (
+	ErrUnknownCapability = errors.New("Internet: Unknown capability.")
+
+	capabilityCheckers = make(map[Capability]func() error)
+)
+
+// RegisterCapabilityChecker registers the function to check whether the process has a capability. It is registered by
+// the package that uses the capability.
+func RegisterCapabilityChecker(capability Capability, checker func() error) {
+	capabilityCheckers[capability] = checker
+}
+
+// CheckCapability returns an error if the process doesn't have the capability.
+func CheckCapability(capability Capability) error {
+	checker, found := capabilityCheckers[capability]
+	if !found {
+		return ErrUnknownCapability
+	}
+	return checker()
+}
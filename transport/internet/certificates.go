@@ -0,0 +1,186 @@
+package internet
+
+import (
+	"crypto/tls"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+)
+
+// CertificateFile is the location of a certificate and its private key on disk.
+type CertificateFile struct {
+	CertFile string
+	KeyFile  string
+}
+
+var (
+	// CertificateCheckInterval is the minimum time between two checks of certificate files for changes.
+	CertificateCheckInterval = 10 * time.Second
+
+	certificateStoreAccess sync.Mutex
+	certificateStores      = make(map[string]*certificateStore)
+)
+
+// certificateStore serves certificates loaded from files, and reloads them when the files change, so that
+// renewed certificates are picked up by new handshakes while existing connections are kept.
+type certificateStore struct {
+	sync.RWMutex
+	files    []*CertificateFile
+	modTimes []time.Time
+	checked  time.Time
+	config   *tls.Config
+}
+
+// getCertificateStore returns the store of the given files, shared by all listeners using the same files.
+// certs are the certificates already loaded from the files.
+func getCertificateStore(files []*CertificateFile, certs []tls.Certificate) *certificateStore {
+	keys := make([]string, len(files))
+	for idx, file := range files {
+		keys[idx] = file.CertFile + "|" + file.KeyFile
+	}
+	key := strings.Join(keys, ";")
+
+	certificateStoreAccess.Lock()
+	defer certificateStoreAccess.Unlock()
+
+	store, found := certificateStores[key]
+	if !found {
+		store = &certificateStore{
+			files:    files,
+			modTimes: make([]time.Time, len(files)),
+			checked:  time.Now(),
+		}
+		for idx, file := range files {
+			store.modTimes[idx] = modTimeOf(file)
+		}
+		store.setCertificates(certs)
+		certificateStores[key] = store
+	}
+	return store
+}
+
+func modTimeOf(file *CertificateFile) time.Time {
+	var latest time.Time
+	for _, name := range []string{file.CertFile, file.KeyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func (this *certificateStore) setCertificates(certs []tls.Certificate) {
+	config := &tls.Config{
+		Certificates: certs,
+	}
+	config.BuildNameToCertificate()
+	this.config = config
+}
+
+// Reload loads the certificates again if any of the files has changed since last load. It returns true
+// if the certificates are replaced.
+func (this *certificateStore) Reload() (bool, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.checked = time.Now()
+
+	modTimes := make([]time.Time, len(this.files))
+	changed := false
+	for idx, file := range this.files {
+		modTimes[idx] = modTimeOf(file)
+		if !modTimes[idx].Equal(this.modTimes[idx]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	certs := make([]tls.Certificate, len(this.files))
+	for idx, file := range this.files {
+		cert, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+		if err != nil {
+			// Files may be in the middle of being replaced. Keep the current certificates and try again later.
+			return false, err
+		}
+		certs[idx] = cert
+	}
+	this.modTimes = modTimes
+	this.setCertificates(certs)
+	return true, nil
+}
+
+func (this *certificateStore) checkFiles() {
+	this.RLock()
+	due := time.Since(this.checked) >= CertificateCheckInterval
+	this.RUnlock()
+	if !due {
+		return
+	}
+
+	reloaded, err := this.Reload()
+	if err != nil {
+		log.Warning("Internet|TLS: Failed to reload certificates: ", err)
+		return
+	}
+	if reloaded {
+		log.Info("Internet|TLS: Certificates reloaded from ", this.files[0].CertFile)
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (this *certificateStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	this.checkFiles()
+
+	this.RLock()
+	config := this.config
+	this.RUnlock()
+
+	if len(config.Certificates) == 0 {
+		return nil, nil
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	if cert, found := config.NameToCertificate[name]; found {
+		return cert, nil
+	}
+	if labels := strings.SplitN(name, ".", 2); len(labels) == 2 {
+		if cert, found := config.NameToCertificate["*."+labels[1]]; found {
+			return cert, nil
+		}
+	}
+	return &config.Certificates[0], nil
+}
+
+// ReloadCertificates reloads certificates of all TLS listeners whose certificate files have changed,
+// regardless of CertificateCheckInterval.
+func ReloadCertificates() error {
+	certificateStoreAccess.Lock()
+	stores := make([]*certificateStore, 0, len(certificateStores))
+	for _, store := range certificateStores {
+		stores = append(stores, store)
+	}
+	certificateStoreAccess.Unlock()
+
+	var lastErr error
+	for _, store := range stores {
+		reloaded, err := store.Reload()
+		if err != nil {
+			log.Warning("Internet|TLS: Failed to reload certificates: ", err)
+			lastErr = err
+			continue
+		}
+		if reloaded {
+			log.Info("Internet|TLS: Certificates reloaded from ", store.files[0].CertFile)
+		}
+	}
+	return lastErr
+}
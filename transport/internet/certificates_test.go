@@ -0,0 +1,99 @@
+package internet_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+)
+
+func writeCertificate(t *testing.T, file *CertificateFile, modTime time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "v2ray.com"},
+		DNSNames:     []string{"v2ray.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(file.CertFile, modTime, modTime)
+	os.Chtimes(file.KeyFile, modTime, modTime)
+	return der
+}
+
+func TestCertificateReload(t *testing.T) {
+	assert := assert.On(t)
+
+	dir, err := ioutil.TempDir("", "v2ray-cert")
+	assert.Error(err).IsNil()
+	defer os.RemoveAll(dir)
+
+	file := &CertificateFile{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+	}
+	now := time.Now()
+	first := writeCertificate(t, file, now.Add(-time.Hour))
+	cert, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+	assert.Error(err).IsNil()
+
+	settings := &TLSSettings{
+		Certs:     []tls.Certificate{cert},
+		CertFiles: []*CertificateFile{file},
+	}
+	config := settings.GetTLSConfig()
+	hello := &tls.ClientHelloInfo{ServerName: "v2ray.com"}
+
+	served, err := config.GetCertificate(hello)
+	assert.Error(err).IsNil()
+	assert.Bool(bytes.Equal(served.Certificate[0], first)).IsTrue()
+
+	second := writeCertificate(t, file, now)
+	assert.Error(ReloadCertificates()).IsNil()
+
+	served, err = config.GetCertificate(hello)
+	assert.Error(err).IsNil()
+	assert.Bool(bytes.Equal(served.Certificate[0], second)).IsTrue()
+
+	// Certificates are checked on handshakes once CertificateCheckInterval passes.
+	interval := CertificateCheckInterval
+	CertificateCheckInterval = 0
+	defer func() {
+		CertificateCheckInterval = interval
+	}()
+	third := writeCertificate(t, file, now.Add(time.Hour))
+
+	served, err = settings.GetTLSConfig().GetCertificate(hello)
+	assert.Error(err).IsNil()
+	assert.Bool(bytes.Equal(served.Certificate[0], third)).IsTrue()
+}
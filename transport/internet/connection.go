@@ -2,7 +2,18 @@ package internet
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
 	"net"
+	"strings"
+	"sync"
+
+	"v2ray.com/core/common/ban"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet/plugin"
+	"v2ray.com/core/transport/internet/reality"
 )
 
 type ConnectionHandler func(Connection)
@@ -19,46 +30,262 @@ const (
 	StreamConnectionTypeTCP       StreamConnectionType = 2
 	StreamConnectionTypeKCP       StreamConnectionType = 4
 	StreamConnectionTypeWebSocket StreamConnectionType = 8
+	// StreamConnectionTypeCustom is a transport registered by RegisterCustomDialer and RegisterCustomListener.
+	StreamConnectionTypeCustom StreamConnectionType = 16
+	// StreamConnectionTypePlugin is a transport of an external process, for outbounds only.
+	StreamConnectionTypePlugin StreamConnectionType = 32
 )
 
 type StreamSecurityType int
 
 const (
-	StreamSecurityTypeNone StreamSecurityType = 0
-	StreamSecurityTypeTLS  StreamSecurityType = 1
+	StreamSecurityTypeNone    StreamSecurityType = 0
+	StreamSecurityTypeTLS     StreamSecurityType = 1
+	StreamSecurityTypeReality StreamSecurityType = 2
+	// StreamSecurityTypeDTLS secures the datagrams of mKCP with DTLS, using the certificates in TLSSettings.
+	StreamSecurityTypeDTLS StreamSecurityType = 3
+)
+
+const (
+	DefaultSessionCacheSize = 128
 )
 
 var (
-	globalSessionCache = tls.NewLRUClientSessionCache(128)
+	sessionCacheAccess sync.Mutex
+	sessionCaches      = map[int]tls.ClientSessionCache{
+		DefaultSessionCacheSize: tls.NewLRUClientSessionCache(DefaultSessionCacheSize),
+	}
 )
 
+// getSessionCache returns the client session cache of the given capacity, shared by all connections using the same capacity.
+func getSessionCache(size int) tls.ClientSessionCache {
+	sessionCacheAccess.Lock()
+	defer sessionCacheAccess.Unlock()
+
+	cache, found := sessionCaches[size]
+	if !found {
+		cache = tls.NewLRUClientSessionCache(size)
+		sessionCaches[size] = cache
+	}
+	return cache
+}
+
 type TLSSettings struct {
 	AllowInsecure bool
 	Certs         []tls.Certificate
+	// DisableSessionResumption turns off session tickets on server side and session caching on client side.
+	DisableSessionResumption bool
+	// SessionCacheSize is the capacity of client session cache. Zero means DefaultSessionCacheSize.
+	SessionCacheSize int
+	// MinVersion and MaxVersion limit the TLS versions in use. Zero means the default of crypto/tls.
+	MinVersion uint16
+	MaxVersion uint16
+	// ECHConfigList enables Encrypted Client Hello on client side, so that the real server name is encrypted.
+	ECHConfigList []byte
+	// ClientCAs makes the server require client certificates signed by one of the CAs. Nil disables client
+	// certificates.
+	ClientCAs *x509.CertPool
+	// ClientEmails maps common names of client certificates to emails, to identify clients as users.
+	ClientEmails map[string]string
+	// CertFiles are the files Certs are loaded from, in the same order. When set, servers reload
+	// certificates from the files once they change.
+	CertFiles []*CertificateFile
+	// ACME makes servers obtain certificates of its domains automatically. Nil disables ACME.
+	ACME *ACMESettings
 }
 
 func (this *TLSSettings) GetTLSConfig() *tls.Config {
 	config := &tls.Config{
 		InsecureSkipVerify: this.AllowInsecure,
-		ClientSessionCache: globalSessionCache,
+		MinVersion:         this.MinVersion,
+		MaxVersion:         this.MaxVersion,
+	}
+
+	if this.DisableSessionResumption {
+		config.SessionTicketsDisabled = true
+	} else {
+		size := this.SessionCacheSize
+		if size <= 0 {
+			size = DefaultSessionCacheSize
+		}
+		config.ClientSessionCache = getSessionCache(size)
+	}
+
+	if len(this.ECHConfigList) > 0 {
+		applyECH(config, this.ECHConfigList)
+	}
+
+	if this.ClientCAs != nil {
+		config.ClientCAs = this.ClientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
 	}
 
 	config.Certificates = this.Certs
 	config.BuildNameToCertificate()
+	if len(this.CertFiles) > 0 && len(this.CertFiles) == len(this.Certs) {
+		config.GetCertificate = getCertificateStore(this.CertFiles, this.Certs).GetCertificate
+	}
+	if this.ACME != nil {
+		applyACME(config, this.ACME)
+	}
 
 	return config
 }
 
+var (
+	ErrECHNotSupported = errors.New("Internet|TLS: ECH is not supported by this build.")
+)
+
+// LoadCertificates loads certificates from their files into Certs. Servers reload them once the files change.
+func (this *TLSSettings) LoadCertificates(files []*CertificateFile) error {
+	this.Certs = make([]tls.Certificate, len(files))
+	this.CertFiles = files
+	for idx, file := range files {
+		cert, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+		if err != nil {
+			return errors.New("Internet|TLS: Failed to load certificate file: " + err.Error())
+		}
+		this.Certs[idx] = cert
+	}
+	return nil
+}
+
+// LoadClientCAs loads the CAs of client certificates from PEM files into ClientCAs.
+func (this *TLSSettings) LoadClientCAs(files []string) error {
+	this.ClientCAs = x509.NewCertPool()
+	for _, file := range files {
+		pem, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.New("Internet|TLS: Failed to read client CA file: " + err.Error())
+		}
+		if !this.ClientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("Internet|TLS: No certificate in client CA file: " + file)
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings that don't work together, after they are all set.
+func (this *TLSSettings) Validate() error {
+	if this.SessionCacheSize < 0 {
+		return errors.New("Internet|TLS: Invalid session cache size.")
+	}
+	if this.MinVersion != 0 && this.MaxVersion != 0 && this.MinVersion > this.MaxVersion {
+		return errors.New("Internet|TLS: minVersion is greater than maxVersion.")
+	}
+	if len(this.ECHConfigList) > 0 && !echSupported {
+		return ErrECHNotSupported
+	}
+	// crypto/tls refuses ECH with versions below TLS 1.3.
+	if len(this.ECHConfigList) > 0 && ((this.MinVersion != 0 && this.MinVersion < tls.VersionTLS13) || (this.MaxVersion != 0 && this.MaxVersion < tls.VersionTLS13)) {
+		return errors.New("Internet|TLS: ECH requires TLS 1.3, but minVersion or maxVersion is lower.")
+	}
+	if this.ClientCAs == nil && len(this.ClientEmails) > 0 {
+		return errors.New("Internet|TLS: clientEmails requires clientCAFiles.")
+	}
+	if this.ACME != nil {
+		if len(this.ACME.Domains) == 0 {
+			return errors.New("Internet|TLS: ACME requires at least one domain.")
+		}
+		if len(this.ACME.CacheDir) == 0 {
+			return errors.New("Internet|TLS: ACME requires a cache directory.")
+		}
+	}
+	return nil
+}
+
+// ParseTLSVersion parses a TLS version in configs, e.g. "1.2". Empty means the default of crypto/tls.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		// Not defined as a constant in all supported Go versions.
+		return 0x0304, nil
+	default:
+		return 0, errors.New("Internet|TLS: Unknown TLS version: " + version)
+	}
+}
+
 type StreamSettings struct {
-	Type        StreamConnectionType
-	Security    StreamSecurityType
-	TLSSettings *TLSSettings
+	Type            StreamConnectionType
+	Security        StreamSecurityType
+	TLSSettings     *TLSSettings
+	RealitySettings *reality.Config
+	// Custom is the name of the custom dialer and listener of StreamConnectionTypeCustom.
+	Custom string
+	// PluginSettings are the settings of StreamConnectionTypePlugin.
+	PluginSettings *plugin.Config
+	// Resolver resolves the domain of destination before dialing. Nil means the system resolver.
+	Resolver Resolver
+	// SourceFilter rejects connections from disallowed sources before any protocol processing. Listeners only.
+	SourceFilter *v2net.IPFilter
+	// Bans rejects connections from banned sources. Nil bans nothing. Listeners only.
+	Bans *ban.List
+	// Logger writes logs of transports that take it, such as mKCP, and access logs of rejected connections on
+	// listeners. Nil for the default logger.
+	Logger *log.Logger
 }
 
 func (this *StreamSettings) IsCapableOf(streamType StreamConnectionType) bool {
 	return (this.Type & streamType) == streamType
 }
 
+// Build sets Type and Security from the networks and the security of configs, once Custom, PluginSettings,
+// TLSSettings and RealitySettings are set, and checks that they can be used together.
+func (this *StreamSettings) Build(networks *v2net.NetworkList, security string) error {
+	if networks == nil {
+		networks = new(v2net.NetworkList)
+	}
+	this.Type = StreamConnectionTypeRawTCP
+	if networks.HasNetwork(v2net.Network_KCP) {
+		this.Type |= StreamConnectionTypeKCP
+	}
+	if networks.HasNetwork(v2net.Network_WebSocket) {
+		this.Type |= StreamConnectionTypeWebSocket
+	}
+	if networks.HasNetwork(v2net.Network_TCP) {
+		this.Type |= StreamConnectionTypeTCP
+	}
+	if len(this.Custom) > 0 {
+		if this.Type != StreamConnectionTypeRawTCP {
+			return errors.New("Internet: Custom transports can't be used with other networks.")
+		}
+		this.Type |= StreamConnectionTypeCustom
+	}
+	if this.PluginSettings != nil {
+		if this.Type != StreamConnectionTypeRawTCP {
+			return errors.New("Internet: Plugins can't be used with other networks or custom transports.")
+		}
+		this.Type |= StreamConnectionTypePlugin
+	}
+	this.Security = StreamSecurityTypeNone
+	switch strings.ToLower(security) {
+	case "tls":
+		this.Security = StreamSecurityTypeTLS
+	case "dtls":
+		if this.Type != StreamConnectionTypeRawTCP|StreamConnectionTypeKCP {
+			return errors.New("Internet: DTLS is only supported by mKCP.")
+		}
+		this.Security = StreamSecurityTypeDTLS
+		if this.TLSSettings == nil {
+			this.TLSSettings = new(TLSSettings)
+		}
+	case "reality":
+		if this.RealitySettings == nil {
+			return errors.New("Internet: REALITY settings are not specified.")
+		}
+		this.Security = StreamSecurityTypeReality
+	}
+	return nil
+}
+
 type Connection interface {
 	net.Conn
 	Reusable
@@ -3,12 +3,13 @@
 package internet
 
 import (
-	"crypto/tls"
-	"encoding/json"
+	"encoding/base64"
 	"errors"
-	"strings"
 
+	"v2ray.com/core/common/loader"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet/plugin"
+	"v2ray.com/core/transport/internet/reality"
 )
 
 func (this *TLSSettings) UnmarshalJSON(data []byte) error {
@@ -16,52 +17,102 @@ func (this *TLSSettings) UnmarshalJSON(data []byte) error {
 		CertFile string `json:"certificateFile"`
 		KeyFile  string `json:"keyFile"`
 	}
+	type JSONACMEConfig struct {
+		Domains      []string `json:"domains"`
+		Email        string   `json:"email"`
+		CacheDir     string   `json:"cacheDir"`
+		DirectoryURL string   `json:"directoryUrl"`
+		HTTPAddress  string   `json:"httpAddress"`
+	}
 	type JSONConfig struct {
-		Insecure bool              `json:"allowInsecure"`
-		Certs    []*JSONCertConfig `json:"certificates"`
+		Insecure         bool              `json:"allowInsecure"`
+		Certs            []*JSONCertConfig `json:"certificates"`
+		SessionTickets   *bool             `json:"sessionTickets"`
+		SessionCacheSize int               `json:"sessionCacheSize"`
+		MinVersion       string            `json:"minVersion"`
+		MaxVersion       string            `json:"maxVersion"`
+		ECHConfigList    string            `json:"echConfigList"`
+		ClientCAFiles    []string          `json:"clientCAFiles"`
+		ClientEmails     map[string]string `json:"clientEmails"`
+		ACME             *JSONACMEConfig   `json:"acme"`
 	}
 	jsonConfig := new(JSONConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
 		return err
 	}
-	this.Certs = make([]tls.Certificate, len(jsonConfig.Certs))
-	for idx, certConf := range jsonConfig.Certs {
-		cert, err := tls.LoadX509KeyPair(certConf.CertFile, certConf.KeyFile)
+	minVersion, err := ParseTLSVersion(jsonConfig.MinVersion)
+	if err != nil {
+		return err
+	}
+	maxVersion, err := ParseTLSVersion(jsonConfig.MaxVersion)
+	if err != nil {
+		return err
+	}
+	if len(jsonConfig.ECHConfigList) > 0 {
+		echConfigList, err := base64.StdEncoding.DecodeString(jsonConfig.ECHConfigList)
 		if err != nil {
-			return errors.New("Internet|TLS: Failed to load certificate file: " + err.Error())
+			return errors.New("Internet|TLS: Failed to decode ECHConfigList: " + err.Error())
 		}
-		this.Certs[idx] = cert
+		this.ECHConfigList = echConfigList
+	}
+	certFiles := make([]*CertificateFile, len(jsonConfig.Certs))
+	for idx, certConf := range jsonConfig.Certs {
+		certFiles[idx] = &CertificateFile{
+			CertFile: certConf.CertFile,
+			KeyFile:  certConf.KeyFile,
+		}
+	}
+	if err := this.LoadCertificates(certFiles); err != nil {
+		return err
 	}
+	if len(jsonConfig.ClientCAFiles) > 0 {
+		if err := this.LoadClientCAs(jsonConfig.ClientCAFiles); err != nil {
+			return err
+		}
+	}
+	if jsonConfig.ACME != nil {
+		this.ACME = &ACMESettings{
+			Domains:      jsonConfig.ACME.Domains,
+			Email:        jsonConfig.ACME.Email,
+			CacheDir:     jsonConfig.ACME.CacheDir,
+			DirectoryURL: jsonConfig.ACME.DirectoryURL,
+			HTTPAddress:  jsonConfig.ACME.HTTPAddress,
+		}
+	}
+	this.ClientEmails = jsonConfig.ClientEmails
 	this.AllowInsecure = jsonConfig.Insecure
-	return nil
+	this.DisableSessionResumption = jsonConfig.SessionTickets != nil && !*jsonConfig.SessionTickets
+	this.SessionCacheSize = jsonConfig.SessionCacheSize
+	this.MinVersion = minVersion
+	this.MaxVersion = maxVersion
+	return this.Validate()
 }
 
 func (this *StreamSettings) UnmarshalJSON(data []byte) error {
 	type JSONConfig struct {
-		Network     v2net.NetworkList `json:"network"`
-		Security    string            `json:"security"`
-		TLSSettings *TLSSettings      `json:"tlsSettings"`
+		Network         v2net.NetworkList `json:"network"`
+		Security        string            `json:"security"`
+		TLSSettings     *TLSSettings      `json:"tlsSettings"`
+		RealitySettings *reality.Config   `json:"realitySettings"`
+		Custom          string            `json:"custom"`
+		PluginSettings  *plugin.Config    `json:"plugin"`
+		Resolver        string            `json:"resolver"`
 	}
-	this.Type = StreamConnectionTypeRawTCP
 	jsonConfig := new(JSONConfig)
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
 		return err
 	}
-	if jsonConfig.Network.HasNetwork(v2net.Network_KCP) {
-		this.Type |= StreamConnectionTypeKCP
-	}
-	if jsonConfig.Network.HasNetwork(v2net.Network_WebSocket) {
-		this.Type |= StreamConnectionTypeWebSocket
-	}
-	if jsonConfig.Network.HasNetwork(v2net.Network_TCP) {
-		this.Type |= StreamConnectionTypeTCP
-	}
-	this.Security = StreamSecurityTypeNone
-	if strings.ToLower(jsonConfig.Security) == "tls" {
-		this.Security = StreamSecurityTypeTLS
+	this.Custom = jsonConfig.Custom
+	this.PluginSettings = jsonConfig.PluginSettings
+	this.TLSSettings = jsonConfig.TLSSettings
+	this.RealitySettings = jsonConfig.RealitySettings
+	if err := this.Build(&jsonConfig.Network, jsonConfig.Security); err != nil {
+		return err
 	}
-	if jsonConfig.TLSSettings != nil {
-		this.TLSSettings = jsonConfig.TLSSettings
+	resolver, err := ParseResolver(jsonConfig.Resolver)
+	if err != nil {
+		return err
 	}
+	this.Resolver = resolver
 	return nil
 }
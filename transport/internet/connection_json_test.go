@@ -0,0 +1,173 @@
+// +build json
+
+package internet_test
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"testing"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+)
+
+func TestTLSSettingsParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "allowInsecure": true,
+    "sessionTickets": false,
+    "sessionCacheSize": 64,
+    "minVersion": "1.1",
+    "maxVersion": "1.2"
+  }`
+	settings := new(TLSSettings)
+	err := json.Unmarshal([]byte(rawJson), settings)
+	assert.Error(err).IsNil()
+	assert.Bool(settings.AllowInsecure).IsTrue()
+	assert.Bool(settings.DisableSessionResumption).IsTrue()
+	assert.Int(settings.SessionCacheSize).Equals(64)
+	assert.Uint16(settings.MinVersion).Equals(tls.VersionTLS11)
+	assert.Uint16(settings.MaxVersion).Equals(tls.VersionTLS12)
+
+	config := settings.GetTLSConfig()
+	assert.Bool(config.SessionTicketsDisabled).IsTrue()
+	assert.Bool(config.ClientSessionCache == nil).IsTrue()
+	assert.Uint16(config.MinVersion).Equals(tls.VersionTLS11)
+}
+
+func TestTLSSettingsDefaults(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(TLSSettings)
+	err := json.Unmarshal([]byte(`{}`), settings)
+	assert.Error(err).IsNil()
+	assert.Bool(settings.DisableSessionResumption).IsFalse()
+
+	config := settings.GetTLSConfig()
+	assert.Bool(config.SessionTicketsDisabled).IsFalse()
+	assert.Bool(config.ClientSessionCache != nil).IsTrue()
+}
+
+func TestTLSSettingsInvalidVersion(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(TLSSettings)
+	assert.Error(json.Unmarshal([]byte(`{"minVersion": "2.0"}`), settings)).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"minVersion": "1.2", "maxVersion": "1.0"}`), settings)).IsNotNil()
+}
+
+func TestTLSSettingsACME(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(TLSSettings)
+	err := json.Unmarshal([]byte(`{
+    "acme": {
+      "domains": ["v2ray.com"],
+      "email": "admin@v2ray.com",
+      "cacheDir": "/tmp/v2ray-acme-test"
+    }
+  }`), settings)
+	assert.Error(err).IsNil()
+	assert.Int(len(settings.ACME.Domains)).Equals(1)
+	assert.String(settings.ACME.Email).Equals("admin@v2ray.com")
+	assert.String(settings.ACME.CacheDir).Equals("/tmp/v2ray-acme-test")
+
+	config := settings.GetTLSConfig()
+	assert.Int(len(config.NextProtos)).Equals(0)
+	challengeConfig, err := config.GetConfigForClient(&tls.ClientHelloInfo{
+		ServerName:      "v2ray.com",
+		SupportedProtos: []string{"acme-tls/1"},
+	})
+	assert.Error(err).IsNil()
+	assert.String(challengeConfig.NextProtos[0]).Equals("acme-tls/1")
+	challengeConfig, err = config.GetConfigForClient(&tls.ClientHelloInfo{
+		ServerName:      "v2ray.com",
+		SupportedProtos: []string{"h2", "http/1.1"},
+	})
+	assert.Error(err).IsNil()
+	assert.Bool(challengeConfig == nil).IsTrue()
+
+	assert.Error(json.Unmarshal([]byte(`{"acme": {"cacheDir": "/tmp"}}`), new(TLSSettings))).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"acme": {"domains": ["v2ray.com"]}}`), new(TLSSettings))).IsNotNil()
+}
+
+func TestTLSSettingsECH(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(TLSSettings)
+	err := json.Unmarshal([]byte(`{"echConfigList": "AEX+DQBBBAAgACDhEe8lgDM2F/WQSHH0Xh1iEIMzwjK2OD0WbXmhDjH7RAAEAAEAAQASY2xvdWRmbGFyZS1lY2guY29tAAA="}`), settings)
+	if err == ErrECHNotSupported {
+		return
+	}
+	assert.Error(err).IsNil()
+	assert.Int(len(settings.ECHConfigList)).Equals(71)
+
+	// The versions are left to crypto/tls.
+	config := settings.GetTLSConfig()
+	assert.Uint16(config.MinVersion).Equals(0)
+	assert.Uint16(config.MaxVersion).Equals(0)
+
+	assert.Error(json.Unmarshal([]byte(`{"echConfigList": "!!!"}`), settings)).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"echConfigList": "AEX+DQBBBAAgACDhEe8lgDM2F/WQSHH0Xh1iEIMzwjK2OD0WbXmhDjH7RAAEAAEAAQASY2xvdWRmbGFyZS1lY2guY29tAAA=", "maxVersion": "1.2"}`), new(TLSSettings))).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"echConfigList": "AEX+DQBBBAAgACDhEe8lgDM2F/WQSHH0Xh1iEIMzwjK2OD0WbXmhDjH7RAAEAAEAAQASY2xvdWRmbGFyZS1lY2guY29tAAA=", "minVersion": "1.2"}`), new(TLSSettings))).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"echConfigList": "AEX+DQBBBAAgACDhEe8lgDM2F/WQSHH0Xh1iEIMzwjK2OD0WbXmhDjH7RAAEAAEAAQASY2xvdWRmbGFyZS1lY2guY29tAAA=", "minVersion": "1.3"}`), new(TLSSettings))).IsNil()
+}
+
+func TestStreamSettingsDTLS(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(StreamSettings)
+	err := json.Unmarshal([]byte(`{"network": "kcp", "security": "dtls", "tlsSettings": {"allowInsecure": true}}`), settings)
+	assert.Error(err).IsNil()
+	assert.Bool(settings.Security == StreamSecurityTypeDTLS).IsTrue()
+	assert.Bool(settings.TLSSettings.AllowInsecure).IsTrue()
+
+	assert.Error(json.Unmarshal([]byte(`{"network": "tcp", "security": "dtls"}`), settings)).IsNotNil()
+}
+
+func TestStreamSettingsResolver(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(StreamSettings)
+	assert.Error(json.Unmarshal([]byte(`{"network": "tcp"}`), settings)).IsNil()
+	assert.Bool(settings.Resolver == nil).IsTrue()
+
+	assert.Error(json.Unmarshal([]byte(`{"network": "tcp", "resolver": "internal"}`), settings)).IsNil()
+	_, ok := settings.Resolver.(*InternalResolver)
+	assert.Bool(ok).IsTrue()
+
+	assert.Error(json.Unmarshal([]byte(`{"network": "tcp", "resolver": "1.1.1.1"}`), settings)).IsNil()
+	assert.String(settings.Resolver.(*NameServerResolver).Server().String()).Equals("udp:1.1.1.1:53")
+
+	assert.Error(json.Unmarshal([]byte(`{"network": "tcp", "resolver": "[2606:4700:4700::1111]:5353"}`), settings)).IsNil()
+	assert.String(settings.Resolver.(*NameServerResolver).Server().String()).Equals("udp:[2606:4700:4700::1111]:5353")
+
+	assert.Error(json.Unmarshal([]byte(`{"network": "tcp", "resolver": "dns.google"}`), settings)).IsNotNil()
+}
+
+func TestStreamSettingsCustom(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(StreamSettings)
+	assert.Error(json.Unmarshal([]byte(`{"custom": "protected", "security": "tls"}`), settings)).IsNil()
+	assert.Bool(settings.IsCapableOf(StreamConnectionTypeCustom)).IsTrue()
+	assert.String(settings.Custom).Equals("protected")
+
+	assert.Error(json.Unmarshal([]byte(`{"network": "kcp", "custom": "protected"}`), settings)).IsNotNil()
+}
+
+func TestStreamSettingsPlugin(t *testing.T) {
+	assert := assert.On(t)
+
+	settings := new(StreamSettings)
+	assert.Error(json.Unmarshal([]byte(`{"plugin": {"address": "127.0.0.1:7000", "command": "obfs", "args": ["-v"], "options": "mode=http"}}`), settings)).IsNil()
+	assert.Bool(settings.IsCapableOf(StreamConnectionTypePlugin)).IsTrue()
+	assert.String(settings.PluginSettings.Address).Equals("127.0.0.1:7000")
+	assert.String(settings.PluginSettings.Command).Equals("obfs")
+	assert.String(settings.PluginSettings.Options).Equals("mode=http")
+
+	assert.Error(json.Unmarshal([]byte(`{"plugin": {"address": "unix:/tmp/obfs.sock"}}`), settings)).IsNil()
+	assert.Error(json.Unmarshal([]byte(`{"plugin": {"command": "obfs"}}`), settings)).IsNotNil()
+	assert.Error(json.Unmarshal([]byte(`{"network": "ws", "plugin": {"address": "127.0.0.1:7000"}}`), settings)).IsNotNil()
+}
@@ -0,0 +1,116 @@
+package internet
+
+import (
+	"net"
+	"sync"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet/plugin"
+)
+
+// CustomDialer dials the connections of a transport implemented outside V2Ray, e.g. by an app embedding V2Ray that
+// dials through sockets protected from its own VPN, such as by VpnService.protect() on Android. Security such as TLS
+// is applied on the returned connections as configured in stream settings.
+type CustomDialer interface {
+	Dial(src v2net.Address, dest v2net.Destination) (net.Conn, error)
+}
+
+// CustomListener listens for the connections of a transport implemented outside V2Ray.
+type CustomListener interface {
+	Listen(address v2net.Address, port v2net.Port) (net.Listener, error)
+}
+
+var (
+	customAccess    sync.RWMutex
+	customDialers   = make(map[string]CustomDialer)
+	customListeners = make(map[string]CustomListener)
+)
+
+// RegisterCustomDialer registers a dialer by name, which stream settings select by "custom". It must be called before
+// any connection is dialed with the name.
+func RegisterCustomDialer(name string, dialer CustomDialer) error {
+	customAccess.Lock()
+	defer customAccess.Unlock()
+
+	if _, found := customDialers[name]; found {
+		return common.ErrDuplicatedName
+	}
+	customDialers[name] = dialer
+	return nil
+}
+
+// RegisterCustomListener registers a listener by name, which stream settings select by "custom". It must be called
+// before any inbound listens with the name.
+func RegisterCustomListener(name string, listener CustomListener) error {
+	customAccess.Lock()
+	defer customAccess.Unlock()
+
+	if _, found := customListeners[name]; found {
+		return common.ErrDuplicatedName
+	}
+	customListeners[name] = listener
+	return nil
+}
+
+func dialCustom(name string, src v2net.Address, dest v2net.Destination) (Connection, error) {
+	customAccess.RLock()
+	dialer, found := customDialers[name]
+	customAccess.RUnlock()
+	if !found {
+		log.Error("Internet: Custom dialer not found: ", name)
+		return nil, ErrUnsupportedStreamType
+	}
+	conn, err := dialer.Dial(src, dest)
+	if err != nil {
+		return nil, err
+	}
+	return &customConnection{Conn: conn}, nil
+}
+
+func dialPlugin(config *plugin.Config, dest v2net.Destination) (Connection, error) {
+	conn, err := plugin.Dial(config, dest)
+	if err != nil {
+		return nil, err
+	}
+	return &customConnection{Conn: conn}, nil
+}
+
+func listenCustom(name string, address v2net.Address, port v2net.Port) (Listener, error) {
+	customAccess.RLock()
+	listener, found := customListeners[name]
+	customAccess.RUnlock()
+	if !found {
+		log.Error("Internet|Listener: Custom listener not found: ", name)
+		return nil, ErrUnsupportedStreamType
+	}
+	l, err := listener.Listen(address, port)
+	if err != nil {
+		return nil, err
+	}
+	return &customListener{Listener: l}, nil
+}
+
+// customConnection is a connection of a custom transport or a plugin, which is never reused.
+type customConnection struct {
+	net.Conn
+}
+
+func (this *customConnection) Reusable() bool {
+	return false
+}
+
+func (this *customConnection) SetReusable(bool) {}
+
+type customListener struct {
+	net.Listener
+}
+
+func (this *customListener) Accept() (Connection, error) {
+	conn, err := this.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &customConnection{Conn: conn}, nil
+}
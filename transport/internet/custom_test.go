@@ -0,0 +1,68 @@
+package internet_test
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"v2ray.com/core/common"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+)
+
+type testCustomTransport struct {
+	dials    int
+	listener net.Listener
+}
+
+func (this *testCustomTransport) Dial(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	this.dials++
+	return net.Dial("tcp", dest.NetAddr())
+}
+
+func (this *testCustomTransport) Listen(address v2net.Address, port v2net.Port) (net.Listener, error) {
+	// The port is picked by the system, as the test doesn't know a free one.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	this.listener = listener
+	return listener, err
+}
+
+func TestCustomTransport(t *testing.T) {
+	assert := assert.On(t)
+
+	transport := new(testCustomTransport)
+	assert.Error(RegisterCustomDialer("test", transport)).IsNil()
+	assert.Error(RegisterCustomListener("test", transport)).IsNil()
+	assert.Error(RegisterCustomDialer("test", transport)).Equals(common.ErrDuplicatedName)
+
+	settings := &StreamSettings{
+		Type:   StreamConnectionTypeRawTCP | StreamConnectionTypeCustom,
+		Custom: "test",
+	}
+	hub, err := ListenTCP(v2net.LocalHostIP, v2net.Port(0), func(conn Connection) {
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}, settings)
+	assert.Error(err).IsNil()
+	defer hub.Close()
+
+	port := v2net.Port(transport.listener.Addr().(*net.TCPAddr).Port)
+	assert.Int(hub.Addr().(*net.TCPAddr).Port).Equals(int(port))
+	conn, err := Dial(nil, v2net.TCPDestination(v2net.LocalHostIP, port), settings)
+	assert.Error(err).IsNil()
+	assert.Int(transport.dials).Equals(1)
+	assert.Bool(conn.Reusable()).IsFalse()
+
+	_, err = conn.Write([]byte("custom"))
+	assert.Error(err).IsNil()
+	response := make([]byte, 6)
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	assert.String(string(response)).Equals("custom")
+	conn.Close()
+
+	settings.Custom = "unknown"
+	_, err = Dial(nil, v2net.TCPDestination(v2net.LocalHostIP, port), settings)
+	assert.Error(err).Equals(ErrUnsupportedStreamType)
+}
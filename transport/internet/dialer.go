@@ -1,44 +1,127 @@
 package internet
 
 import (
+	"context"
 	"crypto/tls"
-	"errors"
 	"net"
+	"sync"
+	"syscall"
+	"time"
 
+	"v2ray.com/core/common/errors"
+	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet/reality"
 	v2tls "v2ray.com/core/transport/internet/tls"
 )
 
 var (
-	ErrUnsupportedStreamType = errors.New("Unsupported stream type.")
+	ErrUnsupportedStreamType    = errors.New("Unsupported stream type.").WithCode(errors.CodeBadConfiguration)
+	ErrMultipathTCPNotSupported = errors.New("MPTCP is not supported by the system dialer.")
+	ErrListenPacketNotSupported = errors.New("Unconnected UDP sockets are not supported by the system dialer.")
 )
 
 type Dialer func(src v2net.Address, dest v2net.Destination) (Connection, error)
 
+// PacketDialer dials a transport over datagrams, such as mKCP. The datagrams are secured by DTLS if config is not nil.
+// Logs go to logger, which is nil for the default logger.
+type PacketDialer func(src v2net.Address, dest v2net.Destination, config *tls.Config, logger *log.Logger) (Connection, error)
+
+// ResolvedDialer dials the given IP in place of the domain of destination, for transports that still need the domain,
+// such as WebSocket for Host header and SNI.
+type ResolvedDialer func(src v2net.Address, dest v2net.Destination, ip v2net.Address) (Connection, error)
+
 var (
 	TCPDialer    Dialer
-	KCPDialer    Dialer
 	RawTCPDialer Dialer
 	UDPDialer    Dialer
 	WSDialer     Dialer
+
+	KCPDialer        PacketDialer
+	WSResolvedDialer ResolvedDialer
 )
 
+// Dial opens a connection to dest with the given transport settings. Errors of the system dialer are wrapped with
+// errors.CodeTimeout for timeouts, or errors.CodeNetworkUnreachable if there is no route to the destination. Other
+// failures, such as refused connections, have no code.
 func Dial(src v2net.Address, dest v2net.Destination, settings *StreamSettings) (Connection, error) {
+	conn, err := dial(src, dest, settings)
+	if err == nil {
+		return conn, nil
+	}
+	if _, ok := err.(*errors.Error); ok {
+		return nil, err
+	}
+	code := errors.CodeUnknown
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		code = errors.CodeTimeout
+	} else if errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH) {
+		code = errors.CodeNetworkUnreachable
+	}
+	return nil, errors.New("Failed to dial ", dest).Path("Internet").WithCode(code).Base(err)
+}
+
+func dial(src v2net.Address, dest v2net.Destination, settings *StreamSettings) (Connection, error) {
+	resolver := settings.Resolver
+	if resolver == nil && dialsIPOnly(settings) {
+		resolver = systemResolver{}
+	}
+	dialDests, err := resolveDestinations(resolver, dest)
+	if err != nil {
+		return nil, err
+	}
+	for _, dialDest := range dialDests {
+		var connection Connection
+		connection, err = dialResolved(src, dest, dialDest, settings)
+		if err == nil {
+			return connection, nil
+		}
+	}
+	return nil, err
+}
+
+// dialsIPOnly returns true if the transport picked by dialResolved doesn't resolve domains by itself, i.e. mKCP, which
+// may run over fake TCP.
+func dialsIPOnly(settings *StreamSettings) bool {
+	if settings.IsCapableOf(StreamConnectionTypePlugin) || settings.IsCapableOf(StreamConnectionTypeCustom) ||
+		settings.IsCapableOf(StreamConnectionTypeTCP) {
+		return false
+	}
+	return settings.IsCapableOf(StreamConnectionTypeKCP)
+}
 
+// dialResolved dials dest through dialDest, which is dest with its domain resolved. The transports dial the resolved
+// destination, while TLS and WebSocket still use the domain.
+func dialResolved(src v2net.Address, dest v2net.Destination, dialDest v2net.Destination, settings *StreamSettings) (Connection, error) {
 	var connection Connection
 	var err error
 	if dest.Network == v2net.Network_TCP {
 		switch {
+		case settings.IsCapableOf(StreamConnectionTypePlugin):
+			// The plugin connects to the server, so it is given the domain.
+			connection, err = dialPlugin(settings.PluginSettings, dest)
+		case settings.IsCapableOf(StreamConnectionTypeCustom):
+			connection, err = dialCustom(settings.Custom, src, dialDest)
 		case settings.IsCapableOf(StreamConnectionTypeTCP):
-			connection, err = TCPDialer(src, dest)
+			connection, err = TCPDialer(src, dialDest)
 		case settings.IsCapableOf(StreamConnectionTypeKCP):
-			connection, err = KCPDialer(src, dest)
+			if settings.Security == StreamSecurityTypeDTLS {
+				config := settings.TLSSettings.GetTLSConfig()
+				// The certificate is verified against the IP address, if the destination is not a domain.
+				config.ServerName = dest.Address.String()
+				return KCPDialer(src, dialDest, config, settings.Logger)
+			}
+			connection, err = KCPDialer(src, dialDest, nil, settings.Logger)
 		case settings.IsCapableOf(StreamConnectionTypeWebSocket):
-			connection, err = WSDialer(src, dest)
+			if dialDest.Address != dest.Address {
+				connection, err = WSResolvedDialer(src, dest, dialDest.Address)
+			} else {
+				connection, err = WSDialer(src, dest)
+			}
 
 			// This check has to be the last one.
 		case settings.IsCapableOf(StreamConnectionTypeRawTCP):
-			connection, err = RawTCPDialer(src, dest)
+			connection, err = RawTCPDialer(src, dialDest)
 		default:
 			return nil, ErrUnsupportedStreamType
 		}
@@ -50,6 +133,20 @@ func Dial(src v2net.Address, dest v2net.Destination, settings *StreamSettings) (
 			return connection, nil
 		}
 
+		if settings.Security == StreamSecurityTypeDTLS {
+			connection.Close()
+			return nil, ErrUnsupportedStreamType
+		}
+
+		if settings.Security == StreamSecurityTypeReality {
+			tlsConn, err := reality.Client(connection, settings.RealitySettings)
+			if err != nil {
+				connection.Close()
+				return nil, err
+			}
+			return v2tls.NewConnection(tlsConn), nil
+		}
+
 		config := settings.TLSSettings.GetTLSConfig()
 		if dest.Address.Family().IsDomain() {
 			config.ServerName = dest.Address.Domain()
@@ -58,9 +155,89 @@ func Dial(src v2net.Address, dest v2net.Destination, settings *StreamSettings) (
 		return v2tls.NewConnection(tlsConn), nil
 	}
 
-	return UDPDialer(src, dest)
+	return UDPDialer(src, dialDest)
+}
+
+// DialContext dials as Dial, but gives up once ctx is done. A connection established after that is closed.
+func DialContext(ctx context.Context, src v2net.Address, dest v2net.Destination, settings *StreamSettings) (Connection, error) {
+	if ctx.Done() == nil {
+		return Dial(src, dest, settings)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, canceledError(dest, err)
+	}
+
+	type dialResult struct {
+		conn Connection
+		err  error
+	}
+	done := make(chan dialResult, 1)
+	go func() {
+		conn, err := Dial(src, dest, settings)
+		done <- dialResult{conn: conn, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.conn, result.err
+	case <-ctx.Done():
+		go func() {
+			if result := <-done; result.conn != nil {
+				result.conn.SetReusable(false)
+				result.conn.Close()
+			}
+		}()
+		return nil, canceledError(dest, ctx.Err())
+	}
+}
+
+func canceledError(dest v2net.Destination, err error) error {
+	return errors.New("Dialing ", dest, " is canceled.").Path("Internet").WithCode(errors.CodeCanceled).WithSeverity(errors.SeverityInfo).Base(err)
+}
+
+// AbortOnDone makes pending and future reads and writes on conn fail once ctx is done, and keeps conn from being
+// reused. The returned function stops watching ctx, and is to be called before conn is closed.
+func AbortOnDone(ctx context.Context, conn Connection) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReusable(false)
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+		})
+	}
 }
 
 func DialToDest(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
 	return effectiveSystemDialer.Dial(src, dest)
 }
+
+// DialMultipathTCPToDest dials an MPTCP connection with the system dialer. It returns ErrMultipathTCPNotSupported if
+// the system dialer is not able to.
+func DialMultipathTCPToDest(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	dialer, ok := effectiveSystemDialer.(MultipathTCPDialer)
+	if !ok {
+		return nil, ErrMultipathTCPNotSupported
+	}
+	return dialer.DialMultipathTCP(src, dest)
+}
+
+// ListenPacketFromSource opens an unconnected UDP socket on src with the system dialer. It returns
+// ErrListenPacketNotSupported if the system dialer is not able to.
+func ListenPacketFromSource(src v2net.Address) (net.PacketConn, error) {
+	listener, ok := effectiveSystemDialer.(PacketListener)
+	if !ok {
+		return nil, ErrListenPacketNotSupported
+	}
+	return listener.ListenPacket(src)
+}
@@ -1,12 +1,15 @@
 package internet_test
 
 import (
+	"net"
 	"testing"
 
+	"v2ray.com/core/common/errors"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/testing/assert"
 	"v2ray.com/core/testing/servers/tcp"
 	. "v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
 )
 
 func TestDialDomain(t *testing.T) {
@@ -36,3 +39,60 @@ func TestDialWithLocalAddr(t *testing.T) {
 	assert.String(conn.RemoteAddr().String()).Equals("127.0.0.1:" + dest.Port.String())
 	conn.Close()
 }
+
+func TestDialWithInternalResolver(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	resolver := new(InternalResolver)
+	settings := &StreamSettings{
+		Type:     StreamConnectionTypeRawTCP,
+		Resolver: resolver,
+	}
+	target := v2net.TCPDestination(v2net.DomainAddress("proxy.v2ray.test"), dest.Port)
+
+	_, err = Dial(nil, target, settings)
+	assert.Bool(errors.Is(err, ErrResolverNotReady)).IsTrue()
+
+	resolver.Bind(func(domain string) []net.IP {
+		if domain == "proxy.v2ray.test" {
+			return []net.IP{net.IPv4(127, 0, 0, 1)}
+		}
+		return nil
+	})
+	conn, err := Dial(nil, target, settings)
+	assert.Error(err).IsNil()
+	assert.String(conn.RemoteAddr().String()).Equals("127.0.0.1:" + dest.Port.String())
+	conn.Close()
+
+	_, err = Dial(nil, v2net.TCPDestination(v2net.DomainAddress("unknown.v2ray.test"), dest.Port), settings)
+	assert.Bool(errors.Is(err, ErrNoIPFound)).IsTrue()
+}
+
+func TestDialPrefersIPv4(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	resolver := new(InternalResolver)
+	resolver.Bind(func(domain string) []net.IP {
+		return []net.IP{net.IPv6loopback, net.IPv4(127, 0, 0, 1)}
+	})
+	settings := &StreamSettings{
+		Type:     StreamConnectionTypeRawTCP,
+		Resolver: resolver,
+	}
+	for i := 0; i < 8; i++ {
+		conn, err := Dial(nil, v2net.TCPDestination(v2net.DomainAddress("proxy.v2ray.test"), dest.Port), settings)
+		assert.Error(err).IsNil()
+		assert.String(conn.RemoteAddr().String()).Equals("127.0.0.1:" + dest.Port.String())
+		conn.Close()
+	}
+}
@@ -0,0 +1,477 @@
+package dtls
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// outgoing is a record of a handshake flight. It is sealed again on every retransmission.
+type outgoing struct {
+	contentType byte
+	epoch       uint16
+	payload     []byte
+}
+
+// DatagramConn is the connection under a DTLS connection. Every Read and Write transfers one whole datagram.
+type DatagramConn interface {
+	io.ReadWriteCloser
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+}
+
+// Conn is a DTLS connection on top of a datagram connection, such as a connected UDP socket.
+type Conn struct {
+	conn     DatagramConn
+	config   *tls.Config
+	isClient bool
+
+	incoming  chan []byte
+	readErr   error
+	closed    chan bool
+	closeOnce sync.Once
+
+	handshakeMutex sync.Mutex
+	handshakeErr   error
+	established    int32
+
+	// The following fields are only accessed by the handshake, and by Read afterwards.
+	readMutex         sync.Mutex
+	messages          *reassembler
+	sendMessageSeq    uint16
+	recvMessageSeq    uint16
+	transcript        []byte
+	flight            []*outgoing
+	retransmitTimeout time.Duration
+	handshakeDeadline time.Time
+	readEpoch         uint16
+	readCipher        *cipherState
+	pendingReadCipher *cipherState
+	deferred          []*record
+	replay            replayWindow
+	pending           [][]byte
+
+	writeMutex  sync.Mutex
+	writeSeqs   [2]uint64
+	writeCipher *cipherState
+
+	deadlineMutex sync.Mutex
+	readDeadline  time.Time
+}
+
+func newConn(conn DatagramConn, config *tls.Config, isClient bool) *Conn {
+	c := &Conn{
+		conn:     conn,
+		config:   config,
+		isClient: isClient,
+		incoming: make(chan []byte, 64),
+		closed:   make(chan bool),
+		messages: newReassembler(),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Client returns a client side DTLS connection on top of the given datagram connection.
+func Client(conn DatagramConn, config *tls.Config) *Conn {
+	return newConn(conn, config, true)
+}
+
+// Server returns a server side DTLS connection on top of the given datagram connection.
+func Server(conn DatagramConn, config *tls.Config) *Conn {
+	return newConn(conn, config, false)
+}
+
+func (this *Conn) readLoop() {
+	buffer := make([]byte, 65536)
+	for {
+		nBytes, err := this.conn.Read(buffer)
+		if err != nil {
+			this.readErr = err
+			close(this.incoming)
+			return
+		}
+		datagram := make([]byte, nBytes)
+		copy(datagram, buffer)
+		select {
+		case this.incoming <- datagram:
+		case <-this.closed:
+			return
+		}
+	}
+}
+
+// Handshake runs the DTLS handshake if it has not been run yet. Read and Write call it automatically.
+func (this *Conn) Handshake() error {
+	this.handshakeMutex.Lock()
+	defer this.handshakeMutex.Unlock()
+
+	if this.handshakeErr != nil || atomic.LoadInt32(&this.established) == 1 {
+		return this.handshakeErr
+	}
+
+	this.readMutex.Lock()
+	defer this.readMutex.Unlock()
+
+	this.retransmitTimeout = initialRetransmitTimeout
+	this.handshakeDeadline = time.Now().Add(handshakeTimeout)
+	if this.isClient {
+		this.handshakeErr = this.clientHandshake()
+	} else {
+		this.handshakeErr = this.serverHandshake()
+	}
+	if this.handshakeErr != nil {
+		return this.handshakeErr
+	}
+	atomic.StoreInt32(&this.established, 1)
+	return nil
+}
+
+func (this *Conn) addTranscript(msg *handshakeMessage) {
+	this.transcript = append(this.transcript, msg.marshal()...)
+}
+
+// addMessage appends a handshake message to the flight, fragmented as needed.
+func (this *Conn) addMessage(flight []*outgoing, msgType byte, body []byte, epoch uint16) []*outgoing {
+	msg := &handshakeMessage{
+		msgType: msgType,
+		seq:     this.sendMessageSeq,
+		body:    body,
+	}
+	this.sendMessageSeq++
+	this.addTranscript(msg)
+
+	offset := 0
+	for {
+		end := offset + maxHandshakeFragment
+		if end > len(body) {
+			end = len(body)
+		}
+		flight = append(flight, &outgoing{
+			contentType: contentTypeHandshake,
+			epoch:       epoch,
+			payload:     marshalHandshakeFragment(msgType, len(body), msg.seq, offset, body[offset:end]),
+		})
+		offset = end
+		if offset >= len(body) {
+			return flight
+		}
+	}
+}
+
+func (this *Conn) sealRecord(contentType byte, epoch uint16, payload []byte) []byte {
+	seq := this.writeSeqs[epoch]
+	this.writeSeqs[epoch]++
+	if epoch == 0 {
+		header := marshalRecordHeader(contentType, epoch, seq, len(payload))
+		return append(header, payload...)
+	}
+	header := marshalRecordHeader(contentType, epoch, seq, explicitNonceLen+len(payload)+gcmTagLength)
+	return append(header, this.writeCipher.seal(header, payload)...)
+}
+
+// writeRecords sends the records in as few datagrams as possible.
+func (this *Conn) writeRecords(records []*outgoing) error {
+	this.writeMutex.Lock()
+	defer this.writeMutex.Unlock()
+
+	var datagram []byte
+	for _, r := range records {
+		sealed := this.sealRecord(r.contentType, r.epoch, r.payload)
+		if len(datagram) > 0 && len(datagram)+len(sealed) > maxDatagramSize {
+			if _, err := this.conn.Write(datagram); err != nil {
+				return err
+			}
+			datagram = nil
+		}
+		datagram = append(datagram, sealed...)
+	}
+	if len(datagram) > 0 {
+		if _, err := this.conn.Write(datagram); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Conn) sendFlight(flight []*outgoing) error {
+	this.flight = flight
+	return this.writeRecords(flight)
+}
+
+func (this *Conn) setWriteCipher(state *cipherState) {
+	this.writeMutex.Lock()
+	this.writeCipher = state
+	this.writeMutex.Unlock()
+}
+
+func (this *Conn) sendAlert(level byte, description byte) {
+	epoch := uint16(0)
+	this.writeMutex.Lock()
+	if this.writeCipher != nil {
+		epoch = 1
+	}
+	this.writeMutex.Unlock()
+	this.writeRecords([]*outgoing{{
+		contentType: contentTypeAlert,
+		epoch:       epoch,
+		payload:     []byte{level, description},
+	}})
+}
+
+// fail sends a fatal alert to the peer and returns err.
+func (this *Conn) fail(description byte, err error) error {
+	this.sendAlert(alertLevelFatal, description)
+	return err
+}
+
+// processRecords handles all records in a datagram. It returns true if the peer retransmitted a previous flight,
+// which means our last flight was lost.
+func (this *Conn) processRecords(datagram []byte) (bool, error) {
+	retransmitted := false
+	for _, r := range parseRecords(datagram) {
+		old, err := this.processRecord(r)
+		if err != nil {
+			return retransmitted, err
+		}
+		retransmitted = retransmitted || old
+	}
+	return retransmitted, nil
+}
+
+func (this *Conn) processRecord(r *record) (bool, error) {
+	if this.readEpoch == 0 && this.pendingReadCipher == nil && (r.epoch == 1 || r.contentType == contentTypeChangeCipherSpec) {
+		// The peer may send ChangeCipherSpec and Finished in the same datagram as the message that we need to derive keys.
+		if len(this.deferred) < 8 {
+			this.deferred = append(this.deferred, r)
+		}
+		return false, nil
+	}
+	if r.epoch != this.readEpoch {
+		return r.epoch < this.readEpoch && r.contentType == contentTypeHandshake, nil
+	}
+	payload := r.payload
+	if r.epoch > 0 {
+		plaintext, err := this.readCipher.open(r.header, payload)
+		if err != nil {
+			return false, nil
+		}
+		payload = plaintext
+	}
+
+	retransmitted := false
+	switch r.contentType {
+	case contentTypeHandshake:
+		for _, seq := range this.messages.add(payload, this.recvMessageSeq) {
+			if seq < this.recvMessageSeq {
+				retransmitted = true
+			}
+		}
+	case contentTypeChangeCipherSpec:
+		if this.pendingReadCipher != nil {
+			this.readCipher = this.pendingReadCipher
+			this.pendingReadCipher = nil
+			this.readEpoch = 1
+		}
+	case contentTypeAlert:
+		if len(payload) < 2 {
+			break
+		}
+		if payload[1] == alertCloseNotify {
+			return retransmitted, io.EOF
+		}
+		if payload[0] == alertLevelFatal {
+			return retransmitted, ErrFatalAlert
+		}
+	case contentTypeApplicationData:
+		if r.epoch > 0 && this.replay.accept(r.seq) {
+			this.pending = append(this.pending, payload)
+		}
+	}
+	return retransmitted, nil
+}
+
+// setPendingReadCipher prepares the keys of the peer for its next epoch, and handles the records that were waiting for them.
+func (this *Conn) setPendingReadCipher(state *cipherState) error {
+	this.pendingReadCipher = state
+	deferred := this.deferred
+	this.deferred = nil
+	for _, r := range deferred {
+		if _, err := this.processRecord(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitDatagram returns the next datagram during handshake, and retransmits the last flight when the peer is silent.
+func (this *Conn) waitDatagram() ([]byte, error) {
+	for {
+		remaining := this.handshakeDeadline.Sub(time.Now())
+		if remaining <= 0 {
+			return nil, ErrHandshakeTimeout
+		}
+		timeout := this.retransmitTimeout
+		if timeout > remaining {
+			timeout = remaining
+		}
+		timer := time.NewTimer(timeout)
+		select {
+		case datagram, ok := <-this.incoming:
+			timer.Stop()
+			if !ok {
+				return nil, this.readErr
+			}
+			return datagram, nil
+		case <-this.closed:
+			timer.Stop()
+			return nil, ErrClosed
+		case <-timer.C:
+			if len(this.flight) > 0 {
+				if err := this.writeRecords(this.flight); err != nil {
+					return nil, err
+				}
+			}
+			this.retransmitTimeout *= 2
+			if this.retransmitTimeout > maxRetransmitTimeout {
+				this.retransmitTimeout = maxRetransmitTimeout
+			}
+		}
+	}
+}
+
+// readHandshakeMessage returns the next handshake message in order.
+func (this *Conn) readHandshakeMessage() (*handshakeMessage, error) {
+	for {
+		if msg := this.messages.pop(this.recvMessageSeq); msg != nil {
+			this.recvMessageSeq++
+			return msg, nil
+		}
+		datagram, err := this.waitDatagram()
+		if err != nil {
+			return nil, err
+		}
+		retransmitted, err := this.processRecords(datagram)
+		if err != nil {
+			return nil, err
+		}
+		if retransmitted && len(this.flight) > 0 {
+			if err := this.writeRecords(this.flight); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (this *Conn) readDatagram() ([]byte, error) {
+	this.deadlineMutex.Lock()
+	deadline := this.readDeadline
+	this.deadlineMutex.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return nil, ErrTimeout
+		}
+		timer := time.NewTimer(remaining)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case datagram, ok := <-this.incoming:
+		if !ok {
+			return nil, this.readErr
+		}
+		return datagram, nil
+	case <-this.closed:
+		return nil, ErrClosed
+	case <-timeout:
+		return nil, ErrTimeout
+	}
+}
+
+// Read returns the content of the next application data record.
+func (this *Conn) Read(b []byte) (int, error) {
+	if err := this.Handshake(); err != nil {
+		return 0, err
+	}
+
+	this.readMutex.Lock()
+	defer this.readMutex.Unlock()
+
+	for len(this.pending) == 0 {
+		datagram, err := this.readDatagram()
+		if err != nil {
+			return 0, err
+		}
+		retransmitted, err := this.processRecords(datagram)
+		if err != nil {
+			return 0, err
+		}
+		// Only the server sends the last flight, so only the server has to answer retransmissions after handshake.
+		if retransmitted && !this.isClient {
+			this.writeRecords(this.flight)
+		}
+	}
+	nBytes := copy(b, this.pending[0])
+	this.pending = this.pending[1:]
+	return nBytes, nil
+}
+
+// Write sends b in a single application data record.
+func (this *Conn) Write(b []byte) (int, error) {
+	if err := this.Handshake(); err != nil {
+		return 0, err
+	}
+	if err := this.writeRecords([]*outgoing{{
+		contentType: contentTypeApplicationData,
+		epoch:       1,
+		payload:     b,
+	}}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (this *Conn) Close() error {
+	var err error
+	this.closeOnce.Do(func() {
+		if atomic.LoadInt32(&this.established) == 1 {
+			this.sendAlert(alertLevelWarning, alertCloseNotify)
+		}
+		close(this.closed)
+		err = this.conn.Close()
+	})
+	return err
+}
+
+func (this *Conn) LocalAddr() net.Addr {
+	return this.conn.LocalAddr()
+}
+
+func (this *Conn) RemoteAddr() net.Addr {
+	return this.conn.RemoteAddr()
+}
+
+func (this *Conn) SetDeadline(t time.Time) error {
+	this.SetReadDeadline(t)
+	return this.SetWriteDeadline(t)
+}
+
+func (this *Conn) SetReadDeadline(t time.Time) error {
+	this.deadlineMutex.Lock()
+	this.readDeadline = t
+	this.deadlineMutex.Unlock()
+	return nil
+}
+
+func (this *Conn) SetWriteDeadline(t time.Time) error {
+	if conn, ok := this.conn.(net.Conn); ok {
+		return conn.SetWriteDeadline(t)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Package dtls implements a subset of DTLS 1.2 (RFC 6347), which is enough to secure the datagrams of mKCP.
+//
+// Only ECDHE key exchange on P-256 or P-384 with AES-128-GCM is supported, and the server authenticates
+// with an ECDSA or RSA certificate. The ClientHello carries the same extensions as the one of a WebRTC
+// endpoint, including use_srtp, so the handshake looks like the start of a video call.
+//
+// Every Write is sent as one record in one datagram, and every Read returns the content of one record,
+// so the boundaries of the packets above are kept.
+package dtls
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	versionDTLS10 = 0xfeff
+	versionDTLS12 = 0xfefd
+
+	recordHeaderLen    = 13
+	handshakeHeaderLen = 12
+	explicitNonceLen   = 8
+
+	// maxHandshakeFragment keeps handshake datagrams below common path MTUs.
+	maxHandshakeFragment = 1100
+	maxDatagramSize      = 1200
+
+	// maxHandshakeMessageLen and maxHandshakeSeqWindow bound the memory that a peer can make the reassembler
+	// allocate. The window covers the largest flight, which is the one of the server.
+	maxHandshakeMessageLen = 16384
+	maxHandshakeSeqWindow  = 8
+
+	contentTypeChangeCipherSpec = 20
+	contentTypeAlert            = 21
+	contentTypeHandshake        = 22
+	contentTypeApplicationData  = 23
+
+	typeClientHello        = 1
+	typeServerHello        = 2
+	typeHelloVerifyRequest = 3
+	typeCertificate        = 11
+	typeServerKeyExchange  = 12
+	typeServerHelloDone    = 14
+	typeClientKeyExchange  = 16
+	typeFinished           = 20
+
+	extensionSupportedGroups      = 10
+	extensionPointFormats         = 11
+	extensionSignatureAlgorithms  = 13
+	extensionUseSRTP              = 14
+	extensionExtendedMasterSecret = 23
+	extensionRenegotiationInfo    = 0xff01
+
+	curveP256 = 23
+	curveP384 = 24
+
+	signatureECDSAWithSHA256 = 0x0403
+	signaturePKCS1WithSHA256 = 0x0401
+
+	srtpAES128CMSHA1_80 = 0x0001
+	srtpAEADAES128GCM   = 0x0007
+
+	alertLevelWarning      = 1
+	alertLevelFatal        = 2
+	alertCloseNotify       = 0
+	alertHandshakeFailure  = 40
+	alertBadCertificate    = 42
+	alertIllegalParameter  = 47
+	alertDecryptError      = 51
+	alertInternalError     = 80
+	changeCipherSpecLength = 1
+
+	initialRetransmitTimeout = time.Second
+	maxRetransmitTimeout     = time.Second * 8
+	handshakeTimeout         = time.Second * 30
+	cookieTimeout            = time.Second * 10
+)
+
+const (
+	TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 uint16 = 0xc02b
+	TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256   uint16 = 0xc02f
+)
+
+var (
+	ErrHandshakeTimeout = errors.New("DTLS: Handshake timed out.")
+	ErrHandshakeFailure = errors.New("DTLS: Handshake failed.")
+	ErrBadCertificate   = errors.New("DTLS: Bad certificate.")
+	ErrNoCertificate    = errors.New("DTLS: No certificate is configured.")
+	ErrUnsupportedKey   = errors.New("DTLS: Unsupported private key.")
+	ErrFatalAlert       = errors.New("DTLS: Received fatal alert.")
+	ErrClosed           = errors.New("DTLS: Connection closed.")
+	ErrTimeout          = &timeoutError{}
+)
+
+type timeoutError struct{}
+
+func (this *timeoutError) Error() string   { return "DTLS: Read timed out." }
+func (this *timeoutError) Timeout() bool   { return true }
+func (this *timeoutError) Temporary() bool { return true }
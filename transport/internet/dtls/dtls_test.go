@@ -0,0 +1,154 @@
+package dtls_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/dtls"
+	"v2ray.com/core/transport/internet/udp"
+)
+
+func generateCertificate(t *testing.T, key crypto.Signer) (tls.Certificate, *x509.CertPool) {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "WebRTC"},
+		DNSNames:              []string{"www.v2ray.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// lossyConn drops the datagrams of the given indexes.
+type lossyConn struct {
+	net.Conn
+	sync.Mutex
+	count int
+	drop  map[int]bool
+}
+
+func (this *lossyConn) Write(b []byte) (int, error) {
+	this.Lock()
+	index := this.count
+	this.count++
+	this.Unlock()
+	if this.drop[index] {
+		return len(b), nil
+	}
+	return this.Conn.Write(b)
+}
+
+func startEchoHub(t *testing.T, config *tls.Config) *Hub {
+	var hub *Hub
+	hub = NewHub(config, func(payload *alloc.Buffer, session *proxy.SessionInfo) {
+		hub.WriteTo(payload.Value, session.Source)
+		payload.Release()
+	})
+	udpHub, err := udp.ListenUDP(v2net.LocalHostIP, v2net.Port(0), udp.ListenOption{Callback: hub.OnReceive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.Start(udpHub)
+	return hub
+}
+
+func testEcho(t *testing.T, key crypto.Signer, drop map[int]bool) {
+	assert := assert.On(t)
+
+	cert, pool := generateCertificate(t, key)
+	hub := startEchoHub(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer hub.Close()
+
+	rawConn, err := net.DialUDP("udp", nil, hub.Addr().(*net.UDPAddr))
+	assert.Error(err).IsNil()
+	conn := Client(&lossyConn{Conn: rawConn, drop: drop}, &tls.Config{
+		ServerName: "www.v2ray.com",
+		RootCAs:    pool,
+	})
+	defer conn.Close()
+	assert.Error(conn.Handshake()).IsNil()
+
+	for _, msg := range []string{"first datagram", "second datagram"} {
+		_, err = conn.Write([]byte(msg))
+		assert.Error(err).IsNil()
+
+		b := make([]byte, 2048)
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		nBytes, err := conn.Read(b)
+		assert.Error(err).IsNil()
+		assert.String(string(b[:nBytes])).Equals(msg)
+	}
+}
+
+func TestECDSAEcho(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testEcho(t, key, nil)
+}
+
+func TestRSAEcho(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testEcho(t, key, nil)
+}
+
+func TestHandshakeRetransmission(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drop the ClientHello with cookie, and then the ClientKeyExchange flight.
+	testEcho(t, key, map[int]bool{1: true, 3: true})
+}
+
+func TestUntrustedCertificate(t *testing.T) {
+	assert := assert.On(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Error(err).IsNil()
+	cert, _ := generateCertificate(t, key)
+	hub := startEchoHub(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer hub.Close()
+
+	rawConn, err := net.DialUDP("udp", nil, hub.Addr().(*net.UDPAddr))
+	assert.Error(err).IsNil()
+	conn := Client(rawConn, &tls.Config{
+		ServerName: "www.v2ray.com",
+		RootCAs:    x509.NewCertPool(),
+	})
+	defer conn.Close()
+	assert.Error(conn.Handshake()).IsNotNil()
+}
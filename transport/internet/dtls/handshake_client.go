@@ -0,0 +1,236 @@
+package dtls
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"math/big"
+)
+
+func newRandom() []byte {
+	random := make([]byte, 32)
+	rand.Read(random)
+	return random
+}
+
+func curveByID(id uint16) ecdh.Curve {
+	switch id {
+	case curveP256:
+		return ecdh.P256()
+	case curveP384:
+		return ecdh.P384()
+	default:
+		return nil
+	}
+}
+
+// sharedSecret returns the x coordinate of the ECDH result, which is the pre-master secret of ECDHE key exchange.
+func sharedSecret(privateKey *ecdh.PrivateKey, peerKey []byte) ([]byte, error) {
+	publicKey, err := privateKey.Curve().NewPublicKey(peerKey)
+	if err != nil {
+		return nil, ErrHandshakeFailure
+	}
+	return privateKey.ECDH(publicKey)
+}
+
+func signedDigest(clientRandom []byte, serverRandom []byte, params []byte) []byte {
+	hash := sha256.New()
+	hash.Write(clientRandom)
+	hash.Write(serverRandom)
+	hash.Write(params)
+	return hash.Sum(nil)
+}
+
+func verifySignature(publicKey crypto.PublicKey, algorithm uint16, digest []byte, signature []byte) error {
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if algorithm != signatureECDSAWithSHA256 {
+			return ErrHandshakeFailure
+		}
+		var sig struct {
+			R, S *big.Int
+		}
+		if _, err := asn1.Unmarshal(signature, &sig); err != nil {
+			return ErrHandshakeFailure
+		}
+		if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+			return ErrHandshakeFailure
+		}
+		if !ecdsa.Verify(publicKey, digest, sig.R, sig.S) {
+			return ErrHandshakeFailure
+		}
+		return nil
+	case *rsa.PublicKey:
+		if algorithm != signaturePKCS1WithSHA256 {
+			return ErrHandshakeFailure
+		}
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest, signature)
+	default:
+		return ErrBadCertificate
+	}
+}
+
+func (this *Conn) verifyCertificates(rawCerts [][]byte) (crypto.PublicKey, error) {
+	if len(rawCerts) == 0 {
+		return nil, ErrBadCertificate
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for idx, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, ErrBadCertificate
+		}
+		certs[idx] = cert
+	}
+	if !this.config.InsecureSkipVerify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         this.config.RootCAs,
+			DNSName:       this.config.ServerName,
+			Intermediates: intermediates,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return certs[0].PublicKey, nil
+}
+
+func (this *Conn) clientHandshake() error {
+	hello := &clientHello{
+		random:               newRandom(),
+		cipherSuites:         []uint16{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		curves:               []uint16{curveP256, curveP384},
+		signatureAlgorithms:  []uint16{signatureECDSAWithSHA256, signaturePKCS1WithSHA256},
+		srtpProfiles:         []uint16{srtpAEADAES128GCM, srtpAES128CMSHA1_80},
+		extendedMasterSecret: true,
+	}
+	if err := this.sendFlight(this.addMessage(nil, typeClientHello, hello.marshal(), 0)); err != nil {
+		return err
+	}
+
+	msg, err := this.readHandshakeMessage()
+	if err != nil {
+		return err
+	}
+	if msg.msgType == typeHelloVerifyRequest {
+		cookie, ok := parseHelloVerifyRequest(msg.body)
+		if !ok {
+			return this.fail(alertIllegalParameter, ErrHandshakeFailure)
+		}
+		// The transcript starts from the ClientHello with cookie.
+		this.transcript = nil
+		hello.cookie = cookie
+		if err := this.sendFlight(this.addMessage(nil, typeClientHello, hello.marshal(), 0)); err != nil {
+			return err
+		}
+		if msg, err = this.readHandshakeMessage(); err != nil {
+			return err
+		}
+	}
+
+	if msg.msgType != typeServerHello {
+		return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+	}
+	serverHello, ok := parseServerHello(msg.body)
+	if !ok || (serverHello.cipherSuite != TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 && serverHello.cipherSuite != TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) {
+		return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+	}
+	this.addTranscript(msg)
+
+	if msg, err = this.readHandshakeMessage(); err != nil {
+		return err
+	}
+	if msg.msgType != typeCertificate {
+		return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+	}
+	rawCerts, ok := parseCertificate(msg.body)
+	if !ok {
+		return this.fail(alertBadCertificate, ErrBadCertificate)
+	}
+	publicKey, err := this.verifyCertificates(rawCerts)
+	if err != nil {
+		return this.fail(alertBadCertificate, err)
+	}
+	this.addTranscript(msg)
+
+	if msg, err = this.readHandshakeMessage(); err != nil {
+		return err
+	}
+	if msg.msgType != typeServerKeyExchange {
+		return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+	}
+	keyExchange, ok := parseServerKeyExchange(msg.body)
+	if !ok {
+		return this.fail(alertIllegalParameter, ErrHandshakeFailure)
+	}
+	curve := curveByID(keyExchange.curve)
+	if curve == nil {
+		return this.fail(alertIllegalParameter, ErrHandshakeFailure)
+	}
+	digest := signedDigest(hello.random, serverHello.random, keyExchange.params())
+	if err := verifySignature(publicKey, keyExchange.algorithm, digest, keyExchange.signature); err != nil {
+		return this.fail(alertDecryptError, err)
+	}
+	this.addTranscript(msg)
+
+	if msg, err = this.readHandshakeMessage(); err != nil {
+		return err
+	}
+	if msg.msgType != typeServerHelloDone {
+		return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+	}
+	this.addTranscript(msg)
+
+	privateKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return this.fail(alertInternalError, err)
+	}
+	preMaster, err := sharedSecret(privateKey, keyExchange.publicKey)
+	if err != nil {
+		return this.fail(alertIllegalParameter, err)
+	}
+	flight := this.addMessage(nil, typeClientKeyExchange, marshalClientKeyExchange(privateKey.PublicKey().Bytes()), 0)
+
+	var master []byte
+	if serverHello.extendedMasterSecret {
+		master = extendedMasterFromPreMaster(preMaster, this.transcript)
+	} else {
+		master = masterFromPreMaster(preMaster, hello.random, serverHello.random)
+	}
+	clientCipher, serverCipher, err := newCipherStates(master, hello.random, serverHello.random)
+	if err != nil {
+		return this.fail(alertInternalError, err)
+	}
+	this.setWriteCipher(clientCipher)
+	if err := this.setPendingReadCipher(serverCipher); err != nil {
+		return err
+	}
+
+	flight = append(flight, &outgoing{
+		contentType: contentTypeChangeCipherSpec,
+		epoch:       0,
+		payload:     []byte{changeCipherSpecLength},
+	})
+	flight = this.addMessage(flight, typeFinished, finishedData(master, "client finished", this.transcript), 1)
+	if err := this.sendFlight(flight); err != nil {
+		return err
+	}
+
+	if msg, err = this.readHandshakeMessage(); err != nil {
+		return err
+	}
+	if msg.msgType != typeFinished || !hmac.Equal(msg.body, finishedData(master, "server finished", this.transcript)) {
+		return this.fail(alertDecryptError, ErrHandshakeFailure)
+	}
+	this.addTranscript(msg)
+	return nil
+}
@@ -0,0 +1,330 @@
+package dtls
+
+import (
+	"encoding/binary"
+)
+
+// builder appends the fields of a handshake message.
+type builder struct {
+	b []byte
+}
+
+func (this *builder) u8(v byte) {
+	this.b = append(this.b, v)
+}
+
+func (this *builder) u16(v uint16) {
+	this.b = append(this.b, byte(v>>8), byte(v))
+}
+
+func (this *builder) bytes(v []byte) {
+	this.b = append(this.b, v...)
+}
+
+func (this *builder) vec8(v []byte) {
+	this.u8(byte(len(v)))
+	this.bytes(v)
+}
+
+func (this *builder) vec16(v []byte) {
+	this.u16(uint16(len(v)))
+	this.bytes(v)
+}
+
+func (this *builder) vec24(v []byte) {
+	this.b = append(this.b, byte(len(v)>>16), byte(len(v)>>8), byte(len(v)))
+	this.bytes(v)
+}
+
+func (this *builder) extension(id uint16, data []byte) {
+	this.u16(id)
+	this.vec16(data)
+}
+
+func uint16List(values []uint16) []byte {
+	b := make([]byte, 2*len(values))
+	for idx, v := range values {
+		binary.BigEndian.PutUint16(b[2*idx:], v)
+	}
+	return b
+}
+
+// parser reads the fields of a handshake message. Once a read goes out of range, all further reads return zero values.
+type parser struct {
+	b   []byte
+	bad bool
+}
+
+func (this *parser) read(n int) []byte {
+	if this.bad || len(this.b) < n {
+		this.bad = true
+		return nil
+	}
+	v := this.b[:n]
+	this.b = this.b[n:]
+	return v
+}
+
+func (this *parser) u8() byte {
+	if v := this.read(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (this *parser) u16() uint16 {
+	if v := this.read(2); v != nil {
+		return binary.BigEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (this *parser) vec8() []byte {
+	return this.read(int(this.u8()))
+}
+
+func (this *parser) vec16() []byte {
+	return this.read(int(this.u16()))
+}
+
+func (this *parser) vec24() []byte {
+	if v := this.read(3); v != nil {
+		return this.read(uint24(v))
+	}
+	return nil
+}
+
+func (this *parser) uint16List() []uint16 {
+	b := this.vec16()
+	if len(b)%2 != 0 {
+		this.bad = true
+		return nil
+	}
+	values := make([]uint16, len(b)/2)
+	for idx := range values {
+		values[idx] = binary.BigEndian.Uint16(b[2*idx:])
+	}
+	return values
+}
+
+type clientHello struct {
+	random               []byte
+	sessionId            []byte
+	cookie               []byte
+	cipherSuites         []uint16
+	curves               []uint16
+	signatureAlgorithms  []uint16
+	srtpProfiles         []uint16
+	extendedMasterSecret bool
+}
+
+func (this *clientHello) marshal() []byte {
+	b := new(builder)
+	b.u16(versionDTLS12)
+	b.bytes(this.random)
+	b.vec8(this.sessionId)
+	b.vec8(this.cookie)
+	b.vec16(uint16List(this.cipherSuites))
+	b.vec8([]byte{0})
+
+	ext := new(builder)
+	if this.extendedMasterSecret {
+		ext.extension(extensionExtendedMasterSecret, nil)
+	}
+	ext.extension(extensionRenegotiationInfo, []byte{0})
+	ext.extension(extensionSupportedGroups, append([]byte{0, byte(2 * len(this.curves))}, uint16List(this.curves)...))
+	ext.extension(extensionPointFormats, []byte{1, 0})
+	ext.extension(extensionSignatureAlgorithms, append([]byte{0, byte(2 * len(this.signatureAlgorithms))}, uint16List(this.signatureAlgorithms)...))
+	if len(this.srtpProfiles) > 0 {
+		srtp := new(builder)
+		srtp.vec16(uint16List(this.srtpProfiles))
+		srtp.vec8(nil)
+		ext.extension(extensionUseSRTP, srtp.b)
+	}
+	b.vec16(ext.b)
+	return b.b
+}
+
+func parseClientHello(body []byte) (*clientHello, bool) {
+	p := &parser{b: body}
+	hello := new(clientHello)
+	p.u16()
+	hello.random = p.read(32)
+	hello.sessionId = p.vec8()
+	hello.cookie = p.vec8()
+	hello.cipherSuites = p.uint16List()
+	p.vec8()
+	if p.bad {
+		return nil, false
+	}
+	if len(p.b) == 0 {
+		return hello, true
+	}
+	extensions := &parser{b: p.vec16()}
+	for len(extensions.b) > 0 && !extensions.bad {
+		id := extensions.u16()
+		data := &parser{b: extensions.vec16()}
+		switch id {
+		case extensionSupportedGroups:
+			hello.curves = data.uint16List()
+		case extensionSignatureAlgorithms:
+			hello.signatureAlgorithms = data.uint16List()
+		case extensionUseSRTP:
+			hello.srtpProfiles = data.uint16List()
+		case extensionExtendedMasterSecret:
+			hello.extendedMasterSecret = true
+		}
+	}
+	if p.bad || extensions.bad {
+		return nil, false
+	}
+	return hello, true
+}
+
+type serverHello struct {
+	random               []byte
+	sessionId            []byte
+	cipherSuite          uint16
+	srtpProfile          uint16
+	extendedMasterSecret bool
+}
+
+func (this *serverHello) marshal() []byte {
+	b := new(builder)
+	b.u16(versionDTLS12)
+	b.bytes(this.random)
+	b.vec8(this.sessionId)
+	b.u16(this.cipherSuite)
+	b.u8(0)
+
+	ext := new(builder)
+	if this.extendedMasterSecret {
+		ext.extension(extensionExtendedMasterSecret, nil)
+	}
+	ext.extension(extensionRenegotiationInfo, []byte{0})
+	if this.srtpProfile != 0 {
+		srtp := new(builder)
+		srtp.vec16(uint16List([]uint16{this.srtpProfile}))
+		srtp.vec8(nil)
+		ext.extension(extensionUseSRTP, srtp.b)
+	}
+	ext.extension(extensionPointFormats, []byte{1, 0})
+	b.vec16(ext.b)
+	return b.b
+}
+
+func parseServerHello(body []byte) (*serverHello, bool) {
+	p := &parser{b: body}
+	hello := new(serverHello)
+	if p.u16() != versionDTLS12 {
+		return nil, false
+	}
+	hello.random = p.read(32)
+	hello.sessionId = p.vec8()
+	hello.cipherSuite = p.u16()
+	if p.u8() != 0 || p.bad {
+		return nil, false
+	}
+	if len(p.b) == 0 {
+		return hello, true
+	}
+	extensions := &parser{b: p.vec16()}
+	for len(extensions.b) > 0 && !extensions.bad {
+		id := extensions.u16()
+		data := &parser{b: extensions.vec16()}
+		switch id {
+		case extensionUseSRTP:
+			if profiles := data.uint16List(); len(profiles) == 1 {
+				hello.srtpProfile = profiles[0]
+			}
+		case extensionExtendedMasterSecret:
+			hello.extendedMasterSecret = true
+		}
+	}
+	if p.bad || extensions.bad {
+		return nil, false
+	}
+	return hello, true
+}
+
+func marshalHelloVerifyRequest(cookie []byte) []byte {
+	b := new(builder)
+	b.u16(versionDTLS10)
+	b.vec8(cookie)
+	return b.b
+}
+
+func parseHelloVerifyRequest(body []byte) ([]byte, bool) {
+	p := &parser{b: body}
+	p.u16()
+	cookie := p.vec8()
+	return cookie, !p.bad
+}
+
+func marshalCertificate(certs [][]byte) []byte {
+	list := new(builder)
+	for _, cert := range certs {
+		list.vec24(cert)
+	}
+	b := new(builder)
+	b.vec24(list.b)
+	return b.b
+}
+
+func parseCertificate(body []byte) ([][]byte, bool) {
+	p := &parser{b: body}
+	list := &parser{b: p.vec24()}
+	var certs [][]byte
+	for len(list.b) > 0 && !list.bad {
+		certs = append(certs, list.vec24())
+	}
+	return certs, !p.bad && !list.bad
+}
+
+type serverKeyExchange struct {
+	curve     uint16
+	publicKey []byte
+	algorithm uint16
+	signature []byte
+}
+
+// params returns the part of the message covered by the signature.
+func (this *serverKeyExchange) params() []byte {
+	b := new(builder)
+	b.u8(3) // named_curve
+	b.u16(this.curve)
+	b.vec8(this.publicKey)
+	return b.b
+}
+
+func (this *serverKeyExchange) marshal() []byte {
+	b := &builder{b: this.params()}
+	b.u16(this.algorithm)
+	b.vec16(this.signature)
+	return b.b
+}
+
+func parseServerKeyExchange(body []byte) (*serverKeyExchange, bool) {
+	p := &parser{b: body}
+	if p.u8() != 3 {
+		return nil, false
+	}
+	ske := new(serverKeyExchange)
+	ske.curve = p.u16()
+	ske.publicKey = p.vec8()
+	ske.algorithm = p.u16()
+	ske.signature = p.vec16()
+	return ske, !p.bad
+}
+
+func marshalClientKeyExchange(publicKey []byte) []byte {
+	b := new(builder)
+	b.vec8(publicKey)
+	return b.b
+}
+
+func parseClientKeyExchange(body []byte) ([]byte, bool) {
+	p := &parser{b: body}
+	publicKey := p.vec8()
+	return publicKey, !p.bad
+}
@@ -0,0 +1,204 @@
+package dtls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"time"
+)
+
+var (
+	cookieSecret = newRandom()
+)
+
+// cookie binds the client address and random, so that the server doesn't commit any state to spoofed addresses.
+func (this *Conn) cookie(clientRandom []byte) []byte {
+	mac := hmac.New(sha256.New, cookieSecret)
+	mac.Write([]byte(this.conn.RemoteAddr().String()))
+	mac.Write(clientRandom)
+	return mac.Sum(nil)
+}
+
+func containsUint16(list []uint16, v uint16) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// readClientHello returns the first ClientHello with a valid cookie, and answers all others with HelloVerifyRequest.
+// A peer that doesn't return a valid cookie within cookieTimeout fails the handshake, so that its state is dropped.
+func (this *Conn) readClientHello() (*handshakeMessage, *clientHello, error) {
+	deadline := this.handshakeDeadline
+	this.handshakeDeadline = time.Now().Add(cookieTimeout)
+	for {
+		if msg := this.messages.popAny(typeClientHello); msg != nil {
+			hello, ok := parseClientHello(msg.body)
+			if !ok {
+				continue
+			}
+			if hmac.Equal(hello.cookie, this.cookie(hello.random)) {
+				this.messages.reset()
+				this.handshakeDeadline = deadline
+				return msg, hello, nil
+			}
+			// Nothing is kept for a ClientHello without a valid cookie.
+			this.messages.reset()
+			verify := &handshakeMessage{
+				msgType: typeHelloVerifyRequest,
+				seq:     msg.seq,
+				body:    marshalHelloVerifyRequest(this.cookie(hello.random)),
+			}
+			if err := this.writeRecords([]*outgoing{{
+				contentType: contentTypeHandshake,
+				epoch:       0,
+				payload:     verify.marshal(),
+			}}); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		datagram, err := this.waitDatagram()
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := this.processRecords(datagram); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (this *Conn) serverHandshake() error {
+	if len(this.config.Certificates) == 0 {
+		return ErrNoCertificate
+	}
+	cert := this.config.Certificates[0]
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return ErrUnsupportedKey
+	}
+	var cipherSuite, algorithm uint16
+	switch signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		cipherSuite = TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
+		algorithm = signatureECDSAWithSHA256
+	case *rsa.PublicKey:
+		cipherSuite = TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+		algorithm = signaturePKCS1WithSHA256
+	default:
+		return ErrUnsupportedKey
+	}
+
+	msg, hello, err := this.readClientHello()
+	if err != nil {
+		return err
+	}
+	this.recvMessageSeq = msg.seq + 1
+	this.sendMessageSeq = msg.seq
+	this.addTranscript(msg)
+
+	if !containsUint16(hello.cipherSuites, cipherSuite) {
+		return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+	}
+	curveID := uint16(curveP256)
+	if len(hello.curves) > 0 {
+		curveID = 0
+		for _, id := range hello.curves {
+			if curveByID(id) != nil {
+				curveID = id
+				break
+			}
+		}
+		if curveID == 0 {
+			return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+		}
+	}
+	curve := curveByID(curveID)
+
+	serverHello := &serverHello{
+		random:               newRandom(),
+		sessionId:            newRandom(),
+		cipherSuite:          cipherSuite,
+		extendedMasterSecret: hello.extendedMasterSecret,
+	}
+	for _, profile := range hello.srtpProfiles {
+		if profile == srtpAEADAES128GCM || profile == srtpAES128CMSHA1_80 {
+			serverHello.srtpProfile = profile
+			break
+		}
+	}
+
+	privateKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return this.fail(alertInternalError, err)
+	}
+	keyExchange := &serverKeyExchange{
+		curve:     curveID,
+		publicKey: privateKey.PublicKey().Bytes(),
+		algorithm: algorithm,
+	}
+	keyExchange.signature, err = signer.Sign(rand.Reader, signedDigest(hello.random, serverHello.random, keyExchange.params()), crypto.SHA256)
+	if err != nil {
+		return this.fail(alertInternalError, err)
+	}
+
+	flight := this.addMessage(nil, typeServerHello, serverHello.marshal(), 0)
+	flight = this.addMessage(flight, typeCertificate, marshalCertificate(cert.Certificate), 0)
+	flight = this.addMessage(flight, typeServerKeyExchange, keyExchange.marshal(), 0)
+	flight = this.addMessage(flight, typeServerHelloDone, nil, 0)
+	if err := this.sendFlight(flight); err != nil {
+		return err
+	}
+
+	if msg, err = this.readHandshakeMessage(); err != nil {
+		return err
+	}
+	if msg.msgType != typeClientKeyExchange {
+		return this.fail(alertHandshakeFailure, ErrHandshakeFailure)
+	}
+	clientKey, ok := parseClientKeyExchange(msg.body)
+	if !ok {
+		return this.fail(alertIllegalParameter, ErrHandshakeFailure)
+	}
+	preMaster, err := sharedSecret(privateKey, clientKey)
+	if err != nil {
+		return this.fail(alertIllegalParameter, err)
+	}
+	this.addTranscript(msg)
+
+	var master []byte
+	if serverHello.extendedMasterSecret {
+		master = extendedMasterFromPreMaster(preMaster, this.transcript)
+	} else {
+		master = masterFromPreMaster(preMaster, hello.random, serverHello.random)
+	}
+	clientCipher, serverCipher, err := newCipherStates(master, hello.random, serverHello.random)
+	if err != nil {
+		return this.fail(alertInternalError, err)
+	}
+	if err := this.setPendingReadCipher(clientCipher); err != nil {
+		return err
+	}
+
+	if msg, err = this.readHandshakeMessage(); err != nil {
+		return err
+	}
+	if msg.msgType != typeFinished || !hmac.Equal(msg.body, finishedData(master, "client finished", this.transcript)) {
+		return this.fail(alertDecryptError, ErrHandshakeFailure)
+	}
+	this.addTranscript(msg)
+
+	this.setWriteCipher(serverCipher)
+	flight = []*outgoing{{
+		contentType: contentTypeChangeCipherSpec,
+		epoch:       0,
+		payload:     []byte{changeCipherSpecLength},
+	}}
+	flight = this.addMessage(flight, typeFinished, finishedData(master, "server finished", this.transcript), 1)
+	return this.sendFlight(flight)
+}
@@ -0,0 +1,227 @@
+package dtls
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet/udp"
+)
+
+const (
+	peerIdleTimeout = time.Minute * 5
+
+	// maxPendingPeers is the number of peers that may be in handshake at the same time. Datagrams from new peers
+	// are dropped above it.
+	maxPendingPeers = 256
+)
+
+// PacketHub is the datagram transport under a Hub, such as udp.UDPHub.
+type PacketHub interface {
+	WriteTo(payload []byte, dest v2net.Destination) (int, error)
+	Addr() net.Addr
+	Close()
+}
+
+// peerRemover is implemented by hubs that keep per peer state, such as faketcp.Hub.
+type peerRemover interface {
+	Remove(dest v2net.Destination)
+}
+
+// Hub is the server side of DTLS on a PacketHub. It keeps one DTLS session per remote address, and hands decrypted
+// datagrams to the callback.
+type Hub struct {
+	sync.Mutex
+	hub      PacketHub
+	config   *tls.Config
+	peers    map[string]*peerConn
+	pending  int
+	callback udp.UDPPayloadHandler
+	closed   bool
+}
+
+func NewHub(config *tls.Config, callback udp.UDPPayloadHandler) *Hub {
+	return &Hub{
+		config:   config,
+		peers:    make(map[string]*peerConn),
+		callback: callback,
+	}
+}
+
+// Start attaches the underlying hub, whose callback must be OnReceive. Datagrams received before are dropped.
+func (this *Hub) Start(hub PacketHub) {
+	this.Lock()
+	this.hub = hub
+	this.Unlock()
+}
+
+// OnReceive takes a datagram from the underlying hub.
+func (this *Hub) OnReceive(payload *alloc.Buffer, session *proxy.SessionInfo) {
+	defer payload.Release()
+
+	this.Lock()
+	if this.hub == nil || this.closed {
+		this.Unlock()
+		return
+	}
+	id := session.Source.NetAddr()
+	peer, found := this.peers[id]
+	if !found {
+		if this.pending >= maxPendingPeers {
+			this.Unlock()
+			return
+		}
+		this.pending++
+		peer = &peerConn{
+			hub:      this,
+			dest:     session.Source,
+			incoming: make(chan []byte, 64),
+			closed:   make(chan bool),
+		}
+		peer.conn = Server(peer, this.config)
+		this.peers[id] = peer
+		go this.serve(peer)
+	}
+	this.Unlock()
+
+	peer.push(append([]byte(nil), payload.Value...))
+}
+
+func (this *Hub) serve(peer *peerConn) {
+	conn := peer.conn
+	defer conn.Close()
+
+	err := conn.Handshake()
+	this.Lock()
+	this.pending--
+	this.Unlock()
+	if err != nil {
+		log.Info("DTLS|Hub: Handshake with ", peer.dest, " failed: ", err)
+		return
+	}
+	log.Debug("DTLS|Hub: Established session with ", peer.dest)
+
+	buffer := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(peerIdleTimeout))
+		nBytes, err := conn.Read(buffer)
+		if err != nil {
+			return
+		}
+		payload := alloc.NewBuffer().Clear()
+		payload.Append(buffer[:nBytes])
+		this.callback(payload, &proxy.SessionInfo{
+			Source: peer.dest,
+		})
+	}
+}
+
+func (this *Hub) remove(peer *peerConn) {
+	this.Lock()
+	defer this.Unlock()
+
+	id := peer.dest.NetAddr()
+	if this.peers[id] == peer {
+		delete(this.peers, id)
+	}
+}
+
+// WriteTo sends the payload in the DTLS session with the given remote address.
+func (this *Hub) WriteTo(payload []byte, dest v2net.Destination) (int, error) {
+	this.Lock()
+	peer, found := this.peers[dest.NetAddr()]
+	this.Unlock()
+	if !found {
+		return 0, ErrClosed
+	}
+	return peer.conn.Write(payload)
+}
+
+// Remove closes the DTLS session with the given remote address.
+func (this *Hub) Remove(dest v2net.Destination) {
+	this.Lock()
+	peer, found := this.peers[dest.NetAddr()]
+	this.Unlock()
+	if found {
+		peer.conn.Close()
+	}
+}
+
+func (this *Hub) Addr() net.Addr {
+	return this.hub.Addr()
+}
+
+func (this *Hub) Close() {
+	this.Lock()
+	this.closed = true
+	peers := make([]*peerConn, 0, len(this.peers))
+	for _, peer := range this.peers {
+		peers = append(peers, peer)
+	}
+	this.Unlock()
+
+	for _, peer := range peers {
+		peer.conn.Close()
+	}
+	if this.hub != nil {
+		this.hub.Close()
+	}
+}
+
+// peerConn is the datagram connection between a Hub and one remote address.
+type peerConn struct {
+	hub       *Hub
+	dest      v2net.Destination
+	conn      *Conn
+	incoming  chan []byte
+	closed    chan bool
+	closeOnce sync.Once
+}
+
+func (this *peerConn) push(datagram []byte) {
+	select {
+	case this.incoming <- datagram:
+	default:
+	}
+}
+
+func (this *peerConn) Read(b []byte) (int, error) {
+	select {
+	case datagram := <-this.incoming:
+		return copy(b, datagram), nil
+	case <-this.closed:
+		return 0, io.EOF
+	}
+}
+
+func (this *peerConn) Write(b []byte) (int, error) {
+	return this.hub.hub.WriteTo(b, this.dest)
+}
+
+func (this *peerConn) Close() error {
+	this.closeOnce.Do(func() {
+		close(this.closed)
+		this.hub.remove(this)
+		if hub, ok := this.hub.hub.(peerRemover); ok {
+			hub.Remove(this.dest)
+		}
+	})
+	return nil
+}
+
+func (this *peerConn) LocalAddr() net.Addr {
+	return this.hub.Addr()
+}
+
+func (this *peerConn) RemoteAddr() net.Addr {
+	return &net.UDPAddr{
+		IP:   this.dest.Address.IP(),
+		Port: int(this.dest.Port),
+	}
+}
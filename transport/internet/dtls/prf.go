@@ -0,0 +1,151 @@
+package dtls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	masterSecretLength = 48
+	finishedLength     = 12
+	gcmKeyLength       = 16
+	gcmSaltLength      = 4
+	gcmTagLength       = 16
+)
+
+var (
+	errDecrypt = errors.New("DTLS: Failed to decrypt record.")
+)
+
+// prf12 is the pseudo random function of TLS 1.2 with SHA-256.
+func prf12(result []byte, secret []byte, label string, seed []byte) {
+	labelAndSeed := make([]byte, 0, len(label)+len(seed))
+	labelAndSeed = append(labelAndSeed, label...)
+	labelAndSeed = append(labelAndSeed, seed...)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(labelAndSeed)
+	a := mac.Sum(nil)
+
+	for offset := 0; offset < len(result); {
+		mac.Reset()
+		mac.Write(a)
+		mac.Write(labelAndSeed)
+		offset += copy(result[offset:], mac.Sum(nil))
+
+		mac.Reset()
+		mac.Write(a)
+		a = mac.Sum(nil)
+	}
+}
+
+func masterFromPreMaster(preMaster []byte, clientRandom []byte, serverRandom []byte) []byte {
+	seed := make([]byte, 0, len(clientRandom)+len(serverRandom))
+	seed = append(seed, clientRandom...)
+	seed = append(seed, serverRandom...)
+	master := make([]byte, masterSecretLength)
+	prf12(master, preMaster, "master secret", seed)
+	return master
+}
+
+// extendedMasterFromPreMaster derives the master secret as RFC 7627, which binds it to the whole handshake.
+func extendedMasterFromPreMaster(preMaster []byte, transcript []byte) []byte {
+	sessionHash := sha256.Sum256(transcript)
+	master := make([]byte, masterSecretLength)
+	prf12(master, preMaster, "extended master secret", sessionHash[:])
+	return master
+}
+
+func finishedData(master []byte, label string, transcript []byte) []byte {
+	hash := sha256.Sum256(transcript)
+	result := make([]byte, finishedLength)
+	prf12(result, master, label, hash[:])
+	return result
+}
+
+// cipherState protects records of one direction in one epoch with AES-128-GCM.
+type cipherState struct {
+	aead cipher.AEAD
+	salt []byte
+}
+
+func newCipherState(key []byte, salt []byte) (*cipherState, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &cipherState{
+		aead: aead,
+		salt: salt,
+	}, nil
+}
+
+// newCipherStates returns the client write and server write states derived from the master secret.
+func newCipherStates(master []byte, clientRandom []byte, serverRandom []byte) (*cipherState, *cipherState, error) {
+	seed := make([]byte, 0, len(clientRandom)+len(serverRandom))
+	seed = append(seed, serverRandom...)
+	seed = append(seed, clientRandom...)
+	keyBlock := make([]byte, 2*gcmKeyLength+2*gcmSaltLength)
+	prf12(keyBlock, master, "key expansion", seed)
+
+	clientKey := keyBlock[:gcmKeyLength]
+	serverKey := keyBlock[gcmKeyLength : 2*gcmKeyLength]
+	clientSalt := keyBlock[2*gcmKeyLength : 2*gcmKeyLength+gcmSaltLength]
+	serverSalt := keyBlock[2*gcmKeyLength+gcmSaltLength:]
+
+	client, err := newCipherState(clientKey, clientSalt)
+	if err != nil {
+		return nil, nil, err
+	}
+	server, err := newCipherState(serverKey, serverSalt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, server, nil
+}
+
+func (this *cipherState) nonce(header []byte) []byte {
+	nonce := make([]byte, 0, gcmSaltLength+explicitNonceLen)
+	nonce = append(nonce, this.salt...)
+	return append(nonce, header[3:11]...)
+}
+
+func additionalData(header []byte, length int) []byte {
+	ad := make([]byte, 13)
+	copy(ad, header[3:11])
+	ad[8] = header[0]
+	copy(ad[9:11], header[1:3])
+	binary.BigEndian.PutUint16(ad[11:], uint16(length))
+	return ad
+}
+
+// seal encrypts the plaintext of the record with the given header. The explicit nonce is the epoch and sequence number.
+func (this *cipherState) seal(header []byte, plaintext []byte) []byte {
+	nonce := this.nonce(header)
+	out := make([]byte, explicitNonceLen, explicitNonceLen+len(plaintext)+gcmTagLength)
+	copy(out, nonce[gcmSaltLength:])
+	return this.aead.Seal(out, nonce, plaintext, additionalData(header, len(plaintext)))
+}
+
+func (this *cipherState) open(header []byte, payload []byte) ([]byte, error) {
+	if len(payload) < explicitNonceLen+gcmTagLength {
+		return nil, errDecrypt
+	}
+	nonce := make([]byte, 0, gcmSaltLength+explicitNonceLen)
+	nonce = append(nonce, this.salt...)
+	nonce = append(nonce, payload[:explicitNonceLen]...)
+	ciphertext := payload[explicitNonceLen:]
+	plaintext, err := this.aead.Open(nil, nonce, ciphertext, additionalData(header, len(ciphertext)-gcmTagLength))
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return plaintext, nil
+}
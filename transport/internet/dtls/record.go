@@ -0,0 +1,213 @@
+package dtls
+
+import (
+	"encoding/binary"
+)
+
+type record struct {
+	contentType byte
+	epoch       uint16
+	seq         uint64
+	header      []byte
+	payload     []byte
+}
+
+// parseRecords splits a datagram into records. Malformed trailing bytes are ignored.
+func parseRecords(datagram []byte) []*record {
+	var records []*record
+	for len(datagram) >= recordHeaderLen {
+		length := int(binary.BigEndian.Uint16(datagram[11:13]))
+		if len(datagram) < recordHeaderLen+length {
+			break
+		}
+		records = append(records, &record{
+			contentType: datagram[0],
+			epoch:       binary.BigEndian.Uint16(datagram[3:5]),
+			seq:         uint64(binary.BigEndian.Uint16(datagram[5:7]))<<32 | uint64(binary.BigEndian.Uint32(datagram[7:11])),
+			header:      datagram[:recordHeaderLen],
+			payload:     datagram[recordHeaderLen : recordHeaderLen+length],
+		})
+		datagram = datagram[recordHeaderLen+length:]
+	}
+	return records
+}
+
+func marshalRecordHeader(contentType byte, epoch uint16, seq uint64, length int) []byte {
+	header := make([]byte, recordHeaderLen)
+	header[0] = contentType
+	binary.BigEndian.PutUint16(header[1:3], versionDTLS12)
+	binary.BigEndian.PutUint16(header[3:5], epoch)
+	binary.BigEndian.PutUint16(header[5:7], uint16(seq>>32))
+	binary.BigEndian.PutUint32(header[7:11], uint32(seq))
+	binary.BigEndian.PutUint16(header[11:13], uint16(length))
+	return header
+}
+
+type handshakeMessage struct {
+	msgType byte
+	seq     uint16
+	body    []byte
+}
+
+// marshal returns the message as a single fragment, which is also the form used in the handshake transcript.
+func (this *handshakeMessage) marshal() []byte {
+	return marshalHandshakeFragment(this.msgType, len(this.body), this.seq, 0, this.body)
+}
+
+func marshalHandshakeFragment(msgType byte, length int, seq uint16, offset int, fragment []byte) []byte {
+	b := make([]byte, handshakeHeaderLen, handshakeHeaderLen+len(fragment))
+	b[0] = msgType
+	putUint24(b[1:4], length)
+	binary.BigEndian.PutUint16(b[4:6], seq)
+	putUint24(b[6:9], offset)
+	putUint24(b[9:12], len(fragment))
+	return append(b, fragment...)
+}
+
+// fragmentBuffer reassembles one handshake message.
+type fragmentBuffer struct {
+	msgType byte
+	body    []byte
+	// received is a bitmap of the bytes of body received so far. Fragments may overlap, as a peer may fragment a
+	// retransmission differently.
+	received []byte
+	missing  int
+}
+
+func newFragmentBuffer(msgType byte, length int) *fragmentBuffer {
+	return &fragmentBuffer{
+		msgType:  msgType,
+		body:     make([]byte, length),
+		received: make([]byte, (length+7)/8),
+		missing:  length,
+	}
+}
+
+func (this *fragmentBuffer) add(offset int, fragment []byte) {
+	copy(this.body[offset:], fragment)
+	for i := offset; i < offset+len(fragment); i++ {
+		if this.received[i/8]&(1<<uint(i%8)) == 0 {
+			this.received[i/8] |= 1 << uint(i%8)
+			this.missing--
+		}
+	}
+}
+
+func (this *fragmentBuffer) complete() bool {
+	return this.missing == 0
+}
+
+// reassembler collects handshake fragments and returns complete messages.
+type reassembler struct {
+	buffers map[uint16]*fragmentBuffer
+}
+
+func newReassembler() *reassembler {
+	return &reassembler{
+		buffers: make(map[uint16]*fragmentBuffer),
+	}
+}
+
+// add parses the handshake fragments in payload, and keeps those whose sequence number is in the window starting at
+// minSeq, and whose message is not longer than maxHandshakeMessageLen. It returns the sequence numbers found in the
+// payload.
+func (this *reassembler) add(payload []byte, minSeq uint16) []uint16 {
+	var seqs []uint16
+	for len(payload) >= handshakeHeaderLen {
+		msgType := payload[0]
+		length := uint24(payload[1:4])
+		seq := binary.BigEndian.Uint16(payload[4:6])
+		offset := uint24(payload[6:9])
+		fragLen := uint24(payload[9:12])
+		if len(payload) < handshakeHeaderLen+fragLen || offset+fragLen > length {
+			return seqs
+		}
+		fragment := payload[handshakeHeaderLen : handshakeHeaderLen+fragLen]
+		payload = payload[handshakeHeaderLen+fragLen:]
+		seqs = append(seqs, seq)
+		if seq < minSeq || int(seq) >= int(minSeq)+maxHandshakeSeqWindow || length > maxHandshakeMessageLen {
+			continue
+		}
+
+		buffer, found := this.buffers[seq]
+		if !found {
+			if len(this.buffers) >= maxHandshakeSeqWindow {
+				continue
+			}
+			buffer = newFragmentBuffer(msgType, length)
+			this.buffers[seq] = buffer
+		}
+		if buffer.msgType != msgType || len(buffer.body) != length {
+			continue
+		}
+		buffer.add(offset, fragment)
+	}
+	return seqs
+}
+
+// pop removes and returns the complete message with the given sequence number.
+func (this *reassembler) pop(seq uint16) *handshakeMessage {
+	buffer, found := this.buffers[seq]
+	if !found || !buffer.complete() {
+		return nil
+	}
+	delete(this.buffers, seq)
+	return &handshakeMessage{
+		msgType: buffer.msgType,
+		seq:     seq,
+		body:    buffer.body,
+	}
+}
+
+// popAny removes and returns any complete message of the given type, regardless of its sequence number.
+func (this *reassembler) popAny(msgType byte) *handshakeMessage {
+	for seq, buffer := range this.buffers {
+		if buffer.msgType == msgType && buffer.complete() {
+			return this.pop(seq)
+		}
+	}
+	return nil
+}
+
+func (this *reassembler) reset() {
+	this.buffers = make(map[uint16]*fragmentBuffer)
+}
+
+// replayWindow drops application records that were received before, as RFC 6347 section 4.1.2.6.
+type replayWindow struct {
+	latest uint64
+	bitmap uint64
+}
+
+func (this *replayWindow) accept(seq uint64) bool {
+	if seq > this.latest {
+		shift := seq - this.latest
+		if shift >= 64 {
+			this.bitmap = 1
+		} else {
+			this.bitmap = this.bitmap<<shift | 1
+		}
+		this.latest = seq
+		return true
+	}
+	diff := this.latest - seq
+	if diff >= 64 {
+		return false
+	}
+	mask := uint64(1) << diff
+	if this.bitmap&mask != 0 {
+		return false
+	}
+	this.bitmap |= mask
+	return true
+}
+
+func putUint24(b []byte, v int) {
+	b[0] = byte(v >> 16)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v)
+}
+
+func uint24(b []byte) int {
+	return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+}
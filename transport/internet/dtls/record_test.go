@@ -0,0 +1,54 @@
+package dtls
+
+import (
+	"bytes"
+	"testing"
+
+	"v2ray.com/core/testing/assert"
+)
+
+func TestReassembleOverlappingFragments(t *testing.T) {
+	assert := assert.On(t)
+
+	body := []byte("0123456789abcdef")
+	r := newReassembler()
+
+	var payload []byte
+	payload = append(payload, marshalHandshakeFragment(11, len(body), 1, 0, body[0:6])...)
+	payload = append(payload, marshalHandshakeFragment(11, len(body), 1, 4, body[4:10])...)
+	r.add(payload, 0)
+	assert.Pointer(r.pop(1)).IsNil()
+
+	// A retransmission fragmented differently overlaps both fragments, and covers the same bytes twice.
+	r.add(marshalHandshakeFragment(11, len(body), 1, 2, body[2:12]), 0)
+	assert.Pointer(r.pop(1)).IsNil()
+
+	r.add(marshalHandshakeFragment(11, len(body), 1, 8, body[8:]), 0)
+	message := r.pop(1)
+	assert.Pointer(message).IsNotNil()
+	assert.Bool(bytes.Equal(message.body, body)).IsTrue()
+}
+
+func TestReassemblerBoundsMessages(t *testing.T) {
+	assert := assert.On(t)
+
+	r := newReassembler()
+
+	// A message longer than maxHandshakeMessageLen is not allocated.
+	r.add(marshalHandshakeFragment(11, 1<<24-1, 0, 0, []byte("abc")), 0)
+	assert.Int(len(r.buffers)).Equals(0)
+
+	// Neither is a message out of the window after the expected sequence number.
+	r.add(marshalHandshakeFragment(11, 3, maxHandshakeSeqWindow, 0, []byte("abc")), 0)
+	assert.Int(len(r.buffers)).Equals(0)
+
+	for seq := uint16(0); seq < maxHandshakeSeqWindow; seq++ {
+		r.add(marshalHandshakeFragment(11, 4, seq, 0, []byte("ab")), 0)
+	}
+	assert.Int(len(r.buffers)).Equals(maxHandshakeSeqWindow)
+
+	// The number of incomplete messages is bounded even when the window moves.
+	r.add(marshalHandshakeFragment(11, 3, maxHandshakeSeqWindow, 0, []byte("abc")), 1)
+	assert.Int(len(r.buffers)).Equals(maxHandshakeSeqWindow)
+	assert.Pointer(r.pop(maxHandshakeSeqWindow)).IsNil()
+}
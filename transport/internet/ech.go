@@ -0,0 +1,15 @@
+// +build go1.23
+
+package internet
+
+import (
+	"crypto/tls"
+)
+
+const echSupported = true
+
+// applyECH enables Encrypted Client Hello with the given ECHConfigList. ECH is only available in TLS 1.3, which
+// TLSSettings.Validate ensures the versions allow.
+func applyECH(config *tls.Config, configList []byte) {
+	config.EncryptedClientHelloConfigList = configList
+}
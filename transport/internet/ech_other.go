@@ -0,0 +1,11 @@
+// +build !go1.23
+
+package internet
+
+import (
+	"crypto/tls"
+)
+
+const echSupported = false
+
+func applyECH(config *tls.Config, configList []byte) {}
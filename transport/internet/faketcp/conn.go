@@ -0,0 +1,185 @@
+package faketcp
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	handshakeAttempts = 3
+	handshakeTimeout  = time.Second * 2
+)
+
+// Conn is a client side fake TCP connection to a single remote endpoint.
+type Conn struct {
+	sync.Mutex
+	socket    *rawSocket
+	portFd    int
+	local     *net.TCPAddr
+	remote    *net.TCPAddr
+	seq       uint32
+	ack       uint32
+	recvBuf   []byte
+	closeOnce sync.Once
+}
+
+// DialFakeTCP connects to the IP of dest. The domain of dest, if any, has to be resolved by the caller, as
+// internet.Dial does with the resolver of the stream settings. Logs go to logger, which is nil for the default logger.
+func DialFakeTCP(src v2net.Address, dest v2net.Destination, logger *log.Logger) (*Conn, error) {
+	if dest.Address.Family().IsDomain() {
+		return nil, ErrDomainNotResolved
+	}
+	remoteIP := dest.Address.IP().To4()
+	if remoteIP == nil {
+		return nil, ErrIPv6NotSupported
+	}
+
+	var localIP net.IP
+	if src != nil && src != v2net.AnyIP && src.Family().IsIPv4() {
+		localIP = src.IP()
+	} else {
+		// Let the kernel pick the outgoing interface without sending anything.
+		probe, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: remoteIP, Port: int(dest.Port)})
+		if err != nil {
+			return nil, err
+		}
+		localIP = probe.LocalAddr().(*net.UDPAddr).IP.To4()
+		probe.Close()
+	}
+
+	socket, err := newRawSocket()
+	if err != nil {
+		return nil, err
+	}
+	portFd, localPort, err := reservePort(localIP)
+	if err != nil {
+		socket.Close()
+		return nil, err
+	}
+	conn := &Conn{
+		socket:  socket,
+		portFd:  portFd,
+		local:   &net.TCPAddr{IP: localIP, Port: int(localPort)},
+		remote:  &net.TCPAddr{IP: remoteIP, Port: int(dest.Port)},
+		seq:     uint32(dice.Roll(65536)) << 16,
+		recvBuf: make([]byte, 65536),
+	}
+	if err := conn.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	logger.Info("FakeTCP|Conn: Connected to ", dest, " from ", conn.local)
+	return conn, nil
+}
+
+func (this *Conn) send(flags byte, payload []byte) error {
+	this.Lock()
+	seg := &Segment{
+		SrcPort: uint16(this.local.Port),
+		DstPort: uint16(this.remote.Port),
+		Seq:     this.seq,
+		Ack:     this.ack,
+		Flags:   flags,
+		Payload: payload,
+	}
+	this.seq += uint32(len(payload))
+	if flags&(flagSYN|flagFIN) != 0 {
+		this.seq++
+	}
+	this.Unlock()
+
+	return this.socket.Send(seg.Marshal(nil, this.local.IP, this.remote.IP), this.remote.IP)
+}
+
+// receive returns the next segment from the remote endpoint, or nil if nothing arrived before the socket timed out.
+func (this *Conn) receive() (*Segment, error) {
+	nBytes, err := this.socket.Recv(this.recvBuf)
+	if err != nil {
+		return nil, err
+	}
+	if nBytes == 0 {
+		return nil, nil
+	}
+	src, dst, seg, err := ParseIPv4Packet(this.recvBuf[:nBytes])
+	if err != nil {
+		return nil, nil
+	}
+	if !src.Equal(this.remote.IP) || !dst.Equal(this.local.IP) ||
+		int(seg.SrcPort) != this.remote.Port || int(seg.DstPort) != this.local.Port {
+		return nil, nil
+	}
+	return seg, nil
+}
+
+func (this *Conn) handshake() error {
+	isn := this.seq
+	for attempt := 0; attempt < handshakeAttempts; attempt++ {
+		this.seq = isn
+		if err := this.send(flagSYN, nil); err != nil {
+			return err
+		}
+		deadline := time.Now().Add(handshakeTimeout)
+		for time.Now().Before(deadline) {
+			seg, err := this.receive()
+			if err != nil {
+				return err
+			}
+			if seg == nil || !seg.Has(flagSYN|flagACK) || seg.Ack != isn+1 {
+				continue
+			}
+			this.Lock()
+			this.ack = seg.Seq + 1
+			this.Unlock()
+			return this.send(flagACK, nil)
+		}
+	}
+	return ErrHandshakeTimeout
+}
+
+func (this *Conn) Read(b []byte) (int, error) {
+	for {
+		seg, err := this.receive()
+		if err == ErrClosed {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		if seg == nil || seg.Has(flagRST) || len(seg.Payload) == 0 {
+			continue
+		}
+		this.Lock()
+		this.ack = seg.Seq + uint32(len(seg.Payload))
+		this.Unlock()
+		return copy(b, seg.Payload), nil
+	}
+}
+
+func (this *Conn) Write(b []byte) (int, error) {
+	if err := this.send(flagPSH|flagACK, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (this *Conn) Close() error {
+	this.closeOnce.Do(func() {
+		this.socket.Close()
+		releasePort(this.portFd)
+	})
+	return nil
+}
+
+func (this *Conn) LocalAddr() net.Addr {
+	return this.local
+}
+
+func (this *Conn) RemoteAddr() net.Addr {
+	return this.remote
+}
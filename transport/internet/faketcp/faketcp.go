@@ -0,0 +1,131 @@
+// Package faketcp carries datagrams in hand-crafted TCP segments over raw sockets.
+//
+// It is used as the packet layer of mKCP in networks that throttle or block UDP.
+// There is no real TCP state machine behind the segments: after a SYN / SYN-ACK / ACK
+// exchange, every datagram is sent as a single PSH-ACK segment and reliability is left
+// to mKCP.
+//
+// Raw sockets require root or CAP_NET_RAW, and only IPv4 on Linux is supported.
+// The kernel does not know about the fake connections and answers the segments with RST,
+// which must be dropped by the firewall on both ends, for example:
+//
+//	Server: iptables -I OUTPUT -p tcp --sport <server port> --tcp-flags RST RST -j DROP
+//	Client: iptables -I OUTPUT -p tcp --dport <server port> --tcp-flags RST RST -j DROP
+package faketcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	flagFIN = 0x01
+	flagSYN = 0x02
+	flagRST = 0x04
+	flagPSH = 0x08
+	flagACK = 0x10
+
+	headerSize = 20
+)
+
+var (
+	ErrNotSupported      = errors.New("FakeTCP: Raw sockets are not supported on this platform.")
+	ErrIPv6NotSupported  = errors.New("FakeTCP: IPv6 is not supported.")
+	ErrDomainNotResolved = errors.New("FakeTCP: Domain of destination is not resolved.")
+	ErrClosed            = errors.New("FakeTCP: Connection closed.")
+	ErrHandshakeTimeout  = errors.New("FakeTCP: Handshake timed out.")
+	ErrInvalidPacket     = errors.New("FakeTCP: Invalid packet.")
+)
+
+// Segment is a TCP segment without options.
+type Segment struct {
+	SrcPort uint16
+	DstPort uint16
+	Seq     uint32
+	Ack     uint32
+	Flags   byte
+	Payload []byte
+}
+
+func (this *Segment) Has(flag byte) bool {
+	return this.Flags&flag == flag
+}
+
+// Marshal appends the wire format of the segment to b, with checksum computed for the given IPv4 addresses.
+func (this *Segment) Marshal(b []byte, src net.IP, dst net.IP) []byte {
+	start := len(b)
+	b = append(b, make([]byte, headerSize)...)
+	header := b[start:]
+	binary.BigEndian.PutUint16(header[0:], this.SrcPort)
+	binary.BigEndian.PutUint16(header[2:], this.DstPort)
+	binary.BigEndian.PutUint32(header[4:], this.Seq)
+	binary.BigEndian.PutUint32(header[8:], this.Ack)
+	header[12] = (headerSize / 4) << 4
+	header[13] = this.Flags
+	binary.BigEndian.PutUint16(header[14:], 65535)
+
+	b = append(b, this.Payload...)
+	sum := checksum(src, dst, b[start:])
+	binary.BigEndian.PutUint16(b[start+16:], sum)
+	return b
+}
+
+// ParseIPv4Packet parses an IPv4 packet carrying a TCP segment, as received from a raw socket.
+func ParseIPv4Packet(b []byte) (src net.IP, dst net.IP, seg *Segment, err error) {
+	if len(b) < 20 || b[0]>>4 != 4 || b[9] != 6 {
+		return nil, nil, nil, ErrInvalidPacket
+	}
+	ihl := int(b[0]&0x0f) * 4
+	totalLen := int(binary.BigEndian.Uint16(b[2:]))
+	if totalLen > len(b) || totalLen < ihl+headerSize {
+		return nil, nil, nil, ErrInvalidPacket
+	}
+	src = net.IP(b[12:16])
+	dst = net.IP(b[16:20])
+	tcp := b[ihl:totalLen]
+	dataOffset := int(tcp[12]>>4) * 4
+	if dataOffset < headerSize || dataOffset > len(tcp) {
+		return nil, nil, nil, ErrInvalidPacket
+	}
+	seg = &Segment{
+		SrcPort: binary.BigEndian.Uint16(tcp[0:]),
+		DstPort: binary.BigEndian.Uint16(tcp[2:]),
+		Seq:     binary.BigEndian.Uint32(tcp[4:]),
+		Ack:     binary.BigEndian.Uint32(tcp[8:]),
+		Flags:   tcp[13],
+		Payload: tcp[dataOffset:],
+	}
+	return src, dst, seg, nil
+}
+
+func checksum(src net.IP, dst net.IP, tcp []byte) uint16 {
+	var sum uint32
+	add := func(b []byte) {
+		for len(b) >= 2 {
+			sum += uint32(b[0])<<8 | uint32(b[1])
+			b = b[2:]
+		}
+		if len(b) == 1 {
+			sum += uint32(b[0]) << 8
+		}
+	}
+	add(src.To4())
+	add(dst.To4())
+	sum += 6 + uint32(len(tcp))
+
+	// The checksum field itself is treated as zero.
+	add(tcp[:16])
+	add(tcp[18:])
+
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+func init() {
+	internet.RegisterCapabilityChecker(internet.CapabilityRawSocket, checkRawSocket)
+}
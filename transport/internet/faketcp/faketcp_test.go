@@ -0,0 +1,107 @@
+package faketcp_test
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/faketcp"
+)
+
+func TestSegmentMarshalAndParse(t *testing.T) {
+	assert := assert.On(t)
+
+	src := net.IPv4(10, 0, 0, 1)
+	dst := net.IPv4(10, 0, 0, 2)
+	seg := &Segment{
+		SrcPort: 12345,
+		DstPort: 443,
+		Seq:     1000,
+		Ack:     2000,
+		Flags:   0x18,
+		Payload: []byte("abcde"),
+	}
+
+	packet := make([]byte, 20)
+	packet[0] = 0x45
+	packet[9] = 6
+	copy(packet[12:], src.To4())
+	copy(packet[16:], dst.To4())
+	packet = seg.Marshal(packet, src, dst)
+	binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)))
+
+	parsedSrc, parsedDst, parsed, err := ParseIPv4Packet(packet)
+	assert.Error(err).IsNil()
+	assert.IP(parsedSrc).Equals(src.To4())
+	assert.IP(parsedDst).Equals(dst.To4())
+	assert.Uint16(parsed.SrcPort).Equals(12345)
+	assert.Uint16(parsed.DstPort).Equals(443)
+	assert.Uint32(parsed.Seq).Equals(1000)
+	assert.Uint32(parsed.Ack).Equals(2000)
+	assert.Bytes(parsed.Payload).Equals([]byte("abcde"))
+
+	// Checksum of a valid segment, including the pseudo header, folds to zero.
+	var sum uint32
+	pseudo := append(append(append([]byte{}, src.To4()...), dst.To4()...), 0, 6, 0, byte(len(packet)-20))
+	data := append(pseudo, packet[20:]...)
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	for i := 0; i < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	assert.Uint32(sum).Equals(0xffff)
+}
+
+func TestInvalidPacket(t *testing.T) {
+	assert := assert.On(t)
+
+	_, _, _, err := ParseIPv4Packet([]byte{0x45, 0, 0})
+	assert.Error(err).Equals(ErrInvalidPacket)
+}
+
+func TestLoopback(t *testing.T) {
+	assert := assert.On(t)
+
+	received := make(chan string, 1)
+	var hub *Hub
+	var err error
+	hub, err = ListenFakeTCP(v2net.LocalHostIP, v2net.Port(18374), func(payload *alloc.Buffer, session *proxy.SessionInfo) {
+		defer payload.Release()
+		hub.WriteTo(payload.Value, session.Source)
+	}, nil)
+	if err != nil {
+		t.Skip("Raw socket is not available: ", err)
+	}
+	defer hub.Close()
+
+	conn, err := DialFakeTCP(nil, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(18374)), nil)
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	go func() {
+		b := make([]byte, 64)
+		nBytes, err := conn.Read(b)
+		if err == nil {
+			received <- string(b[:nBytes])
+		}
+	}()
+
+	_, err = conn.Write([]byte("fake tcp"))
+	assert.Error(err).IsNil()
+
+	select {
+	case s := <-received:
+		assert.String(s).Equals("fake tcp")
+	case <-time.After(time.Second * 5):
+		t.Error("Timed out waiting for echo.")
+	}
+}
@@ -0,0 +1,223 @@
+package faketcp
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/task"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet/udp"
+)
+
+var (
+	// PeerTimeout is how long the state of a peer is kept without receiving any segment from it.
+	PeerTimeout = time.Minute * 2
+	// MaxPeers limits the peers of a hub. Once reached, the least recently active peer is evicted for a new one.
+	MaxPeers = 4096
+)
+
+type peer struct {
+	local  net.IP
+	seq    uint32
+	ack    uint32
+	active time.Time
+}
+
+// Hub receives fake TCP segments on a port and hands their payload to a callback, like udp.UDPHub.
+type Hub struct {
+	sync.Mutex
+	socket   *rawSocket
+	address  net.IP
+	port     uint16
+	peers    map[string]*peer
+	callback udp.UDPPayloadHandler
+	cleanup  *task.Periodic
+	logger   *log.Logger
+}
+
+// ListenFakeTCP listens on the IPv4 address and port. Logs go to logger, which is nil for the default logger.
+func ListenFakeTCP(address v2net.Address, port v2net.Port, callback udp.UDPPayloadHandler, logger *log.Logger) (*Hub, error) {
+	if !address.Family().IsIPv4() {
+		return nil, ErrIPv6NotSupported
+	}
+	socket, err := newRawSocket()
+	if err != nil {
+		return nil, err
+	}
+	hub := &Hub{
+		socket:   socket,
+		address:  address.IP(),
+		port:     uint16(port),
+		peers:    make(map[string]*peer),
+		callback: callback,
+		logger:   logger,
+	}
+	hub.cleanup = &task.Periodic{
+		Name:     "FakeTCP peer cleanup",
+		Interval: PeerTimeout / 2,
+		Execute:  hub.removeIdlePeers,
+	}
+	hub.cleanup.Start()
+	go hub.start()
+	logger.Info("FakeTCP|Hub: Listening on ", address, ":", port)
+	return hub, nil
+}
+
+func (this *Hub) start() {
+	b := make([]byte, 65536)
+	for {
+		nBytes, err := this.socket.Recv(b)
+		if err == ErrClosed {
+			return
+		}
+		if err != nil {
+			this.logger.Warning("FakeTCP|Hub: Failed to read packet: ", err)
+			return
+		}
+		if nBytes == 0 {
+			continue
+		}
+		src, dst, seg, err := ParseIPv4Packet(b[:nBytes])
+		if err != nil || seg.DstPort != this.port {
+			continue
+		}
+		if !this.address.IsUnspecified() && !this.address.Equal(dst) {
+			continue
+		}
+		this.handleSegment(src, dst, seg)
+	}
+}
+
+func (this *Hub) handleSegment(src net.IP, dst net.IP, seg *Segment) {
+	if seg.Has(flagRST) {
+		return
+	}
+	source := v2net.TCPDestination(v2net.IPAddress(src), v2net.Port(seg.SrcPort))
+	id := source.NetAddr()
+
+	this.Lock()
+	p, found := this.peers[id]
+	if !found || seg.Has(flagSYN) {
+		p = &peer{
+			local: append(net.IP(nil), dst...),
+			seq:   uint32(dice.Roll(65536)) << 16,
+		}
+		this.addPeer(id, p)
+	}
+	p.active = time.Now()
+	p.ack = seg.Seq + uint32(len(seg.Payload))
+	if seg.Has(flagSYN) {
+		p.ack++
+		synAck := &Segment{
+			SrcPort: this.port,
+			DstPort: seg.SrcPort,
+			Seq:     p.seq,
+			Ack:     p.ack,
+			Flags:   flagSYN | flagACK,
+		}
+		p.seq++
+		this.Unlock()
+		if err := this.socket.Send(synAck.Marshal(nil, dst, src), src); err != nil {
+			this.logger.Info("FakeTCP|Hub: Failed to send SYN-ACK to ", source, ": ", err)
+		}
+		return
+	}
+	this.Unlock()
+
+	if len(seg.Payload) == 0 {
+		return
+	}
+	payload := alloc.NewBuffer().Clear()
+	payload.Append(seg.Payload)
+	this.callback(payload, &proxy.SessionInfo{
+		Source: source,
+	})
+}
+
+func (this *Hub) WriteTo(payload []byte, dest v2net.Destination) (int, error) {
+	if !dest.Address.Family().IsIPv4() {
+		return 0, ErrIPv6NotSupported
+	}
+	id := dest.NetAddr()
+	this.Lock()
+	p, found := this.peers[id]
+	if !found {
+		p = &peer{
+			local:  this.address,
+			seq:    uint32(dice.Roll(65536)) << 16,
+			active: time.Now(),
+		}
+		this.addPeer(id, p)
+	}
+	seg := &Segment{
+		SrcPort: this.port,
+		DstPort: uint16(dest.Port),
+		Seq:     p.seq,
+		Ack:     p.ack,
+		Flags:   flagPSH | flagACK,
+		Payload: payload,
+	}
+	p.seq += uint32(len(payload))
+	local := p.local
+	this.Unlock()
+
+	if err := this.socket.Send(seg.Marshal(nil, local, dest.Address.IP()), dest.Address.IP()); err != nil {
+		return 0, err
+	}
+	return len(payload), nil
+}
+
+// addPeer adds a peer, and evicts the least recently active one if the hub is full. It must be called with the hub
+// locked.
+func (this *Hub) addPeer(id string, p *peer) {
+	if _, found := this.peers[id]; !found && len(this.peers) >= MaxPeers {
+		var oldestID string
+		var oldest *peer
+		for key, value := range this.peers {
+			if oldest == nil || value.active.Before(oldest.active) {
+				oldestID, oldest = key, value
+			}
+		}
+		delete(this.peers, oldestID)
+	}
+	this.peers[id] = p
+}
+
+// removeIdlePeers forgets the peers that have sent nothing for PeerTimeout, such as clients gone away or spoofed SYNs.
+func (this *Hub) removeIdlePeers() error {
+	this.Lock()
+	defer this.Unlock()
+
+	expire := time.Now().Add(-PeerTimeout)
+	for id, p := range this.peers {
+		if p.active.Before(expire) {
+			delete(this.peers, id)
+		}
+	}
+	return nil
+}
+
+// Remove forgets the state of the given peer.
+func (this *Hub) Remove(dest v2net.Destination) {
+	this.Lock()
+	defer this.Unlock()
+
+	delete(this.peers, dest.NetAddr())
+}
+
+func (this *Hub) Addr() net.Addr {
+	return &net.TCPAddr{
+		IP:   this.address,
+		Port: int(this.port),
+	}
+}
+
+func (this *Hub) Close() {
+	this.cleanup.Close()
+	this.socket.Close()
+}
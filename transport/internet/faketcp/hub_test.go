@@ -0,0 +1,69 @@
+package faketcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+)
+
+func newTestHub() *Hub {
+	return &Hub{
+		address: net.IPv4(127, 0, 0, 1),
+		port:    443,
+		peers:   make(map[string]*peer),
+		callback: func(payload *alloc.Buffer, session *proxy.SessionInfo) {
+			payload.Release()
+		},
+	}
+}
+
+func sendData(hub *Hub, srcPort uint16) {
+	hub.handleSegment(net.IPv4(10, 0, 0, 1), net.IPv4(127, 0, 0, 1), &Segment{
+		SrcPort: srcPort,
+		DstPort: 443,
+		Flags:   flagPSH | flagACK,
+		Payload: []byte("data"),
+	})
+}
+
+func TestHubEvictsIdlePeers(t *testing.T) {
+	assert := assert.On(t)
+
+	hub := newTestHub()
+	sendData(hub, 10001)
+	sendData(hub, 10002)
+	assert.Int(len(hub.peers)).Equals(2)
+
+	hub.peers["10.0.0.1:10001"].active = time.Now().Add(-PeerTimeout - time.Second)
+	assert.Error(hub.removeIdlePeers()).IsNil()
+	assert.Int(len(hub.peers)).Equals(1)
+	_, found := hub.peers["10.0.0.1:10002"]
+	assert.Bool(found).IsTrue()
+}
+
+func TestHubLimitsPeers(t *testing.T) {
+	assert := assert.On(t)
+
+	maxPeers := MaxPeers
+	MaxPeers = 2
+	defer func() {
+		MaxPeers = maxPeers
+	}()
+
+	hub := newTestHub()
+	sendData(hub, 10001)
+	hub.peers["10.0.0.1:10001"].active = time.Now().Add(-time.Second)
+	sendData(hub, 10002)
+	sendData(hub, 10003)
+	assert.Int(len(hub.peers)).Equals(2)
+	_, found := hub.peers["10.0.0.1:10001"]
+	assert.Bool(found).IsFalse()
+
+	// Known peers are not evicted by their own segments.
+	sendData(hub, 10003)
+	assert.Int(len(hub.peers)).Equals(2)
+}
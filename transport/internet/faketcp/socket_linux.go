@@ -0,0 +1,95 @@
+// +build linux
+
+package faketcp
+
+import (
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+)
+
+type rawSocket struct {
+	fd     int
+	closed int32
+}
+
+func newRawSocket() (*rawSocket, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.IPPROTO_TCP)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	// Wakes up the reader periodically so that Close() takes effect.
+	timeout := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	return &rawSocket{fd: fd}, nil
+}
+
+// Recv reads an IPv4 packet. It returns zero bytes without error when the read times out.
+func (this *rawSocket) Recv(b []byte) (int, error) {
+	for {
+		if atomic.LoadInt32(&this.closed) == 1 {
+			return 0, ErrClosed
+		}
+		nBytes, _, err := syscall.Recvfrom(this.fd, b, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, os.NewSyscallError("recvfrom", err)
+		}
+		return nBytes, nil
+	}
+}
+
+func (this *rawSocket) Send(b []byte, dst net.IP) error {
+	addr := &syscall.SockaddrInet4{}
+	copy(addr.Addr[:], dst.To4())
+	if err := syscall.Sendto(this.fd, b, 0, addr); err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+	return nil
+}
+
+func (this *rawSocket) Close() error {
+	if !atomic.CompareAndSwapInt32(&this.closed, 0, 1) {
+		return ErrClosed
+	}
+	return syscall.Close(this.fd)
+}
+
+// reservePort binds a TCP socket without listening on it, so that the kernel does not hand out the same port to others.
+func reservePort(ip net.IP) (int, uint16, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, 0, os.NewSyscallError("socket", err)
+	}
+	addr := &syscall.SockaddrInet4{}
+	copy(addr.Addr[:], ip.To4())
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return -1, 0, os.NewSyscallError("bind", err)
+	}
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		syscall.Close(fd)
+		return -1, 0, os.NewSyscallError("getsockname", err)
+	}
+	return fd, uint16(sa.(*syscall.SockaddrInet4).Port), nil
+}
+
+func releasePort(fd int) {
+	syscall.Close(fd)
+}
+
+// checkRawSocket opens and closes a raw socket, to check whether the process is privileged to do so.
+func checkRawSocket() error {
+	socket, err := newRawSocket()
+	if err != nil {
+		return err
+	}
+	return socket.Close()
+}
@@ -0,0 +1,35 @@
+// +build !linux
+
+package faketcp
+
+import (
+	"net"
+)
+
+type rawSocket struct{}
+
+func newRawSocket() (*rawSocket, error) {
+	return nil, ErrNotSupported
+}
+
+func (this *rawSocket) Recv(b []byte) (int, error) {
+	return 0, ErrNotSupported
+}
+
+func (this *rawSocket) Send(b []byte, dst net.IP) error {
+	return ErrNotSupported
+}
+
+func (this *rawSocket) Close() error {
+	return ErrNotSupported
+}
+
+func reservePort(ip net.IP) (int, uint16, error) {
+	return -1, 0, ErrNotSupported
+}
+
+func releasePort(fd int) {}
+
+func checkRawSocket() error {
+	return ErrNotSupported
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"net"
+	"os"
+	"sync"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+// ActivatedSocket is a socket bound by the service manager and passed to V2Ray, e.g. by systemd socket activation.
+type ActivatedSocket struct {
+	File *os.File
+	Addr net.Addr
+}
+
+func (this *ActivatedSocket) matches(network string, address v2net.Address, port v2net.Port) bool {
+	var ip net.IP
+	var socketPort int
+	switch addr := this.Addr.(type) {
+	case *net.TCPAddr:
+		if network != "tcp" {
+			return false
+		}
+		ip, socketPort = addr.IP, addr.Port
+	case *net.UDPAddr:
+		if network != "udp" {
+			return false
+		}
+		ip, socketPort = addr.IP, addr.Port
+	default:
+		return false
+	}
+	if socketPort != int(port) {
+		return false
+	}
+	if address == nil || address.Family().IsDomain() {
+		return false
+	}
+	if address.IP().IsUnspecified() {
+		return ip.IsUnspecified()
+	}
+	return ip.Equal(address.IP())
+}
+
+var (
+	activatedSockets     []*ActivatedSocket
+	activatedSocketsOnce sync.Once
+)
+
+func getActivatedSockets() []*ActivatedSocket {
+	activatedSocketsOnce.Do(func() {
+		activatedSockets = loadActivatedSockets()
+		for _, socket := range activatedSockets {
+			log.Info("Internet: Received socket on ", socket.Addr, " from the service manager.")
+		}
+	})
+	return activatedSockets
+}
+
+// FindActivatedSocket returns the activated socket of the network on the address and port, or nil if there is none.
+func FindActivatedSocket(sockets []*ActivatedSocket, network string, address v2net.Address, port v2net.Port) *ActivatedSocket {
+	for _, socket := range sockets {
+		if socket.matches(network, address, port) {
+			return socket
+		}
+	}
+	return nil
+}
+
+// HasActivatedSocket returns true if the service manager passed a socket of the network on the address and port.
+func HasActivatedSocket(network string, address v2net.Address, port v2net.Port) bool {
+	return FindActivatedSocket(getActivatedSockets(), network, address, port) != nil
+}
+
+// ActivatedTCPListener returns a listener on the activated TCP socket of the address and port, or nil if there is
+// none. The socket is duplicated, so that a new listener can be created after the previous one is closed.
+func ActivatedTCPListener(address v2net.Address, port v2net.Port) (*net.TCPListener, error) {
+	socket := FindActivatedSocket(getActivatedSockets(), "tcp", address, port)
+	if socket == nil {
+		return nil, nil
+	}
+	listener, err := net.FileListener(socket.File)
+	if err != nil {
+		return nil, err
+	}
+	return listener.(*net.TCPListener), nil
+}
+
+// ActivatedUDPConn returns a connection on the activated UDP socket of the address and port, or nil if there is none.
+func ActivatedUDPConn(address v2net.Address, port v2net.Port) (*net.UDPConn, error) {
+	socket := FindActivatedSocket(getActivatedSockets(), "udp", address, port)
+	if socket == nil {
+		return nil, nil
+	}
+	conn, err := net.FilePacketConn(socket.File)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
@@ -0,0 +1,73 @@
+// +build linux
+
+package internal
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	// listenFdsStart is the first file descriptor passed by systemd socket activation.
+	listenFdsStart = 3
+)
+
+// loadActivatedSockets returns the sockets passed by systemd socket activation, as described in sd_listen_fds(3).
+// The environment variables are removed, so that child processes don't take the sockets as theirs.
+func loadActivatedSockets() []*ActivatedSocket {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	var sockets []*ActivatedSocket
+	for idx := 0; idx < count; idx++ {
+		fd := listenFdsStart + idx
+		syscall.CloseOnExec(fd)
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if idx < len(names) && len(names[idx]) > 0 {
+			name = names[idx]
+		}
+		file := os.NewFile(uintptr(fd), name)
+		sockType, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+		if err != nil {
+			log.Warning("Internet: Ignored activated file ", name, " that is not a socket.")
+			continue
+		}
+		var addr net.Addr
+		switch sockType {
+		case syscall.SOCK_STREAM:
+			if listener, err := net.FileListener(file); err == nil {
+				addr = listener.Addr()
+				listener.Close()
+			}
+		case syscall.SOCK_DGRAM:
+			if conn, err := net.FilePacketConn(file); err == nil {
+				addr = conn.LocalAddr()
+				conn.Close()
+			}
+		}
+		if addr == nil {
+			log.Warning("Internet: Ignored activated socket ", name, " that is neither a TCP listener nor a UDP socket.")
+			continue
+		}
+		sockets = append(sockets, &ActivatedSocket{
+			File: file,
+			Addr: addr,
+		})
+	}
+	return sockets
+}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package internal
+
+// loadActivatedSockets returns nil, as socket activation is only supported with systemd.
+func loadActivatedSockets() []*ActivatedSocket {
+	return nil
+}
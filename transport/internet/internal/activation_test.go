@@ -0,0 +1,26 @@
+package internal_test
+
+import (
+	"net"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/internal"
+)
+
+func TestFindActivatedSocket(t *testing.T) {
+	assert := assert.On(t)
+
+	sockets := []*ActivatedSocket{
+		{Addr: &net.TCPAddr{IP: net.IPv6unspecified, Port: 443}},
+		{Addr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53}},
+	}
+
+	assert.Pointer(FindActivatedSocket(sockets, "tcp", v2net.AnyIP, 443)).Equals(sockets[0])
+	assert.Pointer(FindActivatedSocket(sockets, "tcp", v2net.LocalHostIP, 443)).IsNil()
+	assert.Pointer(FindActivatedSocket(sockets, "udp", v2net.AnyIP, 443)).IsNil()
+	assert.Pointer(FindActivatedSocket(sockets, "udp", v2net.LocalHostIP, 53)).Equals(sockets[1])
+	assert.Pointer(FindActivatedSocket(sockets, "tcp", v2net.LocalHostIP, 53)).IsNil()
+	assert.Pointer(FindActivatedSocket(sockets, "udp", v2net.LocalHostIP, 54)).IsNil()
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"net"
+	"time"
+)
+
+const (
+	AddressCheckInterval = time.Second * 10
+)
+
+// Backoff retries a failed bind with exponentially growing delays.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+var (
+	DefaultBackoff = Backoff{
+		Initial: time.Second,
+		Max:     time.Minute,
+	}
+)
+
+// Retry calls bind until it succeeds, or running returns false. It returns true if bind succeeded.
+func (this Backoff) Retry(bind func() error, running func() bool) bool {
+	delay := this.Initial
+	for running() {
+		if err := bind(); err == nil {
+			return true
+		}
+		time.Sleep(delay)
+		delay *= 2
+		if delay > this.Max {
+			delay = this.Max
+		}
+	}
+	return false
+}
+
+// HasLocalAddress returns true if the given IP is assigned to one of the local interfaces. Unspecified addresses
+// are always available.
+func HasLocalAddress(ip net.IP) bool {
+	if ip == nil || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return true
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchAddress checks the given IP periodically while running returns true, and calls rebind when the IP comes back
+// after being removed from local interfaces, e.g. when a VPN reconnects.
+func WatchAddress(ip net.IP, running func() bool, onRemoved func(), rebind func()) {
+	if ip == nil || ip.IsUnspecified() {
+		return
+	}
+	present := true
+	for {
+		time.Sleep(AddressCheckInterval)
+		if !running() {
+			return
+		}
+		available := HasLocalAddress(ip)
+		switch {
+		case present && !available:
+			onRemoved()
+		case !present && available:
+			rebind()
+		}
+		present = available
+	}
+}
@@ -0,0 +1,54 @@
+package internal_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/internal"
+)
+
+func TestBackoffRetry(t *testing.T) {
+	assert := assert.On(t)
+
+	backoff := Backoff{
+		Initial: time.Millisecond,
+		Max:     time.Millisecond * 4,
+	}
+	attempts := 0
+	ok := backoff.Retry(func() error {
+		attempts++
+		if attempts < 5 {
+			return errors.New("bind failed")
+		}
+		return nil
+	}, func() bool { return true })
+	assert.Bool(ok).IsTrue()
+	assert.Int(attempts).Equals(5)
+}
+
+func TestBackoffStopsWhenClosed(t *testing.T) {
+	assert := assert.On(t)
+
+	backoff := Backoff{
+		Initial: time.Millisecond,
+		Max:     time.Millisecond,
+	}
+	attempts := 0
+	ok := backoff.Retry(func() error {
+		attempts++
+		return errors.New("bind failed")
+	}, func() bool { return attempts < 3 })
+	assert.Bool(ok).IsFalse()
+	assert.Int(attempts).Equals(3)
+}
+
+func TestHasLocalAddress(t *testing.T) {
+	assert := assert.On(t)
+
+	assert.Bool(HasLocalAddress(net.IPv4(127, 0, 0, 1))).IsTrue()
+	assert.Bool(HasLocalAddress(net.IPv4zero)).IsTrue()
+	assert.Bool(HasLocalAddress(net.IPv4(192, 0, 2, 123))).IsFalse()
+}
@@ -104,6 +104,7 @@ type Config struct {
 	WriteBuffer      *WriteBuffer                                       `protobuf:"bytes,6,opt,name=write_buffer,json=writeBuffer" json:"write_buffer,omitempty"`
 	ReadBuffer       *ReadBuffer                                        `protobuf:"bytes,7,opt,name=read_buffer,json=readBuffer" json:"read_buffer,omitempty"`
 	HeaderConfig     *v2ray_core_transport_internet.AuthenticatorConfig `protobuf:"bytes,8,opt,name=header_config,json=headerConfig" json:"header_config,omitempty"`
+	FakeTcp          bool                                               `protobuf:"varint,9,opt,name=fake_tcp,json=fakeTcp" json:"fake_tcp,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -173,31 +174,32 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/transport/internet/kcp/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 411 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x93, 0x51, 0xab, 0xda, 0x30,
-	0x1c, 0xc5, 0x71, 0xf5, 0xba, 0xcb, 0xbf, 0xf7, 0xde, 0xb9, 0xb2, 0x87, 0xb2, 0xc1, 0x50, 0x61,
-	0xe2, 0xcb, 0x52, 0x56, 0x19, 0x6c, 0x8f, 0xd3, 0xbd, 0xf8, 0xe0, 0xd8, 0x42, 0x45, 0xf0, 0xc5,
-	0xc5, 0x34, 0x6a, 0xa8, 0x26, 0x25, 0xa6, 0x8a, 0xfb, 0xa0, 0xfb, 0x3c, 0xa3, 0xa9, 0x9d, 0x55,
-	0xf0, 0xb6, 0x6f, 0x4d, 0x73, 0xce, 0x2f, 0xed, 0x39, 0xf9, 0x83, 0xbf, 0xf7, 0x15, 0x39, 0x22,
-	0x2a, 0xb7, 0x1e, 0x95, 0x8a, 0x79, 0x5a, 0x11, 0xb1, 0x8b, 0xa5, 0xd2, 0x1e, 0x17, 0x9a, 0x29,
-	0xc1, 0xb4, 0x17, 0xd1, 0xd8, 0xa3, 0x52, 0x2c, 0xf9, 0x0a, 0xc5, 0x4a, 0x6a, 0xe9, 0xb4, 0x73,
-	0x8f, 0x62, 0xe8, 0xbf, 0x1e, 0xe5, 0x7a, 0x14, 0xd1, 0xf8, 0xed, 0xe7, 0x72, 0x2c, 0x49, 0xf4,
-	0x9a, 0x09, 0xcd, 0x29, 0xd1, 0x52, 0x65, 0xe4, 0xce, 0x3b, 0xb0, 0xc6, 0xc1, 0xc4, 0x79, 0x03,
-	0x77, 0x7b, 0xb2, 0x49, 0x98, 0x5b, 0x6b, 0xd5, 0x7a, 0x8f, 0x38, 0x5b, 0xa4, 0x9b, 0x41, 0x30,
-	0xba, 0xb1, 0xd9, 0x85, 0xa7, 0x49, 0xbc, 0xe1, 0x22, 0x1a, 0x92, 0x98, 0x50, 0xae, 0x8f, 0x37,
-	0x74, 0x3d, 0x68, 0x7e, 0x97, 0x07, 0x51, 0x41, 0xd9, 0x06, 0x7b, 0xaa, 0xb8, 0x66, 0x83, 0x64,
-	0xb9, 0x64, 0xca, 0x71, 0xa0, 0xbe, 0xe3, 0x7f, 0x72, 0x8d, 0x79, 0xee, 0xb4, 0x00, 0x30, 0x23,
-	0xe1, 0x33, 0x8a, 0xbf, 0x75, 0x68, 0x0c, 0x4d, 0x76, 0xce, 0x17, 0xb0, 0xb6, 0x3a, 0x31, 0xbb,
-	0xb6, 0xdf, 0x45, 0xa5, 0x19, 0xa2, 0x71, 0x30, 0xc1, 0xa9, 0x25, 0x75, 0x6a, 0xcd, 0xdd, 0x17,
-	0x95, 0x9d, 0x41, 0x30, 0xc2, 0xa9, 0xc5, 0x99, 0xc1, 0xab, 0xc4, 0xa4, 0x32, 0xa7, 0xa7, 0x9f,
-	0x75, 0x2d, 0x43, 0xf9, 0x54, 0x81, 0x72, 0x99, 0x27, 0x7e, 0x4a, 0x2e, 0xf3, 0xfd, 0x0d, 0xaf,
-	0xc3, 0x53, 0x92, 0x67, 0x7a, 0xdd, 0xd0, 0xfb, 0x15, 0xe8, 0xd7, 0x2d, 0xe0, 0x66, 0x78, 0xdd,
-	0xcb, 0x7b, 0x00, 0x2a, 0xc5, 0x8a, 0xed, 0x34, 0x97, 0xc2, 0xbd, 0x6b, 0xd5, 0x7a, 0xf7, 0xb8,
-	0xf0, 0xc6, 0xf9, 0x05, 0x0f, 0x87, 0xb4, 0xa1, 0xf9, 0xc2, 0x14, 0xe0, 0x36, 0xcc, 0xe1, 0xa8,
-	0xc2, 0xe1, 0x85, 0x62, 0xb1, 0x7d, 0x28, 0xb4, 0xfc, 0x03, 0x6c, 0xc5, 0x48, 0x98, 0x13, 0x5f,
-	0x1a, 0xe2, 0xc7, 0x0a, 0xc4, 0xf3, 0x3d, 0xc0, 0xa0, 0xce, 0x77, 0x62, 0x0a, 0x8f, 0x6b, 0x46,
-	0x42, 0xa6, 0xe6, 0xd9, 0x04, 0xb9, 0xf7, 0x86, 0xe8, 0x97, 0x10, 0xbf, 0x15, 0x67, 0x23, 0xbb,
-	0x3f, 0xf8, 0x21, 0x03, 0x65, 0xab, 0xc1, 0x57, 0xf8, 0x40, 0xe5, 0xb6, 0xfc, 0xc3, 0x06, 0x76,
-	0x66, 0xf8, 0x99, 0xce, 0xd7, 0xcc, 0x8a, 0x68, 0xbc, 0x68, 0x98, 0x59, 0xeb, 0xff, 0x0b, 0x00,
-	0x00, 0xff, 0xff, 0xe8, 0xba, 0x79, 0x48, 0xfb, 0x03, 0x00, 0x00,
+	// 428 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x93, 0x5f, 0x6f, 0xd3, 0x30,
+	0x14, 0xc5, 0x55, 0xd2, 0x75, 0xe5, 0x66, 0x1b, 0xc3, 0xe2, 0x21, 0x80, 0x84, 0xba, 0x49, 0x4c,
+	0x7d, 0xc1, 0x11, 0x99, 0x90, 0xe0, 0x91, 0x8e, 0x97, 0x3d, 0x0c, 0x81, 0x95, 0x6a, 0xd2, 0x5e,
+	0x82, 0xe7, 0x38, 0x9b, 0x95, 0xd5, 0xb6, 0x5c, 0xa7, 0x55, 0xf9, 0xd6, 0x7c, 0x03, 0x14, 0xa7,
+	0xe9, 0x3f, 0xa9, 0x4b, 0xde, 0x7c, 0x7b, 0xcf, 0xfd, 0xb9, 0x39, 0xc7, 0x17, 0xa2, 0x59, 0x64,
+	0xe8, 0x02, 0x33, 0x35, 0x09, 0x99, 0x32, 0x3c, 0xb4, 0x86, 0xca, 0xa9, 0x56, 0xc6, 0x86, 0x42,
+	0x5a, 0x6e, 0x24, 0xb7, 0x61, 0xce, 0x74, 0xc8, 0x94, 0xcc, 0xc4, 0x03, 0xd6, 0x46, 0x59, 0x85,
+	0xce, 0xea, 0x19, 0xc3, 0xf1, 0x4a, 0x8f, 0x6b, 0x3d, 0xce, 0x99, 0x7e, 0xf7, 0xa5, 0x19, 0x4b,
+	0x0b, 0xfb, 0xc8, 0xa5, 0x15, 0x8c, 0x5a, 0x65, 0x2a, 0xf2, 0xf9, 0x7b, 0xf0, 0x6e, 0xe2, 0x31,
+	0x7a, 0x03, 0x07, 0x33, 0xfa, 0x54, 0xf0, 0xa0, 0x33, 0xe8, 0x0c, 0x8f, 0x49, 0x55, 0x94, 0xcd,
+	0x38, 0xbe, 0xde, 0xd3, 0xbc, 0x80, 0x93, 0xb1, 0x7e, 0x12, 0x32, 0xbf, 0xa2, 0x9a, 0x32, 0x61,
+	0x17, 0x7b, 0x74, 0x43, 0x38, 0xfd, 0xa1, 0xe6, 0xb2, 0x85, 0xf2, 0x0c, 0xfc, 0x5b, 0x23, 0x2c,
+	0x1f, 0x15, 0x59, 0xc6, 0x0d, 0x42, 0xd0, 0x9d, 0x8a, 0xbf, 0xb5, 0xc6, 0x9d, 0xcf, 0x07, 0x00,
+	0x84, 0xd3, 0xf4, 0x19, 0xc5, 0xbf, 0x2e, 0xf4, 0xae, 0x9c, 0x77, 0xe8, 0x2b, 0x78, 0x13, 0x5b,
+	0xb8, 0xae, 0x1f, 0x5d, 0xe0, 0x46, 0x0f, 0xf1, 0x4d, 0x3c, 0x26, 0xe5, 0x48, 0x39, 0x69, 0xad,
+	0x08, 0x5e, 0xb4, 0x9e, 0x8c, 0xe3, 0x6b, 0x52, 0x8e, 0xa0, 0x3b, 0x78, 0x55, 0x38, 0x57, 0x12,
+	0xb6, 0xfc, 0xd8, 0xc0, 0x73, 0x94, 0xcf, 0x2d, 0x28, 0xdb, 0x7e, 0x92, 0x93, 0x62, 0xdb, 0xdf,
+	0x3f, 0xf0, 0x3a, 0x5d, 0x3a, 0xb9, 0xa6, 0x77, 0x1d, 0xfd, 0xb2, 0x05, 0x7d, 0x37, 0x05, 0x72,
+	0x9a, 0xee, 0xe6, 0xf2, 0x01, 0x80, 0x29, 0xf9, 0xc0, 0xa7, 0x56, 0x28, 0x19, 0x1c, 0x0c, 0x3a,
+	0xc3, 0x3e, 0xd9, 0xf8, 0x05, 0xfd, 0x86, 0xa3, 0x79, 0x99, 0x50, 0x72, 0xef, 0x02, 0x08, 0x7a,
+	0xee, 0x72, 0xdc, 0xe2, 0xf2, 0x8d, 0x60, 0x89, 0x3f, 0x5f, 0x17, 0xe8, 0x27, 0xf8, 0x86, 0xd3,
+	0xb4, 0x26, 0x1e, 0x3a, 0xe2, 0xa7, 0x16, 0xc4, 0xf5, 0x3b, 0x20, 0x60, 0x56, 0x67, 0x74, 0x0b,
+	0xc7, 0x8f, 0x9c, 0xa6, 0xdc, 0x24, 0xd5, 0x06, 0x05, 0x7d, 0x47, 0x8c, 0x1a, 0x88, 0xdf, 0x37,
+	0x77, 0xa3, 0x7a, 0x3f, 0xe4, 0xa8, 0x02, 0x55, 0x15, 0x7a, 0x0b, 0xfd, 0x8c, 0xe6, 0x3c, 0xb1,
+	0x4c, 0x07, 0x2f, 0x9d, 0x33, 0x87, 0x65, 0x1d, 0x33, 0x3d, 0xfa, 0x06, 0x1f, 0x99, 0x9a, 0x34,
+	0xff, 0xe7, 0x91, 0x5f, 0xb1, 0x7e, 0x95, 0xab, 0x77, 0xe7, 0xe5, 0x4c, 0xdf, 0xf7, 0xdc, 0x1a,
+	0x5e, 0xfe, 0x1f, 0x00, 0xc0, 0x27, 0x88, 0x43, 0x16, 0x04, 0x00, 0x00,
 }
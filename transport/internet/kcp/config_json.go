@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 
 	"v2ray.com/core/common"
+	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/transport/internet"
+
+	"github.com/golang/protobuf/ptypes"
 )
 
 func (this *Config) UnmarshalJSON(data []byte) error {
@@ -20,9 +23,10 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		ReadBufferSize  *uint32         `json:"readBufferSize"`
 		WriteBufferSize *uint32         `json:"writeBufferSize"`
 		HeaderConfig    json.RawMessage `json:"header"`
+		FakeTCP         *bool           `json:"faketcp"`
 	}
 	jsonConfig := new(JSONConfig)
-	if err := json.Unmarshal(data, &jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, &jsonConfig); err != nil {
 		return err
 	}
 	if jsonConfig.Mtu != nil {
@@ -66,6 +70,9 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 			this.WriteBuffer = &WriteBuffer{Size: 512 * 1024}
 		}
 	}
+	if jsonConfig.FakeTCP != nil {
+		this.FakeTcp = *jsonConfig.FakeTCP
+	}
 	if len(jsonConfig.HeaderConfig) > 0 {
 		name, config, err := internet.CreateAuthenticatorConfig(jsonConfig.HeaderConfig)
 		if err != nil {
@@ -82,3 +89,60 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON. Buffer sizes are rounded down to MB.
+func (this *Config) MarshalJSON() ([]byte, error) {
+	type JSONConfig struct {
+		Mtu             *uint32                `json:"mtu,omitempty"`
+		Tti             *uint32                `json:"tti,omitempty"`
+		UpCap           *uint32                `json:"uplinkCapacity,omitempty"`
+		DownCap         *uint32                `json:"downlinkCapacity,omitempty"`
+		Congestion      bool                   `json:"congestion,omitempty"`
+		ReadBufferSize  *uint32                `json:"readBufferSize,omitempty"`
+		WriteBufferSize *uint32                `json:"writeBufferSize,omitempty"`
+		HeaderConfig    map[string]interface{} `json:"header,omitempty"`
+		FakeTCP         bool                   `json:"faketcp,omitempty"`
+	}
+	jsonConfig := &JSONConfig{
+		Congestion: this.Congestion,
+		FakeTCP:    this.FakeTcp,
+	}
+	if this.Mtu != nil {
+		jsonConfig.Mtu = &this.Mtu.Value
+	}
+	if this.Tti != nil {
+		jsonConfig.Tti = &this.Tti.Value
+	}
+	if this.UplinkCapacity != nil {
+		jsonConfig.UpCap = &this.UplinkCapacity.Value
+	}
+	if this.DownlinkCapacity != nil {
+		jsonConfig.DownCap = &this.DownlinkCapacity.Value
+	}
+	if this.ReadBuffer != nil {
+		size := this.ReadBuffer.Size / 1024 / 1024
+		jsonConfig.ReadBufferSize = &size
+	}
+	if this.WriteBuffer != nil {
+		size := this.WriteBuffer.Size / 1024 / 1024
+		jsonConfig.WriteBufferSize = &size
+	}
+	if this.HeaderConfig != nil {
+		jsonConfig.HeaderConfig = make(map[string]interface{})
+		if this.HeaderConfig.Settings != nil {
+			settings := new(ptypes.DynamicAny)
+			if err := ptypes.UnmarshalAny(this.HeaderConfig.Settings, settings); err != nil {
+				return nil, err
+			}
+			rawSettings, err := json.Marshal(settings.Message)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(rawSettings, &jsonConfig.HeaderConfig); err != nil {
+				return nil, err
+			}
+		}
+		jsonConfig.HeaderConfig["type"] = this.HeaderConfig.Name
+	}
+	return json.Marshal(jsonConfig)
+}
@@ -1,40 +1,85 @@
 package kcp
 
 import (
+	"crypto/tls"
+	"io"
 	"net"
-	"sync/atomic"
 
 	"v2ray.com/core/common/dice"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/dtls"
+	"v2ray.com/core/transport/internet/faketcp"
 )
 
-var (
-	globalConv = uint32(dice.Roll(65536))
-)
+type packetConn interface {
+	io.ReadWriteCloser
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+}
+
+// toUDPAddr converts the address of the underlying packet connection, which may be a fake TCP one.
+func toUDPAddr(addr net.Addr) *net.UDPAddr {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr
+	case *net.TCPAddr:
+		return &net.UDPAddr{
+			IP:   addr.IP,
+			Port: addr.Port,
+		}
+	default:
+		return nil
+	}
+}
 
 func DialKCP(src v2net.Address, dest v2net.Destination) (internet.Connection, error) {
+	return dialKCP(src, dest, nil, nil)
+}
+
+// DialKCPWithDTLS dials a KCP connection whose datagrams are secured by DTLS.
+func DialKCPWithDTLS(src v2net.Address, dest v2net.Destination, config *tls.Config) (internet.Connection, error) {
+	return dialKCP(src, dest, config, nil)
+}
+
+func dialKCP(src v2net.Address, dest v2net.Destination, tlsConfig *tls.Config, logger *log.Logger) (internet.Connection, error) {
 	dest.Network = v2net.Network_UDP
-	log.Info("KCP|Dialer: Dialing KCP to ", dest)
-	conn, err := internet.DialToDest(src, dest)
+	logger.Info("KCP|Dialer: Dialing KCP to ", dest)
+	var conn packetConn
+	var err error
+	if effectiveConfig.FakeTcp {
+		conn, err = faketcp.DialFakeTCP(src, dest, logger)
+	} else {
+		conn, err = internet.DialToDest(src, dest)
+	}
 	if err != nil {
-		log.Error("KCP|Dialer: Failed to dial to dest: ", err)
+		logger.Error("KCP|Dialer: Failed to dial to dest: ", err)
 		return nil, err
 	}
+	if tlsConfig != nil {
+		dtlsConn := dtls.Client(conn, tlsConfig)
+		if err := dtlsConn.Handshake(); err != nil {
+			logger.Error("KCP|Dialer: DTLS handshake with ", dest, " failed: ", err)
+			dtlsConn.Close()
+			return nil, err
+		}
+		conn = dtlsConn
+	}
 
 	cpip, err := effectiveConfig.GetAuthenticator()
 	if err != nil {
-		log.Error("KCP|Dialer: Failed to create authenticator: ", err)
+		logger.Error("KCP|Dialer: Failed to create authenticator: ", err)
 		return nil, err
 	}
-	conv := uint16(atomic.AddUint32(&globalConv, 1))
-	session := NewConnection(conv, conn, conn.LocalAddr().(*net.UDPAddr), conn.RemoteAddr().(*net.UDPAddr), cpip)
+	// Every dial has a socket of its own, so the conversation only needs to be unpredictable, not unique in the process.
+	conv := uint16(dice.Roll(65536))
+	session := NewConnection(conv, conn, toUDPAddr(conn.LocalAddr()), toUDPAddr(conn.RemoteAddr()), cpip)
 	session.FetchInputFrom(conn)
 
 	return session, nil
 }
 
 func init() {
-	internet.KCPDialer = DialKCP
+	internet.KCPDialer = dialKCP
 }
@@ -1,8 +1,14 @@
 package kcp_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"io"
+	"math/big"
 	"net"
 	"sync"
 	"testing"
@@ -16,7 +22,7 @@ import (
 func TestDialAndListen(t *testing.T) {
 	assert := assert.On(t)
 
-	listerner, err := NewListener(v2net.LocalHostIP, v2net.Port(0))
+	listerner, err := NewListener(v2net.LocalHostIP, v2net.Port(0), nil)
 	assert.Error(err).IsNil()
 	port := v2net.Port(listerner.Addr().(*net.UDPAddr).Port)
 
@@ -75,3 +81,49 @@ func TestDialAndListen(t *testing.T) {
 
 	listerner.Close()
 }
+
+func TestDialAndListenWithDTLS(t *testing.T) {
+	assert := assert.On(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Error(err).IsNil()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "WebRTC"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.Error(err).IsNil()
+
+	listerner, err := NewListener(v2net.LocalHostIP, v2net.Port(0), &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	assert.Error(err).IsNil()
+	defer listerner.Close()
+	port := v2net.Port(listerner.Addr().(*net.UDPAddr).Port)
+
+	go func() {
+		conn, err := listerner.Accept()
+		if err != nil {
+			return
+		}
+		io.Copy(conn, conn)
+		conn.Close()
+	}()
+
+	clientConn, err := DialKCPWithDTLS(v2net.LocalHostIP, v2net.UDPDestination(v2net.LocalHostIP, port), &tls.Config{
+		InsecureSkipVerify: true,
+	})
+	assert.Error(err).IsNil()
+	defer clientConn.Close()
+
+	clientSend := make([]byte, 64*1024)
+	rand.Read(clientSend)
+	go clientConn.Write(clientSend)
+
+	clientReceived := make([]byte, 64*1024)
+	nBytes, _ := io.ReadFull(clientConn, clientReceived)
+	assert.Int(nBytes).Equals(len(clientReceived))
+	assert.Bytes(clientReceived).Equals(clientSend)
+}
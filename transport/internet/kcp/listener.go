@@ -1,6 +1,7 @@
 package kcp
 
 import (
+	"crypto/tls"
 	"net"
 	"sync"
 	"time"
@@ -11,9 +12,18 @@ import (
 	"v2ray.com/core/common/serial"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/dtls"
+	"v2ray.com/core/transport/internet/faketcp"
 	"v2ray.com/core/transport/internet/udp"
 )
 
+// packetHub is the underlying transport of a Listener, either a UDP hub or a fake TCP one, optionally secured by DTLS.
+type packetHub interface {
+	WriteTo(payload []byte, dest v2net.Destination) (int, error)
+	Addr() net.Addr
+	Close()
+}
+
 // Listener defines a server listening for connections
 type Listener struct {
 	sync.Mutex
@@ -21,10 +31,16 @@ type Listener struct {
 	authenticator internet.Authenticator
 	sessions      map[string]*Connection
 	awaitingConns chan *Connection
-	hub           *udp.UDPHub
+	hub           packetHub
+	logger        *log.Logger
 }
 
-func NewListener(address v2net.Address, port v2net.Port) (*Listener, error) {
+// NewListener creates a KCP listener. If tlsConfig is not nil, datagrams are secured by DTLS.
+func NewListener(address v2net.Address, port v2net.Port, tlsConfig *tls.Config) (*Listener, error) {
+	return newListener(address, port, tlsConfig, nil)
+}
+
+func newListener(address v2net.Address, port v2net.Port, tlsConfig *tls.Config, logger *log.Logger) (*Listener, error) {
 	auth, err := effectiveConfig.GetAuthenticator()
 	if err != nil {
 		return nil, err
@@ -34,13 +50,32 @@ func NewListener(address v2net.Address, port v2net.Port) (*Listener, error) {
 		sessions:      make(map[string]*Connection),
 		awaitingConns: make(chan *Connection, 64),
 		running:       true,
+		logger:        logger,
 	}
-	hub, err := udp.ListenUDP(address, port, udp.ListenOption{Callback: l.OnReceive})
-	if err != nil {
-		return nil, err
+	callback := l.OnReceive
+	var dtlsHub *dtls.Hub
+	if tlsConfig != nil {
+		dtlsHub = dtls.NewHub(tlsConfig, l.OnReceive)
+		callback = dtlsHub.OnReceive
 	}
-	l.hub = hub
-	log.Info("KCP|Listener: listening on ", address, ":", port)
+	if effectiveConfig.FakeTcp {
+		hub, err := faketcp.ListenFakeTCP(address, port, callback, logger)
+		if err != nil {
+			return nil, err
+		}
+		l.hub = hub
+	} else {
+		hub, err := udp.ListenUDP(address, port, udp.ListenOption{Callback: callback})
+		if err != nil {
+			return nil, err
+		}
+		l.hub = hub
+	}
+	if dtlsHub != nil {
+		dtlsHub.Start(l.hub)
+		l.hub = dtlsHub
+	}
+	logger.Info("KCP|Listener: listening on ", address, ":", port)
 	return l, nil
 }
 
@@ -50,7 +85,7 @@ func (this *Listener) OnReceive(payload *alloc.Buffer, session *proxy.SessionInf
 	src := session.Source
 
 	if valid := this.authenticator.Open(payload); !valid {
-		log.Info("KCP|Listener: discarding invalid payload from ", src)
+		this.logger.Info("KCP|Listener: discarding invalid payload from ", src)
 		return
 	}
 	if !this.running {
@@ -72,7 +107,7 @@ func (this *Listener) OnReceive(payload *alloc.Buffer, session *proxy.SessionInf
 		if cmd == CommandTerminate {
 			return
 		}
-		log.Debug("KCP|Listener: Creating session with id(", sourceId, ") from ", src)
+		this.logger.Debug("KCP|Listener: Creating session with id(", sourceId, ") from ", src)
 		writer := &Writer{
 			id:       sourceId,
 			hub:      this.hub,
@@ -85,9 +120,9 @@ func (this *Listener) OnReceive(payload *alloc.Buffer, session *proxy.SessionInf
 		}
 		auth, err := effectiveConfig.GetAuthenticator()
 		if err != nil {
-			log.Error("KCP|Listener: Failed to create authenticator: ", err)
+			this.logger.Error("KCP|Listener: Failed to create authenticator: ", err)
 		}
-		conn = NewConnection(conv, writer, this.Addr().(*net.UDPAddr), srcAddr, auth)
+		conn = NewConnection(conv, writer, toUDPAddr(this.Addr()), srcAddr, auth)
 		select {
 		case this.awaitingConns <- conn:
 		case <-time.After(time.Second * 5):
@@ -108,7 +143,7 @@ func (this *Listener) Remove(dest string) {
 	if !this.running {
 		return
 	}
-	log.Debug("KCP|Listener: Removing session ", dest)
+	this.logger.Debug("KCP|Listener: Removing session ", dest)
 	delete(this.sessions, dest)
 }
 
@@ -160,7 +195,7 @@ func (this *Listener) Addr() net.Addr {
 type Writer struct {
 	id       string
 	dest     v2net.Destination
-	hub      *udp.UDPHub
+	hub      packetHub
 	listener *Listener
 }
 
@@ -170,13 +205,27 @@ func (this *Writer) Write(payload []byte) (int, error) {
 
 func (this *Writer) Close() error {
 	this.listener.Remove(this.id)
+	switch hub := this.hub.(type) {
+	case *faketcp.Hub:
+		hub.Remove(this.dest)
+	case *dtls.Hub:
+		hub.Remove(this.dest)
+	}
 	return nil
 }
 
 func ListenKCP(address v2net.Address, port v2net.Port) (internet.Listener, error) {
-	return NewListener(address, port)
+	return NewListener(address, port, nil)
+}
+
+func ListenKCPWithDTLS(address v2net.Address, port v2net.Port, config *tls.Config) (internet.Listener, error) {
+	return NewListener(address, port, config)
+}
+
+func listenKCP(address v2net.Address, port v2net.Port, config *tls.Config, logger *log.Logger) (internet.Listener, error) {
+	return newListener(address, port, config, logger)
 }
 
 func init() {
-	internet.KCPListenFunc = ListenKCP
+	internet.KCPListenFunc = listenKCP
 }
@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// EnvAddress is the environment variable of the address that processes started by V2Ray listen on.
+	EnvAddress = "V2RAY_PLUGIN_ADDRESS"
+
+	DefaultStartTimeout = time.Second * 5
+)
+
+// Config is the settings of a transport plugin of an outbound.
+type Config struct {
+	// Address is where the plugin listens, in host:port form on loopback, or "unix:" followed by the path of a Unix
+	// socket.
+	Address string
+	// Command starts the plugin, if it is not started separately. It is run with EnvAddress in its environment, and
+	// V2Ray waits up to StartTimeout for it to listen.
+	Command string
+	Args    []string
+	// StartTimeout is how long to wait for a started plugin to accept connections. Zero means DefaultStartTimeout.
+	StartTimeout time.Duration
+	// Options are sent to the plugin on each connection, e.g. settings of obfuscation. Their format is up to the
+	// plugin.
+	Options string
+}
+
+// Validate checks that Address is either host:port or a Unix socket.
+func (this *Config) Validate() error {
+	if len(this.Address) == 0 {
+		return errors.New("Plugin: Address is not specified.")
+	}
+	if !strings.HasPrefix(this.Address, "unix:") {
+		if _, _, err := net.SplitHostPort(this.Address); err != nil {
+			return errors.New("Plugin: Invalid address: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func (this *Config) network() (string, string) {
+	if strings.HasPrefix(this.Address, "unix:") {
+		return "unix", strings.TrimPrefix(this.Address, "unix:")
+	}
+	return "tcp", this.Address
+}
+
+func (this *Config) startTimeout() time.Duration {
+	if this.StartTimeout <= 0 {
+		return DefaultStartTimeout
+	}
+	return this.StartTimeout
+}
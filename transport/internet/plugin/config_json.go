@@ -0,0 +1,29 @@
+// +build json
+
+package plugin
+
+import (
+	"time"
+
+	"v2ray.com/core/common/loader"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Address      string   `json:"address"`
+		Command      string   `json:"command"`
+		Args         []string `json:"args"`
+		StartTimeout uint32   `json:"startTimeout"`
+		Options      string   `json:"options"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("Plugin: Failed to parse config: ", err)
+	}
+	this.Address = jsonConfig.Address
+	this.Command = jsonConfig.Command
+	this.Args = jsonConfig.Args
+	this.StartTimeout = time.Duration(jsonConfig.StartTimeout) * time.Second
+	this.Options = jsonConfig.Options
+	return this.Validate()
+}
@@ -0,0 +1,275 @@
+// Package plugin dials outbound connections through transports implemented by external processes, so that
+// experimental obfuscators can be developed without changing V2Ray.
+//
+// A plugin listens on a local socket. For each connection, V2Ray connects to the socket and sends a request:
+//
+//	version (1 byte, 1) | destination length (2 bytes) | destination | options length (2 bytes) | options
+//
+// where destination is the server in host:port form, and options are the options of the outbound. Lengths are in big
+// endian. The plugin replies:
+//
+//	version (1 byte, 1) | status (1 byte)
+//
+// with status 0 if it connected to the server, followed by the stream carried to the server. Otherwise, the status is
+// followed by a message of the error, in 2 bytes of length and the message, and the connection is closed.
+package plugin
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"reflect"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	Version = 1
+
+	StatusOK = 0
+
+	handshakeTimeout = time.Second * 16
+	retryInterval    = time.Millisecond * 100
+	maxFieldLength   = 0xffff
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("Plugin: Unsupported version.")
+	ErrFieldTooLong       = errors.New("Plugin: Destination or options are too long.")
+	ErrAddressInUse       = errors.New("Plugin: Address is used by another plugin.")
+)
+
+// process is a plugin started by V2Ray.
+type process struct {
+	command string
+	args    []string
+	proc    *os.Process
+	done    chan struct{}
+	// ready is the time by which the plugin is expected to listen.
+	ready time.Time
+	// users are the configs of the outbounds that dialed through the plugin. The plugin is stopped once all of them
+	// stop it.
+	users map[*Config]bool
+}
+
+func (this *process) running() bool {
+	select {
+	case <-this.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (this *process) runs(config *Config) bool {
+	return this.command == config.Command && reflect.DeepEqual(this.args, config.Args)
+}
+
+func (this *process) kill() {
+	if this.running() {
+		this.proc.Kill()
+		<-this.done
+	}
+}
+
+var (
+	processAccess sync.Mutex
+	// processes are the plugins started by V2Ray by their addresses. Outbounds of any instance with the same address
+	// and command share the process.
+	processes = make(map[string]*process)
+)
+
+// start runs the command of the config, unless it is already running. It returns the time by which the plugin is
+// expected to listen, which is in the past if the plugin is not started by V2Ray or has been running for long.
+func start(config *Config) (time.Time, error) {
+	if len(config.Command) == 0 {
+		return time.Time{}, nil
+	}
+
+	processAccess.Lock()
+	defer processAccess.Unlock()
+
+	p, found := processes[config.Address]
+	if found && !p.runs(config) {
+		log.Error("Plugin: ", config.Address, " is used by ", p.command, ", not ", config.Command)
+		return time.Time{}, ErrAddressInUse
+	}
+	if found && p.running() {
+		// The plugin may still be starting for a concurrent dial.
+		p.users[config] = true
+		return p.ready, nil
+	}
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = append(os.Environ(), EnvAddress+"="+config.Address)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		log.Error("Plugin: Failed to start ", config.Command, ": ", err)
+		return time.Time{}, err
+	}
+	users := make(map[*Config]bool)
+	if found {
+		// Restarts the plugin for its users, as it exited.
+		users = p.users
+	}
+	users[config] = true
+	p = &process{
+		command: config.Command,
+		args:    config.Args,
+		proc:    cmd.Process,
+		done:    make(chan struct{}),
+		ready:   time.Now().Add(config.startTimeout()),
+		users:   users,
+	}
+	go func() {
+		err := cmd.Wait()
+		log.Warning("Plugin: ", p.command, " exited: ", err)
+		close(p.done)
+	}()
+	processes[config.Address] = p
+	log.Info("Plugin: Started ", config.Command, " on ", config.Address)
+	return p.ready, nil
+}
+
+// Stop stops using the plugin of the config, and kills it if it is started by V2Ray and no other outbound uses it.
+func Stop(config *Config) {
+	processAccess.Lock()
+	p, found := processes[config.Address]
+	if !found || !p.users[config] {
+		processAccess.Unlock()
+		return
+	}
+	delete(p.users, config)
+	if len(p.users) > 0 {
+		processAccess.Unlock()
+		return
+	}
+	delete(processes, config.Address)
+	processAccess.Unlock()
+
+	p.kill()
+}
+
+// Dial connects to dest through the plugin of the config. The plugin is started first, if it has a command and is not
+// running.
+func Dial(config *Config, dest v2net.Destination) (net.Conn, error) {
+	ready, err := start(config)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connect(config, ready)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := handshake(conn, dest.NetAddr(), config.Options); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// connect connects to the socket of the plugin. A plugin still starting is given time until ready to listen.
+func connect(config *Config, ready time.Time) (net.Conn, error) {
+	network, address := config.network()
+	for {
+		conn, err := net.Dial(network, address)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(ready) {
+			log.Warning("Plugin: Failed to connect to ", config.Address, ": ", err)
+			return nil, err
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+func handshake(conn net.Conn, dest string, options string) error {
+	if len(dest) > maxFieldLength || len(options) > maxFieldLength {
+		return ErrFieldTooLong
+	}
+	request := make([]byte, 0, 1+2+len(dest)+2+len(options))
+	request = append(request, Version)
+	request = appendField(request, dest)
+	request = appendField(request, options)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	var response [2]byte
+	if _, err := io.ReadFull(conn, response[:]); err != nil {
+		return err
+	}
+	if response[0] != Version {
+		return ErrUnsupportedVersion
+	}
+	if response[1] == StatusOK {
+		return nil
+	}
+	message, err := readField(conn)
+	if err != nil {
+		return err
+	}
+	return errors.New("Plugin: Failed to connect to " + dest + ": " + message)
+}
+
+func appendField(b []byte, field string) []byte {
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(field)))
+	return append(append(b, length[:]...), field...)
+}
+
+func readField(reader io.Reader) (string, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		return "", err
+	}
+	field := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(reader, field); err != nil {
+		return "", err
+	}
+	return string(field), nil
+}
+
+// ReadRequest reads the request of a connection on the plugin side, and returns the destination and the options. It
+// helps plugins written in Go.
+func ReadRequest(reader io.Reader) (string, string, error) {
+	var version [1]byte
+	if _, err := io.ReadFull(reader, version[:]); err != nil {
+		return "", "", err
+	}
+	if version[0] != Version {
+		return "", "", ErrUnsupportedVersion
+	}
+	dest, err := readField(reader)
+	if err != nil {
+		return "", "", err
+	}
+	options, err := readField(reader)
+	if err != nil {
+		return "", "", err
+	}
+	return dest, options, nil
+}
+
+// WriteResponse replies to the request of a connection on the plugin side. A nil error means the server is connected.
+func WriteResponse(writer io.Writer, err error) error {
+	if err == nil {
+		_, e := writer.Write([]byte{Version, StatusOK})
+		return e
+	}
+	message := err.Error()
+	if len(message) > maxFieldLength {
+		message = message[:maxFieldLength]
+	}
+	_, e := writer.Write(appendField([]byte{Version, 1}, message))
+	return e
+}
@@ -0,0 +1,222 @@
+package plugin_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
+	. "v2ray.com/core/transport/internet/plugin"
+)
+
+// servePlugin serves connections as a plugin that connects to the destination directly, and rejects connections with
+// "reject" in options.
+func servePlugin(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			dest, options, err := ReadRequest(conn)
+			if err != nil {
+				return
+			}
+			if options == "reject" {
+				WriteResponse(conn, errors.New("rejected"))
+				return
+			}
+			server, err := net.Dial("tcp", dest)
+			if err != nil {
+				WriteResponse(conn, err)
+				return
+			}
+			defer server.Close()
+			if WriteResponse(conn, nil) != nil {
+				return
+			}
+			go io.Copy(server, conn)
+			io.Copy(conn, server)
+		}()
+	}
+}
+
+func newEchoServer(assert *assert.Assert) (*tcp.Server, v2net.Destination) {
+	server := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return data
+		},
+	}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	return server, dest
+}
+
+func assertEcho(assert *assert.Assert, conn net.Conn) {
+	_, err := conn.Write([]byte("plugin"))
+	assert.Error(err).IsNil()
+	response := make([]byte, 6)
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	assert.String(string(response)).Equals("plugin")
+}
+
+func TestDial(t *testing.T) {
+	assert := assert.On(t)
+
+	server, dest := newEchoServer(assert)
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go servePlugin(listener)
+
+	config := &Config{
+		Address: listener.Addr().String(),
+	}
+	conn, err := Dial(config, dest)
+	assert.Error(err).IsNil()
+	assertEcho(assert, conn)
+	conn.Close()
+
+	config.Options = "reject"
+	_, err = Dial(config, dest)
+	assert.Error(err).IsNotNil()
+	assert.String(err.Error()).Contains("rejected")
+}
+
+// TestHelperPlugin is the plugin process started by TestDialStartedPlugin.
+func TestHelperPlugin(t *testing.T) {
+	address := os.Getenv(EnvAddress)
+	if len(address) == 0 {
+		return
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		os.Exit(1)
+	}
+	servePlugin(listener)
+}
+
+func TestDialStartedPlugin(t *testing.T) {
+	assert := assert.On(t)
+
+	server, dest := newEchoServer(assert)
+	defer server.Close()
+
+	// Picks a free port for the plugin.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := &Config{
+		Address: address,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperPlugin"},
+	}
+	conn, err := Dial(config, dest)
+	assert.Error(err).IsNil()
+	assertEcho(assert, conn)
+	conn.Close()
+
+	Stop(config)
+	_, err = net.Dial("tcp", address)
+	assert.Error(err).IsNotNil()
+}
+
+func TestConcurrentDialsStartingPlugin(t *testing.T) {
+	assert := assert.On(t)
+
+	server, dest := newEchoServer(assert)
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := &Config{
+		Address: address,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperPlugin"},
+	}
+	defer Stop(config)
+
+	// Dials other than the one starting the plugin wait for it to listen as well.
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			conn, err := Dial(config, dest)
+			if err == nil {
+				conn.Close()
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		assert.Error(<-errs).IsNil()
+	}
+}
+
+func TestSharedPlugin(t *testing.T) {
+	assert := assert.On(t)
+
+	server, dest := newEchoServer(assert)
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	address := listener.Addr().String()
+	listener.Close()
+
+	config1 := &Config{
+		Address: address,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperPlugin"},
+	}
+	config2 := &Config{
+		Address: address,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperPlugin"},
+	}
+	for _, config := range []*Config{config1, config2} {
+		conn, err := Dial(config, dest)
+		assert.Error(err).IsNil()
+		assertEcho(assert, conn)
+		conn.Close()
+	}
+
+	// The address is taken by the running plugin.
+	changed := &Config{
+		Address: address,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperPlugin$"},
+	}
+	_, err = Dial(changed, dest)
+	assert.Error(err).Equals(ErrAddressInUse)
+
+	// The plugin keeps running for the other outbound.
+	Stop(config1)
+	conn, err := Dial(config2, dest)
+	assert.Error(err).IsNil()
+	assertEcho(assert, conn)
+	conn.Close()
+
+	Stop(config2)
+	_, err = net.Dial("tcp", address)
+	assert.Error(err).IsNotNil()
+
+	// The plugin is started with the changed command once the old one is stopped.
+	conn, err = Dial(changed, dest)
+	assert.Error(err).IsNil()
+	assertEcho(assert, conn)
+	conn.Close()
+	Stop(changed)
+}
@@ -0,0 +1,93 @@
+package reality
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	recordTypeHandshake      = 22
+	handshakeTypeClientHello = 1
+	extensionServerName      = 0
+	maxRecordSize            = 16384 + 2048
+)
+
+// readRecord reads a full TLS handshake record from reader.
+func readRecord(reader io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[3:]))
+	if header[0] != recordTypeHandshake || length > maxRecordSize {
+		return header, ErrInvalidClientHello
+	}
+	record := make([]byte, 5+length)
+	copy(record, header)
+	if _, err := io.ReadFull(reader, record[5:]); err != nil {
+		return record[:5], err
+	}
+	return record, nil
+}
+
+type clientHello struct {
+	random     []byte
+	sessionId  []byte
+	serverName string
+}
+
+// parseClientHello parses the fields of ClientHello used by REALITY, from a TLS record.
+func parseClientHello(record []byte) (*clientHello, error) {
+	if len(record) < 5+4+2+32+1 || record[5] != handshakeTypeClientHello {
+		return nil, ErrInvalidClientHello
+	}
+	b := record[5+4+2:]
+	hello := &clientHello{
+		random: b[:32],
+	}
+	b = b[32:]
+
+	sessionIdLen := int(b[0])
+	if len(b) < 1+sessionIdLen+2 {
+		return nil, ErrInvalidClientHello
+	}
+	hello.sessionId = b[1 : 1+sessionIdLen]
+	b = b[1+sessionIdLen:]
+
+	cipherSuitesLen := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+cipherSuitesLen+1 {
+		return nil, ErrInvalidClientHello
+	}
+	b = b[2+cipherSuitesLen:]
+
+	compressionLen := int(b[0])
+	if len(b) < 1+compressionLen+2 {
+		return nil, ErrInvalidClientHello
+	}
+	b = b[1+compressionLen:]
+
+	extensionsLen := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < extensionsLen {
+		return nil, ErrInvalidClientHello
+	}
+	b = b[:extensionsLen]
+	for len(b) >= 4 {
+		extType := binary.BigEndian.Uint16(b)
+		extLen := int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < 4+extLen {
+			return nil, ErrInvalidClientHello
+		}
+		ext := b[4 : 4+extLen]
+		b = b[4+extLen:]
+		if extType != extensionServerName || len(ext) < 5 {
+			continue
+		}
+		// server_name_list: list length, name type, name length, name
+		nameLen := int(binary.BigEndian.Uint16(ext[3:]))
+		if ext[2] == 0 && len(ext) >= 5+nameLen {
+			hello.serverName = string(ext[5 : 5+nameLen])
+		}
+	}
+	return hello, nil
+}
@@ -0,0 +1,94 @@
+package reality
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"time"
+)
+
+var (
+	ErrNotSupported       = errors.New("REALITY: Not supported by this build.")
+	ErrFallback           = errors.New("REALITY: Connection is forwarded to the fallback destination.")
+	ErrAuthFailed         = errors.New("REALITY: Server failed to authenticate.")
+	ErrInvalidClientHello = errors.New("REALITY: Invalid ClientHello.")
+)
+
+const (
+	DefaultMaxTimeDiff = time.Minute * 2
+)
+
+// Config is the settings of REALITY. Server and client use different fields.
+type Config struct {
+	// Dest is the address of a real TLS server, in host:port form. Connections that fail to authenticate are forwarded there.
+	Dest string
+	// ServerNames are the SNIs accepted from clients. They should be served by Dest.
+	ServerNames []string
+	// PrivateKey is the X25519 private key of the server.
+	PrivateKey []byte
+	// ShortIds are the accepted client IDs, up to 8 bytes each.
+	ShortIds [][]byte
+	// MaxTimeDiff is the maximum allowed difference between the clocks of client and server.
+	MaxTimeDiff time.Duration
+
+	// ServerName is the SNI sent by the client.
+	ServerName string
+	// PublicKey is the X25519 public key of the server.
+	PublicKey []byte
+	// ShortId is the ID of the client.
+	ShortId []byte
+}
+
+// Validate checks the server fields if PrivateKey is set, and the client fields if PublicKey is set.
+func (this *Config) Validate() error {
+	if len(this.PrivateKey) == 0 && len(this.PublicKey) == 0 {
+		return errors.New("REALITY: Either privateKey or publicKey must be specified.")
+	}
+	if len(this.PrivateKey) > 0 {
+		if len(this.PrivateKey) != 32 {
+			return errors.New("REALITY: Invalid private key: key must be 32 bytes")
+		}
+		if len(this.Dest) == 0 {
+			return errors.New("REALITY: Dest is not specified.")
+		}
+		if _, _, err := net.SplitHostPort(this.Dest); err != nil {
+			return errors.New("REALITY: Invalid dest: " + err.Error())
+		}
+		if len(this.ServerNames) == 0 {
+			return errors.New("REALITY: ServerNames are not specified.")
+		}
+		for _, shortId := range this.ShortIds {
+			if len(shortId) > 8 {
+				return errors.New("REALITY: Invalid short ID: " + hex.EncodeToString(shortId))
+			}
+		}
+	}
+	if len(this.PublicKey) > 0 {
+		if len(this.PublicKey) != 32 {
+			return errors.New("REALITY: Invalid public key: key must be 32 bytes")
+		}
+		if len(this.ServerName) == 0 {
+			return errors.New("REALITY: ServerName is not specified.")
+		}
+		if len(this.ShortId) > 8 {
+			return errors.New("REALITY: Invalid short ID: " + hex.EncodeToString(this.ShortId))
+		}
+	}
+	return nil
+}
+
+func (this *Config) GetMaxTimeDiff() time.Duration {
+	if this.MaxTimeDiff == 0 {
+		return DefaultMaxTimeDiff
+	}
+	return this.MaxTimeDiff
+}
+
+func (this *Config) HasServerName(name string) bool {
+	for _, serverName := range this.ServerNames {
+		if serverName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+// +build json
+
+package reality
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"v2ray.com/core/common/loader"
+)
+
+func (this *Config) UnmarshalJSON(data []byte) error {
+	type JsonConfig struct {
+		Dest        string   `json:"dest"`
+		ServerNames []string `json:"serverNames"`
+		PrivateKey  string   `json:"privateKey"`
+		ShortIds    []string `json:"shortIds"`
+		MaxTimeDiff uint32   `json:"maxTimeDiff"`
+		ServerName  string   `json:"serverName"`
+		PublicKey   string   `json:"publicKey"`
+		ShortId     string   `json:"shortId"`
+	}
+	jsonConfig := new(JsonConfig)
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
+		return loader.WrapError("REALITY: Failed to parse config: ", err)
+	}
+
+	if len(jsonConfig.PrivateKey) > 0 {
+		key, err := decodeKey(jsonConfig.PrivateKey)
+		if err != nil {
+			return errors.New("REALITY: Invalid private key: " + err.Error())
+		}
+		this.PrivateKey = key
+		this.Dest = jsonConfig.Dest
+		this.ServerNames = jsonConfig.ServerNames
+		this.ShortIds = make([][]byte, len(jsonConfig.ShortIds))
+		for idx, shortId := range jsonConfig.ShortIds {
+			id, err := decodeShortId(shortId)
+			if err != nil {
+				return err
+			}
+			this.ShortIds[idx] = id
+		}
+		this.MaxTimeDiff = time.Duration(jsonConfig.MaxTimeDiff) * time.Second
+	}
+
+	if len(jsonConfig.PublicKey) > 0 {
+		key, err := decodeKey(jsonConfig.PublicKey)
+		if err != nil {
+			return errors.New("REALITY: Invalid public key: " + err.Error())
+		}
+		id, err := decodeShortId(jsonConfig.ShortId)
+		if err != nil {
+			return err
+		}
+		this.PublicKey = key
+		this.ServerName = jsonConfig.ServerName
+		this.ShortId = id
+	}
+
+	return this.Validate()
+}
+
+// decodeKey decodes a 32-byte key in base64, with or without padding.
+func decodeKey(s string) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(s), "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, errors.New("key must be 32 bytes")
+	}
+	return key, nil
+}
+
+func decodeShortId(s string) ([]byte, error) {
+	id, err := hex.DecodeString(s)
+	if err != nil || len(id) > 8 {
+		return nil, errors.New("REALITY: Invalid short ID: " + s)
+	}
+	return id, nil
+}
@@ -0,0 +1,85 @@
+// +build go1.20
+
+package reality
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+)
+
+func TestReleaseServerContext(t *testing.T) {
+	assert := assert.On(t)
+
+	privateKey, _, err := GenerateKey()
+	assert.Error(err).IsNil()
+	config := &Config{
+		PrivateKey: privateKey,
+	}
+
+	Retain(config)
+	Retain(config)
+	context, err := getServerContext(config)
+	assert.Error(err).IsNil()
+	assert.Pointer(serverContexts[config]).Equals(context)
+
+	Release(config)
+	assert.Pointer(serverContexts[config]).Equals(context)
+
+	Release(config)
+	_, found := serverContexts[config]
+	assert.Bool(found).IsFalse()
+}
+
+func TestUnretainedServerContext(t *testing.T) {
+	assert := assert.On(t)
+
+	privateKey, _, err := GenerateKey()
+	assert.Error(err).IsNil()
+	config := &Config{
+		PrivateKey: privateKey,
+	}
+
+	// Connections of a config not retained share the certificate and the session IDs seen.
+	context, err := getServerContext(config)
+	assert.Error(err).IsNil()
+	again, err := getServerContext(config)
+	assert.Error(err).IsNil()
+	assert.Pointer(again).Equals(context)
+
+	Retain(config)
+	assert.Pointer(serverContexts[config]).Equals(context)
+	Release(config)
+	_, found := serverContexts[config]
+	assert.Bool(found).IsFalse()
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	assert := assert.On(t)
+
+	timeout := handshakeTimeout
+	handshakeTimeout = time.Millisecond * 100
+	defer func() {
+		handshakeTimeout = timeout
+	}()
+
+	privateKey, _, err := GenerateKey()
+	assert.Error(err).IsNil()
+	config := &Config{
+		PrivateKey: privateKey,
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	start := time.Now()
+	_, err = Server(server, config)
+	assert.Error(err).IsNotNil()
+	assert.Bool(time.Since(start) < time.Second).IsTrue()
+
+	_, err = client.Read(make([]byte, 1))
+	assert.Error(err).Equals(io.EOF)
+}
@@ -0,0 +1,404 @@
+// +build go1.20
+
+// Package reality implements a TLS camouflage, where probers see the handshake of a real website.
+//
+// The client puts an ephemeral X25519 public key in the random field of ClientHello, and an encrypted token
+// with timestamp and short ID in the session ID. The server derives the same key with its private key.
+// Clients that authenticate get a TLS 1.3 handshake with a temporary certificate whose signature is replaced
+// by an HMAC of the shared key, so the client can verify the server without any CA. Everything else is
+// forwarded byte by byte to the configured destination.
+package reality
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	tokenVersion = 1
+)
+
+var (
+	// handshakeTimeout limits the time a client may take to send its ClientHello and complete the handshake.
+	handshakeTimeout = time.Second * 16
+)
+
+// GenerateKey returns a new X25519 key pair.
+func GenerateKey() (privateKey []byte, publicKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key.Bytes(), key.PublicKey().Bytes(), nil
+}
+
+// PublicKey returns the X25519 public key of the given private key.
+func PublicKey(privateKey []byte) ([]byte, error) {
+	key, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return key.PublicKey().Bytes(), nil
+}
+
+func deriveAuthKey(shared []byte, random []byte) []byte {
+	hash := sha256.New()
+	hash.Write(shared)
+	hash.Write(random[:20])
+	return hash.Sum(nil)
+}
+
+func newTokenAEAD(authKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(authKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func certificateHMAC(authKey []byte, publicKey ed25519.PublicKey) []byte {
+	mac := hmac.New(sha512.New, authKey)
+	mac.Write(publicKey)
+	return mac.Sum(nil)
+}
+
+// helloRandom feeds the prepared random and session ID to crypto/tls, which reads them first when building ClientHello.
+type helloRandom struct {
+	prepared []byte
+}
+
+func (this *helloRandom) Read(b []byte) (int, error) {
+	if len(this.prepared) > 0 {
+		n := copy(b, this.prepared)
+		this.prepared = this.prepared[n:]
+		return n, nil
+	}
+	return rand.Read(b)
+}
+
+// Client performs a REALITY handshake on the given connection.
+func Client(conn net.Conn, config *Config) (*tls.Conn, error) {
+	serverKey, err := ecdh.X25519().NewPublicKey(config.PublicKey)
+	if err != nil {
+		return nil, errors.New("REALITY: Invalid public key: " + err.Error())
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(serverKey)
+	if err != nil {
+		return nil, err
+	}
+
+	random := ephemeral.PublicKey().Bytes()
+	// The highest bit of an X25519 public key is always zero. Randomize it, as it is ignored by the server.
+	var b [1]byte
+	rand.Read(b[:])
+	random[31] |= b[0] & 0x80
+	authKey := deriveAuthKey(shared, random)
+
+	token := make([]byte, 16)
+	token[0] = tokenVersion
+	binary.BigEndian.PutUint32(token[4:], uint32(time.Now().Unix()))
+	copy(token[8:], config.ShortId)
+	aead, err := newTokenAEAD(authKey)
+	if err != nil {
+		return nil, err
+	}
+	sessionId := aead.Seal(nil, random[20:32], token, nil)
+
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true,
+		Rand:               &helloRandom{prepared: append(random, sessionId...)},
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return ErrAuthFailed
+			}
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return ErrAuthFailed
+			}
+			publicKey, ok := cert.PublicKey.(ed25519.PublicKey)
+			if !ok || !hmac.Equal(cert.Signature, certificateHMAC(authKey, publicKey)) {
+				return ErrAuthFailed
+			}
+			return nil
+		},
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+type serverContext struct {
+	sync.Mutex
+	privateKey *ecdh.PrivateKey
+	certKey    ed25519.PrivateKey
+	certDER    []byte
+	refs       int
+	// seen are the session IDs of authenticated ClientHellos, until their timestamps are out of the allowed time
+	// difference.
+	seen  map[string]time.Time
+	swept time.Time
+}
+
+var (
+	serverContextAccess sync.Mutex
+	serverContexts      = make(map[*Config]*serverContext)
+)
+
+func newServerContext(config *Config) (*serverContext, error) {
+	privateKey, err := ecdh.X25519().NewPrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, errors.New("REALITY: Invalid private key: " + err.Error())
+	}
+	certPub, certKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour * 24 * 365),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, certPub, certKey)
+	if err != nil {
+		return nil, err
+	}
+	return &serverContext{
+		privateKey: privateKey,
+		certKey:    certKey,
+		certDER:    certDER,
+		seen:       make(map[string]time.Time),
+	}, nil
+}
+
+// Retain keeps the server context of the config, i.e. its certificate and the session IDs seen, for the connections
+// of a listener. Each call must be paired with a call to Release, once the listener closes.
+func Retain(config *Config) {
+	serverContextAccess.Lock()
+	defer serverContextAccess.Unlock()
+
+	if context, found := serverContexts[config]; found {
+		context.refs++
+		return
+	}
+	context, err := newServerContext(config)
+	if err != nil {
+		// Reported on each connection by Server.
+		return
+	}
+	context.refs = 1
+	serverContexts[config] = context
+}
+
+// Release releases the server context of the config retained by a listener. The context is removed once released by
+// all its listeners.
+func Release(config *Config) {
+	serverContextAccess.Lock()
+	defer serverContextAccess.Unlock()
+
+	context, found := serverContexts[config]
+	if !found {
+		return
+	}
+	context.refs--
+	if context.refs <= 0 {
+		delete(serverContexts, config)
+	}
+}
+
+// getServerContext returns the server context of the config. A config not retained by any listener gets its context
+// on the first connection, and keeps it for later connections, as generating the certificate is expensive.
+func getServerContext(config *Config) (*serverContext, error) {
+	serverContextAccess.Lock()
+	defer serverContextAccess.Unlock()
+
+	if context, found := serverContexts[config]; found {
+		return context, nil
+	}
+	context, err := newServerContext(config)
+	if err != nil {
+		return nil, err
+	}
+	serverContexts[config] = context
+	return context, nil
+}
+
+// markSeen records the session ID of an authenticated ClientHello until it expires. It returns false if the session ID
+// is seen before, i.e. the ClientHello is replayed.
+func (this *serverContext) markSeen(sessionId []byte, expire time.Time) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	now := time.Now()
+	if now.Sub(this.swept) > time.Minute {
+		for id, t := range this.seen {
+			if t.Before(now) {
+				delete(this.seen, id)
+			}
+		}
+		this.swept = now
+	}
+	id := string(sessionId)
+	if _, found := this.seen[id]; found {
+		return false
+	}
+	this.seen[id] = expire
+	return true
+}
+
+// authenticate returns the auth key if the ClientHello comes from a genuine client, and is not replayed.
+func (this *serverContext) authenticate(hello *clientHello, config *Config) ([]byte, bool) {
+	if len(hello.sessionId) != 32 || !config.HasServerName(hello.serverName) {
+		return nil, false
+	}
+	random := append([]byte(nil), hello.random...)
+	random[31] &= 0x7f
+	clientKey, err := ecdh.X25519().NewPublicKey(random)
+	if err != nil {
+		return nil, false
+	}
+	shared, err := this.privateKey.ECDH(clientKey)
+	if err != nil {
+		return nil, false
+	}
+	authKey := deriveAuthKey(shared, hello.random)
+	aead, err := newTokenAEAD(authKey)
+	if err != nil {
+		return nil, false
+	}
+	token, err := aead.Open(nil, hello.random[20:32], hello.sessionId, nil)
+	if err != nil || token[0] != tokenVersion {
+		return nil, false
+	}
+	timestamp := time.Unix(int64(binary.BigEndian.Uint32(token[4:])), 0)
+	diff := time.Since(timestamp)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > config.GetMaxTimeDiff() {
+		log.Info("REALITY: Client time differs by ", diff)
+		return nil, false
+	}
+	for _, shortId := range config.ShortIds {
+		if bytes.Equal(token[8:8+len(shortId)], shortId) && isZero(token[8+len(shortId):]) {
+			// Tokens are rejected after the max time difference anyway, so session IDs are kept no longer than that.
+			if !this.markSeen(hello.sessionId, timestamp.Add(config.GetMaxTimeDiff()+time.Second)) {
+				log.Info("REALITY: Replayed ClientHello.")
+				return nil, false
+			}
+			return authKey, true
+		}
+	}
+	return nil, false
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type prefixConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (this *prefixConn) Read(b []byte) (int, error) {
+	return this.reader.Read(b)
+}
+
+// Server performs a REALITY handshake on the given connection. If the client fails to authenticate, or replays the
+// ClientHello of another connection, the connection is forwarded to config.Dest, and ErrFallback is returned. Replays
+// are only detected for configs retained by listeners. Clients that stay silent are disconnected after a timeout.
+func Server(conn net.Conn, config *Config) (*tls.Conn, error) {
+	context, err := getServerContext(config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	record, err := readRecord(conn)
+	if err == nil {
+		hello, err := parseClientHello(record)
+		if err == nil {
+			if authKey, ok := context.authenticate(hello, config); ok {
+				certDER := append([]byte(nil), context.certDER...)
+				copy(certDER[len(certDER)-64:], certificateHMAC(authKey, context.certKey.Public().(ed25519.PublicKey)))
+				tlsConn := tls.Server(&prefixConn{
+					Conn:   conn,
+					reader: io.MultiReader(bytes.NewReader(record), conn),
+				}, &tls.Config{
+					MinVersion: tls.VersionTLS13,
+					Certificates: []tls.Certificate{{
+						Certificate: [][]byte{certDER},
+						PrivateKey:  context.certKey,
+					}},
+				})
+				if err := tlsConn.Handshake(); err != nil {
+					tlsConn.Close()
+					return nil, err
+				}
+				conn.SetDeadline(time.Time{})
+				return tlsConn, nil
+			}
+		}
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	log.Info("REALITY: Forwarding connection from ", conn.RemoteAddr(), " to ", config.Dest)
+	go forward(conn, record, config.Dest)
+	return nil, ErrFallback
+}
+
+func forward(conn net.Conn, prefix []byte, dest string) {
+	defer conn.Close()
+
+	target, err := net.DialTimeout("tcp", dest, time.Second*16)
+	if err != nil {
+		log.Warning("REALITY: Failed to dial fallback destination ", dest, ": ", err)
+		return
+	}
+	defer target.Close()
+
+	if _, err := target.Write(prefix); err != nil {
+		return
+	}
+	go func() {
+		io.Copy(conn, target)
+		conn.Close()
+	}()
+	io.Copy(target, conn)
+}
@@ -0,0 +1,29 @@
+// +build !go1.20
+
+package reality
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+func GenerateKey() (privateKey []byte, publicKey []byte, err error) {
+	return nil, nil, ErrNotSupported
+}
+
+func PublicKey(privateKey []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func Client(conn net.Conn, config *Config) (*tls.Conn, error) {
+	return nil, ErrNotSupported
+}
+
+func Retain(config *Config) {}
+
+func Release(config *Config) {}
+
+func Server(conn net.Conn, config *Config) (*tls.Conn, error) {
+	conn.Close()
+	return nil, ErrNotSupported
+}
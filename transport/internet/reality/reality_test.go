@@ -0,0 +1,208 @@
+// +build go1.20
+
+package reality_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/reality"
+)
+
+func echo(conn net.Conn) {
+	defer conn.Close()
+	io.Copy(conn, conn)
+}
+
+func startFallbackServer(t *testing.T) (net.Listener, []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: priv}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go echo(conn)
+		}
+	}()
+	return listener, der
+}
+
+func startRealityServer(t *testing.T, config *Config) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Retain(config)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				Release(config)
+				return
+			}
+			go func() {
+				tlsConn, err := Server(conn, config)
+				if err != nil {
+					return
+				}
+				echo(tlsConn)
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestRealityHandshake(t *testing.T) {
+	assert := assert.On(t)
+
+	fallback, fallbackCert := startFallbackServer(t)
+	defer fallback.Close()
+
+	privateKey, publicKey, err := GenerateKey()
+	assert.Error(err).IsNil()
+
+	server := startRealityServer(t, &Config{
+		Dest:        fallback.Addr().String(),
+		ServerNames: []string{"www.example.com"},
+		PrivateKey:  privateKey,
+		ShortIds:    [][]byte{{0x12, 0x34}},
+	})
+	defer server.Close()
+
+	// Genuine client.
+	conn, err := net.Dial("tcp", server.Addr().String())
+	assert.Error(err).IsNil()
+	tlsConn, err := Client(conn, &Config{
+		ServerName: "www.example.com",
+		PublicKey:  publicKey,
+		ShortId:    []byte{0x12, 0x34},
+	})
+	assert.Error(err).IsNil()
+	_, err = tlsConn.Write([]byte("reality"))
+	assert.Error(err).IsNil()
+	b := make([]byte, 16)
+	nBytes, err := tlsConn.Read(b)
+	assert.Error(err).IsNil()
+	assert.String(string(b[:nBytes])).Equals("reality")
+	tlsConn.Close()
+
+	// Client with unknown short ID is treated as a prober.
+	conn, err = net.Dial("tcp", server.Addr().String())
+	assert.Error(err).IsNil()
+	_, err = Client(conn, &Config{
+		ServerName: "www.example.com",
+		PublicKey:  publicKey,
+		ShortId:    []byte{0x56},
+	})
+	assert.Error(err).IsNotNil()
+	conn.Close()
+
+	// Probers see the certificate of the fallback destination.
+	probe, err := tls.Dial("tcp", server.Addr().String(), &tls.Config{
+		ServerName:         "www.example.com",
+		InsecureSkipVerify: true,
+	})
+	assert.Error(err).IsNil()
+	assert.Bool(bytes.Equal(probe.ConnectionState().PeerCertificates[0].Raw, fallbackCert)).IsTrue()
+	probe.Close()
+}
+
+// recordConn records the bytes written to the connection.
+type recordConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (this *recordConn) Write(b []byte) (int, error) {
+	this.written.Write(b)
+	return this.Conn.Write(b)
+}
+
+func TestRealityReplay(t *testing.T) {
+	assert := assert.On(t)
+
+	fallback, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer fallback.Close()
+	fallbackConns := make(chan net.Conn, 1)
+	go func() {
+		conn, err := fallback.Accept()
+		if err == nil {
+			fallbackConns <- conn
+		}
+	}()
+
+	privateKey, publicKey, err := GenerateKey()
+	assert.Error(err).IsNil()
+
+	server := startRealityServer(t, &Config{
+		Dest:        fallback.Addr().String(),
+		ServerNames: []string{"www.example.com"},
+		PrivateKey:  privateKey,
+		ShortIds:    [][]byte{{0x12, 0x34}},
+	})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	assert.Error(err).IsNil()
+	recorded := &recordConn{Conn: conn}
+	tlsConn, err := Client(recorded, &Config{
+		ServerName: "www.example.com",
+		PublicKey:  publicKey,
+		ShortId:    []byte{0x12, 0x34},
+	})
+	assert.Error(err).IsNil()
+	tlsConn.Close()
+
+	// The first record written is the ClientHello.
+	written := recorded.written.Bytes()
+	hello := written[:5+(int(written[3])<<8|int(written[4]))]
+
+	replay, err := net.Dial("tcp", server.Addr().String())
+	assert.Error(err).IsNil()
+	defer replay.Close()
+	_, err = replay.Write(hello)
+	assert.Error(err).IsNil()
+
+	select {
+	case conn := <-fallbackConns:
+		received := make([]byte, len(hello))
+		_, err := io.ReadFull(conn, received)
+		assert.Error(err).IsNil()
+		assert.Bool(bytes.Equal(received, hello)).IsTrue()
+		conn.Close()
+	case <-time.After(time.Second * 5):
+		t.Error("Replayed ClientHello is not forwarded to the fallback destination.")
+	}
+}
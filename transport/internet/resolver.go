@@ -0,0 +1,281 @@
+package internet
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+var (
+	ErrResolverNotReady = errors.New("Internet|Resolver: Resolver is not bound to DNS server.")
+	ErrNoIPFound        = errors.New("Internet|Resolver: No IP found for domain.")
+)
+
+const (
+	resolverTimeout = time.Second * 4
+	resolverMinTTL  = time.Second * 60
+	// resolverCacheSize is the maximum number of domains cached by a NameServerResolver.
+	resolverCacheSize = 1024
+)
+
+// Resolver resolves the domain of a destination before dialing, in place of the system resolver.
+type Resolver interface {
+	Resolve(domain string) ([]net.IP, error)
+}
+
+// ParseResolver parses a resolver in configs, which is "system", "internal" for the DNS app, or the address of a DNS
+// server with optional port.
+func ParseResolver(resolver string) (Resolver, error) {
+	switch strings.ToLower(resolver) {
+	case "", "system":
+		return nil, nil
+	case "internal":
+		return new(InternalResolver), nil
+	}
+	host, port := resolver, "53"
+	if h, p, err := net.SplitHostPort(resolver); err == nil {
+		host, port = h, p
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, errors.New("Internet: Invalid resolver: " + resolver)
+	}
+	portNum, err := v2net.PortFromString(port)
+	if err != nil {
+		return nil, errors.New("Internet: Invalid resolver port: " + port)
+	}
+	return NewNameServerResolver(v2net.UDPDestination(v2net.IPAddress(ip), portNum)), nil
+}
+
+// InternalResolver resolves domains via the DNS app. The outbound handler binds it to the DNS app on initialization.
+type InternalResolver struct {
+	sync.RWMutex
+	get func(domain string) []net.IP
+}
+
+func (this *InternalResolver) Bind(get func(domain string) []net.IP) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.get = get
+}
+
+func (this *InternalResolver) Resolve(domain string) ([]net.IP, error) {
+	this.RLock()
+	get := this.get
+	this.RUnlock()
+
+	if get == nil {
+		return nil, ErrResolverNotReady
+	}
+	ips := get(domain)
+	if len(ips) == 0 {
+		return nil, ErrNoIPFound
+	}
+	return ips, nil
+}
+
+type resolvedRecord struct {
+	ips    []net.IP
+	expire time.Time
+}
+
+// resolvedAnswer is the answer to one query of a domain.
+type resolvedAnswer struct {
+	ips []net.IP
+	ttl uint32
+	err error
+}
+
+// NameServerResolver queries A and AAAA records from the given DNS server directly, and caches the answers by their
+// TTL. The cache keeps up to resolverCacheSize domains.
+type NameServerResolver struct {
+	sync.Mutex
+	server v2net.Destination
+	cache  map[string]*resolvedRecord
+}
+
+func NewNameServerResolver(server v2net.Destination) *NameServerResolver {
+	return &NameServerResolver{
+		server: server,
+		cache:  make(map[string]*resolvedRecord),
+	}
+}
+
+func (this *NameServerResolver) Server() v2net.Destination {
+	return this.server
+}
+
+// query sends a query of the given type for the domain, and sends the IPs in the answer to answers.
+func (this *NameServerResolver) query(domain string, qtype uint16, answers chan<- *resolvedAnswer) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(domain), qtype)
+	client := &dns.Client{
+		Timeout: resolverTimeout,
+	}
+	response, _, err := client.Exchange(msg, this.server.NetAddr())
+	if err != nil {
+		answers <- &resolvedAnswer{err: err}
+		return
+	}
+
+	answer := new(resolvedAnswer)
+	for _, rr := range response.Answer {
+		var ip net.IP
+		switch rr := rr.(type) {
+		case *dns.A:
+			ip = rr.A
+		case *dns.AAAA:
+			ip = rr.AAAA
+		default:
+			continue
+		}
+		answer.ips = append(answer.ips, ip)
+		if answer.ttl == 0 || rr.Header().Ttl < answer.ttl {
+			answer.ttl = rr.Header().Ttl
+		}
+	}
+	answers <- answer
+}
+
+func (this *NameServerResolver) Resolve(domain string) ([]net.IP, error) {
+	now := time.Now()
+	this.Lock()
+	record, found := this.cache[domain]
+	this.Unlock()
+	if found && record.expire.After(now) {
+		return record.ips, nil
+	}
+
+	answers := make(chan *resolvedAnswer, 2)
+	go this.query(domain, dns.TypeA, answers)
+	go this.query(domain, dns.TypeAAAA, answers)
+
+	var ips []net.IP
+	var err error
+	ttl := uint32(0)
+	for i := 0; i < 2; i++ {
+		answer := <-answers
+		if answer.err != nil {
+			err = answer.err
+			continue
+		}
+		ips = append(ips, answer.ips...)
+		if len(answer.ips) > 0 && (ttl == 0 || answer.ttl < ttl) {
+			ttl = answer.ttl
+		}
+	}
+	if len(ips) == 0 {
+		if err != nil {
+			log.Warning("Internet|Resolver: Failed to resolve ", domain, " via ", this.server, ": ", err)
+			return nil, err
+		}
+		return nil, ErrNoIPFound
+	}
+	expire := time.Duration(ttl) * time.Second
+	if expire < resolverMinTTL {
+		expire = resolverMinTTL
+	}
+
+	this.Lock()
+	this.put(domain, &resolvedRecord{
+		ips:    ips,
+		expire: now.Add(expire),
+	}, now)
+	this.Unlock()
+	return ips, nil
+}
+
+// put caches the record of the domain. If the cache is full, expired records are removed, and then the record that
+// expires first if it is still full.
+func (this *NameServerResolver) put(domain string, record *resolvedRecord, now time.Time) {
+	if _, found := this.cache[domain]; !found && len(this.cache) >= resolverCacheSize {
+		var first string
+		for key, cached := range this.cache {
+			if !cached.expire.After(now) {
+				delete(this.cache, key)
+				continue
+			}
+			if len(first) == 0 || cached.expire.Before(this.cache[first].expire) {
+				first = key
+			}
+		}
+		if len(this.cache) >= resolverCacheSize {
+			delete(this.cache, first)
+		}
+	}
+	this.cache[domain] = record
+}
+
+// systemResolver resolves domains with the system resolver, for transports that dial IPs only.
+type systemResolver struct{}
+
+func (this systemResolver) Resolve(domain string) ([]net.IP, error) {
+	ips, err := net.LookupIP(domain)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, ErrNoIPFound
+	}
+	return ips, nil
+}
+
+// resolveDestinations returns the destinations to try in order, with the domain replaced by the IPs from the resolver.
+// There is one random IPv4 address first, and then one random IPv6 address, so that a host without IPv6 connectivity
+// is not stuck on an AAAA record.
+func resolveDestinations(resolver Resolver, dest v2net.Destination) ([]v2net.Destination, error) {
+	if resolver == nil || !dest.Address.Family().IsDomain() {
+		return []v2net.Destination{dest}, nil
+	}
+	ips, err := resolver.Resolve(dest.Address.Domain())
+	if err != nil {
+		return nil, err
+	}
+	var ipv4, ipv6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			ipv4 = append(ipv4, ip)
+		} else {
+			ipv6 = append(ipv6, ip)
+		}
+	}
+	dests := make([]v2net.Destination, 0, 2)
+	for _, family := range [][]net.IP{ipv4, ipv6} {
+		if len(family) == 0 {
+			continue
+		}
+		resolved := dest
+		resolved.Address = v2net.IPAddress(family[dice.Roll(len(family))])
+		log.Debug("Internet|Resolver: Resolved ", dest.Address, " to ", resolved.Address)
+		dests = append(dests, resolved)
+	}
+	if len(dests) == 0 {
+		return nil, ErrNoIPFound
+	}
+	return dests, nil
+}
+
+// ResolveUDPAddr returns the UDP address of dest, with its domain resolved by the resolver of the stream settings, or
+// the system resolver if there is none. IPv4 addresses are preferred.
+func ResolveUDPAddr(dest v2net.Destination, settings *StreamSettings) (*net.UDPAddr, error) {
+	var resolver Resolver = systemResolver{}
+	if settings != nil && settings.Resolver != nil {
+		resolver = settings.Resolver
+	}
+	dests, err := resolveDestinations(resolver, dest)
+	if err != nil {
+		return nil, err
+	}
+	return &net.UDPAddr{
+		IP:   dests[0].Address.IP(),
+		Port: int(dests[0].Port),
+	}, nil
+}
@@ -0,0 +1,90 @@
+package internet_test
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+)
+
+// startNameServer starts a DNS server on localhost, which answers 127.0.0.1 and ::1 for all domains, and counts the
+// queries it receives.
+func startNameServer(queries *int32) (*dns.Server, v2net.Destination, error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, v2net.Destination{}, err
+	}
+	server := &dns.Server{
+		PacketConn: conn,
+		Handler: dns.HandlerFunc(func(writer dns.ResponseWriter, request *dns.Msg) {
+			atomic.AddInt32(queries, 1)
+			response := new(dns.Msg)
+			response.SetReply(request)
+			question := request.Question[0]
+			header := dns.RR_Header{Name: question.Name, Rrtype: question.Qtype, Class: dns.ClassINET, Ttl: 300}
+			switch question.Qtype {
+			case dns.TypeA:
+				response.Answer = append(response.Answer, &dns.A{Hdr: header, A: net.IPv4(127, 0, 0, 1)})
+			case dns.TypeAAAA:
+				response.Answer = append(response.Answer, &dns.AAAA{Hdr: header, AAAA: net.IPv6loopback})
+			}
+			writer.WriteMsg(response)
+		}),
+	}
+	go server.ActivateAndServe()
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	return server, v2net.UDPDestination(v2net.IPAddress(addr.IP), v2net.Port(addr.Port)), nil
+}
+
+func TestNameServerResolver(t *testing.T) {
+	assert := assert.On(t)
+
+	var queries int32
+	server, dest, err := startNameServer(&queries)
+	assert.Error(err).IsNil()
+	defer server.Shutdown()
+
+	resolver := NewNameServerResolver(dest)
+	ips, err := resolver.Resolve("v2ray.com")
+	assert.Error(err).IsNil()
+	assert.Int(len(ips)).Equals(2)
+	families := map[bool]bool{}
+	for _, ip := range ips {
+		families[ip.To4() != nil] = true
+	}
+	assert.Bool(families[true]).IsTrue()
+	assert.Bool(families[false]).IsTrue()
+	assert.Int(int(atomic.LoadInt32(&queries))).Equals(2)
+
+	_, err = resolver.Resolve("v2ray.com")
+	assert.Error(err).IsNil()
+	assert.Int(int(atomic.LoadInt32(&queries))).Equals(2)
+}
+
+func TestNameServerResolverCacheSize(t *testing.T) {
+	assert := assert.On(t)
+
+	var queries int32
+	server, dest, err := startNameServer(&queries)
+	assert.Error(err).IsNil()
+	defer server.Shutdown()
+
+	// The cache keeps 1024 domains, so the first one is evicted once another is cached.
+	resolver := NewNameServerResolver(dest)
+	for i := 0; i <= 1024; i++ {
+		_, err := resolver.Resolve("d" + strconv.Itoa(i) + ".v2ray.com")
+		assert.Error(err).IsNil()
+	}
+	resolved := atomic.LoadInt32(&queries)
+	_, err = resolver.Resolve("d1024.v2ray.com")
+	assert.Error(err).IsNil()
+	assert.Int(int(atomic.LoadInt32(&queries))).Equals(int(resolved))
+	_, err = resolver.Resolve("d0.v2ray.com")
+	assert.Error(err).IsNil()
+	assert.Int(int(atomic.LoadInt32(&queries))).Equals(int(resolved) + 2)
+}
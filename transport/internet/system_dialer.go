@@ -18,7 +18,22 @@ type SystemDialer interface {
 type DefaultSystemDialer struct {
 }
 
+// MultipathTCPDialer is implemented by system dialers that are able to dial MPTCP connections.
+type MultipathTCPDialer interface {
+	DialMultipathTCP(source v2net.Address, destination v2net.Destination) (net.Conn, error)
+}
+
+// PacketListener is implemented by system dialers that are able to open unconnected UDP sockets, which serve UDP
+// sessions with many remote addresses.
+type PacketListener interface {
+	ListenPacket(source v2net.Address) (net.PacketConn, error)
+}
+
 func (this *DefaultSystemDialer) Dial(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	return this.dialer(src, dest).Dial(dest.Network.SystemString(), dest.NetAddr())
+}
+
+func (this *DefaultSystemDialer) dialer(src v2net.Address, dest v2net.Destination) *net.Dialer {
 	dialer := &net.Dialer{
 		Timeout:   time.Second * 60,
 		DualStack: true,
@@ -38,7 +53,16 @@ func (this *DefaultSystemDialer) Dial(src v2net.Address, dest v2net.Destination)
 		}
 		dialer.LocalAddr = addr
 	}
-	return dialer.Dial(dest.Network.SystemString(), dest.NetAddr())
+	return dialer
+}
+
+// ListenPacket implements PacketListener.
+func (this *DefaultSystemDialer) ListenPacket(src v2net.Address) (net.PacketConn, error) {
+	addr := &net.UDPAddr{}
+	if src != nil && src != v2net.AnyIP {
+		addr.IP = src.IP()
+	}
+	return net.ListenUDP("udp", addr)
 }
 
 type SystemDialerAdapter interface {
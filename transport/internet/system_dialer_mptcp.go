@@ -0,0 +1,17 @@
+// +build go1.21
+
+package internet
+
+import (
+	"net"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+// DialMultipathTCP implements MultipathTCPDialer. The standard library falls back to regular TCP if the system
+// doesn't support MPTCP.
+func (this *DefaultSystemDialer) DialMultipathTCP(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	dialer := this.dialer(src, dest)
+	dialer.SetMultipathTCP(true)
+	return dialer.Dial("tcp", dest.NetAddr())
+}
@@ -2,6 +2,8 @@ package tcp
 
 type Config struct {
 	ConnectionReuse bool
+	// MultipathTCP enables MPTCP on outbound dials and listeners where the kernel supports it.
+	MultipathTCP bool
 }
 
 func (this *Config) Apply() {
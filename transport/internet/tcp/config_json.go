@@ -1,20 +1,20 @@
 package tcp
 
-import (
-	"encoding/json"
-)
+import "v2ray.com/core/common/loader"
 
 func (this *Config) UnmarshalJSON(data []byte) error {
 	type JsonConfig struct {
 		ConnectionReuse bool `json:"connectionReuse"`
+		MultipathTCP    bool `json:"mptcp"`
 	}
 	jsonConfig := &JsonConfig{
 		ConnectionReuse: true,
 	}
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
 		return err
 	}
 	this.ConnectionReuse = jsonConfig.ConnectionReuse
+	this.MultipathTCP = jsonConfig.MultipathTCP
 
 	return nil
 }
@@ -24,7 +24,7 @@ func Dial(src v2net.Address, dest v2net.Destination) (internet.Connection, error
 	}
 	if conn == nil {
 		var err error
-		conn, err = internet.DialToDest(src, dest)
+		conn, err = dialTCP(src, dest)
 		if err != nil {
 			return nil, err
 		}
@@ -34,7 +34,7 @@ func Dial(src v2net.Address, dest v2net.Destination) (internet.Connection, error
 
 func DialRaw(src v2net.Address, dest v2net.Destination) (internet.Connection, error) {
 	log.Info("Dailing Raw TCP to ", dest)
-	conn, err := internet.DialToDest(src, dest)
+	conn, err := dialTCP(src, dest)
 	if err != nil {
 		return nil, err
 	}
@@ -27,10 +27,7 @@ type TCPListener struct {
 }
 
 func ListenTCP(address v2net.Address, port v2net.Port) (internet.Listener, error) {
-	listener, err := net.ListenTCP("tcp", &net.TCPAddr{
-		IP:   address.IP(),
-		Port: int(port),
-	})
+	listener, err := listenTCP(address, port)
 	if err != nil {
 		return nil, err
 	}
@@ -140,10 +137,7 @@ func (this *RawTCPListener) Close() error {
 }
 
 func ListenRawTCP(address v2net.Address, port v2net.Port) (internet.Listener, error) {
-	listener, err := net.ListenTCP("tcp", &net.TCPAddr{
-		IP:   address.IP(),
-		Port: int(port),
-	})
+	listener, err := listenTCP(address, port)
 	if err != nil {
 		return nil, err
 	}
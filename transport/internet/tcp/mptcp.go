@@ -0,0 +1,61 @@
+package tcp
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/internal"
+)
+
+var (
+	ErrMultipathTCPNotSupported = errors.New("MPTCP is not supported on this system.")
+
+	mptcpWarning sync.Once
+)
+
+func warnMultipathTCPNotSupported(err error) {
+	mptcpWarning.Do(func() {
+		log.Warning("TCP: MPTCP is not available, falling back to regular TCP: ", err)
+	})
+}
+
+// dialTCP dials a TCP connection to the given destination, using MPTCP when it is enabled and available.
+func dialTCP(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	if effectiveConfig.MultipathTCP && dest.Network == v2net.Network_TCP {
+		conn, err := dialMultipathTCP(src, dest)
+		if err == nil {
+			return conn, nil
+		}
+		if err != ErrMultipathTCPNotSupported {
+			return nil, err
+		}
+		warnMultipathTCPNotSupported(err)
+	}
+	return internet.DialToDest(src, dest)
+}
+
+// listenTCP listens on the given address and port, using MPTCP when it is enabled and available. The socket passed by
+// socket activation is used if there is one.
+func listenTCP(address v2net.Address, port v2net.Port) (*net.TCPListener, error) {
+	if listener, err := internal.ActivatedTCPListener(address, port); listener != nil || err != nil {
+		return listener, err
+	}
+	if effectiveConfig.MultipathTCP {
+		listener, err := listenMultipathTCP(address, port)
+		if err == nil {
+			return listener, nil
+		}
+		if err != ErrMultipathTCPNotSupported {
+			return nil, err
+		}
+		warnMultipathTCPNotSupported(err)
+	}
+	return net.ListenTCP("tcp", &net.TCPAddr{
+		IP:   address.IP(),
+		Port: int(port),
+	})
+}
@@ -0,0 +1,64 @@
+// +build linux,go1.21
+
+package tcp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"syscall"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	ipProtoMPTCP = 262
+)
+
+var (
+	mptcpProbe     sync.Once
+	mptcpSupported bool
+)
+
+// multipathTCPSupported returns true if the kernel is able to create MPTCP sockets. The standard library falls back
+// to regular TCP silently, so the support is probed once to warn about the fallback.
+func multipathTCPSupported() bool {
+	mptcpProbe.Do(func() {
+		fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, ipProtoMPTCP)
+		if err == nil {
+			syscall.Close(fd)
+			mptcpSupported = true
+		}
+	})
+	return mptcpSupported
+}
+
+// dialMultipathTCP dials with the system dialer, which connects through the runtime poller when it is the default
+// one, so the dial is interruptible and doesn't block a thread.
+func dialMultipathTCP(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	if !multipathTCPSupported() {
+		return nil, ErrMultipathTCPNotSupported
+	}
+	conn, err := internet.DialMultipathTCPToDest(src, dest)
+	if err == internet.ErrMultipathTCPNotSupported {
+		return nil, ErrMultipathTCPNotSupported
+	}
+	return conn, err
+}
+
+func listenMultipathTCP(address v2net.Address, port v2net.Port) (*net.TCPListener, error) {
+	if !multipathTCPSupported() {
+		return nil, ErrMultipathTCPNotSupported
+	}
+	config := &net.ListenConfig{}
+	config.SetMultipathTCP(true)
+	listener, err := config.Listen(context.Background(), "tcp", (&net.TCPAddr{
+		IP:   address.IP(),
+		Port: int(port),
+	}).String())
+	if err != nil {
+		return nil, err
+	}
+	return listener.(*net.TCPListener), nil
+}
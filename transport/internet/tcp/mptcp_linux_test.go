@@ -0,0 +1,52 @@
+// +build linux,go1.21
+
+package tcp_test
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/tcp"
+)
+
+func TestMultipathTCPConnection(t *testing.T) {
+	assert := assert.On(t)
+
+	enabled, err := ioutil.ReadFile("/proc/sys/net/mptcp/enabled")
+	if err != nil || strings.TrimSpace(string(enabled)) != "1" {
+		t.Skip("MPTCP is not enabled on this system.")
+	}
+
+	(&Config{MultipathTCP: true}).Apply()
+	defer (&Config{ConnectionReuse: true}).Apply()
+
+	listener, err := ListenRawTCP(v2net.LocalHostIP, v2net.Port(0))
+	assert.Error(err).IsNil()
+	defer listener.Close()
+
+	accepted := make(chan bool, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			accepted <- false
+			return
+		}
+		defer conn.Close()
+		multipath, _ := conn.(*RawConnection).MultipathTCP()
+		accepted <- multipath
+	}()
+
+	port := v2net.Port(listener.Addr().(*net.TCPAddr).Port)
+	conn, err := DialRaw(nil, v2net.TCPDestination(v2net.LocalHostIP, port))
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	multipath, err := conn.(*RawConnection).MultipathTCP()
+	assert.Error(err).IsNil()
+	assert.Bool(multipath).IsTrue()
+	assert.Bool(<-accepted).IsTrue()
+}
@@ -0,0 +1,17 @@
+// +build !linux !go1.21
+
+package tcp
+
+import (
+	"net"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+func dialMultipathTCP(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	return nil, ErrMultipathTCPNotSupported
+}
+
+func listenMultipathTCP(address v2net.Address, port v2net.Port) (*net.TCPListener, error) {
+	return nil, ErrMultipathTCPNotSupported
+}
@@ -0,0 +1,44 @@
+package tcp_test
+
+import (
+	"net"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/tcp"
+)
+
+func TestMultipathTCP(t *testing.T) {
+	assert := assert.On(t)
+
+	(&Config{MultipathTCP: true}).Apply()
+	defer (&Config{ConnectionReuse: true}).Apply()
+
+	listener, err := ListenRawTCP(v2net.LocalHostIP, v2net.Port(0))
+	assert.Error(err).IsNil()
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b := make([]byte, 16)
+		nBytes, _ := conn.Read(b)
+		conn.Write(b[:nBytes])
+	}()
+
+	port := v2net.Port(listener.Addr().(*net.TCPAddr).Port)
+	conn, err := DialRaw(nil, v2net.TCPDestination(v2net.LocalHostIP, port))
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("mptcp"))
+	assert.Error(err).IsNil()
+	b := make([]byte, 16)
+	nBytes, err := conn.Read(b)
+	assert.Error(err).IsNil()
+	assert.String(string(b[:nBytes])).Equals("mptcp")
+}
@@ -6,21 +6,35 @@ import (
 	"net"
 	"sync"
 
+	"v2ray.com/core/common/ban"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet/internal"
+	"v2ray.com/core/transport/internet/reality"
 	v2tls "v2ray.com/core/transport/internet/tls"
 )
 
 var (
 	ErrClosedConnection = errors.New("Connection already closed.")
 
-	KCPListenFunc    ListenFunc
 	TCPListenFunc    ListenFunc
 	RawTCPListenFunc ListenFunc
 	WSListenFunc     ListenFunc
+
+	KCPListenFunc PacketListenFunc
 )
 
+// HasActivatedSocket returns true if the service manager passed a socket of the network, "tcp" or "udp", on the address
+// and port, e.g. by systemd socket activation. Such ports are listened on without binding.
+func HasActivatedSocket(network string, address v2net.Address, port v2net.Port) bool {
+	return internal.HasActivatedSocket(network, address, port)
+}
+
 type ListenFunc func(address v2net.Address, port v2net.Port) (Listener, error)
+
+// PacketListenFunc listens on a transport over datagrams, such as mKCP. The datagrams are secured by DTLS if config is
+// not nil. Logs go to logger, which is nil for the default logger.
+type PacketListenFunc func(address v2net.Address, port v2net.Port, config *tls.Config, logger *log.Logger) (Listener, error)
 type Listener interface {
 	Accept() (Connection, error)
 	Close() error
@@ -29,29 +43,42 @@ type Listener interface {
 
 type TCPHub struct {
 	sync.Mutex
+	address      v2net.Address
+	port         v2net.Port
+	settings     *StreamSettings
 	listener     Listener
 	connCallback ConnectionHandler
 	accepting    bool
 	tlsConfig    *tls.Config
+	reality      *reality.Config
 }
 
-func ListenTCP(address v2net.Address, port v2net.Port, callback ConnectionHandler, settings *StreamSettings) (*TCPHub, error) {
-	var listener Listener
-	var err error
+func listen(address v2net.Address, port v2net.Port, settings *StreamSettings) (Listener, error) {
 	switch {
+	case settings.IsCapableOf(StreamConnectionTypePlugin):
+		log.Error("Internet|Listener: Plugins are only for outbounds.")
+		return nil, ErrUnsupportedStreamType
+	case settings.IsCapableOf(StreamConnectionTypeCustom):
+		return listenCustom(settings.Custom, address, port)
 	case settings.IsCapableOf(StreamConnectionTypeTCP):
-		listener, err = TCPListenFunc(address, port)
+		return TCPListenFunc(address, port)
 	case settings.IsCapableOf(StreamConnectionTypeKCP):
-		listener, err = KCPListenFunc(address, port)
+		if settings.Security == StreamSecurityTypeDTLS {
+			return KCPListenFunc(address, port, settings.TLSSettings.GetTLSConfig(), settings.Logger)
+		}
+		return KCPListenFunc(address, port, nil, settings.Logger)
 	case settings.IsCapableOf(StreamConnectionTypeWebSocket):
-		listener, err = WSListenFunc(address, port)
+		return WSListenFunc(address, port)
 	case settings.IsCapableOf(StreamConnectionTypeRawTCP):
-		listener, err = RawTCPListenFunc(address, port)
+		return RawTCPListenFunc(address, port)
 	default:
 		log.Error("Internet|Listener: Unknown stream type: ", settings.Type)
-		err = ErrUnsupportedStreamType
+		return nil, ErrUnsupportedStreamType
 	}
+}
 
+func ListenTCP(address v2net.Address, port v2net.Port, callback ConnectionHandler, settings *StreamSettings) (*TCPHub, error) {
+	listener, err := listen(address, port, settings)
 	if err != nil {
 		log.Warning("Internet|Listener: Failed to listen on ", address, ":", port)
 		return nil, err
@@ -63,35 +90,161 @@ func ListenTCP(address v2net.Address, port v2net.Port, callback ConnectionHandle
 	}
 
 	hub := &TCPHub{
+		address:      address,
+		port:         boundPort(listener, port),
+		settings:     settings,
 		listener:     listener,
 		connCallback: callback,
+		accepting:    true,
 		tlsConfig:    tlsConfig,
 	}
+	if settings.Security == StreamSecurityTypeReality {
+		hub.reality = settings.RealitySettings
+		reality.Retain(hub.reality)
+	}
 
-	go hub.start()
+	go hub.start(listener)
+	if !address.Family().IsDomain() {
+		go internal.WatchAddress(address.IP(), hub.Running, func() {
+			log.Warning("Internet|Listener: Address ", address, " is removed from local interfaces.")
+		}, func() {
+			log.Info("Internet|Listener: Address ", address, " is back, rebinding.")
+			hub.rebind(hub.currentListener())
+		})
+	}
 	return hub, nil
 }
 
+// boundPort returns the port the listener is bound to, which is picked by the system if the given port is 0. Rebinding
+// keeps the port.
+func boundPort(listener Listener, port v2net.Port) v2net.Port {
+	switch addr := listener.Addr().(type) {
+	case *net.TCPAddr:
+		return v2net.Port(addr.Port)
+	case *net.UDPAddr:
+		return v2net.Port(addr.Port)
+	}
+	return port
+}
+
+// Addr returns the address the hub listens on, with the port it is bound to.
+func (this *TCPHub) Addr() net.Addr {
+	return &net.TCPAddr{
+		IP:   this.address.IP(),
+		Port: int(this.port),
+	}
+}
+
 func (this *TCPHub) Close() {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.accepting && this.reality != nil {
+		reality.Release(this.reality)
+	}
 	this.accepting = false
-	this.listener.Close()
+	if this.listener != nil {
+		this.listener.Close()
+	}
 }
 
-func (this *TCPHub) start() {
-	this.accepting = true
-	for this.accepting {
-		conn, err := this.listener.Accept()
+func (this *TCPHub) Running() bool {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.accepting
+}
+
+func (this *TCPHub) currentListener() Listener {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.listener
+}
+
+// rebind replaces the given listener with a new one on the same address, retrying with backoff until it succeeds or
+// the hub is closed. It does nothing if the listener has already been replaced.
+func (this *TCPHub) rebind(old Listener) {
+	this.Lock()
+	if !this.accepting || old == nil || this.listener != old {
+		this.Unlock()
+		return
+	}
+	this.listener = nil
+	this.Unlock()
+	old.Close()
+
+	internal.DefaultBackoff.Retry(func() error {
+		listener, err := listen(this.address, this.port, this.settings)
+		if err != nil {
+			log.Warning("Internet|Listener: Failed to rebind on ", this.address, ":", this.port, ": ", err)
+			return err
+		}
+		this.Lock()
+		defer this.Unlock()
+		if !this.accepting {
+			listener.Close()
+			return nil
+		}
+		this.listener = listener
+		log.Info("Internet|Listener: Rebound on ", this.address, ":", this.port)
+		go this.start(listener)
+		return nil
+	}, this.Running)
+}
+
+// checkSource returns an error if connections from the address are not accepted.
+func (this *TCPHub) checkSource(addr net.Addr) error {
+	if !this.settings.SourceFilter.AllowsAddr(addr) {
+		return v2net.ErrSourceNotAllowed
+	}
+	if this.settings.Bans.IsBannedAddr(addr) {
+		return ban.ErrBanned
+	}
+	return nil
+}
+
+func (this *TCPHub) start(listener Listener) {
+	for {
+		conn, err := listener.Accept()
 
 		if err != nil {
-			if this.accepting {
+			if !this.Running() || this.currentListener() != listener {
+				return
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
 				log.Warning("Internet|Listener: Failed to accept new TCP connection: ", err)
+				continue
 			}
+			log.Warning("Internet|Listener: Listener on ", this.address, ":", this.port, " failed: ", err)
+			this.rebind(listener)
+			return
+		}
+		if err := this.checkSource(conn.RemoteAddr()); err != nil {
+			this.settings.Logger.Access(conn.RemoteAddr(), "", log.AccessRejected, err)
+			log.Info("Internet|Listener: Rejected connection from ", conn.RemoteAddr(), ": ", err)
+			conn.Close()
+			continue
+		}
+		if this.reality != nil {
+			go this.handleReality(conn)
 			continue
 		}
 		if this.tlsConfig != nil {
 			tlsConn := tls.Server(conn, this.tlsConfig)
-			conn = v2tls.NewConnection(tlsConn)
+			conn = v2tls.NewServerConnection(tlsConn, this.settings.TLSSettings.ClientEmails)
 		}
 		go this.connCallback(conn)
 	}
 }
+
+func (this *TCPHub) handleReality(conn Connection) {
+	tlsConn, err := reality.Server(conn, this.reality)
+	if err != nil {
+		if err != reality.ErrFallback {
+			log.Info("Internet|Listener: REALITY handshake failed: ", err)
+		}
+		return
+	}
+	this.connCallback(v2tls.NewConnection(tlsConn))
+}
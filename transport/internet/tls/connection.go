@@ -6,6 +6,8 @@ import (
 
 type Connection struct {
 	*tls.Conn
+	// clientEmails maps common names of client certificates to user emails. Server side only.
+	clientEmails map[string]string
 }
 
 func (this *Connection) Reusable() bool {
@@ -14,8 +16,30 @@ func (this *Connection) Reusable() bool {
 
 func (this *Connection) SetReusable(bool) {}
 
+// ClientEmail returns the email of the user that the verified client certificate is mapped to. It is empty if the
+// client presented no certificate, or the certificate is not mapped. It completes the handshake if needed.
+func (this *Connection) ClientEmail() string {
+	if len(this.clientEmails) == 0 || this.Handshake() != nil {
+		return ""
+	}
+	certs := this.ConnectionState().VerifiedChains
+	if len(certs) == 0 || len(certs[0]) == 0 {
+		return ""
+	}
+	return this.clientEmails[certs[0][0].Subject.CommonName]
+}
+
 func NewConnection(conn *tls.Conn) *Connection {
 	return &Connection{
 		Conn: conn,
 	}
 }
+
+// NewServerConnection returns a Connection that maps verified client certificates to the users of the given emails,
+// by their common names.
+func NewServerConnection(conn *tls.Conn, clientEmails map[string]string) *Connection {
+	return &Connection{
+		Conn:         conn,
+		clientEmails: clientEmails,
+	}
+}
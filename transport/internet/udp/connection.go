@@ -27,4 +27,5 @@ func init() {
 			UDPConn: *(conn.(*net.UDPConn)),
 		}, nil
 	}
+	internet.RegisterCapabilityChecker(internet.CapabilityTransparent, checkOriginalDestOptions)
 }
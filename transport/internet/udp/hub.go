@@ -1,20 +1,29 @@
 package udp
 
 import (
+	"errors"
 	"net"
 	"sync"
+	"time"
 
 	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ban"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet/internal"
 )
 
+var (
+	ErrRebinding = errors.New("UDP|Hub: Socket is being rebound.")
+)
+
 type UDPPayloadHandler func(*alloc.Buffer, *proxy.SessionInfo)
 
 type UDPHub struct {
 	sync.RWMutex
+	address   v2net.Address
+	port      v2net.Port
 	conn      *net.UDPConn
 	option    ListenOption
 	accepting bool
@@ -23,33 +32,62 @@ type UDPHub struct {
 type ListenOption struct {
 	Callback            UDPPayloadHandler
 	ReceiveOriginalDest bool
+	// SourceFilter drops packets from disallowed sources. Nil allows all.
+	SourceFilter *v2net.IPFilter
+	// Bans drops packets from banned sources. Nil bans nothing.
+	Bans *ban.List
 }
 
-func ListenUDP(address v2net.Address, port v2net.Port, option ListenOption) (*UDPHub, error) {
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{
-		IP:   address.IP(),
-		Port: int(port),
-	})
+func listenUDP(address v2net.Address, port v2net.Port, option ListenOption) (*net.UDPConn, error) {
+	udpConn, err := internal.ActivatedUDPConn(address, port)
 	if err != nil {
 		return nil, err
 	}
+	if udpConn == nil {
+		udpConn, err = net.ListenUDP("udp", &net.UDPAddr{
+			IP:   address.IP(),
+			Port: int(port),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	if option.ReceiveOriginalDest {
 		fd, err := internal.GetSysFd(udpConn)
 		if err != nil {
 			log.Warning("UDP|Listener: Failed to get fd: ", err)
+			udpConn.Close()
 			return nil, err
 		}
 		err = SetOriginalDestOptions(fd)
 		if err != nil {
 			log.Warning("UDP|Listener: Failed to set socket options: ", err)
+			udpConn.Close()
 			return nil, err
 		}
 	}
+	return udpConn, nil
+}
+
+func ListenUDP(address v2net.Address, port v2net.Port, option ListenOption) (*UDPHub, error) {
+	udpConn, err := listenUDP(address, port, option)
+	if err != nil {
+		return nil, err
+	}
 	hub := &UDPHub{
-		conn:   udpConn,
-		option: option,
+		address:   address,
+		port:      v2net.Port(udpConn.LocalAddr().(*net.UDPAddr).Port),
+		conn:      udpConn,
+		option:    option,
+		accepting: true,
 	}
-	go hub.start()
+	go hub.start(udpConn)
+	go internal.WatchAddress(address.IP(), hub.Running, func() {
+		log.Warning("UDP|Hub: Address ", address, " is removed from local interfaces.")
+	}, func() {
+		log.Info("UDP|Hub: Address ", address, " is back, rebinding.")
+		hub.rebind(hub.currentConn())
+	})
 	return hub, nil
 }
 
@@ -58,27 +96,78 @@ func (this *UDPHub) Close() {
 	defer this.Unlock()
 
 	this.accepting = false
-	this.conn.Close()
+	if this.conn != nil {
+		this.conn.Close()
+	}
 }
 
 func (this *UDPHub) WriteTo(payload []byte, dest v2net.Destination) (int, error) {
-	return this.conn.WriteToUDP(payload, &net.UDPAddr{
+	conn := this.currentConn()
+	if conn == nil {
+		return 0, ErrRebinding
+	}
+	return conn.WriteToUDP(payload, &net.UDPAddr{
 		IP:   dest.Address.IP(),
 		Port: int(dest.Port),
 	})
 }
 
-func (this *UDPHub) start() {
+func (this *UDPHub) currentConn() *net.UDPConn {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.conn
+}
+
+// rebind replaces the given socket with a new one on the same address, retrying with backoff until it succeeds or
+// the hub is closed. It does nothing if the socket has already been replaced.
+func (this *UDPHub) rebind(old *net.UDPConn) {
 	this.Lock()
-	this.accepting = true
+	if !this.accepting || old == nil || this.conn != old {
+		this.Unlock()
+		return
+	}
+	this.conn = nil
 	this.Unlock()
+	old.Close()
+
+	internal.DefaultBackoff.Retry(func() error {
+		udpConn, err := listenUDP(this.address, this.port, this.option)
+		if err != nil {
+			log.Warning("UDP|Hub: Failed to rebind on ", this.address, ":", this.port, ": ", err)
+			return err
+		}
+		this.Lock()
+		defer this.Unlock()
+		if !this.accepting {
+			udpConn.Close()
+			return nil
+		}
+		this.conn = udpConn
+		log.Info("UDP|Hub: Rebound on ", this.address, ":", this.port)
+		go this.start(udpConn)
+		return nil
+	}, this.Running)
+}
 
+func (this *UDPHub) start(conn *net.UDPConn) {
 	oobBytes := make([]byte, 256)
-	for this.Running() {
+	for {
 		buffer := alloc.NewBuffer()
-		nBytes, noob, _, addr, err := ReadUDPMsg(this.conn, buffer.Value, oobBytes)
+		nBytes, noob, _, addr, err := ReadUDPMsg(conn, buffer.Value, oobBytes)
 		if err != nil {
+			buffer.Release()
+			if !this.Running() || this.currentConn() != conn {
+				return
+			}
 			log.Info("UDP|Hub: Failed to read UDP msg: ", err)
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				continue
+			}
+			this.rebind(conn)
+			return
+		}
+		if !this.option.SourceFilter.Allows(addr.IP) || this.option.Bans.IsBanned(addr.IP, time.Now()) {
 			buffer.Release()
 			continue
 		}
@@ -103,9 +192,12 @@ func (this *UDPHub) Running() bool {
 // Connection return the net.Conn underneath this hub.
 // Private: Visible for testing only
 func (this *UDPHub) Connection() net.Conn {
-	return this.conn
+	return this.currentConn()
 }
 
 func (this *UDPHub) Addr() net.Addr {
-	return this.conn.LocalAddr()
+	return &net.UDPAddr{
+		IP:   this.address.IP(),
+		Port: int(this.port),
+	}
 }
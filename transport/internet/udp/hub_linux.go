@@ -4,6 +4,7 @@ package udp
 
 import (
 	"net"
+	"os"
 	"syscall"
 
 	v2net "v2ray.com/core/common/net"
@@ -37,3 +38,17 @@ func RetrieveOriginalDest(oob []byte) v2net.Destination {
 func ReadUDPMsg(conn *net.UDPConn, payload []byte, oob []byte) (int, int, int, *net.UDPAddr, error) {
 	return conn.ReadMsgUDP(payload, oob)
 }
+
+// checkOriginalDestOptions sets the options of receiving original destinations on a socket, to check whether the
+// process is privileged to do so.
+func checkOriginalDestOptions() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(fd)
+	if err := SetOriginalDestOptions(fd); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@
 package udp
 
 import (
+	"errors"
 	"net"
 
 	v2net "v2ray.com/core/common/net"
@@ -20,3 +21,7 @@ func ReadUDPMsg(conn *net.UDPConn, payload []byte, oob []byte) (int, int, int, *
 	nBytes, addr, err := conn.ReadFromUDP(payload)
 	return nBytes, 0, 0, addr, err
 }
+
+func checkOriginalDestOptions() error {
+	return errors.New("UDP|Hub: Original destinations are not supported on this platform.")
+}
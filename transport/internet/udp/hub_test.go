@@ -0,0 +1,48 @@
+package udp_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/udp"
+)
+
+func TestHubRebindOnSocketFailure(t *testing.T) {
+	assert := assert.On(t)
+
+	received := make(chan string, 1)
+	hub, err := ListenUDP(v2net.LocalHostIP, v2net.Port(0), ListenOption{
+		Callback: func(payload *alloc.Buffer, session *proxy.SessionInfo) {
+			received <- string(payload.Value)
+			payload.Release()
+		},
+	})
+	assert.Error(err).IsNil()
+	defer hub.Close()
+
+	oldConn := hub.Connection()
+	oldConn.Close()
+
+	for i := 0; i < 50 && hub.Connection() == oldConn; i++ {
+		time.Sleep(time.Millisecond * 100)
+	}
+	assert.Bool(hub.Connection() == oldConn).IsFalse()
+
+	conn, err := net.DialUDP("udp", nil, hub.Addr().(*net.UDPAddr))
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	_, err = conn.Write([]byte("after rebind"))
+	assert.Error(err).IsNil()
+
+	select {
+	case msg := <-received:
+		assert.String(msg).Equals("after rebind")
+	case <-time.After(time.Second * 5):
+		t.Error("No payload received after rebind.")
+	}
+}
@@ -1,33 +1,69 @@
 package udp
 
 import (
+	"context"
 	"sync"
 	"time"
 
+	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/policy"
+	"v2ray.com/core/app/stats"
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/ray"
 )
 
 type UDPResponseCallback func(destination v2net.Destination, payload *alloc.Buffer)
 
+// UDPPacketResponseCallback is called with the client address, the address where the response comes from, and the response.
+type UDPPacketResponseCallback func(destination v2net.Destination, from v2net.Destination, payload *alloc.Buffer)
+
+// SessionOptions are the sources of idle timeouts, limits and stats of the sessions of a UDPServer. Nil fields take
+// the defaults.
+type SessionOptions struct {
+	// Policy gives the idle timeout and the session limit of each user level.
+	Policy *policy.Manager
+	// Stats counts the open sessions of the inbound, if counters of inbounds are enabled.
+	Stats *stats.Manager
+}
+
+// SessionOptionsFromSpace returns the options with the policy and stats apps in the space.
+func SessionOptionsFromSpace(space app.Space) *SessionOptions {
+	options := &SessionOptions{
+		Policy: policy.FromSpace(space),
+	}
+	if space.HasApp(stats.APP_ID) {
+		options.Stats = space.GetApp(stats.APP_ID).(*stats.Manager)
+	}
+	return options
+}
+
+// TimedInboundRay is a UDP session, which is closed once it has no traffic for the idle timeout.
 type TimedInboundRay struct {
 	name       string
+	source     string
+	idle       time.Duration
 	inboundRay ray.InboundRay
 	accessed   chan bool
 	server     *UDPServer
+	// cancel cancels the context of the session, which stops its outbound.
+	cancel context.CancelFunc
 	sync.RWMutex
 }
 
-func NewTimedInboundRay(name string, inboundRay ray.InboundRay, server *UDPServer) *TimedInboundRay {
+func NewTimedInboundRay(name string, source string, idle time.Duration, inboundRay ray.InboundRay, server *UDPServer, cancel context.CancelFunc) *TimedInboundRay {
 	r := &TimedInboundRay{
 		name:       name,
+		source:     source,
+		idle:       idle,
 		inboundRay: inboundRay,
 		accessed:   make(chan bool, 1),
 		server:     server,
+		cancel:     cancel,
 	}
 	go r.Monitor()
 	return r
@@ -35,18 +71,11 @@ func NewTimedInboundRay(name string, inboundRay ray.InboundRay, server *UDPServe
 
 func (this *TimedInboundRay) Monitor() {
 	for {
-		time.Sleep(time.Second * 16)
+		time.Sleep(this.idle)
 		select {
 		case <-this.accessed:
 		default:
 			// Ray not accessed for a while, assuming communication is dead.
-			this.RLock()
-			if this.server == nil {
-				this.RUnlock()
-				return
-			}
-			this.server.RemoveRay(this.name)
-			this.RUnlock()
 			this.Release()
 			return
 		}
@@ -79,38 +108,84 @@ func (this *TimedInboundRay) InboundOutput() ray.InputStream {
 	return this.inboundRay.InboundOutput()
 }
 
+func (this *TimedInboundRay) isOpen() bool {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.server != nil
+}
+
+// Release closes the session and removes it from its server.
 func (this *TimedInboundRay) Release() {
 	log.Debug("UDP Server: Releasing TimedInboundRay: ", this.name)
 	this.Lock()
-	defer this.Unlock()
-	if this.server == nil {
+	server := this.server
+	if server == nil {
+		this.Unlock()
 		return
 	}
 	this.server = nil
 	this.inboundRay.InboundInput().Close()
 	this.inboundRay.InboundOutput().Release()
 	this.inboundRay = nil
+	this.Unlock()
+	this.cancel()
+
+	// The server is locked after the session, as the server locks sessions while holding its own lock.
+	server.removeSession(this)
 }
 
+// UDPServer keeps the NAT table of the UDP sessions of an inbound, which maps each pair of client and destination, or
+// each client for packet-addressed sessions, to a connection through the dispatcher.
 type UDPServer struct {
 	sync.RWMutex
 	conns            map[string]*TimedInboundRay
+	sources          map[string]int
 	packetDispatcher dispatcher.PacketDispatcher
 	meta             *proxy.InboundHandlerMeta
+	policy           *policy.Manager
+	sessions         *stats.Counter
 }
 
-func NewUDPServer(meta *proxy.InboundHandlerMeta, packetDispatcher dispatcher.PacketDispatcher) *UDPServer {
-	return &UDPServer{
+// NewUDPServer creates the UDP sessions manager of an inbound. Options may be nil for the defaults.
+func NewUDPServer(meta *proxy.InboundHandlerMeta, packetDispatcher dispatcher.PacketDispatcher, options *SessionOptions) *UDPServer {
+	server := &UDPServer{
 		conns:            make(map[string]*TimedInboundRay),
+		sources:          make(map[string]int),
 		packetDispatcher: packetDispatcher,
 		meta:             meta,
 	}
+	if options != nil {
+		server.policy = options.Policy
+		if options.Stats != nil && options.Stats.InboundsEnabled() && len(meta.Tag) > 0 {
+			server.sessions = options.Stats.RegisterCounter(stats.InboundUDPSessionsCounterName(meta.Tag))
+		}
+	}
+	return server
 }
 
-func (this *UDPServer) RemoveRay(name string) {
+// Sessions returns the number of open sessions.
+func (this *UDPServer) Sessions() int {
+	this.RLock()
+	defer this.RUnlock()
+
+	return len(this.conns)
+}
+
+func (this *UDPServer) removeSession(session *TimedInboundRay) {
 	this.Lock()
 	defer this.Unlock()
-	delete(this.conns, name)
+
+	if this.conns[session.name] == session {
+		delete(this.conns, session.name)
+	}
+	this.sources[session.source]--
+	if this.sources[session.source] <= 0 {
+		delete(this.sources, session.source)
+	}
+	if this.sessions != nil {
+		this.sessions.Add(-1)
+	}
 }
 
 func (this *UDPServer) locateExistingAndDispatch(name string, payload *alloc.Buffer) bool {
@@ -132,6 +207,46 @@ func (this *UDPServer) locateExistingAndDispatch(name string, payload *alloc.Buf
 	return false
 }
 
+// openSession dispatches a new session of the given name with the first payload. It returns nil and drops the payload
+// if the source is at the session limit of its policy.
+func (this *UDPServer) openSession(name string, session *proxy.SessionInfo, payload *alloc.Buffer) *TimedInboundRay {
+	level := uint32(0)
+	if session.User != nil {
+		level = session.User.Level
+	}
+	p := this.policy.ForLevel(level)
+	source := session.Source.Address.String()
+
+	this.Lock()
+	if p.UDPSessions > 0 && this.sources[source] >= p.UDPSessions {
+		this.Unlock()
+		log.Warning("UDP Server: Too many sessions from ", source, ", dropping packet.")
+		payload.Release()
+		return nil
+	}
+	this.sources[source]++
+	this.Unlock()
+	if this.sessions != nil {
+		this.sessions.Add(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	inboundRay := this.packetDispatcher.DispatchToOutbound(ctx, this.meta, session)
+	timedInboundRay := NewTimedInboundRay(name, source, p.UDPIdle, inboundRay, this, cancel)
+	outputStream := timedInboundRay.InboundInput()
+	if outputStream != nil {
+		outputStream.Write(payload)
+	}
+
+	this.Lock()
+	// A session released already, e.g. rejected by the dispatcher, has been removed and is not added again.
+	if timedInboundRay.isOpen() {
+		this.conns[name] = timedInboundRay
+	}
+	this.Unlock()
+	return timedInboundRay
+}
+
 func (this *UDPServer) Dispatch(session *proxy.SessionInfo, payload *alloc.Buffer, callback UDPResponseCallback) {
 	source := session.Source
 	destination := session.Destination
@@ -144,16 +259,10 @@ func (this *UDPServer) Dispatch(session *proxy.SessionInfo, payload *alloc.Buffe
 	}
 
 	log.Info("UDP Server: establishing new connection for ", destString)
-	inboundRay := this.packetDispatcher.DispatchToOutbound(this.meta, session)
-	timedInboundRay := NewTimedInboundRay(destString, inboundRay, this)
-	outputStream := timedInboundRay.InboundInput()
-	if outputStream != nil {
-		outputStream.Write(payload)
+	timedInboundRay := this.openSession(destString, session, payload)
+	if timedInboundRay == nil {
+		return
 	}
-
-	this.Lock()
-	this.conns[destString] = timedInboundRay
-	this.Unlock()
 	go this.handleConnection(timedInboundRay, source, callback)
 }
 
@@ -171,3 +280,52 @@ func (this *UDPServer) handleConnection(inboundRay *TimedInboundRay, source v2ne
 	}
 	inboundRay.Release()
 }
+
+// DispatchPacketAddr sends the payload through a packet-addressed session, which is shared by all destinations
+// of the same source. Responses from any remote address are delivered to the callback, so the client sees a full-cone NAT.
+func (this *UDPServer) DispatchPacketAddr(session *proxy.SessionInfo, payload *alloc.Buffer, callback UDPPacketResponseCallback) {
+	source := session.Source
+	payload, err := protocol.EncodePacketAddr(session.Destination, payload)
+	if err != nil {
+		log.Info("UDP Server: Dropping packet to ", session.Destination, ": ", err)
+		return
+	}
+
+	destString := source.String() + "-" + protocol.PacketAddrDomain
+	log.Debug("UDP Server: Dispatch packet-addressed request: ", destString)
+	if this.locateExistingAndDispatch(destString, payload) {
+		return
+	}
+
+	log.Info("UDP Server: establishing new packet-addressed connection for ", source)
+	timedInboundRay := this.openSession(destString, &proxy.SessionInfo{
+		Source:      source,
+		Destination: protocol.PacketAddrDestination(),
+		User:        session.User,
+	}, payload)
+	if timedInboundRay == nil {
+		return
+	}
+	go this.handlePacketAddrConnection(timedInboundRay, source, callback)
+}
+
+func (this *UDPServer) handlePacketAddrConnection(inboundRay *TimedInboundRay, source v2net.Destination, callback UDPPacketResponseCallback) {
+	for {
+		inputStream := inboundRay.InboundOutput()
+		if inputStream == nil {
+			break
+		}
+		data, err := inputStream.Read()
+		if err != nil {
+			break
+		}
+		from, err := protocol.DecodePacketAddr(data)
+		if err != nil {
+			log.Warning("UDP Server: Dropping invalid packet-addressed response: ", err)
+			data.Release()
+			continue
+		}
+		callback(source, from, data)
+	}
+	inboundRay.Release()
+}
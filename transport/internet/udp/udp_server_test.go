@@ -0,0 +1,64 @@
+package udp_test
+
+import (
+	"testing"
+	"time"
+
+	testdispatcher "v2ray.com/core/app/dispatcher/testing"
+	"v2ray.com/core/app/policy"
+	"v2ray.com/core/app/stats"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/udp"
+)
+
+func TestUDPServerSessions(t *testing.T) {
+	assert := assert.On(t)
+
+	packetDispatcher := testdispatcher.NewTestPacketDispatcher(nil)
+	go func() {
+		for range packetDispatcher.Destination {
+		}
+	}()
+
+	p := policy.DefaultPolicy(0)
+	p.UDPIdle = 200 * time.Millisecond
+	p.UDPSessions = 1
+	counters := stats.NewManager(&stats.Config{Inbounds: true})
+	server := NewUDPServer(&proxy.InboundHandlerMeta{Tag: "in"}, packetDispatcher, &SessionOptions{
+		Policy: policy.NewManager(&policy.Config{Levels: map[uint32]*policy.Policy{0: p}}),
+		Stats:  counters,
+	})
+
+	responses := make(chan string, 4)
+	callback := func(destination v2net.Destination, payload *alloc.Buffer) {
+		responses <- string(payload.Value)
+		payload.Release()
+	}
+	dispatch := func(source v2net.Address, port v2net.Port) {
+		server.Dispatch(&proxy.SessionInfo{
+			Source:      v2net.UDPDestination(source, v2net.Port(10000)),
+			Destination: v2net.UDPDestination(v2net.LocalHostIP, port),
+		}, alloc.NewLocalBuffer(64).Clear().AppendString("a"), callback)
+	}
+
+	dispatch(v2net.IPAddress([]byte{10, 0, 0, 1}), v2net.Port(53))
+	assert.String(<-responses).Equals("Processed: a")
+	assert.Int(server.Sessions()).Equals(1)
+
+	// The second session of the same source is over the limit of its policy.
+	dispatch(v2net.IPAddress([]byte{10, 0, 0, 1}), v2net.Port(54))
+	assert.Int(server.Sessions()).Equals(1)
+
+	dispatch(v2net.IPAddress([]byte{10, 0, 0, 2}), v2net.Port(53))
+	assert.String(<-responses).Equals("Processed: a")
+	assert.Int(server.Sessions()).Equals(2)
+	assert.Int64(counters.GetCounter(stats.InboundUDPSessionsCounterName("in")).Value()).Equals(2)
+
+	// Sessions are removed once idle.
+	time.Sleep(time.Second)
+	assert.Int(server.Sessions()).Equals(0)
+	assert.Int64(counters.GetCounter(stats.InboundUDPSessionsCounterName("in")).Value()).Equals(0)
+}
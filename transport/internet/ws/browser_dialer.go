@@ -0,0 +1,192 @@
+package ws
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"v2ray.com/core/common/log"
+)
+
+var (
+	ErrNoBrowser       = errors.New("WebSocket|BrowserDialer: No browser is connected to the bridge.")
+	ErrBrowserRejected = errors.New("WebSocket|BrowserDialer: Browser failed to connect.")
+
+	browserDialTimeout = time.Second * 16
+
+	bridgeAccess sync.Mutex
+	bridges      = make(map[string]*browserBridge)
+)
+
+// browserBridge serves a web page that makes WebSocket connections on behalf of V2Ray.
+// Every page keeps an idle WebSocket connection to the bridge. When V2Ray dials, the bridge takes one from the queue
+// and sends the target URL. The page connects to the target and relays all messages between the two connections.
+// Only WebSocket outbounds use the bridge, as there is no gRPC transport yet.
+// The URL of the page carries a random token, which the page passes back, so that only those who see the URL in the
+// log are able to connect to the bridge.
+type browserBridge struct {
+	token string
+	idle  chan *websocket.Conn
+}
+
+func getBrowserBridge(address string) (*browserBridge, error) {
+	bridgeAccess.Lock()
+	defer bridgeAccess.Unlock()
+
+	if bridge, found := bridges[address]; found {
+		return bridge, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	bridge := &browserBridge{
+		token: hex.EncodeToString(token),
+		idle:  make(chan *websocket.Conn, 256),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", bridge.servePage)
+	mux.HandleFunc("/websocket", bridge.serveWebSocket)
+	go http.Serve(listener, mux)
+
+	log.Info("WebSocket|BrowserDialer: Open http://", listener.Addr(), "/?token=", bridge.token, " in a browser to start dialing.")
+	bridges[address] = bridge
+	return bridge, nil
+}
+
+func (this *browserBridge) authorized(request *http.Request) bool {
+	token := request.URL.Query().Get("token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(this.token)) == 1
+}
+
+func (this *browserBridge) servePage(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		http.NotFound(writer, request)
+		return
+	}
+	if !this.authorized(request) {
+		http.Error(writer, "Forbidden", http.StatusForbidden)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Write([]byte(browserPage))
+}
+
+func (this *browserBridge) serveWebSocket(writer http.ResponseWriter, request *http.Request) {
+	if !this.authorized(request) {
+		log.Warning("WebSocket|BrowserDialer: Rejected connection without valid token from ", request.RemoteAddr)
+		http.Error(writer, "Forbidden", http.StatusForbidden)
+		return
+	}
+	// The default origin check only allows the page served by this bridge.
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  65536,
+		WriteBufferSize: 65536,
+	}
+	conn, err := upgrader.Upgrade(writer, request, nil)
+	if err != nil {
+		log.Warning("WebSocket|BrowserDialer: Failed to accept connection from browser: ", err)
+		return
+	}
+	select {
+	case this.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (this *browserBridge) Dial(uri string) (*wsconn, error) {
+	timeout := time.After(browserDialTimeout)
+	for {
+		var conn *websocket.Conn
+		select {
+		case conn = <-this.idle:
+		case <-timeout:
+			return nil, ErrNoBrowser
+		}
+
+		// The page may have been closed since the connection was queued.
+		conn.SetWriteDeadline(time.Now().Add(time.Second * 4))
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(uri)); err != nil {
+			conn.Close()
+			continue
+		}
+		conn.SetWriteDeadline(time.Time{})
+
+		conn.SetReadDeadline(time.Now().Add(browserDialTimeout))
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil || msgType != websocket.TextMessage || string(msg) != "ok" {
+			conn.Close()
+			return nil, ErrBrowserRejected
+		}
+		conn.SetReadDeadline(time.Time{})
+
+		c := &wsconn{wsc: conn, connClosing: false}
+		c.setup()
+		return c, nil
+	}
+}
+
+const browserPage = `<!DOCTYPE html>
+<html>
+<head><title>V2Ray Browser Dialer</title></head>
+<body>
+<p>V2Ray is dialing through this page. Keep it open.</p>
+<script>
+"use strict";
+const bridgeURL = (location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/websocket" + location.search;
+
+function connect() {
+	const bridge = new WebSocket(bridgeURL);
+	bridge.binaryType = "arraybuffer";
+	let used = false;
+	bridge.onmessage = function(event) {
+		used = true;
+		connect();
+		const remote = new WebSocket(event.data);
+		remote.binaryType = "arraybuffer";
+		bridge.onmessage = function(event) {
+			remote.send(event.data);
+		};
+		remote.onopen = function() {
+			bridge.send("ok");
+			remote.onmessage = function(event) {
+				bridge.send(event.data);
+			};
+		};
+		remote.onerror = function() {
+			bridge.close();
+		};
+		remote.onclose = function() {
+			bridge.close();
+		};
+		bridge.onclose = function() {
+			remote.close();
+		};
+	};
+	bridge.onclose = function() {
+		if (!used) {
+			setTimeout(connect, 1000);
+		}
+	};
+}
+
+for (let i = 0; i < 4; i++) {
+	connect();
+}
+</script>
+</body>
+</html>
+`
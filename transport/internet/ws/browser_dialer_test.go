@@ -0,0 +1,103 @@
+package ws
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+)
+
+// fakeBrowser does what the bridge page does in a browser.
+func fakeBrowser(bridgeAddress string, token string) {
+	header := http.Header{}
+	header.Set("Origin", "http://"+bridgeAddress)
+	for {
+		bridge, _, err := websocket.DefaultDialer.Dial("ws://"+bridgeAddress+"/websocket?token="+token, header)
+		if err != nil {
+			time.Sleep(time.Millisecond * 100)
+			continue
+		}
+		_, uri, err := bridge.ReadMessage()
+		if err != nil {
+			return
+		}
+		remote, _, err := websocket.DefaultDialer.Dial(string(uri), nil)
+		if err != nil {
+			bridge.Close()
+			return
+		}
+		bridge.WriteMessage(websocket.TextMessage, []byte("ok"))
+		go func() {
+			for {
+				msgType, msg, err := remote.ReadMessage()
+				if err != nil {
+					bridge.Close()
+					return
+				}
+				bridge.WriteMessage(msgType, msg)
+			}
+		}()
+		for {
+			msgType, msg, err := bridge.ReadMessage()
+			if err != nil {
+				remote.Close()
+				return
+			}
+			remote.WriteMessage(msgType, msg)
+		}
+	}
+}
+
+func TestBrowserDialer(t *testing.T) {
+	assert := assert.On(t)
+
+	(&Config{Pto: "ws", Path: "browser"}).Apply()
+	listen, err := ListenWS(v2net.DomainAddress("localhost"), 13144)
+	assert.Error(err).IsNil()
+	go func() {
+		conn, err := listen.Accept()
+		assert.Error(err).IsNil()
+		b := make([]byte, 16)
+		nBytes, _ := conn.Read(b)
+		conn.Write(b[:nBytes])
+		conn.Close()
+	}()
+
+	(&Config{Pto: "ws", Path: "browser", BrowserDialer: "127.0.0.1:13145"}).Apply()
+	defer (&Config{ConnectionReuse: true}).Apply()
+	bridge, err := getBrowserBridge("127.0.0.1:13145")
+	assert.Error(err).IsNil()
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		fakeBrowser("127.0.0.1:13145", bridge.token)
+	}()
+
+	conn, err := Dial(nil, v2net.TCPDestination(v2net.DomainAddress("localhost"), 13144))
+	assert.Error(err).IsNil()
+	_, err = conn.Write([]byte("browser"))
+	assert.Error(err).IsNil()
+	b := make([]byte, 16)
+	nBytes, err := conn.Read(b)
+	assert.Error(err).IsNil()
+	assert.String(string(b[:nBytes])).Equals("browser")
+	conn.Close()
+	listen.Close()
+
+	resp, err := http.Get("http://127.0.0.1:13145/?token=" + bridge.token)
+	assert.Error(err).IsNil()
+	assert.Int(resp.StatusCode).Equals(200)
+	resp.Body.Close()
+
+	resp, err = http.Get("http://127.0.0.1:13145/")
+	assert.Error(err).IsNil()
+	assert.Int(resp.StatusCode).Equals(403)
+	resp.Body.Close()
+
+	header := http.Header{}
+	header.Set("Origin", "http://127.0.0.1:13145")
+	_, _, err = websocket.DefaultDialer.Dial("ws://127.0.0.1:13145/websocket?token=wrong", header)
+	assert.Error(err).IsNotNil()
+}
@@ -7,6 +7,8 @@ type Config struct {
 	Cert                        string
 	PrivKey                     string
 	DeveloperInsecureSkipVerify bool
+	// BrowserDialer is the listening address of the browser bridge. If set, outbound connections are made by a browser.
+	BrowserDialer string
 }
 
 func (this *Config) Apply() {
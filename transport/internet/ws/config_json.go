@@ -1,8 +1,6 @@
 package ws
 
-import (
-	"encoding/json"
-)
+import "v2ray.com/core/common/loader"
 
 func (this *Config) UnmarshalJSON(data []byte) error {
 	type JsonConfig struct {
@@ -11,13 +9,14 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 		Pto             string `json:"Pto"`
 		Cert            string `json:"Cert"`
 		PrivKey         string `json:"PrivKey"`
+		BrowserDialer   string `json:"browserDialer"`
 	}
 	jsonConfig := &JsonConfig{
 		ConnectionReuse: true,
 		Path:            "",
 		Pto:             "",
 	}
-	if err := json.Unmarshal(data, jsonConfig); err != nil {
+	if err := loader.DecodeJSON(data, jsonConfig); err != nil {
 		return err
 	}
 	this.ConnectionReuse = jsonConfig.ConnectionReuse
@@ -25,6 +24,7 @@ func (this *Config) UnmarshalJSON(data []byte) error {
 	this.Pto = jsonConfig.Pto
 	this.PrivKey = jsonConfig.PrivKey
 	this.Cert = jsonConfig.Cert
+	this.BrowserDialer = jsonConfig.BrowserDialer
 	this.DeveloperInsecureSkipVerify = false
 	return nil
 }
@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"v2ray.com/core/common/log"
-	"v2ray.com/core/common/signal"
+	"v2ray.com/core/common/task"
 )
 
 type AwaitingConnection struct {
@@ -20,45 +20,56 @@ func (this *AwaitingConnection) Expired() bool {
 
 type ConnectionCache struct {
 	sync.Mutex
-	cache       map[string][]*AwaitingConnection
-	cleanupOnce signal.Once
+	cache   map[string][]*AwaitingConnection
+	cleanup *task.Periodic
 }
 
 func NewConnectionCache() *ConnectionCache {
-	return &ConnectionCache{
+	cache := &ConnectionCache{
 		cache: make(map[string][]*AwaitingConnection),
 	}
+	cache.cleanup = &task.Periodic{
+		Name:     "Connection cache cleanup",
+		Interval: time.Second * 7,
+		Execute:  cache.Cleanup,
+	}
+	return cache
 }
 
-func (this *ConnectionCache) Cleanup() {
-	defer this.cleanupOnce.Reset()
-
-	for len(this.cache) > 0 {
-		time.Sleep(time.Second * 7)
-		this.Lock()
-		for key, value := range this.cache {
-			size := len(value)
-			changed := false
-			for i := 0; i < size; {
-				if value[i].Expired() {
-					value[i].conn.Close()
-					value[i] = value[size-1]
-					size--
-					changed = true
-				} else {
-					i++
-				}
+// Cleanup closes the expired connections in the cache. The cleanup task stops once the cache is empty, and starts
+// again when a connection is recycled.
+func (this *ConnectionCache) Cleanup() error {
+	this.Lock()
+	defer this.Unlock()
+
+	for key, value := range this.cache {
+		size := len(value)
+		changed := false
+		for i := 0; i < size; {
+			if value[i].Expired() {
+				value[i].conn.Close()
+				value[i] = value[size-1]
+				size--
+				changed = true
+			} else {
+				i++
 			}
-			if changed {
-				for i := size; i < len(value); i++ {
-					value[i] = nil
-				}
-				value = value[:size]
-				this.cache[key] = value
+		}
+		if changed {
+			for i := size; i < len(value); i++ {
+				value[i] = nil
 			}
+			value = value[:size]
+			this.cache[key] = value
+		}
+		if len(value) == 0 {
+			delete(this.cache, key)
 		}
-		this.Unlock()
 	}
+	if len(this.cache) == 0 {
+		this.cleanup.Stop()
+	}
+	return nil
 }
 
 func (this *ConnectionCache) Recycle(dest string, conn *wsconn) {
@@ -79,7 +90,7 @@ func (this *ConnectionCache) Recycle(dest string, conn *wsconn) {
 	}
 	this.cache[dest] = list
 
-	go this.cleanupOnce.Do(this.Cleanup)
+	this.cleanup.Start()
 }
 
 func FindFirstValid(list []*AwaitingConnection) int {
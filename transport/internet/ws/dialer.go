@@ -17,6 +17,12 @@ var (
 )
 
 func Dial(src v2net.Address, dest v2net.Destination) (internet.Connection, error) {
+	return DialResolved(src, dest, nil)
+}
+
+// DialResolved dials a WebSocket connection to the given IP, while using the domain of destination in Host header and
+// SNI. Nil ip means the domain is resolved by the system.
+func DialResolved(src v2net.Address, dest v2net.Destination, ip v2net.Address) (internet.Connection, error) {
 	log.Info("WebSocket|Dailer: Creating connection to ", dest)
 	if src == nil {
 		src = v2net.AnyIP
@@ -31,7 +37,7 @@ func Dial(src v2net.Address, dest v2net.Destination) (internet.Connection, error
 	}
 	if conn == nil {
 		var err error
-		conn, err = wsDial(src, dest)
+		conn, err = wsDial(src, dest, ip)
 		if err != nil {
 			log.Warning("WebSocket|Dialer: Dial failed: ", err)
 			return nil, err
@@ -42,11 +48,16 @@ func Dial(src v2net.Address, dest v2net.Destination) (internet.Connection, error
 
 func init() {
 	internet.WSDialer = Dial
+	internet.WSResolvedDialer = DialResolved
 }
 
-func wsDial(src v2net.Address, dest v2net.Destination) (*wsconn, error) {
+func wsDial(src v2net.Address, dest v2net.Destination, ip v2net.Address) (*wsconn, error) {
+	dialDest := dest
+	if ip != nil {
+		dialDest.Address = ip
+	}
 	commonDial := func(network, addr string) (net.Conn, error) {
-		return internet.DialToDest(src, dest)
+		return internet.DialToDest(src, dialDest)
 	}
 
 	tlsconf := &tls.Config{ServerName: dest.Address.Domain(), InsecureSkipVerify: effectiveConfig.DeveloperInsecureSkipVerify}
@@ -59,6 +70,14 @@ func wsDial(src v2net.Address, dest v2net.Destination) (*wsconn, error) {
 		return fmt.Sprintf("%v://%v/%v", pto, dst.NetAddr(), path)
 	}(dest, effpto, effectiveConfig.Path)
 
+	if len(effectiveConfig.BrowserDialer) > 0 {
+		bridge, err := getBrowserBridge(effectiveConfig.BrowserDialer)
+		if err != nil {
+			return nil, err
+		}
+		return bridge.Dial(uri)
+	}
+
 	conn, resp, err := dialer.Dial(uri, nil)
 	if err != nil {
 		if resp != nil {
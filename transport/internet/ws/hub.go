@@ -12,6 +12,7 @@ import (
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/internal"
 )
 
 var (
@@ -44,7 +45,9 @@ func ListenWS(address v2net.Address, port v2net.Port) (internet.Listener, error)
 
 func (wsl *WSListener) listenws(address v2net.Address, port v2net.Port) error {
 
-	http.HandleFunc("/"+effectiveConfig.Path, func(w http.ResponseWriter, r *http.Request) {
+	// A mux of its own, so that handlers registered on http.DefaultServeMux are not exposed on this port.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+effectiveConfig.Path, func(w http.ResponseWriter, r *http.Request) {
 		con, err := wsl.converttovws(w, r)
 		if err != nil {
 			log.Warning("WebSocket|Listener: Failed to convert connection: ", err)
@@ -66,15 +69,24 @@ func (wsl *WSListener) listenws(address v2net.Address, port v2net.Port) error {
 	errchan := make(chan error)
 
 	listenerfunc := func() error {
-		ol, err := net.Listen("tcp", address.String()+":"+strconv.Itoa(int(port.Value())))
+		var ol net.Listener
+		activated, err := internal.ActivatedTCPListener(address, port)
 		if err != nil {
 			return err
 		}
+		if activated != nil {
+			ol = activated
+		} else {
+			ol, err = net.Listen("tcp", address.String()+":"+strconv.Itoa(int(port.Value())))
+			if err != nil {
+				return err
+			}
+		}
 		wsl.listener, err = NewStoppableListener(ol)
 		if err != nil {
 			return err
 		}
-		return http.Serve(wsl.listener, nil)
+		return http.Serve(wsl.listener, mux)
 	}
 
 	if effectiveConfig.Pto == "wss" {
@@ -84,7 +96,7 @@ func (wsl *WSListener) listenws(address v2net.Address, port v2net.Port) error {
 			if err != nil {
 				return err
 			}
-			return http.Serve(wsl.listener, nil)
+			return http.Serve(wsl.listener, mux)
 		}
 	}
 
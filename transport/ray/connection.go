@@ -0,0 +1,71 @@
+package ray
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+)
+
+// Connection is the client side of an InboundRay as a net.Conn, for using a dispatched connection with the standard
+// library, e.g. in an http.Client. Deadlines are not supported.
+type Connection struct {
+	sync.Mutex
+	link   InboundRay
+	reader *v2io.ChanReader
+}
+
+func NewConnection(link InboundRay) *Connection {
+	return &Connection{
+		link: link,
+	}
+}
+
+func (this *Connection) Read(b []byte) (int, error) {
+	this.Lock()
+	if this.reader == nil {
+		// ChanReader blocks on creation until the first response arrives.
+		this.reader = v2io.NewChanReader(this.link.InboundOutput())
+	}
+	reader := this.reader
+	this.Unlock()
+	return reader.Read(b)
+}
+
+func (this *Connection) Write(b []byte) (int, error) {
+	buffer := alloc.NewBuffer().Clear()
+	buffer.Append(b)
+	if err := this.link.InboundInput().Write(buffer); err != nil {
+		buffer.Release()
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (this *Connection) Close() error {
+	this.link.InboundInput().Close()
+	this.link.InboundOutput().Release()
+	return nil
+}
+
+func (this *Connection) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4zero}
+}
+
+func (this *Connection) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4zero}
+}
+
+func (this *Connection) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (this *Connection) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (this *Connection) SetWriteDeadline(t time.Time) error {
+	return nil
+}
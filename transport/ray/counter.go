@@ -0,0 +1,104 @@
+package ray
+
+import (
+	"v2ray.com/core/common/alloc"
+)
+
+// Counter counts the bytes passing through a stream.
+type Counter interface {
+	Add(delta int64) int64
+}
+
+// NewCountedRay wraps a Ray so that data read from its streams is counted. Uplink counts the traffic from inbound to
+// outbound, and downlink counts the other direction. A nil counter leaves the corresponding direction uncounted.
+func NewCountedRay(ray Ray, uplink Counter, downlink Counter) Ray {
+	if uplink == nil && downlink == nil {
+		return ray
+	}
+	counted := &countedRay{
+		Ray:    ray,
+		input:  ray.OutboundInput(),
+		output: ray.InboundOutput(),
+	}
+	if uplink != nil {
+		counted.input = &countedInputStream{InputStream: counted.input, counter: uplink}
+	}
+	if downlink != nil {
+		counted.output = &countedInputStream{InputStream: counted.output, counter: downlink}
+	}
+	return counted
+}
+
+type countedRay struct {
+	Ray
+	input  InputStream
+	output InputStream
+}
+
+func (this *countedRay) OutboundInput() InputStream {
+	return this.input
+}
+
+func (this *countedRay) InboundOutput() InputStream {
+	return this.output
+}
+
+// NewCountedOutboundRay wraps an OutboundRay so that data read from its input and written to its output are counted.
+func NewCountedOutboundRay(ray OutboundRay, uplink Counter, downlink Counter) OutboundRay {
+	if uplink == nil && downlink == nil {
+		return ray
+	}
+	counted := &countedOutboundRay{
+		input:  ray.OutboundInput(),
+		output: ray.OutboundOutput(),
+	}
+	if uplink != nil {
+		counted.input = &countedInputStream{InputStream: counted.input, counter: uplink}
+	}
+	if downlink != nil {
+		counted.output = &countedOutputStream{OutputStream: counted.output, counter: downlink}
+	}
+	return counted
+}
+
+type countedOutboundRay struct {
+	input  InputStream
+	output OutputStream
+}
+
+func (this *countedOutboundRay) OutboundInput() InputStream {
+	return this.input
+}
+
+func (this *countedOutboundRay) OutboundOutput() OutputStream {
+	return this.output
+}
+
+type countedInputStream struct {
+	InputStream
+	counter Counter
+}
+
+func (this *countedInputStream) Read() (*alloc.Buffer, error) {
+	buffer, err := this.InputStream.Read()
+	if err != nil {
+		return nil, err
+	}
+	this.counter.Add(int64(buffer.Len()))
+	return buffer, nil
+}
+
+type countedOutputStream struct {
+	OutputStream
+	counter Counter
+}
+
+func (this *countedOutputStream) Write(buffer *alloc.Buffer) error {
+	// The buffer is released by the stream once written.
+	size := buffer.Len()
+	if err := this.OutputStream.Write(buffer); err != nil {
+		return err
+	}
+	this.counter.Add(int64(size))
+	return nil
+}
@@ -19,9 +19,20 @@ var (
 
 // NewRay creates a new Ray for direct traffic transport.
 func NewRay() Ray {
+	return NewBufferedRay(bufferSize)
+}
+
+// NewBufferedRay creates a new Ray whose streams each queue up to the given number of buffers.
+func NewBufferedRay(size int) Ray {
+	return NewLimitedRay(size, nil)
+}
+
+// NewLimitedRay creates a new Ray whose streams each queue up to the given number of buffers, and share the limit of
+// pooled buffers. A nil limit leaves pooled buffers unlimited.
+func NewLimitedRay(size int, limit *BufferLimit) Ray {
 	return &directRay{
-		Input:  NewStream(),
-		Output: NewStream(),
+		Input:  newLimitedStream(size, limit),
+		Output: newLimitedStream(size, limit),
 	}
 }
 
@@ -50,11 +61,22 @@ type Stream struct {
 	access sync.RWMutex
 	closed bool
 	buffer chan *alloc.Buffer
+	limit  *BufferLimit
 }
 
 func NewStream() *Stream {
+	return NewBufferedStream(bufferSize)
+}
+
+// NewBufferedStream creates a new Stream that queues up to the given number of buffers.
+func NewBufferedStream(size int) *Stream {
+	return newLimitedStream(size, nil)
+}
+
+func newLimitedStream(size int, limit *BufferLimit) *Stream {
 	return &Stream{
-		buffer: make(chan *alloc.Buffer, bufferSize),
+		buffer: make(chan *alloc.Buffer, size),
+		limit:  limit,
 	}
 }
 
@@ -73,10 +95,32 @@ func (this *Stream) Read() (*alloc.Buffer, error) {
 	if !open {
 		return nil, io.EOF
 	}
+	if this.limit != nil {
+		this.limit.release(result)
+	}
 	return result, nil
 }
 
 func (this *Stream) Write(data *alloc.Buffer) error {
+	if this.limit == nil {
+		return this.write(data)
+	}
+	buffers := this.limit.hold(data)
+	for i, buffer := range buffers {
+		if err := this.write(buffer); err != nil {
+			for _, unwritten := range buffers[i:] {
+				this.limit.release(unwritten)
+				if unwritten != data {
+					unwritten.Release()
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Stream) write(data *alloc.Buffer) error {
 	for !this.closed {
 		err := this.TryWriteOnce(data)
 		if err != ErrIOTimeout {
@@ -124,6 +168,9 @@ func (this *Stream) Release() {
 		return
 	}
 	for data := range this.buffer {
+		if this.limit != nil {
+			this.limit.release(data)
+		}
 		data.Release()
 	}
 	this.buffer = nil
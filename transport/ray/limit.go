@@ -0,0 +1,69 @@
+package ray
+
+import (
+	"sync/atomic"
+
+	"v2ray.com/core/common/alloc"
+)
+
+// BufferLimit limits the pooled buffers queued in the streams of one connection, so that slow connections don't hold
+// the shared pools. Data beyond the limit is queued in buffers that are not pooled.
+type BufferLimit struct {
+	held  int32
+	max   int32
+	class alloc.SizeClass
+}
+
+// NewBufferLimit returns a limit of the given number of pooled buffers, taken from pools up to the given class. Zero
+// means no limit on the number. It returns nil if nothing is limited.
+func NewBufferLimit(max int, class alloc.SizeClass) *BufferLimit {
+	if max == 0 && class == alloc.LargeClass {
+		return nil
+	}
+	return &BufferLimit{
+		max:   int32(max),
+		class: class,
+	}
+}
+
+// Held returns the number of pooled buffers currently queued.
+func (this *BufferLimit) Held() int {
+	return int(atomic.LoadInt32(&this.held))
+}
+
+func (this *BufferLimit) acquire(buffer *alloc.Buffer) *alloc.Buffer {
+	if !buffer.IsPooled() {
+		return buffer
+	}
+	if held := atomic.AddInt32(&this.held, 1); this.max > 0 && held > this.max {
+		atomic.AddInt32(&this.held, -1)
+		return buffer.Unpool()
+	}
+	return buffer
+}
+
+func (this *BufferLimit) release(buffer *alloc.Buffer) {
+	if buffer.IsPooled() {
+		atomic.AddInt32(&this.held, -1)
+	}
+}
+
+// hold converts the buffer into the ones to queue. Content of a buffer from a larger pool than allowed is copied
+// into buffers of the allowed class, and the original buffer is released.
+func (this *BufferLimit) hold(buffer *alloc.Buffer) []*alloc.Buffer {
+	if this.class.Fits(buffer) {
+		return []*alloc.Buffer{this.acquire(buffer)}
+	}
+	size := this.class.Size()
+	buffers := make([]*alloc.Buffer, 0, (buffer.Len()+size-1)/size)
+	for content := buffer.Value; len(content) > 0; {
+		n := len(content)
+		if n > size {
+			n = size
+		}
+		buffers = append(buffers, this.acquire(this.class.NewBuffer().Clear().Append(content[:n])))
+		content = content[n:]
+	}
+	buffer.Release()
+	return buffers
+}
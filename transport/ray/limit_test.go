@@ -0,0 +1,53 @@
+package ray_test
+
+import (
+	"testing"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/ray"
+)
+
+func TestBufferLimit(t *testing.T) {
+	assert := assert.On(t)
+
+	limit := NewBufferLimit(1, alloc.LargeClass)
+	ray := NewLimitedRay(4, limit)
+
+	assert.Error(ray.InboundInput().Write(alloc.NewBuffer().Clear().Append([]byte("a")))).IsNil()
+	assert.Error(ray.OutboundOutput().Write(alloc.NewBuffer().Clear().Append([]byte("b")))).IsNil()
+	assert.Int(limit.Held()).Equals(1)
+
+	first, err := ray.OutboundInput().Read()
+	assert.Error(err).IsNil()
+	assert.Bool(first.IsPooled()).IsTrue()
+	first.Release()
+
+	second, err := ray.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	assert.Bool(second.IsPooled()).IsFalse()
+	assert.String(second.String()).Equals("b")
+	assert.Int(limit.Held()).Equals(0)
+}
+
+func TestBufferLimitClass(t *testing.T) {
+	assert := assert.On(t)
+
+	ray := NewLimitedRay(8, NewBufferLimit(0, alloc.MediumClass))
+
+	payload := make([]byte, alloc.BufferSize+10)
+	assert.Error(ray.InboundInput().Write(alloc.NewLargeBuffer().Clear().Append(payload))).IsNil()
+	ray.InboundInput().Close()
+
+	total := 0
+	for {
+		buffer, err := ray.OutboundInput().Read()
+		if err != nil {
+			break
+		}
+		assert.Bool(alloc.MediumClass.Fits(buffer)).IsTrue()
+		total += buffer.Len()
+		buffer.Release()
+	}
+	assert.Int(total).Equals(len(payload))
+}
@@ -0,0 +1,91 @@
+package ray
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+)
+
+// NewLingeringRay wraps a Ray so that once one direction finishes, the whole Ray is released after the other stays
+// idle for the given time. UplinkOnly applies when the downlink finishes first, and downlinkOnly when the uplink does.
+// A zero time leaves the Ray open until the other direction finishes as well.
+func NewLingeringRay(ray Ray, uplinkOnly time.Duration, downlinkOnly time.Duration) Ray {
+	if uplinkOnly == 0 && downlinkOnly == 0 {
+		return ray
+	}
+	lingering := &lingeringRay{
+		Ray:          ray,
+		uplinkOnly:   uplinkOnly,
+		downlinkOnly: downlinkOnly,
+		lastActivity: time.Now().UnixNano(),
+	}
+	lingering.input = &lingeringStream{InputStream: ray.OutboundInput(), ray: lingering, remaining: downlinkOnly}
+	lingering.output = &lingeringStream{InputStream: ray.InboundOutput(), ray: lingering, remaining: uplinkOnly}
+	return lingering
+}
+
+type lingeringRay struct {
+	Ray
+	input        InputStream
+	output       InputStream
+	uplinkOnly   time.Duration
+	downlinkOnly time.Duration
+	lastActivity int64
+	linger       sync.Once
+}
+
+func (this *lingeringRay) OutboundInput() InputStream {
+	return this.input
+}
+
+func (this *lingeringRay) InboundOutput() InputStream {
+	return this.output
+}
+
+func (this *lingeringRay) update() {
+	atomic.StoreInt64(&this.lastActivity, time.Now().UnixNano())
+}
+
+// finish starts counting the idle time of the remaining direction, once the first direction finishes.
+func (this *lingeringRay) finish(timeout time.Duration) {
+	if timeout == 0 {
+		return
+	}
+	this.linger.Do(func() {
+		this.update()
+		time.AfterFunc(timeout, func() {
+			this.check(timeout)
+		})
+	})
+}
+
+func (this *lingeringRay) check(timeout time.Duration) {
+	idle := time.Since(time.Unix(0, atomic.LoadInt64(&this.lastActivity)))
+	if idle < timeout {
+		time.AfterFunc(timeout-idle, func() {
+			this.check(timeout)
+		})
+		return
+	}
+	this.Ray.OutboundInput().Release()
+	this.Ray.InboundOutput().Release()
+}
+
+type lingeringStream struct {
+	InputStream
+	ray *lingeringRay
+	// remaining is the idle time allowed for the other direction once this one finishes.
+	remaining time.Duration
+}
+
+func (this *lingeringStream) Read() (*alloc.Buffer, error) {
+	buffer, err := this.InputStream.Read()
+	if err != nil {
+		this.ray.finish(this.remaining)
+		return nil, err
+	}
+	this.ray.update()
+	return buffer, nil
+}
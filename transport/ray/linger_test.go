@@ -0,0 +1,30 @@
+package ray_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/ray"
+)
+
+func TestLingeringRayReleasesIdleDownlink(t *testing.T) {
+	assert := assert.On(t)
+
+	ray := NewLingeringRay(NewRay(), 0, 100*time.Millisecond)
+	ray.InboundInput().Close()
+	_, err := ray.OutboundInput().Read()
+	assert.Error(err).Equals(io.EOF)
+
+	// The downlink is still open right after the uplink finishes.
+	assert.Error(ray.OutboundOutput().Write(alloc.NewLocalBuffer(32).Clear())).IsNil()
+	buffer, err := ray.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	buffer.Release()
+
+	time.Sleep(300 * time.Millisecond)
+	_, err = ray.InboundOutput().Read()
+	assert.Error(err).Equals(io.EOF)
+}
@@ -0,0 +1,97 @@
+package ray
+
+import (
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/ratelimit"
+)
+
+// NewThrottledRay wraps a Ray so that data read from its streams is limited by the given buckets.
+// Uplink limits the traffic from inbound to outbound, and downlink limits the other direction.
+// A nil bucket leaves the corresponding direction unlimited.
+func NewThrottledRay(ray Ray, uplink *ratelimit.TokenBucket, downlink *ratelimit.TokenBucket) Ray {
+	if uplink == nil && downlink == nil {
+		return ray
+	}
+	throttled := &throttledRay{
+		Ray:    ray,
+		input:  ray.OutboundInput(),
+		output: ray.InboundOutput(),
+	}
+	if uplink != nil {
+		throttled.input = &throttledStream{InputStream: throttled.input, bucket: uplink}
+	}
+	if downlink != nil {
+		throttled.output = &throttledStream{InputStream: throttled.output, bucket: downlink}
+	}
+	return throttled
+}
+
+type throttledRay struct {
+	Ray
+	input  InputStream
+	output InputStream
+}
+
+func (this *throttledRay) OutboundInput() InputStream {
+	return this.input
+}
+
+func (this *throttledRay) InboundOutput() InputStream {
+	return this.output
+}
+
+type throttledStream struct {
+	InputStream
+	bucket *ratelimit.TokenBucket
+}
+
+func (this *throttledStream) Read() (*alloc.Buffer, error) {
+	buffer, err := this.InputStream.Read()
+	if err != nil {
+		return nil, err
+	}
+	this.bucket.Wait(buffer.Len())
+	return buffer, nil
+}
+
+// NewThrottledOutboundRay wraps an OutboundRay so that data read from its input and written to its output are limited
+// by the given buckets. A nil bucket leaves the corresponding direction unlimited.
+func NewThrottledOutboundRay(ray OutboundRay, uplink *ratelimit.TokenBucket, downlink *ratelimit.TokenBucket) OutboundRay {
+	if uplink == nil && downlink == nil {
+		return ray
+	}
+	throttled := &throttledOutboundRay{
+		input:  ray.OutboundInput(),
+		output: ray.OutboundOutput(),
+	}
+	if uplink != nil {
+		throttled.input = &throttledStream{InputStream: throttled.input, bucket: uplink}
+	}
+	if downlink != nil {
+		throttled.output = &throttledOutputStream{OutputStream: throttled.output, bucket: downlink}
+	}
+	return throttled
+}
+
+type throttledOutboundRay struct {
+	input  InputStream
+	output OutputStream
+}
+
+func (this *throttledOutboundRay) OutboundInput() InputStream {
+	return this.input
+}
+
+func (this *throttledOutboundRay) OutboundOutput() OutputStream {
+	return this.output
+}
+
+type throttledOutputStream struct {
+	OutputStream
+	bucket *ratelimit.TokenBucket
+}
+
+func (this *throttledOutputStream) Write(buffer *alloc.Buffer) error {
+	this.bucket.Wait(buffer.Len())
+	return this.OutputStream.Write(buffer)
+}